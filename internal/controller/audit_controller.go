@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"oss-backend/internal/model/dto"
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/service"
+	"oss-backend/pkg/common"
+)
+
+// AuditController 审计日志控制器
+type AuditController struct {
+	auditService service.AuditService
+}
+
+// NewAuditController 创建审计日志控制器
+func NewAuditController(auditService service.AuditService) *AuditController {
+	return &AuditController{
+		auditService: auditService,
+	}
+}
+
+// GetMyActivity 获取个人操作记录
+// @Summary 获取个人操作记录
+// @Description 分页获取当前用户本人执行的操作记录，可按操作类型和日期筛选
+// @Tags 用户模块
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param operation query string false "操作类型(upload/download/delete/restore/share)"
+// @Param start_date query string false "开始日期，格式 2006-01-02"
+// @Param end_date query string false "结束日期，格式 2006-01-02"
+// @Param page query int false "页码，默认1"
+// @Param size query int false "每页大小，默认10"
+// @Success 200 {object} common.Response{data=dto.PageResult[dto.AuditLogResponse]} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/user/activity [get]
+func (c *AuditController) GetMyActivity(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	var req dto.AuditActivityQueryRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	logs, total, err := c.auditService.GetMyActivity(ctx, userID, &req)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse("获取操作记录失败: "+err.Error()))
+		return
+	}
+
+	items := make([]dto.AuditLogResponse, 0, len(logs))
+	for _, l := range logs {
+		items = append(items, buildAuditLogResponse(l))
+	}
+
+	pageQuery := dto.PageQuery{Page: req.Page, Size: req.Size}.WithDefaultValues()
+	ctx.JSON(http.StatusOK, common.SuccessResponse(dto.NewPageResult(items, total, pageQuery)))
+}
+
+// GetArchivedLogs 获取归档日志
+// @Summary 获取归档日志
+// @Description 按日期范围读取已归档到对象存储的审计日志
+// @Tags 系统管理员API
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param start_date query string true "开始日期，格式 2006-01-02"
+// @Param end_date query string true "结束日期，格式 2006-01-02"
+// @Success 200 {object} common.Response{data=[]dto.AuditLogResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/admin/audit/archives [get]
+func (c *AuditController) GetArchivedLogs(ctx *gin.Context) {
+	var req dto.AuditArchiveQueryRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	startTime, err := time.ParseInLocation("2006-01-02", req.StartDate, time.Local)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse("开始日期格式错误"))
+		return
+	}
+	endDate, err := time.ParseInLocation("2006-01-02", req.EndDate, time.Local)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse("结束日期格式错误"))
+		return
+	}
+	// 结束日期包含当天
+	endTime := endDate.Add(24*time.Hour - time.Nanosecond)
+
+	logs, err := c.auditService.GetArchivedLogs(ctx, startTime, endTime)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取归档日志失败: "+err.Error()))
+		return
+	}
+
+	response := make([]dto.AuditLogResponse, 0, len(logs))
+	for _, l := range logs {
+		response = append(response, buildAuditLogResponse(l))
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(response))
+}
+
+// ArchiveLogs 立即执行日志归档
+// @Summary 立即归档日志
+// @Description 将超过保留期的审计日志立即归档并从在线表中删除
+// @Tags 系统管理员API
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Success 200 {object} common.Response{data=dto.AuditArchiveResultResponse} "成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/admin/audit/archive [post]
+func (c *AuditController) ArchiveLogs(ctx *gin.Context) {
+	count, err := c.auditService.ArchiveExpiredLogs(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("归档日志失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(dto.AuditArchiveResultResponse{ArchivedCount: count}))
+}
+
+// 构建审计日志响应对象
+func buildAuditLogResponse(l *entity.Log) dto.AuditLogResponse {
+	response := dto.AuditLogResponse{
+		ID:        l.ID,
+		UserID:    l.UserID,
+		GroupID:   l.GroupID,
+		ProjectID: l.ProjectID,
+		FileID:    l.FileID,
+		Operation: l.Operation,
+		IPAddress: l.IPAddress,
+		UserAgent: l.UserAgent,
+		Status:    l.Status,
+		CreatedAt: l.CreatedAt,
+	}
+
+	if l.User.ID != "" {
+		response.UserName = l.User.Name
+	}
+
+	return response
+}
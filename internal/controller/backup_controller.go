@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"oss-backend/internal/service"
+	"oss-backend/pkg/common"
+)
+
+// BackupController 定时备份控制器
+type BackupController struct {
+	scheduler  *service.BackupScheduler
+	jobService service.JobService
+}
+
+// NewBackupController 创建定时备份控制器
+func NewBackupController(scheduler *service.BackupScheduler, jobService service.JobService) *BackupController {
+	return &BackupController{
+		scheduler:  scheduler,
+		jobService: jobService,
+	}
+}
+
+// ListBackups 获取备份计划
+// @Summary 获取备份计划
+// @Description 列出配置的备份目标、下次执行时间和最近一次备份任务的状态
+// @Tags 系统管理员API
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Success 200 {object} common.Response{data=[]dto.BackupScheduleResponse} "成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Router /api/oss/admin/backups [get]
+func (c *BackupController) ListBackups(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, common.SuccessResponse(c.scheduler.ListSchedules(ctx)))
+}
+
+// TriggerBackup 立即执行备份
+// @Summary 立即执行备份
+// @Description 以后台任务方式立即执行一次指定目标的备份，任务结果为备份清单的对象名
+// @Tags 系统管理员API
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param name path string true "备份目标名称"
+// @Success 200 {object} common.Response{data=dto.JobResponse} "成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "备份目标不存在"
+// @Failure 409 {object} common.Response "该目标的备份正在执行"
+// @Router /api/oss/admin/backups/{name}/run [post]
+func (c *BackupController) TriggerBackup(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	jobID, err := c.scheduler.Trigger(ctx.Param("name"), userID)
+	if err != nil {
+		if errors.Is(err, service.ErrBackupTargetNotFound) {
+			ctx.JSON(http.StatusNotFound, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrBackupRunning) {
+			ctx.JSON(http.StatusConflict, common.ErrorResponse(err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	job, err := c.jobService.GetJob(ctx, jobID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(job))
+}
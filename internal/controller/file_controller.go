@@ -1,16 +1,26 @@
 package controller
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"oss-backend/internal/middleware"
 	"oss-backend/internal/model/dto"
 	"oss-backend/internal/model/entity"
 	"oss-backend/internal/service"
 	"oss-backend/pkg/common"
+	"oss-backend/pkg/thumbnail"
 )
 
 // FileController 文件控制器
@@ -31,20 +41,26 @@ func NewFileController(fileService service.FileService, projectService service.P
 
 // Upload 上传文件
 // @Summary 上传文件
-// @Description 上传文件到指定项目和路径
+// @Description 上传文件到指定项目和路径，需要该目录的写入权限：目录或其上级文件夹上有授权时按授权的角色检查，否则按项目角色检查
 // @Tags 文件管理
 // @Accept multipart/form-data
 // @Produce json
 // @Param Authorization header string true "Bearer {{token}}"
+// @Param X-Content-SHA256 header string false "文件内容的SHA256（十六进制），提供时服务端不再预先读取文件计算哈希"
+// @Param Idempotency-Key header string false "幂等键，重试时携带同一值不会重复上传，返回首次上传的文件"
 // @Param project_id formData int true "项目ID"
 // @Param path formData string false "上传路径，默认为根目录"
+// @Param parent_folder_id formData string false "上传到的文件夹ID，指定时优先于path"
 // @Param comment formData string false "文件注释"
 // @Param overwrite formData bool false "是否覆盖同名文件"
 // @Param file formData file true "上传的文件"
 // @Success 200 {object} common.Response{data=dto.FileResponse} "成功"
-// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 400 {object} common.Response "请求参数错误或文件不符合上传策略"
 // @Failure 401 {object} common.Response "未授权"
 // @Failure 403 {object} common.Response "权限不足"
+// @Failure 409 {object} common.Response "使用同一幂等键的上传正在处理，或同名文件夹已存在"
+// @Failure 413 {object} common.Response "项目或群组存储配额不足"
+// @Failure 422 {object} common.Response "幂等键已用于其他上传请求"
 // @Failure 500 {object} common.Response "内部服务器错误"
 // @Router /api/oss/file/upload [post]
 func (c *FileController) Upload(ctx *gin.Context) {
@@ -70,21 +86,59 @@ func (c *FileController) Upload(ctx *gin.Context) {
 		return
 	}
 
-	// 检查项目权限 (需要写入权限)
-	projectDomain := fmt.Sprintf("project:%s", req.ProjectID)
-	canWrite, err := c.authService.CanUserAccessResource(ctx, userID, "files", service.ActionCreate, projectDomain)
+	// 指定了文件夹ID时以该文件夹作为上传目录
+	uploadPath, err := c.fileService.ResolveUploadPath(ctx, req.ProjectID, req.ParentFolderID, req.Path)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPath) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	// 检查目标目录的写入权限，目录或其上级文件夹上的授权优先于项目角色
+	canWrite, err := c.fileService.CheckPathPermission(ctx, req.ProjectID, uploadPath, userID, service.ActionCreate)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidPath) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
 		return
 	}
 	if !canWrite {
-		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有项目写入权限"))
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有该目录的写入权限"))
 		return
 	}
 
-	// 上传文件
-	uploadedFile, err := c.fileService.Upload(ctx, req.ProjectID, userID, file, req.Path)
+	// 上传文件，客户端可通过 X-Content-SHA256 提供预先计算的哈希以避免服务端重复读取，通过 Idempotency-Key 安全重试
+	uploadedFile, err := c.fileService.UploadIdempotent(ctx, req.ProjectID, userID, ctx.GetHeader("Idempotency-Key"), file, uploadPath, ctx.GetHeader("X-Content-SHA256"))
 	if err != nil {
+		if errors.Is(err, service.ErrProjectArchived) {
+			ctx.JSON(http.StatusForbidden, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrIdempotencyInProgress) {
+			ctx.JSON(http.StatusConflict, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrIdempotencyKeyReused) {
+			ctx.JSON(http.StatusUnprocessableEntity, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrFileExists) || errors.Is(err, service.ErrFolderExists) {
+			ctx.JSON(http.StatusConflict, common.ErrorResponse("上传文件失败: "+err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrUploadPolicyViolation) || errors.Is(err, service.ErrInvalidContentHash) || errors.Is(err, service.ErrContentHashMismatch) || errors.Is(err, service.ErrInvalidPath) || errors.Is(err, service.ErrInvalidIdempotencyKey) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse("上传文件失败: "+err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrProjectQuotaExceeded) || errors.Is(err, service.ErrGroupQuotaExceeded) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, common.ErrorResponse("上传文件失败: "+err.Error()))
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("上传文件失败: "+err.Error()))
 		return
 	}
@@ -95,21 +149,20 @@ func (c *FileController) Upload(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, common.SuccessResponse(response))
 }
 
-// Download 下载文件
-// @Summary 下载文件
-// @Description 下载指定ID的文件
+// GetUploadConfig 获取项目上传配置
+// @Summary 获取项目上传配置
+// @Description 获取项目生效的上传策略，以及项目、群组两级存储配额的已用量与剩余空间，供客户端上传前检查
 // @Tags 文件管理
-// @Produce octet-stream
+// @Produce json
 // @Param Authorization header string true "Bearer {{token}}"
-// @Param id path int true "文件ID"
-// @Success 200 {file} octet-stream "文件内容"
+// @Param project_id query string true "项目ID"
+// @Success 200 {object} common.Response{data=dto.UploadConfigResponse} "成功"
 // @Failure 400 {object} common.Response "请求参数错误"
 // @Failure 401 {object} common.Response "未授权"
 // @Failure 403 {object} common.Response "权限不足"
-// @Failure 404 {object} common.Response "文件不存在"
 // @Failure 500 {object} common.Response "内部服务器错误"
-// @Router /api/oss/file/download/{id} [get]
-func (c *FileController) Download(ctx *gin.Context) {
+// @Router /api/oss/file/upload-config [get]
+func (c *FileController) GetUploadConfig(ctx *gin.Context) {
 	// 获取当前用户ID
 	userIDValue, exists := ctx.Get("userID")
 	if !exists {
@@ -118,71 +171,51 @@ func (c *FileController) Download(ctx *gin.Context) {
 	}
 	userID := userIDValue.(string)
 
-	// 获取文件ID
-	idStr := ctx.Param("id")
-	id := idStr
-
-	// 获取文件信息
-	fileInfo, err := c.fileService.GetFileInfo(ctx, id)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取文件信息失败: "+err.Error()))
-		return
-	}
-	if fileInfo == nil {
-		ctx.JSON(http.StatusNotFound, common.ErrorResponse("文件不存在"))
+	projectID := ctx.Query("project_id")
+	if projectID == "" {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse("缺少项目ID"))
 		return
 	}
 
 	// 检查项目权限 (需要读取权限)
-	projectDomain := fmt.Sprintf("project:%s", fileInfo.ProjectID)
+	projectDomain := fmt.Sprintf("project:%s", projectID)
 	canRead, err := c.authService.CanUserAccessResource(ctx, userID, "files", service.ActionRead, projectDomain)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
 		return
 	}
 	if !canRead {
-		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有文件读取权限"))
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有项目读取权限"))
 		return
 	}
 
-	// 下载文件
-	fileReader, file, err := c.fileService.Download(ctx, id, userID)
+	config, err := c.fileService.GetUploadConfig(ctx, projectID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("下载文件失败: "+err.Error()))
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取上传配置失败: "+err.Error()))
 		return
 	}
-	defer fileReader.Close()
-
-	// 设置响应头
-	ctx.Header("Content-Description", "File Transfer")
-	ctx.Header("Content-Transfer-Encoding", "binary")
-	ctx.Header("Content-Disposition", "attachment; filename="+file.FileName)
-	ctx.Header("Content-Type", file.MimeType)
-	ctx.Header("Content-Length", strconv.FormatInt(file.FileSize, 10))
-	ctx.Header("Accept-Ranges", "bytes")
 
-	// 发送文件内容
-	ctx.DataFromReader(http.StatusOK, file.FileSize, file.MimeType, fileReader, nil)
+	ctx.JSON(http.StatusOK, common.SuccessResponse(config))
 }
 
-// ListFiles 获取文件列表
-// @Summary 获取文件列表
-// @Description 获取指定项目和路径下的文件列表
+// UploadFolder 上传文件夹
+// @Summary 上传文件夹
+// @Description 一次上传一组文件并保留目录结构，自动创建缺少的文件夹；逐个文件返回结果，同路径已有相同内容的文件会跳过，中断后可重新提交同一批文件继续；需要上传目录的写入权限，规则同上传文件
 // @Tags 文件管理
+// @Accept multipart/form-data
 // @Produce json
 // @Param Authorization header string true "Bearer {{token}}"
-// @Param project_id query int true "项目ID"
-// @Param path query string false "文件路径，默认为根目录"
-// @Param recursive query bool false "是否递归获取子目录"
-// @Param page query int false "页码，默认1"
-// @Param size query int false "每页大小，默认20"
-// @Success 200 {object} common.Response{data=dto.FileListResponse} "成功"
+// @Param project_id formData string true "项目ID"
+// @Param path formData string false "上传到的目录，默认为根目录"
+// @Param files formData file true "上传的文件，可重复"
+// @Param paths formData string true "文件的相对路径（如 docs/a.txt），与 files 数量和顺序一致，可重复"
+// @Success 200 {object} common.Response{data=dto.FolderUploadResponse} "成功"
 // @Failure 400 {object} common.Response "请求参数错误"
 // @Failure 401 {object} common.Response "未授权"
 // @Failure 403 {object} common.Response "权限不足"
 // @Failure 500 {object} common.Response "内部服务器错误"
-// @Router /api/oss/file/list [get]
-func (c *FileController) ListFiles(ctx *gin.Context) {
+// @Router /api/oss/file/upload-folder [post]
+func (c *FileController) UploadFolder(ctx *gin.Context) {
 	// 获取当前用户ID
 	userIDValue, exists := ctx.Get("userID")
 	if !exists {
@@ -192,132 +225,72 @@ func (c *FileController) ListFiles(ctx *gin.Context) {
 	userID := userIDValue.(string)
 
 	// 绑定请求参数
-	var req dto.FileListRequest
-	if err := ctx.ShouldBindQuery(&req); err != nil {
+	var req dto.FolderUploadRequest
+	if err := ctx.ShouldBind(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
 		return
 	}
 
-	// 检查项目权限 (需要读取权限)
-	projectDomain := fmt.Sprintf("project:%s", req.ProjectID)
-	canRead, err := c.authService.CanUserAccessResource(ctx, userID, "files", service.ActionRead, projectDomain)
+	form, err := ctx.MultipartForm()
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
-		return
-	}
-	if !canRead {
-		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有项目读取权限"))
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse("获取上传文件失败: "+err.Error()))
 		return
 	}
 
-	// 获取文件列表
-	files, total, err := c.fileService.ListFiles(ctx, req.ProjectID, req.Path, req.Recursive, req.Page, req.Size)
+	// 检查目标目录的写入权限，目录或其上级文件夹上的授权优先于项目角色
+	canWrite, err := c.fileService.CheckPathPermission(ctx, req.ProjectID, req.Path, userID, service.ActionCreate)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取文件列表失败: "+err.Error()))
-		return
-	}
-
-	// 构建响应
-	response := dto.FileListResponse{
-		Total: total,
-		Items: make([]dto.FileResponse, 0, len(files)),
-	}
-
-	for _, file := range files {
-		fileResponse := buildFileResponse(file)
-
-		// 如果不是文件夹且预览URL为空，则尝试获取
-		if !file.IsFolder && fileResponse.PreviewURL == "" {
-			publicURL, _ := c.fileService.GetPublicDownloadURL(ctx, file.ID)
-			if publicURL != "" {
-				fileResponse.PreviewURL = publicURL
-			}
+		if errors.Is(err, service.ErrInvalidPath) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
 		}
-
-		response.Items = append(response.Items, fileResponse)
-	}
-
-	ctx.JSON(http.StatusOK, common.SuccessResponse(response))
-}
-
-// CreateFolder 创建文件夹
-// @Summary 创建文件夹
-// @Description 在指定项目和路径下创建文件夹
-// @Tags 文件管理
-// @Accept json
-// @Produce json
-// @Param Authorization header string true "Bearer {{token}}"
-// @Param request body dto.FileFolderCreateRequest true "创建文件夹请求"
-// @Success 200 {object} common.Response{data=dto.FileResponse} "成功"
-// @Failure 400 {object} common.Response "请求参数错误"
-// @Failure 401 {object} common.Response "未授权"
-// @Failure 403 {object} common.Response "权限不足"
-// @Failure 500 {object} common.Response "内部服务器错误"
-// @Router /api/oss/file/folder [post]
-func (c *FileController) CreateFolder(ctx *gin.Context) {
-	// 获取当前用户ID
-	userIDValue, exists := ctx.Get("userID")
-	if !exists {
-		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
-		return
-	}
-	userID := userIDValue.(string)
-
-	// 绑定请求参数
-	var req dto.FileFolderCreateRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
-		return
-	}
-
-	// 检查项目权限 (需要写入权限)
-	projectDomain := fmt.Sprintf("project:%s", req.ProjectID)
-	canWrite, err := c.authService.CanUserAccessResource(ctx, userID, "files", service.ActionCreate, projectDomain)
-	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
 		return
 	}
 	if !canWrite {
-		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有创建文件夹的权限"))
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有该目录的写入权限"))
 		return
 	}
 
-	// 创建文件夹
-	folder, err := c.fileService.CreateFolder(ctx, req.ProjectID, userID, req.Path, req.FolderName)
+	response, err := c.fileService.UploadFolder(ctx, req.ProjectID, userID, req.Path, form.File["files"], form.Value["paths"])
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("创建文件夹失败: "+err.Error()))
+		if errors.Is(err, service.ErrProjectArchived) {
+			ctx.JSON(http.StatusForbidden, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrInvalidFolderUpload) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("上传文件夹失败: "+err.Error()))
 		return
 	}
 
-	// 构建响应
-	response := buildFileResponse(folder)
-
 	ctx.JSON(http.StatusOK, common.SuccessResponse(response))
 }
 
-// DeleteFile 删除文件
-// @Summary 删除文件
-// @Description 删除指定ID的文件或文件夹(软删除)
+// Download 下载文件
+// @Summary 下载文件
+// @Description 下载指定ID的文件
 // @Tags 文件管理
-// @Accept json
-// @Produce json
-// @Param Authorization header string true "Bearer {{token}}"
+// @Produce octet-stream
+// @Param Authorization header string false "Bearer {{token}}，项目开启匿名读取时可不传"
 // @Param id path int true "文件ID"
-// @Success 200 {object} common.Response "成功"
+// @Success 200 {file} octet-stream "文件内容"
 // @Failure 400 {object} common.Response "请求参数错误"
 // @Failure 401 {object} common.Response "未授权"
 // @Failure 403 {object} common.Response "权限不足"
 // @Failure 404 {object} common.Response "文件不存在"
 // @Failure 500 {object} common.Response "内部服务器错误"
-// @Router /api/oss/file/delete/{id} [get]
-func (c *FileController) DeleteFile(ctx *gin.Context) {
-	// 获取当前用户ID
-	userIDValue, exists := ctx.Get("userID")
-	if !exists {
+// @Router /api/oss/file/download/{id} [get]
+func (c *FileController) Download(ctx *gin.Context) {
+	// 获取当前用户ID，项目开启匿名读取时可为空
+	userID := ctx.GetString("userID")
+	publicRead := middleware.IsPublicRead(ctx)
+	if userID == "" && !publicRead {
 		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
 		return
 	}
-	userID := userIDValue.(string)
 
 	// 获取文件ID
 	idStr := ctx.Param("id")
@@ -334,284 +307,2347 @@ func (c *FileController) DeleteFile(ctx *gin.Context) {
 		return
 	}
 
-	// 检查项目权限 (需要删除权限)
-	projectDomain := fmt.Sprintf("project:%s", fileInfo.ProjectID)
-	canWrite, err := c.authService.CanUserAccessResource(ctx, userID, "files", service.ActionDelete, projectDomain)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
+	// 检查项目权限 (需要读取权限)
+	canRead := publicRead
+	if !canRead {
+		allowed, err := c.fileService.CheckFilePermission(ctx, fileInfo.ID, userID, service.ActionRead)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
+			return
+		}
+		canRead = allowed
+	}
+	if !canRead {
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有文件读取权限"))
 		return
 	}
 
-	if !canWrite {
-		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有删除文件的权限"))
+	// 下载文件
+	fileReader, file, err := c.fileService.Download(ctx, id, userID)
+	if err != nil {
+		if errors.Is(err, service.ErrWatermarkUnsupported) {
+			ctx.JSON(http.StatusUnsupportedMediaType, common.ErrorResponse("下载文件失败: "+err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("下载文件失败: "+err.Error()))
 		return
 	}
+	defer fileReader.Close()
 
-	// 删除文件
-	err = c.fileService.DeleteFile(ctx, id, userID)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("删除文件失败: "+err.Error()))
+	// 设置响应头
+	contentType := setDownloadHeaders(ctx, file.FileName, file.MimeType)
+
+	// 未添加水印的对象可随机读取，按 Range 返回部分内容，用于断点续传和按下载清单分块并行下载
+	if seeker, ok := fileReader.(io.ReadSeeker); ok && !file.WatermarkRequired {
+		if file.FileHash != "" {
+			ctx.Header("ETag", `"`+file.FileHash+`"`)
+		}
+		http.ServeContent(ctx.Writer, ctx.Request, "", file.UpdatedAt, seeker)
 		return
 	}
 
-	ctx.JSON(http.StatusOK, common.SuccessResponse(nil))
+	ctx.Header("Content-Length", strconv.FormatInt(file.FileSize, 10))
+	ctx.Header("Accept-Ranges", "none")
+
+	// 发送文件内容
+	ctx.DataFromReader(http.StatusOK, file.FileSize, contentType, fileReader, nil)
 }
 
-// GetFileVersions 获取文件版本列表
-// @Summary 获取文件版本列表
-// @Description 获取指定文件的所有版本信息
+// GetDownloadManifest 获取分块下载清单
+// @Summary 获取分块下载清单
+// @Description 返回文件大小、建议的分块大小和各分块的SHA-256，客户端可按 Range 并行下载各分块、分别校验并只重试失败的分块。需要水印的文件和文件夹不支持
 // @Tags 文件管理
 // @Produce json
-// @Param Authorization header string true "Bearer {{token}}"
-// @Param id path int true "文件ID"
-// @Success 200 {object} common.Response{data=dto.FileVersionListResponse} "成功"
-// @Failure 400 {object} common.Response "请求参数错误"
+// @Param Authorization header string false "Bearer {{token}}，项目开启匿名读取时可不传"
+// @Param id path string true "文件ID"
+// @Success 200 {object} common.Response{data=dto.DownloadManifestResponse} "成功"
 // @Failure 401 {object} common.Response "未授权"
 // @Failure 403 {object} common.Response "权限不足"
 // @Failure 404 {object} common.Response "文件不存在"
+// @Failure 409 {object} common.Response "文件不支持分块校验下载"
 // @Failure 500 {object} common.Response "内部服务器错误"
-// @Router /api/oss/file/versions/{id} [get]
-func (c *FileController) GetFileVersions(ctx *gin.Context) {
-	// 获取当前用户ID
-	userIDValue, exists := ctx.Get("userID")
-	if !exists {
+// @Router /api/oss/file/{id}/download-manifest [get]
+func (c *FileController) GetDownloadManifest(ctx *gin.Context) {
+	// 获取当前用户ID，项目开启匿名读取时可为空
+	userID := ctx.GetString("userID")
+	publicRead := middleware.IsPublicRead(ctx)
+	if userID == "" && !publicRead {
 		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
 		return
 	}
-	userID := userIDValue.(string)
-
-	// 获取文件ID
-	idStr := ctx.Param("id")
-	id := idStr
 
 	// 获取文件信息
-	fileInfo, err := c.fileService.GetFileInfo(ctx, id)
+	fileInfo, err := c.fileService.GetFileInfo(ctx, ctx.Param("id"))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取文件信息失败: "+err.Error()))
 		return
 	}
-	if fileInfo == nil {
+	if fileInfo == nil || fileInfo.IsDeleted {
 		ctx.JSON(http.StatusNotFound, common.ErrorResponse("文件不存在"))
 		return
 	}
 
 	// 检查项目权限 (需要读取权限)
-	projectDomain := fmt.Sprintf("project:%s", fileInfo.ProjectID)
-	canRead, err := c.authService.CanUserAccessResource(ctx, userID, "files", service.ActionRead, projectDomain)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
-		return
+	canRead := publicRead
+	if !canRead {
+		allowed, err := c.fileService.CheckFilePermission(ctx, fileInfo.ID, userID, service.ActionRead)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
+			return
+		}
+		canRead = allowed
 	}
 	if !canRead {
-		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有查看文件版本的权限"))
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有文件读取权限"))
 		return
 	}
 
-	// 获取文件版本列表
-	versions, err := c.fileService.GetFileVersions(ctx, id)
+	manifest, err := c.fileService.GetDownloadManifest(ctx, fileInfo.ID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取文件版本失败: "+err.Error()))
-		return
-	}
-
-	// 构建响应
-	response := dto.FileVersionListResponse{
-		FileID: id,
-		Total:  len(versions),
-		Items:  make([]dto.FileVersionResponse, 0, len(versions)),
-	}
-
-	for _, version := range versions {
-		item := dto.FileVersionResponse{
-			ID:           version.ID,
-			FileID:       version.FileID,
-			Version:      version.Version,
-			FileHash:     version.FileHash,
-			FileSize:     version.FileSize,
-			UploaderID:   version.UploaderID,
-			UploaderName: version.Uploader.Name,
-			CreatedAt:    version.CreatedAt,
-			Comment:      version.Comment,
+		if errors.Is(err, service.ErrManifestUnsupported) {
+			ctx.JSON(http.StatusConflict, common.ErrorResponse(err.Error()))
+			return
 		}
-		response.Items = append(response.Items, item)
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取下载清单失败: "+err.Error()))
+		return
 	}
 
-	ctx.JSON(http.StatusOK, common.SuccessResponse(response))
+	ctx.JSON(http.StatusOK, common.SuccessResponse(manifest))
 }
 
-// CreateShare 创建文件分享
-// @Summary 创建文件分享
-// @Description 创建文件分享链接
-// @Tags 文件分享
-// @Accept json
+// GetFileDetail 获取文件详情
+// @Summary 获取文件详情
+// @Description 获取文件或文件夹的详细信息，图片、PDF和文本文件返回有时效的预览URL（preview_url）
+// @Tags 文件管理
 // @Produce json
-// @Param Authorization header string true "Bearer {{token}}"
-// @Param request body dto.FileShareCreateRequest true "创建分享请求"
-// @Success 200 {object} common.Response{data=dto.FileShareResponse} "成功"
-// @Failure 400 {object} common.Response "请求参数错误"
+// @Param Authorization header string false "Bearer {{token}}，项目开启匿名读取时可不传"
+// @Param id path string true "文件ID"
+// @Success 200 {object} common.Response{data=dto.FileResponse} "成功"
 // @Failure 401 {object} common.Response "未授权"
 // @Failure 403 {object} common.Response "权限不足"
 // @Failure 404 {object} common.Response "文件不存在"
 // @Failure 500 {object} common.Response "内部服务器错误"
-// @Router /api/oss/file/share [post]
-func (c *FileController) CreateShare(ctx *gin.Context) {
-	// 获取当前用户ID
-	userIDValue, exists := ctx.Get("userID")
-	if !exists {
+// @Router /api/oss/file/detail/{id} [get]
+func (c *FileController) GetFileDetail(ctx *gin.Context) {
+	// 获取当前用户ID，项目开启匿名读取时可为空
+	userID := ctx.GetString("userID")
+	publicRead := middleware.IsPublicRead(ctx)
+	if userID == "" && !publicRead {
 		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
 		return
 	}
-	userID := userIDValue.(string)
 
-	// 绑定请求参数
-	var req dto.FileShareCreateRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
+	// 获取文件信息
+	fileInfo, err := c.fileService.GetFileInfo(ctx, ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取文件信息失败: "+err.Error()))
+		return
+	}
+	if fileInfo == nil || fileInfo.IsDeleted {
+		ctx.JSON(http.StatusNotFound, common.ErrorResponse("文件不存在"))
+		return
+	}
+
+	// 检查文件权限 (需要读取权限)
+	canRead := publicRead
+	if !canRead {
+		allowed, err := c.fileService.CheckFilePermission(ctx, fileInfo.ID, userID, service.ActionRead)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
+			return
+		}
+		canRead = allowed
+	}
+	if !canRead {
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有文件读取权限"))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(c.buildFileResponseWithPreview(ctx, fileInfo)))
+}
+
+// GetThumbnail 获取图片缩略图
+// @Summary 获取图片缩略图
+// @Description 返回图片文件当前版本的JPEG缩略图，上传后在后台生成，尚未生成、不是图片或需要水印的文件返回404
+// @Tags 文件管理
+// @Produce jpeg
+// @Param Authorization header string false "Bearer {{token}}，项目开启匿名读取时可不传"
+// @Param id path string true "文件ID"
+// @Success 200 {file} binary "缩略图"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "文件或缩略图不存在"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/file/{id}/thumbnail [get]
+func (c *FileController) GetThumbnail(ctx *gin.Context) {
+	// 获取当前用户ID，项目开启匿名读取时可为空
+	userID := ctx.GetString("userID")
+	publicRead := middleware.IsPublicRead(ctx)
+	if userID == "" && !publicRead {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	// 匿名读取时不再检查用户权限
+	if publicRead {
+		userID = ""
+	}
+
+	fileInfo, err := c.fileService.GetFileInfo(ctx, ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取文件信息失败: "+err.Error()))
+		return
+	}
+	if fileInfo == nil || fileInfo.IsDeleted {
+		ctx.JSON(http.StatusNotFound, common.ErrorResponse("文件不存在"))
+		return
+	}
+
+	reader, size, err := c.fileService.GetThumbnail(ctx, fileInfo.ID, userID)
+	if err != nil {
+		if errors.Is(err, service.ErrThumbnailForbidden) {
+			ctx.JSON(http.StatusForbidden, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrThumbnailUnavailable) {
+			ctx.JSON(http.StatusNotFound, common.ErrorResponse(err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取缩略图失败: "+err.Error()))
+		return
+	}
+	defer reader.Close()
+
+	ctx.Header("Cache-Control", "private, max-age=3600")
+	ctx.DataFromReader(http.StatusOK, size, thumbnail.ContentType, reader, nil)
+}
+
+// DownloadFolder 打包下载文件夹
+// @Summary 打包下载文件夹
+// @Description 将文件夹及其子目录以zip格式流式下载，文件数或总大小超出配置上限时返回413
+// @Tags 文件管理
+// @Produce application/zip
+// @Param Authorization header string false "Bearer {{token}}，项目开启匿名读取时可不传"
+// @Param id path string true "文件夹ID"
+// @Success 200 {file} binary "zip文件流"
+// @Failure 400 {object} common.Response "目标不是文件夹"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "文件夹不存在"
+// @Failure 413 {object} common.Response "超出打包下载限制"
+// @Failure 415 {object} common.Response "包含不支持水印的文件"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/file/download-folder/{id} [get]
+func (c *FileController) DownloadFolder(ctx *gin.Context) {
+	// 获取当前用户ID，项目开启匿名读取时可为空
+	userID := ctx.GetString("userID")
+	publicRead := middleware.IsPublicRead(ctx)
+	if userID == "" && !publicRead {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+
+	id := ctx.Param("id")
+
+	// 获取文件夹信息
+	folderInfo, err := c.fileService.GetFileInfo(ctx, id)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取文件夹信息失败: "+err.Error()))
+		return
+	}
+	if folderInfo == nil {
+		ctx.JSON(http.StatusNotFound, common.ErrorResponse("文件夹不存在"))
+		return
+	}
+	if !folderInfo.IsFolder {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse("目标不是文件夹"))
+		return
+	}
+
+	// 检查项目权限 (需要读取权限)
+	canRead := publicRead
+	if !canRead {
+		allowed, err := c.fileService.CheckFilePermission(ctx, folderInfo.ID, userID, service.ActionRead)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
+			return
+		}
+		canRead = allowed
+	}
+	if !canRead {
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有文件读取权限"))
+		return
+	}
+
+	// 写出响应前完成校验，超出限制时仍可返回明确的错误
+	archive, err := c.fileService.PrepareFolderArchive(ctx, id, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrArchiveLimitExceeded):
+			ctx.JSON(http.StatusRequestEntityTooLarge, common.ErrorResponse("打包下载失败: "+err.Error()))
+		case errors.Is(err, service.ErrWatermarkUnsupported):
+			ctx.JSON(http.StatusUnsupportedMediaType, common.ErrorResponse("打包下载失败: "+err.Error()))
+		default:
+			ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("打包下载失败: "+err.Error()))
+		}
+		return
+	}
+
+	// 流式输出，不设置Content-Length
+	setDownloadHeaders(ctx, archive.Folder.FileName+".zip", "application/zip")
+	ctx.Status(http.StatusOK)
+
+	// 客户端断开时请求上下文被取消，剩余的对象获取随之停止；此时响应头已发出，只能记录日志
+	if err := c.fileService.WriteFolderArchive(ctx.Request.Context(), archive, ctx.Writer); err != nil {
+		log.Printf("打包下载文件夹 %s 中断: %v", id, err)
+	}
+}
+
+// FindDuplicates 查找项目内的重复文件
+// @Summary 查找重复文件
+// @Description 按内容哈希分组列出项目内内容相同的文件，按可回收空间倒序，最多返回200组
+// @Tags 文件管理
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "项目ID"
+// @Success 200 {object} common.Response{data=dto.DuplicatesResponse} "成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/project/{id}/duplicates [get]
+func (c *FileController) FindDuplicates(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	projectID := ctx.Param("id")
+
+	// 检查项目权限 (需要读取权限)
+	projectDomain := fmt.Sprintf("project:%s", projectID)
+	canRead, err := c.authService.CanUserAccessResource(ctx, userID, "files", service.ActionRead, projectDomain)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
+		return
+	}
+	if !canRead {
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有文件读取权限"))
+		return
+	}
+
+	result, err := c.fileService.FindDuplicates(ctx, projectID, userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("查找重复文件失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(result))
+}
+
+// ResolveDuplicates 保留一份并删除其余重复文件
+// @Summary 删除重复文件
+// @Description 保留指定文件，删除（移入回收站）同项目内与其内容相同的其他文件
+// @Tags 文件管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "项目ID"
+// @Param request body dto.ResolveDuplicatesRequest true "保留的文件"
+// @Success 200 {object} common.Response{data=dto.ResolveDuplicatesResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/project/{id}/duplicates/resolve [post]
+func (c *FileController) ResolveDuplicates(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	projectID := ctx.Param("id")
+
+	var req dto.ResolveDuplicatesRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	// 检查项目权限 (需要删除权限)
+	projectDomain := fmt.Sprintf("project:%s", projectID)
+	canDelete, err := c.authService.CanUserAccessResource(ctx, userID, "files", service.ActionDelete, projectDomain)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
+		return
+	}
+	if !canDelete {
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有文件删除权限"))
+		return
+	}
+
+	result, err := c.fileService.ResolveDuplicates(ctx, projectID, req.KeepFileID, userID)
+	if err != nil {
+		if errors.Is(err, service.ErrProjectArchived) {
+			ctx.JSON(http.StatusForbidden, common.ErrorResponse(err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("删除重复文件失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(result))
+}
+
+// ListFiles 获取文件列表
+// @Summary 获取文件列表
+// @Description 获取指定项目和路径下的文件列表，需要该目录的读取权限：目录或其上级文件夹上有授权时按授权的角色检查，否则按项目角色检查
+// @Tags 文件管理
+// @Produce json
+// @Param Authorization header string false "Bearer {{token}}，项目开启匿名读取时可不传"
+// @Param project_id query int true "项目ID"
+// @Param path query string false "文件路径，默认为根目录"
+// @Param recursive query bool false "是否递归获取子目录"
+// @Param tag query string false "标签，指定时列出项目内所有带该标签的文件，忽略路径"
+// @Param category query string false "文件分类，只列出该分类的文件" Enums(image, video, audio, document, archive, other)
+// @Param page query int false "页码，默认1"
+// @Param size query int false "每页大小，默认20"
+// @Success 200 {object} common.Response{data=dto.PageResult[dto.FileResponse]} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/file/list [get]
+func (c *FileController) ListFiles(ctx *gin.Context) {
+	// 获取当前用户ID，项目开启匿名读取时可为空
+	userID := ctx.GetString("userID")
+	publicRead := middleware.IsPublicRead(ctx)
+	if userID == "" && !publicRead {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+
+	// 绑定请求参数
+	var req dto.FileListRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	// 检查读取权限，按目录列出时目录或其上级文件夹上的授权优先于项目角色；按标签列出时检查项目权限
+	canRead := publicRead
+	if !canRead {
+		var allowed bool
+		var err error
+		if req.Tag == "" {
+			allowed, err = c.fileService.CheckPathPermission(ctx, req.ProjectID, req.Path, userID, service.ActionRead)
+		} else {
+			allowed, err = c.authService.CanUserAccessResource(ctx, userID, "files", service.ActionRead, fmt.Sprintf("project:%s", req.ProjectID))
+		}
+		if errors.Is(err, service.ErrInvalidPath) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
+			return
+		}
+		canRead = allowed
+	}
+	if !canRead {
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有读取权限"))
+		return
+	}
+
+	// 获取文件列表
+	files, total, err := c.fileService.ListFiles(ctx, req.ProjectID, userID, req.Path, req.Tag, req.Category, req.Recursive, req.Page, req.Size)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidTag) || errors.Is(err, service.ErrInvalidPath) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取文件列表失败: "+err.Error()))
+		return
+	}
+
+	// 构建响应
+	items := make([]dto.FileResponse, 0, len(files))
+	for _, file := range files {
+		items = append(items, c.buildFileResponseWithPreview(ctx, file))
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(dto.NewPageResult(items, total, dto.PageQuery{Page: req.Page, Size: req.Size})))
+}
+
+// SearchFiles 搜索文件
+// @Summary 搜索文件
+// @Description 在项目内按文件名关键字以及扩展名、内容类型、大小、上传者、修改时间、标签搜索文件，默认不包含已删除的文件
+// @Tags 文件管理
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param project_id query string true "项目ID"
+// @Param q query string false "文件名包含的关键字"
+// @Param extension query string false "扩展名，如 .pdf"
+// @Param mime_prefix query string false "内容类型前缀，如 image/"
+// @Param min_size query int false "最小文件大小（字节）"
+// @Param max_size query int false "最大文件大小（字节）"
+// @Param uploader_id query string false "上传者ID"
+// @Param updated_from query string false "修改时间起点（RFC3339）"
+// @Param updated_to query string false "修改时间终点（RFC3339）"
+// @Param include_deleted query bool false "是否包含已删除的文件"
+// @Param tag query string false "带有该标签"
+// @Param category query string false "文件分类，按内容类型划分" Enums(image, video, audio, document, archive, other)
+// @Param order_by query string false "排序方式：relevance 或 updated_at，有关键字时默认 relevance"
+// @Param page query int false "页码，默认1"
+// @Param size query int false "每页大小，默认20"
+// @Success 200 {object} common.Response{data=dto.PageResult[dto.FileResponse]} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/file/search [get]
+func (c *FileController) SearchFiles(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	// 绑定请求参数
+	var req dto.FileSearchRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+	if req.Size > 100 {
+		req.Size = 100
+	}
+
+	// 检查项目权限 (需要读取权限)
+	projectDomain := fmt.Sprintf("project:%s", req.ProjectID)
+	canRead, err := c.authService.CanUserAccessResource(ctx, userID, "files", service.ActionRead, projectDomain)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
+		return
+	}
+	if !canRead {
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有项目读取权限"))
+		return
+	}
+
+	files, total, err := c.fileService.SearchFiles(ctx, req.ProjectID, userID, req.Query, &req.FileSearchFilters, req.Page, req.Size)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidSearch) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("搜索文件失败: "+err.Error()))
+		return
+	}
+
+	items := make([]dto.FileResponse, 0, len(files))
+	for _, file := range files {
+		items = append(items, buildFileResponse(file))
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(dto.NewPageResult(items, total, dto.PageQuery{Page: req.Page, Size: req.Size})))
+}
+
+// CreateFolder 创建文件夹
+// @Summary 创建文件夹
+// @Description 在指定项目和路径下创建文件夹
+// @Tags 文件管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param request body dto.FileFolderCreateRequest true "创建文件夹请求"
+// @Success 200 {object} common.Response{data=dto.FileResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 409 {object} common.Response "同名文件夹或文件已存在"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/file/folder [post]
+func (c *FileController) CreateFolder(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	// 绑定请求参数
+	var req dto.FileFolderCreateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	// 检查项目权限 (需要写入权限)
+	projectDomain := fmt.Sprintf("project:%s", req.ProjectID)
+	canWrite, err := c.authService.CanUserAccessResource(ctx, userID, "files", service.ActionCreate, projectDomain)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
+		return
+	}
+	if !canWrite {
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有创建文件夹的权限"))
+		return
+	}
+
+	// 创建文件夹
+	folder, err := c.fileService.CreateFolder(ctx, req.ProjectID, userID, req.Path, req.FolderName)
+	if err != nil {
+		if errors.Is(err, service.ErrProjectArchived) {
+			ctx.JSON(http.StatusForbidden, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrInvalidPath) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse("创建文件夹失败: "+err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrFolderExists) || errors.Is(err, service.ErrFileExists) {
+			ctx.JSON(http.StatusConflict, common.ErrorResponse("创建文件夹失败: "+err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("创建文件夹失败: "+err.Error()))
+		return
+	}
+
+	// 构建响应
+	response := buildFileResponse(folder)
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(response))
+}
+
+// DeleteFile 删除文件
+// @Summary 删除文件
+// @Description 删除指定ID的文件或文件夹(软删除)，删除非空文件夹需指定 recursive=true，连同其下全部内容一起删除
+// @Tags 文件管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path int true "文件ID"
+// @Param recursive query bool false "是否连同文件夹中的内容一起删除，默认false"
+// @Success 200 {object} common.Response "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "文件不存在"
+// @Failure 409 {object} common.Response "文件夹不为空"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/file/delete/{id} [get]
+func (c *FileController) DeleteFile(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	// 获取文件ID
+	idStr := ctx.Param("id")
+	id := idStr
+
+	var query dto.FileDeleteQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	// 获取文件信息
+	fileInfo, err := c.fileService.GetFileInfo(ctx, id)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取文件信息失败: "+err.Error()))
+		return
+	}
+	if fileInfo == nil {
+		ctx.JSON(http.StatusNotFound, common.ErrorResponse("文件不存在"))
+		return
+	}
+
+	// 检查项目权限 (需要删除权限)
+	canWrite, err := c.fileService.CheckFilePermission(ctx, fileInfo.ID, userID, service.ActionDelete)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
+		return
+	}
+
+	if !canWrite {
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有删除文件的权限"))
+		return
+	}
+
+	// 删除文件
+	err = c.fileService.DeleteFile(ctx, id, userID, query.Recursive)
+	if err != nil {
+		if errors.Is(err, service.ErrProjectArchived) {
+			ctx.JSON(http.StatusForbidden, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrFolderNotEmpty) {
+			ctx.JSON(http.StatusConflict, common.ErrorResponse(err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("删除文件失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(nil))
+}
+
+// GetFileVersions 获取文件版本列表
+// @Summary 获取文件版本列表
+// @Description 获取指定文件的所有版本信息
+// @Tags 文件管理
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path int true "文件ID"
+// @Success 200 {object} common.Response{data=dto.FileVersionListResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "文件不存在"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/file/versions/{id} [get]
+func (c *FileController) GetFileVersions(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	// 获取文件ID
+	idStr := ctx.Param("id")
+	id := idStr
+
+	// 获取文件信息
+	fileInfo, err := c.fileService.GetFileInfo(ctx, id)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取文件信息失败: "+err.Error()))
+		return
+	}
+	if fileInfo == nil {
+		ctx.JSON(http.StatusNotFound, common.ErrorResponse("文件不存在"))
+		return
+	}
+
+	// 检查项目权限 (需要读取权限)
+	canRead, err := c.fileService.CheckFilePermission(ctx, fileInfo.ID, userID, service.ActionRead)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
+		return
+	}
+	if !canRead {
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有查看文件版本的权限"))
+		return
+	}
+
+	// 获取文件版本列表
+	versions, err := c.fileService.GetFileVersions(ctx, id)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取文件版本失败: "+err.Error()))
+		return
+	}
+
+	// 构建响应
+	response := dto.FileVersionListResponse{
+		FileID: id,
+		Total:  len(versions),
+		Items:  make([]dto.FileVersionResponse, 0, len(versions)),
+	}
+
+	for _, version := range versions {
+		item := dto.FileVersionResponse{
+			ID:           version.ID,
+			FileID:       version.FileID,
+			Version:      version.Version,
+			FileHash:     version.FileHash,
+			FileSize:     version.FileSize,
+			UploaderID:   version.UploaderID,
+			UploaderName: version.Uploader.Name,
+			CreatedAt:    version.CreatedAt,
+			Comment:      version.Comment,
+		}
+		response.Items = append(response.Items, item)
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(response))
+}
+
+// CreateShare 创建文件分享
+// @Summary 创建文件分享
+// @Description 创建文件分享链接
+// @Tags 文件分享
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param request body dto.FileShareCreateRequest true "创建分享请求"
+// @Success 200 {object} common.Response{data=dto.FileShareResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "文件不存在"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/file/share [post]
+func (c *FileController) CreateShare(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	// 绑定请求参数
+	var req dto.FileShareCreateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	// 获取文件信息
+	fileInfo, err := c.fileService.GetFileInfo(ctx, req.FileID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取文件信息失败: "+err.Error()))
+		return
+	}
+	if fileInfo == nil {
+		ctx.JSON(http.StatusNotFound, common.ErrorResponse("文件不存在"))
+		return
+	}
+
+	// 检查项目权限 (需要读取权限，因为分享的是文件内容)
+	canRead, err := c.fileService.CheckFilePermission(ctx, fileInfo.ID, userID, service.ActionRead)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
+		return
+	}
+	if !canRead {
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有分享该文件的权限"))
+		return
+	}
+
+	// 按需生成强密码
+	password := req.Password
+	generatedPassword := ""
+	if req.AutoPassword {
+		generatedPassword, err = service.GenerateSharePassword()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("创建分享失败: "+err.Error()))
+			return
+		}
+		password = generatedPassword
+	}
+
+	// 创建分享
+	share, err := c.fileService.CreateShare(ctx, req.FileID, userID, password, req.ExpireHours, req.DownloadLimit, req.Watermark)
+	if err != nil {
+		if errors.Is(err, service.ErrWeakSharePassword) || errors.Is(err, service.ErrWatermarkUnsupported) || errors.Is(err, service.ErrSharePolicyViolation) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("创建分享失败: "+err.Error()))
+		return
+	}
+
+	// 构建响应
+	response := dto.FileShareResponse{
+		ID:            share.ID,
+		FileID:        share.FileID,
+		FileName:      share.File.FileName,
+		FileSize:      share.File.FileSize,
+		MimeType:      share.File.MimeType,
+		ShareCode:     share.ShareCode,
+		HasPassword:   share.Password != "",
+		Password:      generatedPassword,
+		ExpireAt:      share.ExpireAt,
+		DownloadLimit: share.DownloadLimit,
+		DownloadCount: share.DownloadCount,
+		Watermark:     share.Watermark,
+		CreatedAt:     share.CreatedAt,
+		CreatorName:   share.User.Name,
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(response))
+}
+
+// RevokeShare 撤销分享
+// @Summary 撤销分享
+// @Description 撤销自己创建的分享，撤销后分享码立即失效
+// @Tags 文件分享
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "分享ID"
+// @Success 200 {object} common.Response "成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "不是分享创建者"
+// @Failure 404 {object} common.Response "分享不存在"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/share/{id} [delete]
+func (c *FileController) RevokeShare(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	if err := c.fileService.RevokeShare(ctx, ctx.Param("id"), userID); err != nil {
+		if errors.Is(err, service.ErrShareNotFound) {
+			ctx.JSON(http.StatusNotFound, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrShareNotOwner) {
+			ctx.JSON(http.StatusForbidden, common.ErrorResponse(err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("撤销分享失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(nil))
+}
+
+// ListMyShares 获取我的分享
+// @Summary 获取我的分享
+// @Description 分页获取当前用户创建的有效分享（未过期且未达到下载次数限制），按创建时间倒序
+// @Tags 文件分享
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param page query int false "页码，默认1"
+// @Param size query int false "每页大小，默认10"
+// @Success 200 {object} common.Response{data=dto.PageResult[dto.FileShareResponse]} "成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/file/shares/mine [get]
+func (c *FileController) ListMyShares(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	var pageQuery dto.PageQuery
+	if err := ctx.ShouldBindQuery(&pageQuery); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+	pageQuery = pageQuery.WithDefaultValues()
+
+	shares, total, err := c.fileService.ListUserShares(ctx, userID, pageQuery.Page, pageQuery.Size)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取分享列表失败: "+err.Error()))
+		return
+	}
+
+	items := make([]dto.FileShareResponse, 0, len(shares))
+	for _, share := range shares {
+		items = append(items, dto.FileShareResponse{
+			ID:            share.ID,
+			FileID:        share.FileID,
+			FileName:      share.File.FileName,
+			FileSize:      share.File.FileSize,
+			MimeType:      share.File.MimeType,
+			ShareCode:     share.ShareCode,
+			HasPassword:   share.Password != "",
+			ExpireAt:      share.ExpireAt,
+			DownloadLimit: share.DownloadLimit,
+			DownloadCount: share.DownloadCount,
+			Watermark:     share.Watermark,
+			CreatedAt:     share.CreatedAt,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(dto.NewPageResult(items, total, pageQuery)))
+}
+
+// GetShareInfo 获取分享信息
+// @Summary 获取分享信息
+// @Description 根据分享码获取分享信息
+// @Tags 文件分享
+// @Produce json
+// @Param code path string true "分享码"
+// @Param If-None-Match header string false "上次响应的ETag"
+// @Param If-Modified-Since header string false "上次响应的Last-Modified"
+// @Success 200 {object} common.Response{data=dto.FileShareResponse} "成功"
+// @Success 304 "分享信息未变化"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 403 {object} common.Response "已达到下载次数限制"
+// @Failure 404 {object} common.Response "分享不存在"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/share/{code} [get]
+func (c *FileController) GetShareInfo(ctx *gin.Context) {
+	// 获取分享码
+	code := ctx.Param("code")
+	if code == "" {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse("分享码不能为空"))
+		return
+	}
+
+	// 获取分享信息
+	share, err := c.fileService.GetShareInfo(ctx, code)
+	if err != nil {
+		if errors.Is(err, service.ErrShareNotFound) {
+			ctx.JSON(http.StatusNotFound, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrShareLimitReached) {
+			ctx.JSON(http.StatusForbidden, common.ErrorResponse("获取分享信息失败: "+err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取分享信息失败: "+err.Error()))
+		return
+	}
+	if share == nil {
+		ctx.JSON(http.StatusNotFound, common.ErrorResponse("分享不存在或已过期"))
+		return
+	}
+
+	// 构建响应
+	response := dto.FileShareResponse{
+		ID:            share.ID,
+		FileID:        share.FileID,
+		FileName:      share.File.FileName,
+		FileSize:      share.File.FileSize,
+		MimeType:      share.File.MimeType,
+		ShareCode:     share.ShareCode,
+		HasPassword:   share.Password != "",
+		ExpireAt:      share.ExpireAt,
+		DownloadLimit: share.DownloadLimit,
+		DownloadCount: share.DownloadCount,
+		Watermark:     share.Watermark,
+		CreatedAt:     share.CreatedAt,
+		CreatorName:   share.User.Name,
+	}
+
+	// 支持条件请求，ETag 覆盖响应中的全部字段，下载次数或文件变化后随之改变
+	lastModified := share.CreatedAt
+	for _, t := range []time.Time{share.UpdatedAt, share.File.UpdatedAt} {
+		if t.After(lastModified) {
+			lastModified = t
+		}
+	}
+	body := common.SuccessResponse(response)
+	etag, err := jsonETag(body)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取分享信息失败: "+err.Error()))
+		return
+	}
+	ctx.Header("ETag", etag)
+	ctx.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	ctx.Header("Cache-Control", "no-cache")
+	if notModified(ctx, etag, lastModified) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, body)
+}
+
+// setDownloadHeaders 设置文件下载的响应头，返回实际使用的内容类型
+// 下载内容来自用户上传，禁止浏览器嗅探内容类型，并以沙箱策略阻止其中的脚本执行
+func setDownloadHeaders(ctx *gin.Context, fileName, contentType string) string {
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	ctx.Header("Content-Description", "File Transfer")
+	ctx.Header("Content-Transfer-Encoding", "binary")
+	ctx.Header("Content-Disposition", "attachment; filename="+fileName)
+	ctx.Header("Content-Type", contentType)
+	ctx.Header("X-Content-Type-Options", "nosniff")
+	ctx.Header("Content-Security-Policy", "default-src 'none'; sandbox")
+	return contentType
+}
+
+// jsonETag 根据JSON序列化结果生成强ETag
+func jsonETag(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// notModified 判断条件请求是否可返回304
+// 有 If-None-Match 时只比较ETag（弱比较），否则比较 If-Modified-Since，精确到秒
+func notModified(ctx *gin.Context, etag string, lastModified time.Time) bool {
+	if ifNoneMatch := ctx.GetHeader("If-None-Match"); ifNoneMatch != "" {
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == etag {
+				return true
+			}
+		}
+		return false
+	}
+	if ifModifiedSince := ctx.GetHeader("If-Modified-Since"); ifModifiedSince != "" {
+		since, err := http.ParseTime(ifModifiedSince)
+		return err == nil && !lastModified.Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+// DownloadSharedFile 下载分享文件
+// @Summary 下载分享文件
+// @Description 下载通过分享链接的文件
+// @Tags 文件分享
+// @Accept json
+// @Produce octet-stream
+// @Param request body dto.FileShareAccessRequest true "访问分享请求"
+// @Success 200 {file} octet-stream "文件内容"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "密码错误"
+// @Failure 403 {object} common.Response "已达到下载次数限制"
+// @Failure 404 {object} common.Response "分享不存在"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/share/download [post]
+func (c *FileController) DownloadSharedFile(ctx *gin.Context) {
+	// 绑定请求参数
+	var req dto.FileShareAccessRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	c.streamSharedFile(ctx, req.ShareCode, req.Password)
+}
+
+// DownloadSharedFileByLink 通过链接下载分享文件
+// @Summary 通过链接下载分享文件
+// @Description 以GET方式下载分享文件，便于在浏览器中直接打开分享链接；有密码的分享通过 password 查询参数传入
+// @Tags 文件分享
+// @Produce octet-stream
+// @Param code path string true "分享码"
+// @Param password query string false "访问密码"
+// @Success 200 {file} octet-stream "文件内容"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "密码错误"
+// @Failure 403 {object} common.Response "已达到下载次数限制"
+// @Failure 404 {object} common.Response "分享不存在"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/share/{code}/download [get]
+func (c *FileController) DownloadSharedFileByLink(ctx *gin.Context) {
+	c.streamSharedFile(ctx, ctx.Param("code"), ctx.Query("password"))
+}
+
+// streamSharedFile 校验分享并输出文件内容
+func (c *FileController) streamSharedFile(ctx *gin.Context, shareCode, password string) {
+	// 下载分享文件
+	fileReader, file, err := c.fileService.DownloadSharedFile(ctx, shareCode, password)
+	if err != nil {
+		if errors.Is(err, service.ErrShareNotFound) {
+			ctx.JSON(http.StatusNotFound, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrSharePasswordIncorrect) {
+			ctx.JSON(http.StatusUnauthorized, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrShareLimitReached) {
+			ctx.JSON(http.StatusForbidden, common.ErrorResponse("下载文件失败: "+err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrWatermarkUnsupported) {
+			ctx.JSON(http.StatusUnsupportedMediaType, common.ErrorResponse("下载文件失败: "+err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("下载文件失败: "+err.Error()))
+		return
+	}
+	defer fileReader.Close()
+
+	// 设置响应头
+	contentType := setDownloadHeaders(ctx, file.FileName, file.MimeType)
+	ctx.Header("Content-Length", strconv.FormatInt(file.FileSize, 10))
+	ctx.Header("Accept-Ranges", "bytes")
+
+	// 发送文件内容
+	ctx.DataFromReader(http.StatusOK, file.FileSize, contentType, fileReader, nil)
+}
+
+// SetWatermark 设置文件下载水印
+// @Summary 设置文件下载水印
+// @Description 设置文件下载时是否强制添加水印，水印在下载时实时生成，不修改存储的原文件。目前仅支持PNG、JPEG图片和PDF文档
+// @Tags 文件管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param request body dto.FileWatermarkRequest true "水印设置"
+// @Success 200 {object} common.Response{data=dto.FileResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "文件不存在"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/file/watermark [post]
+func (c *FileController) SetWatermark(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	// 绑定请求参数
+	var req dto.FileWatermarkRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	// 获取文件信息
+	fileInfo, err := c.fileService.GetFileInfo(ctx, req.FileID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取文件信息失败: "+err.Error()))
+		return
+	}
+	if fileInfo == nil {
+		ctx.JSON(http.StatusNotFound, common.ErrorResponse("文件不存在"))
+		return
+	}
+
+	// 检查项目权限 (需要更新权限)
+	canUpdate, err := c.fileService.CheckFilePermission(ctx, fileInfo.ID, userID, service.ActionUpdate)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
+		return
+	}
+	if !canUpdate {
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有修改该文件的权限"))
+		return
+	}
+
+	// 更新水印设置
+	file, err := c.fileService.SetFileWatermark(ctx, req.FileID, req.Required, req.Text)
+	if err != nil {
+		if errors.Is(err, service.ErrProjectArchived) {
+			ctx.JSON(http.StatusForbidden, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrWatermarkUnsupported) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("设置水印失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(buildFileResponse(file)))
+}
+
+// CopyFile 复制文件或文件夹
+// @Summary 复制文件或文件夹
+// @Description 将文件或文件夹复制到同一项目或其他项目的目标目录下，文件夹连同其下的子文件夹和文件一起复制，新文件只保留初始版本。需要源项目的读取权限和目标项目的上传权限
+// @Tags 文件管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "文件ID"
+// @Param request body dto.FileCopyRequest true "复制目标"
+// @Success 200 {object} common.Response{data=dto.FileResponse} "成功，返回新建的文件或文件夹"
+// @Failure 400 {object} common.Response "目标位置无效"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "文件不存在"
+// @Failure 413 {object} common.Response "存储配额不足"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/file/{id}/copy [post]
+func (c *FileController) CopyFile(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	// 绑定请求参数
+	var req dto.FileCopyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	// 获取文件信息
+	fileID := ctx.Param("id")
+	fileInfo, err := c.fileService.GetFileInfo(ctx, fileID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取文件信息失败: "+err.Error()))
+		return
+	}
+	if fileInfo == nil || fileInfo.IsDeleted {
+		ctx.JSON(http.StatusNotFound, common.ErrorResponse("文件不存在"))
+		return
+	}
+	if req.TargetProjectID == "" {
+		req.TargetProjectID = fileInfo.ProjectID
+	}
+
+	// 检查源项目的读取权限与目标项目的上传权限
+	canRead, err := c.fileService.CheckFilePermission(ctx, fileInfo.ID, userID, service.ActionRead)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
+		return
+	}
+	if !canRead {
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有文件读取权限"))
+		return
+	}
+	canCreate, err := c.authService.CanUserAccessResource(ctx, userID, "files", service.ActionCreate, fmt.Sprintf("project:%s", req.TargetProjectID))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
+		return
+	}
+	if !canCreate {
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有目标项目的上传权限"))
+		return
+	}
+
+	file, err := c.fileService.CopyFile(ctx, fileID, req.TargetProjectID, req.TargetPath, userID)
+	if err != nil {
+		if errors.Is(err, service.ErrProjectArchived) {
+			ctx.JSON(http.StatusForbidden, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrInvalidCopy) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrProjectQuotaExceeded) || errors.Is(err, service.ErrGroupQuotaExceeded) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, common.ErrorResponse("复制文件失败: "+err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("复制文件失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(buildFileResponse(file)))
+}
+
+// BatchMoveFiles 批量移动文件
+// @Summary 批量移动文件
+// @Description 将同一项目中的多个文件移动到目标目录下，名称不变；没有修改权限或与目标目录下文件重名的文件不移动，返回每个文件的结果
+// @Tags 文件管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param request body dto.FileBatchMoveRequest true "移动信息"
+// @Success 200 {object} common.Response{data=dto.FileBatchMoveResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/file/batch/move [post]
+func (c *FileController) BatchMoveFiles(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	// 绑定请求参数
+	var req dto.FileBatchMoveRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	result, err := c.fileService.BatchMoveFiles(ctx, req.FileIDs, req.TargetPath, userID)
+	if err != nil {
+		if errors.Is(err, service.ErrProjectArchived) || errors.Is(err, service.ErrMoveTargetForbidden) {
+			ctx.JSON(http.StatusForbidden, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrInvalidMove) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("移动文件失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(result))
+}
+
+// RenameFile 重命名文件
+// @Summary 重命名文件
+// @Description 在原目录内重命名文件，扩展名变化时重新推断内容类型；新扩展名与文件实际内容不符时在 warning 中提示
+// @Tags 文件管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param request body dto.FileRenameRequest true "重命名信息"
+// @Success 200 {object} common.Response{data=dto.FileRenameResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "文件不存在"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/file/rename [post]
+func (c *FileController) RenameFile(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	// 绑定请求参数
+	var req dto.FileRenameRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	// 获取文件信息
+	fileInfo, err := c.fileService.GetFileInfo(ctx, req.FileID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取文件信息失败: "+err.Error()))
+		return
+	}
+	if fileInfo == nil || fileInfo.IsDeleted {
+		ctx.JSON(http.StatusNotFound, common.ErrorResponse("文件不存在"))
+		return
+	}
+
+	// 检查项目权限 (需要更新权限)
+	canUpdate, err := c.fileService.CheckFilePermission(ctx, fileInfo.ID, userID, service.ActionUpdate)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
+		return
+	}
+	if !canUpdate {
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有修改该文件的权限"))
+		return
+	}
+
+	file, warning, err := c.fileService.RenameFile(ctx, req.FileID, userID, req.NewName)
+	if err != nil {
+		if errors.Is(err, service.ErrProjectArchived) {
+			ctx.JSON(http.StatusForbidden, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrInvalidRename) || errors.Is(err, service.ErrUploadPolicyViolation) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("重命名文件失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(dto.FileRenameResponse{
+		File:    buildFileResponse(file),
+		Warning: warning,
+	}))
+}
+
+// RollbackFile 回滚文件版本
+// @Summary 回滚文件版本
+// @Description 以指定历史版本的内容创建一个新版本，原有版本均保留
+// @Tags 文件管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "文件ID"
+// @Param request body dto.FileRollbackRequest true "目标版本"
+// @Success 200 {object} common.Response{data=dto.FileResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "文件不存在"
+// @Failure 409 {object} common.Response "该版本的文件内容已不可用"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/file/{id}/rollback [post]
+func (c *FileController) RollbackFile(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	// 绑定请求参数
+	var req dto.FileRollbackRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	// 获取文件信息
+	fileID := ctx.Param("id")
+	fileInfo, err := c.fileService.GetFileInfo(ctx, fileID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取文件信息失败: "+err.Error()))
+		return
+	}
+	if fileInfo == nil || fileInfo.IsDeleted {
+		ctx.JSON(http.StatusNotFound, common.ErrorResponse("文件不存在"))
+		return
+	}
+
+	// 检查项目权限 (需要更新权限)
+	canUpdate, err := c.fileService.CheckFilePermission(ctx, fileInfo.ID, userID, service.ActionUpdate)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
+		return
+	}
+	if !canUpdate {
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有修改该文件的权限"))
+		return
+	}
+
+	file, err := c.fileService.RollbackToVersion(ctx, fileID, req.Version, userID)
+	if err != nil {
+		if errors.Is(err, service.ErrProjectArchived) {
+			ctx.JSON(http.StatusForbidden, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrInvalidRollback) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrVersionContentUnavailable) {
+			ctx.JSON(http.StatusConflict, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrProjectQuotaExceeded) || errors.Is(err, service.ErrGroupQuotaExceeded) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, common.ErrorResponse("回滚文件失败: "+err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("回滚文件失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(buildFileResponse(file)))
+}
+
+// DownloadFileVersion 下载文件的历史版本
+// @Summary 下载文件的历史版本
+// @Description 下载文件指定版本的内容，文件要求水印时同样添加水印
+// @Tags 文件管理
+// @Produce octet-stream
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "文件ID"
+// @Param version path int true "版本号"
+// @Success 200 {file} octet-stream "文件内容"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "文件或版本不存在"
+// @Failure 409 {object} common.Response "该版本的文件内容已不可用"
+// @Failure 415 {object} common.Response "文件类型不支持添加水印"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/file/{id}/versions/{version}/download [get]
+func (c *FileController) DownloadFileVersion(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	version, err := strconv.Atoi(ctx.Param("version"))
+	if err != nil || version < 1 {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse("无效的版本号"))
+		return
+	}
+
+	// 获取文件信息
+	fileID := ctx.Param("id")
+	fileInfo, err := c.fileService.GetFileInfo(ctx, fileID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取文件信息失败: "+err.Error()))
+		return
+	}
+	if fileInfo == nil || fileInfo.IsDeleted {
+		ctx.JSON(http.StatusNotFound, common.ErrorResponse("文件不存在"))
+		return
+	}
+
+	// 检查项目权限 (需要读取权限)
+	canRead, err := c.fileService.CheckFilePermission(ctx, fileInfo.ID, userID, service.ActionRead)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
+		return
+	}
+	if !canRead {
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有文件读取权限"))
+		return
+	}
+
+	fileReader, file, err := c.fileService.DownloadFileVersion(ctx, fileID, version, userID)
+	if err != nil {
+		if errors.Is(err, service.ErrVersionNotFound) {
+			ctx.JSON(http.StatusNotFound, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrVersionContentUnavailable) {
+			ctx.JSON(http.StatusConflict, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrWatermarkUnsupported) {
+			ctx.JSON(http.StatusUnsupportedMediaType, common.ErrorResponse("下载文件失败: "+err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("下载文件失败: "+err.Error()))
+		return
+	}
+	defer fileReader.Close()
+
+	// 设置响应头
+	contentType := setDownloadHeaders(ctx, file.FileName, file.MimeType)
+	ctx.Header("Content-Length", strconv.FormatInt(file.FileSize, 10))
+
+	// 发送文件内容
+	ctx.DataFromReader(http.StatusOK, file.FileSize, contentType, fileReader, nil)
+}
+
+// PruneFileVersions 清理文件旧版本
+// @Summary 清理文件旧版本
+// @Description 按项目的最大版本数立即删除文件最旧的版本及其历史版本对象，当前版本始终保留，仅项目管理员可操作
+// @Tags 文件管理
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "文件ID"
+// @Success 200 {object} common.Response{data=dto.VersionPruneResponse} "成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "文件不存在"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/file/{id}/versions/prune [post]
+func (c *FileController) PruneFileVersions(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	// 获取文件信息
+	fileID := ctx.Param("id")
+	fileInfo, err := c.fileService.GetFileInfo(ctx, fileID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取文件信息失败: "+err.Error()))
+		return
+	}
+	if fileInfo == nil || fileInfo.IsDeleted {
+		ctx.JSON(http.StatusNotFound, common.ErrorResponse("文件不存在"))
+		return
+	}
+
+	// 仅项目管理员可操作
+	hasAccess, err := c.projectService.CheckUserProjectAccess(ctx, userID, fileInfo.ProjectID, []string{service.ProjectRoleAdmin})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
+		return
+	}
+	if !hasAccess {
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("只有项目管理员可以清理文件版本"))
+		return
+	}
+
+	result, err := c.fileService.PruneVersions(ctx, fileID)
+	if err != nil {
+		if errors.Is(err, service.ErrProjectArchived) {
+			ctx.JSON(http.StatusForbidden, common.ErrorResponse(err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("清理文件版本失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(result))
+}
+
+// ListFileTags 获取文件标签
+// @Summary 获取文件标签
+// @Description 获取文件的标签，按名称排序
+// @Tags 文件管理
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "文件ID"
+// @Success 200 {object} common.Response{data=dto.FileTagsResponse} "成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "文件不存在"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/file/{id}/tags [get]
+func (c *FileController) ListFileTags(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	// 获取文件信息
+	fileID := ctx.Param("id")
+	fileInfo, err := c.fileService.GetFileInfo(ctx, fileID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取文件信息失败: "+err.Error()))
+		return
+	}
+	if fileInfo == nil || fileInfo.IsDeleted {
+		ctx.JSON(http.StatusNotFound, common.ErrorResponse("文件不存在"))
+		return
+	}
+
+	// 检查项目权限 (需要读取权限)
+	allowed, err := c.fileService.CheckFilePermission(ctx, fileInfo.ID, userID, service.ActionRead)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
+		return
+	}
+	if !allowed {
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有文件读取权限"))
+		return
+	}
+
+	tags, err := c.fileService.ListFileTags(ctx, fileID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(dto.FileTagsResponse{FileID: fileID, Tags: tags}))
+}
+
+// AddFileTag 添加文件标签
+// @Summary 添加文件标签
+// @Description 为文件添加标签，标签名称不区分大小写，同一项目内的文件可复用相同的标签；文件已有该标签时不重复添加
+// @Tags 文件管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "文件ID"
+// @Param request body dto.FileTagRequest true "标签"
+// @Success 200 {object} common.Response{data=dto.FileTagsResponse} "成功"
+// @Failure 400 {object} common.Response "标签无效或超出数量限制"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "文件不存在"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/file/{id}/tags [post]
+func (c *FileController) AddFileTag(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	// 绑定请求参数
+	var req dto.FileTagRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	// 获取文件信息
+	fileID := ctx.Param("id")
+	fileInfo, err := c.fileService.GetFileInfo(ctx, fileID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取文件信息失败: "+err.Error()))
+		return
+	}
+	if fileInfo == nil || fileInfo.IsDeleted {
+		ctx.JSON(http.StatusNotFound, common.ErrorResponse("文件不存在"))
+		return
+	}
+
+	// 检查项目权限 (需要更新权限)
+	allowed, err := c.fileService.CheckFilePermission(ctx, fileInfo.ID, userID, service.ActionUpdate)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
+		return
+	}
+	if !allowed {
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有修改该文件的权限"))
+		return
+	}
+
+	tags, err := c.fileService.AddFileTag(ctx, fileID, userID, req.Name)
+	if err != nil {
+		if errors.Is(err, service.ErrProjectArchived) {
+			ctx.JSON(http.StatusForbidden, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrInvalidTag) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(dto.FileTagsResponse{FileID: fileID, Tags: tags}))
+}
+
+// RemoveFileTag 移除文件标签
+// @Summary 移除文件标签
+// @Description 移除文件的标签，返回文件剩余的标签
+// @Tags 文件管理
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "文件ID"
+// @Param tag path string true "标签名称"
+// @Success 200 {object} common.Response{data=dto.FileTagsResponse} "成功"
+// @Failure 400 {object} common.Response "标签无效"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "文件不存在或没有该标签"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/file/{id}/tags/{tag} [delete]
+func (c *FileController) RemoveFileTag(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	// 获取文件信息
+	fileID := ctx.Param("id")
+	fileInfo, err := c.fileService.GetFileInfo(ctx, fileID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取文件信息失败: "+err.Error()))
+		return
+	}
+	if fileInfo == nil || fileInfo.IsDeleted {
+		ctx.JSON(http.StatusNotFound, common.ErrorResponse("文件不存在"))
+		return
+	}
+
+	// 检查项目权限 (需要更新权限)
+	allowed, err := c.fileService.CheckFilePermission(ctx, fileInfo.ID, userID, service.ActionUpdate)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
+		return
+	}
+	if !allowed {
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有修改该文件的权限"))
+		return
+	}
+
+	tags, err := c.fileService.RemoveFileTag(ctx, fileID, ctx.Param("tag"))
+	if err != nil {
+		if errors.Is(err, service.ErrProjectArchived) {
+			ctx.JSON(http.StatusForbidden, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrInvalidTag) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrTagNotFound) {
+			ctx.JSON(http.StatusNotFound, common.ErrorResponse(err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(dto.FileTagsResponse{FileID: fileID, Tags: tags}))
+}
+
+// getManagedFile 获取文件并确认当前用户可管理其访问拒绝规则：项目管理员或文件上传者
+// 不满足时已写出错误响应，返回 nil
+func (c *FileController) getManagedFile(ctx *gin.Context, userID string) *entity.File {
+	fileInfo, err := c.fileService.GetFileInfo(ctx, ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取文件信息失败: "+err.Error()))
+		return nil
+	}
+	if fileInfo == nil || fileInfo.IsDeleted {
+		ctx.JSON(http.StatusNotFound, common.ErrorResponse("文件不存在"))
+		return nil
+	}
+	if fileInfo.UploaderID == userID {
+		return fileInfo
+	}
+
+	hasAccess, err := c.projectService.CheckUserProjectAccess(ctx, userID, fileInfo.ProjectID, []string{service.ProjectRoleAdmin})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
+		return nil
+	}
+	if !hasAccess {
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("只有项目管理员或文件上传者可以管理访问拒绝"))
+		return nil
+	}
+	return fileInfo
+}
+
+// DenyFileAccess 拒绝用户访问文件
+// @Summary 拒绝用户访问文件
+// @Description 拒绝指定用户访问文件或文件夹，优先于其项目角色授予的权限；被拒绝的用户不能查看、下载或修改该文件（文件夹时包括其下全部内容），列表和搜索中也不再显示。不能拒绝项目管理员（需要项目管理员权限或为文件上传者）
+// @Tags 文件管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "文件ID"
+// @Param request body dto.FileDenyRequest true "被拒绝访问的用户"
+// @Success 200 {object} common.Response "成功"
+// @Failure 400 {object} common.Response "不能拒绝该用户"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "文件不存在"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/file/{id}/deny [post]
+func (c *FileController) DenyFileAccess(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	// 绑定请求参数
+	var req dto.FileDenyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	fileInfo := c.getManagedFile(ctx, userID)
+	if fileInfo == nil {
+		return
+	}
+
+	// 项目管理员可以随时移除拒绝规则，不允许拒绝
+	isAdmin, err := c.projectService.CheckUserProjectAccess(ctx, req.UserID, fileInfo.ProjectID, []string{service.ProjectRoleAdmin})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
+		return
+	}
+	if isAdmin {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse("不能拒绝项目管理员访问"))
+		return
+	}
+
+	if err := c.fileService.DenyFileAccess(ctx, fileInfo.ID, req.UserID, userID); err != nil {
+		if errors.Is(err, service.ErrInvalidFileDeny) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(nil))
+}
+
+// RemoveFileDeny 移除文件的访问拒绝
+// @Summary 移除文件的访问拒绝
+// @Description 移除直接设置在文件上的访问拒绝，用户恢复其项目角色授予的权限（需要项目管理员权限或为文件上传者）
+// @Tags 文件管理
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "文件ID"
+// @Param user_id path string true "被拒绝访问的用户ID"
+// @Success 200 {object} common.Response "成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "文件不存在或该用户没有被拒绝访问"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/file/{id}/deny/{user_id} [delete]
+func (c *FileController) RemoveFileDeny(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	fileInfo := c.getManagedFile(ctx, userID)
+	if fileInfo == nil {
+		return
+	}
+
+	if err := c.fileService.RemoveFileDeny(ctx, fileInfo.ID, ctx.Param("user_id")); err != nil {
+		if errors.Is(err, service.ErrFileDenyNotFound) {
+			ctx.JSON(http.StatusNotFound, common.ErrorResponse(err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(nil))
+}
+
+// ListFileDenies 获取文件的访问拒绝
+// @Summary 获取文件的访问拒绝
+// @Description 获取直接设置在文件上的访问拒绝，不包括上级文件夹上的规则（需要项目管理员权限或为文件上传者）
+// @Tags 文件管理
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "文件ID"
+// @Success 200 {object} common.Response{data=[]dto.FileDenyResponse} "成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "文件不存在"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/file/{id}/denies [get]
+func (c *FileController) ListFileDenies(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	fileInfo := c.getManagedFile(ctx, userID)
+	if fileInfo == nil {
+		return
+	}
+
+	denies, err := c.fileService.ListFileDenies(ctx, fileInfo.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(denies))
+}
+
+// getPermissionFolder 获取文件并确认当前用户是其项目的管理员，文件夹授权只能由项目管理员管理
+// 不满足时已写出错误响应，返回 nil
+func (c *FileController) getPermissionFolder(ctx *gin.Context, userID string) *entity.File {
+	fileInfo, err := c.fileService.GetFileInfo(ctx, ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取文件信息失败: "+err.Error()))
+		return nil
+	}
+	if fileInfo == nil || fileInfo.IsDeleted {
+		ctx.JSON(http.StatusNotFound, common.ErrorResponse("文件不存在"))
+		return nil
+	}
+
+	hasAccess, err := c.projectService.CheckUserProjectAccess(ctx, userID, fileInfo.ProjectID, []string{service.ProjectRoleAdmin})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
+		return nil
+	}
+	if !hasAccess {
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("只有项目管理员可以管理文件夹授权"))
+		return nil
+	}
+	return fileInfo
+}
+
+// GrantFolderPermission 授予用户文件夹权限
+// @Summary 授予用户文件夹权限
+// @Description 授予用户对文件夹及其下全部内容的角色（admin、editor、viewer），已有授权时更新角色。检查权限时使用离文件最近的上级文件夹上的授权代替用户的项目角色，可以高于或低于项目角色；访问拒绝仍然优先。不能对项目管理员授权（需要项目管理员权限）
+// @Tags 文件管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "文件夹ID"
+// @Param request body dto.FolderPermissionRequest true "被授权的用户和角色"
+// @Success 200 {object} common.Response{data=dto.FolderPermissionResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误或不能对该用户授权"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "文件夹不存在"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/file/{id}/permissions [post]
+func (c *FileController) GrantFolderPermission(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	// 绑定请求参数
+	var req dto.FolderPermissionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
 		return
 	}
 
-	// 获取文件信息
-	fileInfo, err := c.fileService.GetFileInfo(ctx, req.FileID)
+	folder := c.getPermissionFolder(ctx, userID)
+	if folder == nil {
+		return
+	}
+
+	permission, err := c.fileService.GrantFolderPermission(ctx, folder.ID, req.UserID, req.Role, userID)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidFolderPermission) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(permission))
+}
+
+// RevokeFolderPermission 移除用户的文件夹权限
+// @Summary 移除用户的文件夹权限
+// @Description 移除直接设置在文件夹上的授权，用户恢复上级文件夹上的授权或项目角色（需要项目管理员权限）
+// @Tags 文件管理
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "文件夹ID"
+// @Param user_id path string true "被授权的用户ID"
+// @Success 200 {object} common.Response "成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "文件夹不存在或该用户没有授权"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/file/{id}/permissions/{user_id} [delete]
+func (c *FileController) RevokeFolderPermission(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	folder := c.getPermissionFolder(ctx, userID)
+	if folder == nil {
+		return
+	}
+
+	if err := c.fileService.RevokeFolderPermission(ctx, folder.ID, ctx.Param("user_id")); err != nil {
+		if errors.Is(err, service.ErrFolderPermissionNotFound) {
+			ctx.JSON(http.StatusNotFound, common.ErrorResponse(err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(nil))
+}
+
+// ListFolderPermissions 获取文件夹授权
+// @Summary 获取文件夹授权
+// @Description 获取直接设置在文件夹上的授权，不包括上级文件夹上的授权（需要项目管理员权限）
+// @Tags 文件管理
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "文件夹ID"
+// @Success 200 {object} common.Response{data=[]dto.FolderPermissionResponse} "成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "文件夹不存在"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/file/{id}/permissions [get]
+func (c *FileController) ListFolderPermissions(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	folder := c.getPermissionFolder(ctx, userID)
+	if folder == nil {
+		return
+	}
+
+	permissions, err := c.fileService.ListFolderPermissions(ctx, folder.ID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取文件信息失败: "+err.Error()))
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(err.Error()))
 		return
 	}
-	if fileInfo == nil {
-		ctx.JSON(http.StatusNotFound, common.ErrorResponse("文件不存在"))
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(permissions))
+}
+
+// ListProjectTags 获取项目标签
+// @Summary 获取项目标签
+// @Description 获取项目内使用中的标签及带有各标签的文件数，便于复用已有标签
+// @Tags 文件管理
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "项目ID"
+// @Success 200 {object} common.Response{data=[]dto.ProjectTagResponse} "成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/project/{id}/tags [get]
+func (c *FileController) ListProjectTags(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
 		return
 	}
+	userID := userIDValue.(string)
 
-	// 检查项目权限 (需要读取权限，因为分享的是文件内容)
-	projectDomain := fmt.Sprintf("project:%s", fileInfo.ProjectID)
+	projectID := ctx.Param("id")
+
+	// 检查项目权限 (需要读取权限)
+	projectDomain := fmt.Sprintf("project:%s", projectID)
 	canRead, err := c.authService.CanUserAccessResource(ctx, userID, "files", service.ActionRead, projectDomain)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
 		return
 	}
 	if !canRead {
-		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有分享该文件的权限"))
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有文件读取权限"))
 		return
 	}
 
-	// 创建分享
-	share, err := c.fileService.CreateShare(ctx, req.FileID, userID, req.Password, req.ExpireHours, req.DownloadLimit)
+	tags, err := c.fileService.ListProjectTags(ctx, projectID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("创建分享失败: "+err.Error()))
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(err.Error()))
 		return
 	}
 
-	// 构建响应
-	response := dto.FileShareResponse{
-		ID:            share.ID,
-		FileID:        share.FileID,
-		FileName:      share.File.FileName,
-		FileSize:      share.File.FileSize,
-		MimeType:      share.File.MimeType,
-		ShareCode:     share.ShareCode,
-		HasPassword:   share.Password != "",
-		ExpireAt:      share.ExpireAt,
-		DownloadLimit: share.DownloadLimit,
-		DownloadCount: share.DownloadCount,
-		CreatedAt:     share.CreatedAt,
-		CreatorName:   share.User.Name,
-	}
-
-	ctx.JSON(http.StatusOK, common.SuccessResponse(response))
+	ctx.JSON(http.StatusOK, common.SuccessResponse(tags))
 }
 
-// GetShareInfo 获取分享信息
-// @Summary 获取分享信息
-// @Description 根据分享码获取分享信息
+// GetProjectShareStats 获取项目分享统计
+// @Summary 获取项目分享统计
+// @Description 获取项目的分享数、有效分享数、通过分享的下载次数、密码与有效期的使用情况及分享最多的文件，仅项目管理员可查看
 // @Tags 文件分享
 // @Produce json
-// @Param code path string true "分享码"
-// @Success 200 {object} common.Response{data=dto.FileShareResponse} "成功"
-// @Failure 400 {object} common.Response "请求参数错误"
-// @Failure 404 {object} common.Response "分享不存在"
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "项目ID"
+// @Success 200 {object} common.Response{data=dto.ProjectShareStatsResponse} "成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
 // @Failure 500 {object} common.Response "内部服务器错误"
-// @Router /api/oss/share/{code} [get]
-func (c *FileController) GetShareInfo(ctx *gin.Context) {
-	// 获取分享码
-	code := ctx.Param("code")
-	if code == "" {
-		ctx.JSON(http.StatusBadRequest, common.ErrorResponse("分享码不能为空"))
+// @Router /api/oss/project/{id}/share-stats [get]
+func (c *FileController) GetProjectShareStats(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
 		return
 	}
+	userID := userIDValue.(string)
 
-	// 获取分享信息
-	share, err := c.fileService.GetShareInfo(ctx, code)
+	projectID := ctx.Param("id")
+
+	// 仅项目管理员可查看
+	hasAccess, err := c.projectService.CheckUserProjectAccess(ctx, userID, projectID, []string{service.ProjectRoleAdmin})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取分享信息失败: "+err.Error()))
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
 		return
 	}
-	if share == nil {
-		ctx.JSON(http.StatusNotFound, common.ErrorResponse("分享不存在或已过期"))
+	if !hasAccess {
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("只有项目管理员可以查看分享统计"))
 		return
 	}
 
-	// 构建响应
-	response := dto.FileShareResponse{
-		ID:            share.ID,
-		FileID:        share.FileID,
-		FileName:      share.File.FileName,
-		FileSize:      share.File.FileSize,
-		MimeType:      share.File.MimeType,
-		ShareCode:     share.ShareCode,
-		HasPassword:   share.Password != "",
-		ExpireAt:      share.ExpireAt,
-		DownloadLimit: share.DownloadLimit,
-		DownloadCount: share.DownloadCount,
-		CreatedAt:     share.CreatedAt,
-		CreatorName:   share.User.Name,
+	stats, err := c.fileService.GetProjectShareStats(ctx, projectID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取分享统计失败: "+err.Error()))
+		return
 	}
 
-	ctx.JSON(http.StatusOK, common.SuccessResponse(response))
+	ctx.JSON(http.StatusOK, common.SuccessResponse(stats))
 }
 
-// DownloadSharedFile 下载分享文件
-// @Summary 下载分享文件
-// @Description 下载通过分享链接的文件
-// @Tags 文件分享
-// @Accept json
-// @Produce octet-stream
-// @Param request body dto.FileShareAccessRequest true "访问分享请求"
-// @Success 200 {file} octet-stream "文件内容"
-// @Failure 400 {object} common.Response "请求参数错误"
-// @Failure 401 {object} common.Response "密码错误"
-// @Failure 404 {object} common.Response "分享不存在"
+// GetFileAuditLogs 获取文件审计日志
+// @Summary 获取文件审计日志
+// @Description 分页获取文件的上传、下载、删除、恢复、分享记录，仅项目管理员可查看
+// @Tags 文件管理
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "文件ID"
+// @Param page query int false "页码，默认1"
+// @Param size query int false "每页大小，默认10"
+// @Success 200 {object} common.Response{data=dto.PageResult[dto.AuditLogResponse]} "成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "文件不存在"
 // @Failure 500 {object} common.Response "内部服务器错误"
-// @Router /api/oss/share/download [post]
-func (c *FileController) DownloadSharedFile(ctx *gin.Context) {
-	// 绑定请求参数
-	var req dto.FileShareAccessRequest
-	if err := ctx.ShouldBindJSON(&req); err != nil {
+// @Router /api/oss/file/{id}/audit [get]
+func (c *FileController) GetFileAuditLogs(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	var pageQuery dto.PageQuery
+	if err := ctx.ShouldBindQuery(&pageQuery); err != nil {
 		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
 		return
 	}
+	pageQuery = pageQuery.WithDefaultValues()
 
-	// 下载分享文件
-	fileReader, file, err := c.fileService.DownloadSharedFile(ctx, req.ShareCode, req.Password)
+	// 获取文件信息
+	fileID := ctx.Param("id")
+	fileInfo, err := c.fileService.GetFileInfo(ctx, fileID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("下载文件失败: "+err.Error()))
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取文件信息失败: "+err.Error()))
+		return
+	}
+	if fileInfo == nil {
+		ctx.JSON(http.StatusNotFound, common.ErrorResponse("文件不存在"))
 		return
 	}
-	defer fileReader.Close()
 
-	// 设置响应头
-	ctx.Header("Content-Description", "File Transfer")
-	ctx.Header("Content-Transfer-Encoding", "binary")
-	ctx.Header("Content-Disposition", "attachment; filename="+file.FileName)
-	ctx.Header("Content-Type", file.MimeType)
-	ctx.Header("Content-Length", strconv.FormatInt(file.FileSize, 10))
-	ctx.Header("Accept-Ranges", "bytes")
+	// 仅项目管理员可查看
+	hasAccess, err := c.projectService.CheckUserProjectAccess(ctx, userID, fileInfo.ProjectID, []string{service.ProjectRoleAdmin})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
+		return
+	}
+	if !hasAccess {
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("只有项目管理员可以查看审计日志"))
+		return
+	}
 
-	// 发送文件内容
-	ctx.DataFromReader(http.StatusOK, file.FileSize, file.MimeType, fileReader, nil)
+	logs, total, err := c.fileService.ListFileAuditLogs(ctx, fileID, pageQuery.Page, pageQuery.Size)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取审计日志失败: "+err.Error()))
+		return
+	}
+
+	items := make([]dto.AuditLogResponse, 0, len(logs))
+	for _, l := range logs {
+		items = append(items, buildAuditLogResponse(l))
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(dto.NewPageResult(items, total, pageQuery)))
 }
 
 // GetPublicURL 获取文件公共访问URL
 // @Summary 获取文件公共访问URL
-// @Description 获取指定ID文件的公共访问URL（有效期7天）
+// @Description 获取指定ID文件的公共访问URL（有效期7天），加密存储或需要水印的文件返回400
 // @Tags 文件管理
 // @Produce json
 // @Param Authorization header string true "Bearer {{token}}"
@@ -642,14 +2678,13 @@ func (c *FileController) GetPublicURL(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取文件信息失败: "+err.Error()))
 		return
 	}
-	if fileInfo == nil {
+	if fileInfo == nil || fileInfo.IsDeleted {
 		ctx.JSON(http.StatusNotFound, common.ErrorResponse("文件不存在"))
 		return
 	}
 
 	// 检查项目权限 (需要读取权限)
-	projectDomain := fmt.Sprintf("project:%s", fileInfo.ProjectID)
-	canRead, err := c.authService.CanUserAccessResource(ctx, userID, "files", service.ActionRead, projectDomain)
+	canRead, err := c.fileService.CheckFilePermission(ctx, fileInfo.ID, userID, service.ActionRead)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
 		return
@@ -662,6 +2697,10 @@ func (c *FileController) GetPublicURL(ctx *gin.Context) {
 	// 获取公共下载URL
 	url, err := c.fileService.GetPublicDownloadURL(ctx, id)
 	if err != nil {
+		if errors.Is(err, service.ErrEncryptedDirectAccess) || errors.Is(err, service.ErrWatermarkDirectAccess) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取公共URL失败: "+err.Error()))
 		return
 	}
@@ -669,26 +2708,179 @@ func (c *FileController) GetPublicURL(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, common.SuccessResponse(url))
 }
 
+// GetPresignedUploadURL 获取预签名上传URL
+// @Summary 获取预签名上传URL
+// @Description 生成MinIO预签名PUT地址，客户端直接上传到临时对象，上传完成后需调用确认接口
+// @Tags 文件管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param request body dto.FilePresignUploadRequest true "预签名上传请求"
+// @Success 200 {object} common.Response{data=dto.FilePresignUploadResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 409 {object} common.Response "同名文件夹已存在"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/file/presign/upload [post]
+func (c *FileController) GetPresignedUploadURL(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	// 绑定请求参数
+	var req dto.FilePresignUploadRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	// 检查项目权限 (需要写入权限)
+	projectDomain := fmt.Sprintf("project:%s", req.ProjectID)
+	canWrite, err := c.authService.CanUserAccessResource(ctx, userID, "files", service.ActionCreate, projectDomain)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
+		return
+	}
+	if !canWrite {
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有项目写入权限"))
+		return
+	}
+
+	// 生成预签名URL
+	uploadURL, objectKey, expiresAt, err := c.fileService.GetPresignedUploadURL(ctx, req.ProjectID, userID, req.FileName, req.Path)
+	if err != nil {
+		if errors.Is(err, service.ErrProjectArchived) {
+			ctx.JSON(http.StatusForbidden, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrUploadPolicyViolation) || errors.Is(err, service.ErrInvalidPath) || errors.Is(err, service.ErrEncryptedDirectAccess) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse("获取预签名上传URL失败: "+err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrFolderExists) {
+			ctx.JSON(http.StatusConflict, common.ErrorResponse("获取预签名上传URL失败: "+err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取预签名上传URL失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(dto.FilePresignUploadResponse{
+		UploadURL: uploadURL,
+		ObjectKey: objectKey,
+		ExpiresAt: expiresAt,
+	}))
+}
+
+// ConfirmPresignedUpload 确认预签名上传
+// @Summary 确认预签名上传
+// @Description 客户端通过预签名URL上传完成后调用，服务端计算哈希并将临时对象保存为文件，创建文件与版本记录；覆盖已有文件时保留上一版本的内容
+// @Tags 文件管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param request body dto.FilePresignConfirmRequest true "确认上传请求"
+// @Success 200 {object} common.Response{data=dto.FileResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 409 {object} common.Response "同名文件已存在"
+// @Failure 413 {object} common.Response "项目或群组存储配额不足"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/file/presign/confirm [post]
+func (c *FileController) ConfirmPresignedUpload(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	// 绑定请求参数
+	var req dto.FilePresignConfirmRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	// 检查项目权限 (需要写入权限)
+	projectDomain := fmt.Sprintf("project:%s", req.ProjectID)
+	canWrite, err := c.authService.CanUserAccessResource(ctx, userID, "files", service.ActionCreate, projectDomain)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("检查权限失败: "+err.Error()))
+		return
+	}
+	if !canWrite {
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse("没有项目写入权限"))
+		return
+	}
+
+	// 确认上传
+	file, err := c.fileService.ConfirmPresignedUpload(ctx, req.ProjectID, userID, req.ObjectKey, req.FileSize, req.FileHash)
+	if err != nil {
+		if errors.Is(err, service.ErrProjectArchived) {
+			ctx.JSON(http.StatusForbidden, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrUploadPolicyViolation) || errors.Is(err, service.ErrPresignHashMismatch) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse("确认上传失败: "+err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrFileExists) {
+			ctx.JSON(http.StatusConflict, common.ErrorResponse("确认上传失败: "+err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrProjectQuotaExceeded) || errors.Is(err, service.ErrGroupQuotaExceeded) {
+			ctx.JSON(http.StatusRequestEntityTooLarge, common.ErrorResponse("确认上传失败: "+err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("确认上传失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(buildFileResponse(file)))
+}
+
+// buildFileResponseWithPreview 构建文件响应对象并生成预览URL，生成失败时只记录日志，不返回预览URL
+func (c *FileController) buildFileResponseWithPreview(ctx *gin.Context, file *entity.File) dto.FileResponse {
+	if _, err := c.fileService.GetPreviewURL(ctx, file); err != nil {
+		log.Printf("生成文件 %s 的预览URL失败: %v", file.ID, err)
+	}
+	return buildFileResponse(file)
+}
+
 // 构建文件响应对象
 func buildFileResponse(file *entity.File) dto.FileResponse {
 	response := dto.FileResponse{
-		ID:             file.ID,
-		ProjectID:      file.ProjectID,
-		FileName:       file.FileName,
-		FilePath:       file.FilePath,
-		FullPath:       file.FullPath,
-		FileSize:       file.FileSize,
-		MimeType:       file.MimeType,
-		Extension:      file.Extension,
-		IsFolder:       file.IsFolder,
-		IsDeleted:      file.IsDeleted,
-		UploaderID:     file.UploaderID,
-		CreatedAt:      file.CreatedAt,
-		UpdatedAt:      file.UpdatedAt,
-		DeletedAt:      file.DeletedAt,
-		DeletedBy:      file.DeletedBy,
-		CurrentVersion: file.CurrentVersion,
-		PreviewURL:     file.PreviewURL,
+		ID:                file.ID,
+		ProjectID:         file.ProjectID,
+		FileName:          file.FileName,
+		FilePath:          file.FilePath,
+		FullPath:          file.FullPath,
+		FileSize:          file.FileSize,
+		MimeType:          file.MimeType,
+		Extension:         file.Extension,
+		IsFolder:          file.IsFolder,
+		IsDeleted:         file.IsDeleted,
+		UploaderID:        file.UploaderID,
+		CreatedAt:         file.CreatedAt,
+		UpdatedAt:         file.UpdatedAt,
+		DeletedAt:         file.DeletedAt,
+		DeletedBy:         file.DeletedBy,
+		CurrentVersion:    file.CurrentVersion,
+		PreviewURL:        file.PreviewURL,
+		WatermarkRequired: file.WatermarkRequired,
+		WatermarkText:     file.WatermarkText,
+	}
+
+	if file.ThumbnailKey != "" && !file.WatermarkRequired {
+		response.ThumbnailURL = fmt.Sprintf("/api/oss/file/%s/thumbnail", file.ID)
 	}
 
 	if file.Uploader.ID != "" {
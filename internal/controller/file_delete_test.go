@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/service"
+)
+
+// testDeleteFileService 文件夹 "docs" 不为空，只有指定 recursive 时才能删除
+type testDeleteFileService struct {
+	service.FileService
+	deleted []string
+}
+
+func (s *testDeleteFileService) GetFileInfo(ctx context.Context, fileID string) (*entity.File, error) {
+	return &entity.File{ID: fileID, IsFolder: true}, nil
+}
+
+func (s *testDeleteFileService) CheckFilePermission(ctx context.Context, fileID, userID, action string) (bool, error) {
+	return true, nil
+}
+
+func (s *testDeleteFileService) DeleteFile(ctx context.Context, fileID, userID string, recursive bool) error {
+	if fileID == "docs" && !recursive {
+		return fmt.Errorf("%w，需要指定 recursive=true 连同其中的内容一起删除", service.ErrFolderNotEmpty)
+	}
+	s.deleted = append(s.deleted, fileID)
+	return nil
+}
+
+// TestDeleteFolderConflict 未指定 recursive 删除非空文件夹返回409，指定 recursive=true 时删除成功
+func TestDeleteFolderConflict(t *testing.T) {
+	fileService := &testDeleteFileService{}
+	controller := NewFileController(fileService, nil, nil)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/oss/file/delete/:id", func(c *gin.Context) { c.Set("userID", "user-1") }, controller.DeleteFile)
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{"未指定 recursive", "", http.StatusConflict},
+		{"recursive=false", "?recursive=false", http.StatusConflict},
+		{"recursive=true", "?recursive=true", http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/oss/file/delete/docs"+tt.query, nil))
+		if w.Code != tt.want {
+			t.Errorf("%s: 返回 %d，应为 %d: %s", tt.name, w.Code, tt.want, w.Body.String())
+		}
+	}
+	if len(fileService.deleted) != 1 || fileService.deleted[0] != "docs" {
+		t.Fatalf("删除了 %v，应只在指定 recursive 时删除 docs", fileService.deleted)
+	}
+}
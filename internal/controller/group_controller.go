@@ -1,6 +1,8 @@
 package controller
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
 
@@ -113,6 +115,48 @@ func (c *GroupController) UpdateGroup(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, common.SuccessResponse(nil))
 }
 
+// DeleteGroup 删除群组
+// @Summary 删除群组
+// @Description 删除群组及其成员关系和权限，仅群组管理员可操作；群组下还有项目或未删除的文件时需指定 force
+// @Tags 群组管理
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "群组ID"
+// @Param force query bool false "一并删除群组下的项目和文件"
+// @Param remove_bucket query bool false "同时删除群组的存储桶"
+// @Success 200 {object} common.Response "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 409 {object} common.Response "群组下还有项目或文件"
+// @Router /api/oss/group/{id} [delete]
+func (c *GroupController) DeleteGroup(ctx *gin.Context) {
+	var req dto.GroupDeleteRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	err := c.groupService.DeleteGroup(ctx, ctx.Param("id"), &req, userID)
+	if err != nil {
+		if errors.Is(err, service.ErrGroupNotEmpty) {
+			ctx.JSON(http.StatusConflict, common.ErrorResponse(err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(nil))
+}
+
 // GetGroupByID 获取群组详情
 // @Summary 获取群组详情
 // @Description 根据ID获取群组详情
@@ -148,6 +192,115 @@ func (c *GroupController) GetGroupByID(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, common.SuccessResponse(group))
 }
 
+// GetGroupQuota 获取群组配额
+// @Summary 获取群组配额
+// @Description 获取群组存储配额、已用量与新建项目的默认配额，群组成员可查看
+// @Tags 群组管理
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "群组ID"
+// @Success 200 {object} common.Response{data=dto.GroupQuotaResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Router /api/oss/group/quota/{id} [get]
+func (c *GroupController) GetGroupQuota(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	quota, err := c.groupService.GetGroupQuota(ctx, ctx.Param("id"), userID)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(quota))
+}
+
+// SetDefaultProjectQuota 设置新建项目的默认配额
+// @Summary 设置新建项目的默认配额
+// @Description 设置群组内新建项目未指定配额时使用的存储配额，不能超过群组配额，已有项目不受影响，仅群组管理员可操作
+// @Tags 群组管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "群组ID"
+// @Param request body dto.GroupQuotaRequest true "默认项目配额"
+// @Success 200 {object} common.Response{data=dto.GroupQuotaResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Router /api/oss/group/quota/{id} [post]
+func (c *GroupController) SetDefaultProjectQuota(ctx *gin.Context) {
+	var req dto.GroupQuotaRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	quota, err := c.groupService.SetDefaultProjectQuota(ctx, ctx.Param("id"), req.DefaultProjectQuota, userID)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(quota))
+}
+
+// GetGroupStorageTrend 获取群组存储用量趋势
+// @Summary 获取群组存储用量趋势
+// @Description 按日汇总群组内所有项目的存储用量，没有变更的日期沿用上一次的总量，适合绘制趋势图，群组成员可查看
+// @Tags 群组管理
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "群组ID"
+// @Param from query string false "开始日期，格式 2006-01-02，默认最近30天"
+// @Param to query string false "结束日期，格式 2006-01-02，包含当天，默认今天"
+// @Success 200 {object} common.Response{data=dto.StorageTrendResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Router /api/oss/group/{id}/storage-trend [get]
+func (c *GroupController) GetGroupStorageTrend(ctx *gin.Context) {
+	var query dto.StorageTrendQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	from, to, err := service.ParseStorageTrendRange(query.From, query.To)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	trend, err := c.groupService.GetGroupStorageTrend(ctx, ctx.Param("id"), userID, from, to)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(trend))
+}
+
 // ListGroups 获取群组列表
 // @Summary 获取群组列表
 // @Description 根据条件获取群组列表
@@ -159,7 +312,7 @@ func (c *GroupController) GetGroupByID(ctx *gin.Context) {
 // @Param status query int false "状态：1-正常，2-禁用，3-锁定"
 // @Param page query int false "页码，默认1"
 // @Param size query int false "每页数量，默认10"
-// @Success 200 {object} common.Response{data=dto.GroupListResponse} "成功"
+// @Success 200 {object} common.Response{data=dto.PageResult[dto.GroupResponse]} "成功"
 // @Failure 400 {object} common.Response "请求参数错误"
 // @Failure 401 {object} common.Response "未授权"
 // @Failure 500 {object} common.Response "内部服务器错误"
@@ -315,6 +468,334 @@ func (c *GroupController) UpdateMemberRole(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, common.SuccessResponse(nil))
 }
 
+// LeaveGroup 退出群组
+// @Summary 退出群组
+// @Description 当前用户退出群组；唯一的管理员需先转让群组
+// @Tags 群组管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param request body dto.GroupLeaveRequest true "群组信息"
+// @Success 200 {object} common.Response "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/group/leave [post]
+func (c *GroupController) LeaveGroup(ctx *gin.Context) {
+	var req dto.GroupLeaveRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	err := c.groupService.LeaveGroup(ctx, req.GroupID, userID)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(nil))
+}
+
+// TransferGroupOwnership 转让群组
+// @Summary 转让群组
+// @Description 将群组转让给另一名成员，新所有者成为群组管理员；所有者已离开群组时群组管理员也可转让
+// @Tags 群组管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param request body dto.GroupTransferRequest true "转让信息"
+// @Success 200 {object} common.Response "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/group/transfer [post]
+func (c *GroupController) TransferGroupOwnership(ctx *gin.Context) {
+	var req dto.GroupTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	err := c.groupService.TransferGroupOwnership(ctx, req.GroupID, req.NewOwnerID, userID)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(nil))
+}
+
+// BulkMoveMembers 批量移动群组成员
+// @Summary 批量移动群组成员
+// @Description 组织调整时将多个用户从原群组移到目标群组，每个用户单独处理并返回结果；可选择保留或删除用户在原群组各项目中的权限
+// @Tags 群组管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param request body dto.GroupBulkMoveRequest true "移动信息"
+// @Success 200 {object} common.Response{data=dto.GroupBulkMoveResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "需要系统管理员权限"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/group/member/move [post]
+func (c *GroupController) BulkMoveMembers(ctx *gin.Context) {
+	var req dto.GroupBulkMoveRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	response, err := c.groupService.BulkMoveMembers(ctx, &req, userID)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidGroupMove) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("移动群组成员失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(response))
+}
+
+// InviteMembers 按邮箱批量邀请成员
+// @Summary 按邮箱批量邀请成员
+// @Description 群组管理员按邮箱邀请成员，已注册的用户直接加入群组，未注册的邮箱在用户注册并激活后自动加入；每个邮箱单独处理并返回结果
+// @Tags 群组管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "群组ID"
+// @Param request body dto.GroupInviteMembersRequest true "邀请信息"
+// @Success 200 {object} common.Response{data=dto.GroupInviteMembersResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "需要群组管理员权限"
+// @Failure 404 {object} common.Response "群组不存在"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/group/{id}/invite-members [post]
+func (c *GroupController) InviteMembers(ctx *gin.Context) {
+	var req dto.GroupInviteMembersRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	response, err := c.groupService.InviteMembersByEmail(ctx, ctx.Param("id"), req.Emails, req.Role, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrGroupNotFound):
+			ctx.JSON(http.StatusNotFound, common.ErrorResponse(err.Error()))
+		case errors.Is(err, service.ErrGroupInviteForbidden):
+			ctx.JSON(http.StatusForbidden, common.ErrorResponse(err.Error()))
+		default:
+			ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("邀请成员失败: "+err.Error()))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(response))
+}
+
+// RequestToJoin 申请加入群组
+// @Summary 申请加入群组
+// @Description 提交入群申请，群组管理员批准后以普通成员加入；同一用户在一个群组同时只能有一个待审批的申请
+// @Tags 群组管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "群组ID"
+// @Param request body dto.GroupJoinApplyRequest false "申请说明"
+// @Success 200 {object} common.Response{data=dto.GroupJoinRequestResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误或已是群组成员"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 404 {object} common.Response "群组不存在"
+// @Failure 409 {object} common.Response "已有待审批的申请"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/group/{id}/join-request [post]
+func (c *GroupController) RequestToJoin(ctx *gin.Context) {
+	// 申请说明可选，允许不带请求体
+	var req dto.GroupJoinApplyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	response, err := c.groupService.RequestToJoin(ctx, ctx.Param("id"), userID, req.Message)
+	if err != nil {
+		respondJoinRequestError(ctx, "提交入群申请失败", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(response))
+}
+
+// ListJoinRequests 获取入群申请列表
+// @Summary 获取入群申请列表
+// @Description 群组管理员查看入群申请，默认只返回待审批的申请
+// @Tags 群组管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "群组ID"
+// @Param status query string false "状态:pending(默认)、approved、rejected"
+// @Param page query int false "页码，默认1"
+// @Param size query int false "每页数量，默认10"
+// @Success 200 {object} common.Response{data=dto.PageResult[dto.GroupJoinRequestResponse]} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "需要群组管理员权限"
+// @Failure 404 {object} common.Response "群组不存在"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/group/{id}/join-requests [get]
+func (c *GroupController) ListJoinRequests(ctx *gin.Context) {
+	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(ctx.DefaultQuery("size", "10"))
+
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	response, err := c.groupService.ListJoinRequests(ctx, ctx.Param("id"), ctx.Query("status"), page, size, userID)
+	if err != nil {
+		respondJoinRequestError(ctx, "获取入群申请失败", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(response))
+}
+
+// ApproveJoinRequest 批准入群申请
+// @Summary 批准入群申请
+// @Description 群组管理员批准入群申请，申请人以普通成员加入群组
+// @Tags 群组管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "群组ID"
+// @Param request_id path string true "申请ID"
+// @Success 200 {object} common.Response{data=dto.GroupJoinRequestResponse} "成功"
+// @Failure 400 {object} common.Response "申请人已是群组成员"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "需要群组管理员权限"
+// @Failure 404 {object} common.Response "群组或申请不存在"
+// @Failure 409 {object} common.Response "申请已处理"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/group/{id}/join-requests/{request_id}/approve [post]
+func (c *GroupController) ApproveJoinRequest(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	response, err := c.groupService.ApproveJoinRequest(ctx, ctx.Param("id"), ctx.Param("request_id"), userID)
+	if err != nil {
+		respondJoinRequestError(ctx, "批准入群申请失败", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(response))
+}
+
+// RejectJoinRequest 拒绝入群申请
+// @Summary 拒绝入群申请
+// @Description 群组管理员拒绝入群申请，申请人之后可以重新申请
+// @Tags 群组管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "群组ID"
+// @Param request_id path string true "申请ID"
+// @Success 200 {object} common.Response{data=dto.GroupJoinRequestResponse} "成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "需要群组管理员权限"
+// @Failure 404 {object} common.Response "群组或申请不存在"
+// @Failure 409 {object} common.Response "申请已处理"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/group/{id}/join-requests/{request_id}/reject [post]
+func (c *GroupController) RejectJoinRequest(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	response, err := c.groupService.RejectJoinRequest(ctx, ctx.Param("id"), ctx.Param("request_id"), userID)
+	if err != nil {
+		respondJoinRequestError(ctx, "拒绝入群申请失败", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(response))
+}
+
+// respondJoinRequestError 按错误类型返回入群申请接口的错误响应
+func respondJoinRequestError(ctx *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidJoinRequest):
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+	case errors.Is(err, service.ErrJoinRequestForbidden):
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse(err.Error()))
+	case errors.Is(err, service.ErrGroupNotFound), errors.Is(err, service.ErrJoinRequestNotFound):
+		ctx.JSON(http.StatusNotFound, common.ErrorResponse(err.Error()))
+	case errors.Is(err, service.ErrJoinRequestPending), errors.Is(err, service.ErrJoinRequestProcessed):
+		ctx.JSON(http.StatusConflict, common.ErrorResponse(err.Error()))
+	default:
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(message+": "+err.Error()))
+	}
+}
+
 // RemoveMember 移除成员
 // @Summary 移除成员
 // @Description 从群组中移除成员
@@ -365,7 +846,7 @@ func (c *GroupController) RemoveMember(ctx *gin.Context) {
 // @Param id path int true "群组ID"
 // @Param page query int false "页码，默认1"
 // @Param size query int false "每页数量，默认10"
-// @Success 200 {object} common.Response{data=dto.GroupMemberListResponse} "成功"
+// @Success 200 {object} common.Response{data=dto.PageResult[dto.GroupMemberResponse]} "成功"
 // @Failure 400 {object} common.Response "请求参数错误"
 // @Failure 401 {object} common.Response "未授权"
 // @Failure 500 {object} common.Response "内部服务器错误"
@@ -453,3 +934,42 @@ func (c *GroupController) GenerateInviteCode(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, common.SuccessResponse(invite))
 }
+
+// RotateInviteCode 轮换邀请码
+// @Summary 轮换邀请码
+// @Description 为群组生成新的邀请码，原邀请码立即失效
+// @Tags 群组管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "群组ID"
+// @Param request body dto.GroupInviteRotateRequest false "有效期"
+// @Success 200 {object} common.Response{data=dto.GroupInviteResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/group/{id}/invite-code/rotate [post]
+func (c *GroupController) RotateInviteCode(ctx *gin.Context) {
+	// 有效期可选，允许不带请求体
+	var req dto.GroupInviteRotateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	invite, err := c.groupService.RotateInviteCode(ctx, ctx.Param("id"), req.ExpireDays, userID)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(invite))
+}
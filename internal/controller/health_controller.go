@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"oss-backend/internal/service"
+	"oss-backend/pkg/common"
+)
+
+// HealthController 健康检查控制器，供负载均衡和容器探针使用，无需认证
+type HealthController struct {
+	checker *service.HealthChecker
+}
+
+// NewHealthController 创建健康检查控制器
+func NewHealthController(checker *service.HealthChecker) *HealthController {
+	return &HealthController{checker: checker}
+}
+
+// Health 存活检查
+// @Summary 存活检查
+// @Description 进程正常运行即返回200，不检查依赖服务
+// @Tags 健康检查
+// @Produce json
+// @Success 200 {object} common.Response "成功"
+// @Router /health [get]
+func (c *HealthController) Health(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, common.SuccessResponse(nil))
+}
+
+// Ready 就绪检查
+// @Summary 就绪检查
+// @Description 检查 MySQL、Redis 和 MinIO 是否可用，任一不可用时返回503，data 中为各依赖的检查结果
+// @Tags 健康检查
+// @Produce json
+// @Success 200 {object} common.Response{data=dto.ReadinessResponse} "服务就绪"
+// @Failure 503 {object} common.Response{data=dto.ReadinessResponse} "依赖服务不可用"
+// @Router /ready [get]
+func (c *HealthController) Ready(ctx *gin.Context) {
+	result := c.checker.CheckReadiness(ctx.Request.Context())
+	if !result.Ready {
+		ctx.JSON(http.StatusServiceUnavailable, &common.Response{
+			Code:    common.CodeError,
+			Message: "服务未就绪",
+			Data:    result,
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, common.SuccessResponse(result))
+}
@@ -0,0 +1,359 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"oss-backend/internal/model/dto"
+	"oss-backend/internal/service"
+	"oss-backend/pkg/common"
+)
+
+// JobController 后台任务控制器
+type JobController struct {
+	jobService  service.JobService
+	fileService service.FileService
+}
+
+// NewJobController 创建后台任务控制器
+func NewJobController(jobService service.JobService, fileService service.FileService) *JobController {
+	return &JobController{
+		jobService:  jobService,
+		fileService: fileService,
+	}
+}
+
+// ListJobs 获取后台任务列表
+// @Summary 获取后台任务列表
+// @Description 列出后台任务及其进度，可按状态和类型筛选
+// @Tags 系统管理员API
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param status query string false "任务状态(running/completed/failed/cancelled)"
+// @Param type query string false "任务类型"
+// @Success 200 {object} common.Response{data=[]dto.JobResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Router /api/oss/admin/jobs [get]
+func (c *JobController) ListJobs(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	var req dto.JobListRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	jobs, err := c.jobService.ListJobs(ctx, userID, &req)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取任务列表失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(jobs))
+}
+
+// GetJob 获取后台任务详情
+// @Summary 获取后台任务详情
+// @Description 获取指定后台任务的状态、进度和结果
+// @Tags 系统管理员API
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "任务ID"
+// @Success 200 {object} common.Response{data=dto.JobResponse} "成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "任务不存在"
+// @Router /api/oss/admin/jobs/{id} [get]
+func (c *JobController) GetJob(ctx *gin.Context) {
+	job, err := c.jobService.GetJob(ctx, ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(job))
+}
+
+// CancelJob 取消后台任务
+// @Summary 取消后台任务
+// @Description 取消运行中的后台任务，任务在安全点退出，返回取消时的进度
+// @Tags 系统管理员API
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "任务ID"
+// @Success 200 {object} common.Response{data=dto.JobResponse} "成功"
+// @Failure 400 {object} common.Response "任务已结束"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Router /api/oss/admin/jobs/{id}/cancel [post]
+func (c *JobController) CancelJob(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	job, err := c.jobService.CancelJob(ctx, ctx.Param("id"), userID)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse("取消任务失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(job))
+}
+
+// StartStatsRecalculation 启动存储统计重算任务
+// @Summary 重算存储统计
+// @Description 以后台任务方式重新计算所有项目的存储统计
+// @Tags 系统管理员API
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Success 200 {object} common.Response{data=dto.JobResponse} "成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Router /api/oss/admin/jobs/stats-recalculate [post]
+func (c *JobController) StartStatsRecalculation(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	jobID := c.jobService.Submit(service.JobTypeStatsRecalculate, userID, c.fileService.VerifyAllProjectsStats)
+
+	job, err := c.jobService.GetJob(ctx, jobID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(job))
+}
+
+// StartStorageReport 生成存储报表
+// @Summary 生成存储报表
+// @Description 以后台任务方式生成群组在日期范围内的每日存储报表(CSV或PDF)，完成后通过任务报表接口下载
+// @Tags 系统管理员API
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param request body dto.StorageReportRequest true "报表参数"
+// @Success 200 {object} common.Response{data=dto.JobResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Router /api/oss/admin/jobs/storage-report [post]
+func (c *JobController) StartStorageReport(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	var req dto.StorageReportRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	from, to, err := service.ParseStorageReportRange(req.StartDate, req.EndDate)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	jobID := c.jobService.Submit(service.JobTypeStorageReport, userID, func(jobCtx context.Context) error {
+		objectName, err := c.fileService.GenerateStorageReport(jobCtx, req.GroupID, userID, from, to, req.Format)
+		if err != nil {
+			return err
+		}
+		service.SetJobResult(jobCtx, objectName)
+		return nil
+	})
+
+	job, err := c.jobService.GetJob(ctx, jobID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(job))
+}
+
+// StartFileMigration 迁移文件存储后端
+// @Summary 迁移文件存储后端
+// @Description 以后台任务方式将文件及其历史版本迁移到另一个存储后端，复制后校验内容哈希，校验通过且文件未被修改时才切换并删除源对象
+// @Tags 系统管理员API
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param request body dto.FileMigrationRequest true "迁移参数"
+// @Success 200 {object} common.Response{data=dto.JobResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "文件或存储后端不存在"
+// @Router /api/oss/admin/jobs/file-migration [post]
+func (c *JobController) StartFileMigration(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	var req dto.FileMigrationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	if err := service.CheckStorageBackend(req.Backend); err != nil {
+		if errors.Is(err, service.ErrStorageBackendNotFound) {
+			ctx.JSON(http.StatusNotFound, common.ErrorResponse(err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(err.Error()))
+		return
+	}
+	file, err := c.fileService.GetFileInfo(ctx, req.FileID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(err.Error()))
+		return
+	}
+	if file == nil || file.IsDeleted {
+		ctx.JSON(http.StatusNotFound, common.ErrorResponse("文件不存在"))
+		return
+	}
+
+	jobID := c.jobService.Submit(service.JobTypeFileMigration, userID, func(jobCtx context.Context) error {
+		if err := c.fileService.MigrateFileStorage(jobCtx, req.FileID, req.Backend, userID); err != nil {
+			return err
+		}
+		service.SetJobResult(jobCtx, req.Backend)
+		return nil
+	})
+
+	job, err := c.jobService.GetJob(ctx, jobID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(job))
+}
+
+// StartBucketMigration 迁移存储桶布局
+// @Summary 迁移存储桶布局
+// @Description 切换存储桶策略（minio.bucket_strategy）并重启后，以后台任务方式将默认存储后端中已有的对象从原布局移动到当前布局；对象在服务端复制，每个存储桶全部复制成功后才删除原位置的对象，失败的存储桶可重新执行。迁移期间应暂停写入
+// @Tags 系统管理员API
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param request body dto.BucketMigrationRequest true "原布局"
+// @Success 200 {object} common.Response{data=dto.JobResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误或原布局与当前布局相同"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Router /api/oss/admin/jobs/bucket-migration [post]
+func (c *JobController) StartBucketMigration(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	var req dto.BucketMigrationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	if err := c.fileService.CheckBucketMigration(req.From, req.SharedBucket); err != nil {
+		if errors.Is(err, service.ErrInvalidBucketMigration) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	jobID := c.jobService.Submit(service.JobTypeBucketMigration, userID, func(jobCtx context.Context) error {
+		summary, err := c.fileService.MigrateBucketLayout(jobCtx, req.From, req.SharedBucket)
+		if err != nil {
+			return err
+		}
+		service.SetJobResult(jobCtx, summary)
+		return nil
+	})
+
+	job, err := c.jobService.GetJob(ctx, jobID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(job))
+}
+
+// DownloadStorageReport 下载存储报表
+// @Summary 下载存储报表
+// @Description 下载已完成的存储报表任务生成的CSV或PDF文件
+// @Tags 系统管理员API
+// @Produce text/csv,application/pdf
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "任务ID"
+// @Success 200 {file} file "报表文件"
+// @Failure 400 {object} common.Response "任务未完成或不是报表任务"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "任务不存在"
+// @Router /api/oss/admin/jobs/{id}/report [get]
+func (c *JobController) DownloadStorageReport(ctx *gin.Context) {
+	job, err := c.jobService.GetJob(ctx, ctx.Param("id"))
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, common.ErrorResponse(err.Error()))
+		return
+	}
+	if job.Type != service.JobTypeStorageReport {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse("该任务不是存储报表任务"))
+		return
+	}
+	if job.Status != service.JobStatusCompleted || job.Result == "" {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(fmt.Sprintf("报表尚未生成，任务状态: %s", job.Status)))
+		return
+	}
+
+	reader, err := c.fileService.OpenStorageReport(ctx, job.Result)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(err.Error()))
+		return
+	}
+	defer reader.Close()
+
+	format, contentType := service.StorageReportFormat(job.Result)
+	ctx.DataFromReader(http.StatusOK, -1, contentType, reader, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="storage-report-%s.%s"`, job.ID, format),
+	})
+}
@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"oss-backend/internal/model/dto"
+	"oss-backend/internal/service"
+	"oss-backend/pkg/common"
+)
+
+// PolicyController 授权策略备份控制器
+type PolicyController struct {
+	policyService service.PolicyService
+}
+
+// NewPolicyController 创建授权策略备份控制器
+func NewPolicyController(policyService service.PolicyService) *PolicyController {
+	return &PolicyController{
+		policyService: policyService,
+	}
+}
+
+// ExportPolicies 导出授权策略
+// @Summary 导出授权策略
+// @Description 导出全部Casbin策略与角色继承规则，规则排序稳定，可用于备份与比对
+// @Tags 系统管理员API
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Success 200 {object} common.Response{data=dto.PolicySnapshot} "成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/admin/policies/export [get]
+func (c *PolicyController) ExportPolicies(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	snapshot, err := c.policyService.ExportPolicies(ctx, userID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("导出策略失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(snapshot))
+}
+
+// ImportPolicies 导入授权策略
+// @Summary 导入授权策略
+// @Description 校验并应用策略快照，使当前策略与快照一致。dry_run为true时仅返回新增与删除的差异，不做修改。导入会记录审计及导入前快照
+// @Tags 系统管理员API
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param request body dto.PolicyImportRequest true "策略快照"
+// @Success 200 {object} common.Response{data=dto.PolicyImportResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/admin/policies/import [post]
+func (c *PolicyController) ImportPolicies(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	var req dto.PolicyImportRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	result, err := c.policyService.ImportPolicies(ctx, userID, &req.Snapshot, req.DryRun)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse("导入策略失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(result))
+}
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -151,7 +152,7 @@ func (c *ProjectController) GetProjectByID(ctx *gin.Context) {
 // @Param keyword query string false "关键词"
 // @Param page query int false "页码"
 // @Param size query int false "每页大小"
-// @Success 200 {object} common.Response{data=common.PageResult{list=[]dto.ProjectResponse}} "成功"
+// @Success 200 {object} common.Response{data=dto.PageResult[dto.ProjectResponse]} "成功"
 // @Failure 401 {object} common.Response "未授权"
 // @Failure 500 {object} common.Response "服务器内部错误"
 // @Router /api/oss/project/list [get]
@@ -178,10 +179,7 @@ func (c *ProjectController) ListProjects(ctx *gin.Context) {
 	}
 
 	// 返回成功响应
-	ctx.JSON(http.StatusOK, common.SuccessResponse(common.PageResult{
-		Total: result.Total,
-		List:  result.Items,
-	}))
+	ctx.JSON(http.StatusOK, common.SuccessResponse(result))
 }
 
 // GetUserProjects 获取用户参与的项目
@@ -196,7 +194,7 @@ func (c *ProjectController) ListProjects(ctx *gin.Context) {
 // @Param keyword query string false "关键词"
 // @Param page query int false "页码"
 // @Param size query int false "每页大小"
-// @Success 200 {object} common.Response{data=common.PageResult{list=[]dto.ProjectResponse}} "成功"
+// @Success 200 {object} common.Response{data=dto.PageResult[dto.ProjectResponse]} "成功"
 // @Failure 401 {object} common.Response "未授权"
 // @Failure 500 {object} common.Response "服务器内部错误"
 // @Router /api/oss/project/user [get]
@@ -216,17 +214,14 @@ func (c *ProjectController) GetUserProjects(ctx *gin.Context) {
 	}
 
 	// 调用服务获取用户参与的项目
-	projects, total, err := c.projectService.GetUserProjects(ctx, &query, userID.(string))
+	result, err := c.projectService.GetUserProjects(ctx, &query, userID.(string))
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取用户项目失败: "+err.Error()))
 		return
 	}
 
 	// 返回成功响应
-	ctx.JSON(http.StatusOK, common.SuccessResponse(common.PageResult{
-		Total: total,
-		List:  projects,
-	}))
+	ctx.JSON(http.StatusOK, common.SuccessResponse(result))
 }
 
 // DeleteProject 删除项目
@@ -266,6 +261,336 @@ func (c *ProjectController) DeleteProject(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, common.SuccessResponse(nil))
 }
 
+// CloneProject 克隆项目
+// @Summary 克隆项目
+// @Description 复制项目的文件夹结构（不含文件），可选复制成员角色（需要项目管理员权限）
+// @Tags 项目管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "源项目ID"
+// @Param request body dto.CloneProjectRequest true "克隆信息"
+// @Success 200 {object} common.Response{data=dto.ProjectResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "无权限"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /api/oss/project/{id}/clone [post]
+func (c *ProjectController) CloneProject(ctx *gin.Context) {
+	// 获取当前用户ID
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+
+	// 获取源项目ID
+	projectID := ctx.Param("id")
+
+	// 解析请求参数
+	var req dto.CloneProjectRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse("请求参数错误: "+err.Error()))
+		return
+	}
+
+	// 调用服务克隆项目
+	project, err := c.projectService.CloneProject(ctx, projectID, req.Name, userID.(string), req.IncludeMembers)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("克隆项目失败: "+err.Error()))
+		return
+	}
+
+	// 返回成功响应
+	ctx.JSON(http.StatusOK, common.SuccessResponse(project))
+}
+
+// ArchiveProject 归档项目
+// @Summary 归档项目
+// @Description 将项目设为归档状态，归档后项目内的文件只能列表和下载，上传、新建文件夹、删除等写操作返回403（需要项目管理员权限）
+// @Tags 项目管理
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "项目ID"
+// @Success 200 {object} common.Response{data=dto.ProjectResponse} "成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "无权限"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /api/oss/project/{id}/archive [post]
+func (c *ProjectController) ArchiveProject(ctx *gin.Context) {
+	// 获取当前用户ID
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+
+	// 调用服务归档项目
+	project, err := c.projectService.ArchiveProject(ctx, ctx.Param("id"), userID.(string))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("归档项目失败: "+err.Error()))
+		return
+	}
+
+	// 返回成功响应
+	ctx.JSON(http.StatusOK, common.SuccessResponse(project))
+}
+
+// UnarchiveProject 取消归档项目
+// @Summary 取消归档项目
+// @Description 将归档的项目恢复为正常状态，恢复文件写操作（需要项目管理员权限）
+// @Tags 项目管理
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "项目ID"
+// @Success 200 {object} common.Response{data=dto.ProjectResponse} "成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "无权限"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /api/oss/project/{id}/unarchive [post]
+func (c *ProjectController) UnarchiveProject(ctx *gin.Context) {
+	// 获取当前用户ID
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+
+	// 调用服务取消归档
+	project, err := c.projectService.UnarchiveProject(ctx, ctx.Param("id"), userID.(string))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("取消归档失败: "+err.Error()))
+		return
+	}
+
+	// 返回成功响应
+	ctx.JSON(http.StatusOK, common.SuccessResponse(project))
+}
+
+// SetSharePolicy 设置项目分享策略
+// @Summary 设置项目分享策略
+// @Description 设置项目内分享的默认及最长有效期、默认及最大下载次数（需要项目管理员权限）
+// @Tags 项目管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param request body dto.SharePolicyRequest true "分享策略"
+// @Success 200 {object} common.Response{data=dto.SharePolicyResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "无权限"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /api/oss/project/share-policy [post]
+func (c *ProjectController) SetSharePolicy(ctx *gin.Context) {
+	// 获取当前用户ID
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+
+	// 解析请求参数
+	var req dto.SharePolicyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse("请求参数错误: "+err.Error()))
+		return
+	}
+
+	// 调用服务设置分享策略
+	policy, err := c.projectService.SetSharePolicy(ctx, &req, userID.(string))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("设置分享策略失败: "+err.Error()))
+		return
+	}
+
+	// 返回成功响应
+	ctx.JSON(http.StatusOK, common.SuccessResponse(policy))
+}
+
+// GetSharePolicy 获取项目分享策略
+// @Summary 获取项目分享策略
+// @Description 获取项目的分享默认值与上限，供客户端创建分享前展示
+// @Tags 项目管理
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "项目ID"
+// @Success 200 {object} common.Response{data=dto.SharePolicyResponse} "成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "无权限"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /api/oss/project/{id}/share-policy [get]
+func (c *ProjectController) GetSharePolicy(ctx *gin.Context) {
+	// 获取当前用户ID
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+
+	// 调用服务获取分享策略
+	policy, err := c.projectService.GetSharePolicy(ctx, ctx.Param("id"), userID.(string))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取分享策略失败: "+err.Error()))
+		return
+	}
+
+	// 返回成功响应
+	ctx.JSON(http.StatusOK, common.SuccessResponse(policy))
+}
+
+// ListWebhooks 获取项目Webhook列表
+// @Summary 获取项目Webhook列表
+// @Description 获取项目配置的Webhook，不返回签名密钥（需要项目管理员权限）
+// @Tags 项目管理
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "项目ID"
+// @Success 200 {object} common.Response{data=[]dto.WebhookResponse} "成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "无权限"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /api/oss/project/{id}/webhooks [get]
+func (c *ProjectController) ListWebhooks(ctx *gin.Context) {
+	// 获取当前用户ID
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+
+	webhooks, err := c.projectService.ListWebhooks(ctx, ctx.Param("id"), userID.(string))
+	if err != nil {
+		respondWebhookError(ctx, "获取Webhook列表失败", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(webhooks))
+}
+
+// CreateWebhook 添加项目Webhook
+// @Summary 添加项目Webhook
+// @Description 项目内文件上传、删除、分享时向指定地址推送JSON，请求头 X-Signature 为以密钥对请求体计算的 HMAC-SHA256；未指定密钥时自动生成，密钥只在本次响应中返回（需要项目管理员权限）
+// @Tags 项目管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "项目ID"
+// @Param request body dto.WebhookRequest true "Webhook配置"
+// @Success 200 {object} common.Response{data=dto.WebhookResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "无权限"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /api/oss/project/{id}/webhooks [post]
+func (c *ProjectController) CreateWebhook(ctx *gin.Context) {
+	// 获取当前用户ID
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+
+	// 解析请求参数
+	var req dto.WebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse("请求参数错误: "+err.Error()))
+		return
+	}
+
+	webhook, err := c.projectService.CreateWebhook(ctx, ctx.Param("id"), &req, userID.(string))
+	if err != nil {
+		respondWebhookError(ctx, "添加Webhook失败", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(webhook))
+}
+
+// UpdateWebhook 更新项目Webhook
+// @Summary 更新项目Webhook
+// @Description 更新推送地址、订阅事件和启用状态；密钥为空时保持不变，指定新密钥时在响应中返回（需要项目管理员权限）
+// @Tags 项目管理
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "项目ID"
+// @Param webhookID path string true "Webhook ID"
+// @Param request body dto.WebhookRequest true "Webhook配置"
+// @Success 200 {object} common.Response{data=dto.WebhookResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "无权限"
+// @Failure 404 {object} common.Response "Webhook不存在"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /api/oss/project/{id}/webhooks/{webhookID} [put]
+func (c *ProjectController) UpdateWebhook(ctx *gin.Context) {
+	// 获取当前用户ID
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+
+	// 解析请求参数
+	var req dto.WebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse("请求参数错误: "+err.Error()))
+		return
+	}
+
+	webhook, err := c.projectService.UpdateWebhook(ctx, ctx.Param("id"), ctx.Param("webhookID"), &req, userID.(string))
+	if err != nil {
+		respondWebhookError(ctx, "更新Webhook失败", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(webhook))
+}
+
+// DeleteWebhook 删除项目Webhook
+// @Summary 删除项目Webhook
+// @Description 删除项目Webhook，已在推送队列中的事件仍会发出（需要项目管理员权限）
+// @Tags 项目管理
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "项目ID"
+// @Param webhookID path string true "Webhook ID"
+// @Success 200 {object} common.Response "成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "无权限"
+// @Failure 404 {object} common.Response "Webhook不存在"
+// @Failure 500 {object} common.Response "服务器内部错误"
+// @Router /api/oss/project/{id}/webhooks/{webhookID} [delete]
+func (c *ProjectController) DeleteWebhook(ctx *gin.Context) {
+	// 获取当前用户ID
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+
+	if err := c.projectService.DeleteWebhook(ctx, ctx.Param("id"), ctx.Param("webhookID"), userID.(string)); err != nil {
+		respondWebhookError(ctx, "删除Webhook失败", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(nil))
+}
+
+// respondWebhookError 按错误类型返回Webhook接口的错误响应
+func respondWebhookError(ctx *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidWebhook):
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+	case errors.Is(err, service.ErrWebhookForbidden):
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse(err.Error()))
+	case errors.Is(err, service.ErrWebhookNotFound):
+		ctx.JSON(http.StatusNotFound, common.ErrorResponse(err.Error()))
+	default:
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(message+": "+err.Error()))
+	}
+}
+
 // SetPermission 设置项目成员权限
 // @Summary 设置项目成员权限
 // @Description 为项目成员设置权限（需要项目管理员权限）
@@ -348,20 +673,20 @@ func (c *ProjectController) RemovePermission(ctx *gin.Context) {
 
 // ListProjectUsers 列出项目成员
 // @Summary 列出项目成员
-// @Description 获取项目所有成员及其权限
+// @Description 分页获取项目成员及其权限，按加入时间排序
 // @Tags 项目管理
 // @Accept json
 // @Produce json
 // @Param Authorization header string true "Bearer {{token}}"
 // @Param id path int true "项目ID"
-// @Param page query int false "页码"
-// @Param size query int false "每页大小"
-// @Success 200 {object} common.Response{data=common.PageResult{list=[]dto.ProjectUserResponse}} "成功"
+// @Param page query int false "页码，默认1"
+// @Param size query int false "每页大小，默认10"
+// @Success 200 {object} common.Response{data=dto.PageResult[dto.ProjectUserResponse]} "成功"
 // @Failure 400 {object} common.Response "请求参数错误"
 // @Failure 401 {object} common.Response "未授权"
 // @Failure 403 {object} common.Response "无权限"
 // @Failure 500 {object} common.Response "服务器内部错误"
-// @Router /api/oss/project/users/{id} [get]
+// @Router /api/oss/project/member/list/{id} [get]
 func (c *ProjectController) ListProjectUsers(ctx *gin.Context) {
 	// 获取当前用户ID
 	userID, exists := ctx.Get("userID")
@@ -374,15 +699,13 @@ func (c *ProjectController) ListProjectUsers(ctx *gin.Context) {
 	projectIDStr := ctx.Param("id")
 	projectID := projectIDStr
 
-	// 解析分页参数
+	// 解析分页参数，成员列表固定按加入时间排序，不接受客户端的排序字段
 	var pageQuery dto.PageQuery
 	if err := ctx.ShouldBindQuery(&pageQuery); err != nil {
-		// 使用默认分页参数
-		pageQuery = dto.PageQuery{
-			Page: 1,
-			Size: 100, // 默认获取较多记录
-		}
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse("无效的分页参数: "+err.Error()))
+		return
 	}
+	pageQuery = dto.PageQuery{Page: pageQuery.Page, Size: pageQuery.Size}.WithDefaultValues()
 
 	// 调用服务获取项目成员
 	users, total, err := c.projectService.ListProjectUsers(ctx, projectID, userID.(string), &pageQuery)
@@ -392,8 +715,5 @@ func (c *ProjectController) ListProjectUsers(ctx *gin.Context) {
 	}
 
 	// 返回成功响应
-	ctx.JSON(http.StatusOK, common.SuccessResponse(common.PageResult{
-		Total: total,
-		List:  users,
-	}))
+	ctx.JSON(http.StatusOK, common.SuccessResponse(dto.NewPageResult(users, total, pageQuery)))
 }
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -172,7 +173,7 @@ func (c *RoleController) GetRoleByID(ctx *gin.Context) {
 // @Param status query int false "状态：1-启用，0-禁用" Enums(0, 1)
 // @Param page query int false "页码，默认1"
 // @Param size query int false "每页数量，默认10"
-// @Success 200 {object} common.Response{data=dto.RoleListResponse} "成功"
+// @Success 200 {object} common.Response{data=dto.PageResult[dto.RoleResponse]} "成功"
 // @Failure 400 {object} common.Response "请求参数错误"
 // @Failure 401 {object} common.Response "未授权"
 // @Failure 500 {object} common.Response "内部服务器错误"
@@ -193,3 +194,114 @@ func (c *RoleController) ListRoles(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, common.SuccessResponse(roles))
 }
+
+// ListPolicies 获取权限策略列表
+// @Summary 获取权限策略列表
+// @Description 获取Casbin权限策略（p规则），可按主体和域过滤（需要系统管理员权限）
+// @Tags 系统管理员API
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param sub query string false "主体，角色编码或 user:<用户ID>"
+// @Param domain query string false "域，如 system、group:<ID>、project:<ID>"
+// @Success 200 {object} common.Response{data=[]dto.RolePolicy} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/role/policies [get]
+// @Security ApiKeyAuth
+func (c *RoleController) ListPolicies(ctx *gin.Context) {
+	var query dto.RolePolicyQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	policies, err := c.authService.ListPolicies(ctx, query.Sub, query.Domain)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(policies))
+}
+
+// AddPolicy 添加权限策略
+// @Summary 添加权限策略
+// @Description 添加一条Casbin权限策略，立即生效并写入数据库（需要系统管理员权限）
+// @Tags 系统管理员API
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param request body dto.RolePolicy true "策略"
+// @Success 200 {object} common.Response "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 409 {object} common.Response "策略已存在"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/role/policies [post]
+// @Security ApiKeyAuth
+func (c *RoleController) AddPolicy(ctx *gin.Context) {
+	var req dto.RolePolicy
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	err := c.authService.AddPolicy(ctx, req.Sub, req.Domain, req.Obj, req.Act)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPolicy) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrPolicyExists) {
+			ctx.JSON(http.StatusConflict, common.ErrorResponse(err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(nil))
+}
+
+// RemovePolicy 删除权限策略
+// @Summary 删除权限策略
+// @Description 删除一条Casbin权限策略，立即生效并从数据库删除（需要系统管理员权限）
+// @Tags 系统管理员API
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param request body dto.RolePolicy true "策略"
+// @Success 200 {object} common.Response "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 404 {object} common.Response "策略不存在"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/role/policies [delete]
+// @Security ApiKeyAuth
+func (c *RoleController) RemovePolicy(ctx *gin.Context) {
+	var req dto.RolePolicy
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	err := c.authService.RemovePolicy(ctx, req.Sub, req.Domain, req.Obj, req.Act)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPolicy) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrPolicyNotFound) {
+			ctx.JSON(http.StatusNotFound, common.ErrorResponse(err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(nil))
+}
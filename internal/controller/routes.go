@@ -1,6 +1,11 @@
 package controller
 
 import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
 	_ "oss-backend/docs/swagger" // 统一Swagger文档导入路径
 
 	"github.com/casbin/casbin/v2"
@@ -15,11 +20,17 @@ import (
 	"oss-backend/pkg/minio"
 )
 
-// SetupRouter 设置路由 (接收 Enforcer)
-func SetupRouter(r *gin.Engine, db *gorm.DB, enforcer *casbin.Enforcer, minioClient *minio.Client) {
+// SetupRouter 设置路由 (接收 Enforcer)，webhooks 为项目Webhook推送队列，streamTimeout 为上传下载等流式路由的读写超时，0表示不限制，
+// corsConfig 为 /api/oss 下接口的跨域访问配置
+func SetupRouter(r *gin.Engine, db *gorm.DB, enforcer *casbin.Enforcer, minioClient *minio.Client, statQueue *service.StorageStatQueue, webhooks *service.WebhookDispatcher, streamTimeout time.Duration, corsConfig middleware.CorsConfig) {
 	// Swagger 文档
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
+	// 健康检查，无需认证
+	healthController := NewHealthController(service.NewHealthChecker(db, minioClient))
+	r.GET("/health", healthController.Health)
+	r.GET("/ready", healthController.Ready)
+
 	// 创建仓库
 	userRepo := repository.NewUserRepository(db)
 	roleRepo := repository.NewRoleRepository(db)
@@ -28,9 +39,14 @@ func SetupRouter(r *gin.Engine, db *gorm.DB, enforcer *casbin.Enforcer, minioCli
 	fileRepo := repository.NewFileRepository(db)
 	casbinRepo := repository.NewCasbinRepository(db)
 	statRepo := repository.NewStorageStatRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
 
-	// 创建JWT中间件
-	jwtMiddleware := middleware.NewJWTAuthMiddleware()
+	// 创建令牌黑名单、登录失败限制、上传幂等键存储与JWT中间件
+	tokenBlacklist := service.NewMemoryTokenBlacklist()
+	loginLimiter := service.NewMemoryLoginLimiter()
+	idempotency := service.NewMemoryIdempotencyStore()
+	mailer := service.NewMailer()
+	jwtMiddleware := middleware.NewJWTAuthMiddleware(tokenBlacklist, userRepo)
 
 	// 创建统一的认证授权服务 (需要 Enforcer, 在 main.go 初始化)
 	authService := service.NewAuthService(enforcer, roleRepo, userRepo, casbinRepo, db)
@@ -38,23 +54,113 @@ func SetupRouter(r *gin.Engine, db *gorm.DB, enforcer *casbin.Enforcer, minioCli
 	// 创建认证与授权中间件 (传入 Enforcer)
 	authMiddleware := middleware.NewAuthMiddleware(authService, userRepo, enforcer)
 
+	// 流式路由的读写超时，取代服务器按整个请求计算的超时
+	streamingMiddleware := middleware.StreamingTimeout(streamTimeout)
+
 	// API 路由组
 	apiGroup := r.Group("/api/oss")
+	apiGroup.Use(middleware.Cors(corsConfig))
+	// 预检请求只在匹配到路由时才经过分组中间件，由跨域中间件直接返回
+	apiGroup.OPTIONS("/*path", func(ctx *gin.Context) {
+		ctx.Status(http.StatusNoContent)
+	})
 	{
 		// 注册用户相关路由
-		registerUserRoutes(apiGroup, userRepo, roleRepo, jwtMiddleware, authMiddleware, authService)
+		registerUserRoutes(apiGroup, userRepo, roleRepo, auditRepo, tokenBlacklist, loginLimiter, mailer, minioClient, jwtMiddleware, authMiddleware, authService)
 
 		// 注册角色相关路由
 		registerRoleRoutes(apiGroup, jwtMiddleware, authMiddleware, authService)
 
 		// 注册群组相关路由
-		registerGroupRoutes(apiGroup, userRepo, roleRepo, groupRepo, jwtMiddleware, authMiddleware, authService, minioClient)
+		registerGroupRoutes(apiGroup, userRepo, roleRepo, groupRepo, statRepo, jwtMiddleware, authMiddleware, authService, minioClient, mailer)
 
 		// 注册项目相关路由
 		registerProjectRoutes(apiGroup, projectRepo, groupRepo, userRepo, fileRepo, statRepo, jwtMiddleware, authMiddleware, authService, db, minioClient)
 
 		// 注册文件相关路由
-		registerFileRoutes(apiGroup, fileRepo, projectRepo, statRepo, minioClient, jwtMiddleware, authMiddleware, authService, db)
+		registerFileRoutes(apiGroup, fileRepo, projectRepo, groupRepo, userRepo, statRepo, auditRepo, statQueue, webhooks, idempotency, minioClient, mailer, jwtMiddleware, authMiddleware, streamingMiddleware, authService, db)
+
+		// 注册系统管理相关路由
+		registerAdminRoutes(apiGroup, userRepo, roleRepo, auditRepo, fileRepo, projectRepo, statRepo, statQueue, webhooks, casbinRepo, enforcer, tokenBlacklist, loginLimiter, mailer, minioClient, jwtMiddleware, authMiddleware, authService, db)
+	}
+}
+
+// 注册系统管理相关路由
+func registerAdminRoutes(
+	apiGroup *gin.RouterGroup,
+	userRepo repository.UserRepository,
+	roleRepo repository.RoleRepository,
+	auditRepo repository.AuditRepository,
+	fileRepo repository.FileRepository,
+	projectRepo repository.ProjectRepository,
+	statRepo repository.StorageStatRepository,
+	statQueue *service.StorageStatQueue,
+	webhooks *service.WebhookDispatcher,
+	casbinRepo repository.CasbinRepository,
+	enforcer *casbin.Enforcer,
+	tokenBlacklist service.TokenBlacklist,
+	loginLimiter service.LoginLimiter,
+	mailer service.Mailer,
+	minioClient *minio.Client,
+	jwtMiddleware *middleware.JWTAuthMiddleware,
+	authMiddleware *middleware.AuthMiddleware,
+	authService service.AuthService,
+	db *gorm.DB,
+) {
+	// 创建依赖
+	userController := NewUserController(service.NewUserService(userRepo, roleRepo, authService, tokenBlacklist, loginLimiter, mailer, minioClient))
+	auditService := service.NewAuditService(auditRepo, minioClient)
+	auditController := NewAuditController(auditService)
+	fileService := service.NewFileService(fileRepo, projectRepo, statRepo, auditRepo, statQueue, webhooks, nil, minioClient, authService, mailer, db)
+	jobService := service.NewJobService()
+	jobController := NewJobController(jobService, fileService)
+	policyService := service.NewPolicyService(enforcer, casbinRepo)
+	policyController := NewPolicyController(policyService)
+	storageController := NewStorageController(fileService)
+
+	// 启动审计日志保留归档任务
+	go auditService.StartRetentionWorker(context.Background())
+
+	// 启动定时备份，配置已在启动时校验
+	backupScheduler, err := service.NewBackupScheduler(fileService, jobService)
+	if err != nil {
+		log.Fatalf("初始化定时备份失败: %v", err)
+	}
+	backupScheduler.Start()
+	backupController := NewBackupController(backupScheduler, jobService)
+
+	// 系统管理路由 - 需要系统管理员权限
+	adminGroup := apiGroup.Group("/admin")
+	adminGroup.Use(jwtMiddleware.AuthMiddleware())
+	adminGroup.Use(authMiddleware.RequireAdmin())
+	{
+		// 审计日志归档
+		adminGroup.GET("/audit/archives", auditController.GetArchivedLogs)
+		adminGroup.POST("/audit/archive", auditController.ArchiveLogs)
+
+		// 后台任务管理
+		adminGroup.GET("/jobs", jobController.ListJobs)
+		adminGroup.GET("/jobs/:id", jobController.GetJob)
+		adminGroup.POST("/jobs/:id/cancel", jobController.CancelJob)
+		adminGroup.POST("/jobs/stats-recalculate", jobController.StartStatsRecalculation)
+		adminGroup.POST("/jobs/storage-report", jobController.StartStorageReport)
+		adminGroup.POST("/jobs/file-migration", jobController.StartFileMigration)
+		adminGroup.POST("/jobs/bucket-migration", jobController.StartBucketMigration)
+		adminGroup.GET("/jobs/:id/report", jobController.DownloadStorageReport)
+
+		// 定时备份
+		adminGroup.GET("/backups", backupController.ListBackups)
+		adminGroup.POST("/backups/:name/run", backupController.TriggerBackup)
+
+		// 授权策略备份与恢复
+		adminGroup.GET("/policies/export", policyController.ExportPolicies)
+		adminGroup.POST("/policies/import", policyController.ImportPolicies)
+
+		// 用户状态批量管理
+		adminGroup.POST("/users/status", userController.BulkUpdateUserStatus)
+
+		// 存储桶生命周期规则
+		adminGroup.POST("/storage/lifecycle", storageController.ApplyLifecycle)
 	}
 }
 
@@ -78,6 +184,15 @@ func registerRoleRoutes(
 		roleGroup.GET("/delete/:id", roleController.DeleteRole)
 		roleGroup.GET("/detail/:id", roleController.GetRoleByID)
 		roleGroup.GET("/list", roleController.ListRoles)
+
+		// 权限策略管理 - 需要系统管理员
+		policyGroup := roleGroup.Group("/policies")
+		policyGroup.Use(authMiddleware.RequireAdmin())
+		{
+			policyGroup.GET("", roleController.ListPolicies)
+			policyGroup.POST("", roleController.AddPolicy)
+			policyGroup.DELETE("", roleController.RemovePolicy)
+		}
 	}
 }
 
@@ -86,13 +201,26 @@ func registerUserRoutes(
 	apiGroup *gin.RouterGroup,
 	userRepo repository.UserRepository,
 	roleRepo repository.RoleRepository,
+	auditRepo repository.AuditRepository,
+	tokenBlacklist service.TokenBlacklist,
+	loginLimiter service.LoginLimiter,
+	mailer service.Mailer,
+	minioClient *minio.Client,
 	jwtMiddleware *middleware.JWTAuthMiddleware,
 	authMiddleware *middleware.AuthMiddleware,
 	authService service.AuthService,
 ) {
 	// 创建依赖
-	userService := service.NewUserService(userRepo, roleRepo, authService)
+	userService := service.NewUserService(userRepo, roleRepo, authService, tokenBlacklist, loginLimiter, mailer, minioClient)
 	userController := NewUserController(userService)
+	auditController := NewAuditController(service.NewAuditService(auditRepo, minioClient))
+
+	// 第三方登录，与本地账号密码登录并存
+	oauthGroup := apiGroup.Group("/auth/oauth")
+	{
+		oauthGroup.GET("/:provider/login", userController.OAuthLogin)
+		oauthGroup.GET("/:provider/callback", userController.OAuthCallback)
+	}
 
 	// 用户相关路由
 	userGroup := apiGroup.Group("/user")
@@ -100,6 +228,11 @@ func registerUserRoutes(
 		// 公共路由，不需要认证
 		userGroup.POST("/register", userController.Register)
 		userGroup.POST("/login", userController.Login)
+		userGroup.POST("/refresh", userController.RefreshToken)
+		userGroup.GET("/verify-email", userController.VerifyEmail)
+		userGroup.POST("/verify-email/resend", userController.ResendVerificationEmail)
+		userGroup.GET("/avatar/:user_id/:name", userController.GetAvatar)
+		userGroup.POST("/2fa/verify", userController.VerifyTOTP)
 
 		// 认证路由组
 		authGroup := userGroup.Group("/")
@@ -107,8 +240,16 @@ func registerUserRoutes(
 		{
 			// 基本用户信息 - 需要登录
 			authGroup.GET("/info", userController.GetUserInfo)
+			authGroup.GET("/permissions", userController.GetUserPermissions)
 			authGroup.POST("/update", userController.UpdateUserInfo)
+			authGroup.POST("/avatar", userController.UploadAvatar)
+			authGroup.POST("/2fa/enable", userController.EnableTOTP)
+			authGroup.POST("/2fa/confirm", userController.ConfirmTOTP)
+			authGroup.POST("/2fa/disable", userController.DisableTOTP)
+			authGroup.POST("/2fa/recovery-codes", userController.RegenerateRecoveryCodes)
 			authGroup.POST("/password", userController.UpdatePassword)
+			authGroup.POST("/logout", userController.Logout)
+			authGroup.GET("/activity", auditController.GetMyActivity)
 
 			// 用户管理 - 需要管理员权限
 			adminGroup := authGroup.Group("/")
@@ -122,6 +263,15 @@ func registerUserRoutes(
 				adminGroup.POST("/roles/:id", userController.AssignRoles)
 				adminGroup.POST("/roles/remove/:id", userController.RemoveRoles)
 			}
+
+			// 账号删除与恢复 - 需要系统管理员权限
+			sysAdminGroup := authGroup.Group("/")
+			sysAdminGroup.Use(authMiddleware.RequireAdmin())
+			{
+				sysAdminGroup.DELETE("/:id", userController.DeleteUser)
+				sysAdminGroup.POST("/reactivate/:id", userController.ReactivateUser)
+				sysAdminGroup.POST("/:id/unlock", userController.UnlockUser)
+			}
 		}
 	}
 }
@@ -132,13 +282,15 @@ func registerGroupRoutes(
 	userRepo repository.UserRepository,
 	roleRepo repository.RoleRepository,
 	groupRepo repository.GroupRepository,
+	statRepo repository.StorageStatRepository,
 	jwtMiddleware *middleware.JWTAuthMiddleware,
 	authMiddleware *middleware.AuthMiddleware,
 	authService service.AuthService,
 	minioClient *minio.Client,
+	mailer service.Mailer,
 ) {
 	// 创建依赖
-	groupService := service.NewGroupService(groupRepo, userRepo, roleRepo, authService, minioClient)
+	groupService := service.NewGroupService(groupRepo, userRepo, roleRepo, statRepo, authService, minioClient, mailer)
 	groupController := NewGroupController(groupService)
 
 	// 群组相关路由
@@ -148,11 +300,23 @@ func registerGroupRoutes(
 		// 群组管理
 		groupGroup.POST("/create", groupController.CreateGroup)
 		groupGroup.POST("/update", groupController.UpdateGroup)
+		groupGroup.DELETE("/:id", groupController.DeleteGroup)
 		groupGroup.GET("/detail/:id", groupController.GetGroupByID)
+		groupGroup.GET("/quota/:id", groupController.GetGroupQuota)
+		groupGroup.POST("/quota/:id", groupController.SetDefaultProjectQuota)
+		groupGroup.GET("/:id/storage-trend", groupController.GetGroupStorageTrend)
 		groupGroup.GET("/list", groupController.ListGroups)
 		groupGroup.GET("/user", groupController.GetUserGroups)
 		groupGroup.POST("/join", groupController.JoinGroup)
 		groupGroup.POST("/invite", groupController.GenerateInviteCode)
+		groupGroup.POST("/:id/invite-code/rotate", groupController.RotateInviteCode)
+		groupGroup.POST("/:id/invite-members", groupController.InviteMembers)
+		groupGroup.POST("/:id/join-request", groupController.RequestToJoin)
+		groupGroup.GET("/:id/join-requests", groupController.ListJoinRequests)
+		groupGroup.POST("/:id/join-requests/:request_id/approve", groupController.ApproveJoinRequest)
+		groupGroup.POST("/:id/join-requests/:request_id/reject", groupController.RejectJoinRequest)
+		groupGroup.POST("/leave", groupController.LeaveGroup)
+		groupGroup.POST("/transfer", groupController.TransferGroupOwnership)
 
 		// 成员管理 - 需要群组管理员权限
 		memberGroup := groupGroup.Group("/member")
@@ -162,6 +326,7 @@ func registerGroupRoutes(
 			memberGroup.POST("/role/:id", groupController.UpdateMemberRole)
 			memberGroup.GET("/remove/:id", groupController.RemoveMember)
 			memberGroup.GET("/list/:id", groupController.ListMembers)
+			memberGroup.POST("/move", groupController.BulkMoveMembers)
 		}
 	}
 }
@@ -208,6 +373,15 @@ func registerProjectRoutes(
 		projectGroup.GET("/delete/:id", authMiddleware.Authorize("projects", "delete", getProjectGroupID), projectController.DeleteProject)
 		projectGroup.GET("/list", authMiddleware.Authorize("projects", "read", getProjectGroupID), projectController.ListProjects)
 		projectGroup.GET("/user", projectController.GetUserProjects)
+		projectGroup.POST("/:id/clone", authMiddleware.Authorize("projects", "create", getProjectGroupID), projectController.CloneProject)
+		projectGroup.POST("/:id/archive", authMiddleware.Authorize("projects", "update", getProjectGroupID), projectController.ArchiveProject)
+		projectGroup.POST("/:id/unarchive", authMiddleware.Authorize("projects", "update", getProjectGroupID), projectController.UnarchiveProject)
+		projectGroup.POST("/share-policy", projectController.SetSharePolicy)
+		projectGroup.GET("/:id/share-policy", projectController.GetSharePolicy)
+		projectGroup.GET("/:id/webhooks", projectController.ListWebhooks)
+		projectGroup.POST("/:id/webhooks", projectController.CreateWebhook)
+		projectGroup.PUT("/:id/webhooks/:webhookID", projectController.UpdateWebhook)
+		projectGroup.DELETE("/:id/webhooks/:webhookID", projectController.DeleteWebhook)
 
 		// 项目成员管理 - 需要群组管理员权限
 		memberGroup := projectGroup.Group("/member")
@@ -225,44 +399,106 @@ func registerFileRoutes(
 	apiGroup *gin.RouterGroup,
 	fileRepo repository.FileRepository,
 	projectRepo repository.ProjectRepository,
+	groupRepo repository.GroupRepository,
+	userRepo repository.UserRepository,
 	statRepo repository.StorageStatRepository,
+	auditRepo repository.AuditRepository,
+	statQueue *service.StorageStatQueue,
+	webhooks *service.WebhookDispatcher,
+	idempotency service.IdempotencyStore,
 	minioClient *minio.Client,
+	mailer service.Mailer,
 	jwtMiddleware *middleware.JWTAuthMiddleware,
 	authMiddleware *middleware.AuthMiddleware,
+	streamingMiddleware gin.HandlerFunc,
 	authService service.AuthService,
 	db *gorm.DB,
 ) {
 	// 创建文件服务
-	fileService := service.NewFileService(fileRepo, projectRepo, statRepo, minioClient, authService, db)
+	fileService := service.NewFileService(fileRepo, projectRepo, statRepo, auditRepo, statQueue, webhooks, idempotency, minioClient, authService, mailer, db)
+
+	projectService := service.NewProjectService(projectRepo, groupRepo, userRepo, statRepo, authService, db, minioClient)
 
 	// 创建文件控制器
-	fileController := NewFileController(fileService, nil, authService)
+	fileController := NewFileController(fileService, projectService, authService)
 
 	// 定义文件中间件辅助函数
 	getFileGroupID := func(c *gin.Context) (string, error) {
 		return middleware.GetGroupIDFromParam(c)
 	}
 
+	// 只读路由 - 项目开启匿名读取时无需登录，其他情况与普通文件路由一样需要认证
+	publicReadMiddleware := middleware.NewPublicReadMiddleware(jwtMiddleware, projectRepo)
+	getFileProjectID := middleware.GetFileProjectIDFunc(fileRepo)
+	getListProjectID := func(c *gin.Context) (string, error) {
+		return c.Query("project_id"), nil
+	}
+	fileReadGroup := apiGroup.Group("/file")
+	{
+		fileReadGroup.GET("/download/:id", streamingMiddleware, publicReadMiddleware.AllowPublicRead(getFileProjectID), authMiddleware.Authorize("files", "read", getFileGroupID), fileController.Download)
+		fileReadGroup.GET("/download-folder/:id", streamingMiddleware, publicReadMiddleware.AllowPublicRead(getFileProjectID), authMiddleware.Authorize("files", "read", getFileGroupID), fileController.DownloadFolder)
+		fileReadGroup.GET("/:id/download-manifest", streamingMiddleware, publicReadMiddleware.AllowPublicRead(getFileProjectID), authMiddleware.Authorize("files", "read", getFileGroupID), fileController.GetDownloadManifest)
+		fileReadGroup.GET("/detail/:id", publicReadMiddleware.AllowPublicRead(getFileProjectID), authMiddleware.Authorize("files", "read", getFileGroupID), fileController.GetFileDetail)
+		fileReadGroup.GET("/:id/thumbnail", publicReadMiddleware.AllowPublicRead(getFileProjectID), authMiddleware.Authorize("files", "read", getFileGroupID), fileController.GetThumbnail)
+		fileReadGroup.GET("/list", publicReadMiddleware.AllowPublicRead(getListProjectID), authMiddleware.Authorize("files", "read", getFileGroupID), fileController.ListFiles)
+	}
+
 	// 文件相关路由
 	fileGroup := apiGroup.Group("/file")
 	fileGroup.Use(jwtMiddleware.AuthMiddleware())
 	{
 		// 文件管理
-		fileGroup.POST("/upload", authMiddleware.Authorize("files", "create", getFileGroupID), fileController.Upload)
-		fileGroup.GET("/download/:id", authMiddleware.Authorize("files", "read", getFileGroupID), fileController.Download)
+		fileGroup.POST("/upload", streamingMiddleware, authMiddleware.Authorize("files", "create", getFileGroupID), fileController.Upload)
+		fileGroup.GET("/upload-config", fileController.GetUploadConfig)
+		fileGroup.GET("/search", fileController.SearchFiles)
+		fileGroup.POST("/upload-folder", streamingMiddleware, authMiddleware.Authorize("files", "create", getFileGroupID), fileController.UploadFolder)
 		fileGroup.GET("/delete/:id", authMiddleware.Authorize("files", "delete", getFileGroupID), fileController.DeleteFile)
 		fileGroup.GET("/versions/:id", authMiddleware.Authorize("files", "read", getFileGroupID), fileController.GetFileVersions)
-		fileGroup.GET("/list", authMiddleware.Authorize("files", "read", getFileGroupID), fileController.ListFiles)
+		fileGroup.GET("/public-url/:id", authMiddleware.Authorize("files", "read", getFileGroupID), fileController.GetPublicURL)
+		fileGroup.POST("/rename", authMiddleware.Authorize("files", "update", getFileGroupID), fileController.RenameFile)
+		fileGroup.POST("/watermark", authMiddleware.Authorize("files", "update", getFileGroupID), fileController.SetWatermark)
+		fileGroup.GET("/:id/audit", fileController.GetFileAuditLogs)
+		fileGroup.POST("/:id/rollback", fileController.RollbackFile)
+		fileGroup.POST("/:id/copy", fileController.CopyFile)
+		fileGroup.POST("/batch/move", fileController.BatchMoveFiles)
+		fileGroup.GET("/:id/versions/:version/download", streamingMiddleware, fileController.DownloadFileVersion)
+		fileGroup.POST("/:id/versions/prune", fileController.PruneFileVersions)
+		fileGroup.GET("/:id/tags", fileController.ListFileTags)
+		fileGroup.POST("/:id/tags", fileController.AddFileTag)
+		fileGroup.DELETE("/:id/tags/:tag", fileController.RemoveFileTag)
+		fileGroup.GET("/:id/denies", fileController.ListFileDenies)
+		fileGroup.POST("/:id/deny", fileController.DenyFileAccess)
+		fileGroup.DELETE("/:id/deny/:user_id", fileController.RemoveFileDeny)
+		fileGroup.GET("/:id/permissions", fileController.ListFolderPermissions)
+		fileGroup.POST("/:id/permissions", fileController.GrantFolderPermission)
+		fileGroup.DELETE("/:id/permissions/:user_id", fileController.RevokeFolderPermission)
+		fileGroup.GET("/shares/mine", fileController.ListMyShares)
+
+		// 预签名直传
+		fileGroup.POST("/presign/upload", authMiddleware.Authorize("files", "create", getFileGroupID), fileController.GetPresignedUploadURL)
+		fileGroup.POST("/presign/confirm", authMiddleware.Authorize("files", "create", getFileGroupID), fileController.ConfirmPresignedUpload)
+	}
+
+	// 项目内重复文件
+	projectFileGroup := apiGroup.Group("/project")
+	projectFileGroup.Use(jwtMiddleware.AuthMiddleware())
+	{
+		projectFileGroup.GET("/:id/duplicates", fileController.FindDuplicates)
+		projectFileGroup.POST("/:id/duplicates/resolve", fileController.ResolveDuplicates)
+		projectFileGroup.GET("/:id/tags", fileController.ListProjectTags)
+		projectFileGroup.GET("/:id/share-stats", fileController.GetProjectShareStats)
 	}
 
 	// 文件分享相关路由
 	shareGroup := apiGroup.Group("/share")
 	{
-		// 创建分享需要认证
+		// 创建与撤销分享需要认证
 		shareGroup.POST("", jwtMiddleware.AuthMiddleware(), fileController.CreateShare)
+		shareGroup.DELETE("/:id", jwtMiddleware.AuthMiddleware(), fileController.RevokeShare)
 
 		// 获取分享信息与下载分享文件不需要认证
 		shareGroup.GET("/:code", fileController.GetShareInfo)
-		shareGroup.POST("/download", fileController.DownloadSharedFile)
+		shareGroup.POST("/download", streamingMiddleware, fileController.DownloadSharedFile)
+		shareGroup.GET("/:code/download", streamingMiddleware, fileController.DownloadSharedFileByLink)
 	}
 }
@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/service"
+)
+
+// testShareFileService 分享码 "abc123" 的密码为 "secret"，分享码 "used" 已达到下载次数限制
+type testShareFileService struct {
+	service.FileService
+	passwords []string
+}
+
+func (s *testShareFileService) DownloadSharedFile(ctx context.Context, shareCode, password string) (io.ReadCloser, *entity.File, error) {
+	s.passwords = append(s.passwords, password)
+	if shareCode == "used" {
+		return nil, nil, service.ErrShareLimitReached
+	}
+	if shareCode != "abc123" {
+		return nil, nil, service.ErrShareNotFound
+	}
+	if password != "secret" {
+		return nil, nil, service.ErrSharePasswordIncorrect
+	}
+	content := "shared content"
+	return io.NopCloser(strings.NewReader(content)), &entity.File{FileName: "report.txt", MimeType: "text/plain", FileSize: int64(len(content))}, nil
+}
+
+// TestDownloadSharedFileByLink GET 分享链接通过查询参数传入密码并直接输出文件，原有的 POST 接口保持可用，
+// 达到下载次数限制时返回403
+func TestDownloadSharedFileByLink(t *testing.T) {
+	fileService := &testShareFileService{}
+	controller := NewFileController(fileService, nil, nil)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/oss/share/download", controller.DownloadSharedFile)
+	r.GET("/api/oss/share/:code/download", controller.DownloadSharedFileByLink)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   int
+	}{
+		{"链接携带正确密码", http.MethodGet, "/api/oss/share/abc123/download?password=secret", "", http.StatusOK},
+		{"链接携带错误密码", http.MethodGet, "/api/oss/share/abc123/download?password=wrong", "", http.StatusUnauthorized},
+		{"链接缺少密码", http.MethodGet, "/api/oss/share/abc123/download", "", http.StatusUnauthorized},
+		{"不存在的分享码", http.MethodGet, "/api/oss/share/nope/download?password=secret", "", http.StatusNotFound},
+		{"已达到下载次数限制", http.MethodGet, "/api/oss/share/used/download", "", http.StatusForbidden},
+		{"POST 携带正确密码", http.MethodPost, "/api/oss/share/download", `{"share_code":"abc123","password":"secret"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+		if tt.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s: 返回 %d，应为 %d: %s", tt.name, w.Code, tt.want, w.Body.String())
+			continue
+		}
+		if tt.want != http.StatusOK {
+			continue
+		}
+		if w.Body.String() != "shared content" {
+			t.Errorf("%s: 下载内容为 %q", tt.name, w.Body.String())
+		}
+		if disposition := w.Header().Get("Content-Disposition"); !strings.Contains(disposition, "attachment") || !strings.Contains(disposition, "report.txt") {
+			t.Errorf("%s: Content-Disposition 为 %q", tt.name, disposition)
+		}
+		// 下载内容来自用户上传，禁止浏览器嗅探内容类型和执行其中的脚本
+		if w.Header().Get("X-Content-Type-Options") != "nosniff" || w.Header().Get("Content-Security-Policy") != "default-src 'none'; sandbox" {
+			t.Errorf("%s: 下载响应缺少防嗅探响应头: %v", tt.name, w.Header())
+		}
+	}
+
+	want := []string{"secret", "wrong", "", "secret", "", "secret"}
+	if strings.Join(fileService.passwords, ",") != strings.Join(want, ",") {
+		t.Fatalf("服务收到的密码为 %q，应为 %q", fileService.passwords, want)
+	}
+}
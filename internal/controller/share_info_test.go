@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/service"
+)
+
+// testShareInfoService 返回固定分享信息的文件服务
+type testShareInfoService struct {
+	service.FileService
+	share *entity.FileShare
+}
+
+func (s *testShareInfoService) GetShareInfo(ctx context.Context, shareCode string) (*entity.FileShare, error) {
+	if shareCode != s.share.ShareCode {
+		return nil, service.ErrShareNotFound
+	}
+	share := *s.share
+	return &share, nil
+}
+
+// TestGetShareInfoConditional 携带上次响应的ETag请求分享信息时返回304，下载次数变化后ETag随之改变
+func TestGetShareInfoConditional(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	fileService := &testShareInfoService{share: &entity.FileShare{
+		ID:            "share-1",
+		FileID:        "file-1",
+		ShareCode:     "abc123",
+		DownloadLimit: 5,
+		CreatedAt:     created,
+		UpdatedAt:     created,
+		File:          entity.File{FileName: "report.txt", FileSize: 7, UpdatedAt: created},
+	}}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/oss/share/:code", NewFileController(fileService, nil, nil).GetShareInfo)
+
+	get := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/oss/share/abc123", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	first := get("", "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("首次请求返回 %d、ETag %q，应返回200和ETag", first.Code, etag)
+	}
+	if lastModified := first.Header().Get("Last-Modified"); lastModified != created.Format(http.TimeFormat) {
+		t.Fatalf("Last-Modified 为 %q，应为 %q", lastModified, created.Format(http.TimeFormat))
+	}
+
+	if w := get("If-None-Match", etag); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Fatalf("携带相同ETag返回 %d，应返回无内容的304", w.Code)
+	}
+	if w := get("If-None-Match", `W/"other", `+etag); w.Code != http.StatusNotModified {
+		t.Fatalf("ETag列表中包含当前ETag时返回 %d，应返回304", w.Code)
+	}
+	if w := get("If-Modified-Since", created.Format(http.TimeFormat)); w.Code != http.StatusNotModified {
+		t.Fatalf("未修改时按 If-Modified-Since 返回 %d，应返回304", w.Code)
+	}
+
+	// 下载一次后分享信息变化，原ETag失效
+	fileService.share.DownloadCount = 1
+	fileService.share.UpdatedAt = created.Add(time.Minute)
+	changed := get("If-None-Match", etag)
+	if changed.Code != http.StatusOK {
+		t.Fatalf("下载次数变化后携带旧ETag返回 %d，应返回200", changed.Code)
+	}
+	newETag := changed.Header().Get("ETag")
+	if newETag == "" || newETag == etag {
+		t.Fatalf("下载次数变化后ETag仍为 %q", newETag)
+	}
+	if w := get("If-Modified-Since", created.Format(http.TimeFormat)); w.Code != http.StatusOK {
+		t.Fatalf("修改后按旧的 If-Modified-Since 返回 %d，应返回200", w.Code)
+	}
+	if w := get("If-None-Match", newETag); w.Code != http.StatusNotModified {
+		t.Fatalf("携带新ETag返回 %d，应返回304", w.Code)
+	}
+}
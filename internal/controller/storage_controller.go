@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"oss-backend/internal/service"
+	"oss-backend/pkg/common"
+)
+
+// StorageController 存储管理控制器
+type StorageController struct {
+	fileService service.FileService
+}
+
+// NewStorageController 创建存储管理控制器
+func NewStorageController(fileService service.FileService) *StorageController {
+	return &StorageController{
+		fileService: fileService,
+	}
+}
+
+// ApplyLifecycle 重新设置存储桶生命周期规则
+// @Summary 重新设置存储桶生命周期规则
+// @Description 按当前配置为所有群组已创建的存储桶重新设置临时对象（temp/ 前缀）的过期规则，保留天数为0时移除该规则；存储桶上的其他生命周期规则保持不变
+// @Tags 系统管理员API
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Success 200 {object} common.Response{data=dto.BucketLifecycleResponse} "成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/admin/storage/lifecycle [post]
+func (c *StorageController) ApplyLifecycle(ctx *gin.Context) {
+	result, err := c.fileService.ApplyTempLifecycle(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(result))
+}
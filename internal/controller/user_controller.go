@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -52,13 +53,15 @@ func (c *UserController) Register(ctx *gin.Context) {
 
 // Login 用户登录
 // @Summary 用户登录
-// @Description 用户登录并获取令牌
+// @Description 用户登录并获取令牌，同一邮箱在同一IP上连续登录失败达到上限后暂时锁定；开启两步验证的用户只返回 two_factor_required 和 challenge_token，需再调用 /api/oss/user/2fa/verify 提交验证码
 // @Tags 用户模块
 // @Accept json
 // @Produce json
 // @Param request body dto.UserLoginRequest true "登录信息"
 // @Success 200 {object} common.Response{data=dto.LoginResponse} "成功"
 // @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 403 {object} common.Response "邮箱尚未验证"
+// @Failure 429 {object} common.Response "登录失败次数过多，账号暂时锁定"
 // @Failure 500 {object} common.Response "内部服务器错误"
 // @Router /api/oss/user/login [post]
 func (c *UserController) Login(ctx *gin.Context) {
@@ -73,6 +76,14 @@ func (c *UserController) Login(ctx *gin.Context) {
 
 	result, err := c.userService.Login(ctx, &req, clientIP)
 	if err != nil {
+		if errors.Is(err, service.ErrEmailNotVerified) {
+			ctx.JSON(http.StatusForbidden, common.ErrorResponse(err.Error()))
+			return
+		}
+		if errors.Is(err, service.ErrAccountLocked) {
+			ctx.JSON(http.StatusTooManyRequests, common.ErrorResponse(err.Error()))
+			return
+		}
 		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
 		return
 	}
@@ -80,6 +91,185 @@ func (c *UserController) Login(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, common.SuccessResponse(result))
 }
 
+// oauthStateCookie 保存第三方登录 nonce 的Cookie，回调时与 state 比对
+const oauthStateCookie = "oss_oauth_state"
+
+// OAuthLogin 跳转到第三方登录
+// @Summary 跳转到第三方登录
+// @Description 重定向到第三方登录提供方（google、github 或配置的通用OIDC提供方）的授权页面，并设置校验回调用的Cookie
+// @Tags 用户模块
+// @Param provider path string true "提供方名称，即配置 oauth.providers 下的键"
+// @Success 302 "重定向到提供方授权页面"
+// @Failure 404 {object} common.Response "未配置该提供方"
+// @Failure 502 {object} common.Response "无法读取提供方配置"
+// @Router /api/oss/auth/oauth/{provider}/login [get]
+func (c *UserController) OAuthLogin(ctx *gin.Context) {
+	authURL, nonce, err := c.userService.OAuthAuthURL(ctx, ctx.Param("provider"))
+	if err != nil {
+		respondOAuthError(ctx, err)
+		return
+	}
+
+	secure := ctx.Request.TLS != nil || ctx.GetHeader("X-Forwarded-Proto") == "https"
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(oauthStateCookie, nonce, 600, "/api/oss/auth/oauth/", "", secure, true)
+	ctx.Redirect(http.StatusFound, authURL)
+}
+
+// OAuthCallback 第三方登录回调
+// @Summary 第三方登录回调
+// @Description 第三方登录提供方授权后的回调地址：校验 state，用授权码换取用户信息，按已验证的邮箱登录本地账号（oauth.auto_provision 开启时自动创建并分配普通成员角色），返回与本地登录相同的响应；账号开启两步验证时返回两步验证挑战
+// @Tags 用户模块
+// @Produce json
+// @Param provider path string true "提供方名称"
+// @Param code query string true "授权码"
+// @Param state query string true "登录时生成的 state"
+// @Success 200 {object} common.Response{data=dto.LoginResponse} "成功"
+// @Failure 400 {object} common.Response "state 无效或已过期，或提供方拒绝授权"
+// @Failure 403 {object} common.Response "邮箱未验证、没有对应账号或账号已禁用"
+// @Failure 404 {object} common.Response "未配置该提供方"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Failure 502 {object} common.Response "与提供方交互失败"
+// @Router /api/oss/auth/oauth/{provider}/callback [get]
+func (c *UserController) OAuthCallback(ctx *gin.Context) {
+	// state 只能使用一次，无论结果如何都清除
+	nonce, _ := ctx.Cookie(oauthStateCookie)
+	ctx.SetCookie(oauthStateCookie, "", -1, "/api/oss/auth/oauth/", "", false, true)
+
+	if providerErr := ctx.Query("error"); providerErr != "" {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse("第三方登录被拒绝: "+providerErr))
+		return
+	}
+
+	result, err := c.userService.OAuthLogin(ctx, ctx.Param("provider"), ctx.Query("code"), ctx.Query("state"), nonce, ctx.ClientIP())
+	if err != nil {
+		respondOAuthError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(result))
+}
+
+// respondOAuthError 将第三方登录的错误转换为响应
+func respondOAuthError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrOAuthProviderNotFound):
+		ctx.JSON(http.StatusNotFound, common.ErrorResponse(err.Error()))
+	case errors.Is(err, service.ErrInvalidOAuthState):
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+	case errors.Is(err, service.ErrOAuthFailed):
+		ctx.JSON(http.StatusBadGateway, common.ErrorResponse(err.Error()))
+	case errors.Is(err, service.ErrOAuthEmailNotVerified), errors.Is(err, service.ErrOAuthUserNotFound), errors.Is(err, service.ErrOAuthAccountDisabled):
+		ctx.JSON(http.StatusForbidden, common.ErrorResponse(err.Error()))
+	default:
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("第三方登录失败: "+err.Error()))
+	}
+}
+
+// VerifyEmail 验证邮箱
+// @Summary 验证邮箱
+// @Description 打开注册验证邮件中的链接激活账号
+// @Tags 用户模块
+// @Produce json
+// @Param token query string true "邮箱验证令牌"
+// @Success 200 {object} common.Response "成功"
+// @Failure 400 {object} common.Response "验证链接无效或已过期"
+// @Router /api/oss/user/verify-email [get]
+func (c *UserController) VerifyEmail(ctx *gin.Context) {
+	token := ctx.Query("token")
+	if token == "" {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse("缺少验证令牌"))
+		return
+	}
+
+	if err := c.userService.VerifyEmail(ctx, token); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(nil))
+}
+
+// ResendVerificationEmail 重新发送验证邮件
+// @Summary 重新发送验证邮件
+// @Description 为待验证的账号重新发送邮箱验证邮件，邮箱未注册时同样返回成功
+// @Tags 用户模块
+// @Accept json
+// @Produce json
+// @Param request body dto.ResendVerificationRequest true "注册邮箱"
+// @Success 200 {object} common.Response "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Router /api/oss/user/verify-email/resend [post]
+func (c *UserController) ResendVerificationEmail(ctx *gin.Context) {
+	var req dto.ResendVerificationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	if err := c.userService.ResendVerificationEmail(ctx, req.Email); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(nil))
+}
+
+// RefreshToken 刷新令牌
+// @Summary 刷新令牌
+// @Description 使用刷新令牌换取新的访问令牌和刷新令牌
+// @Tags 用户模块
+// @Accept json
+// @Produce json
+// @Param request body dto.RefreshTokenRequest true "刷新令牌"
+// @Success 200 {object} common.Response{data=dto.LoginResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "刷新令牌无效"
+// @Router /api/oss/user/refresh [post]
+func (c *UserController) RefreshToken(ctx *gin.Context) {
+	var req dto.RefreshTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	result, err := c.userService.RefreshToken(ctx, req.RefreshToken)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(result))
+}
+
+// Logout 注销登录
+// @Summary 注销登录
+// @Description 注销当前访问令牌，可选同时注销刷新令牌
+// @Tags 用户模块
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param request body dto.LogoutRequest false "注销信息"
+// @Success 200 {object} common.Response "成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/user/logout [post]
+func (c *UserController) Logout(ctx *gin.Context) {
+	var req dto.LogoutRequest
+	// 请求体可选
+	_ = ctx.ShouldBindJSON(&req)
+
+	tokenID := ctx.GetString("tokenID")
+	expiresAt := ctx.GetTime("tokenExpiresAt")
+
+	if err := c.userService.Logout(ctx, tokenID, expiresAt, req.RefreshToken); err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(nil))
+}
+
 // GetUserInfo 获取用户信息
 // @Summary 获取用户信息
 // @Description 获取当前登录用户的详细信息
@@ -109,6 +299,33 @@ func (c *UserController) GetUserInfo(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, common.SuccessResponse(userInfo))
 }
 
+// GetUserPermissions 获取当前用户的权限汇总
+// @Summary 获取当前用户的权限汇总
+// @Description 一次返回当前用户的系统角色、各群组和项目中的角色、对项目文件可执行的操作以及直接授予的权限策略，前端据此决定显示哪些操作
+// @Tags 用户模块
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Success 200 {object} common.Response{data=dto.UserPermissionsResponse} "成功"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/user/permissions [get]
+func (c *UserController) GetUserPermissions(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+
+	permissions, err := c.userService.GetUserPermissions(ctx, userID.(string))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(permissions))
+}
+
 // UpdateUserInfo 更新用户信息
 // @Summary 更新用户信息
 // @Description 更新当前用户的基本信息
@@ -146,6 +363,257 @@ func (c *UserController) UpdateUserInfo(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, common.SuccessResponse(nil))
 }
 
+// UploadAvatar 上传头像
+// @Summary 上传头像
+// @Description 上传头像图片并设为当前用户的头像，支持 JPEG、PNG、GIF 和 WebP，大小上限由 avatar.max_size 配置（默认2MB）；替换后删除原来上传的头像
+// @Tags 用户模块
+// @Accept multipart/form-data
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param file formData file true "头像图片"
+// @Success 200 {object} common.Response{data=dto.UserAvatarResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误或图片无效"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/user/avatar [post]
+func (c *UserController) UploadAvatar(ctx *gin.Context) {
+	// 从上下文中获取用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	userID := userIDValue.(string)
+
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse("获取上传文件失败: "+err.Error()))
+		return
+	}
+
+	avatar, err := c.userService.UploadAvatar(ctx, userID, file)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidAvatar) {
+			ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(dto.UserAvatarResponse{Avatar: avatar}))
+}
+
+// GetAvatar 获取头像
+// @Summary 获取头像
+// @Description 获取用户上传的头像图片，即上传头像接口返回的URL，无需登录；头像被替换后原URL返回404
+// @Tags 用户模块
+// @Produce image/jpeg,image/png,image/gif,image/webp
+// @Param user_id path string true "用户ID"
+// @Param name path string true "头像文件名"
+// @Success 200 {file} binary "头像图片"
+// @Failure 404 {object} common.Response "头像不存在"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/user/avatar/{user_id}/{name} [get]
+func (c *UserController) GetAvatar(ctx *gin.Context) {
+	reader, size, contentType, err := c.userService.OpenAvatar(ctx, ctx.Param("user_id"), ctx.Param("name"))
+	if err != nil {
+		if errors.Is(err, service.ErrAvatarNotFound) {
+			ctx.JSON(http.StatusNotFound, common.ErrorResponse(err.Error()))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("获取头像失败: "+err.Error()))
+		return
+	}
+	defer reader.Close()
+
+	// 每次上传使用新的URL，内容不会变化
+	ctx.Header("Cache-Control", "public, max-age=31536000, immutable")
+	ctx.DataFromReader(http.StatusOK, size, contentType, reader, nil)
+}
+
+// EnableTOTP 开始开启两步验证
+// @Summary 开始开启两步验证
+// @Description 生成新的TOTP密钥，返回密钥和 otpauth URI（可生成二维码供身份验证器应用扫描）；需调用确认接口提交一次验证码后才生效，重复调用会替换尚未确认的密钥。需要配置加密主密钥
+// @Tags 用户模块
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Success 200 {object} common.Response{data=dto.TOTPSetupResponse} "成功"
+// @Failure 400 {object} common.Response "未配置加密主密钥"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 409 {object} common.Response "已开启两步验证"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/user/2fa/enable [post]
+func (c *UserController) EnableTOTP(ctx *gin.Context) {
+	// 从上下文中获取用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+
+	result, err := c.userService.EnableTOTP(ctx, userIDValue.(string))
+	if err != nil {
+		respondTOTPError(ctx, "开启两步验证失败", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(result))
+}
+
+// ConfirmTOTP 确认开启两步验证
+// @Summary 确认开启两步验证
+// @Description 提交身份验证器应用生成的验证码，开启两步验证并返回恢复码；恢复码只返回这一次，每个可代替验证码使用一次
+// @Tags 用户模块
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param request body dto.TOTPCodeRequest true "验证码"
+// @Success 200 {object} common.Response{data=dto.TOTPRecoveryCodesResponse} "成功"
+// @Failure 400 {object} common.Response "验证码无效或尚未获取密钥"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 409 {object} common.Response "已开启两步验证"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/user/2fa/confirm [post]
+func (c *UserController) ConfirmTOTP(ctx *gin.Context) {
+	var req dto.TOTPCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	// 从上下文中获取用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+
+	result, err := c.userService.ConfirmTOTP(ctx, userIDValue.(string), req.Code)
+	if err != nil {
+		respondTOTPError(ctx, "开启两步验证失败", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(result))
+}
+
+// DisableTOTP 关闭两步验证
+// @Summary 关闭两步验证
+// @Description 提交验证码或恢复码关闭两步验证，同时清除密钥和恢复码
+// @Tags 用户模块
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param request body dto.TOTPCodeRequest true "验证码或恢复码"
+// @Success 200 {object} common.Response "成功"
+// @Failure 400 {object} common.Response "验证码无效或未开启两步验证"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/user/2fa/disable [post]
+func (c *UserController) DisableTOTP(ctx *gin.Context) {
+	var req dto.TOTPCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	// 从上下文中获取用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+
+	if err := c.userService.DisableTOTP(ctx, userIDValue.(string), req.Code); err != nil {
+		respondTOTPError(ctx, "关闭两步验证失败", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(nil))
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码
+// @Summary 重新生成恢复码
+// @Description 提交验证码重新生成恢复码，原有的恢复码全部失效；不接受恢复码
+// @Tags 用户模块
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param request body dto.TOTPCodeRequest true "验证码"
+// @Success 200 {object} common.Response{data=dto.TOTPRecoveryCodesResponse} "成功"
+// @Failure 400 {object} common.Response "验证码无效或未开启两步验证"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/user/2fa/recovery-codes [post]
+func (c *UserController) RegenerateRecoveryCodes(ctx *gin.Context) {
+	var req dto.TOTPCodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	// 从上下文中获取用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+
+	result, err := c.userService.RegenerateRecoveryCodes(ctx, userIDValue.(string), req.Code)
+	if err != nil {
+		respondTOTPError(ctx, "生成恢复码失败", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(result))
+}
+
+// VerifyTOTP 提交两步验证码完成登录
+// @Summary 提交两步验证码完成登录
+// @Description 开启两步验证的用户登录时，使用登录接口返回的 challenge_token 和验证码（或恢复码）换取访问令牌；挑战令牌5分钟内有效且只能成功使用一次，验证失败计入登录失败次数
+// @Tags 用户模块
+// @Accept json
+// @Produce json
+// @Param request body dto.TOTPVerifyRequest true "挑战令牌和验证码"
+// @Success 200 {object} common.Response{data=dto.LoginResponse} "成功"
+// @Failure 400 {object} common.Response "验证码无效"
+// @Failure 401 {object} common.Response "挑战令牌无效或已过期"
+// @Failure 429 {object} common.Response "失败次数过多，账号暂时锁定"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/user/2fa/verify [post]
+func (c *UserController) VerifyTOTP(ctx *gin.Context) {
+	var req dto.TOTPVerifyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	result, err := c.userService.VerifyTOTP(ctx, req.ChallengeToken, req.Code, ctx.ClientIP())
+	if err != nil {
+		respondTOTPError(ctx, "两步验证失败", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(result))
+}
+
+// respondTOTPError 将两步验证相关的错误转换为响应
+func respondTOTPError(ctx *gin.Context, message string, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidTwoFactorChallenge):
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse(err.Error()))
+	case errors.Is(err, service.ErrAccountLocked):
+		ctx.JSON(http.StatusTooManyRequests, common.ErrorResponse(err.Error()))
+	case errors.Is(err, service.ErrTOTPAlreadyEnabled):
+		ctx.JSON(http.StatusConflict, common.ErrorResponse(err.Error()))
+	case errors.Is(err, service.ErrInvalidTOTPCode), errors.Is(err, service.ErrTOTPNotEnabled), errors.Is(err, service.ErrEncryptionNotConfigured):
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(message+": "+err.Error()))
+	default:
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse(message+": "+err.Error()))
+	}
+}
+
 // UpdatePassword 更新密码
 // @Summary 更新密码
 // @Description 更新当前用户的密码
@@ -192,10 +660,11 @@ func (c *UserController) UpdatePassword(ctx *gin.Context) {
 // @Param Authorization header string true "Bearer {{token}}"
 // @Param email query string false "用户邮箱，模糊查询"
 // @Param name query string false "用户姓名，模糊查询"
-// @Param status query int false "状态：1-正常，2-禁用，3-锁定"
+// @Param status query int false "状态：1-正常，2-禁用，3-锁定，5-已删除"
+// @Param include_deleted query bool false "是否包含已删除的用户，默认不包含"
 // @Param page query int false "页码，默认1"
 // @Param size query int false "每页数量，默认10"
-// @Success 200 {object} common.Response{data=dto.UserListResponse} "成功"
+// @Success 200 {object} common.Response{data=dto.PageResult[dto.UserResponse]} "成功"
 // @Failure 401 {object} common.Response "未授权"
 // @Failure 500 {object} common.Response "内部服务器错误"
 // @Router /api/oss/user/list [get]
@@ -251,6 +720,119 @@ func (c *UserController) UpdateUserStatus(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, common.SuccessResponse(nil))
 }
 
+// DeleteUser 删除用户
+// @Summary 删除用户
+// @Description 软删除指定用户：释放其邮箱，并移除其角色、授权和群组/项目成员关系，不能删除自己（需要系统管理员权限）
+// @Tags 系统管理员API
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "用户ID"
+// @Success 200 {object} common.Response "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Router /api/oss/user/{id} [delete]
+func (c *UserController) DeleteUser(ctx *gin.Context) {
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+
+	id := ctx.Param("id")
+	if id == userIDValue.(string) {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse("不能删除自己的账号"))
+		return
+	}
+
+	if err := c.userService.DeleteUser(ctx, id); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(nil))
+}
+
+// ReactivateUser 恢复已删除的用户
+// @Summary 恢复已删除的用户
+// @Description 恢复软删除的用户及其原邮箱，原邮箱已被占用时失败；删除时移除的角色和成员关系需重新分配（需要系统管理员权限）
+// @Tags 系统管理员API
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "用户ID"
+// @Success 200 {object} common.Response "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Router /api/oss/user/reactivate/{id} [post]
+func (c *UserController) ReactivateUser(ctx *gin.Context) {
+	if err := c.userService.ReactivateUser(ctx, ctx.Param("id")); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(nil))
+}
+
+// UnlockUser 解除登录锁定
+// @Summary 解除登录锁定
+// @Description 清除用户因登录失败次数过多产生的临时锁定，使其可立即重新登录；不修改账号状态（需要系统管理员权限）
+// @Tags 系统管理员API
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param id path string true "用户ID"
+// @Success 200 {object} common.Response "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Router /api/oss/user/{id}/unlock [post]
+func (c *UserController) UnlockUser(ctx *gin.Context) {
+	if err := c.userService.UnlockUser(ctx, ctx.Param("id")); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(nil))
+}
+
+// BulkUpdateUserStatus 批量更新用户状态
+// @Summary 批量更新用户状态
+// @Description 批量禁用、锁定或恢复用户账号，返回每个用户的处理结果，不能禁用或锁定自己（需要系统管理员权限）
+// @Tags 系统管理员API
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer {{token}}"
+// @Param request body dto.BulkUserStatusRequest true "用户ID列表与目标状态"
+// @Success 200 {object} common.Response{data=dto.BulkUserStatusResponse} "成功"
+// @Failure 400 {object} common.Response "请求参数错误"
+// @Failure 401 {object} common.Response "未授权"
+// @Failure 403 {object} common.Response "权限不足"
+// @Failure 500 {object} common.Response "内部服务器错误"
+// @Router /api/oss/admin/users/status [post]
+func (c *UserController) BulkUpdateUserStatus(ctx *gin.Context) {
+	// 获取当前用户ID
+	userIDValue, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权"))
+		return
+	}
+	adminID := userIDValue.(string)
+
+	var req dto.BulkUserStatusRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, common.ErrorResponse(err.Error()))
+		return
+	}
+
+	result, err := c.userService.BulkUpdateUserStatus(ctx, req.UserIDs, req.Status, adminID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, common.ErrorResponse("批量更新用户状态失败: "+err.Error()))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, common.SuccessResponse(result))
+}
+
 // GetUserRoles 获取用户角色
 // @Summary 获取用户角色
 // @Description 获取指定用户的所有角色
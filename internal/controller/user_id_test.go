@@ -0,0 +1,151 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/spf13/viper"
+
+	"oss-backend/internal/middleware"
+	"oss-backend/internal/model/dto"
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+	"oss-backend/internal/service"
+)
+
+const testJWTSecret = "0123456789abcdef0123456789abcdef"
+
+// testUserRepo 按ID返回固定用户的用户仓库
+type testUserRepo struct {
+	repository.UserRepository
+	users map[string]*entity.User
+}
+
+func (r *testUserRepo) GetByID(ctx context.Context, id string) (*entity.User, error) {
+	return r.users[id], nil
+}
+
+// testAuthService 允许所有操作并记录检查时使用的用户ID
+type testAuthService struct {
+	service.AuthService
+	userIDs []string
+}
+
+func (s *testAuthService) CanUserAccessResource(ctx context.Context, userID, obj, act, domainID string) (bool, error) {
+	s.userIDs = append(s.userIDs, userID)
+	return true, nil
+}
+
+// testFileService 记录上传者ID的文件服务
+type testFileService struct {
+	service.FileService
+	uploaderID string
+}
+
+func (s *testFileService) ResolveUploadPath(ctx context.Context, projectID, parentFolderID, path string) (string, error) {
+	return path, nil
+}
+
+func (s *testFileService) CheckPathPermission(ctx context.Context, projectID, dirPath, userID, action string) (bool, error) {
+	return true, nil
+}
+
+func (s *testFileService) UploadIdempotent(ctx context.Context, projectID, uploaderID, idempotencyKey string, file *multipart.FileHeader, path, declaredHash string) (*entity.File, error) {
+	s.uploaderID = uploaderID
+	return &entity.File{ID: "file-1", ProjectID: projectID, FileName: file.Filename, FullPath: file.Filename, UploaderID: uploaderID}, nil
+}
+
+// testGroupService 记录创建者ID的群组服务
+type testGroupService struct {
+	service.GroupService
+	creatorID string
+}
+
+func (s *testGroupService) CreateGroup(ctx context.Context, req *dto.GroupCreateRequest, creatorID string) error {
+	s.creatorID = creatorID
+	return nil
+}
+
+func (s *testGroupService) GetUserGroups(ctx context.Context, userID string) ([]dto.GroupResponse, error) {
+	return []dto.GroupResponse{{ID: "group-1", Name: "team"}}, nil
+}
+
+// TestUserIDTypeAcrossHandlers 同一令牌依次上传文件和创建群组，认证中间件、授权中间件和各控制器读取到相同的字符串用户ID
+func TestUserIDTypeAcrossHandlers(t *testing.T) {
+	viper.Set("auth.jwt_secret", testJWTSecret)
+	t.Cleanup(func() { viper.Set("auth.jwt_secret", "") })
+
+	const userID = "5f0c9a1e-7b7d-4a57-9d35-0a2f3c1b8e11"
+	userRepo := &testUserRepo{users: map[string]*entity.User{userID: {ID: userID, Status: entity.UserStatusNormal}}}
+	authService := &testAuthService{}
+	fileService := &testFileService{}
+	groupService := &testGroupService{}
+	jwtMiddleware := middleware.NewJWTAuthMiddleware(service.NewMemoryTokenBlacklist(), userRepo)
+	authMiddleware := middleware.NewAuthMiddleware(authService, userRepo, nil)
+	getProjectDomain := func(c *gin.Context) (string, error) {
+		return "project:" + c.PostForm("project_id"), nil
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	api := r.Group("/api/oss", jwtMiddleware.AuthMiddleware())
+	api.POST("/file/upload", authMiddleware.Authorize("files", service.ActionCreate, getProjectDomain), NewFileController(fileService, nil, authService).Upload)
+	api.POST("/group/create", NewGroupController(groupService).CreateGroup)
+
+	claims := middleware.JWTClaims{
+		UserID: userID,
+		Email:  "alice@example.com",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "token-1",
+			Subject:   "alice@example.com",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testJWTSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("project_id", "project-1")
+	part, _ := writer.CreateFormFile("file", "hello.txt")
+	part.Write([]byte("hello"))
+	writer.Close()
+	req := httptest.NewRequest(http.MethodPost, "/api/oss/file/upload", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("上传文件返回 %d: %s", w.Code, w.Body.String())
+	}
+
+	groupBody, _ := json.Marshal(dto.GroupCreateRequest{Name: "team", GroupKey: "team1"})
+	req = httptest.NewRequest(http.MethodPost, "/api/oss/group/create", bytes.NewReader(groupBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("创建群组返回 %d: %s", w.Code, w.Body.String())
+	}
+
+	if len(authService.userIDs) != 1 || authService.userIDs[0] != userID {
+		t.Fatalf("授权检查使用的用户ID为 %v，应为 %s", authService.userIDs, userID)
+	}
+	if fileService.uploaderID != userID {
+		t.Fatalf("上传者ID为 %q，应为 %s", fileService.uploaderID, userID)
+	}
+	if groupService.creatorID != userID {
+		t.Fatalf("群组创建者ID为 %q，应为 %s", groupService.creatorID, userID)
+	}
+}
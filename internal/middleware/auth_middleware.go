@@ -392,6 +392,12 @@ func GetDomainIDFromProjectParam(c *gin.Context) (string, error) {
 // getDomainIDFunc: 获取域ID的函数（如群组ID或项目ID）
 func (m *AuthMiddleware) Authorize(obj string, act string, getDomainIDFunc func(c *gin.Context) (string, error)) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// 项目开启匿名读取时，读操作无需检查权限
+		if act == service.ActionRead && IsPublicRead(c) {
+			c.Next()
+			return
+		}
+
 		// 从上下文获取用户ID
 		userIDValue, exists := c.Get("userID")
 		if !exists {
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CorsConfig 跨域访问配置，对应配置中的 cors
+type CorsConfig struct {
+	AllowedOrigins   []string `mapstructure:"allowed_origins"`   // 允许的来源，如 https://app.example.com；* 表示任意来源，为空表示不允许跨域访问
+	AllowedMethods   []string `mapstructure:"allowed_methods"`   // 允许的请求方法
+	AllowedHeaders   []string `mapstructure:"allowed_headers"`   // 允许携带的请求头
+	ExposedHeaders   []string `mapstructure:"exposed_headers"`   // 允许浏览器读取的响应头
+	AllowCredentials bool     `mapstructure:"allow_credentials"` // 是否允许携带Cookie等凭据
+	MaxAge           int      `mapstructure:"max_age"`           // 预检结果的缓存时间（秒），0表示不发送
+}
+
+// DefaultCorsConfig 默认跨域配置，未配置来源时不允许跨域访问
+func DefaultCorsConfig() CorsConfig {
+	return CorsConfig{
+		AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete},
+		AllowedHeaders: []string{"Authorization", "Content-Type", "Range", "If-Range", "If-None-Match", "If-Modified-Since", "X-Content-SHA256", RequestIDHeader},
+		ExposedHeaders: []string{"Content-Disposition", "Content-Length", "Content-Range", "ETag", RequestIDHeader},
+		MaxAge:         600,
+	}
+}
+
+// Validate 校验跨域配置，允许携带凭据时不能允许任意来源
+func (cfg CorsConfig) Validate() error {
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" && cfg.AllowCredentials {
+			return errors.New("cors.allow_credentials 为 true 时 cors.allowed_origins 不能包含 *")
+		}
+		if origin != "*" && (strings.Contains(origin, "*") || strings.HasSuffix(origin, "/")) {
+			return errors.New("cors.allowed_origins 应为完整的来源，如 https://app.example.com")
+		}
+	}
+	return nil
+}
+
+// Cors 跨域访问中间件
+// 请求的 Origin 在允许列表中时添加跨域响应头；预检请求（带 Access-Control-Request-Method 的 OPTIONS）
+// 直接返回，来源或方法不允许时返回 403。来源不允许的普通请求照常处理，但不添加跨域响应头，由浏览器拦截
+func Cors(cfg CorsConfig) gin.HandlerFunc {
+	anyOrigin := false
+	origins := make(map[string]bool, len(cfg.AllowedOrigins))
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			anyOrigin = true
+			continue
+		}
+		origins[strings.ToLower(origin)] = true
+	}
+	methods := make(map[string]bool, len(cfg.AllowedMethods))
+	for _, method := range cfg.AllowedMethods {
+		methods[strings.ToUpper(method)] = true
+	}
+	allowMethods := strings.Join(cfg.AllowedMethods, ", ")
+	allowHeaders := strings.Join(cfg.AllowedHeaders, ", ")
+	exposeHeaders := strings.Join(cfg.ExposedHeaders, ", ")
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		header.Add("Vary", "Origin")
+		allowed := anyOrigin || origins[strings.ToLower(origin)]
+		if !allowed {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		// 允许任意来源且不携带凭据时返回 *，其余情况返回请求的来源
+		if anyOrigin && !cfg.AllowCredentials {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if cfg.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if exposeHeaders != "" {
+				header.Set("Access-Control-Expose-Headers", exposeHeaders)
+			}
+			c.Next()
+			return
+		}
+
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+		if !methods[strings.ToUpper(c.GetHeader("Access-Control-Request-Method"))] {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		header.Set("Access-Control-Allow-Methods", allowMethods)
+		if allowHeaders != "" {
+			header.Set("Access-Control-Allow-Headers", allowHeaders)
+		}
+		if cfg.MaxAge > 0 {
+			header.Set("Access-Control-Max-Age", strconv.Itoa(cfg.MaxAge))
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serveCors 经过跨域中间件处理请求，请求到达处理函数时返回200
+func serveCors(cfg CorsConfig, method, origin string, headers map[string]string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Cors(cfg))
+	r.Any("/api/oss/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(method, "/api/oss/ping", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCorsOriginMatching(t *testing.T) {
+	cfg := DefaultCorsConfig()
+	cfg.AllowedOrigins = []string{"https://app.example.com", "http://localhost:3000"}
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://app.example.com", true},
+		{"HTTPS://APP.EXAMPLE.COM", true},
+		{"http://localhost:3000", true},
+		{"http://app.example.com", false},
+		{"https://app.example.com:8443", false},
+		{"https://app.example.com.evil.com", false},
+		{"https://evil-app.example.com", false},
+		{"https://app.example.co", false},
+		{"http://localhost:3001", false},
+		{"null", false},
+	}
+	for _, tt := range tests {
+		w := serveCors(cfg, http.MethodGet, tt.origin, nil)
+		if w.Code != http.StatusOK {
+			t.Errorf("来源 %s 的普通请求返回 %d，应照常处理", tt.origin, w.Code)
+		}
+		got := w.Header().Get("Access-Control-Allow-Origin")
+		if tt.want && got != tt.origin {
+			t.Errorf("来源 %s 应被允许，Access-Control-Allow-Origin 为 %q", tt.origin, got)
+		}
+		if !tt.want && got != "" {
+			t.Errorf("来源 %s 不应被允许，Access-Control-Allow-Origin 为 %q", tt.origin, got)
+		}
+		if w.Header().Get("Vary") != "Origin" {
+			t.Errorf("来源 %s 的响应缺少 Vary: Origin", tt.origin)
+		}
+	}
+
+	// 没有 Origin 的请求不添加跨域响应头
+	w := serveCors(cfg, http.MethodGet, "", nil)
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "" || w.Header().Get("Vary") != "" {
+		t.Fatalf("同源请求返回 %d，响应头 %v", w.Code, w.Header())
+	}
+}
+
+func TestCorsPreflight(t *testing.T) {
+	cfg := DefaultCorsConfig()
+	cfg.AllowedOrigins = []string{"https://app.example.com"}
+
+	w := serveCors(cfg, http.MethodOptions, "https://app.example.com", map[string]string{"Access-Control-Request-Method": "delete"})
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("允许的预检请求返回 %d，应为204", w.Code)
+	}
+	if w.Header().Get("Access-Control-Allow-Methods") == "" || w.Header().Get("Access-Control-Allow-Headers") == "" {
+		t.Fatalf("预检响应缺少允许的方法或请求头: %v", w.Header())
+	}
+	if w.Header().Get("Access-Control-Max-Age") != "600" {
+		t.Fatalf("Access-Control-Max-Age 为 %q，应为600", w.Header().Get("Access-Control-Max-Age"))
+	}
+
+	w = serveCors(cfg, http.MethodOptions, "https://app.example.com", map[string]string{"Access-Control-Request-Method": "TRACE"})
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("不允许的方法的预检请求返回 %d，应为403", w.Code)
+	}
+
+	w = serveCors(cfg, http.MethodOptions, "https://evil.example.com", map[string]string{"Access-Control-Request-Method": "GET"})
+	if w.Code != http.StatusForbidden || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("不允许的来源的预检请求返回 %d，响应头 %v", w.Code, w.Header())
+	}
+
+	// 未配置来源时不允许任何跨域访问
+	w = serveCors(DefaultCorsConfig(), http.MethodOptions, "https://app.example.com", map[string]string{"Access-Control-Request-Method": "GET"})
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("未配置来源时预检请求返回 %d，应为403", w.Code)
+	}
+}
+
+func TestCorsAnyOrigin(t *testing.T) {
+	cfg := DefaultCorsConfig()
+	cfg.AllowedOrigins = []string{"*"}
+	w := serveCors(cfg, http.MethodGet, "https://anywhere.example.org", nil)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Fatalf("允许任意来源且不携带凭据时应返回 *，实际为 %q", got)
+	}
+	if w.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Fatalf("未允许凭据时不应返回 Access-Control-Allow-Credentials")
+	}
+
+	cfg.AllowedOrigins = []string{"https://app.example.com"}
+	cfg.AllowCredentials = true
+	w = serveCors(cfg, http.MethodGet, "https://app.example.com", nil)
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" || w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Fatalf("携带凭据时应返回请求的来源，响应头 %v", w.Header())
+	}
+}
+
+func TestCorsConfigValidate(t *testing.T) {
+	tests := []struct {
+		origins     []string
+		credentials bool
+		wantErr     bool
+	}{
+		{nil, false, false},
+		{[]string{"https://app.example.com"}, true, false},
+		{[]string{"*"}, false, false},
+		{[]string{"*"}, true, true},
+		{[]string{"https://*.example.com"}, false, true},
+		{[]string{"https://app.example.com/"}, false, true},
+	}
+	for _, tt := range tests {
+		cfg := CorsConfig{AllowedOrigins: tt.origins, AllowCredentials: tt.credentials}
+		if err := cfg.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("来源 %v、凭据 %v 的校验结果为 %v，是否应报错: %v", tt.origins, tt.credentials, err, tt.wantErr)
+		}
+	}
+}
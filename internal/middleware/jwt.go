@@ -1,19 +1,21 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
+	"gorm.io/gorm"
 
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+	"oss-backend/internal/service"
 	"oss-backend/pkg/common"
 )
 
-// 使用与服务层相同的JWT密钥，实际应用中应从配置文件读取
-var jwtSecret = []byte("oss-backend-secret-key")
-
 // JWTClaims JWT声明
 type JWTClaims struct {
 	UserID string `json:"user_id"`
@@ -22,11 +24,19 @@ type JWTClaims struct {
 }
 
 // JWTAuthMiddleware JWT认证中间件
-type JWTAuthMiddleware struct{}
+type JWTAuthMiddleware struct {
+	jwtSecret []byte
+	blacklist service.TokenBlacklist
+	userRepo  repository.UserRepository
+}
 
-// NewJWTAuthMiddleware 创建JWT认证中间件
-func NewJWTAuthMiddleware() *JWTAuthMiddleware {
-	return &JWTAuthMiddleware{}
+// NewJWTAuthMiddleware 创建JWT认证中间件，使用与服务层相同的配置密钥
+func NewJWTAuthMiddleware(blacklist service.TokenBlacklist, userRepo repository.UserRepository) *JWTAuthMiddleware {
+	return &JWTAuthMiddleware{
+		jwtSecret: service.LoadJWTSecret(),
+		blacklist: blacklist,
+		userRepo:  userRepo,
+	}
 }
 
 // AuthMiddleware 认证中间件
@@ -54,7 +64,7 @@ func (m *JWTAuthMiddleware) AuthMiddleware() gin.HandlerFunc {
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 				return nil, jwt.ErrSignatureInvalid
 			}
-			return jwtSecret, nil
+			return m.jwtSecret, nil
 		})
 
 		if err != nil {
@@ -72,9 +82,58 @@ func (m *JWTAuthMiddleware) AuthMiddleware() gin.HandlerFunc {
 				return
 			}
 
-			// 设置用户ID到上下文
+			// 刷新令牌只能用于换取新令牌，不能访问业务接口
+			if service.IsRefreshTokenSubject(claims.Subject) {
+				c.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权:不能使用刷新令牌访问"))
+				c.Abort()
+				return
+			}
+
+			// 邮箱验证令牌只能用于验证邮箱
+			if service.IsEmailVerificationSubject(claims.Subject) {
+				c.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权:不能使用邮箱验证令牌访问"))
+				c.Abort()
+				return
+			}
+
+			// 两步验证挑战令牌只能用于提交验证码
+			if service.IsTwoFactorChallengeSubject(claims.Subject) {
+				c.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权:不能使用两步验证挑战令牌访问"))
+				c.Abort()
+				return
+			}
+
+			// 检查token是否已注销
+			revoked, err := m.blacklist.IsRevoked(c, claims.ID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, common.ErrorResponse("检查token状态失败"))
+				c.Abort()
+				return
+			}
+			if revoked {
+				c.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权:token已注销"))
+				c.Abort()
+				return
+			}
+
+			// 令牌签发后账号可能被删除、禁用或锁定，每次请求都确认账号状态正常
+			user, err := m.userRepo.GetByID(c, claims.UserID)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusInternalServerError, common.ErrorResponse("检查账号状态失败"))
+				c.Abort()
+				return
+			}
+			if user == nil || user.Status != entity.UserStatusNormal {
+				c.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权:账号已被删除、禁用或锁定"))
+				c.Abort()
+				return
+			}
+
+			// 设置用户ID到上下文，统一为 string 类型，与实体ID一致，各处均以 .(string) 读取
 			c.Set("userID", claims.UserID)
 			c.Set("userEmail", claims.Email)
+			c.Set("tokenID", claims.ID)
+			c.Set("tokenExpiresAt", claims.ExpiresAt.Time)
 			c.Next()
 			return
 		}
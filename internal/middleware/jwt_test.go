@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+	"oss-backend/internal/service"
+)
+
+const testJWTSecret = "0123456789abcdef0123456789abcdef"
+
+// testUserRepo 只支持按ID查找用户的用户仓库，用户不存在时与真实仓库一样返回 gorm.ErrRecordNotFound
+type testUserRepo struct {
+	repository.UserRepository
+	users map[string]*entity.User
+	err   error
+}
+
+func (r *testUserRepo) GetByID(ctx context.Context, id string) (*entity.User, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	user, ok := r.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return user, nil
+}
+
+// newTestAccessToken 为用户签发访问令牌
+func newTestAccessToken(t *testing.T, userID string) string {
+	t.Helper()
+	claims := JWTClaims{
+		UserID: userID,
+		Email:  userID + "@example.com",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "token-" + userID,
+			Subject:   userID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testJWTSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// serveAuth 携带令牌经过认证中间件请求，到达处理函数时返回200
+func serveAuth(t *testing.T, repo repository.UserRepository, token string) int {
+	t.Helper()
+	viper.Set("auth.jwt_secret", testJWTSecret)
+	t.Cleanup(func() { viper.Set("auth.jwt_secret", "") })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(NewJWTAuthMiddleware(service.NewMemoryTokenBlacklist(), repo).AuthMiddleware())
+	r.GET("/api/oss/user/info", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/api/oss/user/info", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+// TestAuthMiddlewareRejectsInactiveUser 签发令牌后被删除、禁用或锁定的用户不能继续使用原令牌
+func TestAuthMiddlewareRejectsInactiveUser(t *testing.T) {
+	repo := &testUserRepo{users: map[string]*entity.User{
+		"user-normal":   {ID: "user-normal", Status: entity.UserStatusNormal},
+		"user-disabled": {ID: "user-disabled", Status: entity.UserStatusDisabled},
+		"user-locked":   {ID: "user-locked", Status: entity.UserStatusLocked},
+		"user-deleted":  {ID: "user-deleted", Status: entity.UserStatusDeleted},
+	}}
+
+	tests := []struct {
+		userID string
+		want   int
+	}{
+		{"user-normal", http.StatusOK},
+		{"user-disabled", http.StatusUnauthorized},
+		{"user-locked", http.StatusUnauthorized},
+		{"user-deleted", http.StatusUnauthorized},
+		{"user-missing", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		if got := serveAuth(t, repo, newTestAccessToken(t, tt.userID)); got != tt.want {
+			t.Errorf("用户 %s 的令牌返回 %d，应为 %d", tt.userID, got, tt.want)
+		}
+	}
+
+	repo.err = errors.New("connection refused")
+	if got := serveAuth(t, repo, newTestAccessToken(t, "user-normal")); got != http.StatusInternalServerError {
+		t.Fatalf("查询账号状态失败时返回 %d，应为500", got)
+	}
+}
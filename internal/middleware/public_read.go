@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"oss-backend/internal/repository"
+	"oss-backend/pkg/common"
+)
+
+// contextKeyPublicRead 上下文标记：本次请求访问的项目开启了匿名读取
+const contextKeyPublicRead = "publicRead"
+
+// projectStatusNormal 项目正常状态（见 entity.Project.Status），归档或删除的项目不开放匿名读取
+const projectStatusNormal = 1
+
+// PublicReadMiddleware 项目匿名读取中间件
+type PublicReadMiddleware struct {
+	jwtMiddleware *JWTAuthMiddleware
+	projectRepo   repository.ProjectRepository
+}
+
+// NewPublicReadMiddleware 创建项目匿名读取中间件
+func NewPublicReadMiddleware(jwtMiddleware *JWTAuthMiddleware, projectRepo repository.ProjectRepository) *PublicReadMiddleware {
+	return &PublicReadMiddleware{
+		jwtMiddleware: jwtMiddleware,
+		projectRepo:   projectRepo,
+	}
+}
+
+// AllowPublicRead 只读接口的条件认证中间件，替代 JWT 认证中间件使用
+// 携带令牌时按正常流程认证；未携带令牌时仅在目标项目开启匿名读取时放行。
+// 项目开启匿名读取时在上下文中标记，后续的读权限检查据此跳过；
+// 项目不存在与未开启匿名读取返回相同的响应，避免泄露私有项目是否存在
+func (m *PublicReadMiddleware) AllowPublicRead(getProjectID func(c *gin.Context) (string, error)) gin.HandlerFunc {
+	authenticate := m.jwtMiddleware.AuthMiddleware()
+	return func(c *gin.Context) {
+		public := m.isPublicProject(c, getProjectID)
+		if public {
+			c.Set(contextKeyPublicRead, true)
+		}
+
+		if c.GetHeader("Authorization") != "" {
+			authenticate(c)
+			return
+		}
+
+		if !public {
+			c.JSON(http.StatusUnauthorized, common.ErrorResponse("未授权:请先登录"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// isPublicProject 判断请求访问的项目是否开启了匿名读取
+func (m *PublicReadMiddleware) isPublicProject(c *gin.Context, getProjectID func(c *gin.Context) (string, error)) bool {
+	projectID, err := getProjectID(c)
+	if err != nil || projectID == "" {
+		return false
+	}
+	project, err := m.projectRepo.GetByID(c, projectID)
+	if err != nil || project == nil {
+		return false
+	}
+	return project.PublicRead && project.Status == projectStatusNormal
+}
+
+// IsPublicRead 当前请求访问的项目是否开启了匿名读取，仅用于读操作的权限判断
+func IsPublicRead(c *gin.Context) bool {
+	return c.GetBool(contextKeyPublicRead)
+}
+
+// GetFileProjectIDFunc 返回按路径参数 id 指定的文件获取所属项目ID的函数
+func GetFileProjectIDFunc(fileRepo repository.FileRepository) func(c *gin.Context) (string, error) {
+	return func(c *gin.Context) (string, error) {
+		file, err := fileRepo.GetByID(c, c.Param("id"))
+		if err != nil || file == nil {
+			return "", err
+		}
+		return file.ProjectID, nil
+	}
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/viper"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+	"oss-backend/internal/service"
+)
+
+// testProjectRepo 按ID返回固定项目的项目仓库
+type testProjectRepo struct {
+	repository.ProjectRepository
+	projects map[string]*entity.Project
+}
+
+func (r *testProjectRepo) GetByID(ctx context.Context, id string) (*entity.Project, error) {
+	return r.projects[id], nil
+}
+
+// testAuthService 只有 allowed 中的用户有权限的授权服务
+type testAuthService struct {
+	service.AuthService
+	allowed map[string]bool
+}
+
+func (s *testAuthService) CanUserAccessResource(ctx context.Context, userID, obj, act, domainID string) (bool, error) {
+	return s.allowed[userID], nil
+}
+
+// TestAllowPublicRead 匿名用户只能读取开启了匿名读取的正常项目，私有、归档和不存在的项目返回相同的401；
+// 写接口即使项目公开也需要认证，登录用户仍按权限检查
+func TestAllowPublicRead(t *testing.T) {
+	viper.Set("auth.jwt_secret", testJWTSecret)
+	t.Cleanup(func() { viper.Set("auth.jwt_secret", "") })
+
+	projectRepo := &testProjectRepo{projects: map[string]*entity.Project{
+		"public":          {ID: "public", PublicRead: true, Status: entity.ProjectStatusNormal},
+		"private":         {ID: "private", Status: entity.ProjectStatusNormal},
+		"public-archived": {ID: "public-archived", PublicRead: true, Status: entity.ProjectStatusArchived},
+	}}
+	userRepo := &testUserRepo{users: map[string]*entity.User{
+		"member":   {ID: "member", Status: entity.UserStatusNormal},
+		"outsider": {ID: "outsider", Status: entity.UserStatusNormal},
+	}}
+	jwtMiddleware := NewJWTAuthMiddleware(service.NewMemoryTokenBlacklist(), userRepo)
+	publicRead := NewPublicReadMiddleware(jwtMiddleware, projectRepo)
+	authMiddleware := NewAuthMiddleware(&testAuthService{allowed: map[string]bool{"member": true}}, userRepo, nil)
+
+	getProjectID := func(c *gin.Context) (string, error) {
+		return c.Query("project_id"), nil
+	}
+	getDomainID := func(c *gin.Context) (string, error) {
+		return "project:" + c.Query("project_id"), nil
+	}
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/file/list", publicRead.AllowPublicRead(getProjectID), authMiddleware.Authorize("files", service.ActionRead, getDomainID), ok)
+	// 写操作误挂在匿名读取中间件之后时，授权中间件也不能放行
+	r.POST("/file/rename", publicRead.AllowPublicRead(getProjectID), authMiddleware.Authorize("files", service.ActionUpdate, getDomainID), ok)
+	writeGroup := r.Group("/file", jwtMiddleware.AuthMiddleware())
+	writeGroup.POST("/upload", authMiddleware.Authorize("files", service.ActionCreate, getDomainID), ok)
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		project string
+		userID  string
+		want    int
+	}{
+		{"匿名读取公开项目", http.MethodGet, "/file/list", "public", "", http.StatusOK},
+		{"匿名读取私有项目", http.MethodGet, "/file/list", "private", "", http.StatusUnauthorized},
+		{"匿名读取归档的公开项目", http.MethodGet, "/file/list", "public-archived", "", http.StatusUnauthorized},
+		{"匿名读取不存在的项目", http.MethodGet, "/file/list", "missing", "", http.StatusUnauthorized},
+		{"匿名上传到公开项目", http.MethodPost, "/file/upload", "public", "", http.StatusUnauthorized},
+		{"匿名重命名公开项目的文件", http.MethodPost, "/file/rename", "public", "", http.StatusUnauthorized},
+		{"成员读取私有项目", http.MethodGet, "/file/list", "private", "member", http.StatusOK},
+		{"非成员读取私有项目", http.MethodGet, "/file/list", "private", "outsider", http.StatusForbidden},
+		{"非成员读取公开项目", http.MethodGet, "/file/list", "public", "outsider", http.StatusOK},
+		{"非成员上传到公开项目", http.MethodPost, "/file/upload", "public", "outsider", http.StatusForbidden},
+		{"非成员重命名公开项目的文件", http.MethodPost, "/file/rename", "public", "outsider", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path+"?project_id="+tt.project, nil)
+		if tt.userID != "" {
+			req.Header.Set("Authorization", "Bearer "+newTestAccessToken(t, tt.userID))
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s: 返回 %d，应为 %d", tt.name, w.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"oss-backend/internal/utils"
+	"oss-backend/pkg/logger"
+)
+
+// RequestIDHeader 请求ID的请求头和响应头
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength 沿用客户端传入的请求ID时允许的最大长度
+const maxRequestIDLength = 128
+
+// RequestLogger 请求日志中间件
+// 沿用请求中合法的 X-Request-ID，否则生成新的ID，并在响应头中返回；带有请求ID的日志记录器
+// 写入请求的 context 和 gin.Context，服务层通过 logger.FromContext 取出。请求结束后记录方法、路径、
+// 状态码、耗时和用户ID，5xx 按 error 级别、4xx 按 warn 级别记录
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = utils.GenerateUUID()
+		}
+		c.Header(RequestIDHeader, requestID)
+		c.Set("requestID", requestID)
+
+		reqLogger := slog.Default().With("request_id", requestID)
+		c.Set(logger.ContextKey, reqLogger)
+		c.Request = c.Request.WithContext(logger.WithContext(c.Request.Context(), reqLogger))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		attrs := []any{
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", status,
+			"latency_ms", time.Since(start).Milliseconds(),
+			"client_ip", c.ClientIP(),
+			"size", max(c.Writer.Size(), 0),
+		}
+		if userID := c.GetString("userID"); userID != "" {
+			attrs = append(attrs, "user_id", userID)
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, "errors", c.Errors.String())
+		}
+		reqLogger.Log(c.Request.Context(), level, "请求完成", attrs...)
+	}
+}
+
+// validRequestID 客户端传入的请求ID只接受长度有限的可打印ASCII字符，避免写入日志和响应头的内容被伪造
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serveSecurity 经过安全响应头中间件请求 path
+func serveSecurity(cfg SecurityConfig, path string, headers map[string]string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(SecurityHeaders(cfg))
+	r.GET("/api/oss/ping", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) })
+	r.GET("/swagger/index.html", func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte("<html></html>"))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// TestSecurityHeaders API响应带有默认的安全响应头，HSTS只在HTTPS请求中发送，CSP只加在HTML响应上
+func TestSecurityHeaders(t *testing.T) {
+	cfg := DefaultSecurityConfig()
+
+	w := serveSecurity(cfg, "/api/oss/ping", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("请求返回 %d", w.Code)
+	}
+	for header, want := range map[string]string{
+		"X-Content-Type-Options": "nosniff",
+		"X-Frame-Options":        "DENY",
+		"Referrer-Policy":        "no-referrer",
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("API响应的 %s 为 %q，应为 %q", header, got, want)
+		}
+	}
+	if got := w.Header().Get("Strict-Transport-Security"); got != "" {
+		t.Errorf("HTTP请求不应发送HSTS，实际为 %q", got)
+	}
+	if got := w.Header().Get("Content-Security-Policy"); got != "" {
+		t.Errorf("JSON响应不应添加CSP，实际为 %q", got)
+	}
+
+	w = serveSecurity(cfg, "/api/oss/ping", map[string]string{"X-Forwarded-Proto": "https"})
+	if got := w.Header().Get("Strict-Transport-Security"); got != "max-age=31536000; includeSubDomains" {
+		t.Errorf("HTTPS请求的HSTS为 %q", got)
+	}
+
+	w = serveSecurity(cfg, "/swagger/index.html", nil)
+	if got := w.Header().Get("Content-Security-Policy"); got != cfg.ContentSecurityPolicy {
+		t.Errorf("HTML响应的CSP为 %q，应为 %q", got, cfg.ContentSecurityPolicy)
+	}
+
+	// 配置覆盖默认值，空字符串表示不发送
+	cfg.FrameOptions = "SAMEORIGIN"
+	cfg.ReferrerPolicy = ""
+	w = serveSecurity(cfg, "/api/oss/ping", nil)
+	if got := w.Header().Get("X-Frame-Options"); got != "SAMEORIGIN" {
+		t.Errorf("覆盖后的 X-Frame-Options 为 %q", got)
+	}
+	if _, ok := w.Header()["Referrer-Policy"]; ok {
+		t.Errorf("配置为空时仍发送了 Referrer-Policy")
+	}
+}
+
+// TestSecurityRequireTLS 开启 RequireTLS 时通过HTTP携带令牌的请求被拒绝，HTTPS请求和不带令牌的请求正常处理
+func TestSecurityRequireTLS(t *testing.T) {
+	cfg := DefaultSecurityConfig()
+	cfg.RequireTLS = true
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    int
+	}{
+		{"HTTP携带令牌", map[string]string{"Authorization": "Bearer token"}, http.StatusForbidden},
+		{"HTTPS携带令牌", map[string]string{"Authorization": "Bearer token", "X-Forwarded-Proto": "https"}, http.StatusOK},
+		{"HTTP不带令牌", nil, http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := serveSecurity(cfg, "/api/oss/ping", tt.headers)
+		if w.Code != tt.want {
+			t.Errorf("%s: 返回 %d，应为 %d", tt.name, w.Code, tt.want)
+		}
+		if w.Header().Get("X-Content-Type-Options") != "nosniff" {
+			t.Errorf("%s: 缺少 X-Content-Type-Options", tt.name)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ServerTimeoutConfig HTTP服务器超时与请求头限制配置，对应配置中的 server，时间单位为秒，0表示不限制
+type ServerTimeoutConfig struct {
+	ReadHeaderTimeout int `mapstructure:"read_header_timeout"` // 读取请求头的超时，防止客户端缓慢发送请求头占用连接（slowloris）
+	ReadTimeout       int `mapstructure:"read_timeout"`        // 读取整个请求（含请求体）的超时
+	WriteTimeout      int `mapstructure:"write_timeout"`       // 从读完请求头到写完响应的超时
+	IdleTimeout       int `mapstructure:"idle_timeout"`        // keep-alive 连接等待下一个请求的超时
+	MaxHeaderBytes    int `mapstructure:"max_header_bytes"`    // 请求头的最大字节数
+	StreamTimeout     int `mapstructure:"stream_timeout"`      // 上传、下载等流式路由的读写超时，取代 ReadTimeout 和 WriteTimeout
+}
+
+// DefaultServerTimeoutConfig 默认超时配置，普通接口的读写各1分钟，流式路由不限制
+func DefaultServerTimeoutConfig() ServerTimeoutConfig {
+	return ServerTimeoutConfig{
+		ReadHeaderTimeout: 10,
+		ReadTimeout:       60,
+		WriteTimeout:      60,
+		IdleTimeout:       120,
+		MaxHeaderBytes:    1 << 20,
+	}
+}
+
+// Apply 将超时配置应用到HTTP服务器
+func (cfg ServerTimeoutConfig) Apply(srv *http.Server) {
+	srv.ReadHeaderTimeout = time.Duration(cfg.ReadHeaderTimeout) * time.Second
+	srv.ReadTimeout = time.Duration(cfg.ReadTimeout) * time.Second
+	srv.WriteTimeout = time.Duration(cfg.WriteTimeout) * time.Second
+	srv.IdleTimeout = time.Duration(cfg.IdleTimeout) * time.Second
+	srv.MaxHeaderBytes = cfg.MaxHeaderBytes
+}
+
+// StreamingTimeout 流式路由中间件，将当前请求的读写截止时间改为从现在起 timeout 之后，0表示不限制
+// 服务器的 ReadTimeout 和 WriteTimeout 按整个请求计算，会中断大文件的上传和下载；
+// 读取请求体的中间件（如按表单参数鉴权）之前必须先执行本中间件
+func StreamingTimeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var deadline time.Time
+		if timeout > 0 {
+			deadline = time.Now().Add(timeout)
+		}
+		rc := http.NewResponseController(c.Writer)
+		if err := rc.SetReadDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			log.Printf("设置请求读取截止时间失败: %v", err)
+		}
+		if err := rc.SetWriteDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			log.Printf("设置响应写入截止时间失败: %v", err)
+		}
+		c.Next()
+	}
+}
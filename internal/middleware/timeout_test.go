@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestServerTimeouts 缓慢发送请求头的客户端被 ReadHeaderTimeout 断开；
+// 流式路由的下载超过 WriteTimeout 仍能完整写出，普通路由超过 WriteTimeout 被中断
+func TestServerTimeouts(t *testing.T) {
+	const chunks = 15
+	chunk := bytes.Repeat([]byte("a"), 1024)
+	slowWrite := func(c *gin.Context) {
+		c.Status(http.StatusOK)
+		for i := 0; i < chunks; i++ {
+			c.Writer.Write(chunk)
+			c.Writer.Flush()
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/download", StreamingTimeout(0), slowWrite)
+	r.GET("/slow", slowWrite)
+
+	srv := httptest.NewUnstartedServer(r)
+	cfg := DefaultServerTimeoutConfig()
+	cfg.ReadHeaderTimeout, cfg.ReadTimeout, cfg.WriteTimeout = 1, 1, 1
+	cfg.Apply(srv.Config)
+	srv.Start()
+	defer srv.Close()
+
+	// 只发送一部分请求头后停止
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("GET /download HTTP/1.1\r\nHost: example.com\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = io.ReadAll(conn)
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		t.Fatalf("服务器在5秒内没有断开缓慢发送请求头的连接")
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("缓慢发送请求头的连接 %v 后才被断开", elapsed)
+	}
+
+	// 流式路由的下载持续约1.5秒，超过 WriteTimeout 仍完整返回
+	resp, err := http.Get(srv.URL + "/download")
+	if err != nil {
+		t.Fatalf("下载请求失败: %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || len(body) != chunks*len(chunk) {
+		t.Fatalf("流式下载读取 %d 字节（%v），应完整读取 %d 字节", len(body), err, chunks*len(chunk))
+	}
+
+	// 未使用流式中间件的路由超过 WriteTimeout 被中断
+	resp, err = http.Get(srv.URL + "/slow")
+	if err == nil {
+		body, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	if err == nil && len(body) == chunks*len(chunk) {
+		t.Fatalf("普通路由的响应超过 WriteTimeout 仍完整写出")
+	}
+}
@@ -0,0 +1,42 @@
+package dto
+
+import "time"
+
+// ===== 请求结构 =====
+
+// AuditArchiveQueryRequest 归档日志查询请求
+type AuditArchiveQueryRequest struct {
+	StartDate string `form:"start_date" binding:"required"` // 开始日期，格式 2006-01-02
+	EndDate   string `form:"end_date" binding:"required"`   // 结束日期，格式 2006-01-02
+}
+
+// AuditActivityQueryRequest 个人操作记录查询请求
+type AuditActivityQueryRequest struct {
+	Operation string `form:"operation" binding:"omitempty,max=20"` // 操作类型，如 upload、download
+	StartDate string `form:"start_date" binding:"omitempty"`       // 开始日期，格式 2006-01-02
+	EndDate   string `form:"end_date" binding:"omitempty"`         // 结束日期，格式 2006-01-02
+	Page      int    `form:"page" binding:"omitempty,min=1"`
+	Size      int    `form:"size" binding:"omitempty,min=1,max=100"`
+}
+
+// ===== 响应结构 =====
+
+// AuditLogResponse 审计日志响应
+type AuditLogResponse struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	UserName  string    `json:"user_name,omitempty"`
+	GroupID   string    `json:"group_id"`
+	ProjectID string    `json:"project_id"`
+	FileID    *string   `json:"file_id,omitempty"`
+	Operation string    `json:"operation"`
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	Status    int       `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuditArchiveResultResponse 手动归档结果响应
+type AuditArchiveResultResponse struct {
+	ArchivedCount int `json:"archived_count"`
+}
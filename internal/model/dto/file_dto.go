@@ -6,10 +6,17 @@ import "time"
 
 // FileUploadRequest 文件上传请求
 type FileUploadRequest struct {
+	ProjectID      string `form:"project_id" binding:"required"`        // 项目ID
+	Path           string `form:"path" binding:"omitempty"`             // 上传路径，默认为根目录
+	ParentFolderID string `form:"parent_folder_id" binding:"omitempty"` // 上传到的文件夹ID，指定时优先于 path
+	Comment        string `form:"comment" binding:"omitempty"`          // 文件注释
+	Overwrite      bool   `form:"overwrite" binding:"omitempty"`        // 是否覆盖同名文件
+}
+
+// FolderUploadRequest 文件夹上传请求，文件通过多个 files 字段上传，相对路径通过同样数量、同样顺序的 paths 字段提供
+type FolderUploadRequest struct {
 	ProjectID string `form:"project_id" binding:"required"` // 项目ID
-	Path      string `form:"path" binding:"omitempty"`      // 上传路径，默认为根目录
-	Comment   string `form:"comment" binding:"omitempty"`   // 文件注释
-	Overwrite bool   `form:"overwrite" binding:"omitempty"` // 是否覆盖同名文件
+	Path      string `form:"path" binding:"omitempty"`      // 上传到的目录，默认为根目录
 }
 
 // FileDownloadRequest 文件下载请求
@@ -19,13 +26,44 @@ type FileDownloadRequest struct {
 
 // FileListRequest 文件列表请求
 type FileListRequest struct {
-	ProjectID      string `form:"project_id" binding:"required"` // 项目ID
-	Path           string `form:"path" binding:"omitempty"`      // 文件路径，默认为根目录
-	Recursive      bool   `form:"recursive" binding:"omitempty"` // 是否递归获取子目录
-	Page           int    `form:"page,default=1"`                // 页码
-	Size           int    `form:"size,default=20"`               // 每页大小
-	OrderBy        string `form:"order_by,default=updated_at"`   // 排序字段
-	OrderDirection string `form:"order_direction,default=desc"`  // 排序方向
+	ProjectID      string `form:"project_id" binding:"required"`                                               // 项目ID
+	Path           string `form:"path" binding:"omitempty"`                                                    // 文件路径，默认为根目录
+	Recursive      bool   `form:"recursive" binding:"omitempty"`                                               // 是否递归获取子目录
+	Page           int    `form:"page,default=1"`                                                              // 页码
+	Size           int    `form:"size,default=20"`                                                             // 每页大小
+	OrderBy        string `form:"order_by,default=updated_at"`                                                 // 排序字段
+	OrderDirection string `form:"order_direction,default=desc"`                                                // 排序方向
+	Tag            string `form:"tag"`                                                                         // 标签，指定时列出项目内所有带该标签的文件，忽略路径
+	Category       string `form:"category" binding:"omitempty,oneof=image video audio document archive other"` // 文件分类，只列出该分类的文件
+}
+
+// FileDeleteQuery 删除文件的查询参数
+type FileDeleteQuery struct {
+	Recursive bool `form:"recursive"` // 删除非空文件夹时需为 true，连同其下全部内容一起删除
+}
+
+// FileSearchFilters 文件搜索条件，未设置的条件不参与筛选
+type FileSearchFilters struct {
+	Extension      string     `form:"extension"`                                                                   // 扩展名，如 .pdf
+	MimePrefix     string     `form:"mime_prefix"`                                                                 // 内容类型前缀，如 image/
+	MinSize        *int64     `form:"min_size" binding:"omitempty,min=0"`                                          // 最小文件大小（字节）
+	MaxSize        *int64     `form:"max_size" binding:"omitempty,min=0"`                                          // 最大文件大小（字节）
+	UploaderID     string     `form:"uploader_id"`                                                                 // 上传者ID
+	UpdatedFrom    *time.Time `form:"updated_from" time_format:"2006-01-02T15:04:05Z07:00"`                        // 修改时间起点（RFC3339）
+	UpdatedTo      *time.Time `form:"updated_to" time_format:"2006-01-02T15:04:05Z07:00"`                          // 修改时间终点（RFC3339）
+	IncludeDeleted bool       `form:"include_deleted"`                                                             // 是否包含已删除的文件
+	Tag            string     `form:"tag"`                                                                         // 带有该标签
+	Category       string     `form:"category" binding:"omitempty,oneof=image video audio document archive other"` // 文件分类，按内容类型划分
+	OrderBy        string     `form:"order_by" binding:"omitempty,oneof=relevance updated_at"`                     // 排序方式，有关键字时默认 relevance，否则默认 updated_at
+}
+
+// FileSearchRequest 文件搜索请求
+type FileSearchRequest struct {
+	ProjectID string `form:"project_id" binding:"required"` // 项目ID
+	Query     string `form:"q"`                             // 文件名包含的关键字
+	FileSearchFilters
+	Page int `form:"page,default=1"`  // 页码
+	Size int `form:"size,default=20"` // 每页大小
 }
 
 // FileFolderCreateRequest 创建文件夹请求
@@ -40,6 +78,29 @@ type FileDeleteRequest struct {
 	FileID string `json:"file_id" binding:"required"` // 文件ID
 }
 
+// FileRenameRequest 文件重命名请求
+type FileRenameRequest struct {
+	FileID  string `json:"file_id" binding:"required"`          // 文件ID
+	NewName string `json:"new_name" binding:"required,max=255"` // 新文件名，不含路径
+}
+
+// FileCopyRequest 复制文件或文件夹请求
+type FileCopyRequest struct {
+	TargetProjectID string `json:"target_project_id"` // 目标项目ID，为空表示源文件所在项目
+	TargetPath      string `json:"target_path"`       // 目标目录，为空表示根目录
+}
+
+// FileBatchMoveRequest 批量移动文件请求
+type FileBatchMoveRequest struct {
+	FileIDs    []string `json:"file_ids" binding:"required,min=1,max=200,dive,required"` // 要移动的文件ID，须属于同一项目
+	TargetPath string   `json:"target_path"`                                             // 目标目录，为空表示根目录
+}
+
+// FileRollbackRequest 文件版本回滚请求
+type FileRollbackRequest struct {
+	Version int `json:"version" binding:"required,min=1"` // 目标版本号
+}
+
 // FileRestoreRequest 文件恢复请求
 type FileRestoreRequest struct {
 	FileID string `json:"file_id" binding:"required"` // 文件ID
@@ -47,10 +108,25 @@ type FileRestoreRequest struct {
 
 // FileShareCreateRequest 创建文件分享请求
 type FileShareCreateRequest struct {
-	FileID        string `json:"file_id" binding:"required"`               // 文件ID
-	Password      string `json:"password" binding:"omitempty"`             // 访问密码
-	ExpireHours   int    `json:"expire_hours" binding:"omitempty"`         // 过期小时数，0表示永不过期
-	DownloadLimit int    `json:"download_limit" binding:"omitempty,min=0"` // 下载次数限制，0表示无限制
+	FileID        string                 `json:"file_id" binding:"required"`               // 文件ID
+	Password      string                 `json:"password" binding:"omitempty"`             // 访问密码
+	AutoPassword  bool                   `json:"auto_password" binding:"omitempty"`        // 是否由服务端生成强密码，为true时忽略password
+	ExpireHours   *int                   `json:"expire_hours" binding:"omitempty,min=0"`   // 过期小时数，0表示永不过期，不传时使用项目默认值
+	DownloadLimit *int                   `json:"download_limit" binding:"omitempty,min=0"` // 下载次数限制，0表示无限制，不传时使用项目默认值
+	Watermark     *ShareWatermarkRequest `json:"watermark" binding:"omitempty"`            // 水印设置，为空表示不加水印
+}
+
+// ShareWatermarkRequest 分享水印设置
+type ShareWatermarkRequest struct {
+	Text      string `json:"text" binding:"omitempty,max=255"`      // 水印模板，支持{{.Recipient}}、{{.Date}}、{{.FileName}}
+	Recipient string `json:"recipient" binding:"omitempty,max=100"` // 接收人标识，如邮箱
+}
+
+// FileWatermarkRequest 设置文件下载水印请求
+type FileWatermarkRequest struct {
+	FileID   string `json:"file_id" binding:"required"`       // 文件ID
+	Required bool   `json:"required"`                         // 是否下载时强制添加水印
+	Text     string `json:"text" binding:"omitempty,max=255"` // 水印模板，空表示使用默认模板
 }
 
 // FileShareAccessRequest 访问分享文件请求
@@ -59,29 +135,74 @@ type FileShareAccessRequest struct {
 	Password  string `json:"password" binding:"omitempty"`  // 访问密码
 }
 
+// FilePresignUploadRequest 获取预签名上传URL请求
+type FilePresignUploadRequest struct {
+	ProjectID string `json:"project_id" binding:"required"` // 项目ID
+	Path      string `json:"path" binding:"omitempty"`      // 上传路径，默认为根目录
+	FileName  string `json:"file_name" binding:"required"`  // 文件名
+}
+
+// FilePresignConfirmRequest 确认预签名上传请求
+type FilePresignConfirmRequest struct {
+	ProjectID string `json:"project_id" binding:"required"`      // 项目ID
+	ObjectKey string `json:"object_key" binding:"required"`      // 对象键
+	FileSize  int64  `json:"file_size" binding:"required,min=0"` // 文件大小
+	FileHash  string `json:"file_hash" binding:"omitempty"`      // 文件SHA-256哈希，可选，提供时须与上传的内容一致
+}
+
+// ResolveDuplicatesRequest 保留一份并删除其余重复文件请求
+type ResolveDuplicatesRequest struct {
+	KeepFileID string `json:"keep_file_id" binding:"required"` // 保留的文件ID，同项目内与其内容相同的其他文件将被删除
+}
+
+// FileTagRequest 添加文件标签请求
+type FileTagRequest struct {
+	Name string `json:"name" binding:"required"` // 标签名称
+}
+
+// FileDenyRequest 拒绝用户访问文件请求
+type FileDenyRequest struct {
+	UserID string `json:"user_id" binding:"required"` // 被拒绝访问的用户ID
+}
+
+// FolderPermissionRequest 文件夹授权请求
+type FolderPermissionRequest struct {
+	UserID string `json:"user_id" binding:"required"`                        // 被授权的用户ID
+	Role   string `json:"role" binding:"required,oneof=admin editor viewer"` // 授予的角色
+}
+
 // ===== 响应结构 =====
 
 // FileResponse 文件响应
 type FileResponse struct {
-	ID             string     `json:"id"`
-	ProjectID      string     `json:"project_id"`
-	FileName       string     `json:"file_name"`
-	FilePath       string     `json:"file_path"`
-	FullPath       string     `json:"full_path"`
-	FileSize       int64      `json:"file_size"`
-	MimeType       string     `json:"mime_type"`
-	Extension      string     `json:"extension"`
-	IsFolder       bool       `json:"is_folder"`
-	IsDeleted      bool       `json:"is_deleted"`
-	UploaderID     string     `json:"uploader_id"`
-	UploaderName   string     `json:"uploader_name"`
-	CreatedAt      time.Time  `json:"created_at"`
-	UpdatedAt      time.Time  `json:"updated_at"`
-	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
-	DeletedBy      *string    `json:"deleted_by,omitempty"`
-	DeleterName    string     `json:"deleter_name,omitempty"`
-	CurrentVersion int        `json:"current_version"`
-	PreviewURL     string     `json:"preview_url,omitempty"`
+	ID                string     `json:"id"`
+	ProjectID         string     `json:"project_id"`
+	FileName          string     `json:"file_name"`
+	FilePath          string     `json:"file_path"`
+	FullPath          string     `json:"full_path"`
+	FileSize          int64      `json:"file_size"`
+	MimeType          string     `json:"mime_type"`
+	Extension         string     `json:"extension"`
+	IsFolder          bool       `json:"is_folder"`
+	IsDeleted         bool       `json:"is_deleted"`
+	UploaderID        string     `json:"uploader_id"`
+	UploaderName      string     `json:"uploader_name"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+	DeletedAt         *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy         *string    `json:"deleted_by,omitempty"`
+	DeleterName       string     `json:"deleter_name,omitempty"`
+	CurrentVersion    int        `json:"current_version"`
+	PreviewURL        string     `json:"preview_url,omitempty"`
+	ThumbnailURL      string     `json:"thumbnail_url,omitempty"` // 缩略图地址，图片文件生成缩略图后才有
+	WatermarkRequired bool       `json:"watermark_required"`
+	WatermarkText     string     `json:"watermark_text,omitempty"`
+}
+
+// FileRenameResponse 文件重命名响应
+type FileRenameResponse struct {
+	File    FileResponse `json:"file"`
+	Warning string       `json:"warning,omitempty"` // 新扩展名与文件实际内容不符时的提示
 }
 
 // FileVersionResponse 文件版本响应
@@ -106,17 +227,112 @@ type FileShareResponse struct {
 	MimeType      string     `json:"mime_type"`
 	ShareCode     string     `json:"share_code"`
 	HasPassword   bool       `json:"has_password"`
+	Password      string     `json:"password,omitempty"` // 服务端生成的密码，仅在创建时返回一次
 	ExpireAt      *time.Time `json:"expire_at,omitempty"`
 	DownloadLimit int        `json:"download_limit"`
 	DownloadCount int        `json:"download_count"`
+	Watermark     bool       `json:"watermark"`
 	CreatedAt     time.Time  `json:"created_at"`
 	CreatorName   string     `json:"creator_name"`
 }
 
-// FileListResponse 文件列表响应
-type FileListResponse struct {
-	Total int64          `json:"total"`
-	Items []FileResponse `json:"items"`
+// ProjectShareStatsResponse 项目分享统计响应
+type ProjectShareStatsResponse struct {
+	ProjectID         string           `json:"project_id"`
+	TotalShares       int64            `json:"total_shares"`        // 分享总数，包括已失效的分享
+	ActiveShares      int64            `json:"active_shares"`       // 未过期、未达到下载次数且文件未删除的分享数
+	TotalDownloads    int64            `json:"total_downloads"`     // 通过分享下载的总次数
+	PasswordProtected int64            `json:"password_protected"`  // 设置了密码的分享数
+	WithExpiry        int64            `json:"with_expiry"`         // 设置了有效期的分享数
+	WithDownloadLimit int64            `json:"with_download_limit"` // 设置了下载次数限制的分享数
+	TopFiles          []SharedFileStat `json:"top_files"`           // 分享最多的文件
+}
+
+// SharedFileStat 单个文件的分享统计
+type SharedFileStat struct {
+	FileID        string `json:"file_id"`
+	FileName      string `json:"file_name"`
+	FullPath      string `json:"full_path"`
+	ShareCount    int64  `json:"share_count"`
+	DownloadCount int64  `json:"download_count"`
+}
+
+// FilePresignUploadResponse 预签名上传URL响应
+type FilePresignUploadResponse struct {
+	UploadURL string    `json:"upload_url"` // 预签名PUT地址
+	ObjectKey string    `json:"object_key"` // 临时对象键，确认上传时回传
+	ExpiresAt time.Time `json:"expires_at"` // 过期时间
+}
+
+// QuotaHeadroom 一级存储配额的使用情况
+type QuotaHeadroom struct {
+	Quota     int64 `json:"quota"`     // 配额（字节），0表示不限制
+	Used      int64 `json:"used"`      // 已用（字节）
+	Remaining int64 `json:"remaining"` // 剩余（字节），-1表示不限制
+}
+
+// UploadConfigResponse 项目上传配置响应
+type UploadConfigResponse struct {
+	ProjectID    string        `json:"project_id"`
+	UploadPolicy UploadPolicy  `json:"upload_policy"` // 合并全局与项目配置后生效的上传策略
+	ProjectQuota QuotaHeadroom `json:"project_quota"` // 项目配额
+	GroupQuota   QuotaHeadroom `json:"group_quota"`   // 群组配额
+	Remaining    int64         `json:"remaining"`     // 当前可上传的字节数，取两级剩余空间的较小值，-1表示不限制
+}
+
+// FolderUploadResult 文件夹上传中单个文件的结果
+type FolderUploadResult struct {
+	Path   string `json:"path"`              // 请求中的相对路径
+	Status string `json:"status"`            // uploaded、skipped 或 failed
+	FileID string `json:"file_id,omitempty"` // 文件ID
+	Size   int64  `json:"size"`
+	Error  string `json:"error,omitempty"` // 失败原因
+}
+
+// FolderUploadResponse 文件夹上传响应
+type FolderUploadResponse struct {
+	Uploaded       int                  `json:"uploaded"`        // 上传的文件数
+	Skipped        int                  `json:"skipped"`         // 同路径已有相同内容而跳过的文件数
+	Failed         int                  `json:"failed"`          // 失败的文件数
+	FoldersCreated int                  `json:"folders_created"` // 新建的文件夹数
+	TotalSize      int64                `json:"total_size"`      // 上传文件的总大小
+	Results        []FolderUploadResult `json:"results"`
+}
+
+// FileTagsResponse 文件标签响应
+type FileTagsResponse struct {
+	FileID string   `json:"file_id"`
+	Tags   []string `json:"tags"`
+}
+
+// FileDenyResponse 文件的访问拒绝规则
+type FileDenyResponse struct {
+	UserID    string    `json:"user_id"`    // 被拒绝访问的用户ID
+	CreatedBy string    `json:"created_by"` // 设置规则的用户ID
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FolderPermissionResponse 文件夹上的授权
+type FolderPermissionResponse struct {
+	UserID    string    `json:"user_id"`    // 被授权的用户ID
+	Role      string    `json:"role"`       // admin、editor、viewer
+	CreatedBy string    `json:"created_by"` // 最后设置授权的用户ID
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ProjectTagResponse 项目内使用中的标签
+type ProjectTagResponse struct {
+	Name      string `json:"name"`
+	FileCount int64  `json:"file_count"` // 带有该标签的未删除文件数
+}
+
+// VersionPruneResponse 清理文件旧版本响应
+type VersionPruneResponse struct {
+	FileID      string `json:"file_id"`
+	MaxVersions int    `json:"max_versions"` // 生效的最大版本数，0表示不限制
+	Pruned      []int  `json:"pruned"`       // 删除的版本号
+	Remaining   int    `json:"remaining"`    // 保留的版本数
 }
 
 // FileVersionListResponse 文件版本列表响应
@@ -125,3 +341,98 @@ type FileVersionListResponse struct {
 	Total  int                   `json:"total"`
 	Items  []FileVersionResponse `json:"items"`
 }
+
+// DuplicateFileItem 重复文件条目
+type DuplicateFileItem struct {
+	ID         string    `json:"id"`
+	FileName   string    `json:"file_name"`
+	FullPath   string    `json:"full_path"`
+	FileSize   int64     `json:"file_size"`
+	UploaderID string    `json:"uploader_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// DuplicateGroup 内容相同的一组文件
+type DuplicateGroup struct {
+	FileHash        string              `json:"file_hash"`
+	FileSize        int64               `json:"file_size"`
+	Count           int                 `json:"count"`
+	ReclaimableSize int64               `json:"reclaimable_size"` // 每组只保留一份时可释放的空间
+	Files           []DuplicateFileItem `json:"files"`            // 按创建时间升序
+}
+
+// DuplicatesResponse 项目重复文件响应
+type DuplicatesResponse struct {
+	Groups           []DuplicateGroup `json:"groups"`
+	TotalReclaimable int64            `json:"total_reclaimable"`
+}
+
+// ResolveDuplicatesResponse 删除重复文件响应
+type ResolveDuplicatesResponse struct {
+	KeptFileID string   `json:"kept_file_id"`
+	DeletedIDs []string `json:"deleted_ids"`
+	FreedSize  int64    `json:"freed_size"`
+}
+
+// 批量移动中单个文件的结果
+const (
+	FileMoveStatusMoved     = "moved"     // 已移动
+	FileMoveStatusUnchanged = "unchanged" // 文件已在目标目录中
+	FileMoveStatusForbidden = "forbidden" // 没有修改该文件的权限
+	FileMoveStatusConflict  = "conflict"  // 目标目录下已有同名文件
+	FileMoveStatusNotFound  = "not_found" // 文件不存在
+	FileMoveStatusFailed    = "failed"    // 处理失败
+)
+
+// FileMoveResult 批量移动中单个文件的结果
+type FileMoveResult struct {
+	FileID   string `json:"file_id"`
+	Status   string `json:"status"`              // moved、unchanged、forbidden、conflict、not_found、failed
+	FullPath string `json:"full_path,omitempty"` // 移动后的完整路径
+	Error    string `json:"error,omitempty"`     // 未移动的原因
+}
+
+// FileBatchMoveResponse 批量移动文件响应
+type FileBatchMoveResponse struct {
+	ProjectID  string           `json:"project_id"`
+	TargetPath string           `json:"target_path"`
+	Moved      int              `json:"moved"`
+	Failed     int              `json:"failed"` // 未移动的文件数，不含已在目标目录中的文件
+	Results    []FileMoveResult `json:"results"`
+}
+
+// DownloadChunk 下载清单中的一个分块
+type DownloadChunk struct {
+	Index    int    `json:"index"`    // 分块序号，从0开始
+	Offset   int64  `json:"offset"`   // 分块在文件中的起始字节
+	Size     int64  `json:"size"`     // 分块字节数，最后一块可能小于分块大小
+	Checksum string `json:"checksum"` // 分块内容的SHA-256，十六进制
+}
+
+// DownloadManifestResponse 分块并行下载清单
+type DownloadManifestResponse struct {
+	FileID      string          `json:"file_id"`
+	FileName    string          `json:"file_name"`
+	FileSize    int64           `json:"file_size"`
+	FileHash    string          `json:"file_hash"` // 整个文件的SHA-256，与下载响应的ETag一致
+	Version     int             `json:"version"`
+	Algorithm   string          `json:"algorithm"`  // 校验算法，固定为 sha256
+	ChunkSize   int64           `json:"chunk_size"` // 建议的分块大小
+	ChunkCount  int             `json:"chunk_count"`
+	DownloadURL string          `json:"download_url"` // 按 Range 请求分块的下载地址
+	Chunks      []DownloadChunk `json:"chunks"`
+}
+
+// BucketLifecycleFailure 设置生命周期规则失败的存储桶
+type BucketLifecycleFailure struct {
+	Bucket string `json:"bucket"`
+	Error  string `json:"error"`
+}
+
+// BucketLifecycleResponse 重新设置临时对象过期规则的结果
+type BucketLifecycleResponse struct {
+	Prefix     string                   `json:"prefix" example:"temp/"`  // 临时对象前缀
+	ExpireDays int                      `json:"expire_days" example:"7"` // 保留天数，0表示已移除过期规则
+	Applied    []string                 `json:"applied"`                 // 设置成功的存储桶
+	Failed     []BucketLifecycleFailure `json:"failed"`                  // 设置失败的存储桶及原因
+}
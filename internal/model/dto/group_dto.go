@@ -17,6 +17,8 @@ type GroupUpdateRequest struct {
 	Name        string `json:"name" binding:"required,max=64"`
 	Description string `json:"description" binding:"max=500"`
 	Status      *int   `json:"status,omitempty"`
+	// 是否对之后上传的文件加密存储，开启前需配置加密主密钥；为空时不修改
+	EncryptionEnabled *bool `json:"encryption_enabled,omitempty"`
 }
 
 // GroupListRequest 群组列表请求
@@ -42,30 +44,95 @@ type GroupMemberUpdateRequest struct {
 	Role   string `json:"role" binding:"required,oneof=admin member"` // 角色
 }
 
+// GroupLeaveRequest 退出群组请求
+type GroupLeaveRequest struct {
+	GroupID string `json:"group_id" binding:"required"` // 群组ID
+}
+
+// GroupTransferRequest 转让群组请求
+type GroupTransferRequest struct {
+	GroupID    string `json:"group_id" binding:"required"`     // 群组ID
+	NewOwnerID string `json:"new_owner_id" binding:"required"` // 新所有者的用户ID，必须已是群组成员
+}
+
+// GroupDeleteRequest 删除群组请求
+type GroupDeleteRequest struct {
+	Force        bool `form:"force"`         // 群组下还有项目或未删除的文件时一并删除
+	RemoveBucket bool `form:"remove_bucket"` // 同时删除群组的存储桶及其中的全部对象
+}
+
+// GroupBulkMoveRequest 批量将成员从一个群组移到另一个群组的请求
+type GroupBulkMoveRequest struct {
+	FromGroupID   string   `json:"from_group_id" binding:"required"`                        // 原群组ID
+	ToGroupID     string   `json:"to_group_id" binding:"required"`                          // 目标群组ID
+	UserIDs       []string `json:"user_ids" binding:"required,min=1,max=200,dive,required"` // 要移动的用户ID
+	Role          string   `json:"role" binding:"omitempty,oneof=admin member"`             // 在目标群组中的角色，默认member
+	ResetProjects *bool    `json:"reset_projects"`                                          // 是否删除用户在原群组各项目中的权限，不传时按 group.move_reset_projects 配置
+}
+
+// GroupInviteMembersRequest 按邮箱批量邀请成员请求
+type GroupInviteMembersRequest struct {
+	Emails []string `json:"emails" binding:"required,min=1,max=100,dive,required,email"` // 被邀请的邮箱
+	Role   string   `json:"role" binding:"omitempty,oneof=admin member"`                 // 加入后的角色，默认member
+}
+
+// GroupJoinApplyRequest 申请加入群组请求
+type GroupJoinApplyRequest struct {
+	Message string `json:"message" binding:"max=255"` // 申请说明，可选
+}
+
 // GroupInviteRequest 生成邀请码请求
 type GroupInviteRequest struct {
 	GroupID    string `json:"group_id" binding:"required"` // 群组ID
-	ExpireDays int    `json:"expire_days,omitempty"`       // 过期天数,0表示永不过期
+	ExpireDays int    `json:"expire_days,omitempty"`       // 有效天数,0表示默认30天
+
+	Emails []string `json:"emails,omitempty" binding:"omitempty,max=50,dive,email"` // 接收邀请邮件的邮箱，可选
+}
+
+// GroupInviteRotateRequest 轮换邀请码请求
+type GroupInviteRotateRequest struct {
+	ExpireDays int `json:"expire_days" binding:"min=0"` // 新邀请码的有效天数,0表示默认30天
+}
+
+// GroupQuotaRequest 设置群组新建项目默认配额请求
+type GroupQuotaRequest struct {
+	DefaultProjectQuota int64 `json:"default_project_quota" binding:"min=0"` // 新建项目的默认存储配额（字节），0表示不单独限制
+}
+
+// StorageTrendQuery 存储用量趋势查询参数
+type StorageTrendQuery struct {
+	From string `form:"from"` // 开始日期，格式 2006-01-02，为空时默认最近30天
+	To   string `form:"to"`   // 结束日期，格式 2006-01-02，包含当天，为空时默认今天
 }
 
 // ===== 响应结构 =====
 
 // GroupResponse 群组响应
 type GroupResponse struct {
-	ID           string    `json:"id"`
-	Name         string    `json:"name"`
-	Description  string    `json:"description"`
-	GroupKey     string    `json:"group_key"`
-	InviteCode   string    `json:"invite_code,omitempty"` // 仅群组管理员可见
-	StorageQuota int64     `json:"storage_quota"`         // 存储配额,0表示无限制
-	StorageUsed  int64     `json:"storage_used"`          // 已使用存储量
-	MemberCount  int       `json:"member_count"`          // 成员数量
-	ProjectCount int       `json:"project_count"`         // 项目数量
-	Status       int       `json:"status"`                // 状态:1-正常,2-禁用,3-锁定
-	CreatorID    string    `json:"creator_id"`            // 创建者ID
-	CreatorName  string    `json:"creator_name"`          // 创建者名称
-	CreatedAt    time.Time `json:"created_at"`            // 创建时间
-	UserRole     string    `json:"user_role,omitempty"`   // 当前用户在群组中的角色
+	ID                  string    `json:"id"`
+	Name                string    `json:"name"`
+	Description         string    `json:"description"`
+	GroupKey            string    `json:"group_key"`
+	InviteCode          string    `json:"invite_code,omitempty"` // 仅群组管理员可见
+	StorageQuota        int64     `json:"storage_quota"`         // 存储配额,0表示无限制
+	DefaultProjectQuota int64     `json:"default_project_quota"` // 新建项目的默认存储配额,0表示不单独限制
+	StorageUsed         int64     `json:"storage_used"`          // 已使用存储量
+	MemberCount         int       `json:"member_count"`          // 成员数量
+	ProjectCount        int       `json:"project_count"`         // 项目数量
+	Status              int       `json:"status"`                // 状态:1-正常,2-禁用,3-锁定
+	EncryptionEnabled   bool      `json:"encryption_enabled"`    // 是否对新上传的文件加密存储
+	CreatorID           string    `json:"creator_id"`            // 创建者ID
+	CreatorName         string    `json:"creator_name"`          // 创建者名称
+	CreatedAt           time.Time `json:"created_at"`            // 创建时间
+	UserRole            string    `json:"user_role,omitempty"`   // 当前用户在群组中的角色
+}
+
+// GroupQuotaResponse 群组配额响应
+type GroupQuotaResponse struct {
+	GroupID             string `json:"group_id"`
+	StorageQuota        int64  `json:"storage_quota"`         // 群组存储配额,0表示无限制
+	StorageUsed         int64  `json:"storage_used"`          // 群组已使用存储量
+	DefaultProjectQuota int64  `json:"default_project_quota"` // 新建项目的默认存储配额,0表示不单独限制
 }
 
 // GroupMemberResponse 群组成员响应
@@ -80,6 +147,20 @@ type GroupMemberResponse struct {
 	LastActiveAt *time.Time `json:"last_active_at,omitempty"` // 最后活跃时间
 }
 
+// GroupJoinRequestResponse 入群申请响应
+type GroupJoinRequestResponse struct {
+	ID         string     `json:"id"`                    // 申请ID
+	GroupID    string     `json:"group_id"`              // 群组ID
+	UserID     string     `json:"user_id"`               // 申请人ID
+	UserName   string     `json:"user_name,omitempty"`   // 申请人名称
+	Email      string     `json:"email,omitempty"`       // 申请人邮箱
+	Message    string     `json:"message"`               // 申请说明
+	Status     string     `json:"status"`                // 状态:pending-待审批,approved-已批准,rejected-已拒绝
+	ReviewerID string     `json:"reviewer_id,omitempty"` // 审批人ID
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"` // 审批时间
+	CreatedAt  time.Time  `json:"created_at"`            // 申请时间
+}
+
 // GroupInviteResponse 群组邀请响应
 type GroupInviteResponse struct {
 	GroupID    string     `json:"group_id"`    // 群组ID
@@ -88,14 +169,63 @@ type GroupInviteResponse struct {
 	ExpireAt   *time.Time `json:"expire_at"`   // 过期时间
 }
 
-// GroupListResponse 群组列表响应
-type GroupListResponse struct {
-	Total int64           `json:"total"` // 总数
-	Items []GroupResponse `json:"items"` // 群组列表
+// GroupMoveResult 批量移动中单个用户的结果
+type GroupMoveResult struct {
+	UserID  string `json:"user_id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"` // 移动失败的原因
+}
+
+// 按邮箱邀请的处理结果
+const (
+	GroupInviteStatusAdded         = "added"          // 已注册的用户，直接加入群组
+	GroupInviteStatusInvited       = "invited"        // 未注册的邮箱，已记录邀请，注册并激活后自动加入
+	GroupInviteStatusAlreadyMember = "already_member" // 用户已经是群组成员
+	GroupInviteStatusFailed        = "failed"         // 处理失败
+)
+
+// GroupInviteMemberResult 按邮箱邀请中单个邮箱的结果
+type GroupInviteMemberResult struct {
+	Email  string `json:"email"`
+	Status string `json:"status"`            // added、invited、already_member、failed
+	UserID string `json:"user_id,omitempty"` // 已注册用户的ID
+	Error  string `json:"error,omitempty"`   // 处理失败的原因
+}
+
+// GroupInviteMembersResponse 按邮箱批量邀请成员响应
+type GroupInviteMembersResponse struct {
+	GroupID string                    `json:"group_id"`
+	Role    string                    `json:"role"`
+	Added   int                       `json:"added"`
+	Invited int                       `json:"invited"`
+	Failed  int                       `json:"failed"`
+	Results []GroupInviteMemberResult `json:"results"`
+}
+
+// GroupBulkMoveResponse 批量移动成员响应
+type GroupBulkMoveResponse struct {
+	FromGroupID   string            `json:"from_group_id"`
+	ToGroupID     string            `json:"to_group_id"`
+	Role          string            `json:"role"`
+	ResetProjects bool              `json:"reset_projects"` // 是否删除了用户在原群组各项目中的权限
+	Moved         int               `json:"moved"`
+	Failed        int               `json:"failed"`
+	Results       []GroupMoveResult `json:"results"`
+}
+
+// StorageTrendPoint 存储用量趋势中的单日数据
+type StorageTrendPoint struct {
+	Date         string `json:"date"`          // 日期，格式 2006-01-02
+	TotalSize    int64  `json:"total_size"`    // 当日总大小（字节），无变更的日期沿用上一次的总量
+	IncreaseSize int64  `json:"increase_size"` // 当日新增大小（字节）
+	FileCount    int64  `json:"file_count"`    // 当日文件数
 }
 
-// GroupMemberListResponse 群组成员列表响应
-type GroupMemberListResponse struct {
-	Total int64                 `json:"total"` // 总数
-	Items []GroupMemberResponse `json:"items"` // 成员列表
+// StorageTrendResponse 存储用量趋势响应，Points 按日期升序且每天一条
+type StorageTrendResponse struct {
+	GroupID   string              `json:"group_id,omitempty"`
+	ProjectID string              `json:"project_id,omitempty"`
+	From      string              `json:"from"`
+	To        string              `json:"to"`
+	Points    []StorageTrendPoint `json:"points"`
 }
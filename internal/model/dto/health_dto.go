@@ -0,0 +1,7 @@
+package dto
+
+// ReadinessResponse 就绪检查响应
+type ReadinessResponse struct {
+	Ready  bool              `json:"ready"`
+	Checks map[string]string `json:"checks"` // 各依赖的检查结果，正常为 ok，否则为错误信息
+}
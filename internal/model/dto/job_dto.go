@@ -0,0 +1,60 @@
+package dto
+
+import "time"
+
+// ===== 请求结构 =====
+
+// JobListRequest 后台任务列表请求
+type JobListRequest struct {
+	Status string `form:"status" binding:"omitempty,oneof=running completed failed cancelled"` // 任务状态
+	Type   string `form:"type" binding:"omitempty"`                                            // 任务类型
+}
+
+// StorageReportRequest 生成存储报表请求
+type StorageReportRequest struct {
+	GroupID   string `json:"group_id" binding:"required"`              // 群组ID
+	StartDate string `json:"start_date" binding:"required"`            // 开始日期，格式 2006-01-02
+	EndDate   string `json:"end_date" binding:"required"`              // 结束日期，格式 2006-01-02，包含当天
+	Format    string `json:"format" binding:"omitempty,oneof=csv pdf"` // 报表格式，csv（默认）或 pdf
+}
+
+// FileMigrationRequest 迁移文件存储后端请求
+type FileMigrationRequest struct {
+	FileID  string `json:"file_id" binding:"required"` // 文件ID
+	Backend string `json:"backend" binding:"required"` // 目标存储后端名称，default 表示默认后端
+}
+
+// BucketMigrationRequest 迁移存储桶布局请求，描述切换存储桶策略之前的布局
+type BucketMigrationRequest struct {
+	From         string `json:"from" binding:"required,oneof=group shared"` // 原存储桶策略
+	SharedBucket string `json:"shared_bucket"`                              // 原共享存储桶名称，原策略为 shared 时必填
+}
+
+// ===== 响应结构 =====
+
+// JobResponse 后台任务响应
+type JobResponse struct {
+	ID         string     `json:"id"`
+	Type       string     `json:"type"`
+	CreatorID  string     `json:"creator_id"`
+	Status     string     `json:"status"`
+	Processed  int        `json:"processed"` // 已处理数量
+	Total      int        `json:"total"`     // 总数量，0表示未知
+	Message    string     `json:"message,omitempty"`
+	Result     string     `json:"result,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// BackupScheduleResponse 备份计划响应
+type BackupScheduleResponse struct {
+	Name       string       `json:"name"`
+	Schedule   string       `json:"schedule"`              // cron表达式
+	Mode       string       `json:"mode"`                  // manifest 或 content
+	ProjectIDs []string     `json:"project_ids,omitempty"` // 为空表示全部项目
+	Retention  int          `json:"retention"`             // 保留最近几次备份
+	Backend    string       `json:"backend"`               // 写入备份的存储后端，default 表示默认后端
+	NextRunAt  *time.Time   `json:"next_run_at,omitempty"`
+	LastRunAt  *time.Time   `json:"last_run_at,omitempty"`
+	LastJob    *JobResponse `json:"last_job,omitempty"` // 最近一次备份任务，服务重启后清空
+}
@@ -19,24 +19,28 @@ func (q PageQuery) WithDefaultValues() PageQuery {
 	return q
 }
 
-// PageResult 统一分页响应结构
-type PageResult struct {
-	List      interface{} `json:"list"`       // 数据列表
-	Total     int64       `json:"total"`      // 总记录数
-	Page      int         `json:"page"`       // 当前页码
-	Size      int         `json:"size"`       // 每页大小
-	TotalPage int         `json:"total_page"` // 总页数
+// PageResult 统一分页响应结构，所有分页列表接口都返回该结构
+type PageResult[T any] struct {
+	List      []T   `json:"list"`       // 数据列表
+	Total     int64 `json:"total"`      // 总记录数
+	Page      int   `json:"page"`       // 当前页码
+	Size      int   `json:"size"`       // 每页大小
+	TotalPage int   `json:"total_page"` // 总页数
 }
 
-// NewPageResult 创建分页结果
-func NewPageResult(list interface{}, total int64, query PageQuery) PageResult {
+// NewPageResult 创建分页结果，list 为 nil 时返回空列表
+func NewPageResult[T any](list []T, total int64, query PageQuery) *PageResult[T] {
+	if list == nil {
+		list = []T{}
+	}
+
 	// 计算总页数
 	totalPage := 0
 	if query.Size > 0 {
 		totalPage = int((total + int64(query.Size) - 1) / int64(query.Size))
 	}
 
-	return PageResult{
+	return &PageResult[T]{
 		List:      list,
 		Total:     total,
 		Page:      query.Page,
@@ -0,0 +1,48 @@
+package dto
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNewPageResultTotalPage(t *testing.T) {
+	tests := []struct {
+		total int64
+		size  int
+		want  int
+	}{
+		{0, 10, 0},
+		{1, 10, 1},
+		{10, 10, 1},
+		{11, 10, 2},
+		{100, 20, 5},
+		{101, 20, 6},
+		{5, 0, 0},
+	}
+	for _, tt := range tests {
+		result := NewPageResult([]int{}, tt.total, PageQuery{Page: 1, Size: tt.size})
+		if result.TotalPage != tt.want {
+			t.Errorf("总数 %d、每页 %d 时总页数为 %d，应为 %d", tt.total, tt.size, result.TotalPage, tt.want)
+		}
+		if result.Total != tt.total || result.Page != 1 || result.Size != tt.size {
+			t.Errorf("分页字段为 %+v，与查询不一致", result)
+		}
+	}
+}
+
+func TestNewPageResultNilList(t *testing.T) {
+	var list []string
+	result := NewPageResult(list, 0, PageQuery{Page: 1, Size: 10})
+	if result.List == nil || len(result.List) != 0 {
+		t.Fatalf("nil 列表应转换为空列表，实际为 %#v", result.List)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"list":[],"total":0,"page":1,"size":10,"total_page":0}`
+	if string(data) != want {
+		t.Fatalf("序列化结果为 %s，应为 %s", data, want)
+	}
+}
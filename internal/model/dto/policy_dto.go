@@ -0,0 +1,35 @@
+package dto
+
+import "time"
+
+// PolicySnapshotVersion 当前策略快照格式版本
+const PolicySnapshotVersion = 1
+
+// PolicyRule 单条Casbin规则
+type PolicyRule struct {
+	PType  string   `json:"ptype" binding:"required"`  // 规则类型，如 p、g
+	Values []string `json:"values" binding:"required"` // 规则字段，顺序与模型定义一致
+}
+
+// PolicySnapshot 策略快照，规则按类型和字段排序，便于备份与比对
+type PolicySnapshot struct {
+	Version    int          `json:"version" binding:"required"` // 快照格式版本
+	ExportedAt time.Time    `json:"exported_at"`
+	ExportedBy string       `json:"exported_by"`
+	Policies   []PolicyRule `json:"policies"`  // 权限规则（p）
+	Groupings  []PolicyRule `json:"groupings"` // 角色继承规则（g）
+}
+
+// PolicyImportRequest 策略导入请求
+type PolicyImportRequest struct {
+	Snapshot PolicySnapshot `json:"snapshot" binding:"required"` // 要导入的快照
+	DryRun   bool           `json:"dry_run"`                     // 仅返回差异，不实际应用
+}
+
+// PolicyImportResponse 策略导入结果
+type PolicyImportResponse struct {
+	DryRun  bool         `json:"dry_run"`
+	Applied bool         `json:"applied"`
+	Added   []PolicyRule `json:"added"`   // 导入后新增的规则
+	Removed []PolicyRule `json:"removed"` // 导入后删除的规则
+}
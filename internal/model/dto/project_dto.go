@@ -4,17 +4,61 @@ import "time"
 
 // CreateProjectRequest 创建项目请求
 type CreateProjectRequest struct {
-	Name        string `json:"name" binding:"required,min=2,max=64"`
-	Description string `json:"description" binding:"max=500"`
-	GroupID     string `json:"group_id" binding:"required"`
+	Name                 string        `json:"name" binding:"required,min=2,max=64"`
+	Description          string        `json:"description" binding:"max=500"`
+	GroupID              string        `json:"group_id" binding:"required"`
+	StorageQuota         *int64        `json:"storage_quota" binding:"omitempty,min=0"` // 项目存储配额（字节），不传表示使用群组的默认项目配额，0表示不单独限制
+	CaseInsensitivePaths bool          `json:"case_insensitive_paths"`                  // 文件名冲突检查是否忽略大小写，默认区分大小写
+	PublicRead           bool          `json:"public_read"`                             // 是否允许匿名读取（列表与下载），默认关闭
+	UploadPolicy         *UploadPolicy `json:"upload_policy"`                           // 项目级上传策略，不传表示使用全局配置
+	MaxVersions          int           `json:"max_versions" binding:"omitempty,min=0"`  // 每个文件保留的最大版本数，0表示使用全局配置
 }
 
 // UpdateProjectRequest 更新项目请求
 type UpdateProjectRequest struct {
-	ID          string `json:"id" binding:"required"`
-	Name        string `json:"name" binding:"required,min=2,max=64"`
-	Description string `json:"description" binding:"max=500"`
-	Status      int    `json:"status" binding:"omitempty,oneof=1 2"`
+	ID                   string        `json:"id" binding:"required"`
+	Name                 string        `json:"name" binding:"required,min=2,max=64"`
+	Description          string        `json:"description" binding:"max=500"`
+	Status               int           `json:"status" binding:"omitempty,oneof=1 2"`
+	StorageQuota         *int64        `json:"storage_quota" binding:"omitempty,min=0"` // 项目存储配额（字节），不传表示不修改，0表示不单独限制
+	CaseInsensitivePaths *bool         `json:"case_insensitive_paths"`                  // 文件名冲突检查是否忽略大小写，不传表示不修改
+	PublicRead           *bool         `json:"public_read"`                             // 是否允许匿名读取，不传表示不修改
+	UploadPolicy         *UploadPolicy `json:"upload_policy"`                           // 项目级上传策略，不传表示不修改，传空对象表示恢复全局配置
+	MaxVersions          *int          `json:"max_versions" binding:"omitempty,min=0"`  // 每个文件保留的最大版本数，不传表示不修改，0表示使用全局配置
+}
+
+// SharePolicyRequest 设置项目分享策略请求
+// 默认值在创建分享未指定时使用；设置了上限时，超过上限或不限制（0）的分享将被拒绝
+type SharePolicyRequest struct {
+	ProjectID            string `json:"project_id" binding:"required"`
+	DefaultExpireHours   int    `json:"default_expire_hours" binding:"min=0"`   // 默认有效期（小时），0表示未设置，有上限时默认取上限
+	MaxExpireHours       int    `json:"max_expire_hours" binding:"min=0"`       // 最长有效期（小时），0表示不限制
+	DefaultDownloadLimit int    `json:"default_download_limit" binding:"min=0"` // 默认下载次数限制，0表示未设置，有上限时默认取上限
+	MaxDownloadLimit     int    `json:"max_download_limit" binding:"min=0"`     // 最大下载次数限制，0表示不限制
+}
+
+// SharePolicyResponse 项目分享策略响应
+type SharePolicyResponse struct {
+	ProjectID            string `json:"project_id"`
+	DefaultExpireHours   int    `json:"default_expire_hours"`
+	MaxExpireHours       int    `json:"max_expire_hours"`
+	DefaultDownloadLimit int    `json:"default_download_limit"`
+	MaxDownloadLimit     int    `json:"max_download_limit"`
+}
+
+// UploadPolicy 上传策略
+// 项目级配置中 max_file_size 为0或列表为 null 的字段沿用全局配置，空列表表示不限制
+type UploadPolicy struct {
+	MaxFileSize         int64    `json:"max_file_size"`         // 单个文件大小上限（字节），0表示不限制
+	AllowedExtensions   []string `json:"allowed_extensions"`    // 允许的扩展名，如 .jpg，为空表示不限制
+	BlockedExtensions   []string `json:"blocked_extensions"`    // 禁止的扩展名，优先于允许列表
+	AllowedMimePrefixes []string `json:"allowed_mime_prefixes"` // 允许的内容类型前缀，如 image/，为空表示不限制
+}
+
+// CloneProjectRequest 克隆项目请求
+type CloneProjectRequest struct {
+	Name           string `json:"name" binding:"required,min=2,max=64"`
+	IncludeMembers bool   `json:"include_members"`
 }
 
 // ProjectQuery 项目查询参数
@@ -28,19 +72,25 @@ type ProjectQuery struct {
 
 // ProjectResponse 项目响应
 type ProjectResponse struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	GroupID     string    `json:"group_id"`
-	GroupName   string    `json:"group_name"`
-	PathPrefix  string    `json:"path_prefix"`
-	CreatorID   string    `json:"creator_id"`
-	CreatorName string    `json:"creator_name"`
-	Status      int       `json:"status"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	FileCount   int64     `json:"file_count"`
-	TotalSize   int64     `json:"total_size"`
+	ID                   string        `json:"id"`
+	Name                 string        `json:"name"`
+	Description          string        `json:"description"`
+	GroupID              string        `json:"group_id"`
+	GroupName            string        `json:"group_name"`
+	PathPrefix           string        `json:"path_prefix"`
+	CreatorID            string        `json:"creator_id"`
+	CreatorName          string        `json:"creator_name"`
+	Status               int           `json:"status"`      // 1-正常, 2-归档, 3-删除
+	StatusName           string        `json:"status_name"` // active-正常, archived-归档（文件只读）, deleted-已删除
+	StorageQuota         int64         `json:"storage_quota"`
+	CaseInsensitivePaths bool          `json:"case_insensitive_paths"`
+	PublicRead           bool          `json:"public_read"`   // 是否允许匿名读取
+	UploadPolicy         *UploadPolicy `json:"upload_policy"` // 项目级上传策略，未设置时为null
+	MaxVersions          int           `json:"max_versions"`  // 每个文件保留的最大版本数，0表示使用全局配置
+	CreatedAt            time.Time     `json:"created_at"`
+	UpdatedAt            time.Time     `json:"updated_at"`
+	FileCount            int64         `json:"file_count"`
+	TotalSize            int64         `json:"total_size"`
 }
 
 // SetPermissionRequest 设置项目权限请求
@@ -81,8 +131,52 @@ type ProjectListRequest struct {
 	SortOrder string `json:"sort_order" form:"sort_order"` // 排序方向（asc/desc）
 }
 
-// PaginatedProjectResponse 分页项目响应
-type PaginatedProjectResponse struct {
-	Items []*ProjectResponse `json:"items"` // 项目列表
-	Total int64              `json:"total"` // 总项目数
+// WebhookRequest 创建或更新项目Webhook请求
+type WebhookRequest struct {
+	URL     string   `json:"url" binding:"required" example:"https://ci.example.com/hooks/oss"` // 接收推送的地址，只支持 http 和 https
+	Secret  string   `json:"secret" example:""`                                                 // 签名密钥，创建时为空则自动生成，更新时为空则保持不变
+	Events  []string `json:"events" binding:"required,min=1" example:"upload,delete"`           // 订阅的事件：upload、delete、share
+	Enabled *bool    `json:"enabled" example:"true"`                                            // 是否启用，创建时默认启用，更新时为空则保持不变
+}
+
+// WebhookResponse 项目Webhook
+type WebhookResponse struct {
+	ID        string    `json:"id"`
+	ProjectID string    `json:"project_id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Enabled   bool      `json:"enabled"`
+	Secret    string    `json:"secret,omitempty"` // 仅在创建或更换密钥时返回一次
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookPayload Webhook推送的请求体，签名在 X-Signature 请求头中
+type WebhookPayload struct {
+	Event      string        `json:"event"`           // 事件：upload、delete、share
+	OccurredAt time.Time     `json:"occurred_at"`     // 事件发生时间
+	ProjectID  string        `json:"project_id"`      // 项目ID
+	UserID     string        `json:"user_id"`         // 操作人ID
+	File       WebhookFile   `json:"file"`            // 相关的文件或文件夹
+	Share      *WebhookShare `json:"share,omitempty"` // 分享事件的分享信息
+}
+
+// WebhookFile Webhook推送中的文件信息
+type WebhookFile struct {
+	ID       string `json:"id"`
+	FileName string `json:"file_name"`
+	FullPath string `json:"full_path"`
+	FileSize int64  `json:"file_size"`
+	FileHash string `json:"file_hash"`
+	MimeType string `json:"mime_type"`
+	IsFolder bool   `json:"is_folder"`
+	Version  int    `json:"version"`
+}
+
+// WebhookShare Webhook推送中的分享信息，不包含分享码和密码
+type WebhookShare struct {
+	ID            string     `json:"id"`
+	ExpireAt      *time.Time `json:"expire_at"`
+	DownloadLimit int        `json:"download_limit"` // 0表示无限制
 }
@@ -35,8 +35,16 @@ type RoleListRequest struct {
 	Size   int    `form:"size" example:"10"`  // 每页数量
 }
 
-// RoleListResponse 角色列表响应
-type RoleListResponse struct {
-	Total int64          `json:"total" example:"100"` // 总数
-	List  []RoleResponse `json:"list"`                // 角色列表
+// RolePolicy 权限策略（Casbin p规则）
+type RolePolicy struct {
+	Sub    string `json:"sub" binding:"required" example:"GROUP_ADMIN"` // 主体，角色编码或 user:<用户ID>
+	Domain string `json:"domain" binding:"required" example:"group:1"`  // 域，如 system、group:<ID>、project:<ID>
+	Obj    string `json:"obj" binding:"required" example:"projects"`    // 资源类型，* 表示全部
+	Act    string `json:"act" binding:"required" example:"read"`        // 操作，* 表示全部
+}
+
+// RolePolicyQuery 权限策略查询条件
+type RolePolicyQuery struct {
+	Sub    string `form:"sub" example:"GROUP_ADMIN"` // 主体，精确匹配
+	Domain string `form:"domain" example:"group:1"`  // 域，精确匹配
 }
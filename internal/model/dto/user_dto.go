@@ -21,6 +21,11 @@ type UserUpdateRequest struct {
 	Avatar string `json:"avatar" example:"https://example.com/avatar.jpg"` // 头像URL
 }
 
+// UserAvatarResponse 上传头像响应
+type UserAvatarResponse struct {
+	Avatar string `json:"avatar" example:"/api/oss/user/avatar/u_123/r_456.png"` // 头像URL
+}
+
 // UserPasswordUpdateRequest 用户密码更新请求
 type UserPasswordUpdateRequest struct {
 	OldPassword string `json:"old_password" binding:"required" example:"oldpassword123"`              // 旧密码
@@ -37,6 +42,7 @@ type UserResponse struct {
 	LastLoginAt *time.Time     `json:"last_login_at,omitempty"`                         // 最后登录时间
 	LastLoginIP string         `json:"last_login_ip,omitempty"`                         // 最后登录IP
 	CreatedAt   time.Time      `json:"created_at" example:"2023-01-01T12:00:00Z"`       // 创建时间
+	TOTPEnabled bool           `json:"totp_enabled"`                                    // 是否已开启两步验证
 	Roles       []RoleResponse `json:"roles,omitempty"`                                 // 用户角色
 }
 
@@ -44,21 +50,103 @@ type UserResponse struct {
 type UserListRequest struct {
 	Email  string `form:"email" example:"user@example.com"` // 用户邮箱，模糊查询
 	Name   string `form:"name" example:"张"`                 // 用户姓名，模糊查询
-	Status int    `form:"status" example:"1"`               // 状态：1-正常，2-禁用，3-锁定
+	Status int    `form:"status" example:"1"`               // 状态：1-正常，2-禁用，3-锁定，5-已删除
 	Page   int    `form:"page" example:"1"`                 // 页码
 	Size   int    `form:"size" example:"10"`                // 每页数量
+
+	IncludeDeleted bool `form:"include_deleted" example:"false"` // 是否包含已删除的用户，默认不包含
+}
+
+// RefreshTokenRequest 刷新令牌请求
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"` // 刷新令牌
+}
+
+// ResendVerificationRequest 重新发送验证邮件请求
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email" example:"user@x.com"` // 注册邮箱
 }
 
-// UserListResponse 用户列表响应
-type UserListResponse struct {
-	Total int64          `json:"total" example:"100"` // 总数
-	List  []UserResponse `json:"list"`                // 用户列表
+// LogoutRequest 注销请求
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"omitempty"` // 同时注销的刷新令牌（可选）
+}
+
+// BulkUserStatusRequest 批量更新用户状态请求
+type BulkUserStatusRequest struct {
+	UserIDs []string `json:"user_ids" binding:"required,min=1,max=500,dive,required"` // 用户ID列表
+	Status  int      `json:"status" binding:"required,oneof=1 2 3"`                   // 状态：1-正常，2-禁用，3-锁定
+}
+
+// BulkUserStatusResult 单个用户的状态更新结果
+type BulkUserStatusResult struct {
+	UserID  string `json:"user_id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"` // 失败原因
+}
+
+// BulkUserStatusResponse 批量更新用户状态响应
+type BulkUserStatusResponse struct {
+	Updated int                    `json:"updated"` // 成功更新的用户数
+	Failed  int                    `json:"failed"`  // 未更新的用户数
+	Results []BulkUserStatusResult `json:"results"` // 每个用户的结果，顺序与请求一致
 }
 
 // LoginResponse 登录响应
+// 用户开启两步验证时只返回 two_factor_required、challenge_token 和挑战的过期时间，提交验证码后才返回令牌
 type LoginResponse struct {
-	Token        string       `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`         // 访问令牌
-	RefreshToken string       `json:"refresh_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."` // 刷新令牌
-	ExpiresAt    int64        `json:"expires_at" example:"1672531200"`                                 // 过期时间戳
-	UserInfo     UserResponse `json:"user_info"`                                                       // 用户信息
+	Token             string       `json:"token,omitempty" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`         // 访问令牌
+	RefreshToken      string       `json:"refresh_token,omitempty" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."` // 刷新令牌
+	ExpiresAt         int64        `json:"expires_at" example:"1672531200"`                                           // 过期时间戳
+	UserInfo          UserResponse `json:"user_info"`                                                                 // 用户信息
+	TwoFactorRequired bool         `json:"two_factor_required,omitempty"`                                             // 需要提交两步验证码
+	ChallengeToken    string       `json:"challenge_token,omitempty"`                                                 // 提交两步验证码时使用的挑战令牌
+}
+
+// TOTPSetupResponse 开启两步验证响应
+type TOTPSetupResponse struct {
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXP"`                                           // base32编码的密钥，供手动输入
+	URI    string `json:"uri" example:"otpauth://totp/OSS:user%40example.com?secret=JBSWY3DPEHPK3PXP"` // otpauth URI，可生成二维码供身份验证器应用扫描
+}
+
+// TOTPCodeRequest 两步验证码请求
+type TOTPCodeRequest struct {
+	Code string `json:"code" binding:"required" example:"123456"` // 身份验证器应用生成的6位验证码，部分接口也接受恢复码
+}
+
+// TOTPVerifyRequest 登录时提交两步验证码请求
+type TOTPVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`       // 登录接口返回的挑战令牌
+	Code           string `json:"code" binding:"required" example:"123456"` // 6位验证码或恢复码
+}
+
+// TOTPRecoveryCodesResponse 恢复码响应，恢复码只返回这一次
+type TOTPRecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes" example:"a1b2c-3d4e5"` // 每个恢复码可代替验证码使用一次
+}
+
+// UserPermissionsResponse 当前用户的权限汇总，前端据此决定显示哪些操作
+type UserPermissionsResponse struct {
+	SystemRoles []string                `json:"system_roles" example:"MEMBER"` // 系统角色编码
+	Groups      []GroupPermissionItem   `json:"groups"`                        // 所在群组及群组内的角色
+	Projects    []ProjectPermissionItem `json:"projects"`                      // 可访问的项目及项目内的角色和文件权限
+	Policies    []RolePolicy            `json:"policies"`                      // 直接授予用户的权限策略，不包括通过角色获得的权限
+}
+
+// GroupPermissionItem 用户在群组中的权限
+type GroupPermissionItem struct {
+	GroupID   string   `json:"group_id"`
+	GroupName string   `json:"group_name"`
+	Role      string   `json:"role,omitempty" example:"member"` // 群组成员角色：admin、member，不是群组成员时为空
+	Roles     []string `json:"roles" example:"GROUP_ADMIN"`     // 在群组域中的 Casbin 角色
+}
+
+// ProjectPermissionItem 用户在项目中的权限
+type ProjectPermissionItem struct {
+	ProjectID   string   `json:"project_id"`
+	ProjectName string   `json:"project_name"`
+	GroupID     string   `json:"group_id"`
+	Role        string   `json:"role,omitempty" example:"editor"`           // 项目成员角色：admin、editor、viewer，不是项目成员时为空
+	Roles       []string `json:"roles" example:"GROUP_ADMIN"`               // 在项目域中的 Casbin 角色
+	FileActions []string `json:"file_actions" example:"read,create,update"` // 对项目文件可执行的操作，不含文件夹授权的调整
 }
@@ -1,5 +1,7 @@
 package entity
 
+import "time"
+
 // CasbinRule Casbin规则实体
 type CasbinRule struct {
 	ID    string `gorm:"primaryKey;autoIncrement"`
@@ -16,3 +18,19 @@ type CasbinRule struct {
 func (CasbinRule) TableName() string {
 	return "casbin_rule"
 }
+
+// PolicyImportRecord 策略导入审计记录，保存导入前的策略快照以便回滚
+type PolicyImportRecord struct {
+	ID             string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	AdminID        string    `gorm:"type:varchar(36);not null;index" json:"admin_id"`
+	Version        int       `gorm:"not null" json:"version"`              // 导入快照的格式版本
+	AddedCount     int       `gorm:"not null" json:"added_count"`          // 新增规则数
+	RemovedCount   int       `gorm:"not null" json:"removed_count"`        // 删除规则数
+	PreviousPolicy string    `gorm:"type:longtext" json:"previous_policy"` // 导入前的策略快照(JSON)
+	CreatedAt      time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName 表名
+func (PolicyImportRecord) TableName() string {
+	return "casbin_policy_imports"
+}
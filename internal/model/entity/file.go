@@ -8,25 +8,34 @@ import (
 
 // File 文件模型
 type File struct {
-	ID             string         `gorm:"primaryKey;type:varchar(36)" json:"id"`
-	ProjectID      string         `gorm:"type:varchar(36);not null;index:idx_project_path,priority:1" json:"project_id"`
-	FileName       string         `gorm:"type:varchar(255);not null" json:"file_name"`
-	FilePath       string         `gorm:"type:varchar(512);not null;index" json:"file_path"`
-	FullPath       string         `gorm:"type:varchar(768);not null" json:"full_path"`
-	FileHash       string         `gorm:"type:varchar(64);not null;index" json:"file_hash"`
-	FileSize       int64          `gorm:"not null" json:"file_size"`
-	MimeType       string         `gorm:"type:varchar(128)" json:"mime_type"`
-	Extension      string         `gorm:"type:varchar(20)" json:"extension"`
-	IsFolder       bool           `gorm:"default:false;not null" json:"is_folder"`
-	IsDeleted      bool           `gorm:"default:false;not null;index" json:"is_deleted"`
-	UploaderID     string         `gorm:"type:varchar(36);not null" json:"uploader_id"`
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
-	DeletedAt      *time.Time     `json:"deleted_at"`
-	DeletedBy      *string        `gorm:"type:varchar(36)" json:"deleted_by"`
-	CurrentVersion int            `gorm:"default:1;not null" json:"current_version"`
-	PreviewURL     string         `gorm:"type:varchar(512)" json:"preview_url"`
-	GormDeletedAt  gorm.DeletedAt `gorm:"index" json:"-"` // 用于GORM的软删除，区别于业务上的IsDeleted标志
+	ID                string         `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	ProjectID         string         `gorm:"type:varchar(36);not null;index:idx_project_path,priority:1;index:idx_project_hash,priority:1;index:idx_project_ext,priority:1;index:idx_project_updated,priority:1;uniqueIndex:idx_project_live_path,priority:1" json:"project_id"`
+	FileName          string         `gorm:"type:varchar(255);not null" json:"file_name"`
+	FilePath          string         `gorm:"type:varchar(512);not null;index" json:"file_path"`
+	FullPath          string         `gorm:"type:varchar(768);not null" json:"full_path"`
+	FileHash          string         `gorm:"type:varchar(64);not null;index;index:idx_project_hash,priority:2" json:"file_hash"`
+	FileSize          int64          `gorm:"not null" json:"file_size"`
+	MimeType          string         `gorm:"type:varchar(128)" json:"mime_type"`
+	Extension         string         `gorm:"type:varchar(20);index:idx_project_ext,priority:2" json:"extension"`
+	IsFolder          bool           `gorm:"default:false;not null" json:"is_folder"`
+	IsDeleted         bool           `gorm:"default:false;not null;index" json:"is_deleted"`
+	UploaderID        string         `gorm:"type:varchar(36);not null" json:"uploader_id"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `gorm:"index:idx_project_updated,priority:2" json:"updated_at"`
+	DeletedAt         *time.Time     `json:"deleted_at"`
+	DeletedBy         *string        `gorm:"type:varchar(36)" json:"deleted_by"`
+	CurrentVersion    int            `gorm:"default:1;not null" json:"current_version"`
+	PreviewURL        string         `gorm:"type:varchar(512)" json:"preview_url"`
+	WatermarkRequired bool           `gorm:"default:false;not null" json:"watermark_required"`            // 下载时是否强制添加水印
+	WatermarkText     string         `gorm:"type:varchar(255)" json:"watermark_text"`                     // 水印模板，空表示使用默认模板
+	StorageBackend    string         `gorm:"type:varchar(64);not null;default:''" json:"storage_backend"` // 文件及其历史版本所在的存储后端，空表示默认后端
+	ThumbnailKey      string         `gorm:"type:varchar(1024)" json:"-"`                                 // 缩略图对象名，位于默认存储后端，空表示没有缩略图
+	GormDeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`                                              // 用于GORM的软删除，区别于业务上的IsDeleted标志
+	ObjectEncryption                 // 当前版本对象的加密信息
+
+	// 未删除文件的路径键，由数据库根据 full_path 生成，删除后为NULL；与 project_id 组成唯一索引，防止并发创建同一路径的文件或文件夹
+	// 文件与文件夹去掉末尾的/后比较，与按路径查找时的规则一致；按字节比较，忽略大小写的项目仍由写入前的检查保证
+	PathKey *string `gorm:"->;type:char(64) GENERATED ALWAYS AS (IF(is_deleted OR gorm_deleted_at IS NOT NULL, NULL, SHA2(TRIM(TRAILING '/' FROM full_path), 256))) STORED;uniqueIndex:idx_project_live_path,priority:2" json:"-"`
 
 	Project  Project `gorm:"foreignKey:ProjectID" json:"project"`
 	Uploader User    `gorm:"foreignKey:UploaderID" json:"uploader"`
@@ -38,6 +47,17 @@ func (File) TableName() string {
 	return "files"
 }
 
+// ObjectEncryption 对象的加密信息，密钥不保存在数据库中
+type ObjectEncryption struct {
+	EncryptionVersion int    `gorm:"not null;default:0" json:"-"` // 加密格式版本，0表示未加密
+	EncryptionNonce   string `gorm:"type:varchar(32)" json:"-"`   // 对象的 nonce 前缀，十六进制
+}
+
+// Encrypted 对象是否加密存储
+func (e ObjectEncryption) Encrypted() bool {
+	return e.EncryptionVersion > 0
+}
+
 // FileVersion 文件版本模型
 type FileVersion struct {
 	ID         string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
@@ -45,10 +65,18 @@ type FileVersion struct {
 	Version    int       `gorm:"not null;index:idx_file_version,priority:2" json:"version"`
 	FileHash   string    `gorm:"type:varchar(64);not null" json:"file_hash"`
 	FileSize   int64     `gorm:"not null" json:"file_size"`
+	StorageKey string    `gorm:"type:varchar(1024)" json:"-"` // 该版本内容所在的对象名，为空表示内容已被覆盖或为早期数据
 	UploaderID string    `gorm:"type:varchar(36);not null" json:"uploader_id"`
 	CreatedAt  time.Time `json:"created_at"`
 	Comment    string    `gorm:"type:varchar(255)" json:"comment"`
 
+	// 该版本对象的加密信息，对象在当前对象与历史版本对象之间复制时密文不变
+	ObjectEncryption
+
+	// 下载清单的分块校验和缓存，首次请求下载清单时计算；分块大小配置变化后重新计算
+	ChunkSize      int64  `gorm:"not null;default:0" json:"-"` // 计算校验和时的分块大小，0表示尚未计算
+	ChunkChecksums string `gorm:"type:mediumtext" json:"-"`    // 各分块的SHA-256，按顺序以逗号分隔
+
 	File     File `gorm:"foreignKey:FileID" json:"file"`
 	Uploader User `gorm:"foreignKey:UploaderID" json:"uploader"`
 }
@@ -58,17 +86,70 @@ func (FileVersion) TableName() string {
 	return "file_versions"
 }
 
+// FileTag 文件标签，标签按项目划分，同一项目内的文件可使用相同的标签
+type FileTag struct {
+	ID        string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	ProjectID string    `gorm:"type:varchar(36);not null;index:idx_project_tag,priority:1" json:"project_id"`
+	FileID    string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_file_tag,priority:1" json:"file_id"`
+	Name      string    `gorm:"type:varchar(64);not null;uniqueIndex:idx_file_tag,priority:2;index:idx_project_tag,priority:2" json:"name"`
+	CreatedBy string    `gorm:"type:varchar(36);not null" json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 表名
+func (FileTag) TableName() string {
+	return "file_tags"
+}
+
+// FileDeny 文件访问拒绝规则，优先于项目角色授予的权限，被拒绝的用户不能查看、下载或修改该文件
+// 对文件夹设置时同样作用于其下的子文件夹和文件
+type FileDeny struct {
+	ID        string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	ProjectID string    `gorm:"type:varchar(36);not null;index:idx_project_deny_user,priority:1" json:"project_id"`
+	FileID    string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_file_deny,priority:1" json:"file_id"`
+	UserID    string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_file_deny,priority:2;index:idx_project_deny_user,priority:2" json:"user_id"`
+	CreatedBy string    `gorm:"type:varchar(36);not null" json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 表名
+func (FileDeny) TableName() string {
+	return "file_denies"
+}
+
+// FolderPermission 文件夹授权，授予用户对文件夹及其下全部内容的角色
+// 检查权限时使用离文件最近的上级文件夹上的授权，代替用户的项目角色；没有授权时使用项目角色
+type FolderPermission struct {
+	ID        string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	ProjectID string    `gorm:"type:varchar(36);not null;index:idx_project_folder_user,priority:1" json:"project_id"`
+	FolderID  string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_folder_user,priority:1" json:"folder_id"`
+	UserID    string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_folder_user,priority:2;index:idx_project_folder_user,priority:2" json:"user_id"`
+	Role      string    `gorm:"type:varchar(20);not null" json:"role"` // admin、editor、viewer，与项目角色相同
+	CreatedBy string    `gorm:"type:varchar(36);not null" json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 表名
+func (FolderPermission) TableName() string {
+	return "folder_permissions"
+}
+
 // FileShare 文件分享模型
 type FileShare struct {
 	ID            string     `gorm:"primaryKey;type:varchar(36)" json:"id"`
 	FileID        string     `gorm:"type:varchar(36);not null" json:"file_id"`
 	UserID        string     `gorm:"type:varchar(36);not null;index" json:"user_id"`
 	ShareCode     string     `gorm:"type:varchar(32);uniqueIndex;not null" json:"share_code"`
-	Password      string     `gorm:"type:varchar(32)" json:"password,omitempty"`
+	Password      string     `gorm:"type:varchar(100)" json:"-"` // bcrypt哈希，空表示无需密码
 	ExpireAt      *time.Time `json:"expire_at"`
 	DownloadLimit int        `gorm:"default:0" json:"download_limit"` // 0表示无限制
 	DownloadCount int        `gorm:"default:0" json:"download_count"`
+	Watermark     bool       `gorm:"default:false;not null" json:"watermark"` // 下载时是否添加水印
+	WatermarkText string     `gorm:"type:varchar(255)" json:"watermark_text"` // 水印模板，空表示使用默认模板
+	Recipient     string     `gorm:"type:varchar(100)" json:"recipient"`      // 接收人标识，用于水印
 	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"` // 下载次数变化时同步更新
 
 	File File `gorm:"foreignKey:FileID" json:"file"`
 	User User `gorm:"foreignKey:UserID" json:"user"`
@@ -0,0 +1,48 @@
+package entity
+
+// 文件分类常量，按文件的 MIME 类型划分，文件夹不属于任何分类
+const (
+	FileCategoryImage    = "image"
+	FileCategoryVideo    = "video"
+	FileCategoryAudio    = "audio"
+	FileCategoryDocument = "document"
+	FileCategoryArchive  = "archive"
+	FileCategoryOther    = "other" // 不属于以上任何分类的文件，包括未知类型
+)
+
+// FileCategoryMimePrefixes 各分类包含的 MIME 类型前缀，按前缀匹配以兼容带参数的类型（如 text/plain; charset=utf-8）
+// 新增类型时只需修改这里，列表筛选与搜索都据此生成查询条件
+var FileCategoryMimePrefixes = map[string][]string{
+	FileCategoryImage: {"image/"},
+	FileCategoryVideo: {"video/"},
+	FileCategoryAudio: {"audio/"},
+	FileCategoryDocument: {
+		"text/",
+		"application/pdf",
+		"application/msword",
+		"application/vnd.openxmlformats-officedocument.",
+		"application/vnd.ms-excel",
+		"application/vnd.ms-powerpoint",
+		"application/vnd.oasis.opendocument.",
+		"application/rtf",
+		"application/epub+zip",
+	},
+	FileCategoryArchive: {
+		"application/zip",
+		"application/x-zip-compressed",
+		"application/vnd.rar",
+		"application/x-rar-compressed",
+		"application/x-7z-compressed",
+		"application/x-tar",
+		"application/gzip",
+		"application/x-gzip",
+		"application/x-bzip2",
+		"application/x-xz",
+	},
+}
+
+// IsValidFileCategory 判断是否为支持的文件分类
+func IsValidFileCategory(category string) bool {
+	_, ok := FileCategoryMimePrefixes[category]
+	return ok || category == FileCategoryOther
+}
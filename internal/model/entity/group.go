@@ -8,18 +8,21 @@ import (
 
 // Group 群组模型
 type Group struct {
-	ID              string         `gorm:"primaryKey;type:varchar(36)" json:"id"`
-	Name            string         `gorm:"type:varchar(64);not null" json:"name"`
-	Description     string         `gorm:"type:text" json:"description"`
-	GroupKey        string         `gorm:"type:varchar(64);uniqueIndex;not null" json:"group_key"` // MinIO桶名
-	InviteCode      string         `gorm:"type:varchar(32);uniqueIndex;not null" json:"invite_code"`
-	InviteExpiresAt *time.Time     `json:"invite_expires_at"`
-	StorageQuota    int64          `gorm:"default:0" json:"storage_quota"` // 存储配额，0表示无限制
-	CreatorID       string         `gorm:"type:varchar(36);not null" json:"creator_id"`
-	CreatedAt       time.Time      `json:"created_at"`
-	UpdatedAt       time.Time      `json:"updated_at"`
-	Status          int            `gorm:"type:tinyint;default:1;not null" json:"status"` // 1-正常, 2-禁用, 3-锁定
-	DeletedAt       gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                  string         `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	Name                string         `gorm:"type:varchar(64);not null" json:"name"`
+	Description         string         `gorm:"type:text" json:"description"`
+	GroupKey            string         `gorm:"type:varchar(64);uniqueIndex;not null" json:"group_key"` // MinIO桶名
+	InviteCode          string         `gorm:"type:varchar(32);uniqueIndex;not null" json:"invite_code"`
+	InviteExpiresAt     *time.Time     `json:"invite_expires_at"`
+	StorageQuota        int64          `gorm:"default:0" json:"storage_quota"`                   // 存储配额，0表示无限制
+	DefaultProjectQuota int64          `gorm:"default:0" json:"default_project_quota"`           // 新建项目的默认存储配额，0表示不单独限制
+	EncryptionEnabled   bool           `gorm:"default:false;not null" json:"encryption_enabled"` // 新上传的文件是否加密存储，不影响已有文件
+	StorageReserved     int64          `gorm:"->;default:0;not null" json:"-"`                   // 进行中的写入预占的存储量，只由配额预占语句修改，Save 不会覆盖
+	CreatorID           string         `gorm:"type:varchar(36);not null" json:"creator_id"`
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+	Status              int            `gorm:"type:tinyint;default:1;not null" json:"status"` // 1-正常, 2-禁用, 3-锁定
+	DeletedAt           gorm.DeletedAt `gorm:"index" json:"-"`
 
 	Creator User `gorm:"foreignKey:CreatorID" json:"creator"`
 }
@@ -47,3 +50,46 @@ type GroupMember struct {
 func (GroupMember) TableName() string {
 	return "group_members"
 }
+
+// GroupInvitation 按邮箱邀请尚未注册的用户加入群组，用户注册并激活后自动加入，随后删除邀请
+type GroupInvitation struct {
+	ID        string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	GroupID   string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_group_email,priority:1" json:"group_id"`
+	Email     string    `gorm:"type:varchar(128);not null;uniqueIndex:idx_group_email,priority:2;index" json:"email"` // 小写
+	Role      string    `gorm:"type:varchar(20);not null" json:"role"`                                                // 加入后的角色：admin、member
+	InviterID string    `gorm:"type:varchar(36);not null" json:"inviter_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 表名
+func (GroupInvitation) TableName() string {
+	return "group_invitations"
+}
+
+// 入群申请状态
+const (
+	GroupJoinRequestPending  = "pending"  // 待审批
+	GroupJoinRequestApproved = "approved" // 已批准
+	GroupJoinRequestRejected = "rejected" // 已拒绝
+)
+
+// GroupJoinRequest 用户申请加入群组，群组管理员批准后成为群组成员
+type GroupJoinRequest struct {
+	ID         string     `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	GroupID    string     `gorm:"type:varchar(36);not null;index:idx_group_join_status,priority:1" json:"group_id"`
+	UserID     string     `gorm:"type:varchar(36);not null;index" json:"user_id"`
+	Message    string     `gorm:"type:varchar(255)" json:"message"`                                               // 申请说明
+	Status     string     `gorm:"type:varchar(16);not null;index:idx_group_join_status,priority:2" json:"status"` // pending、approved、rejected
+	ReviewerID string     `gorm:"type:varchar(36)" json:"reviewer_id"`                                            // 审批人
+	ReviewedAt *time.Time `json:"reviewed_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	User User `gorm:"foreignKey:UserID" json:"user"`
+}
+
+// TableName 表名
+func (GroupJoinRequest) TableName() string {
+	return "group_join_requests"
+}
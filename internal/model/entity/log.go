@@ -8,11 +8,11 @@ import (
 
 // OperationLog 操作日志模型
 type OperationLog struct {
-	ID         string         `gorm:"primaryKey;type:bigint unsigned" json:"id"`
-	UserID     string         `gorm:"type:bigint unsigned;index;not null" json:"user_id"`
+	ID         string         `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	UserID     string         `gorm:"type:varchar(36);index;not null" json:"user_id"`
 	Action     string         `gorm:"type:varchar(50);not null" json:"action"` // 操作类型: create, update, delete, share, etc.
 	Module     string         `gorm:"type:varchar(50);not null" json:"module"` // 模块: file, group, project, etc.
-	TargetID   string         `gorm:"type:bigint unsigned;index" json:"target_id"`
+	TargetID   string         `gorm:"type:varchar(36);index" json:"target_id"`
 	TargetType string         `gorm:"type:varchar(50)" json:"target_type"` // 目标类型: file, folder, group, project, etc.
 	Details    string         `gorm:"type:text" json:"details"`            // 详细信息，JSON格式
 	IP         string         `gorm:"type:varchar(50)" json:"ip"`          // 操作IP
@@ -50,16 +50,16 @@ func (AccessLog) TableName() string {
 
 // Log 操作日志模型
 type Log struct {
-	ID              string    `gorm:"primaryKey;type:bigint unsigned" json:"id"`
-	UserID          string    `gorm:"type:bigint unsigned;not null;index:idx_user_time,priority:1" json:"user_id"`
-	GroupID         string    `gorm:"type:bigint unsigned;not null" json:"group_id"`
-	ProjectID       string    `gorm:"type:bigint unsigned;not null;index:idx_project_time,priority:1" json:"project_id"`
-	FileID          *string   `gorm:"type:bigint unsigned" json:"file_id"`
+	ID              string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	UserID          string    `gorm:"type:varchar(36);not null;index:idx_user_time,priority:1" json:"user_id"`
+	GroupID         string    `gorm:"type:varchar(36);not null" json:"group_id"`
+	ProjectID       string    `gorm:"type:varchar(36);not null;index:idx_project_time,priority:1" json:"project_id"`
+	FileID          *string   `gorm:"type:varchar(36);index:idx_file_time,priority:1" json:"file_id"`
 	Operation       string    `gorm:"type:varchar(20);not null" json:"operation"`
 	IPAddress       string    `gorm:"type:varchar(50);not null" json:"ip_address"`
 	UserAgent       string    `gorm:"type:varchar(255)" json:"user_agent"`
 	Status          int       `gorm:"default:200;not null" json:"status"`
-	CreatedAt       time.Time `gorm:"not null;index:idx_user_time,priority:2;index:idx_project_time,priority:2" json:"created_at"`
+	CreatedAt       time.Time `gorm:"not null;index:idx_user_time,priority:2;index:idx_project_time,priority:2;index:idx_file_time,priority:2" json:"created_at"`
 	RequestDetails  string    `gorm:"type:text" json:"request_details"`
 	ResponseDetails string    `gorm:"type:text" json:"response_details"`
 	ExecutionTime   int       `json:"execution_time"` // 毫秒
@@ -75,12 +75,29 @@ func (Log) TableName() string {
 	return "logs"
 }
 
+// LogArchive 操作日志归档记录，对应MinIO中的一个压缩归档对象
+type LogArchive struct {
+	ID         string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	BucketName string    `gorm:"type:varchar(64);not null" json:"bucket_name"`
+	ObjectName string    `gorm:"type:varchar(255);not null" json:"object_name"`
+	StartTime  time.Time `gorm:"not null;index:idx_archive_range,priority:1" json:"start_time"` // 归档内最早日志时间
+	EndTime    time.Time `gorm:"not null;index:idx_archive_range,priority:2" json:"end_time"`   // 归档内最晚日志时间
+	EntryCount int       `gorm:"not null" json:"entry_count"`
+	Size       int64     `gorm:"not null" json:"size"` // 压缩后大小
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TableName 表名
+func (LogArchive) TableName() string {
+	return "log_archives"
+}
+
 // StorageStat 存储统计模型
 type StorageStat struct {
-	ID           string    `gorm:"primaryKey;type:bigint unsigned" json:"id"`
-	GroupID      string    `gorm:"type:bigint unsigned;not null" json:"group_id"`
-	ProjectID    string    `gorm:"type:bigint unsigned;not null;index:idx_project_date,priority:1" json:"project_id"`
-	StatDate     time.Time `gorm:"not null;index:idx_project_date,priority:2;index:idx_date" json:"stat_date"`
+	ID           string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	GroupID      string    `gorm:"type:varchar(36);not null" json:"group_id"`
+	ProjectID    string    `gorm:"type:varchar(36);not null;uniqueIndex:idx_project_date,priority:1" json:"project_id"`
+	StatDate     time.Time `gorm:"not null;uniqueIndex:idx_project_date,priority:2;index:idx_date" json:"stat_date"`
 	FileCount    int64     `gorm:"default:0;not null" json:"file_count"`
 	TotalSize    int64     `gorm:"default:0;not null" json:"total_size"`
 	IncreaseSize int64     `gorm:"default:0;not null" json:"increase_size"`
@@ -109,4 +126,7 @@ const (
 	OperationRename       = "rename"
 	OperationMove         = "move"
 	OperationCopy         = "copy"
+	OperationUpdateStatus = "update_status"
+	OperationRollback     = "rollback"
+	OperationMigrate      = "migrate"
 )
@@ -6,18 +6,35 @@ import (
 	"gorm.io/gorm"
 )
 
+// 项目状态
+const (
+	ProjectStatusNormal   = 1 // 正常
+	ProjectStatusArchived = 2 // 归档，文件只读，仍可列表和下载
+	ProjectStatusDeleted  = 3 // 已删除
+)
+
 // Project 项目模型
 type Project struct {
-	ID          string         `gorm:"primaryKey;type:varchar(36)" json:"id"`
-	GroupID     string         `gorm:"type:varchar(36);not null;index" json:"group_id"`
-	Name        string         `gorm:"type:varchar(64);not null" json:"name"`
-	Description string         `gorm:"type:text" json:"description"`
-	PathPrefix  string         `gorm:"type:varchar(128);not null" json:"path_prefix"`
-	CreatorID   string         `gorm:"type:varchar(36);not null" json:"creator_id"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	Status      int            `gorm:"type:tinyint;default:1;not null" json:"status"` // 1-正常, 2-归档, 3-删除
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID                        string         `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	GroupID                   string         `gorm:"type:varchar(36);not null;index" json:"group_id"`
+	Name                      string         `gorm:"type:varchar(64);not null" json:"name"`
+	Description               string         `gorm:"type:text" json:"description"`
+	PathPrefix                string         `gorm:"type:varchar(128);not null" json:"path_prefix"`
+	CreatorID                 string         `gorm:"type:varchar(36);not null" json:"creator_id"`
+	CreatedAt                 time.Time      `json:"created_at"`
+	UpdatedAt                 time.Time      `json:"updated_at"`
+	Status                    int            `gorm:"type:tinyint;default:1;not null" json:"status"`          // 1-正常, 2-归档, 3-删除
+	StorageQuota              int64          `gorm:"default:0" json:"storage_quota"`                         // 项目存储配额，0表示不单独限制（仅受群组配额约束）
+	CaseInsensitivePaths      bool           `gorm:"default:false;not null" json:"case_insensitive_paths"`   // 文件名冲突检查是否忽略大小写
+	PublicRead                bool           `gorm:"default:false;not null" json:"public_read"`              // 是否允许匿名读取文件列表与下载，写操作仍需授权
+	ShareDefaultExpireHours   int            `gorm:"default:0;not null" json:"share_default_expire_hours"`   // 分享默认有效期（小时），0表示未设置
+	ShareMaxExpireHours       int            `gorm:"default:0;not null" json:"share_max_expire_hours"`       // 分享最长有效期（小时），0表示不限制
+	ShareDefaultDownloadLimit int            `gorm:"default:0;not null" json:"share_default_download_limit"` // 分享默认下载次数限制，0表示未设置
+	ShareMaxDownloadLimit     int            `gorm:"default:0;not null" json:"share_max_download_limit"`     // 分享最大下载次数限制，0表示不限制
+	UploadPolicy              string         `gorm:"type:text" json:"upload_policy"`                         // 项目级上传策略（JSON），为空时使用全局配置
+	MaxVersions               int            `gorm:"default:0;not null" json:"max_versions"`                 // 每个文件保留的最大版本数，0表示使用全局配置
+	StorageReserved           int64          `gorm:"->;default:0;not null" json:"-"`                         // 进行中的写入预占的存储量，只由配额预占语句修改，Save 不会覆盖
+	DeletedAt                 gorm.DeletedAt `gorm:"index" json:"-"`
 
 	Group   Group `gorm:"foreignKey:GroupID" json:"group"`
 	Creator User  `gorm:"foreignKey:CreatorID" json:"creator"`
@@ -67,4 +84,22 @@ func (ProjectMember) TableName() string {
 	return "project_members"
 }
 
+// ProjectWebhook 项目Webhook配置，项目内发生订阅的文件事件时向 URL 推送签名的JSON
+type ProjectWebhook struct {
+	ID        string    `gorm:"primaryKey;type:varchar(36)" json:"id"`
+	ProjectID string    `gorm:"type:varchar(36);not null;index" json:"project_id"`
+	URL       string    `gorm:"type:varchar(1024);not null" json:"url"`
+	Secret    string    `gorm:"type:varchar(128);not null" json:"-"`         // 签名密钥，推送时以 HMAC-SHA256 签名请求体
+	Events    string    `gorm:"type:varchar(255);not null" json:"events"`    // 订阅的事件，逗号分隔，如 upload,delete,share
+	Enabled   bool      `gorm:"not null" json:"enabled"`                     // 是否启用
+	CreatedBy string    `gorm:"type:varchar(36);not null" json:"created_by"` // 创建人ID
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 表名
+func (ProjectWebhook) TableName() string {
+	return "project_webhooks"
+}
+
 // 角色常量在 roles.go 中定义
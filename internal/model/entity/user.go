@@ -7,15 +7,22 @@ import (
 // User 用户实体
 type User struct {
 	ID           string     `gorm:"primaryKey;type:varchar(36)" json:"id"`      // 用户ID (UUID)
-	Email        string     `gorm:"size:100;not null;uniqueIndex" json:"email"` // 用户邮箱，登录凭证
+	Email        string     `gorm:"size:128;not null;uniqueIndex" json:"email"` // 用户邮箱，登录凭证；删除后追加墓碑后缀以释放原邮箱
 	Name         string     `gorm:"size:50;not null" json:"name"`               // 用户姓名
 	PasswordHash string     `gorm:"size:100;not null" json:"-"`                 // 密码哈希值，不返回给前端
 	Avatar       string     `gorm:"size:255" json:"avatar"`                     // 用户头像URL
-	Status       int        `gorm:"default:1" json:"status"`                    // 状态（1-正常，2-禁用，3-锁定）
+	Status       int        `gorm:"default:1" json:"status"`                    // 状态（1-正常，2-禁用，3-锁定，4-待验证邮箱，5-已删除）
 	LastLoginAt  *time.Time `json:"last_login_at"`                              // 最后登录时间
 	LastLoginIP  string     `gorm:"size:50" json:"last_login_ip"`               // 最后登录IP
 	CreatedAt    time.Time  `gorm:"autoCreateTime" json:"created_at"`           // 创建时间
 	UpdatedAt    time.Time  `gorm:"autoUpdateTime" json:"updated_at"`           // 更新时间
+	DeletedAt    *time.Time `gorm:"index" json:"deleted_at,omitempty"`          // 删除时间，未删除时为空
+
+	// 两步验证（TOTP），密钥使用加密主密钥派生的密钥加密保存
+	TOTPSecret        string `gorm:"column:totp_secret;size:255" json:"-"`          // 加密后的TOTP密钥，开启流程中尚未确认时 TOTPEnabled 为false
+	TOTPEnabled       bool   `gorm:"column:totp_enabled;default:false" json:"-"`    // 是否已开启两步验证
+	TOTPLastStep      int64  `gorm:"column:totp_last_step;default:0" json:"-"`      // 最近一次验证通过的时间步，拒绝重复使用同一验证码
+	TOTPRecoveryCodes string `gorm:"column:totp_recovery_codes;type:text" json:"-"` // 未使用的恢复码的SHA-256，以逗号分隔
 
 	// 用户角色关联（多对多）
 	Roles []Role `gorm:"many2many:user_roles;" json:"roles,omitempty"` // 用户角色
@@ -31,8 +38,14 @@ const (
 	UserStatusNormal   = 1 // 正常状态
 	UserStatusDisabled = 2 // 禁用状态
 	UserStatusLocked   = 3 // 锁定状态
+
+	UserStatusPendingVerification = 4 // 待验证邮箱，开启注册邮箱验证时新用户的初始状态
+	UserStatusDeleted             = 5 // 已删除（软删除），可由管理员恢复
 )
 
+// UserEmailTombstone 软删除用户邮箱的墓碑后缀分隔符，后接删除时间戳
+const UserEmailTombstone = "#deleted-"
+
 // 隐藏敏感信息
 func (u *User) HideSensitiveInfo() {
 	u.PasswordHash = ""
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/utils"
+)
+
+// AuditRepository 审计日志仓库接口
+type AuditRepository interface {
+	// 写入与查询
+	Create(ctx context.Context, log *entity.Log) error
+	ListByFile(ctx context.Context, fileID string, page, pageSize int) ([]*entity.Log, int64, error)
+	ListByUser(ctx context.Context, filter *AuditLogFilter, page, pageSize int) ([]*entity.Log, int64, error)
+
+	// 保留与归档
+	ListBefore(ctx context.Context, before time.Time, limit int) ([]*entity.Log, error)
+	ArchiveAndDelete(ctx context.Context, archive *entity.LogArchive, logIDs []string) error
+	ListArchives(ctx context.Context, startTime, endTime time.Time) ([]*entity.LogArchive, error)
+}
+
+// AuditLogFilter 审计日志查询条件，零值字段不参与过滤
+type AuditLogFilter struct {
+	UserID    string
+	Operation string
+	StartTime *time.Time
+	EndTime   *time.Time
+}
+
+// auditRepository 审计日志仓库实现
+type auditRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditRepository 创建审计日志仓库实例
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{
+		db: db,
+	}
+}
+
+// Create 写入一条审计日志
+func (r *auditRepository) Create(ctx context.Context, log *entity.Log) error {
+	if log.ID == "" {
+		log.ID = utils.GenerateRecordID()
+	}
+	if log.CreatedAt.IsZero() {
+		log.CreatedAt = time.Now()
+	}
+	return r.db.WithContext(ctx).Omit("User", "Group", "Project", "File").Create(log).Error
+}
+
+// ListByFile 按时间倒序分页获取文件的审计日志
+func (r *auditRepository) ListByFile(ctx context.Context, fileID string, page, pageSize int) ([]*entity.Log, int64, error) {
+	var logs []*entity.Log
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.Log{}).Where("file_id = ?", fileID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("User").
+		Order("created_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&logs).Error
+	return logs, total, err
+}
+
+// ListByUser 按时间倒序分页获取用户作为操作者的审计日志
+func (r *auditRepository) ListByUser(ctx context.Context, filter *AuditLogFilter, page, pageSize int) ([]*entity.Log, int64, error) {
+	var logs []*entity.Log
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.Log{}).Where("user_id = ?", filter.UserID)
+	if filter.Operation != "" {
+		query = query.Where("operation = ?", filter.Operation)
+	}
+	if filter.StartTime != nil {
+		query = query.Where("created_at >= ?", *filter.StartTime)
+	}
+	if filter.EndTime != nil {
+		query = query.Where("created_at <= ?", *filter.EndTime)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("created_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&logs).Error
+	return logs, total, err
+}
+
+// ListBefore 按时间升序获取指定时间之前的日志
+func (r *auditRepository) ListBefore(ctx context.Context, before time.Time, limit int) ([]*entity.Log, error) {
+	var logs []*entity.Log
+	err := r.db.WithContext(ctx).
+		Where("created_at < ?", before).
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&logs).Error
+	return logs, err
+}
+
+// ArchiveAndDelete 在同一事务中记录归档并从热表删除已归档日志
+func (r *auditRepository) ArchiveAndDelete(ctx context.Context, archive *entity.LogArchive, logIDs []string) error {
+	if archive.ID == "" {
+		archive.ID = utils.GenerateRecordID()
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(archive).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", logIDs).Delete(&entity.Log{}).Error
+	})
+}
+
+// ListArchives 获取与时间范围有交集的归档记录
+func (r *auditRepository) ListArchives(ctx context.Context, startTime, endTime time.Time) ([]*entity.LogArchive, error) {
+	var archives []*entity.LogArchive
+	err := r.db.WithContext(ctx).
+		Where("start_time <= ? AND end_time >= ?", endTime, startTime).
+		Order("start_time ASC").
+		Find(&archives).Error
+	return archives, err
+}
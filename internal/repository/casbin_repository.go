@@ -1,13 +1,20 @@
 package repository
 
 import (
+	"context"
+
 	"gorm.io/gorm"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/utils"
 )
 
 // CasbinRepository Casbin规则仓库接口
 type CasbinRepository interface {
 	// DeleteRoleRules 删除与角色相关的所有规则
 	DeleteRoleRules(tx *gorm.DB, roleCode string) error
+	// CreateImportRecord 记录策略导入审计
+	CreateImportRecord(ctx context.Context, record *entity.PolicyImportRecord) error
 }
 
 // casbinRepository Casbin规则仓库实现
@@ -42,3 +49,11 @@ func (r *casbinRepository) DeleteRoleRules(tx *gorm.DB, roleCode string) error {
 
 	return nil
 }
+
+// CreateImportRecord 记录策略导入审计
+func (r *casbinRepository) CreateImportRecord(ctx context.Context, record *entity.PolicyImportRecord) error {
+	if record.ID == "" {
+		record.ID = utils.GenerateRecordID()
+	}
+	return r.db.WithContext(ctx).Create(record).Error
+}
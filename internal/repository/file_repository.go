@@ -3,13 +3,23 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
+	"oss-backend/internal/model/dto"
 	"oss-backend/internal/model/entity"
 	"oss-backend/internal/utils"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrShareCodeTaken 分享码已被其他分享占用
+var ErrShareCodeTaken = errors.New("分享码已存在")
+
+// ErrFilePathTaken 同一目录下已存在同名的未删除文件或文件夹，由 files 表的路径唯一索引检测
+var ErrFilePathTaken = errors.New("同一目录下已存在同名文件或文件夹")
+
 // FileRepository 文件仓库接口
 type FileRepository interface {
 	// 基础CRUD操作
@@ -17,24 +27,60 @@ type FileRepository interface {
 	GetByID(ctx context.Context, id string) (*entity.File, error)
 	Update(ctx context.Context, file *entity.File) error
 	Delete(ctx context.Context, id string) error
+	HasChildren(ctx context.Context, folder *entity.File) (bool, error)
+	DeleteSubtree(ctx context.Context, folder *entity.File, deletedBy string, deletedAt time.Time) (int64, int64, error)
+	RestoreSubtree(ctx context.Context, folder *entity.File) (int64, int64, error)
 
 	// 文件列表操作
-	List(ctx context.Context, projectID string, path string, recursive bool, includeDeleted bool, page, pageSize int) ([]*entity.File, int64, error)
+	List(ctx context.Context, projectID string, path, category, viewerID string, recursive bool, includeDeleted bool, page, pageSize int) ([]*entity.File, int64, error)
 	ListByIDs(ctx context.Context, ids []string) ([]*entity.File, error)
+	ListProjectFilesAfter(ctx context.Context, projectID, afterID string, limit int) ([]*entity.File, error)
+	Search(ctx context.Context, projectID, query, viewerID string, filters *dto.FileSearchFilters, page, pageSize int) ([]*entity.File, int64, error)
 
 	// 特定查询方法
 	GetByHash(ctx context.Context, hash string) (*entity.File, error)
+	FindDuplicateHashes(ctx context.Context, projectID string, limit int) ([]*DuplicateHash, error)
+	ListByHash(ctx context.Context, projectID string, hashes []string) ([]*entity.File, error)
 	GetByPath(ctx context.Context, projectID string, path string, fileName string) (*entity.File, error)
+	FindByPath(ctx context.Context, projectID string, path string, fileName string, caseSensitive bool) (*entity.File, error)
+	UpdateStorageBackend(ctx context.Context, file *entity.File, backend string) error
+	UpdateThumbnailKey(ctx context.Context, fileID string, version int, thumbnailKey string) error
 
 	// 版本管理
 	CreateVersion(ctx context.Context, version *entity.FileVersion) error
 	GetVersions(ctx context.Context, fileID string) ([]*entity.FileVersion, error)
 	GetVersionByID(ctx context.Context, fileID string, version int) (*entity.FileVersion, error)
+	UpdateVersionStorageKey(ctx context.Context, fileID string, version int, storageKey string) error
+	UpdateVersionChunkChecksums(ctx context.Context, fileID string, version int, chunkSize int64, checksums string) error
+	DeleteVersion(ctx context.Context, fileID string, version int) error
+
+	// 标签管理
+	AddTag(ctx context.Context, tag *entity.FileTag) error
+	RemoveTag(ctx context.Context, fileID, name string) error
+	ListTags(ctx context.Context, fileID string) ([]*entity.FileTag, error)
+	ListProjectTags(ctx context.Context, projectID string) ([]*TagCount, error)
+
+	// 访问拒绝
+	AddDeny(ctx context.Context, deny *entity.FileDeny) error
+	RemoveDeny(ctx context.Context, fileID, userID string) error
+	ListDenies(ctx context.Context, fileID string) ([]*entity.FileDeny, error)
+	IsDenied(ctx context.Context, file *entity.File, userID string) (bool, error)
+
+	// 文件夹授权
+	SaveFolderPermission(ctx context.Context, permission *entity.FolderPermission) error
+	RemoveFolderPermission(ctx context.Context, folderID, userID string) error
+	ListFolderPermissions(ctx context.Context, folderID string) ([]*entity.FolderPermission, error)
+	GetNearestFolderPermission(ctx context.Context, projectID, userID, fullPath string) (*entity.FolderPermission, error)
 
 	// 分享管理
 	CreateShare(ctx context.Context, share *entity.FileShare) error
 	GetShareByCode(ctx context.Context, code string) (*entity.FileShare, error)
+	GetShareByID(ctx context.Context, id string) (*entity.FileShare, error)
+	ListActiveSharesByUser(ctx context.Context, userID string, page, pageSize int) ([]*entity.FileShare, int64, error)
+	GetProjectShareStats(ctx context.Context, projectID string) (*ShareStats, error)
+	ListMostSharedFiles(ctx context.Context, projectID string, limit int) ([]*SharedFileCount, error)
 	UpdateShareDownloadCount(ctx context.Context, shareID string) error
+	ReleaseShareDownloadCount(ctx context.Context, shareID string) error
 	DeleteShare(ctx context.Context, id string) error
 }
 
@@ -55,7 +101,7 @@ func (r *fileRepository) Create(ctx context.Context, file *entity.File) error {
 	if file.ID == "" {
 		file.ID = utils.GenerateFileID()
 	}
-	return r.db.WithContext(ctx).Create(file).Error
+	return r.translateFileError(r.db.WithContext(ctx).Create(file).Error)
 }
 
 // GetByID 根据ID获取文件
@@ -73,7 +119,54 @@ func (r *fileRepository) GetByID(ctx context.Context, id string) (*entity.File,
 
 // Update 更新文件记录
 func (r *fileRepository) Update(ctx context.Context, file *entity.File) error {
-	return r.db.WithContext(ctx).Save(file).Error
+	return r.translateFileError(r.db.WithContext(ctx).Save(file).Error)
+}
+
+// translateFileError 将写入 files 表时的唯一索引冲突转换为 ErrFilePathTaken
+func (r *fileRepository) translateFileError(err error) error {
+	if err != nil && IsDuplicatedKey(r.db, err) {
+		return ErrFilePathTaken
+	}
+	return err
+}
+
+// IsDuplicatedKey 判断错误是否由唯一索引冲突引起，供直接使用事务写入的调用方识别冲突
+func IsDuplicatedKey(db *gorm.DB, err error) bool {
+	if translator, ok := db.Dialector.(gorm.ErrorTranslator); ok {
+		return errors.Is(translator.Translate(err), gorm.ErrDuplicatedKey)
+	}
+	return false
+}
+
+// UpdateThumbnailKey 更新文件的缩略图对象名
+// 仅在文件仍为指定版本时更新，文件已有新版本时返回 gorm.ErrRecordNotFound
+func (r *fileRepository) UpdateThumbnailKey(ctx context.Context, fileID string, version int, thumbnailKey string) error {
+	result := r.db.WithContext(ctx).Model(&entity.File{}).
+		Where("id = ? AND current_version = ?", fileID, version).
+		UpdateColumn("thumbnail_key", thumbnailKey)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// UpdateStorageBackend 更新文件所在的存储后端
+// 仅在文件的路径、版本和内容与传入的记录一致时更新，文件已被修改时返回 gorm.ErrRecordNotFound
+func (r *fileRepository) UpdateStorageBackend(ctx context.Context, file *entity.File, backend string) error {
+	result := r.db.WithContext(ctx).Model(&entity.File{}).
+		Where("id = ? AND storage_backend = ? AND full_path = ? AND current_version = ? AND file_hash = ?",
+			file.ID, file.StorageBackend, file.FullPath, file.CurrentVersion, file.FileHash).
+		UpdateColumn("storage_backend", backend)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
 
 // Delete 删除文件（软删除）
@@ -81,8 +174,83 @@ func (r *fileRepository) Delete(ctx context.Context, id string) error {
 	return r.db.WithContext(ctx).Model(&entity.File{}).Where("id = ?", id).Update("is_deleted", true).Error
 }
 
-// List 获取文件列表
-func (r *fileRepository) List(ctx context.Context, projectID string, path string, recursive bool, includeDeleted bool, page, pageSize int) ([]*entity.File, int64, error) {
+// subtreeFileStats 文件夹下的文件数量与总大小，不含文件夹
+type subtreeFileStats struct {
+	Count int64
+	Size  int64
+}
+
+// descendants 文件夹下的全部内容（不含文件夹本身）
+func descendants(tx *gorm.DB, folder *entity.File) *gorm.DB {
+	return tx.Model(&entity.File{}).
+		Where("project_id = ? AND file_path LIKE ?", folder.ProjectID, escapeLike(folder.FullPath)+"%")
+}
+
+// HasChildren 文件夹下是否有未删除的内容
+func (r *fileRepository) HasChildren(ctx context.Context, folder *entity.File) (bool, error) {
+	var ids []string
+	err := descendants(r.db.WithContext(ctx), folder).Where("is_deleted = ?", false).Limit(1).Pluck("id", &ids).Error
+	return len(ids) > 0, err
+}
+
+// DeleteSubtree 在一个事务中软删除文件夹及其下所有未删除的内容，返回随之删除的文件（不含文件夹）数量和总大小
+// 内容与文件夹记录相同的删除时间，恢复文件夹时据此区分随文件夹删除的内容和此前单独删除的内容；文件夹已被删除时返回 gorm.ErrRecordNotFound
+func (r *fileRepository) DeleteSubtree(ctx context.Context, folder *entity.File, deletedBy string, deletedAt time.Time) (int64, int64, error) {
+	var stats subtreeFileStats
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{"is_deleted": true, "deleted_at": deletedAt, "deleted_by": deletedBy}
+		result := tx.Model(&entity.File{}).Where("id = ? AND is_deleted = ?", folder.ID, false).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := descendants(tx, folder).Where("is_deleted = ? AND is_folder = ?", false, false).
+			Select("COUNT(*) AS count, COALESCE(SUM(file_size), 0) AS size").Scan(&stats).Error; err != nil {
+			return err
+		}
+		return descendants(tx, folder).Where("is_deleted = ?", false).Updates(updates).Error
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return stats.Count, stats.Size, nil
+}
+
+// RestoreSubtree 在一个事务中恢复文件夹及随其一起删除的内容，返回恢复的文件（不含文件夹）数量和总大小
+// 删除文件夹之前已单独删除的内容保持删除状态；文件夹未被删除时返回 gorm.ErrRecordNotFound
+func (r *fileRepository) RestoreSubtree(ctx context.Context, folder *entity.File) (int64, int64, error) {
+	var stats subtreeFileStats
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{"is_deleted": false, "deleted_at": nil, "deleted_by": nil}
+		result := tx.Model(&entity.File{}).Where("id = ? AND is_deleted = ?", folder.ID, true).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if folder.DeletedAt == nil {
+			return nil
+		}
+		deletedTogether := func() *gorm.DB {
+			return descendants(tx, folder).Where("is_deleted = ? AND deleted_at = ?", true, *folder.DeletedAt)
+		}
+		if err := deletedTogether().Where("is_folder = ?", false).
+			Select("COUNT(*) AS count, COALESCE(SUM(file_size), 0) AS size").Scan(&stats).Error; err != nil {
+			return err
+		}
+		return deletedTogether().Updates(updates).Error
+	})
+	if err != nil {
+		return 0, 0, r.translateFileError(err)
+	}
+	return stats.Count, stats.Size, nil
+}
+
+// List 获取文件列表，category 不为空时只列出该分类的文件，viewerID 不为空时排除该用户被拒绝访问的文件
+func (r *fileRepository) List(ctx context.Context, projectID string, path, category, viewerID string, recursive bool, includeDeleted bool, page, pageSize int) ([]*entity.File, int64, error) {
 	var files []*entity.File
 	var total int64
 
@@ -110,6 +278,16 @@ func (r *fileRepository) List(ctx context.Context, projectID string, path string
 		query = query.Where("is_deleted = ?", false)
 	}
 
+	// 分类筛选
+	if category != "" {
+		query = whereCategory(query, category)
+	}
+
+	// 排除被拒绝访问的文件
+	if viewerID != "" {
+		query = whereNotDenied(query, viewerID)
+	}
+
 	// 计算总数
 	err := query.Count(&total).Error
 	if err != nil {
@@ -131,6 +309,127 @@ func (r *fileRepository) List(ctx context.Context, projectID string, path string
 	return files, total, nil
 }
 
+// fileCategories 有 MIME 类型前缀的分类，按固定顺序生成查询条件
+var fileCategories = []string{
+	entity.FileCategoryImage,
+	entity.FileCategoryVideo,
+	entity.FileCategoryAudio,
+	entity.FileCategoryDocument,
+	entity.FileCategoryArchive,
+}
+
+// mimePrefixCondition 生成 MIME 类型匹配任一前缀的条件
+func mimePrefixCondition(prefixes []string) (string, []interface{}) {
+	conditions := make([]string, 0, len(prefixes))
+	args := make([]interface{}, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		conditions = append(conditions, "COALESCE(mime_type, '') LIKE ?")
+		args = append(args, escapeLike(prefix)+"%")
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
+
+// whereCategory 按 entity.FileCategoryMimePrefixes 筛选分类，文件夹不属于任何分类
+// other 为不匹配其他任何分类的文件
+func whereCategory(db *gorm.DB, category string) *gorm.DB {
+	db = db.Where("is_folder = ?", false)
+	if category != entity.FileCategoryOther {
+		condition, args := mimePrefixCondition(entity.FileCategoryMimePrefixes[category])
+		return db.Where(condition, args...)
+	}
+
+	var prefixes []string
+	for _, name := range fileCategories {
+		prefixes = append(prefixes, entity.FileCategoryMimePrefixes[name]...)
+	}
+	condition, args := mimePrefixCondition(prefixes)
+	return db.Where("NOT "+condition, args...)
+}
+
+// deniedCondition 用户被拒绝访问文件的条件：拒绝规则设置在文件本身或其所在的任一上级文件夹上
+// 按 full_path 前缀判断上级文件夹，文件夹的 full_path 以/结尾，不会匹配到名称相近的其他目录
+const deniedCondition = "file_denies.user_id = ? AND (denied.id = %[1]s.id OR (denied.is_folder = ? AND LEFT(%[1]s.full_path, CHAR_LENGTH(denied.full_path)) = denied.full_path))"
+
+// whereNotDenied 排除用户被拒绝访问的文件
+func whereNotDenied(db *gorm.DB, userID string) *gorm.DB {
+	return db.Where(fmt.Sprintf("NOT EXISTS (SELECT 1 FROM file_denies JOIN files AS denied ON denied.id = file_denies.file_id WHERE file_denies.project_id = files.project_id AND "+deniedCondition+")", "files"), userID, true)
+}
+
+// escapeLike 转义 LIKE 模式中的通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// Search 在项目内按文件名关键字与筛选条件搜索文件，viewerID 不为空时排除该用户被拒绝访问的文件
+// 关键字按文件名子串匹配；按相关度排序时依次为名称完全相同、以关键字开头、包含关键字，同一档内按修改时间倒序
+func (r *fileRepository) Search(ctx context.Context, projectID, query, viewerID string, filters *dto.FileSearchFilters, page, pageSize int) ([]*entity.File, int64, error) {
+	var files []*entity.File
+	var total int64
+
+	db := r.db.WithContext(ctx).Model(&entity.File{}).Where("project_id = ?", projectID)
+	if !filters.IncludeDeleted {
+		db = db.Where("is_deleted = ?", false)
+	}
+	if query != "" {
+		db = db.Where("file_name LIKE ?", "%"+escapeLike(query)+"%")
+	}
+	if filters.Extension != "" {
+		db = db.Where("extension = ?", filters.Extension)
+	}
+	if filters.MimePrefix != "" {
+		db = db.Where("mime_type LIKE ?", escapeLike(filters.MimePrefix)+"%")
+	}
+	if filters.MinSize != nil {
+		db = db.Where("file_size >= ?", *filters.MinSize)
+	}
+	if filters.MaxSize != nil {
+		db = db.Where("file_size <= ?", *filters.MaxSize)
+	}
+	if filters.UploaderID != "" {
+		db = db.Where("uploader_id = ?", filters.UploaderID)
+	}
+	if filters.UpdatedFrom != nil {
+		db = db.Where("updated_at >= ?", *filters.UpdatedFrom)
+	}
+	if filters.UpdatedTo != nil {
+		db = db.Where("updated_at <= ?", *filters.UpdatedTo)
+	}
+	if filters.Category != "" {
+		db = whereCategory(db, filters.Category)
+	}
+	if filters.Tag != "" {
+		db = db.Where("id IN (?)", r.db.Model(&entity.FileTag{}).Select("file_id").Where("project_id = ? AND name = ?", projectID, filters.Tag))
+	}
+	if viewerID != "" {
+		db = whereNotDenied(db, viewerID)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if filters.OrderBy == "relevance" && query != "" {
+		// 带参数的排序表达式不能与其他 Order 合并，整体写在一个表达式中
+		db = db.Clauses(clause.OrderBy{
+			Expression: clause.Expr{
+				SQL:                "CASE WHEN file_name = ? THEN 0 WHEN file_name LIKE ? THEN 1 ELSE 2 END, updated_at DESC, id ASC",
+				Vars:               []interface{}{query, escapeLike(query) + "%"},
+				WithoutParentheses: true,
+			},
+		})
+	} else {
+		db = db.Order("updated_at DESC").Order("id ASC")
+	}
+
+	if page > 0 && pageSize > 0 {
+		db = db.Offset((page - 1) * pageSize).Limit(pageSize)
+	}
+	if err := db.Find(&files).Error; err != nil {
+		return nil, 0, err
+	}
+	return files, total, nil
+}
+
 // ListByIDs 根据ID列表获取文件
 func (r *fileRepository) ListByIDs(ctx context.Context, ids []string) ([]*entity.File, error) {
 	var files []*entity.File
@@ -138,6 +437,17 @@ func (r *fileRepository) ListByIDs(ctx context.Context, ids []string) ([]*entity
 	return files, err
 }
 
+// ListProjectFilesAfter 按ID顺序分批获取项目内未删除的文件和文件夹，afterID 为上一批最后一条的ID
+func (r *fileRepository) ListProjectFilesAfter(ctx context.Context, projectID, afterID string, limit int) ([]*entity.File, error) {
+	var files []*entity.File
+	err := r.db.WithContext(ctx).
+		Where("project_id = ? AND is_deleted = ? AND id > ?", projectID, false, afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&files).Error
+	return files, err
+}
+
 // GetByHash 根据文件哈希获取文件
 func (r *fileRepository) GetByHash(ctx context.Context, hash string) (*entity.File, error) {
 	var file entity.File
@@ -151,6 +461,42 @@ func (r *fileRepository) GetByHash(ctx context.Context, hash string) (*entity.Fi
 	return &file, nil
 }
 
+// DuplicateHash 项目内被多个文件共享的内容哈希
+type DuplicateHash struct {
+	FileHash  string
+	FileCount int64
+	FileSize  int64
+}
+
+// FindDuplicateHashes 按 (project_id, file_hash) 索引分组，查找项目内有多个未删除文件的哈希，按可回收空间倒序
+func (r *fileRepository) FindDuplicateHashes(ctx context.Context, projectID string, limit int) ([]*DuplicateHash, error) {
+	var hashes []*DuplicateHash
+	query := r.db.WithContext(ctx).Model(&entity.File{}).
+		Select("file_hash, COUNT(*) AS file_count, MAX(file_size) AS file_size").
+		Where("project_id = ? AND is_deleted = ? AND is_folder = ? AND file_hash <> ''", projectID, false, false).
+		Group("file_hash").
+		Having("COUNT(*) > 1").
+		Order("(COUNT(*) - 1) * MAX(file_size) DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Scan(&hashes).Error
+	return hashes, err
+}
+
+// ListByHash 获取项目内指定哈希的未删除文件
+func (r *fileRepository) ListByHash(ctx context.Context, projectID string, hashes []string) ([]*entity.File, error) {
+	var files []*entity.File
+	if len(hashes) == 0 {
+		return files, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("project_id = ? AND file_hash IN ? AND is_deleted = ? AND is_folder = ?", projectID, hashes, false, false).
+		Order("created_at ASC").
+		Find(&files).Error
+	return files, err
+}
+
 // GetByPath 根据路径和名称获取文件
 func (r *fileRepository) GetByPath(ctx context.Context, projectID string, path string, fileName string) (*entity.File, error) {
 	var file entity.File
@@ -175,6 +521,40 @@ func (r *fileRepository) GetByPath(ctx context.Context, projectID string, path s
 	return &file, nil
 }
 
+// FindByPath 查找同一位置的文件或文件夹，用于名称冲突检查
+// caseSensitive为false时忽略大小写；为true时按字节比较，不受数据库排序规则影响
+func (r *fileRepository) FindByPath(ctx context.Context, projectID string, path string, fileName string, caseSensitive bool) (*entity.File, error) {
+	var file entity.File
+
+	// 确保路径以/结尾
+	if path != "" && !strings.HasSuffix(path, "/") {
+		path = path + "/"
+	}
+
+	// 文件夹的完整路径以/结尾，两种形式都需要匹配
+	fileName = strings.TrimSuffix(fileName, "/")
+	candidates := []string{path + fileName, path + fileName + "/"}
+
+	query := r.db.WithContext(ctx).Where("project_id = ? AND is_deleted = ?", projectID, false)
+	if caseSensitive {
+		query = query.Where("BINARY full_path IN ?", candidates)
+	} else {
+		for i := range candidates {
+			candidates[i] = strings.ToLower(candidates[i])
+		}
+		query = query.Where("LOWER(full_path) IN ?", candidates)
+	}
+
+	err := query.First(&file).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &file, nil
+}
+
 // CreateVersion 创建文件版本
 func (r *fileRepository) CreateVersion(ctx context.Context, version *entity.FileVersion) error {
 	if version.ID == "" {
@@ -203,12 +583,175 @@ func (r *fileRepository) GetVersionByID(ctx context.Context, fileID string, vers
 	return &fileVersion, nil
 }
 
+// UpdateVersionStorageKey 更新文件版本内容所在的对象名
+func (r *fileRepository) UpdateVersionStorageKey(ctx context.Context, fileID string, version int, storageKey string) error {
+	return r.db.WithContext(ctx).Model(&entity.FileVersion{}).
+		Where("file_id = ? AND version = ?", fileID, version).
+		Update("storage_key", storageKey).Error
+}
+
+// UpdateVersionChunkChecksums 缓存文件版本的分块校验和
+func (r *fileRepository) UpdateVersionChunkChecksums(ctx context.Context, fileID string, version int, chunkSize int64, checksums string) error {
+	return r.db.WithContext(ctx).Model(&entity.FileVersion{}).
+		Where("file_id = ? AND version = ?", fileID, version).
+		Updates(map[string]interface{}{"chunk_size": chunkSize, "chunk_checksums": checksums}).Error
+}
+
+// DeleteVersion 删除文件版本记录
+func (r *fileRepository) DeleteVersion(ctx context.Context, fileID string, version int) error {
+	return r.db.WithContext(ctx).Where("file_id = ? AND version = ?", fileID, version).Delete(&entity.FileVersion{}).Error
+}
+
+// AddTag 为文件添加标签，文件已有同名标签时忽略
+func (r *fileRepository) AddTag(ctx context.Context, tag *entity.FileTag) error {
+	if tag.ID == "" {
+		tag.ID = utils.GenerateRecordID()
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(tag).Error
+}
+
+// RemoveTag 移除文件的标签，文件没有该标签时返回 gorm.ErrRecordNotFound
+func (r *fileRepository) RemoveTag(ctx context.Context, fileID, name string) error {
+	result := r.db.WithContext(ctx).Where("file_id = ? AND name = ?", fileID, name).Delete(&entity.FileTag{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListTags 获取文件的标签，按名称排序
+func (r *fileRepository) ListTags(ctx context.Context, fileID string) ([]*entity.FileTag, error) {
+	var tags []*entity.FileTag
+	err := r.db.WithContext(ctx).Where("file_id = ?", fileID).Order("name ASC").Find(&tags).Error
+	return tags, err
+}
+
+// AddDeny 添加访问拒绝规则，用户已被拒绝访问该文件时不重复添加
+func (r *fileRepository) AddDeny(ctx context.Context, deny *entity.FileDeny) error {
+	if deny.ID == "" {
+		deny.ID = utils.GenerateRecordID()
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(deny).Error
+}
+
+// RemoveDeny 移除访问拒绝规则，没有该规则时返回 gorm.ErrRecordNotFound
+func (r *fileRepository) RemoveDeny(ctx context.Context, fileID, userID string) error {
+	result := r.db.WithContext(ctx).Where("file_id = ? AND user_id = ?", fileID, userID).Delete(&entity.FileDeny{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListDenies 获取直接设置在文件上的访问拒绝规则，按创建时间排序
+func (r *fileRepository) ListDenies(ctx context.Context, fileID string) ([]*entity.FileDeny, error) {
+	var denies []*entity.FileDeny
+	err := r.db.WithContext(ctx).Where("file_id = ?", fileID).Order("created_at ASC").Find(&denies).Error
+	return denies, err
+}
+
+// IsDenied 检查用户是否被拒绝访问文件，包括设置在上级文件夹上的规则
+func (r *fileRepository) IsDenied(ctx context.Context, file *entity.File, userID string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Table("file_denies").
+		Joins("JOIN files AS denied ON denied.id = file_denies.file_id").
+		Joins("JOIN files AS target ON target.id = ?", file.ID).
+		Where("file_denies.project_id = ?", file.ProjectID).
+		Where(fmt.Sprintf(deniedCondition, "target"), userID, true).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// SaveFolderPermission 保存文件夹授权，用户在该文件夹已有授权时更新角色
+func (r *fileRepository) SaveFolderPermission(ctx context.Context, permission *entity.FolderPermission) error {
+	if permission.ID == "" {
+		permission.ID = utils.GenerateRecordID()
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "folder_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "created_by", "updated_at"}),
+	}).Create(permission).Error
+}
+
+// RemoveFolderPermission 移除文件夹授权，没有该授权时返回 gorm.ErrRecordNotFound
+func (r *fileRepository) RemoveFolderPermission(ctx context.Context, folderID, userID string) error {
+	result := r.db.WithContext(ctx).Where("folder_id = ? AND user_id = ?", folderID, userID).Delete(&entity.FolderPermission{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListFolderPermissions 获取直接设置在文件夹上的授权，按创建时间排序
+func (r *fileRepository) ListFolderPermissions(ctx context.Context, folderID string) ([]*entity.FolderPermission, error) {
+	var permissions []*entity.FolderPermission
+	err := r.db.WithContext(ctx).Where("folder_id = ?", folderID).Order("created_at ASC").Find(&permissions).Error
+	return permissions, err
+}
+
+// GetNearestFolderPermission 沿 fullPath 逐级向上查找用户的文件夹授权，返回最近的一个，没有时返回 nil
+// fullPath 为文件或文件夹的完整路径，文件夹本身上的授权同样适用；已删除的文件夹上的授权不生效
+func (r *fileRepository) GetNearestFolderPermission(ctx context.Context, projectID, userID, fullPath string) (*entity.FolderPermission, error) {
+	var permission entity.FolderPermission
+	err := r.db.WithContext(ctx).Model(&entity.FolderPermission{}).
+		Select("folder_permissions.*").
+		Joins("JOIN files AS folder ON folder.id = folder_permissions.folder_id").
+		Where("folder_permissions.project_id = ? AND folder_permissions.user_id = ?", projectID, userID).
+		Where("folder.is_folder = ? AND folder.is_deleted = ? AND folder.gorm_deleted_at IS NULL", true, false).
+		Where("LEFT(?, CHAR_LENGTH(folder.full_path)) = folder.full_path", fullPath).
+		Order("CHAR_LENGTH(folder.full_path) DESC").
+		First(&permission).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &permission, nil
+}
+
+// TagCount 项目内的标签及使用该标签的文件数
+type TagCount struct {
+	Name      string
+	FileCount int64
+}
+
+// ListProjectTags 获取项目内未删除文件使用中的标签，按名称排序
+func (r *fileRepository) ListProjectTags(ctx context.Context, projectID string) ([]*TagCount, error) {
+	var tags []*TagCount
+	err := r.db.WithContext(ctx).Table("file_tags").
+		Select("file_tags.name AS name, COUNT(*) AS file_count").
+		Joins("JOIN files ON files.id = file_tags.file_id").
+		Where("file_tags.project_id = ? AND files.is_deleted = ? AND files.gorm_deleted_at IS NULL", projectID, false).
+		Group("file_tags.name").
+		Order("file_tags.name ASC").
+		Scan(&tags).Error
+	return tags, err
+}
+
 // CreateShare 创建文件分享
 func (r *fileRepository) CreateShare(ctx context.Context, share *entity.FileShare) error {
 	if share.ID == "" {
 		share.ID = utils.GenerateRecordID()
 	}
-	return r.db.WithContext(ctx).Create(share).Error
+	err := r.db.WithContext(ctx).Create(share).Error
+	if err == nil {
+		return nil
+	}
+	// 依赖share_code唯一索引识别分享码冲突，由调用方换码重试
+	if IsDuplicatedKey(r.db, err) {
+		return ErrShareCodeTaken
+	}
+	return err
 }
 
 // GetShareByCode 根据分享码获取分享
@@ -224,11 +767,124 @@ func (r *fileRepository) GetShareByCode(ctx context.Context, code string) (*enti
 	return &share, nil
 }
 
+// GetShareByID 根据ID获取分享
+func (r *fileRepository) GetShareByID(ctx context.Context, id string) (*entity.FileShare, error) {
+	var share entity.FileShare
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&share).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &share, nil
+}
+
+// ListActiveSharesByUser 按创建时间倒序分页获取用户创建的有效分享（未过期且未达到下载次数限制）
+func (r *fileRepository) ListActiveSharesByUser(ctx context.Context, userID string, page, pageSize int) ([]*entity.FileShare, int64, error) {
+	var shares []*entity.FileShare
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.FileShare{}).
+		Where("user_id = ?", userID).
+		Where("expire_at IS NULL OR expire_at > ?", time.Now()).
+		Where("download_limit = 0 OR download_count < download_limit")
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("File").
+		Order("created_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&shares).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return shares, total, nil
+}
+
+// ShareStats 项目内分享的汇总统计
+type ShareStats struct {
+	TotalShares       int64
+	ActiveShares      int64
+	TotalDownloads    int64
+	PasswordProtected int64
+	WithExpiry        int64
+	WithDownloadLimit int64
+}
+
+// GetProjectShareStats 汇总项目内文件的分享，包括已删除文件的分享
+// 有效分享与 ListActiveSharesByUser 的条件一致，并且文件未删除
+func (r *fileRepository) GetProjectShareStats(ctx context.Context, projectID string) (*ShareStats, error) {
+	var stats ShareStats
+	err := r.db.WithContext(ctx).Table("file_shares").
+		Select(`COUNT(*) AS total_shares,
+			COALESCE(SUM(CASE WHEN files.is_deleted = ? AND (file_shares.expire_at IS NULL OR file_shares.expire_at > ?)
+				AND (file_shares.download_limit = 0 OR file_shares.download_count < file_shares.download_limit) THEN 1 ELSE 0 END), 0) AS active_shares,
+			COALESCE(SUM(file_shares.download_count), 0) AS total_downloads,
+			COALESCE(SUM(CASE WHEN file_shares.password <> '' THEN 1 ELSE 0 END), 0) AS password_protected,
+			COALESCE(SUM(CASE WHEN file_shares.expire_at IS NOT NULL THEN 1 ELSE 0 END), 0) AS with_expiry,
+			COALESCE(SUM(CASE WHEN file_shares.download_limit > 0 THEN 1 ELSE 0 END), 0) AS with_download_limit`,
+			false, time.Now()).
+		Joins("JOIN files ON files.id = file_shares.file_id").
+		Where("files.project_id = ?", projectID).
+		Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// SharedFileCount 文件的分享数与通过分享下载的次数
+type SharedFileCount struct {
+	FileID        string
+	FileName      string
+	FullPath      string
+	ShareCount    int64
+	DownloadCount int64
+}
+
+// ListMostSharedFiles 按分享数倒序列出项目内未删除的文件，分享数相同时按下载次数倒序
+func (r *fileRepository) ListMostSharedFiles(ctx context.Context, projectID string, limit int) ([]*SharedFileCount, error) {
+	var files []*SharedFileCount
+	err := r.db.WithContext(ctx).Table("file_shares").
+		Select("files.id AS file_id, files.file_name AS file_name, files.full_path AS full_path, COUNT(*) AS share_count, COALESCE(SUM(file_shares.download_count), 0) AS download_count").
+		Joins("JOIN files ON files.id = file_shares.file_id").
+		Where("files.project_id = ? AND files.is_deleted = ? AND files.gorm_deleted_at IS NULL", projectID, false).
+		Group("files.id, files.file_name, files.full_path").
+		Order("share_count DESC, download_count DESC, files.id ASC").
+		Limit(limit).
+		Scan(&files).Error
+	return files, err
+}
+
 // UpdateShareDownloadCount 更新下载计数
+// 计数与次数限制检查在同一条语句中完成，并发下载不会超过限制；已达到限制时返回 gorm.ErrRecordNotFound
 func (r *fileRepository) UpdateShareDownloadCount(ctx context.Context, shareID string) error {
+	result := r.db.WithContext(ctx).Model(&entity.FileShare{}).
+		Where("id = ? AND (download_limit = 0 OR download_count < download_limit)", shareID).
+		UpdateColumns(map[string]interface{}{
+			"download_count": gorm.Expr("download_count + ?", 1),
+			"updated_at":     time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ReleaseShareDownloadCount 归还一次已占用的下载次数，用于占用后文件未能成功输出的情况
+func (r *fileRepository) ReleaseShareDownloadCount(ctx context.Context, shareID string) error {
 	return r.db.WithContext(ctx).Model(&entity.FileShare{}).
-		Where("id = ?", shareID).
-		UpdateColumn("download_count", gorm.Expr("download_count + ?", 1)).
+		Where("id = ? AND download_count > 0", shareID).
+		UpdateColumns(map[string]interface{}{
+			"download_count": gorm.Expr("download_count - ?", 1),
+			"updated_at":     time.Now(),
+		}).
 		Error
 }
 
@@ -2,18 +2,27 @@ package repository
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/hex"
 	"errors"
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"oss-backend/internal/model/dto"
 	"oss-backend/internal/model/entity"
 	"oss-backend/internal/utils"
 )
 
+// ErrGroupNotEmpty 群组下还有项目或未删除的文件
+var ErrGroupNotEmpty = errors.New("群组下还有项目或文件")
+
+// ErrJoinRequestPending 用户在该群组已有待审批的入群申请
+var ErrJoinRequestPending = errors.New("已有待审批的入群申请")
+
+// ErrJoinRequestProcessed 入群申请已被审批
+var ErrJoinRequestProcessed = errors.New("入群申请已处理")
+
 // GroupRepository 群组仓库接口
 type GroupRepository interface {
 	// 群组管理
@@ -22,6 +31,7 @@ type GroupRepository interface {
 	GetGroupByKey(ctx context.Context, key string) (*entity.Group, error)
 	GetGroupByInviteCode(ctx context.Context, code string) (*entity.Group, error)
 	UpdateGroup(ctx context.Context, group *entity.Group) error
+	DeleteGroup(ctx context.Context, groupID string, force bool) error
 	ListGroups(ctx context.Context, req *dto.GroupListRequest) ([]entity.Group, int64, error)
 
 	// 成员管理
@@ -29,16 +39,25 @@ type GroupRepository interface {
 	GetMember(ctx context.Context, groupID, userID string) (*entity.GroupMember, error)
 	UpdateMember(ctx context.Context, member *entity.GroupMember) error
 	RemoveMember(ctx context.Context, groupID, userID string) error
+	MoveMember(ctx context.Context, fromGroupID string, member *entity.GroupMember, resetProjects bool) error
 	ListMembers(ctx context.Context, groupID string, page, size int) ([]entity.GroupMember, int64, error)
 
 	// 统计相关
 	GetUserGroups(ctx context.Context, userID string) ([]entity.Group, error)
 	GetMemberCount(ctx context.Context, groupID string) (int, error)
+	GetMemberCountByRole(ctx context.Context, groupID, role string) (int, error)
 	GetProjectCount(ctx context.Context, groupID string) (int, error)
 	GetStorageUsed(ctx context.Context, groupID string) (int64, error)
 
+	// 入群申请
+	CreateJoinRequest(ctx context.Context, request *entity.GroupJoinRequest) error
+	GetJoinRequest(ctx context.Context, groupID, requestID string) (*entity.GroupJoinRequest, error)
+	ListJoinRequests(ctx context.Context, groupID, status string, page, size int) ([]entity.GroupJoinRequest, int64, error)
+	ReviewJoinRequest(ctx context.Context, request *entity.GroupJoinRequest, member *entity.GroupMember) error
+
 	// 邀请码管理
-	GenerateInviteCode(ctx context.Context, groupID string, expireDays int) (string, time.Time, error)
+	SaveInvitation(ctx context.Context, invitation *entity.GroupInvitation) error
+	InviteCodeExists(ctx context.Context, code string) (bool, error)
 	UpdateGroupInviteCode(ctx context.Context, groupID string, code string, expireAt *time.Time) error
 
 	// 新增方法：权限检查
@@ -110,6 +129,96 @@ func (r *groupRepository) UpdateGroup(ctx context.Context, group *entity.Group)
 	return r.db.WithContext(ctx).Save(group).Error
 }
 
+// DeleteGroup 在一个事务中删除群组及其下的项目、文件和成员关系
+// 群组、项目和文件记录以软删除方式保留，供操作日志引用；成员、待注册用户的邀请、入群申请、项目权限、文件夹授权、文件版本、标签、分享、
+// 存储统计以及群组和各项目域的Casbin规则直接删除。force 为 false 时，
+// 群组下还有未删除的项目或项目中还有未删除（不在回收站）的文件则返回 ErrGroupNotEmpty
+func (r *groupRepository) DeleteGroup(ctx context.Context, groupID string, force bool) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 锁定群组，防止删除期间在群组下创建项目
+		var group entity.Group
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", groupID).First(&group).Error; err != nil {
+			return err
+		}
+
+		var projectIDs []string
+		if err := tx.Model(&entity.Project{}).Where("group_id = ?", groupID).Pluck("id", &projectIDs).Error; err != nil {
+			return err
+		}
+
+		if !force && len(projectIDs) > 0 {
+			var activeProjects int64
+			if err := tx.Model(&entity.Project{}).Where("group_id = ? AND status <> ?", groupID, 3).Count(&activeProjects).Error; err != nil {
+				return err
+			}
+			if activeProjects > 0 {
+				return fmt.Errorf("%w: 群组下还有 %d 个项目", ErrGroupNotEmpty, activeProjects)
+			}
+			var liveFiles int64
+			if err := tx.Model(&entity.File{}).Where("project_id IN ? AND is_deleted = ?", projectIDs, false).Count(&liveFiles).Error; err != nil {
+				return err
+			}
+			if liveFiles > 0 {
+				return fmt.Errorf("%w: 群组的项目中还有 %d 个未删除的文件", ErrGroupNotEmpty, liveFiles)
+			}
+		}
+
+		domains := []string{fmt.Sprintf("group:%s", groupID)}
+		if len(projectIDs) > 0 {
+			fileIDs := tx.Unscoped().Model(&entity.File{}).Select("id").Where("project_id IN ?", projectIDs)
+			if err := tx.Where("file_id IN (?)", fileIDs).Delete(&entity.FileVersion{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("file_id IN (?)", fileIDs).Delete(&entity.FileShare{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("project_id IN ?", projectIDs).Delete(&entity.FileTag{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("project_id IN ?", projectIDs).Delete(&entity.File{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("project_id IN ?", projectIDs).Delete(&entity.ProjectMember{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("project_id IN ?", projectIDs).Delete(&entity.Permission{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("project_id IN ?", projectIDs).Delete(&entity.FolderPermission{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("group_id = ?", groupID).Delete(&entity.Project{}).Error; err != nil {
+				return err
+			}
+			for _, projectID := range projectIDs {
+				domains = append(domains, fmt.Sprintf("project:%s", projectID))
+			}
+		}
+
+		if err := tx.Where("group_id = ?", groupID).Delete(&entity.StorageStat{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("group_id = ?", groupID).Delete(&entity.GroupMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("group_id = ?", groupID).Delete(&entity.GroupInvitation{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("group_id = ?", groupID).Delete(&entity.GroupJoinRequest{}).Error; err != nil {
+			return err
+		}
+
+		// 策略规则为 p, 主体, 域, 资源, 操作；角色关联规则为 g, 用户, 角色, 域
+		if err := tx.Table("casbin_rule").
+			Where("(ptype = ? AND v1 IN ?) OR (ptype = ? AND v2 IN ?)", "p", domains, "g", domains).
+			Delete(map[string]interface{}{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&group).Error
+	})
+}
+
 // ListGroups 获取群组列表
 func (r *groupRepository) ListGroups(ctx context.Context, req *dto.GroupListRequest) ([]entity.Group, int64, error) {
 	var groups []entity.Group
@@ -195,6 +304,167 @@ func (r *groupRepository) RemoveMember(ctx context.Context, groupID, userID stri
 	return r.db.WithContext(ctx).Where("group_id = ? AND user_id = ?", groupID, userID).Delete(&entity.GroupMember{}).Error
 }
 
+// CreateJoinRequest 创建入群申请，用户在该群组已有待审批的申请时返回 ErrJoinRequestPending
+// 锁定群组记录，防止同一用户并发提交多个待审批申请
+func (r *groupRepository) CreateJoinRequest(ctx context.Context, request *entity.GroupJoinRequest) error {
+	if request.ID == "" {
+		request.ID = utils.GenerateRecordID()
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var group entity.Group
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", request.GroupID).First(&group).Error; err != nil {
+			return err
+		}
+		var pending int64
+		if err := tx.Model(&entity.GroupJoinRequest{}).
+			Where("group_id = ? AND user_id = ? AND status = ?", request.GroupID, request.UserID, entity.GroupJoinRequestPending).
+			Count(&pending).Error; err != nil {
+			return err
+		}
+		if pending > 0 {
+			return ErrJoinRequestPending
+		}
+		return tx.Create(request).Error
+	})
+}
+
+// GetJoinRequest 获取群组的入群申请，不存在时返回 nil
+func (r *groupRepository) GetJoinRequest(ctx context.Context, groupID, requestID string) (*entity.GroupJoinRequest, error) {
+	var request entity.GroupJoinRequest
+	err := r.db.WithContext(ctx).Where("id = ? AND group_id = ?", requestID, groupID).First(&request).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+// ListJoinRequests 分页获取群组的入群申请，status 为空时返回全部状态，按申请时间倒序
+func (r *groupRepository) ListJoinRequests(ctx context.Context, groupID, status string, page, size int) ([]entity.GroupJoinRequest, int64, error) {
+	var requests []entity.GroupJoinRequest
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&entity.GroupJoinRequest{}).Where("group_id = ?", groupID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if page > 0 && size > 0 {
+		query = query.Offset((page - 1) * size).Limit(size)
+	}
+	if err := query.Preload("User").Order("created_at DESC").Find(&requests).Error; err != nil {
+		return nil, 0, err
+	}
+	return requests, total, nil
+}
+
+// ReviewJoinRequest 在一个事务中保存审批结果，member 不为空时同时添加群组成员
+// 申请已不是待审批状态时返回 ErrJoinRequestProcessed
+func (r *groupRepository) ReviewJoinRequest(ctx context.Context, request *entity.GroupJoinRequest, member *entity.GroupMember) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.GroupJoinRequest{}).
+			Where("id = ? AND status = ?", request.ID, entity.GroupJoinRequestPending).
+			Updates(map[string]interface{}{
+				"status":      request.Status,
+				"reviewer_id": request.ReviewerID,
+				"reviewed_at": request.ReviewedAt,
+				"updated_at":  request.UpdatedAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrJoinRequestProcessed
+		}
+		if member == nil {
+			return nil
+		}
+		if member.ID == "" {
+			member.ID = utils.GenerateRecordID()
+		}
+		return tx.Create(member).Error
+	})
+}
+
+// SaveInvitation 保存按邮箱的群组邀请，同一群组重复邀请同一邮箱时更新角色和邀请人
+func (r *groupRepository) SaveInvitation(ctx context.Context, invitation *entity.GroupInvitation) error {
+	if invitation.ID == "" {
+		invitation.ID = utils.GenerateRecordID()
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "group_id"}, {Name: "email"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "inviter_id", "updated_at"}),
+	}).Create(invitation).Error
+}
+
+// MoveMember 在一个事务中将用户从 fromGroupID 移到 member.GroupID，并同步两个群组域的Casbin角色
+// 用户在原群组域中的角色全部删除，以管理员身份加入时在新群组域中添加 GROUP_ADMIN；
+// resetProjects 为 true 时同时删除用户在原群组各项目中的成员关系、项目权限和项目域的Casbin规则。
+// 直接修改 casbin_rule 表，调用方需在之后重新加载策略
+func (r *groupRepository) MoveMember(ctx context.Context, fromGroupID string, member *entity.GroupMember, resetProjects bool) error {
+	if member.ID == "" {
+		member.ID = utils.GenerateRecordID()
+	}
+	subject := fmt.Sprintf("user:%s", member.UserID)
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("group_id = ? AND user_id = ?", fromGroupID, member.UserID).Delete(&entity.GroupMember{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Create(member).Error; err != nil {
+			return err
+		}
+
+		// 角色关联规则为 g, 用户, 角色, 域
+		if err := tx.Table("casbin_rule").
+			Where("ptype = ? AND v0 = ? AND v2 = ?", "g", subject, fmt.Sprintf("group:%s", fromGroupID)).
+			Delete(map[string]interface{}{}).Error; err != nil {
+			return err
+		}
+		if member.Role == "admin" {
+			if err := tx.Table("casbin_rule").Clauses(clause.OnConflict{DoNothing: true}).Create(map[string]interface{}{
+				"ptype": "g", "v0": subject, "v1": entity.RoleGroupAdmin, "v2": fmt.Sprintf("group:%s", member.GroupID),
+				"v3": "", "v4": "", "v5": "",
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		if !resetProjects {
+			return nil
+		}
+		var projectIDs []string
+		if err := tx.Model(&entity.Project{}).Where("group_id = ?", fromGroupID).Pluck("id", &projectIDs).Error; err != nil {
+			return err
+		}
+		if len(projectIDs) == 0 {
+			return nil
+		}
+		if err := tx.Where("user_id = ? AND project_id IN ?", member.UserID, projectIDs).Delete(&entity.ProjectMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? AND project_id IN ?", member.UserID, projectIDs).Delete(&entity.Permission{}).Error; err != nil {
+			return err
+		}
+		domains := make([]string, 0, len(projectIDs))
+		for _, projectID := range projectIDs {
+			domains = append(domains, fmt.Sprintf("project:%s", projectID))
+		}
+		// 策略规则为 p, 主体, 域, 资源, 操作
+		return tx.Table("casbin_rule").
+			Where("v0 = ? AND ((ptype = ? AND v1 IN ?) OR (ptype = ? AND v2 IN ?))", subject, "p", domains, "g", domains).
+			Delete(map[string]interface{}{}).Error
+	})
+}
+
 // ListMembers 获取群组成员列表
 func (r *groupRepository) ListMembers(ctx context.Context, groupID string, page, size int) ([]entity.GroupMember, int64, error) {
 	var members []entity.GroupMember
@@ -240,6 +510,13 @@ func (r *groupRepository) GetMemberCount(ctx context.Context, groupID string) (i
 	return int(count), err
 }
 
+// GetMemberCountByRole 获取群组中指定角色的成员数量
+func (r *groupRepository) GetMemberCountByRole(ctx context.Context, groupID, role string) (int, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&entity.GroupMember{}).Where("group_id = ? AND role = ?", groupID, role).Count(&count).Error
+	return int(count), err
+}
+
 // GetProjectCount 获取群组项目数量
 func (r *groupRepository) GetProjectCount(ctx context.Context, groupID string) (int, error) {
 	var count int64
@@ -266,33 +543,11 @@ func (r *groupRepository) GetStorageUsed(ctx context.Context, groupID string) (i
 	return result.TotalSize, nil
 }
 
-// GenerateInviteCode 生成邀请码
-func (r *groupRepository) GenerateInviteCode(ctx context.Context, groupID string, expireDays int) (string, time.Time, error) {
-	// 生成随机邀请码
-	buf := make([]byte, 8)
-	_, err := rand.Read(buf)
-	if err != nil {
-		return "", time.Time{}, err
-	}
-
-	code := hex.EncodeToString(buf)
-
-	// 设置过期时间
-	var expireAt time.Time
-	if expireDays > 0 {
-		expireAt = time.Now().AddDate(0, 0, expireDays)
-	} else {
-		// 默认30天
-		expireAt = time.Now().AddDate(0, 0, 30)
-	}
-
-	// 更新到数据库
-	err = r.UpdateGroupInviteCode(ctx, groupID, code, &expireAt)
-	if err != nil {
-		return "", time.Time{}, err
-	}
-
-	return code, expireAt, nil
+// InviteCodeExists 检查邀请码是否已被占用，已删除的群组仍占用其邀请码
+func (r *groupRepository) InviteCodeExists(ctx context.Context, code string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Unscoped().Model(&entity.Group{}).Where("invite_code = ?", code).Count(&count).Error
+	return count > 0, err
 }
 
 // UpdateGroupInviteCode 更新群组邀请码
@@ -25,7 +25,7 @@ type ProjectRepository interface {
 	// 查询方法
 	List(ctx context.Context, req *dto.ProjectListRequest) ([]entity.Project, int64, error)
 	GetByGroupID(ctx context.Context, groupID string) ([]entity.Project, error)
-	GetUserProjects(ctx context.Context, userID string, pageQuery dto.PageQuery) ([]entity.Project, int64, error)
+	GetUserProjects(ctx context.Context, userID string, filter *dto.ProjectQuery, pageQuery dto.PageQuery) ([]entity.Project, int64, error)
 	GetAll(ctx context.Context) ([]entity.Project, error)
 
 	// 权限相关
@@ -41,6 +41,14 @@ type ProjectRepository interface {
 	UpdateProjectPermission(ctx context.Context, permission *entity.Permission) error
 	RemoveProjectPermission(ctx context.Context, projectID, userID string) error
 	ListProjectPermissions(ctx context.Context, projectID string, pageQuery dto.PageQuery) ([]entity.Permission, int64, error)
+
+	// Webhook
+	CreateWebhook(ctx context.Context, webhook *entity.ProjectWebhook) error
+	GetWebhook(ctx context.Context, projectID, id string) (*entity.ProjectWebhook, error)
+	UpdateWebhook(ctx context.Context, webhook *entity.ProjectWebhook) error
+	DeleteWebhook(ctx context.Context, projectID, id string) error
+	ListWebhooks(ctx context.Context, projectID string) ([]entity.ProjectWebhook, error)
+	CountWebhooks(ctx context.Context, projectID string) (int64, error)
 }
 
 // projectRepository 项目仓库实现
@@ -130,15 +138,29 @@ func (r *projectRepository) GetByGroupID(ctx context.Context, groupID string) ([
 	return projects, err
 }
 
-// GetUserProjects 获取用户的项目
-func (r *projectRepository) GetUserProjects(ctx context.Context, userID string, pageQuery dto.PageQuery) ([]entity.Project, int64, error) {
+// GetUserProjects 获取用户的项目，筛选条件在分页之前应用
+func (r *projectRepository) GetUserProjects(ctx context.Context, userID string, filter *dto.ProjectQuery, pageQuery dto.PageQuery) ([]entity.Project, int64, error) {
 	var projects []entity.Project
 
 	// 构建基础查询
 	query := r.db.WithContext(ctx).
+		Model(&entity.Project{}).
 		Joins("JOIN project_members ON project_members.project_id = projects.id").
-		Where("project_members.user_id = ?", userID).
-		Preload("Group")
+		Where("project_members.user_id = ?", userID)
+
+	// 条件筛选，项目成员表也有时间字段，列名需带表名
+	if filter != nil {
+		if filter.Status > 0 {
+			query = query.Where("projects.status = ?", filter.Status)
+		}
+		if filter.Keyword != "" {
+			query = query.Where("projects.name LIKE ?", "%"+escapeLike(filter.Keyword)+"%")
+		}
+		if filter.GroupID != "" {
+			query = query.Where("projects.group_id = ?", filter.GroupID)
+		}
+	}
+	query = query.Preload("Group")
 
 	// 使用通用分页方法执行查询
 	total, err := ExecutePageQuery(query, pageQuery, &projects)
@@ -282,3 +304,48 @@ func (r *projectRepository) GetAll(ctx context.Context) ([]entity.Project, error
 	err := r.db.WithContext(ctx).Find(&projects).Error
 	return projects, err
 }
+
+// CreateWebhook 创建项目Webhook
+func (r *projectRepository) CreateWebhook(ctx context.Context, webhook *entity.ProjectWebhook) error {
+	if webhook.ID == "" {
+		webhook.ID = utils.GenerateRecordID()
+	}
+	return r.db.WithContext(ctx).Create(webhook).Error
+}
+
+// GetWebhook 获取项目的Webhook，不存在时返回nil
+func (r *projectRepository) GetWebhook(ctx context.Context, projectID, id string) (*entity.ProjectWebhook, error) {
+	var webhook entity.ProjectWebhook
+	err := r.db.WithContext(ctx).Where("project_id = ? AND id = ?", projectID, id).First(&webhook).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// UpdateWebhook 更新项目Webhook
+func (r *projectRepository) UpdateWebhook(ctx context.Context, webhook *entity.ProjectWebhook) error {
+	return r.db.WithContext(ctx).Save(webhook).Error
+}
+
+// DeleteWebhook 删除项目Webhook
+func (r *projectRepository) DeleteWebhook(ctx context.Context, projectID, id string) error {
+	return r.db.WithContext(ctx).Where("project_id = ? AND id = ?", projectID, id).Delete(&entity.ProjectWebhook{}).Error
+}
+
+// ListWebhooks 获取项目的全部Webhook，按创建时间排序
+func (r *projectRepository) ListWebhooks(ctx context.Context, projectID string) ([]entity.ProjectWebhook, error) {
+	var webhooks []entity.ProjectWebhook
+	err := r.db.WithContext(ctx).Where("project_id = ?", projectID).Order("created_at ASC").Find(&webhooks).Error
+	return webhooks, err
+}
+
+// CountWebhooks 统计项目的Webhook数量
+func (r *projectRepository) CountWebhooks(ctx context.Context, projectID string) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&entity.ProjectWebhook{}).Where("project_id = ?", projectID).Count(&count).Error
+	return count, err
+}
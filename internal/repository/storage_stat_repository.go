@@ -6,8 +6,10 @@ import (
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"oss-backend/internal/model/entity"
+	"oss-backend/internal/utils"
 )
 
 // StorageStatRepository 存储统计仓库接口
@@ -19,12 +21,24 @@ type StorageStatRepository interface {
 
 	// 特定查询方法
 	GetLatestByProject(ctx context.Context, projectID string) (*entity.StorageStat, error)
+	GetLatestByProjectBefore(ctx context.Context, projectID string, date time.Time) (*entity.StorageStat, error)
 	GetByDateRange(ctx context.Context, projectID string, startDate, endDate time.Time) ([]*entity.StorageStat, error)
 	GetProjectStatsByDate(ctx context.Context, date time.Time) ([]*entity.StorageStat, error)
 	GetGroupStatsByDate(ctx context.Context, groupID string, date time.Time) ([]*entity.StorageStat, error)
+	GetGroupStatsByDateRange(ctx context.Context, groupID string, startDate, endDate time.Time) ([]*entity.StorageStat, error)
+	GetGroupLatestStatsBefore(ctx context.Context, groupID string, date time.Time) ([]*entity.StorageStat, error)
 
 	// 统计查询方法
 	GetProjectTotalStats(ctx context.Context, projectID string) (fileCount int64, totalSize int64, err error)
+
+	// 配额预占：已用量与已预占量之和加上 size 不超过配额时预占 size 字节并返回true，判断与预占在同一条语句中完成
+	ReserveProjectStorage(ctx context.Context, projectID string, size int64) (bool, error)
+	ReserveGroupStorage(ctx context.Context, groupID string, size int64) (bool, error)
+	ReleaseProjectStorage(ctx context.Context, projectID string, size int64) error
+	ReleaseGroupStorage(ctx context.Context, groupID string, size int64) error
+
+	// UpsertDailyStat 写入项目当日统计：文件数和总大小取 stat 中的实际总量，新增量累加 increaseDelta
+	UpsertDailyStat(ctx context.Context, stat *entity.StorageStat, increaseDelta int64) error
 }
 
 // storageStatRepository 存储统计仓库实现
@@ -80,6 +94,22 @@ func (r *storageStatRepository) GetLatestByProject(ctx context.Context, projectI
 	return &stat, nil
 }
 
+// GetLatestByProjectBefore 获取项目在指定日期之前的最后一条统计
+func (r *storageStatRepository) GetLatestByProjectBefore(ctx context.Context, projectID string, date time.Time) (*entity.StorageStat, error) {
+	var stat entity.StorageStat
+	err := r.db.WithContext(ctx).
+		Where("project_id = ? AND stat_date < ?", projectID, date).
+		Order("stat_date DESC").
+		First(&stat).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &stat, nil
+}
+
 // GetByDateRange 获取指定日期范围的存储统计
 func (r *storageStatRepository) GetByDateRange(ctx context.Context, projectID string, startDate, endDate time.Time) ([]*entity.StorageStat, error) {
 	var stats []*entity.StorageStat
@@ -108,6 +138,29 @@ func (r *storageStatRepository) GetGroupStatsByDate(ctx context.Context, groupID
 	return stats, err
 }
 
+// GetGroupStatsByDateRange 获取群组内所有项目在指定日期范围的统计，按日期升序
+func (r *storageStatRepository) GetGroupStatsByDateRange(ctx context.Context, groupID string, startDate, endDate time.Time) ([]*entity.StorageStat, error) {
+	var stats []*entity.StorageStat
+	err := r.db.WithContext(ctx).
+		Where("group_id = ? AND stat_date BETWEEN ? AND ?", groupID, startDate, endDate).
+		Order("stat_date ASC").
+		Find(&stats).Error
+	return stats, err
+}
+
+// GetGroupLatestStatsBefore 获取群组内每个项目在指定日期之前的最后一条统计
+func (r *storageStatRepository) GetGroupLatestStatsBefore(ctx context.Context, groupID string, date time.Time) ([]*entity.StorageStat, error) {
+	var stats []*entity.StorageStat
+	latest := r.db.WithContext(ctx).Model(&entity.StorageStat{}).
+		Select("project_id, MAX(stat_date)").
+		Where("group_id = ? AND stat_date < ?", groupID, date).
+		Group("project_id")
+	err := r.db.WithContext(ctx).
+		Where("group_id = ? AND (project_id, stat_date) IN (?)", groupID, latest).
+		Find(&stats).Error
+	return stats, err
+}
+
 // GetProjectTotalStats 获取项目当前的总文件数和大小
 func (r *storageStatRepository) GetProjectTotalStats(ctx context.Context, projectID string) (fileCount int64, totalSize int64, err error) {
 	// 计算文件数
@@ -133,3 +186,74 @@ func (r *storageStatRepository) GetProjectTotalStats(ctx context.Context, projec
 
 	return fileCount, result.TotalSize, nil
 }
+
+// ReserveProjectStorage 在项目配额内预占 size 字节
+// 用量由 files 表实时汇总，条件更新锁定项目行，并发的预占依次判断，不会一起超出配额
+func (r *storageStatRepository) ReserveProjectStorage(ctx context.Context, projectID string, size int64) (bool, error) {
+	used := r.db.WithContext(ctx).Model(&entity.File{}).
+		Select("COALESCE(SUM(file_size), 0)").
+		Where("project_id = ? AND is_deleted = ? AND is_folder = ?", projectID, false, false)
+	return r.reserve(ctx, entity.Project{}.TableName(), projectID, size, used)
+}
+
+// ReserveGroupStorage 在群组配额内预占 size 字节，用量汇总群组下所有项目的文件
+func (r *storageStatRepository) ReserveGroupStorage(ctx context.Context, groupID string, size int64) (bool, error) {
+	projects := r.db.WithContext(ctx).Model(&entity.Project{}).Select("id").Where("group_id = ?", groupID)
+	used := r.db.WithContext(ctx).Model(&entity.File{}).
+		Select("COALESCE(SUM(file_size), 0)").
+		Where("project_id IN (?) AND is_deleted = ? AND is_folder = ?", projects, false, false)
+	return r.reserve(ctx, entity.Group{}.TableName(), groupID, size, used)
+}
+
+// reserve 已用量 used 与已预占量之和加上 size 不超过配额时增加预占量
+// storage_reserved 为只读字段，不能通过模型更新，这里直接按表名更新
+func (r *storageStatRepository) reserve(ctx context.Context, table, id string, size int64, used *gorm.DB) (bool, error) {
+	result := r.db.WithContext(ctx).Table(table).
+		Where("id = ? AND deleted_at IS NULL AND storage_reserved + ? + (?) <= storage_quota", id, size, used).
+		UpdateColumn("storage_reserved", gorm.Expr("storage_reserved + ?", size))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ReleaseProjectStorage 释放项目预占的 size 字节
+func (r *storageStatRepository) ReleaseProjectStorage(ctx context.Context, projectID string, size int64) error {
+	return r.release(ctx, entity.Project{}.TableName(), projectID, size)
+}
+
+// ReleaseGroupStorage 释放群组预占的 size 字节
+func (r *storageStatRepository) ReleaseGroupStorage(ctx context.Context, groupID string, size int64) error {
+	return r.release(ctx, entity.Group{}.TableName(), groupID, size)
+}
+
+// release 减少预占量，不小于0
+func (r *storageStatRepository) release(ctx context.Context, table, id string, size int64) error {
+	return r.db.WithContext(ctx).Table(table).
+		Where("id = ?", id).
+		UpdateColumn("storage_reserved", gorm.Expr("CASE WHEN storage_reserved > ? THEN storage_reserved - ? ELSE 0 END", size, size)).Error
+}
+
+// UpsertDailyStat 写入项目当日统计
+// 依赖 (project_id, stat_date) 唯一索引，使用 INSERT ... ON DUPLICATE KEY UPDATE 避免先查后建的竞争；
+// 文件数和总大小直接取调用方重新计算的实际总量而不是累加，当日首条记录不会重复计入已落库的变更
+func (r *storageStatRepository) UpsertDailyStat(ctx context.Context, stat *entity.StorageStat, increaseDelta int64) error {
+	if stat.ID == "" {
+		stat.ID = utils.GenerateRecordID()
+	}
+	if stat.CreatedAt.IsZero() {
+		stat.CreatedAt = time.Now()
+	}
+
+	return r.db.WithContext(ctx).
+		Omit("Group", "Project").
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "project_id"}, {Name: "stat_date"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"file_count":    stat.FileCount,
+				"total_size":    stat.TotalSize,
+				"increase_size": gorm.Expr("increase_size + ?", increaseDelta),
+			}),
+		}).
+		Create(stat).Error
+}
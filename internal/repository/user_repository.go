@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -20,12 +21,24 @@ type UserRepository interface {
 	GetByID(ctx context.Context, id string) (*entity.User, error)
 	// GetByEmail 根据邮箱获取用户
 	GetByEmail(ctx context.Context, email string) (*entity.User, error)
-	// List 获取用户列表
-	List(ctx context.Context, email, name string, status, page, size int) ([]*entity.User, int64, error)
+	// List 获取用户列表，includeDeleted 为false时排除已删除的用户
+	List(ctx context.Context, email, name string, status, page, size int, includeDeleted bool) ([]*entity.User, int64, error)
 	// UpdatePassword 更新密码
 	UpdatePassword(ctx context.Context, id string, passwordHash string) error
 	// UpdateStatus 更新状态
 	UpdateStatus(ctx context.Context, id string, status int) error
+	// BulkUpdateStatus 在同一事务中批量更新状态并写入操作日志
+	BulkUpdateStatus(ctx context.Context, ids []string, status int, logs []*entity.OperationLog) error
+	// SoftDelete 软删除用户，并移除其角色、授权规则与群组/项目成员关系
+	SoftDelete(ctx context.Context, id string, tombstoneEmail string, deletedAt time.Time) error
+	// Reactivate 恢复已删除的用户
+	Reactivate(ctx context.Context, id string, email string) error
+	// AcceptGroupInvitations 将邮箱收到的群组邀请转为用户的群组成员关系并删除邀请，返回加入的群组数
+	AcceptGroupInvitations(ctx context.Context, userID string, email string) (int, error)
+	// UpdateTOTP 保存用户的两步验证密钥、状态和恢复码
+	UpdateTOTP(ctx context.Context, user *entity.User) error
+	// ConsumeTOTP 记录一次两步验证，仅在状态未被并发修改时更新，返回是否更新
+	ConsumeTOTP(ctx context.Context, user *entity.User, lastStep int64, recoveryCodes string) (bool, error)
 	// UpdateLastLogin 更新最后登录信息
 	UpdateLastLogin(ctx context.Context, id string, ip string) error
 	// GetUserRoles 获取用户角色
@@ -66,6 +79,37 @@ func (r *userRepository) Update(ctx context.Context, user *entity.User) error {
 		}).Error
 }
 
+// UpdateTOTP 保存用户的两步验证密钥、状态和恢复码
+func (r *userRepository) UpdateTOTP(ctx context.Context, user *entity.User) error {
+	return r.db.WithContext(ctx).Model(&entity.User{}).Where("id = ?", user.ID).
+		Updates(map[string]interface{}{
+			"totp_secret":         user.TOTPSecret,
+			"totp_enabled":        user.TOTPEnabled,
+			"totp_last_step":      user.TOTPLastStep,
+			"totp_recovery_codes": user.TOTPRecoveryCodes,
+		}).Error
+}
+
+// ConsumeTOTP 记录一次两步验证通过的时间步和剩余的恢复码
+// 以读取时的时间步和恢复码为条件更新，同一验证码或恢复码被并发提交时只有一次成功；成功时同步更新 user
+func (r *userRepository) ConsumeTOTP(ctx context.Context, user *entity.User, lastStep int64, recoveryCodes string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&entity.User{}).
+		Where("id = ? AND totp_enabled = ? AND totp_last_step = ? AND totp_recovery_codes = ?", user.ID, true, user.TOTPLastStep, user.TOTPRecoveryCodes).
+		Updates(map[string]interface{}{
+			"totp_last_step":      lastStep,
+			"totp_recovery_codes": recoveryCodes,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	user.TOTPLastStep = lastStep
+	user.TOTPRecoveryCodes = recoveryCodes
+	return true, nil
+}
+
 // GetByID 根据ID获取用户
 func (r *userRepository) GetByID(ctx context.Context, id string) (*entity.User, error) {
 	var user entity.User
@@ -87,7 +131,7 @@ func (r *userRepository) GetByEmail(ctx context.Context, email string) (*entity.
 }
 
 // List 获取用户列表
-func (r *userRepository) List(ctx context.Context, email, name string, status, page, size int) ([]*entity.User, int64, error) {
+func (r *userRepository) List(ctx context.Context, email, name string, status, page, size int, includeDeleted bool) ([]*entity.User, int64, error) {
 	var users []*entity.User
 	var total int64
 
@@ -105,6 +149,11 @@ func (r *userRepository) List(ctx context.Context, email, name string, status, p
 		db = db.Where("status = ?", status)
 	}
 
+	// 按已删除状态筛选时视为显式查询已删除用户
+	if !includeDeleted && status != entity.UserStatusDeleted {
+		db = db.Where("deleted_at IS NULL")
+	}
+
 	err := db.Count(&total).Error
 	if err != nil {
 		return nil, 0, err
@@ -135,6 +184,133 @@ func (r *userRepository) UpdateStatus(ctx context.Context, id string, status int
 		Update("status", status).Error
 }
 
+// BulkUpdateStatus 在同一事务中批量更新状态并写入操作日志，任一步失败则全部回滚
+func (r *userRepository) BulkUpdateStatus(ctx context.Context, ids []string, status int, logs []*entity.OperationLog) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entity.User{}).Where("id IN ?", ids).Update("status", status).Error; err != nil {
+			return err
+		}
+		for _, l := range logs {
+			if l.ID == "" {
+				l.ID = utils.GenerateRecordID()
+			}
+		}
+		if len(logs) > 0 {
+			if err := tx.Omit("User").Create(&logs).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// SoftDelete 软删除用户
+// 在同一事务中标记删除状态、替换邮箱以释放唯一约束，并删除用户角色、Casbin规则、入群申请、文件夹授权及群组/项目成员关系
+func (r *userRepository) SoftDelete(ctx context.Context, id string, tombstoneEmail string, deletedAt time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&entity.User{}).Where("id = ? AND deleted_at IS NULL", id).
+			Updates(map[string]interface{}{
+				"email":      tombstoneEmail,
+				"status":     entity.UserStatusDeleted,
+				"deleted_at": deletedAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if err := tx.Where("user_id = ?", id).Delete(&entity.UserRole{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&entity.GroupMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&entity.GroupJoinRequest{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&entity.ProjectMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&entity.Permission{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&entity.FolderPermission{}).Error; err != nil {
+			return err
+		}
+
+		// 删除以该用户为主体的策略与角色关联规则
+		return tx.Table("casbin_rule").
+			Where("p_type IN ? AND v0 = ?", []string{"p", "g"}, utils.BuildUserSubject(id)).
+			Delete(nil).Error
+	})
+}
+
+// AcceptGroupInvitations 在同一事务中按邀请将用户加入群组，已是成员的群组保持原角色，随后删除该邮箱的所有邀请
+func (r *userRepository) AcceptGroupInvitations(ctx context.Context, userID string, email string) (int, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	joined := 0
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var invitations []entity.GroupInvitation
+		if err := tx.Where("email = ?", email).Find(&invitations).Error; err != nil {
+			return err
+		}
+		if len(invitations) == 0 {
+			return nil
+		}
+
+		now := time.Now()
+		for _, invitation := range invitations {
+			var count int64
+			if err := tx.Model(&entity.GroupMember{}).
+				Where("group_id = ? AND user_id = ?", invitation.GroupID, userID).
+				Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+			if err := tx.Create(&entity.GroupMember{
+				ID:        utils.GenerateRecordID(),
+				GroupID:   invitation.GroupID,
+				UserID:    userID,
+				Role:      invitation.Role,
+				JoinedAt:  now,
+				UpdatedAt: now,
+			}).Error; err != nil {
+				return err
+			}
+			joined++
+		}
+		return tx.Where("email = ?", email).Delete(&entity.GroupInvitation{}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return joined, nil
+}
+
+// Reactivate 恢复已删除的用户，恢复后状态为正常
+func (r *userRepository) Reactivate(ctx context.Context, id string, email string) error {
+	result := r.db.WithContext(ctx).Model(&entity.User{}).Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]interface{}{
+			"email":      email,
+			"status":     entity.UserStatusNormal,
+			"deleted_at": nil,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
 // UpdateLastLogin 更新最后登录信息
 func (r *userRepository) UpdateLastLogin(ctx context.Context, id string, ip string) error {
 	now := time.Now()
@@ -0,0 +1,280 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"oss-backend/internal/model/dto"
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+	"oss-backend/internal/utils"
+	"oss-backend/pkg/minio"
+)
+
+// AuditService 审计日志服务接口
+type AuditService interface {
+	// 个人操作记录
+	GetMyActivity(ctx context.Context, userID string, req *dto.AuditActivityQueryRequest) ([]*entity.Log, int64, error)
+
+	// 保留与归档
+	ArchiveExpiredLogs(ctx context.Context) (int, error)
+	GetArchivedLogs(ctx context.Context, startTime, endTime time.Time) ([]*entity.Log, error)
+	StartRetentionWorker(ctx context.Context)
+}
+
+// auditService 审计日志服务实现
+type auditService struct {
+	auditRepo   repository.AuditRepository
+	minioClient *minio.Client
+}
+
+// NewAuditService 创建审计日志服务实例
+func NewAuditService(auditRepo repository.AuditRepository, minioClient *minio.Client) AuditService {
+	return &auditService{
+		auditRepo:   auditRepo,
+		minioClient: minioClient,
+	}
+}
+
+// GetMyActivity 获取用户本人作为操作者的审计日志
+// 只按操作者过滤，他人对该用户资源的操作不在此返回
+func (s *auditService) GetMyActivity(ctx context.Context, userID string, req *dto.AuditActivityQueryRequest) ([]*entity.Log, int64, error) {
+	filter := &repository.AuditLogFilter{
+		UserID:    userID,
+		Operation: req.Operation,
+	}
+
+	if req.StartDate != "" {
+		startTime, err := time.ParseInLocation("2006-01-02", req.StartDate, time.Local)
+		if err != nil {
+			return nil, 0, errors.New("开始日期格式错误")
+		}
+		filter.StartTime = &startTime
+	}
+	if req.EndDate != "" {
+		endDate, err := time.ParseInLocation("2006-01-02", req.EndDate, time.Local)
+		if err != nil {
+			return nil, 0, errors.New("结束日期格式错误")
+		}
+		// 结束日期包含当天
+		endTime := endDate.Add(24*time.Hour - time.Nanosecond)
+		filter.EndTime = &endTime
+	}
+
+	page, size := req.Page, req.Size
+	if page <= 0 {
+		page = 1
+	}
+	if size <= 0 {
+		size = 10
+	}
+
+	return s.auditRepo.ListByUser(ctx, filter, page, size)
+}
+
+// 审计归档默认配置
+const (
+	defaultAuditRetentionDays   = 90
+	defaultAuditArchiveBatch    = 1000
+	defaultAuditArchiveInterval = 24 * time.Hour
+	defaultAuditArchiveBucket   = "oss-audit-archive"
+)
+
+// auditRetentionDays 获取日志保留天数
+func auditRetentionDays() int {
+	days := viper.GetInt("audit.retention_days")
+	if days <= 0 {
+		days = defaultAuditRetentionDays
+	}
+	return days
+}
+
+// auditArchiveBucket 获取归档存储桶名称
+func auditArchiveBucket() string {
+	bucket := viper.GetString("audit.archive_bucket")
+	if bucket == "" {
+		bucket = defaultAuditArchiveBucket
+	}
+	return bucket
+}
+
+// ArchiveExpiredLogs 将超过保留期的日志分批压缩归档到MinIO并从热表删除
+// 返回归档的日志条数
+func (s *auditService) ArchiveExpiredLogs(ctx context.Context) (int, error) {
+	cutoff := time.Now().AddDate(0, 0, -auditRetentionDays())
+	batchSize := viper.GetInt("audit.archive_batch_size")
+	if batchSize <= 0 {
+		batchSize = defaultAuditArchiveBatch
+	}
+
+	bucketName := auditArchiveBucket()
+	if err := s.minioClient.CreateBucketIfNotExists(ctx, bucketName); err != nil {
+		return 0, fmt.Errorf("准备归档存储桶失败: %w", err)
+	}
+
+	archived := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return archived, err
+		}
+
+		logs, err := s.auditRepo.ListBefore(ctx, cutoff, batchSize)
+		if err != nil {
+			return archived, fmt.Errorf("查询过期日志失败: %w", err)
+		}
+		if len(logs) == 0 {
+			return archived, nil
+		}
+
+		// 压缩为 JSON Lines
+		data, err := encodeLogArchive(logs)
+		if err != nil {
+			return archived, fmt.Errorf("压缩日志失败: %w", err)
+		}
+
+		// 对象名包含归档记录ID，同一秒内的多个批次不会互相覆盖
+		startTime := logs[0].CreatedAt
+		endTime := logs[len(logs)-1].CreatedAt
+		archiveID := utils.GenerateRecordID()
+		objectName := fmt.Sprintf("logs/%s/%s_%s_%s.jsonl.gz",
+			startTime.Format("2006-01"),
+			startTime.Format("20060102T150405"),
+			endTime.Format("20060102T150405"),
+			archiveID)
+
+		// 先上传归档对象，成功后再删除热表数据，避免丢失日志
+		err = s.minioClient.PutObject(ctx, bucketName, objectName, bytes.NewReader(data), int64(len(data)), "application/gzip")
+		if err != nil {
+			return archived, fmt.Errorf("上传日志归档失败: %w", err)
+		}
+
+		ids := make([]string, 0, len(logs))
+		for _, l := range logs {
+			ids = append(ids, l.ID)
+		}
+		archive := &entity.LogArchive{
+			ID:         archiveID,
+			BucketName: bucketName,
+			ObjectName: objectName,
+			StartTime:  startTime,
+			EndTime:    endTime,
+			EntryCount: len(logs),
+			Size:       int64(len(data)),
+		}
+		if err := s.auditRepo.ArchiveAndDelete(ctx, archive, ids); err != nil {
+			return archived, fmt.Errorf("删除已归档日志失败: %w", err)
+		}
+
+		archived += len(logs)
+		if len(logs) < batchSize {
+			return archived, nil
+		}
+	}
+}
+
+// GetArchivedLogs 读取时间范围内的归档日志
+func (s *auditService) GetArchivedLogs(ctx context.Context, startTime, endTime time.Time) ([]*entity.Log, error) {
+	if endTime.Before(startTime) {
+		return nil, errors.New("结束时间不能早于开始时间")
+	}
+
+	archives, err := s.auditRepo.ListArchives(ctx, startTime, endTime)
+	if err != nil {
+		return nil, fmt.Errorf("查询归档记录失败: %w", err)
+	}
+
+	result := make([]*entity.Log, 0)
+	for _, archive := range archives {
+		reader, err := s.minioClient.GetObject(ctx, archive.BucketName, archive.ObjectName, nil)
+		if err != nil {
+			return nil, fmt.Errorf("读取归档 %s 失败: %w", archive.ObjectName, err)
+		}
+		logs, err := decodeLogArchive(reader)
+		reader.Close()
+		if err != nil {
+			return nil, fmt.Errorf("解析归档 %s 失败: %w", archive.ObjectName, err)
+		}
+
+		for _, l := range logs {
+			if !l.CreatedAt.Before(startTime) && !l.CreatedAt.After(endTime) {
+				result = append(result, l)
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// StartRetentionWorker 启动定时归档任务，ctx 取消时退出
+func (s *auditService) StartRetentionWorker(ctx context.Context) {
+	interval := time.Duration(viper.GetInt("audit.archive_interval_hours")) * time.Hour
+	if interval <= 0 {
+		interval = defaultAuditArchiveInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		count, err := s.ArchiveExpiredLogs(ctx)
+		if err != nil {
+			log.Printf("归档审计日志失败: %v", err)
+		} else if count > 0 {
+			log.Printf("已归档 %d 条审计日志", count)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// encodeLogArchive 将日志编码为 gzip 压缩的 JSON Lines
+func encodeLogArchive(logs []*entity.Log) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	encoder := json.NewEncoder(gz)
+	for _, l := range logs {
+		if err := encoder.Encode(l); err != nil {
+			gz.Close()
+			return nil, err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeLogArchive 解析 gzip 压缩的 JSON Lines 日志
+func decodeLogArchive(reader io.Reader) ([]*entity.Log, error) {
+	gz, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	var logs []*entity.Log
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var l entity.Log
+		if err := json.Unmarshal(scanner.Bytes(), &l); err != nil {
+			return nil, err
+		}
+		logs = append(logs, &l)
+	}
+	return logs, scanner.Err()
+}
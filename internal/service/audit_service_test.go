@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"oss-backend/internal/model/dto"
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+)
+
+// TestArchiveExpiredLogs 超过保留期的日志分批归档并从热表删除，同一秒内的多个批次不会互相覆盖
+func TestArchiveExpiredLogs(t *testing.T) {
+	viper.Set("audit.retention_days", 30)
+	viper.Set("audit.archive_batch_size", 2)
+	t.Cleanup(func() {
+		viper.Set("audit.retention_days", 0)
+		viper.Set("audit.archive_batch_size", 0)
+	})
+
+	db := newTestDB(t, &entity.Log{}, &entity.LogArchive{})
+	store, client := newFakeObjectStore(t)
+	svc := NewAuditService(repository.NewAuditRepository(db), client)
+
+	// 5条过期日志在同一秒内产生，按批大小分为3个归档
+	expired := time.Now().AddDate(0, 0, -60).Truncate(time.Second)
+	for i := 0; i < 5; i++ {
+		l := &entity.Log{ID: fmt.Sprintf("old-%d", i), UserID: "user-1", Operation: entity.OperationDownload, IPAddress: "127.0.0.1", Status: 200, CreatedAt: expired.Add(time.Duration(i) * time.Millisecond)}
+		if err := db.Create(l).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	recent := &entity.Log{ID: "recent", UserID: "user-1", Operation: entity.OperationDownload, IPAddress: "127.0.0.1", Status: 200, CreatedAt: time.Now()}
+	if err := db.Create(recent).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	archived, err := svc.ArchiveExpiredLogs(context.Background())
+	if err != nil {
+		t.Fatalf("归档失败: %v", err)
+	}
+	if archived != 5 {
+		t.Fatalf("归档了 %d 条日志，应为5条", archived)
+	}
+
+	var live []entity.Log
+	db.Find(&live)
+	if len(live) != 1 || live[0].ID != recent.ID {
+		t.Fatalf("热表中剩余 %d 条日志，应只剩未过期的一条", len(live))
+	}
+
+	var archives []entity.LogArchive
+	db.Find(&archives)
+	if len(archives) != 3 {
+		t.Fatalf("归档记录为 %d 条，应为3条", len(archives))
+	}
+	if keys := store.keys(auditArchiveBucket(), "logs/"); len(keys) != len(archives) {
+		t.Fatalf("归档对象为 %v，同一秒内的批次互相覆盖", keys)
+	}
+
+	logs, err := svc.GetArchivedLogs(context.Background(), expired.Add(-time.Minute), expired.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("读取归档失败: %v", err)
+	}
+	if len(logs) != 5 {
+		t.Fatalf("从归档读取到 %d 条日志，应为5条", len(logs))
+	}
+}
+
+// TestGetMyActivity 用户只能看到自己作为操作者的日志，他人对其文件的操作不返回；按操作类型、日期过滤并分页
+func TestGetMyActivity(t *testing.T) {
+	db := newTestDB(t, &entity.Log{})
+	svc := NewAuditService(repository.NewAuditRepository(db), nil)
+
+	aliceFile := "alice-file"
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 12, 0, 0, 0, time.Local)
+	yesterday := today.AddDate(0, 0, -1)
+	logs := []*entity.Log{
+		{ID: "alice-upload", UserID: "alice", Operation: entity.OperationUpload, CreatedAt: today.Add(-2 * time.Minute), FileID: &aliceFile},
+		{ID: "alice-download", UserID: "alice", Operation: entity.OperationDownload, CreatedAt: today.Add(-time.Minute)},
+		{ID: "alice-old", UserID: "alice", Operation: entity.OperationDownload, CreatedAt: yesterday},
+		// bob 对 alice 的文件的操作
+		{ID: "bob-download", UserID: "bob", Operation: entity.OperationDownload, CreatedAt: today, FileID: &aliceFile},
+		{ID: "bob-delete", UserID: "bob", Operation: entity.OperationDelete, CreatedAt: today, FileID: &aliceFile},
+	}
+	for _, l := range logs {
+		l.IPAddress = "127.0.0.1"
+		l.Status = 200
+		if err := db.Create(l).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ids := func(logs []*entity.Log) []string {
+		result := make([]string, len(logs))
+		for i, l := range logs {
+			result[i] = l.ID
+		}
+		return result
+	}
+
+	tests := []struct {
+		name      string
+		req       dto.AuditActivityQueryRequest
+		wantIDs   []string
+		wantTotal int64
+	}{
+		{"全部", dto.AuditActivityQueryRequest{}, []string{"alice-download", "alice-upload", "alice-old"}, 3},
+		{"按操作类型", dto.AuditActivityQueryRequest{Operation: entity.OperationDownload}, []string{"alice-download", "alice-old"}, 2},
+		{"按日期", dto.AuditActivityQueryRequest{StartDate: today.Format("2006-01-02"), EndDate: today.Format("2006-01-02")}, []string{"alice-download", "alice-upload"}, 2},
+		{"分页", dto.AuditActivityQueryRequest{Page: 2, Size: 2}, []string{"alice-old"}, 3},
+	}
+	for _, tt := range tests {
+		got, total, err := svc.GetMyActivity(context.Background(), "alice", &tt.req)
+		if err != nil {
+			t.Fatalf("%s: 查询失败: %v", tt.name, err)
+		}
+		if total != tt.wantTotal || fmt.Sprint(ids(got)) != fmt.Sprint(tt.wantIDs) {
+			t.Errorf("%s: 返回 %v（共 %d 条），应为 %v（共 %d 条）", tt.name, ids(got), total, tt.wantIDs, tt.wantTotal)
+		}
+	}
+
+	if _, _, err := svc.GetMyActivity(context.Background(), "alice", &dto.AuditActivityQueryRequest{StartDate: "2024/01/01"}); err == nil {
+		t.Fatalf("错误的日期格式没有返回错误")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
+	"strings"
 
 	"github.com/casbin/casbin/v2"
 	"gorm.io/gorm"
@@ -38,6 +40,7 @@ type AuthService interface {
 	RemoveRoleForUser(ctx context.Context, userID string, role string, domain string) error
 	GetRolesForUser(subject string, domain string) ([]string, error)
 	InitializeRBAC() error
+	ReloadPolicy() error
 
 	// 角色服务部分
 	GetAllRoles(ctx context.Context) ([]entity.Role, error)
@@ -46,7 +49,7 @@ type AuthService interface {
 	CreateRole(ctx context.Context, role *entity.Role) error
 	UpdateRole(ctx context.Context, role *entity.Role) error
 	DeleteRole(ctx context.Context, id uint) error
-	ListRoles(ctx context.Context, req *dto.RoleListRequest) (*dto.RoleListResponse, error)
+	ListRoles(ctx context.Context, req *dto.RoleListRequest) (*dto.PageResult[dto.RoleResponse], error)
 
 	// 为控制器提供DTO适配方法
 	CreateRoleFromDTO(ctx context.Context, req *dto.RoleCreateRequest, createdBy string) error
@@ -60,11 +63,27 @@ type AuthService interface {
 	// 权限检查辅助方法
 	CanUserAccessResource(ctx context.Context, userID string, resourceType, action, domain string) (bool, error)
 	IsUserInRole(ctx context.Context, userID string, roleCode string, domain string) (bool, error)
+	GetUserPermissions(ctx context.Context, userID string) (*dto.UserPermissionsResponse, error)
 
 	// 直接资源权限管理
 	AddResourcePermission(ctx context.Context, userID, domain, resource, action string) error
+
+	// 策略管理
+	ListPolicies(ctx context.Context, sub, domain string) ([]dto.RolePolicy, error)
+	AddPolicy(ctx context.Context, sub, domain, obj, act string) error
+	RemovePolicy(ctx context.Context, sub, domain, obj, act string) error
 }
 
+// 策略管理的错误
+var (
+	ErrInvalidPolicy  = errors.New("策略无效")
+	ErrPolicyExists   = errors.New("策略已存在")
+	ErrPolicyNotFound = errors.New("策略不存在")
+)
+
+// casbin_rule 表每个字段的最大长度
+const maxPolicyFieldLength = 100
+
 // authService 认证授权服务实现
 type authService struct {
 	enforcer   *casbin.Enforcer
@@ -123,6 +142,11 @@ func (s *authService) GetRolesForUser(subject string, domain string) ([]string,
 	return s.enforcer.GetRolesForUser(subject, domain)
 }
 
+// ReloadPolicy 从数据库重新加载策略，直接修改 casbin_rule 表后调用
+func (s *authService) ReloadPolicy() error {
+	return s.enforcer.LoadPolicy()
+}
+
 // InitializeRBAC 初始化RBAC（例如加载策略，确保Enforcer可用）
 // 移除硬编码的策略添加逻辑，策略应由 Casbin adapter 从持久化存储加载
 func (s *authService) InitializeRBAC() error {
@@ -376,7 +400,7 @@ func (s *authService) IsUserInRole(ctx context.Context, userID string, roleCode
 }
 
 // ListRoles 获取角色列表
-func (s *authService) ListRoles(ctx context.Context, req *dto.RoleListRequest) (*dto.RoleListResponse, error) {
+func (s *authService) ListRoles(ctx context.Context, req *dto.RoleListRequest) (*dto.PageResult[dto.RoleResponse], error) {
 	// 默认值处理
 	if req.Page <= 0 {
 		req.Page = 1
@@ -392,14 +416,10 @@ func (s *authService) ListRoles(ctx context.Context, req *dto.RoleListRequest) (
 	}
 
 	// 构建响应
-	result := &dto.RoleListResponse{
-		Total: total,
-		List:  make([]dto.RoleResponse, 0, len(roles)),
-	}
-
+	list := make([]dto.RoleResponse, 0, len(roles))
 	for _, role := range roles {
 		createdAt := role.CreatedAt.Format("2006-01-02 15:04:05")
-		result.List = append(result.List, dto.RoleResponse{
+		list = append(list, dto.RoleResponse{
 			ID:          role.ID,
 			Name:        role.Name,
 			Description: role.Description,
@@ -410,7 +430,7 @@ func (s *authService) ListRoles(ctx context.Context, req *dto.RoleListRequest) (
 		})
 	}
 
-	return result, nil
+	return dto.NewPageResult(list, total, dto.PageQuery{Page: req.Page, Size: req.Size}), nil
 }
 
 // 为控制器提供DTO适配方法
@@ -500,3 +520,82 @@ func (s *authService) AddResourcePermission(ctx context.Context, userID, domain,
 	_, err := s.enforcer.AddPermissionForUser(userSub, domain, resource, action)
 	return err
 }
+
+// ListPolicies 获取权限策略（p规则），sub、domain 为空时不过滤该字段
+func (s *authService) ListPolicies(ctx context.Context, sub, domain string) ([]dto.RolePolicy, error) {
+	rules, err := s.enforcer.GetFilteredPolicy(0, sub, domain)
+	if err != nil {
+		return nil, fmt.Errorf("获取策略失败: %w", err)
+	}
+
+	policies := make([]dto.RolePolicy, 0, len(rules))
+	for _, rule := range rules {
+		if len(rule) < 4 {
+			continue
+		}
+		policies = append(policies, dto.RolePolicy{Sub: rule[0], Domain: rule[1], Obj: rule[2], Act: rule[3]})
+	}
+	return policies, nil
+}
+
+// AddPolicy 添加权限策略
+// 通过 gorm adapter 的自动保存只写入这一条规则；不调用 SavePolicy，它会清空 casbin_rule 表后按内存重写，
+// 覆盖其他模块直接写入表中、尚未重新加载的规则。写入失败时重新加载策略，保证内存与数据库一致
+func (s *authService) AddPolicy(ctx context.Context, sub, domain, obj, act string) error {
+	if err := validatePolicy(sub, domain, obj, act); err != nil {
+		return err
+	}
+
+	added, err := s.enforcer.AddPolicy(sub, domain, obj, act)
+	if err != nil {
+		s.resyncPolicy()
+		return fmt.Errorf("添加策略失败: %w", err)
+	}
+	if !added {
+		return ErrPolicyExists
+	}
+	return nil
+}
+
+// RemovePolicy 删除权限策略，持久化方式同 AddPolicy
+func (s *authService) RemovePolicy(ctx context.Context, sub, domain, obj, act string) error {
+	if err := validatePolicy(sub, domain, obj, act); err != nil {
+		return err
+	}
+
+	removed, err := s.enforcer.RemovePolicy(sub, domain, obj, act)
+	if err != nil {
+		s.resyncPolicy()
+		return fmt.Errorf("删除策略失败: %w", err)
+	}
+	if !removed {
+		return ErrPolicyNotFound
+	}
+	return nil
+}
+
+// validatePolicy 校验策略字段不为空、前后没有空白且不超过 casbin_rule 的字段长度
+func validatePolicy(sub, domain, obj, act string) error {
+	fields := []struct{ name, value string }{
+		{"sub", sub}, {"domain", domain}, {"obj", obj}, {"act", act},
+	}
+	for _, field := range fields {
+		if strings.TrimSpace(field.value) == "" {
+			return fmt.Errorf("%w: %s 不能为空", ErrInvalidPolicy, field.name)
+		}
+		if strings.TrimSpace(field.value) != field.value {
+			return fmt.Errorf("%w: %s 不能包含首尾空白", ErrInvalidPolicy, field.name)
+		}
+		if len(field.value) > maxPolicyFieldLength {
+			return fmt.Errorf("%w: %s 长度不能超过%d", ErrInvalidPolicy, field.name, maxPolicyFieldLength)
+		}
+	}
+	return nil
+}
+
+// resyncPolicy 从数据库重新加载策略，失败时只记录日志
+func (s *authService) resyncPolicy() {
+	if err := s.enforcer.LoadPolicy(); err != nil {
+		log.Printf("重新加载Casbin策略失败: %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+
+	"oss-backend/internal/model/entity"
+)
+
+// TestInitializeRBACKeepsPolicies 重复初始化RBAC不删除也不重复添加 GROUP_ADMIN、MEMBER 的策略
+func TestInitializeRBACKeepsPolicies(t *testing.T) {
+	enforcer, err := casbin.NewEnforcer("../../configs/rbac_model.conf", "../../configs/policy.csv")
+	if err != nil {
+		t.Fatalf("创建 Enforcer 失败: %v", err)
+	}
+	svc := NewAuthService(enforcer, nil, nil, nil, nil)
+
+	count := func(role string) int {
+		policies, err := enforcer.GetFilteredPolicy(0, role)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(policies)
+	}
+	groupAdmin, member := count(entity.RoleGroupAdmin), count(entity.RoleMember)
+	if groupAdmin == 0 || member == 0 {
+		t.Fatalf("测试策略中 GROUP_ADMIN 有 %d 条、MEMBER 有 %d 条，应都不为0", groupAdmin, member)
+	}
+
+	// 模拟多次启动：每次启动重新加载策略并初始化RBAC
+	for i := 0; i < 2; i++ {
+		if err := enforcer.LoadPolicy(); err != nil {
+			t.Fatalf("第%d次加载策略失败: %v", i+1, err)
+		}
+		if err := svc.InitializeRBAC(); err != nil {
+			t.Fatalf("第%d次初始化RBAC失败: %v", i+1, err)
+		}
+		if got := count(entity.RoleGroupAdmin); got != groupAdmin {
+			t.Fatalf("第%d次初始化后 GROUP_ADMIN 策略为 %d 条，应为 %d 条", i+1, got, groupAdmin)
+		}
+		if got := count(entity.RoleMember); got != member {
+			t.Fatalf("第%d次初始化后 MEMBER 策略为 %d 条，应为 %d 条", i+1, got, member)
+		}
+	}
+
+	if ok, _ := enforcer.Enforce(entity.RoleMember, "*", "files", "read"); !ok {
+		t.Fatalf("初始化后 MEMBER 应仍可读取文件")
+	}
+}
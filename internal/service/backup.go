@@ -0,0 +1,293 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/spf13/viper"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/pkg/minio"
+)
+
+// 备份方式常量
+const (
+	BackupModeManifest = "manifest" // 只导出文件清单
+	BackupModeContent  = "content"  // 导出文件清单并复制文件内容
+)
+
+// 备份默认配置
+const (
+	defaultBackupBucket    = "oss-backups"
+	defaultBackupRetention = 7
+	backupBatchSize        = 500
+	backupRunLayout        = "20060102T150405Z"
+)
+
+// ErrInvalidBackupConfig 备份配置无效
+var ErrInvalidBackupConfig = errors.New("备份配置无效")
+
+// BackupTarget 备份目标，对应配置中 backup.targets 的一项
+type BackupTarget struct {
+	Name       string   `mapstructure:"name"`        // 目标名称，同时作为备份对象的前缀
+	Schedule   string   `mapstructure:"schedule"`    // 标准5段cron表达式，也支持 @daily 等描述符
+	Mode       string   `mapstructure:"mode"`        // manifest 或 content，默认 manifest
+	ProjectIDs []string `mapstructure:"project_ids"` // 备份的项目，为空表示全部项目
+	Retention  int      `mapstructure:"retention"`   // 保留最近几次备份，0表示使用 backup.retention
+	Backend    string   `mapstructure:"backend"`     // 写入备份的存储后端，default（默认）或 storage.backends 中的外部存储
+}
+
+// backupManifestEntry 备份清单中的一条记录
+type backupManifestEntry struct {
+	ProjectID   string    `json:"project_id"`
+	ProjectName string    `json:"project_name"`
+	FileID      string    `json:"file_id"`
+	FullPath    string    `json:"full_path"`
+	IsFolder    bool      `json:"is_folder"`
+	FileSize    int64     `json:"file_size"`
+	FileHash    string    `json:"file_hash"`
+	MimeType    string    `json:"mime_type"`
+	Version     int       `json:"version"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Object      string    `json:"object,omitempty"` // content方式下文件内容在备份存储桶中的对象名
+
+	// 加密存储的文件备份的是密文，恢复时需要同一主密钥及以下信息解密
+	GroupID           string `json:"group_id,omitempty"`
+	EncryptionVersion int    `json:"encryption_version,omitempty"`
+	EncryptionNonce   string `json:"encryption_nonce,omitempty"`
+}
+
+// LoadBackupTargets 读取并校验备份目标配置
+func LoadBackupTargets() ([]BackupTarget, error) {
+	var targets []BackupTarget
+	if err := viper.UnmarshalKey("backup.targets", &targets); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBackupConfig, err)
+	}
+
+	defaultRetention := viper.GetInt("backup.retention")
+	if defaultRetention <= 0 {
+		defaultRetention = defaultBackupRetention
+	}
+
+	backends, err := LoadStorageBackends()
+	if err != nil {
+		return nil, err
+	}
+	backendNames := make(map[string]bool, len(backends))
+	for _, backend := range backends {
+		backendNames[backend.Name] = true
+	}
+
+	names := make(map[string]bool, len(targets))
+	for i := range targets {
+		target := &targets[i]
+		if target.Name == "" || strings.ContainsAny(target.Name, "/\\ ") {
+			return nil, fmt.Errorf("%w: 第%d个备份目标名称为空或包含非法字符", ErrInvalidBackupConfig, i+1)
+		}
+		if names[target.Name] {
+			return nil, fmt.Errorf("%w: 备份目标 %s 重复", ErrInvalidBackupConfig, target.Name)
+		}
+		names[target.Name] = true
+
+		if _, err := cron.ParseStandard(target.Schedule); err != nil {
+			return nil, fmt.Errorf("%w: 备份目标 %s 的cron表达式错误: %v", ErrInvalidBackupConfig, target.Name, err)
+		}
+		switch target.Mode {
+		case "":
+			target.Mode = BackupModeManifest
+		case BackupModeManifest, BackupModeContent:
+		default:
+			return nil, fmt.Errorf("%w: 备份目标 %s 的备份方式只能是 manifest 或 content", ErrInvalidBackupConfig, target.Name)
+		}
+		if target.Retention <= 0 {
+			target.Retention = defaultRetention
+		}
+		if target.Backend == "" {
+			target.Backend = DefaultStorageBackend
+		}
+		if target.Backend != DefaultStorageBackend && !backendNames[target.Backend] {
+			return nil, fmt.Errorf("%w: 备份目标 %s 的存储后端 %s 不存在", ErrInvalidBackupConfig, target.Name, target.Backend)
+		}
+	}
+	return targets, nil
+}
+
+// backupBucket 获取备份存储桶名称
+func backupBucket() string {
+	if bucket := viper.GetString("backup.bucket"); bucket != "" {
+		return bucket
+	}
+	return defaultBackupBucket
+}
+
+// RunBackup 执行一次备份，清单与文件内容写入目标存储后端中 backup.bucket 下的 <目标名称>/<时间>/ 目录，返回清单对象名
+// 目标存储后端为 default 时写入默认后端，否则写入 storage.backends 中配置的外部存储；完成后按保留数量删除该目标较早的备份
+func (s *fileService) RunBackup(ctx context.Context, target BackupTarget) (string, error) {
+	dest, err := s.storageClient(target.Backend)
+	if err != nil {
+		return "", fmt.Errorf("获取备份存储后端失败: %w", err)
+	}
+	bucketName := backupBucket()
+	if err := dest.CreateBucketIfNotExists(ctx, bucketName); err != nil {
+		return "", fmt.Errorf("创建备份存储桶失败: %w", err)
+	}
+	runPrefix := fmt.Sprintf("%s/%s/", target.Name, time.Now().UTC().Format(backupRunLayout))
+
+	// 1. 确定备份的项目
+	projectIDs := target.ProjectIDs
+	if len(projectIDs) == 0 {
+		projects, err := s.projectRepo.GetAll(ctx)
+		if err != nil {
+			return "", fmt.Errorf("获取项目列表失败: %w", err)
+		}
+		for _, project := range projects {
+			projectIDs = append(projectIDs, project.ID)
+		}
+	}
+
+	// 2. 逐个项目导出清单，content方式同时复制文件内容
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	encoder := json.NewEncoder(zw)
+	for i, projectID := range projectIDs {
+		ReportJobProgress(ctx, i, len(projectIDs))
+
+		project, err := s.projectRepo.GetByID(ctx, projectID)
+		if err != nil {
+			return "", fmt.Errorf("获取项目信息失败: %w", err)
+		}
+		if project == nil {
+			log.Printf("备份 %s 跳过不存在的项目 %s", target.Name, projectID)
+			continue
+		}
+		if err := s.backupProject(ctx, target, project, dest, bucketName, runPrefix, encoder); err != nil {
+			return "", err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return "", fmt.Errorf("压缩备份清单失败: %w", err)
+	}
+	ReportJobProgress(ctx, len(projectIDs), len(projectIDs))
+
+	// 3. 上传清单，清单写入后本次备份才算完成
+	manifestName := runPrefix + "manifest.jsonl.gz"
+	if err := dest.PutObject(ctx, bucketName, manifestName, bytes.NewReader(buf.Bytes()), int64(buf.Len()), "application/gzip"); err != nil {
+		return "", fmt.Errorf("上传备份清单失败: %w", err)
+	}
+
+	// 4. 清理超出保留数量的旧备份，失败不影响本次备份结果
+	if err := pruneBackups(ctx, dest, bucketName, target); err != nil {
+		log.Printf("清理备份 %s 的旧备份失败: %v", target.Name, err)
+	}
+
+	return manifestName, nil
+}
+
+// backupProject 导出一个项目的文件清单，content方式同时复制文件内容
+func (s *fileService) backupProject(ctx context.Context, target BackupTarget, project *entity.Project, dest *minio.Client, bucketName, runPrefix string, encoder *json.Encoder) error {
+	sourceBucket := s.sanitizeBucketName(project.Group.GroupKey)
+	afterID := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		files, err := s.fileRepo.ListProjectFilesAfter(ctx, project.ID, afterID, backupBatchSize)
+		if err != nil {
+			return fmt.Errorf("获取项目 %s 的文件失败: %w", project.ID, err)
+		}
+		if len(files) == 0 {
+			return nil
+		}
+
+		for _, file := range files {
+			entry := backupManifestEntry{
+				ProjectID:   project.ID,
+				ProjectName: project.Name,
+				FileID:      file.ID,
+				FullPath:    file.FullPath,
+				IsFolder:    file.IsFolder,
+				FileSize:    file.FileSize,
+				FileHash:    file.FileHash,
+				MimeType:    file.MimeType,
+				Version:     file.CurrentVersion,
+				UpdatedAt:   file.UpdatedAt,
+			}
+			if file.Encrypted() {
+				entry.GroupID = project.GroupID
+				entry.EncryptionVersion = file.EncryptionVersion
+				entry.EncryptionNonce = file.EncryptionNonce
+			}
+
+			if target.Mode == BackupModeContent && !file.IsFolder {
+				objectName := minio.GetObjectName(file.ProjectID, file.FilePath, file.FileName)
+				entry.Object = runPrefix + "objects/" + objectName
+				if err := s.copyBackupObject(ctx, sourceBucket, objectName, dest, bucketName, entry.Object, file); err != nil {
+					return err
+				}
+			}
+
+			if err := encoder.Encode(&entry); err != nil {
+				return fmt.Errorf("写入备份清单失败: %w", err)
+			}
+		}
+		afterID = files[len(files)-1].ID
+	}
+}
+
+// copyBackupObject 将文件内容从其所在存储后端的群组存储桶复制到备份存储后端的备份存储桶
+func (s *fileService) copyBackupObject(ctx context.Context, sourceBucket, sourceObject string, dest *minio.Client, bucketName, objectName string, file *entity.File) error {
+	client, err := s.fileStorage(file)
+	if err != nil {
+		return fmt.Errorf("读取文件 %s 失败: %w", file.FullPath, err)
+	}
+	reader, err := client.GetObject(ctx, sourceBucket, sourceObject, nil)
+	if err != nil {
+		return fmt.Errorf("读取文件 %s 失败: %w", file.FullPath, err)
+	}
+	defer reader.Close()
+
+	if err := dest.PutObject(ctx, bucketName, objectName, reader, storedObjectSize(file), file.MimeType); err != nil {
+		return fmt.Errorf("备份文件 %s 失败: %w", file.FullPath, err)
+	}
+	return nil
+}
+
+// pruneBackups 删除目标超出保留数量的旧备份，备份目录名按时间排序
+func pruneBackups(ctx context.Context, dest *minio.Client, bucketName string, target BackupTarget) error {
+	var runs []string
+	for object := range dest.ListObjects(ctx, bucketName, target.Name+"/", false) {
+		if object.Err != nil {
+			return object.Err
+		}
+		if strings.HasSuffix(object.Key, "/") {
+			runs = append(runs, object.Key)
+		}
+	}
+	if len(runs) <= target.Retention {
+		return nil
+	}
+
+	sort.Strings(runs)
+	for _, run := range runs[:len(runs)-target.Retention] {
+		for object := range dest.ListObjects(ctx, bucketName, run, true) {
+			if object.Err != nil {
+				return object.Err
+			}
+			if err := dest.RemoveObject(ctx, bucketName, object.Key); err != nil {
+				return fmt.Errorf("删除旧备份对象 %s 失败: %w", object.Key, err)
+			}
+		}
+		log.Printf("已删除备份 %s 的旧备份 %s", target.Name, run)
+	}
+	return nil
+}
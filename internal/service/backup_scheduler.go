@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"oss-backend/internal/model/dto"
+)
+
+// backupSchedulerCreator 定时触发的备份任务的创建者
+const backupSchedulerCreator = "scheduler"
+
+// ErrBackupTargetNotFound 备份目标不存在
+var ErrBackupTargetNotFound = errors.New("备份目标不存在")
+
+// ErrBackupRunning 同一目标的上一次备份仍在执行
+var ErrBackupRunning = errors.New("该目标的备份正在执行")
+
+// backupSchedule 一个备份目标的计划与最近一次执行
+type backupSchedule struct {
+	target    BackupTarget
+	entryID   cron.EntryID
+	lastJobID string
+	lastRunAt *time.Time
+}
+
+// BackupScheduler 定时备份调度器
+// 按各备份目标的cron表达式提交备份任务，任务通过 JobService 执行，可在后台任务列表中查看和取消；
+// 同一目标的上一次备份未结束时跳过本次触发
+type BackupScheduler struct {
+	fileService FileService
+	jobService  JobService
+	cron        *cron.Cron
+
+	mu        sync.Mutex
+	schedules []*backupSchedule
+}
+
+// NewBackupScheduler 按 backup.targets 配置创建备份调度器，调用 Start 后开始定时执行
+func NewBackupScheduler(fileService FileService, jobService JobService) (*BackupScheduler, error) {
+	targets, err := LoadBackupTargets()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &BackupScheduler{
+		fileService: fileService,
+		jobService:  jobService,
+		cron:        cron.New(),
+	}
+	for _, target := range targets {
+		schedule := &backupSchedule{target: target}
+		entryID, err := s.cron.AddFunc(target.Schedule, func() {
+			if _, err := s.run(schedule, backupSchedulerCreator); err != nil {
+				log.Printf("定时备份 %s 未执行: %v", schedule.target.Name, err)
+			}
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w: 备份目标 %s: %v", ErrInvalidBackupConfig, target.Name, err)
+		}
+		schedule.entryID = entryID
+		s.schedules = append(s.schedules, schedule)
+	}
+	return s, nil
+}
+
+// Start 开始按计划执行备份
+func (s *BackupScheduler) Start() {
+	s.cron.Start()
+}
+
+// Stop 停止调度，返回的 context 在正在执行的调度回调结束后完成
+// 已提交的备份任务不会被中断
+func (s *BackupScheduler) Stop() context.Context {
+	return s.cron.Stop()
+}
+
+// Trigger 立即执行一次指定目标的备份，返回任务ID
+func (s *BackupScheduler) Trigger(name, creatorID string) (string, error) {
+	for _, schedule := range s.schedules {
+		if schedule.target.Name == name {
+			return s.run(schedule, creatorID)
+		}
+	}
+	return "", ErrBackupTargetNotFound
+}
+
+// ListSchedules 列出备份计划及最近一次执行情况
+func (s *BackupScheduler) ListSchedules(ctx context.Context) []*dto.BackupScheduleResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]*dto.BackupScheduleResponse, 0, len(s.schedules))
+	for _, schedule := range s.schedules {
+		resp := &dto.BackupScheduleResponse{
+			Name:       schedule.target.Name,
+			Schedule:   schedule.target.Schedule,
+			Mode:       schedule.target.Mode,
+			ProjectIDs: schedule.target.ProjectIDs,
+			Retention:  schedule.target.Retention,
+			Backend:    schedule.target.Backend,
+			LastRunAt:  schedule.lastRunAt,
+		}
+		if next := s.cron.Entry(schedule.entryID).Next; !next.IsZero() {
+			resp.NextRunAt = &next
+		}
+		if schedule.lastJobID != "" {
+			if job, err := s.jobService.GetJob(ctx, schedule.lastJobID); err == nil {
+				resp.LastJob = job
+			}
+		}
+		result = append(result, resp)
+	}
+	return result
+}
+
+// run 提交一次备份任务，同一目标的上一次任务仍在执行时返回 ErrBackupRunning
+func (s *BackupScheduler) run(schedule *backupSchedule, creatorID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if schedule.lastJobID != "" {
+		if job, err := s.jobService.GetJob(context.Background(), schedule.lastJobID); err == nil && job.Status == JobStatusRunning {
+			return "", ErrBackupRunning
+		}
+	}
+
+	target := schedule.target
+	jobID := s.jobService.Submit(JobTypeBackup, creatorID, func(jobCtx context.Context) error {
+		manifestName, err := s.fileService.RunBackup(jobCtx, target)
+		if err != nil {
+			return err
+		}
+		SetJobResult(jobCtx, manifestName)
+		log.Printf("备份 %s 已完成: %s", target.Name, manifestName)
+		return nil
+	})
+
+	now := time.Now()
+	schedule.lastJobID = jobID
+	schedule.lastRunAt = &now
+	return jobID, nil
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+	"oss-backend/pkg/minio"
+)
+
+// setTestStorageBackends 使用测试客户端作为额外存储后端，并写入对应的 storage.backends 配置
+func setTestStorageBackends(t *testing.T, clients map[string]*minio.Client) {
+	t.Helper()
+	var backends []map[string]interface{}
+	for name := range clients {
+		backends = append(backends, map[string]interface{}{"name": name, "endpoint": "test"})
+	}
+	viper.Set("storage.backends", backends)
+
+	storageBackends.once.Do(func() {})
+	previous := storageBackends.clients
+	storageBackends.clients = clients
+	t.Cleanup(func() {
+		viper.Set("storage.backends", nil)
+		storageBackends.clients = previous
+	})
+}
+
+// readBackupManifest 读取备份清单
+func readBackupManifest(t *testing.T, data []byte) []backupManifestEntry {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("解压备份清单失败: %v", err)
+	}
+	var entries []backupManifestEntry
+	decoder := json.NewDecoder(zr)
+	for decoder.More() {
+		var entry backupManifestEntry
+		if err := decoder.Decode(&entry); err != nil {
+			t.Fatalf("解析备份清单失败: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// TestTriggerBackup 手动触发备份生成清单和文件内容，默认目标写入默认后端的备份存储桶，外部目标写入配置的外部存储
+func TestTriggerBackup(t *testing.T) {
+	project := newTestProject()
+	svc, store, db := newTestFileService(t, &testFileRepo{}, project)
+	svc.fileRepo = repository.NewFileRepository(db)
+	externalStore, externalClient := newFakeObjectStore(t)
+	setTestStorageBackends(t, map[string]*minio.Client{"offsite": externalClient})
+
+	viper.Set("backup.targets", []map[string]interface{}{
+		{"name": "nightly", "schedule": "@daily", "mode": BackupModeContent, "project_ids": []string{project.ID}},
+		{"name": "offsite", "schedule": "@daily", "mode": BackupModeContent, "project_ids": []string{project.ID}, "backend": "offsite"},
+	})
+	t.Cleanup(func() { viper.Set("backup.targets", nil) })
+
+	content := "quarterly numbers"
+	records := []*entity.File{
+		{ID: "f-1", ProjectID: project.ID, FileName: "docs", FullPath: "docs/", IsFolder: true, CurrentVersion: 1},
+		{ID: "f-2", ProjectID: project.ID, FileName: "report.txt", FilePath: "docs/", FullPath: "docs/report.txt", FileSize: int64(len(content)), FileHash: "hash", MimeType: "text/plain", CurrentVersion: 1},
+	}
+	for _, record := range records {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatalf("写入测试数据失败: %v", err)
+		}
+	}
+	store.putObject(groupBucketName(project.Group.GroupKey), minio.GetObjectName(project.ID, "docs/", "report.txt"), []byte(content))
+
+	jobService := NewJobService()
+	scheduler, err := NewBackupScheduler(svc, jobService)
+	if err != nil {
+		t.Fatalf("创建备份调度器失败: %v", err)
+	}
+
+	for _, tt := range []struct {
+		target       string
+		store, other *fakeObjectStore
+	}{
+		{"nightly", store, externalStore},
+		{"offsite", externalStore, store},
+	} {
+		jobID, err := scheduler.Trigger(tt.target, "admin")
+		if err != nil {
+			t.Fatalf("触发备份 %s 失败: %v", tt.target, err)
+		}
+		job := waitJob(t, jobService, jobID)
+		if job.Status != JobStatusCompleted {
+			t.Fatalf("备份 %s 的任务状态为 %s: %s", tt.target, job.Status, job.Message)
+		}
+		if !strings.HasPrefix(job.Result, tt.target+"/") || !strings.HasSuffix(job.Result, "/manifest.jsonl.gz") {
+			t.Fatalf("备份 %s 的清单对象名为 %s", tt.target, job.Result)
+		}
+
+		data, ok := tt.store.object(backupBucket(), job.Result)
+		if !ok {
+			t.Fatalf("备份 %s 的清单没有写入目标存储", tt.target)
+		}
+		if keys := tt.other.keys(backupBucket(), tt.target+"/"); len(keys) != 0 {
+			t.Fatalf("备份 %s 写入了其他存储: %v", tt.target, keys)
+		}
+		entries := readBackupManifest(t, data)
+		if len(entries) != 2 {
+			t.Fatalf("备份 %s 的清单有 %d 条，应为文件夹和文件共2条", tt.target, len(entries))
+		}
+		for _, entry := range entries {
+			if entry.IsFolder {
+				continue
+			}
+			if entry.FullPath != "docs/report.txt" || entry.FileHash != "hash" || entry.Object == "" {
+				t.Fatalf("备份 %s 的文件记录为 %+v", tt.target, entry)
+			}
+			if copied, _ := tt.store.object(backupBucket(), entry.Object); string(copied) != content {
+				t.Fatalf("备份 %s 的文件内容为 %q", tt.target, copied)
+			}
+		}
+	}
+
+	schedules := scheduler.ListSchedules(context.Background())
+	if len(schedules) != 2 || schedules[1].Backend != "offsite" || schedules[0].Backend != DefaultStorageBackend {
+		t.Fatalf("备份计划为 %+v", schedules)
+	}
+	for _, schedule := range schedules {
+		if schedule.LastJob == nil || schedule.LastJob.Status != JobStatusCompleted || schedule.LastRunAt == nil {
+			t.Fatalf("备份 %s 的执行情况为 %+v", schedule.Name, schedule)
+		}
+	}
+}
+
+// TestBackupTargetUnknownBackend 备份目标的存储后端不存在时配置无效
+func TestBackupTargetUnknownBackend(t *testing.T) {
+	viper.Set("backup.targets", []map[string]interface{}{
+		{"name": "offsite", "schedule": "@daily", "backend": "missing"},
+	})
+	t.Cleanup(func() { viper.Set("backup.targets", nil) })
+
+	if _, err := LoadBackupTargets(); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Fatalf("存储后端不存在时返回 %v，应返回配置错误", err)
+	}
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/spf13/viper"
+
+	"oss-backend/internal/model/dto"
+	"oss-backend/internal/model/entity"
+)
+
+const (
+	// TempObjectPrefix 临时对象的前缀，该前缀下的对象按生命周期规则自动过期
+	TempObjectPrefix = "temp/"
+	// tempLifecycleRuleID 临时对象过期规则在存储桶生命周期配置中的ID
+	tempLifecycleRuleID   = "oss-temp-expiration"
+	defaultTempExpireDays = 7
+)
+
+// tempExpireDays 获取临时对象的保留天数，配置为0时不设置过期规则
+func tempExpireDays() int {
+	if !viper.IsSet("storage.temp_expire_days") {
+		return defaultTempExpireDays
+	}
+	days := viper.GetInt("storage.temp_expire_days")
+	if days < 0 {
+		days = 0
+	}
+	return days
+}
+
+// applyTempLifecycle 为存储桶设置临时对象的过期规则，保留天数为0时移除该规则
+func (s *fileService) applyTempLifecycle(ctx context.Context, bucketName string) error {
+	return s.minioClient.SetPrefixExpiration(ctx, bucketName, tempLifecycleRuleID, TempObjectPrefix, tempExpireDays())
+}
+
+// ApplyTempLifecycle 为所有群组已创建的存储桶重新设置临时对象的过期规则，用于修改保留天数后或为规则出现前创建的存储桶补充设置
+// 群组尚未上传过文件、存储桶不存在时跳过；单个存储桶失败不影响其他存储桶
+func (s *fileService) ApplyTempLifecycle(ctx context.Context) (*dto.BucketLifecycleResponse, error) {
+	var groupKeys []string
+	if err := s.db.WithContext(ctx).Model(&entity.Group{}).Pluck("group_key", &groupKeys).Error; err != nil {
+		return nil, fmt.Errorf("获取群组失败: %w", err)
+	}
+
+	response := &dto.BucketLifecycleResponse{
+		Prefix:     TempObjectPrefix,
+		ExpireDays: tempExpireDays(),
+		Applied:    []string{},
+		Failed:     []dto.BucketLifecycleFailure{},
+	}
+	seen := make(map[string]bool, len(groupKeys))
+	for _, groupKey := range groupKeys {
+		bucketName := groupBucketName(groupKey)
+		if seen[bucketName] {
+			continue
+		}
+		seen[bucketName] = true
+
+		exists, err := s.minioClient.BucketExists(ctx, bucketName)
+		if err == nil && !exists {
+			continue
+		}
+		if err == nil {
+			err = s.applyTempLifecycle(ctx, bucketName)
+		}
+		if err != nil {
+			log.Printf("设置存储桶 %s 的生命周期规则失败: %v", bucketName, err)
+			response.Failed = append(response.Failed, dto.BucketLifecycleFailure{Bucket: bucketName, Error: err.Error()})
+			continue
+		}
+		response.Applied = append(response.Applied, bucketName)
+	}
+	return response, nil
+}
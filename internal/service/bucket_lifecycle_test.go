@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"encoding/xml"
+	"testing"
+
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
+	"github.com/spf13/viper"
+
+	"oss-backend/internal/model/entity"
+)
+
+// TestTempLifecycleApplied 创建存储桶时设置 temp/ 前缀的过期规则；管理接口按新的保留天数重新设置已有存储桶，
+// 保留存储桶上的其他规则，跳过尚未创建的存储桶
+func TestTempLifecycleApplied(t *testing.T) {
+	ctx := context.Background()
+	t.Cleanup(func() { viper.Set("storage.temp_expire_days", nil) })
+	project := newTestProject()
+	svc, store, db := newTestFileService(t, &testFileRepo{}, project)
+	createTestTables(t, db, &entity.Group{})
+	bucket := groupBucketName(project.Group.GroupKey)
+	delete(store.buckets, bucket)
+
+	rules := func(bucket string) map[string]lifecycle.Rule {
+		t.Helper()
+		data, ok := store.lifecycle(bucket)
+		if !ok {
+			t.Fatalf("存储桶 %s 没有设置生命周期规则", bucket)
+		}
+		var config lifecycle.Configuration
+		if err := xml.Unmarshal(data, &config); err != nil {
+			t.Fatalf("生命周期配置无效: %v", err)
+		}
+		byID := make(map[string]lifecycle.Rule, len(config.Rules))
+		for _, rule := range config.Rules {
+			byID[rule.ID] = rule
+		}
+		return byID
+	}
+
+	// 首次上传时创建存储桶并设置默认的过期规则
+	if _, err := svc.Upload(ctx, project.ID, "user-1", newFileHeader(t, "a.txt", "content"), "", ""); err != nil {
+		t.Fatalf("上传失败: %v", err)
+	}
+	rule, ok := rules(bucket)[tempLifecycleRuleID]
+	if !ok || rule.Status != "Enabled" || rule.RuleFilter.Prefix != TempObjectPrefix || int(rule.Expiration.Days) != defaultTempExpireDays {
+		t.Fatalf("创建存储桶时设置的规则为 %+v，应为 %s 前缀 %d 天后过期", rule, TempObjectPrefix, defaultTempExpireDays)
+	}
+
+	// 存储桶上已有其他规则
+	if err := svc.minioClient.SetPrefixExpiration(ctx, bucket, "other-rule", "logs/", 30); err != nil {
+		t.Fatalf("设置其他规则失败: %v", err)
+	}
+	groups := []*entity.Group{
+		{ID: "group-1", Name: "team", GroupKey: project.Group.GroupKey, InviteCode: "invite-1", CreatorID: "user-1", Status: 1},
+		// 尚未上传过文件的群组没有存储桶
+		{ID: "group-2", Name: "empty", GroupKey: "empty", InviteCode: "invite-2", CreatorID: "user-1", Status: 1},
+	}
+	for _, group := range groups {
+		if err := db.Create(group).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	viper.Set("storage.temp_expire_days", 3)
+	resp, err := svc.ApplyTempLifecycle(ctx)
+	if err != nil {
+		t.Fatalf("重新设置生命周期规则失败: %v", err)
+	}
+	if len(resp.Applied) != 1 || resp.Applied[0] != bucket || len(resp.Failed) != 0 || resp.ExpireDays != 3 {
+		t.Fatalf("重新设置的结果为 %+v，应只设置存储桶 %s", resp, bucket)
+	}
+	current := rules(bucket)
+	if rule := current[tempLifecycleRuleID]; int(rule.Expiration.Days) != 3 || rule.RuleFilter.Prefix != TempObjectPrefix {
+		t.Fatalf("重新设置后的规则为 %+v，应为3天后过期", rule)
+	}
+	if rule, ok := current["other-rule"]; !ok || int(rule.Expiration.Days) != 30 {
+		t.Fatalf("存储桶上的其他规则被修改: %+v", current)
+	}
+	if _, ok := store.lifecycle(groupBucketName("empty")); ok {
+		t.Fatalf("为不存在的存储桶设置了生命周期规则")
+	}
+
+	// 保留天数为0时移除过期规则，其他规则保持不变
+	viper.Set("storage.temp_expire_days", 0)
+	if _, err := svc.ApplyTempLifecycle(ctx); err != nil {
+		t.Fatalf("移除生命周期规则失败: %v", err)
+	}
+	current = rules(bucket)
+	if _, ok := current[tempLifecycleRuleID]; ok || len(current) != 1 {
+		t.Fatalf("保留天数为0时规则为 %+v，应只剩其他规则", current)
+	}
+}
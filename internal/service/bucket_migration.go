@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/pkg/minio"
+)
+
+// ErrInvalidBucketMigration 原存储桶布局无效或与当前布局相同
+var ErrInvalidBucketMigration = errors.New("无法迁移存储桶布局")
+
+// CheckBucketMigration 校验切换存储桶策略之前的布局
+func (s *fileService) CheckBucketMigration(fromStrategy, fromSharedBucket string) error {
+	_, err := s.bucketMigrationSource(fromStrategy, fromSharedBucket)
+	return err
+}
+
+// bucketMigrationSource 解析原存储桶布局，不能与当前布局相同
+func (s *fileService) bucketMigrationSource(fromStrategy, fromSharedBucket string) (minio.BucketLayout, error) {
+	from, err := minio.NewBucketLayout(fromStrategy, fromSharedBucket)
+	if err != nil {
+		return from, fmt.Errorf("%w: %v", ErrInvalidBucketMigration, err)
+	}
+	if from == s.minioClient.Layout() {
+		return from, fmt.Errorf("%w: 原布局与当前配置的布局相同", ErrInvalidBucketMigration)
+	}
+	return from, nil
+}
+
+// MigrateBucketLayout 切换存储桶策略后，将默认存储后端中的群组存储桶以及头像、审计归档、报表、备份存储桶中的对象
+// 从原布局移动到当前配置的布局，返回迁移结果摘要；迁移期间应暂停写入
+// 单个存储桶失败不影响其他存储桶，失败的存储桶原对象保留，可重新执行；群组存储桶迁移后重新设置临时对象的过期规则
+func (s *fileService) MigrateBucketLayout(ctx context.Context, fromStrategy, fromSharedBucket string) (string, error) {
+	from, err := s.bucketMigrationSource(fromStrategy, fromSharedBucket)
+	if err != nil {
+		return "", err
+	}
+
+	var groupKeys []string
+	if err := s.db.WithContext(ctx).Model(&entity.Group{}).Pluck("group_key", &groupKeys).Error; err != nil {
+		return "", fmt.Errorf("获取群组失败: %w", err)
+	}
+	groupBuckets := make(map[string]bool, len(groupKeys))
+	buckets := make([]string, 0, len(groupKeys)+4)
+	for _, groupKey := range groupKeys {
+		bucketName := groupBucketName(groupKey)
+		if !groupBuckets[bucketName] {
+			groupBuckets[bucketName] = true
+			buckets = append(buckets, bucketName)
+		}
+	}
+	for _, bucketName := range []string{avatarBucket(), auditArchiveBucket(), storageReportBucket(), backupBucket()} {
+		if !groupBuckets[bucketName] {
+			buckets = append(buckets, bucketName)
+		}
+	}
+
+	moved := 0
+	var failed []string
+	for i, bucketName := range buckets {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		ReportJobProgress(ctx, i, len(buckets))
+
+		count, err := s.minioClient.MigrateBucket(ctx, bucketName, from)
+		moved += count
+		if err != nil {
+			log.Printf("迁移存储桶 %s 失败: %v", bucketName, err)
+			failed = append(failed, bucketName)
+			continue
+		}
+		if groupBuckets[bucketName] {
+			if exists, err := s.minioClient.BucketExists(ctx, bucketName); err == nil && exists {
+				if err := s.applyTempLifecycle(ctx, bucketName); err != nil {
+					log.Printf("设置存储桶 %s 的生命周期规则失败: %v", bucketName, err)
+				}
+			}
+		}
+	}
+	ReportJobProgress(ctx, len(buckets), len(buckets))
+
+	if len(failed) > 0 {
+		return "", fmt.Errorf("%d 个存储桶迁移失败: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return fmt.Sprintf("已迁移 %d 个存储桶，共 %d 个对象", len(buckets), moved), nil
+}
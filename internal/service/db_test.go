@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	sqlitedriver "github.com/glebarez/go-sqlite"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+
+	"oss-backend/internal/model/entity"
+)
+
+// testDialector 为 SQLite 补充唯一索引冲突的错误转换，与 MySQL 驱动的行为一致
+type testDialector struct {
+	*sqlite.Dialector
+}
+
+// Translate 将 SQLite 的唯一约束错误转换为 gorm.ErrDuplicatedKey
+func (testDialector) Translate(err error) error {
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return gorm.ErrDuplicatedKey
+	}
+	return err
+}
+
+// registerMySQLFunctions 为 SQLite 注册仓库查询中用到的 MySQL 字符串函数，只注册一次
+var registerMySQLFunctions = sync.OnceFunc(func() {
+	// CHAR_LENGTH(s) 按字符计算长度
+	sqlitedriver.MustRegisterDeterministicScalarFunction("char_length", 1, func(ctx *sqlitedriver.FunctionContext, args []driver.Value) (driver.Value, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		return int64(len([]rune(sqliteText(args[0])))), nil
+	})
+	// LEFT(s, n) 取前 n 个字符，LEFT 在 SQLite 中是关键字，查询中的 LEFT( 由 mysqlFuncPool 改写为 mysql_left(
+	sqlitedriver.MustRegisterDeterministicScalarFunction("mysql_left", 2, func(ctx *sqlitedriver.FunctionContext, args []driver.Value) (driver.Value, error) {
+		if args[0] == nil || args[1] == nil {
+			return nil, nil
+		}
+		s := []rune(sqliteText(args[0]))
+		n, ok := args[1].(int64)
+		if !ok {
+			return nil, fmt.Errorf("LEFT 的长度参数类型为 %T", args[1])
+		}
+		if n < 0 {
+			n = 0
+		}
+		if n > int64(len(s)) {
+			n = int64(len(s))
+		}
+		return string(s[:n]), nil
+	})
+})
+
+// sqliteText 将 SQLite 函数的参数转换为字符串
+func sqliteText(v driver.Value) string {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+// mysqlFuncConn 执行前将语句中 SQLite 无法解析的 MySQL 函数调用和运算符改写为 SQLite 的等价形式
+type mysqlFuncConn struct {
+	gorm.ConnPool
+}
+
+var (
+	mysqlLeftCall = regexp.MustCompile(`\bLEFT\(`)
+	// BINARY 用于按字节比较，SQLite 的 = 与 IN 默认即按字节比较，直接去掉
+	mysqlBinary = regexp.MustCompile(`\bBINARY\s+`)
+)
+
+func rewriteMySQLFuncs(query string) string {
+	query = mysqlBinary.ReplaceAllString(query, "")
+	return mysqlLeftCall.ReplaceAllString(query, "mysql_left(")
+}
+
+func (c mysqlFuncConn) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return c.ConnPool.PrepareContext(ctx, rewriteMySQLFuncs(query))
+}
+
+func (c mysqlFuncConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return c.ConnPool.ExecContext(ctx, rewriteMySQLFuncs(query), args...)
+}
+
+func (c mysqlFuncConn) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return c.ConnPool.QueryContext(ctx, rewriteMySQLFuncs(query), args...)
+}
+
+func (c mysqlFuncConn) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return c.ConnPool.QueryRowContext(ctx, rewriteMySQLFuncs(query), args...)
+}
+
+// mysqlFuncPool 改写语句的连接池
+type mysqlFuncPool struct {
+	mysqlFuncConn
+	db *sql.DB
+}
+
+// BeginTx 开启事务，事务内的语句同样改写
+func (p *mysqlFuncPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	tx, err := p.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &mysqlFuncTx{mysqlFuncConn{tx}, tx}, nil
+}
+
+// GetDBConn 返回底层连接，供 db.DB() 使用
+func (p *mysqlFuncPool) GetDBConn() (*sql.DB, error) {
+	return p.db, nil
+}
+
+// mysqlFuncTx 改写语句的事务
+type mysqlFuncTx struct {
+	mysqlFuncConn
+	tx *sql.Tx
+}
+
+func (t *mysqlFuncTx) Commit() error   { return t.tx.Commit() }
+func (t *mysqlFuncTx) Rollback() error { return t.tx.Rollback() }
+
+// newTestDB 创建测试用的 SQLite 数据库，并按实体的字段建表
+// 实体使用 MySQL 专有的列定义，不能直接迁移，这里只按列名建表并建立唯一索引；idx_project_live_path 依赖 MySQL 生成列，改用等价的部分索引
+func newTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+	registerMySQLFunctions()
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_pragma=busy_timeout(5000)"
+	db, err := gorm.Open(testDialector{sqlite.Open(dsn).(*sqlite.Dialector)}, &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("打开测试数据库失败: %v", err)
+	}
+	sqlDB := db.ConnPool.(*sql.DB)
+	db.ConnPool = &mysqlFuncPool{mysqlFuncConn{sqlDB}, sqlDB}
+	db.Statement.ConnPool = db.ConnPool
+	// SQLite 同一时间只允许一个写事务，单连接让并发事务排队而不是返回 database is locked
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	createTestTables(t, db, models...)
+	return db
+}
+
+// createTestTables 在测试数据库中按实体的字段建表
+func createTestTables(t *testing.T, db *gorm.DB, models ...interface{}) {
+	t.Helper()
+	for _, model := range models {
+		s, err := schema.Parse(model, &sync.Map{}, db.NamingStrategy)
+		if err != nil {
+			t.Fatalf("解析实体失败: %v", err)
+		}
+		columns := make([]string, 0, len(s.DBNames))
+		for _, name := range s.DBNames {
+			column := name + " " + sqliteColumnType(s.FieldsByDBName[name])
+			// 只读字段不会出现在 INSERT 中，依赖列默认值
+			if value := s.FieldsByDBName[name].DefaultValue; value != "" {
+				column += " DEFAULT " + value
+			}
+			columns = append(columns, column)
+		}
+		if err := db.Exec(fmt.Sprintf("CREATE TABLE %s (%s)", s.Table, strings.Join(columns, ", "))).Error; err != nil {
+			t.Fatalf("创建表 %s 失败: %v", s.Table, err)
+		}
+		for _, index := range s.ParseIndexes() {
+			if index.Class != "UNIQUE" || index.Name == "idx_project_live_path" {
+				continue
+			}
+			fields := make([]string, 0, len(index.Fields))
+			for _, field := range index.Fields {
+				fields = append(fields, field.DBName)
+			}
+			if err := db.Exec(fmt.Sprintf("CREATE UNIQUE INDEX %s ON %s (%s)", index.Name, s.Table, strings.Join(fields, ", "))).Error; err != nil {
+				t.Fatalf("创建唯一索引 %s 失败: %v", index.Name, err)
+			}
+		}
+		if s.Table == (entity.File{}).TableName() {
+			if err := db.Exec("CREATE UNIQUE INDEX idx_project_live_path ON files (project_id, rtrim(full_path, '/')) WHERE NOT is_deleted AND gorm_deleted_at IS NULL").Error; err != nil {
+				t.Fatalf("创建路径唯一索引失败: %v", err)
+			}
+		}
+	}
+}
+
+// sqliteColumnType 字段在 SQLite 中的列类型，时间列须声明为 datetime 才能读回 time.Time
+func sqliteColumnType(field *schema.Field) string {
+	switch field.DataType {
+	case schema.Bool:
+		return "boolean"
+	case schema.Int, schema.Uint:
+		return "integer"
+	case schema.Float:
+		return "real"
+	case schema.Time:
+		return "datetime"
+	}
+	if field.GORMDataType == schema.Time {
+		return "datetime"
+	}
+	return "text"
+}
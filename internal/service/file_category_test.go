@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+)
+
+// TestListFilesByCategory 按分类筛选混合内容的文件夹只返回该分类的文件，与路径筛选和分页同时生效
+func TestListFilesByCategory(t *testing.T) {
+	ctx := context.Background()
+	project := newTestProject()
+	svc, _, db := newTestFileService(t, nil, project)
+	createTestTables(t, db, &entity.FileDeny{})
+	svc.fileRepo = repository.NewFileRepository(db)
+
+	files := []*entity.File{
+		{FileName: "gallery", FilePath: "", IsFolder: true},
+		{FileName: "sub", FilePath: "gallery/", IsFolder: true},
+		{FileName: "a.png", FilePath: "gallery/", MimeType: "image/png"},
+		{FileName: "b.jpg", FilePath: "gallery/", MimeType: "image/jpeg"},
+		{FileName: "c.svg", FilePath: "gallery/", MimeType: "image/svg+xml"},
+		{FileName: "clip.mp4", FilePath: "gallery/", MimeType: "video/mp4"},
+		{FileName: "notes.txt", FilePath: "gallery/", MimeType: "text/plain; charset=utf-8"},
+		{FileName: "report.pdf", FilePath: "gallery/", MimeType: "application/pdf"},
+		{FileName: "bundle.zip", FilePath: "gallery/", MimeType: "application/zip"},
+		{FileName: "blob.bin", FilePath: "gallery/", MimeType: "application/octet-stream"},
+		{FileName: "unknown", FilePath: "gallery/"},
+		{FileName: "nested.png", FilePath: "gallery/sub/", MimeType: "image/png"},
+		{FileName: "root.png", FilePath: "", MimeType: "image/png"},
+	}
+	for _, f := range files {
+		f.ID = "file-" + f.FileName
+		f.ProjectID = project.ID
+		f.FullPath = f.FilePath + f.FileName
+		if f.IsFolder {
+			f.FullPath += "/"
+		}
+		f.UploaderID, f.CurrentVersion = "user-1", 1
+		if err := db.Create(f).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	names := func(files []*entity.File) []string {
+		var result []string
+		for _, f := range files {
+			result = append(result, f.FileName)
+		}
+		return result
+	}
+	list := func(category string, recursive bool, page, pageSize int) ([]string, int64) {
+		t.Helper()
+		files, total, err := svc.ListFiles(ctx, project.ID, "user-1", "gallery/", "", category, recursive, page, pageSize)
+		if err != nil {
+			t.Fatalf("按分类 %q 列出文件失败: %v", category, err)
+		}
+		return names(files), total
+	}
+	if got, total := list(entity.FileCategoryImage, false, 1, 10); total != 3 || !reflect.DeepEqual(got, []string{"a.png", "b.jpg", "c.svg"}) {
+		t.Fatalf("gallery/ 中的图片为 %v（共 %d 个），应只有 a.png、b.jpg、c.svg", got, total)
+	}
+	if got, total := list(entity.FileCategoryImage, true, 1, 10); total != 4 || !reflect.DeepEqual(got, []string{"a.png", "b.jpg", "c.svg", "nested.png"}) {
+		t.Fatalf("递归列出的图片为 %v（共 %d 个），应包含子目录中的 nested.png", got, total)
+	}
+	// 分页时总数仍为全部图片
+	if got, total := list(entity.FileCategoryImage, false, 2, 2); total != 3 || !reflect.DeepEqual(got, []string{"c.svg"}) {
+		t.Fatalf("第2页图片为 %v（共 %d 个），应为 c.svg（共3个）", got, total)
+	}
+	if got, _ := list(entity.FileCategoryOther, false, 1, 10); !reflect.DeepEqual(got, []string{"blob.bin", "unknown"}) {
+		t.Fatalf("其他分类为 %v，应为 blob.bin、unknown", got)
+	}
+	if got, total := list("", false, 1, 20); total != 10 || got[0] != "sub" {
+		t.Fatalf("不筛选分类时列出 %v（共 %d 个），应包含文件夹在内的10个条目", got, total)
+	}
+}
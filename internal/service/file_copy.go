@@ -0,0 +1,292 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/utils"
+	"oss-backend/pkg/minio"
+)
+
+// 复制文件夹时默认的条目数上限
+const defaultCopyMaxEntries = 1000
+
+// ErrInvalidCopy 无法复制到目标位置
+var ErrInvalidCopy = errors.New("无法复制到目标位置")
+
+// copyMaxEntries 获取单次复制的文件和文件夹数上限
+func copyMaxEntries() int {
+	maxEntries := viper.GetInt("storage.copy_max_entries")
+	if maxEntries <= 0 {
+		maxEntries = defaultCopyMaxEntries
+	}
+	return maxEntries
+}
+
+// copyEntry 复制中的一个文件或文件夹及其在目标项目中的位置
+type copyEntry struct {
+	source   *entity.File
+	filePath string // 目标目录，以/结尾，根目录为空
+	object   string // 目标对象名，文件夹为空
+	enc      entity.ObjectEncryption
+}
+
+// CopyFile 将文件或文件夹复制到目标项目的目标目录下，文件夹连同其下的子文件夹和文件一起复制
+// 对象在服务端复制到目标项目的存储桶，新文件位于默认存储后端，只保留初始版本；返回新建的文件或文件夹
+func (s *fileService) CopyFile(ctx context.Context, fileID, targetProjectID, targetPath, userID string) (*entity.File, error) {
+	// 1. 获取源文件与源、目标项目
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file == nil || file.IsDeleted {
+		return nil, errors.New("文件不存在")
+	}
+	sourceProject, err := s.projectRepo.GetByID(ctx, file.ProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("获取项目信息失败: %w", err)
+	}
+	if sourceProject == nil {
+		return nil, errors.New("项目不存在")
+	}
+	if targetProjectID == "" {
+		targetProjectID = file.ProjectID
+	}
+	targetProject := sourceProject
+	if targetProjectID != file.ProjectID {
+		targetProject, err = s.projectRepo.GetByID(ctx, targetProjectID)
+		if err != nil {
+			return nil, fmt.Errorf("获取目标项目信息失败: %w", err)
+		}
+		if targetProject == nil {
+			return nil, fmt.Errorf("%w: 目标项目不存在", ErrInvalidCopy)
+		}
+	}
+	if err := checkProjectWritable(targetProject); err != nil {
+		return nil, err
+	}
+
+	// 2. 校验目标目录
+	targetPath, err = s.resolveTargetFolder(ctx, targetProject, targetPath, ErrInvalidCopy)
+	if err != nil {
+		return nil, err
+	}
+	if file.IsFolder && targetProject.ID == file.ProjectID && strings.HasPrefix(targetPath, file.FullPath) {
+		return nil, fmt.Errorf("%w: 不能将文件夹复制到其自身或子文件夹中", ErrInvalidCopy)
+	}
+	existing, err := s.findByPath(ctx, targetProject, targetPath, file.FileName)
+	if err != nil {
+		return nil, fmt.Errorf("检查文件路径失败: %w", err)
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("%w: 目标目录下已存在同名文件或文件夹 %s", ErrInvalidCopy, existing.FullPath)
+	}
+
+	// 3. 确定需要复制的条目，文件夹按路径排序使父目录在前
+	entries, err := s.collectCopyEntries(ctx, file, targetProject.ID, targetPath, userID)
+	if err != nil {
+		return nil, err
+	}
+	var totalSize, fileCount int64
+	for _, entry := range entries {
+		if !entry.source.IsFolder {
+			totalSize += entry.source.FileSize
+			fileCount++
+		}
+	}
+	releaseQuota, err := s.reserveStorage(ctx, targetProject, totalSize)
+	if err != nil {
+		return nil, err
+	}
+	defer releaseQuota()
+
+	// 4. 先复制对象，失败时删除已复制的对象
+	sourceBucket := s.sanitizeBucketName(sourceProject.Group.GroupKey)
+	targetBucket := s.sanitizeBucketName(targetProject.Group.GroupKey)
+	if err := s.ensureBucketExists(ctx, targetBucket); err != nil {
+		return nil, fmt.Errorf("存储准备失败: %w", err)
+	}
+	var copied []string
+	cleanup := func() {
+		for _, object := range copied {
+			if err := s.minioClient.RemoveObject(ctx, targetBucket, object); err != nil {
+				log.Printf("复制失败后删除对象 %s 失败: %v", object, err)
+			}
+		}
+	}
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			cleanup()
+			return nil, err
+		}
+		if entry.source.IsFolder {
+			continue
+		}
+		enc, err := s.copyFileObject(ctx, entry.source, &sourceProject.Group, &targetProject.Group, sourceBucket, targetBucket, entry.object)
+		if err != nil {
+			cleanup()
+			return nil, fmt.Errorf("复制文件 %s 失败: %w", entry.source.FullPath, err)
+		}
+		entry.enc = enc
+		copied = append(copied, entry.object)
+	}
+
+	// 5. 事务中创建文件及初始版本记录
+	var root *entity.File
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, entry := range entries {
+			newFile := &entity.File{
+				ID:                utils.GenerateFileID(),
+				ProjectID:         targetProject.ID,
+				FileName:          entry.source.FileName,
+				FilePath:          entry.filePath,
+				FullPath:          entry.filePath + entry.source.FileName,
+				FileHash:          entry.source.FileHash,
+				FileSize:          entry.source.FileSize,
+				MimeType:          entry.source.MimeType,
+				Extension:         entry.source.Extension,
+				IsFolder:          entry.source.IsFolder,
+				UploaderID:        userID,
+				CurrentVersion:    1,
+				WatermarkRequired: entry.source.WatermarkRequired,
+				WatermarkText:     entry.source.WatermarkText,
+				ObjectEncryption:  entry.enc,
+			}
+			if newFile.IsFolder {
+				newFile.FullPath += "/"
+			}
+			if err := tx.Create(newFile).Error; err != nil {
+				return fmt.Errorf("创建文件记录 %s 失败: %w", newFile.FullPath, err)
+			}
+			if !newFile.IsFolder {
+				version := &entity.FileVersion{
+					ID:               utils.GenerateRecordID(),
+					FileID:           newFile.ID,
+					Version:          1,
+					FileHash:         newFile.FileHash,
+					FileSize:         newFile.FileSize,
+					StorageKey:       entry.object,
+					UploaderID:       userID,
+					Comment:          fmt.Sprintf("复制自 %s", entry.source.FullPath),
+					ObjectEncryption: entry.enc,
+				}
+				if err := tx.Create(version).Error; err != nil {
+					return fmt.Errorf("创建版本记录失败: %w", err)
+				}
+			}
+			if root == nil {
+				root = newFile
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	s.enqueueStats(targetProject.ID, fileCount, totalSize)
+	s.recordAudit(ctx, userID, entity.OperationCopy, targetProject, root)
+
+	return root, nil
+}
+
+// resolveTargetFolder 确认目标目录在目标项目中存在，返回其实际路径（以/结尾，根目录为空），目录无效时返回包装了 invalid 的错误
+func (s *fileService) resolveTargetFolder(ctx context.Context, project *entity.Project, targetPath string, invalid error) (string, error) {
+	targetPath, err := utils.NormalizeDirPath(targetPath)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", invalid, err)
+	}
+	if targetPath == "" {
+		return "", nil
+	}
+
+	dir, name := path.Split(strings.TrimSuffix(targetPath, "/"))
+	folder, err := s.findByPath(ctx, project, dir, name)
+	if err != nil {
+		return "", fmt.Errorf("检查目标目录失败: %w", err)
+	}
+	if folder == nil || folder.IsDeleted || !folder.IsFolder {
+		return "", fmt.Errorf("%w: 目标目录 %s 不存在", invalid, targetPath)
+	}
+	return folder.FullPath, nil
+}
+
+// collectCopyEntries 列出需要复制的文件或文件夹及其子项，并计算各自在目标项目中的位置，不包含 userID 被拒绝访问的子项
+func (s *fileService) collectCopyEntries(ctx context.Context, file *entity.File, targetProjectID, targetPath, userID string) ([]*copyEntry, error) {
+	entries := []*copyEntry{{source: file, filePath: targetPath}}
+	if file.IsFolder {
+		maxEntries := copyMaxEntries()
+		children, _, err := s.fileRepo.List(ctx, file.ProjectID, file.FullPath, "", userID, true, false, 1, maxEntries+1)
+		if err != nil {
+			return nil, fmt.Errorf("获取文件夹内容失败: %w", err)
+		}
+		if len(children) >= maxEntries {
+			return nil, fmt.Errorf("%w: 文件夹内容超过 %d 项", ErrInvalidCopy, maxEntries)
+		}
+		sort.Slice(children, func(i, j int) bool { return children[i].FullPath < children[j].FullPath })
+
+		newRoot := targetPath + file.FileName + "/"
+		for _, child := range children {
+			// LIKE 匹配可能包含名称相近的其他目录，按前缀再过滤一次
+			if !strings.HasPrefix(child.FilePath, file.FullPath) {
+				continue
+			}
+			entries = append(entries, &copyEntry{
+				source:   child,
+				filePath: newRoot + strings.TrimPrefix(child.FilePath, file.FullPath),
+			})
+		}
+	}
+
+	for _, entry := range entries {
+		if !entry.source.IsFolder {
+			entry.object = minio.GetObjectName(targetProjectID, entry.filePath, entry.source.FileName)
+		}
+	}
+	return entries, nil
+}
+
+// copyFileObject 将文件的当前对象复制到目标存储桶，返回目标对象的加密信息
+// 通常直接复制对象，密文不变，源文件在默认存储后端时在服务端复制；
+// 加密的文件复制到其他群组（密钥不同）或未加密的文件复制到开启加密存储的群组时，解密后按目标群组的设置重新写入
+func (s *fileService) copyFileObject(ctx context.Context, file *entity.File, sourceGroup, targetGroup *entity.Group, sourceBucket, targetBucket, targetObject string) (entity.ObjectEncryption, error) {
+	sourceObject := minio.GetObjectName(file.ProjectID, file.FilePath, file.FileName)
+	reseal := (file.Encrypted() && sourceGroup.ID != targetGroup.ID) || (!file.Encrypted() && targetGroup.EncryptionEnabled)
+	if !reseal && file.StorageBackend == "" {
+		return file.ObjectEncryption, s.minioClient.CopyObjectToBucket(ctx, sourceBucket, sourceObject, targetBucket, targetObject)
+	}
+
+	source, err := s.fileStorage(file)
+	if err != nil {
+		return entity.ObjectEncryption{}, err
+	}
+	reader, err := source.GetObject(ctx, sourceBucket, sourceObject, nil)
+	if err != nil {
+		return entity.ObjectEncryption{}, err
+	}
+	if !reseal {
+		defer reader.Close()
+		return file.ObjectEncryption, s.minioClient.PutObject(ctx, targetBucket, targetObject, reader, storedObjectSize(file), file.MimeType)
+	}
+
+	plain, err := openSealed(reader, sourceGroup.ID, file.ObjectEncryption)
+	if err != nil {
+		return entity.ObjectEncryption{}, err
+	}
+	defer plain.Close()
+	body, size, enc, err := sealForGroup(targetGroup, plain, file.FileSize)
+	if err != nil {
+		return entity.ObjectEncryption{}, err
+	}
+	return enc, s.minioClient.PutObject(ctx, targetBucket, targetObject, body, size, file.MimeType)
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+)
+
+// newTestFolderTree 创建 docs/ 文件夹及其下的文件、子文件夹，另有名称以 docs 开头的同级文件夹，返回文件服务
+func newTestFolderTree(t *testing.T) (*fileService, *gorm.DB) {
+	t.Helper()
+	project := newTestProject()
+	svc, _, db := newTestFileService(t, nil, project)
+	svc.fileRepo = repository.NewFileRepository(db)
+
+	deletedAt := time.Now().Add(-time.Hour)
+	deletedBy := "user-1"
+	files := []*entity.File{
+		{ID: "docs", FileName: "docs", FullPath: "docs/", IsFolder: true},
+		{ID: "a", FileName: "a.txt", FilePath: "docs/", FullPath: "docs/a.txt", FileSize: 10},
+		{ID: "sub", FileName: "sub", FilePath: "docs/", FullPath: "docs/sub/", IsFolder: true},
+		{ID: "b", FileName: "b.txt", FilePath: "docs/sub/", FullPath: "docs/sub/b.txt", FileSize: 20},
+		// 删除文件夹之前已单独删除的文件
+		{ID: "old", FileName: "old.txt", FilePath: "docs/", FullPath: "docs/old.txt", FileSize: 40, IsDeleted: true, DeletedAt: &deletedAt, DeletedBy: &deletedBy},
+		{ID: "docs2", FileName: "docs2", FullPath: "docs2/", IsFolder: true},
+		{ID: "c", FileName: "c.txt", FilePath: "docs2/", FullPath: "docs2/c.txt", FileSize: 80},
+	}
+	for _, f := range files {
+		f.ProjectID, f.UploaderID, f.CurrentVersion = project.ID, "user-1", 1
+		if err := db.Create(f).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return svc, db
+}
+
+// deletedFiles 返回各文件的删除状态
+func deletedFiles(t *testing.T, db *gorm.DB) map[string]bool {
+	t.Helper()
+	var files []entity.File
+	if err := db.Find(&files).Error; err != nil {
+		t.Fatal(err)
+	}
+	deleted := make(map[string]bool, len(files))
+	for _, f := range files {
+		deleted[f.ID] = f.IsDeleted
+	}
+	return deleted
+}
+
+// TestDeleteRestoreFolderSubtree 删除文件夹时其下全部内容一起删除，恢复时一起恢复；此前单独删除的文件和同名前缀的其他文件夹不受影响，
+// 统计按受影响文件的总大小变化
+func TestDeleteRestoreFolderSubtree(t *testing.T) {
+	ctx := context.Background()
+	svc, db := newTestFolderTree(t)
+
+	if err := svc.DeleteFile(ctx, "docs", "user-1", true); err != nil {
+		t.Fatalf("删除文件夹失败: %v", err)
+	}
+	want := map[string]bool{"docs": true, "a": true, "sub": true, "b": true, "old": true, "docs2": false, "c": false}
+	for id, deleted := range deletedFiles(t, db) {
+		if deleted != want[id] {
+			t.Fatalf("删除文件夹后 %s 的删除状态为 %v，应为 %v", id, deleted, want[id])
+		}
+	}
+	delta := <-svc.statQueue.queue
+	if delta.countDelta != -2 || delta.sizeDelta != -30 {
+		t.Fatalf("删除文件夹的统计变更为 %+v，应为-2个文件、-30字节", delta)
+	}
+
+	if err := svc.RestoreFile(ctx, "docs", "user-1"); err != nil {
+		t.Fatalf("恢复文件夹失败: %v", err)
+	}
+	want = map[string]bool{"docs": false, "a": false, "sub": false, "b": false, "old": true, "docs2": false, "c": false}
+	for id, deleted := range deletedFiles(t, db) {
+		if deleted != want[id] {
+			t.Fatalf("恢复文件夹后 %s 的删除状态为 %v，应为 %v", id, deleted, want[id])
+		}
+	}
+	delta = <-svc.statQueue.queue
+	if delta.countDelta != 2 || delta.sizeDelta != 30 {
+		t.Fatalf("恢复文件夹的统计变更为 %+v，应为2个文件、30字节", delta)
+	}
+}
+
+// TestDeleteNonEmptyFolder 未指定 recursive 时删除非空文件夹返回 ErrFolderNotEmpty 且不修改任何记录；
+// 内容都已删除的文件夹可以直接删除，指定 recursive 时连同内容一起删除
+func TestDeleteNonEmptyFolder(t *testing.T) {
+	ctx := context.Background()
+	svc, db := newTestFolderTree(t)
+
+	before := deletedFiles(t, db)
+	if err := svc.DeleteFile(ctx, "docs", "user-1", false); !errors.Is(err, ErrFolderNotEmpty) {
+		t.Fatalf("删除非空文件夹返回 %v，应返回 ErrFolderNotEmpty", err)
+	}
+	if after := deletedFiles(t, db); !reflect.DeepEqual(after, before) {
+		t.Fatalf("被拒绝的删除修改了文件记录: %v", after)
+	}
+	if n := len(svc.statQueue.queue); n != 0 {
+		t.Fatalf("被拒绝的删除产生了 %d 次统计变更", n)
+	}
+
+	// 子文件夹中的文件单独删除后，子文件夹为空，不需要 recursive
+	if err := svc.DeleteFile(ctx, "b", "user-1", false); err != nil {
+		t.Fatalf("删除文件失败: %v", err)
+	}
+	if err := svc.DeleteFile(ctx, "sub", "user-1", false); err != nil {
+		t.Fatalf("删除空文件夹失败: %v", err)
+	}
+
+	if err := svc.DeleteFile(ctx, "docs", "user-1", true); err != nil {
+		t.Fatalf("指定 recursive 删除文件夹失败: %v", err)
+	}
+	deleted := deletedFiles(t, db)
+	for _, id := range []string{"docs", "a", "sub", "b", "old"} {
+		if !deleted[id] {
+			t.Fatalf("指定 recursive 删除后 %s 没有被删除", id)
+		}
+	}
+	if deleted["docs2"] || deleted["c"] {
+		t.Fatalf("删除 docs/ 时删除了 docs2/ 中的内容")
+	}
+}
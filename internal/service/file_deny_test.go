@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+)
+
+// TestDenyFileAccess 项目编辑者被拒绝访问后不能再读取文件，拒绝设置在文件夹上时包括其下的文件，移除拒绝后恢复
+func TestDenyFileAccess(t *testing.T) {
+	project := newTestProject()
+	svc, _, db := newTestFileService(t, &testFileRepo{}, project)
+	createTestTables(t, db, &entity.User{}, &entity.FileDeny{}, &entity.FolderPermission{})
+	svc.fileRepo = repository.NewFileRepository(db)
+
+	enforcer, err := casbin.NewEnforcer("../../configs/rbac_model.conf")
+	if err != nil {
+		t.Fatalf("创建 Enforcer 失败: %v", err)
+	}
+	svc.authService = NewAuthService(enforcer, nil, nil, nil, db)
+	projectDomain := "project:" + project.ID
+	for _, action := range projectRoleFileActions(ProjectRoleEditor) {
+		if _, err := enforcer.AddPermissionForUser("user:editor", projectDomain, ResourceFile, action); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records := []interface{}{
+		&entity.User{ID: "admin", Email: "admin@example.com", Name: "admin", Status: entity.UserStatusNormal},
+		&entity.User{ID: "editor", Email: "editor@example.com", Name: "editor", Status: entity.UserStatusNormal},
+		&entity.File{ID: "folder", ProjectID: project.ID, FileName: "secret", FullPath: "secret/", IsFolder: true, CurrentVersion: 1},
+		&entity.File{ID: "nested", ProjectID: project.ID, FileName: "plan.txt", FilePath: "secret/", FullPath: "secret/plan.txt", CurrentVersion: 1},
+		&entity.File{ID: "salary", ProjectID: project.ID, FileName: "salary.xlsx", FullPath: "salary.xlsx", CurrentVersion: 1},
+		&entity.File{ID: "public", ProjectID: project.ID, FileName: "readme.md", FullPath: "readme.md", CurrentVersion: 1},
+	}
+	for _, record := range records {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatalf("写入测试数据失败: %v", err)
+		}
+	}
+
+	ctx := context.Background()
+	canRead := func(fileID string) bool {
+		t.Helper()
+		allowed, err := svc.CheckFilePermission(ctx, fileID, "editor", ActionRead)
+		if err != nil {
+			t.Fatalf("检查 %s 的权限失败: %v", fileID, err)
+		}
+		return allowed
+	}
+	for _, id := range []string{"salary", "nested", "public"} {
+		if !canRead(id) {
+			t.Fatalf("拒绝前编辑者应能读取 %s", id)
+		}
+	}
+
+	if err := svc.DenyFileAccess(ctx, "salary", "editor", "admin"); err != nil {
+		t.Fatalf("拒绝访问文件失败: %v", err)
+	}
+	if err := svc.DenyFileAccess(ctx, "folder", "editor", "admin"); err != nil {
+		t.Fatalf("拒绝访问文件夹失败: %v", err)
+	}
+	if canRead("salary") {
+		t.Fatalf("被拒绝后编辑者仍能读取文件")
+	}
+	if canRead("nested") {
+		t.Fatalf("文件夹被拒绝后编辑者仍能读取其下的文件")
+	}
+	if !canRead("public") {
+		t.Fatalf("拒绝不应影响其他文件")
+	}
+	if allowed, _ := svc.CheckFilePermission(ctx, "salary", "editor", ActionUpdate); allowed {
+		t.Fatalf("被拒绝后编辑者仍能修改文件")
+	}
+
+	if err := svc.RemoveFileDeny(ctx, "salary", "editor"); err != nil {
+		t.Fatalf("移除访问拒绝失败: %v", err)
+	}
+	if !canRead("salary") {
+		t.Fatalf("移除拒绝后编辑者应恢复读取权限")
+	}
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"oss-backend/internal/model/dto"
+)
+
+// 单次返回的重复文件组上限
+const maxDuplicateGroups = 200
+
+// FindDuplicates 查找项目内内容相同的未删除文件，按可回收空间倒序分组返回
+func (s *fileService) FindDuplicates(ctx context.Context, projectID, userID string) (*dto.DuplicatesResponse, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, errors.New("项目不存在")
+	}
+
+	// 1. 分组查询重复的哈希
+	hashes, err := s.fileRepo.FindDuplicateHashes(ctx, projectID, maxDuplicateGroups)
+	if err != nil {
+		return nil, fmt.Errorf("查询重复文件失败: %w", err)
+	}
+
+	response := &dto.DuplicatesResponse{Groups: make([]dto.DuplicateGroup, 0, len(hashes))}
+	if len(hashes) == 0 {
+		return response, nil
+	}
+
+	// 2. 一次取出这些哈希对应的文件，按哈希归组
+	hashList := make([]string, 0, len(hashes))
+	for _, h := range hashes {
+		hashList = append(hashList, h.FileHash)
+	}
+	files, err := s.fileRepo.ListByHash(ctx, projectID, hashList)
+	if err != nil {
+		return nil, fmt.Errorf("获取重复文件失败: %w", err)
+	}
+	byHash := make(map[string][]dto.DuplicateFileItem, len(hashes))
+	for _, f := range files {
+		byHash[f.FileHash] = append(byHash[f.FileHash], dto.DuplicateFileItem{
+			ID:         f.ID,
+			FileName:   f.FileName,
+			FullPath:   f.FullPath,
+			FileSize:   f.FileSize,
+			UploaderID: f.UploaderID,
+			CreatedAt:  f.CreatedAt,
+		})
+	}
+
+	// 3. 保持分组查询的排序
+	for _, h := range hashes {
+		items := byHash[h.FileHash]
+		if len(items) < 2 {
+			continue
+		}
+		reclaimable := int64(len(items)-1) * h.FileSize
+		response.Groups = append(response.Groups, dto.DuplicateGroup{
+			FileHash:        h.FileHash,
+			FileSize:        h.FileSize,
+			Count:           len(items),
+			ReclaimableSize: reclaimable,
+			Files:           items,
+		})
+		response.TotalReclaimable += reclaimable
+	}
+
+	return response, nil
+}
+
+// ResolveDuplicates 保留指定文件，删除（软删除）同项目内与其内容相同的其他文件
+func (s *fileService) ResolveDuplicates(ctx context.Context, projectID, keepFileID, userID string) (*dto.ResolveDuplicatesResponse, error) {
+	keep, err := s.fileRepo.GetByID(ctx, keepFileID)
+	if err != nil {
+		return nil, err
+	}
+	if keep == nil || keep.IsDeleted {
+		return nil, errors.New("保留的文件不存在")
+	}
+	if keep.ProjectID != projectID {
+		return nil, errors.New("保留的文件不属于该项目")
+	}
+	if keep.IsFolder || keep.FileHash == "" {
+		return nil, errors.New("保留的对象不是文件")
+	}
+	if err := s.checkFileProjectWritable(ctx, projectID); err != nil {
+		return nil, err
+	}
+
+	files, err := s.fileRepo.ListByHash(ctx, projectID, []string{keep.FileHash})
+	if err != nil {
+		return nil, fmt.Errorf("获取重复文件失败: %w", err)
+	}
+
+	response := &dto.ResolveDuplicatesResponse{KeptFileID: keep.ID, DeletedIDs: []string{}}
+	for _, f := range files {
+		if f.ID == keep.ID {
+			continue
+		}
+		if err := s.DeleteFile(ctx, f.ID, userID, false); err != nil {
+			return response, fmt.Errorf("删除文件 %s 失败: %w", f.FullPath, err)
+		}
+		response.DeletedIDs = append(response.DeletedIDs, f.ID)
+		response.FreedSize += f.FileSize
+	}
+
+	return response, nil
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"oss-backend/internal/repository"
+)
+
+// TestFindDuplicates 相同内容上传到多个路径后报告为一组重复文件，内容不同的文件不在结果中
+func TestFindDuplicates(t *testing.T) {
+	ctx := context.Background()
+	project := newTestProject()
+	svc, _, db := newTestFileService(t, nil, project)
+	svc.fileRepo = &testFileRepo{FileRepository: repository.NewFileRepository(db), db: db}
+
+	const content = "same content in several places"
+	uploads := []struct{ name, path, content string }{
+		{"report.txt", "", content},
+		{"report-copy.txt", "", content},
+		{"report.txt", "archive/", content},
+		{"other.txt", "", "different content"},
+	}
+	for _, u := range uploads {
+		if _, err := svc.Upload(ctx, project.ID, "user-1", newFileHeader(t, u.name, u.content), u.path, ""); err != nil {
+			t.Fatalf("上传 %s%s 失败: %v", u.path, u.name, err)
+		}
+	}
+
+	result, err := svc.FindDuplicates(ctx, project.ID, "user-1")
+	if err != nil {
+		t.Fatalf("查找重复文件失败: %v", err)
+	}
+	if len(result.Groups) != 1 {
+		t.Fatalf("找到 %d 组重复文件，应为1组", len(result.Groups))
+	}
+	group := result.Groups[0]
+	size := int64(len(content))
+	if group.Count != 3 || len(group.Files) != 3 || group.FileSize != size {
+		t.Fatalf("重复文件组为 %d 个、大小 %d，应为3个、大小 %d", group.Count, group.FileSize, size)
+	}
+	if group.ReclaimableSize != 2*size || result.TotalReclaimable != 2*size {
+		t.Fatalf("可回收空间为 %d/%d，应为 %d", group.ReclaimableSize, result.TotalReclaimable, 2*size)
+	}
+	paths := make(map[string]bool)
+	for _, f := range group.Files {
+		paths[f.FullPath] = true
+	}
+	for _, want := range []string{"report.txt", "report-copy.txt", "archive/report.txt"} {
+		if !paths[want] {
+			t.Errorf("重复文件组中缺少 %s，实际为 %v", want, paths)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/pkg/encryption"
+	"oss-backend/pkg/minio"
+)
+
+// ErrEncryptionNotConfigured 未配置加密主密钥
+var ErrEncryptionNotConfigured = errors.New("未配置加密主密钥")
+
+// ErrEncryptedDirectAccess 加密存储的文件不能由客户端直接读写存储
+var ErrEncryptedDirectAccess = errors.New("该文件加密存储，不支持直接访问存储")
+
+// encryptionMasterKey 读取加密主密钥，为 base64 编码的32字节
+// 优先读取 encryption.master_key_file 指向的文件（如由密钥管理服务代理或容器密钥挂载），其次读取 encryption.master_key；
+// 主密钥只来自配置，不写入数据库
+func encryptionMasterKey() ([]byte, error) {
+	encoded := viper.GetString("encryption.master_key")
+	if path := viper.GetString("encryption.master_key_file"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取加密主密钥文件失败: %w", err)
+		}
+		encoded = string(data)
+	}
+	encoded = strings.TrimSpace(encoded)
+	if encoded == "" {
+		return nil, ErrEncryptionNotConfigured
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != encryption.KeySize {
+		return nil, fmt.Errorf("%w: 主密钥应为 base64 编码的%d字节", ErrEncryptionNotConfigured, encryption.KeySize)
+	}
+	return key, nil
+}
+
+// EncryptionConfigured 判断是否已配置有效的加密主密钥
+func EncryptionConfigured() error {
+	_, err := encryptionMasterKey()
+	return err
+}
+
+// groupEncryptionKey 由主密钥派生群组的数据密钥，不同群组的对象使用不同的密钥
+func groupEncryptionKey(groupID string) ([]byte, error) {
+	master, err := encryptionMasterKey()
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, master)
+	mac.Write([]byte("oss-backend/group/" + groupID))
+	return mac.Sum(nil), nil
+}
+
+// sealForGroup 群组开启加密存储时加密上传内容，返回写入存储的内容、对象大小和加密信息；未开启时原样返回
+func sealForGroup(group *entity.Group, reader io.Reader, size int64) (io.Reader, int64, entity.ObjectEncryption, error) {
+	if !group.EncryptionEnabled {
+		return reader, size, entity.ObjectEncryption{}, nil
+	}
+	key, err := groupEncryptionKey(group.ID)
+	if err != nil {
+		return nil, 0, entity.ObjectEncryption{}, err
+	}
+	prefix, err := encryption.NewNoncePrefix()
+	if err != nil {
+		return nil, 0, entity.ObjectEncryption{}, err
+	}
+	sealed, err := encryption.NewEncryptReader(reader, key, prefix)
+	if err != nil {
+		return nil, 0, entity.ObjectEncryption{}, err
+	}
+	return sealed, encryption.EncryptedSize(size), entity.ObjectEncryption{
+		EncryptionVersion: encryption.Version,
+		EncryptionNonce:   hex.EncodeToString(prefix),
+	}, nil
+}
+
+// storedObjectSize 文件当前对象在存储中的大小，加密存储时比文件大小多出每个分块的认证标签
+func storedObjectSize(file *entity.File) int64 {
+	if file.Encrypted() {
+		return encryption.EncryptedSize(file.FileSize)
+	}
+	return file.FileSize
+}
+
+// openSealed 按对象的加密信息解密读取的内容，未加密时原样返回；reader 支持随机读取时解密结果同样支持
+// 返回错误时已关闭 reader
+func openSealed(reader io.ReadCloser, groupID string, enc entity.ObjectEncryption) (io.ReadCloser, error) {
+	if !enc.Encrypted() {
+		return reader, nil
+	}
+	plain, err := newSealedReader(reader, groupID, enc)
+	if err != nil {
+		reader.Close()
+		return nil, err
+	}
+	return plain, nil
+}
+
+// newSealedReader 创建解密 reader 的 ReadCloser
+func newSealedReader(reader io.ReadCloser, groupID string, enc entity.ObjectEncryption) (io.ReadCloser, error) {
+	if enc.EncryptionVersion != encryption.Version {
+		return nil, fmt.Errorf("不支持的加密格式版本 %d", enc.EncryptionVersion)
+	}
+	prefix, err := hex.DecodeString(enc.EncryptionNonce)
+	if err != nil {
+		return nil, fmt.Errorf("加密信息无效: %w", err)
+	}
+	key, err := groupEncryptionKey(groupID)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := encryption.NewDecryptReader(reader, key, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if seeker, ok := plain.(io.ReadSeeker); ok {
+		return &sealedReadSeekCloser{ReadSeeker: seeker, Closer: reader}, nil
+	}
+	return &sealedReadCloser{Reader: plain, Closer: reader}, nil
+}
+
+// openObject 读取对象并按加密信息解密
+func (s *fileService) openObject(ctx context.Context, client *minio.Client, bucketName, objectName, groupID string, enc entity.ObjectEncryption) (io.ReadCloser, error) {
+	reader, _, err := client.DownloadFile(ctx, bucketName, objectName)
+	if err != nil {
+		return nil, err
+	}
+	return openSealed(reader, groupID, enc)
+}
+
+// sealedReadCloser 解密后的对象内容，关闭时关闭原对象
+type sealedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// sealedReadSeekCloser 可随机读取的解密后的对象内容
+type sealedReadSeekCloser struct {
+	io.ReadSeeker
+	io.Closer
+}
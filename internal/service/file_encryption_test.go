@@ -0,0 +1,99 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/pkg/encryption"
+)
+
+// sealForTest 按群组设置加密内容，返回写入存储的内容与加密信息
+func sealForTest(t *testing.T, group *entity.Group, plain []byte) ([]byte, entity.ObjectEncryption) {
+	t.Helper()
+	reader, size, enc, err := sealForGroup(group, bytes.NewReader(plain), int64(len(plain)))
+	if err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+	sealed, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(sealed)) != size {
+		t.Fatalf("对象大小为 %d，返回的大小为 %d", len(sealed), size)
+	}
+	return sealed, enc
+}
+
+func TestSealForGroupRoundTrip(t *testing.T) {
+	setTestMasterKey(t)
+	group := &entity.Group{ID: "group-1", EncryptionEnabled: true}
+	plain := bytes.Repeat([]byte("object content "), 10000)
+
+	sealed, enc := sealForTest(t, group, plain)
+	if !enc.Encrypted() || enc.EncryptionVersion != encryption.Version {
+		t.Fatalf("加密信息错误: %+v", enc)
+	}
+	if file := (&entity.File{FileSize: int64(len(plain)), ObjectEncryption: enc}); storedObjectSize(file) != int64(len(sealed)) {
+		t.Fatalf("storedObjectSize 为 %d，对象大小为 %d", storedObjectSize(file), len(sealed))
+	}
+
+	reader, err := openSealed(io.NopCloser(bytes.NewReader(sealed)), group.ID, enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(reader)
+	if err != nil || !bytes.Equal(got, plain) {
+		t.Fatalf("解密结果不一致, %v", err)
+	}
+
+	// 其他群组的密钥不能解密
+	reader, err = openSealed(io.NopCloser(bytes.NewReader(sealed)), "group-2", enc)
+	if err == nil {
+		_, err = io.ReadAll(reader)
+	}
+	if !errors.Is(err, encryption.ErrCorrupted) {
+		t.Fatalf("使用其他群组的密钥应返回 ErrCorrupted，实际为 %v", err)
+	}
+}
+
+func TestSealForGroupUniqueNonce(t *testing.T) {
+	setTestMasterKey(t)
+	group := &entity.Group{ID: "group-1", EncryptionEnabled: true}
+	plain := []byte("same content")
+
+	a, encA := sealForTest(t, group, plain)
+	b, encB := sealForTest(t, group, plain)
+	if encA.EncryptionNonce == encB.EncryptionNonce {
+		t.Fatalf("两次加密使用了相同的 nonce 前缀")
+	}
+	if bytes.Equal(a, b) {
+		t.Fatalf("相同内容两次加密的密文相同")
+	}
+}
+
+func TestSealForGroupDisabled(t *testing.T) {
+	group := &entity.Group{ID: "group-1"}
+	plain := []byte("plain content")
+
+	sealed, enc := sealForTest(t, group, plain)
+	if enc.Encrypted() || !bytes.Equal(sealed, plain) {
+		t.Fatalf("未开启加密的群组应原样写入")
+	}
+	reader, err := openSealed(io.NopCloser(bytes.NewReader(sealed)), group.ID, enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := io.ReadAll(reader); !bytes.Equal(got, plain) {
+		t.Fatalf("未加密的对象应原样读取")
+	}
+}
+
+func TestSealForGroupRequiresMasterKey(t *testing.T) {
+	group := &entity.Group{ID: "group-1", EncryptionEnabled: true}
+	if _, _, _, err := sealForGroup(group, bytes.NewReader(nil), 0); !errors.Is(err, ErrEncryptionNotConfigured) {
+		t.Fatalf("未配置主密钥时应返回 ErrEncryptionNotConfigured，实际为 %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"oss-backend/internal/model/dto"
+	"oss-backend/internal/model/entity"
+	"oss-backend/pkg/minio"
+)
+
+// 下载清单默认的分块大小
+const defaultManifestChunkSize = 8 << 20
+
+// ErrManifestUnsupported 文件不能按清单分块下载
+var ErrManifestUnsupported = errors.New("文件不支持分块校验下载")
+
+// manifestChunkSize 获取下载清单的分块大小
+func manifestChunkSize() int64 {
+	chunkSize := viper.GetInt64("download.manifest_chunk_size")
+	if chunkSize <= 0 {
+		chunkSize = defaultManifestChunkSize
+	}
+	return chunkSize
+}
+
+// GetDownloadManifest 获取文件的分块下载清单，客户端可按 Range 并行下载各分块并分别校验，只重试校验失败的分块
+// 分块校验和缓存在当前版本的记录中，首次请求时读取一次对象计算；需要水印的文件每次下载内容不同，不提供清单
+func (s *fileService) GetDownloadManifest(ctx context.Context, fileID string) (*dto.DownloadManifestResponse, error) {
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file == nil || file.IsDeleted {
+		return nil, errors.New("文件不存在")
+	}
+	if file.IsFolder {
+		return nil, fmt.Errorf("%w: 文件夹请使用打包下载", ErrManifestUnsupported)
+	}
+	if file.WatermarkRequired {
+		return nil, fmt.Errorf("%w: 文件下载时需要添加水印", ErrManifestUnsupported)
+	}
+
+	chunkSize := manifestChunkSize()
+	checksums, err := s.cachedChunkChecksums(ctx, file, chunkSize)
+	if err != nil {
+		return nil, err
+	}
+	if checksums == nil {
+		if checksums, err = s.computeChunkChecksums(ctx, file, chunkSize); err != nil {
+			return nil, err
+		}
+		if err := s.fileRepo.UpdateVersionChunkChecksums(ctx, file.ID, file.CurrentVersion, chunkSize, strings.Join(checksums, ",")); err != nil {
+			log.Printf("缓存文件 %s 的分块校验和失败: %v", file.ID, err)
+		}
+	}
+
+	chunks := make([]dto.DownloadChunk, 0, len(checksums))
+	for i, checksum := range checksums {
+		offset := int64(i) * chunkSize
+		chunks = append(chunks, dto.DownloadChunk{
+			Index:    i,
+			Offset:   offset,
+			Size:     min(chunkSize, file.FileSize-offset),
+			Checksum: checksum,
+		})
+	}
+
+	return &dto.DownloadManifestResponse{
+		FileID:      file.ID,
+		FileName:    file.FileName,
+		FileSize:    file.FileSize,
+		FileHash:    file.FileHash,
+		Version:     file.CurrentVersion,
+		Algorithm:   "sha256",
+		ChunkSize:   chunkSize,
+		ChunkCount:  len(chunks),
+		DownloadURL: fmt.Sprintf("/api/oss/file/download/%s", file.ID),
+		Chunks:      chunks,
+	}, nil
+}
+
+// cachedChunkChecksums 读取当前版本缓存的分块校验和，版本内容或分块大小与文件不一致时返回nil
+func (s *fileService) cachedChunkChecksums(ctx context.Context, file *entity.File, chunkSize int64) ([]string, error) {
+	version, err := s.fileRepo.GetVersionByID(ctx, file.ID, file.CurrentVersion)
+	if err != nil {
+		return nil, fmt.Errorf("获取文件版本失败: %w", err)
+	}
+	if version == nil || version.ChunkSize != chunkSize || version.FileHash != file.FileHash || version.FileSize != file.FileSize {
+		return nil, nil
+	}
+	if file.FileSize == 0 {
+		return []string{}, nil
+	}
+	checksums := strings.Split(version.ChunkChecksums, ",")
+	if int64(len(checksums)) != (file.FileSize+chunkSize-1)/chunkSize {
+		return nil, nil
+	}
+	return checksums, nil
+}
+
+// computeChunkChecksums 读取文件的当前对象，计算各分块的SHA-256
+// 同时校验整个文件的哈希和大小，对象在读取期间被覆盖时返回错误，避免缓存与文件记录不一致的结果
+func (s *fileService) computeChunkChecksums(ctx context.Context, file *entity.File, chunkSize int64) ([]string, error) {
+	project, err := s.projectRepo.GetByID(ctx, file.ProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("获取项目信息失败: %w", err)
+	}
+	if project == nil {
+		return nil, errors.New("项目不存在")
+	}
+	client, err := s.fileStorage(file)
+	if err != nil {
+		return nil, err
+	}
+	objectName := minio.GetObjectName(file.ProjectID, file.FilePath, file.FileName)
+	reader, err := s.openObject(ctx, client, s.sanitizeBucketName(project.Group.GroupKey), objectName, project.GroupID, file.ObjectEncryption)
+	if err != nil {
+		return nil, fmt.Errorf("读取文件失败: %w", err)
+	}
+	defer reader.Close()
+
+	whole := sha256.New()
+	source := io.TeeReader(reader, whole)
+	checksums := []string{}
+	var size int64
+	for {
+		chunk := sha256.New()
+		n, err := io.CopyN(chunk, source, chunkSize)
+		if n > 0 {
+			checksums = append(checksums, hex.EncodeToString(chunk.Sum(nil)))
+			size += n
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取文件失败: %w", err)
+		}
+	}
+
+	if size != file.FileSize || (file.FileHash != "" && hex.EncodeToString(whole.Sum(nil)) != file.FileHash) {
+		return nil, errors.New("文件内容与记录不一致，可能正在被更新，请稍后重试")
+	}
+	return checksums, nil
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+	"oss-backend/pkg/minio"
+)
+
+// TestDownloadManifestChunks 按清单并行读取的各分块都能通过校验并拼回原文件；校验和缓存后不再读取对象，被篡改的分块校验失败
+func TestDownloadManifestChunks(t *testing.T) {
+	const chunkSize = 1024
+	viper.Set("download.manifest_chunk_size", chunkSize)
+	t.Cleanup(func() { viper.Set("download.manifest_chunk_size", 0) })
+
+	ctx := context.Background()
+	project := newTestProject()
+	svc, store, db := newTestFileService(t, nil, project)
+	svc.fileRepo = repository.NewFileRepository(db)
+
+	content := make([]byte, chunkSize*2+300)
+	rand.Read(content)
+	uploaded, err := svc.Upload(ctx, project.ID, "user-1", newFileHeader(t, "data.bin", string(content)), "", "")
+	if err != nil {
+		t.Fatalf("上传失败: %v", err)
+	}
+
+	manifest, err := svc.GetDownloadManifest(ctx, uploaded.ID)
+	if err != nil {
+		t.Fatalf("获取下载清单失败: %v", err)
+	}
+	if manifest.FileSize != int64(len(content)) || manifest.ChunkSize != chunkSize || manifest.ChunkCount != 3 || len(manifest.Chunks) != 3 {
+		t.Fatalf("清单为 %d 字节、分块 %d 字节、%d 块，应为 %d 字节、%d 字节、3块", manifest.FileSize, manifest.ChunkSize, manifest.ChunkCount, len(content), chunkSize)
+	}
+	whole := sha256.Sum256(content)
+	if manifest.FileHash != hex.EncodeToString(whole[:]) {
+		t.Fatalf("清单中的文件哈希与内容不一致")
+	}
+
+	// 模拟客户端按 Range 并行下载各分块
+	readChunk := func(offset, size int64) []byte {
+		reader, _, err := svc.Download(ctx, uploaded.ID, "user-1")
+		if err != nil {
+			t.Errorf("下载失败: %v", err)
+			return nil
+		}
+		defer reader.Close()
+		if _, err := io.CopyN(io.Discard, reader, offset); err != nil {
+			t.Errorf("跳过 %d 字节失败: %v", offset, err)
+			return nil
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			t.Errorf("读取分块失败: %v", err)
+			return nil
+		}
+		return data
+	}
+	parts := make([][]byte, len(manifest.Chunks))
+	var wg sync.WaitGroup
+	for i, chunk := range manifest.Chunks {
+		wg.Add(1)
+		go func(i int, offset, size int64) {
+			defer wg.Done()
+			parts[i] = readChunk(offset, size)
+		}(i, chunk.Offset, chunk.Size)
+	}
+	wg.Wait()
+	if t.Failed() {
+		t.FailNow()
+	}
+
+	verify := func(data []byte, checksum string) bool {
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:]) == checksum
+	}
+	var assembled bytes.Buffer
+	for i, chunk := range manifest.Chunks {
+		if chunk.Index != i || int64(len(parts[i])) != chunk.Size {
+			t.Fatalf("第 %d 块的序号为 %d、长度为 %d，应为 %d 字节", i, chunk.Index, len(parts[i]), chunk.Size)
+		}
+		if !verify(parts[i], chunk.Checksum) {
+			t.Fatalf("第 %d 块没有通过校验", i)
+		}
+		assembled.Write(parts[i])
+	}
+	if !bytes.Equal(assembled.Bytes(), content) {
+		t.Fatalf("拼接后的内容与原文件不同")
+	}
+
+	// 被篡改的分块不能通过校验，只需重试该分块
+	tampered := append([]byte(nil), parts[1]...)
+	tampered[0] ^= 0xff
+	if verify(tampered, manifest.Chunks[1].Checksum) {
+		t.Fatalf("被篡改的分块通过了校验")
+	}
+
+	// 校验和已缓存在版本记录中，再次获取清单不读取对象
+	var version entity.FileVersion
+	if err := db.First(&version, "file_id = ? AND version = ?", uploaded.ID, uploaded.CurrentVersion).Error; err != nil {
+		t.Fatal(err)
+	}
+	if version.ChunkSize != chunkSize || version.ChunkChecksums == "" {
+		t.Fatalf("版本记录没有缓存分块校验和")
+	}
+	store.mu.Lock()
+	delete(store.objects, groupBucketName(project.Group.GroupKey)+"/"+minio.GetObjectName(project.ID, "", "data.bin"))
+	store.mu.Unlock()
+	cached, err := svc.GetDownloadManifest(ctx, uploaded.ID)
+	if err != nil {
+		t.Fatalf("使用缓存获取清单失败: %v", err)
+	}
+	for i := range cached.Chunks {
+		if cached.Chunks[i] != manifest.Chunks[i] {
+			t.Fatalf("缓存的第 %d 块为 %+v，应为 %+v", i, cached.Chunks[i], manifest.Chunks[i])
+		}
+	}
+}
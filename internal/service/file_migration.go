@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"gorm.io/gorm"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/utils"
+	"oss-backend/pkg/minio"
+)
+
+// ErrInvalidMigration 文件无法迁移到目标存储后端
+var ErrInvalidMigration = errors.New("无法迁移该文件")
+
+// ErrMigrationIntegrity 迁移后的对象内容校验失败
+var ErrMigrationIntegrity = errors.New("迁移后的对象内容校验失败")
+
+// ErrMigrationConflict 文件在迁移期间被修改
+var ErrMigrationConflict = errors.New("文件在迁移期间已被修改")
+
+// MigrateFileStorage 将文件及其历史版本对象迁移到另一个存储后端
+// 对象复制后重新读取校验内容哈希，全部通过且文件在迁移期间未被修改时才切换文件记录并删除源对象；
+// 任一步骤失败时删除已复制到目标后端的对象，文件仍从原后端读取
+func (s *fileService) MigrateFileStorage(ctx context.Context, fileID, targetBackend, adminID string) error {
+	// 1. 校验目标后端与文件
+	if err := CheckStorageBackend(targetBackend); err != nil {
+		return err
+	}
+	targetBackend = normalizeStorageBackend(targetBackend)
+
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return err
+	}
+	if file == nil || file.IsDeleted {
+		return errors.New("文件不存在")
+	}
+	if file.IsFolder {
+		return fmt.Errorf("%w: 文件夹没有存储对象", ErrInvalidMigration)
+	}
+	if file.StorageBackend == targetBackend {
+		return fmt.Errorf("%w: 文件已位于该存储后端", ErrInvalidMigration)
+	}
+
+	project, err := s.projectRepo.GetByID(ctx, file.ProjectID)
+	if err != nil {
+		return fmt.Errorf("获取项目信息失败: %w", err)
+	}
+	if project == nil {
+		return errors.New("项目不存在")
+	}
+	bucketName := s.sanitizeBucketName(project.Group.GroupKey)
+
+	source, err := s.fileStorage(file)
+	if err != nil {
+		return err
+	}
+	target, err := s.storageClient(targetBackend)
+	if err != nil {
+		return err
+	}
+	if err := target.CreateBucketIfNotExists(ctx, bucketName); err != nil {
+		return fmt.Errorf("创建目标存储桶失败: %w", err)
+	}
+
+	// 2. 需要迁移的对象：当前对象及该文件的历史版本对象
+	liveObject := minio.GetObjectName(file.ProjectID, file.FilePath, file.FileName)
+	objects := []string{liveObject}
+	for object := range source.ListObjects(ctx, bucketName, minio.GetVersionObjectPrefix(file.ID), true) {
+		if object.Err != nil {
+			return fmt.Errorf("列出历史版本对象失败: %w", object.Err)
+		}
+		objects = append(objects, object.Key)
+	}
+
+	// 3. 逐个复制并校验，失败时清理目标后端中已复制的对象
+	var copied []string
+	cleanup := func() {
+		for _, object := range copied {
+			if err := target.RemoveObject(ctx, bucketName, object); err != nil {
+				log.Printf("迁移失败后删除目标对象 %s 失败: %v", object, err)
+			}
+		}
+	}
+	for i, object := range objects {
+		ReportJobProgress(ctx, i, len(objects))
+		if err := ctx.Err(); err != nil {
+			cleanup()
+			return err
+		}
+
+		copied = append(copied, object)
+		hash, err := copyStorageObject(ctx, source, target, bucketName, object)
+		if err != nil {
+			cleanup()
+			return fmt.Errorf("迁移对象 %s 失败: %w", object, err)
+		}
+		if object == liveObject && file.FileHash != "" {
+			// 加密存储的对象需解密后与文件记录的明文哈希比较
+			if file.Encrypted() {
+				hash, err = sealedObjectHash(ctx, target, bucketName, object, project.GroupID, file.ObjectEncryption)
+				if err != nil {
+					cleanup()
+					return fmt.Errorf("%w: 解密目标对象失败: %v", ErrMigrationIntegrity, err)
+				}
+			}
+			if hash != file.FileHash {
+				cleanup()
+				return fmt.Errorf("%w: 当前对象的哈希与文件记录不一致", ErrMigrationIntegrity)
+			}
+		}
+	}
+	ReportJobProgress(ctx, len(objects), len(objects))
+
+	// 4. 文件自读取后未被修改时切换存储后端，之后的读写都使用目标后端
+	if err := s.fileRepo.UpdateStorageBackend(ctx, file, targetBackend); err != nil {
+		cleanup()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrMigrationConflict
+		}
+		return fmt.Errorf("更新文件记录失败: %w", err)
+	}
+
+	// 5. 删除源对象，失败不影响迁移结果
+	for _, object := range objects {
+		if err := source.RemoveObject(ctx, bucketName, object); err != nil {
+			log.Printf("删除迁移前的源对象 %s 失败: %v", object, err)
+		}
+	}
+
+	file.StorageBackend = targetBackend
+	s.recordAudit(ctx, adminID, entity.OperationMigrate, project, file)
+	log.Printf("文件 %s 已迁移到存储后端 %s", file.ID, displayStorageBackend(targetBackend))
+
+	return nil
+}
+
+// copyStorageObject 将对象从源存储复制到目标存储，复制后重新读取目标对象校验内容，返回内容的 SHA256
+func copyStorageObject(ctx context.Context, source, target utils.MinioClient, bucketName, objectName string) (string, error) {
+	info, err := source.StatObject(ctx, bucketName, objectName, nil)
+	if err != nil {
+		return "", fmt.Errorf("读取源对象信息失败: %w", err)
+	}
+	reader, err := source.GetObject(ctx, bucketName, objectName, nil)
+	if err != nil {
+		return "", fmt.Errorf("读取源对象失败: %w", err)
+	}
+	defer reader.Close()
+
+	hash := sha256.New()
+	if err := target.PutObject(ctx, bucketName, objectName, io.TeeReader(reader, hash), info.Size, info.ContentType); err != nil {
+		return "", fmt.Errorf("写入目标对象失败: %w", err)
+	}
+	sourceHash := hex.EncodeToString(hash.Sum(nil))
+
+	copiedHash, err := storedObjectHash(ctx, target, bucketName, objectName)
+	if err != nil {
+		return "", fmt.Errorf("读取目标对象失败: %w", err)
+	}
+	if copiedHash != sourceHash {
+		return "", fmt.Errorf("%w: %s", ErrMigrationIntegrity, objectName)
+	}
+	return sourceHash, nil
+}
+
+// storedObjectHash 读取存储中的对象并计算 SHA256
+func storedObjectHash(ctx context.Context, client utils.MinioClient, bucketName, objectName string) (string, error) {
+	reader, err := client.GetObject(ctx, bucketName, objectName, nil)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	return calculateFileHash(reader)
+}
+
+// sealedObjectHash 解密存储中的对象并计算明文的 SHA256
+func sealedObjectHash(ctx context.Context, client utils.MinioClient, bucketName, objectName, groupID string, enc entity.ObjectEncryption) (string, error) {
+	reader, err := client.GetObject(ctx, bucketName, objectName, nil)
+	if err != nil {
+		return "", err
+	}
+	plain, err := openSealed(reader, groupID, enc)
+	if err != nil {
+		return "", err
+	}
+	defer plain.Close()
+	return calculateFileHash(plain)
+}
+
+// displayStorageBackend 存储后端的显示名称，默认后端显示为 default
+func displayStorageBackend(name string) string {
+	if name == "" {
+		return DefaultStorageBackend
+	}
+	return name
+}
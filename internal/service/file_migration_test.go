@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+	"oss-backend/pkg/minio"
+)
+
+// TestMigrateFileStorage 文件及其历史版本迁移到另一个存储后端并校验内容，迁移后从新后端下载；
+// 源对象内容与文件记录不一致时迁移失败，目标后端不留下对象
+func TestMigrateFileStorage(t *testing.T) {
+	ctx := context.Background()
+	project := newTestProject()
+	svc, source, db := newTestFileService(t, nil, project)
+	svc.fileRepo = &testFileRepo{FileRepository: repository.NewFileRepository(db), db: db}
+	target, targetClient := newFakeObjectStore(t)
+	setTestStorageBackends(t, map[string]*minio.Client{"archive": targetClient})
+	bucket := groupBucketName(project.Group.GroupKey)
+
+	if _, err := svc.Upload(ctx, project.ID, "user-1", newFileHeader(t, "report.txt", "version 1"), "", ""); err != nil {
+		t.Fatal(err)
+	}
+	file, err := svc.Upload(ctx, project.ID, "user-1", newFileHeader(t, "report.txt", "version 2"), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	liveObject := minio.GetObjectName(project.ID, "", "report.txt")
+	versionObject := minio.GetVersionObjectName(file.ID, 1)
+
+	if err := svc.MigrateFileStorage(ctx, file.ID, "archive", "admin-1"); err != nil {
+		t.Fatalf("迁移失败: %v", err)
+	}
+	for object, want := range map[string]string{liveObject: "version 2", versionObject: "version 1"} {
+		if data, ok := target.object(bucket, object); !ok || string(data) != want {
+			t.Errorf("目标后端中 %s 的内容为 %q，应为 %q", object, data, want)
+		}
+		if _, ok := source.object(bucket, object); ok {
+			t.Errorf("迁移后源后端仍有对象 %s", object)
+		}
+	}
+	var stored entity.File
+	if err := db.First(&stored, "id = ?", file.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.StorageBackend != "archive" {
+		t.Fatalf("文件的存储后端为 %q，应为 archive", stored.StorageBackend)
+	}
+	reader, _, err := svc.Download(ctx, file.ID, "user-1")
+	if err != nil {
+		t.Fatalf("迁移后下载失败: %v", err)
+	}
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(data) != "version 2" {
+		t.Fatalf("迁移后下载内容为 %q", data)
+	}
+	if err := svc.MigrateFileStorage(ctx, file.ID, "archive", "admin-1"); !errors.Is(err, ErrInvalidMigration) {
+		t.Fatalf("迁移到当前所在后端返回 %v，应返回 ErrInvalidMigration", err)
+	}
+
+	// 源对象被损坏时校验失败，文件仍留在原后端
+	other, err := svc.Upload(ctx, project.ID, "user-1", newFileHeader(t, "notes.txt", "original"), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherObject := minio.GetObjectName(project.ID, "", "notes.txt")
+	source.putObject(bucket, otherObject, []byte("corrupted"))
+	if err := svc.MigrateFileStorage(ctx, other.ID, "archive", "admin-1"); !errors.Is(err, ErrMigrationIntegrity) {
+		t.Fatalf("源对象损坏时迁移返回 %v，应返回 ErrMigrationIntegrity", err)
+	}
+	if _, ok := target.object(bucket, otherObject); ok {
+		t.Fatalf("校验失败后目标后端留下了对象")
+	}
+	if _, ok := source.object(bucket, otherObject); !ok {
+		t.Fatalf("校验失败后源对象被删除")
+	}
+	var unchanged entity.File
+	if err := db.First(&unchanged, "id = ?", other.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if unchanged.StorageBackend != "" {
+		t.Fatalf("校验失败后文件的存储后端变为 %q", unchanged.StorageBackend)
+	}
+}
@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+
+	"oss-backend/internal/model/dto"
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+	"oss-backend/pkg/minio"
+)
+
+// 批量移动时默认并发复制和删除对象的数量
+const defaultMoveConcurrency = 8
+
+// ErrInvalidMove 无法移动到目标位置
+var ErrInvalidMove = errors.New("无法移动到目标位置")
+
+// ErrMoveTargetForbidden 没有在目标目录下创建文件的权限
+var ErrMoveTargetForbidden = errors.New("没有目标目录的上传权限")
+
+// 单个文件不能移动的原因
+var (
+	errMoveForbidden = errors.New("没有修改该文件的权限")
+	errMoveConflict  = errors.New("目标目录下已存在同名文件")
+)
+
+// moveConcurrency 获取批量移动时并发处理对象的数量
+func moveConcurrency() int {
+	if n := viper.GetInt("storage.move_concurrency"); n > 0 {
+		return n
+	}
+	return defaultMoveConcurrency
+}
+
+// moveEntry 批量移动中待移动的一个文件
+type moveEntry struct {
+	file      *entity.File
+	client    *minio.Client
+	oldObject string
+	newObject string
+	result    *dto.FileMoveResult
+}
+
+// BatchMoveFiles 将同一项目中的多个文件移动到目标目录下，名称不变，返回每个文件的结果
+// 需要目标目录的上传权限；没有文件修改权限、目标目录下已有同名文件或对象复制失败的文件不移动，不影响其他文件；
+// 文件记录先在事务中更新以占用目标路径，对象随后在同一事务中并发复制到新位置，提交后再删除旧对象
+func (s *fileService) BatchMoveFiles(ctx context.Context, fileIDs []string, targetPath, userID string) (*dto.FileBatchMoveResponse, error) {
+	// 1. 获取文件，去掉重复的ID，所有文件须属于同一项目
+	results := make([]*dto.FileMoveResult, 0, len(fileIDs))
+	files := make(map[string]*entity.File, len(fileIDs))
+	var projectID string
+	for _, fileID := range fileIDs {
+		if _, ok := files[fileID]; ok {
+			continue
+		}
+		file, err := s.fileRepo.GetByID(ctx, fileID)
+		if err != nil {
+			return nil, fmt.Errorf("获取文件信息失败: %w", err)
+		}
+		files[fileID] = file
+		result := &dto.FileMoveResult{FileID: fileID}
+		results = append(results, result)
+		if file == nil || file.IsDeleted {
+			result.Status = dto.FileMoveStatusNotFound
+			result.Error = "文件不存在"
+			continue
+		}
+		if projectID == "" {
+			projectID = file.ProjectID
+		} else if file.ProjectID != projectID {
+			return nil, fmt.Errorf("%w: 批量移动的文件须属于同一项目", ErrInvalidMove)
+		}
+	}
+	if projectID == "" {
+		return nil, fmt.Errorf("%w: 文件不存在", ErrInvalidMove)
+	}
+
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("获取项目信息失败: %w", err)
+	}
+	if project == nil {
+		return nil, errors.New("项目不存在")
+	}
+	if err := checkProjectWritable(project); err != nil {
+		return nil, err
+	}
+	targetPath, err = s.resolveTargetFolder(ctx, project, targetPath, ErrInvalidMove)
+	if err != nil {
+		return nil, err
+	}
+	canCreate, err := s.checkPathPermission(ctx, project.ID, targetPath, userID, ActionCreate)
+	if err != nil {
+		return nil, err
+	}
+	if !canCreate {
+		return nil, ErrMoveTargetForbidden
+	}
+
+	// 2. 逐个检查权限与重名，同一批中名称相同的文件只移动第一个
+	bucketName := s.sanitizeBucketName(project.Group.GroupKey)
+	var entries []*moveEntry
+	names := make(map[string]bool, len(results))
+	for _, result := range results {
+		if result.Status != "" {
+			continue
+		}
+		file := files[result.FileID]
+		if file.FilePath == targetPath {
+			result.Status = dto.FileMoveStatusUnchanged
+			result.FullPath = file.FullPath
+			continue
+		}
+		if err := s.checkMovable(ctx, project, file, targetPath, userID, names); err != nil {
+			result.Error = err.Error()
+			switch {
+			case errors.Is(err, errMoveForbidden):
+				result.Status = dto.FileMoveStatusForbidden
+			case errors.Is(err, errMoveConflict):
+				result.Status = dto.FileMoveStatusConflict
+			default:
+				result.Status = dto.FileMoveStatusFailed
+			}
+			continue
+		}
+		client, err := s.fileStorage(file)
+		if err != nil {
+			result.Status = dto.FileMoveStatusFailed
+			result.Error = err.Error()
+			continue
+		}
+		entries = append(entries, &moveEntry{
+			file:      file,
+			client:    client,
+			oldObject: minio.GetObjectName(file.ProjectID, file.FilePath, file.FileName),
+			newObject: minio.GetObjectName(file.ProjectID, targetPath, file.FileName),
+			result:    result,
+		})
+	}
+
+	// 3. 事务中先更新文件记录占用目标路径，再并发复制对象到新位置；
+	// 目标路径已被并发创建的文件占用时记为冲突，复制失败的文件恢复原记录，其他文件照常移动。
+	// 事务提交前目标路径一直被本次移动占用，复制的对象不会覆盖其他操作写入的对象
+	now := time.Now()
+	var moved []*moveEntry
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		claimed, err := s.claimMoveTargets(tx, entries, targetPath, now)
+		if err != nil {
+			return err
+		}
+
+		s.forEachMoveEntry(claimed, func(entry *moveEntry) {
+			if err := ctx.Err(); err != nil {
+				entry.result.Status = dto.FileMoveStatusFailed
+				entry.result.Error = err.Error()
+				return
+			}
+			if err := entry.client.CopyObject(ctx, bucketName, entry.oldObject, entry.newObject, entry.file.MimeType); err != nil {
+				entry.result.Status = dto.FileMoveStatusFailed
+				entry.result.Error = fmt.Sprintf("复制存储对象失败: %v", err)
+			}
+		})
+
+		var failed []*moveEntry
+		for _, entry := range claimed {
+			if entry.result.Status == "" {
+				moved = append(moved, entry)
+			} else {
+				failed = append(failed, entry)
+			}
+		}
+		for _, entry := range failed {
+			if err := tx.Model(&entity.File{}).Where("id = ?", entry.file.ID).Updates(map[string]interface{}{
+				"file_path":  entry.file.FilePath,
+				"full_path":  entry.file.FullPath,
+				"updated_at": entry.file.UpdatedAt,
+			}).Error; err != nil {
+				// 事务回滚前删除已复制的对象，此时目标路径仍被本事务占用
+				s.removeMoveObjects(ctx, bucketName, moved, func(entry *moveEntry) string { return entry.newObject })
+				return fmt.Errorf("恢复文件记录 %s 失败: %w", entry.file.FullPath, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. 删除旧对象，失败只打印日志
+	s.removeMoveObjects(ctx, bucketName, moved, func(entry *moveEntry) string { return entry.oldObject })
+
+	response := &dto.FileBatchMoveResponse{
+		ProjectID:  project.ID,
+		TargetPath: targetPath,
+		Results:    make([]dto.FileMoveResult, 0, len(results)),
+	}
+	for _, entry := range moved {
+		entry.file.FilePath = targetPath
+		entry.file.FullPath = targetPath + entry.file.FileName
+		entry.file.UpdatedAt = now
+		entry.result.Status = dto.FileMoveStatusMoved
+		entry.result.FullPath = entry.file.FullPath
+		s.recordAudit(ctx, userID, entity.OperationMove, project, entry.file)
+	}
+	for _, result := range results {
+		switch result.Status {
+		case dto.FileMoveStatusMoved:
+			response.Moved++
+		case dto.FileMoveStatusUnchanged:
+		default:
+			response.Failed++
+		}
+		response.Results = append(response.Results, *result)
+	}
+
+	log.Printf("用户 %s 将 %d 个文件移动到项目 %s 的目录 /%s，未移动 %d 个", userID, response.Moved, project.ID, targetPath, response.Failed)
+	return response, nil
+}
+
+// checkMovable 检查文件能否移动到目标目录，names 记录本批中已占用的名称
+func (s *fileService) checkMovable(ctx context.Context, project *entity.Project, file *entity.File, targetPath, userID string, names map[string]bool) error {
+	if file.IsFolder {
+		return errors.New("暂不支持移动文件夹")
+	}
+
+	// 访问拒绝规则优先于角色授予的权限
+	denied, err := s.fileRepo.IsDenied(ctx, file, userID)
+	if err != nil {
+		return fmt.Errorf("检查访问拒绝规则失败: %w", err)
+	}
+	if denied {
+		return errMoveForbidden
+	}
+	allowed, err := s.checkPathPermission(ctx, project.ID, file.FullPath, userID, ActionUpdate)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return errMoveForbidden
+	}
+
+	name := file.FileName
+	if project.CaseInsensitivePaths {
+		name = strings.ToLower(name)
+	}
+	if names[name] {
+		return errMoveConflict
+	}
+	existing, err := s.findByPath(ctx, project, targetPath, file.FileName)
+	if err != nil {
+		return fmt.Errorf("检查文件路径失败: %w", err)
+	}
+	if existing != nil {
+		return errMoveConflict
+	}
+	names[name] = true
+	return nil
+}
+
+// claimMoveTargets 在事务中逐个将文件记录更新到目标目录，返回更新成功的条目
+// 每个文件在单独的保存点中更新，目标路径已被占用的文件记为冲突并回滚到保存点，不影响其他文件
+func (s *fileService) claimMoveTargets(tx *gorm.DB, entries []*moveEntry, targetPath string, now time.Time) ([]*moveEntry, error) {
+	claimed := make([]*moveEntry, 0, len(entries))
+	for _, entry := range entries {
+		err := tx.Transaction(func(tx *gorm.DB) error {
+			return tx.Model(&entity.File{}).Where("id = ?", entry.file.ID).Updates(map[string]interface{}{
+				"file_path":  targetPath,
+				"full_path":  targetPath + entry.file.FileName,
+				"updated_at": now,
+			}).Error
+		})
+		if err != nil {
+			if repository.IsDuplicatedKey(s.db, err) {
+				entry.result.Status = dto.FileMoveStatusConflict
+				entry.result.Error = errMoveConflict.Error()
+				continue
+			}
+			return nil, fmt.Errorf("更新文件记录 %s 失败: %w", entry.file.FullPath, err)
+		}
+		claimed = append(claimed, entry)
+	}
+	return claimed, nil
+}
+
+// removeMoveObjects 并发删除各条目中由 object 指定的对象，失败只打印日志
+func (s *fileService) removeMoveObjects(ctx context.Context, bucketName string, entries []*moveEntry, object func(entry *moveEntry) string) {
+	s.forEachMoveEntry(entries, func(entry *moveEntry) {
+		if err := entry.client.RemoveObject(ctx, bucketName, object(entry)); err != nil {
+			log.Printf("删除存储对象 %s 失败: %v", object(entry), err)
+		}
+	})
+}
+
+// forEachMoveEntry 以 storage.move_concurrency 的并发数对每个条目执行 fn，全部完成后返回
+func (s *fileService) forEachMoveEntry(entries []*moveEntry, fn func(entry *moveEntry)) {
+	sem := make(chan struct{}, moveConcurrency())
+	var wg sync.WaitGroup
+	for _, entry := range entries {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(entry *moveEntry) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(entry)
+		}(entry)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"oss-backend/internal/model/dto"
+	"oss-backend/internal/model/entity"
+	"oss-backend/pkg/minio"
+)
+
+// TestBatchMoveFilesConcurrentConflict 重名检查之后目标路径被并发创建的文件占用时，该文件记为冲突，
+// 其他文件照常移动，占用目标路径的文件的对象不被覆盖或删除
+func TestBatchMoveFilesConcurrentConflict(t *testing.T) {
+	project := newTestProject()
+	repo := &testFileRepo{}
+	svc, store, db := newTestFileService(t, repo, project)
+	bucket := groupBucketName(project.Group.GroupKey)
+
+	seed := []*entity.File{
+		{ID: "folder-docs", FileName: "docs", FilePath: "", FullPath: "docs/", IsFolder: true},
+		{ID: "file-a", FileName: "a.txt", FilePath: "", FullPath: "a.txt"},
+		{ID: "file-b", FileName: "b.txt", FilePath: "", FullPath: "b.txt"},
+	}
+	for _, file := range seed {
+		file.ProjectID = project.ID
+		file.UploaderID = "user-1"
+		file.CurrentVersion = 1
+		if err := db.Create(file).Error; err != nil {
+			t.Fatalf("创建文件记录失败: %v", err)
+		}
+		if !file.IsFolder {
+			store.putObject(bucket, minio.GetObjectName(project.ID, "", file.FileName), []byte("content of "+file.FileName))
+		}
+	}
+
+	// b.txt 通过重名检查后，另一个请求在目标目录下创建了同名文件
+	concurrentObject := minio.GetObjectName(project.ID, "docs/", "b.txt")
+	repo.afterFind = func(path, fileName string) {
+		if path != "docs/" || fileName != "b.txt" {
+			return
+		}
+		concurrent := &entity.File{ID: "file-concurrent", ProjectID: project.ID, FileName: "b.txt", FilePath: "docs/", FullPath: "docs/b.txt", UploaderID: "user-2", CurrentVersion: 1}
+		if err := db.Create(concurrent).Error; err != nil {
+			t.Errorf("创建并发文件失败: %v", err)
+		}
+		store.putObject(bucket, concurrentObject, []byte("concurrent upload"))
+	}
+
+	response, err := svc.BatchMoveFiles(context.Background(), []string{"file-a", "file-b"}, "docs/", "user-1")
+	if err != nil {
+		t.Fatalf("批量移动失败: %v", err)
+	}
+	if response.Moved != 1 || response.Failed != 1 {
+		t.Fatalf("移动 %d 个、失败 %d 个，应为各1个: %+v", response.Moved, response.Failed, response.Results)
+	}
+	statuses := map[string]string{}
+	for _, result := range response.Results {
+		statuses[result.FileID] = result.Status
+	}
+	if statuses["file-a"] != dto.FileMoveStatusMoved || statuses["file-b"] != dto.FileMoveStatusConflict {
+		t.Fatalf("各文件的结果不符合预期: %v", statuses)
+	}
+
+	var a, b entity.File
+	db.First(&a, "id = ?", "file-a")
+	db.First(&b, "id = ?", "file-b")
+	if a.FullPath != "docs/a.txt" || b.FullPath != "b.txt" {
+		t.Fatalf("文件记录路径为 %s、%s，应为 docs/a.txt、b.txt", a.FullPath, b.FullPath)
+	}
+
+	if data, ok := store.object(bucket, minio.GetObjectName(project.ID, "docs/", "a.txt")); !ok || string(data) != "content of a.txt" {
+		t.Fatalf("a.txt 的对象未移动到目标目录")
+	}
+	if _, ok := store.object(bucket, minio.GetObjectName(project.ID, "", "a.txt")); ok {
+		t.Fatalf("a.txt 的旧对象未删除")
+	}
+	if data, ok := store.object(bucket, minio.GetObjectName(project.ID, "", "b.txt")); !ok || string(data) != "content of b.txt" {
+		t.Fatalf("未移动的 b.txt 的对象应保留")
+	}
+	if data, ok := store.object(bucket, concurrentObject); !ok || string(data) != "concurrent upload" {
+		t.Fatalf("并发创建的文件的对象被覆盖或删除: %q", data)
+	}
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+	"oss-backend/pkg/minio"
+)
+
+// TestPathCaseCollision 默认区分大小写，只差大小写的名称可以共存；项目设置忽略大小写后视为同名，覆盖上传沿用已有文件的名称和存储键
+func TestPathCaseCollision(t *testing.T) {
+	for _, caseInsensitive := range []bool{false, true} {
+		ctx := context.Background()
+		project := newTestProject()
+		project.CaseInsensitivePaths = caseInsensitive
+		svc, store, db := newTestFileService(t, nil, project)
+		svc.fileRepo = repository.NewFileRepository(db)
+		bucket := groupBucketName(project.Group.GroupKey)
+
+		// 文件夹
+		if _, err := svc.CreateFolder(ctx, project.ID, "user-1", "", "Docs"); err != nil {
+			t.Fatalf("忽略大小写=%v: 创建文件夹失败: %v", caseInsensitive, err)
+		}
+		_, err := svc.CreateFolder(ctx, project.ID, "user-1", "", "docs")
+		if caseInsensitive && !errors.Is(err, ErrFolderExists) {
+			t.Fatalf("忽略大小写时创建 docs 返回 %v，应返回 ErrFolderExists", err)
+		}
+		if !caseInsensitive && err != nil {
+			t.Fatalf("区分大小写时创建 docs 失败: %v", err)
+		}
+
+		// 上传
+		first, err := svc.Upload(ctx, project.ID, "user-1", newFileHeader(t, "Report.txt", "first"), "", "")
+		if err != nil {
+			t.Fatalf("忽略大小写=%v: 上传失败: %v", caseInsensitive, err)
+		}
+		second, err := svc.Upload(ctx, project.ID, "user-1", newFileHeader(t, "REPORT.txt", "second"), "", "")
+		if err != nil {
+			t.Fatalf("忽略大小写=%v: 上传 REPORT.txt 失败: %v", caseInsensitive, err)
+		}
+		if caseInsensitive {
+			if second.ID != first.ID || second.FileName != "Report.txt" || second.CurrentVersion != 2 {
+				t.Fatalf("忽略大小写时上传 REPORT.txt 得到 %s/%s v%d，应为 Report.txt 的新版本", second.ID, second.FileName, second.CurrentVersion)
+			}
+			if data, _ := store.object(bucket, minio.GetObjectName(project.ID, "", "Report.txt")); string(data) != "second" {
+				t.Fatalf("Report.txt 的对象内容为 %q，应为覆盖后的内容", data)
+			}
+			if _, ok := store.object(bucket, minio.GetObjectName(project.ID, "", "REPORT.txt")); ok {
+				t.Fatalf("忽略大小写时写入了新的存储键 REPORT.txt")
+			}
+		} else if second.ID == first.ID || second.FileName != "REPORT.txt" {
+			t.Fatalf("区分大小写时上传 REPORT.txt 得到 %s/%s，应为新文件", second.ID, second.FileName)
+		}
+
+		// 重命名
+		other, err := svc.Upload(ctx, project.ID, "user-1", newFileHeader(t, "other.txt", "other"), "", "")
+		if err != nil {
+			t.Fatalf("忽略大小写=%v: 上传 other.txt 失败: %v", caseInsensitive, err)
+		}
+		_, _, err = svc.RenameFile(ctx, other.ID, "user-1", "report.txt")
+		if caseInsensitive && !errors.Is(err, ErrInvalidRename) {
+			t.Fatalf("忽略大小写时重命名为 report.txt 返回 %v，应返回 ErrInvalidRename", err)
+		}
+		if !caseInsensitive && err != nil {
+			t.Fatalf("区分大小写时重命名为 report.txt 失败: %v", err)
+		}
+
+		var count int64
+		db.Model(&entity.File{}).Where("project_id = ? AND is_deleted = ?", project.ID, false).Count(&count)
+		want := int64(5)
+		if caseInsensitive {
+			want = 3
+		}
+		if count != want {
+			t.Fatalf("忽略大小写=%v: 文件记录数为 %d，应为 %d", caseInsensitive, count, want)
+		}
+	}
+}
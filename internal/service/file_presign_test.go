@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+	"oss-backend/pkg/minio"
+)
+
+// TestPresignedUploadKeepsPreviousVersion 直传覆盖已有文件时，确认前不改动当前内容，确认后上一版本的内容仍可用
+func TestPresignedUploadKeepsPreviousVersion(t *testing.T) {
+	project := newTestProject()
+	repo := &testFileRepo{}
+	svc, store, db := newTestFileService(t, repo, project)
+	repo.FileRepository = repository.NewFileRepository(db)
+	bucket := groupBucketName(project.Group.GroupKey)
+
+	oldContent, newContent := "first version", "second version, uploaded directly"
+	oldSum := sha256.Sum256([]byte(oldContent))
+	file := &entity.File{ID: "file-1", ProjectID: project.ID, FileName: "a.txt", FilePath: "docs/", FullPath: "docs/a.txt", FileHash: hex.EncodeToString(oldSum[:]), FileSize: int64(len(oldContent)), UploaderID: "user-1", CurrentVersion: 1}
+	if err := db.Create(file).Error; err != nil {
+		t.Fatal(err)
+	}
+	liveObject := minio.GetObjectName(project.ID, "docs/", "a.txt")
+	if err := db.Create(&entity.FileVersion{ID: "version-1", FileID: file.ID, Version: 1, FileHash: file.FileHash, FileSize: file.FileSize, StorageKey: liveObject, UploaderID: "user-1"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	store.putObject(bucket, liveObject, []byte(oldContent))
+
+	_, objectKey, _, err := svc.GetPresignedUploadURL(context.Background(), project.ID, "user-1", "a.txt", "docs/")
+	if err != nil {
+		t.Fatalf("获取预签名上传URL失败: %v", err)
+	}
+	if !strings.HasPrefix(objectKey, TempObjectPrefix) {
+		t.Fatalf("预签名上传指向 %s，应为临时对象", objectKey)
+	}
+	// 客户端直传到临时对象
+	store.putObject(bucket, objectKey, []byte(newContent))
+	if data, _ := store.object(bucket, liveObject); string(data) != oldContent {
+		t.Fatalf("确认上传前当前内容已被修改为 %q", data)
+	}
+
+	updated, err := svc.ConfirmPresignedUpload(context.Background(), project.ID, "user-1", objectKey, int64(len(newContent)), "")
+	if err != nil {
+		t.Fatalf("确认上传失败: %v", err)
+	}
+	newSum := sha256.Sum256([]byte(newContent))
+	if updated.CurrentVersion != 2 || updated.FileHash != hex.EncodeToString(newSum[:]) {
+		t.Fatalf("确认后版本为 %d、哈希为 %s，应为版本2和新内容的哈希", updated.CurrentVersion, updated.FileHash)
+	}
+	if data, _ := store.object(bucket, liveObject); string(data) != newContent {
+		t.Fatalf("确认后文件对象内容为 %q，应为直传的内容", data)
+	}
+
+	var previous entity.FileVersion
+	if err := db.First(&previous, "file_id = ? AND version = ?", file.ID, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if data, ok := store.object(bucket, previous.StorageKey); !ok || string(data) != oldContent {
+		t.Fatalf("上一版本指向 %s，内容为 %q，应保存被覆盖的内容", previous.StorageKey, data)
+	}
+	if keys := store.keys(bucket, TempObjectPrefix); len(keys) != 0 {
+		t.Fatalf("临时对象未删除: %v", keys)
+	}
+}
+
+// TestPresignedUploadComputesHash 确认上传时哈希以上传对象的实际内容为准，与内容不一致的哈希被拒绝，不会写入文件记录
+func TestPresignedUploadComputesHash(t *testing.T) {
+	project := newTestProject()
+	svc, store, db := newTestFileService(t, &testFileRepo{}, project)
+	bucket := groupBucketName(project.Group.GroupKey)
+
+	content := "uploaded content"
+	sum := sha256.Sum256([]byte(content))
+	actualHash := hex.EncodeToString(sum[:])
+	otherSum := sha256.Sum256([]byte("someone else's file"))
+
+	_, objectKey, _, err := svc.GetPresignedUploadURL(context.Background(), project.ID, "user-1", "b.txt", "")
+	if err != nil {
+		t.Fatalf("获取预签名上传URL失败: %v", err)
+	}
+	store.putObject(bucket, objectKey, []byte(content))
+	if _, err := svc.ConfirmPresignedUpload(context.Background(), project.ID, "user-1", objectKey, int64(len(content)), hex.EncodeToString(otherSum[:])); !errors.Is(err, ErrPresignHashMismatch) {
+		t.Fatalf("哈希与内容不一致时返回 %v，应返回 ErrPresignHashMismatch", err)
+	}
+	var count int64
+	db.Model(&entity.File{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("哈希不一致的上传创建了 %d 条文件记录", count)
+	}
+
+	_, objectKey, _, err = svc.GetPresignedUploadURL(context.Background(), project.ID, "user-1", "b.txt", "")
+	if err != nil {
+		t.Fatalf("获取预签名上传URL失败: %v", err)
+	}
+	store.putObject(bucket, objectKey, []byte(content))
+	file, err := svc.ConfirmPresignedUpload(context.Background(), project.ID, "user-1", objectKey, int64(len(content)), "")
+	if err != nil {
+		t.Fatalf("确认上传失败: %v", err)
+	}
+	if file.FileHash != actualHash {
+		t.Fatalf("文件哈希为 %s，应为服务端计算的 %s", file.FileHash, actualHash)
+	}
+	if data, ok := store.object(bucket, minio.GetObjectName(project.ID, "", "b.txt")); !ok || string(data) != content {
+		t.Fatalf("确认后文件对象内容为 %q", data)
+	}
+
+	// 其他项目的对象键和非直传的对象键不能确认
+	for _, key := range []string{objectKey, minio.GetObjectName(project.ID, "", "b.txt")} {
+		if _, _, err := parsePresignStagingObjectName("project-2", key); err == nil {
+			t.Fatalf("对象键 %s 被当作 project-2 的直传对象", key)
+		}
+	}
+}
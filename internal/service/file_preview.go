@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/pkg/minio"
+)
+
+// previewExpiry 获取预览URL有效期，默认15分钟
+func previewExpiry() time.Duration {
+	minutes := viper.GetInt("storage.preview_expire_minutes")
+	if minutes <= 0 {
+		minutes = 15
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// previewContentType 判断文件能否在浏览器中直接预览，返回预览时使用的内容类型
+// 支持图片、PDF和文本；SVG 可包含脚本，不预览；文本统一按纯文本返回，避免 HTML 等内容被浏览器渲染
+func previewContentType(file *entity.File) (string, bool) {
+	mimeType := strings.ToLower(strings.TrimSpace(file.MimeType))
+	if i := strings.Index(mimeType, ";"); i >= 0 {
+		mimeType = strings.TrimSpace(mimeType[:i])
+	}
+
+	switch {
+	case mimeType == "image/svg+xml":
+		return "", false
+	case strings.HasPrefix(mimeType, "image/"), mimeType == "application/pdf":
+		return mimeType, true
+	case strings.HasPrefix(mimeType, "text/"):
+		return "text/plain; charset=utf-8", true
+	}
+	return "", false
+}
+
+// GetPreviewURL 生成文件的预览URL，浏览器打开时直接显示而不是下载，有效期由 storage.preview_expire_minutes 配置
+// 预览URL不保存，每次按需生成并写入 file.PreviewURL；文件夹、已删除、不支持预览的文件返回空字符串，
+// 需要水印的文件也返回空字符串，避免绕过下载时添加的水印；加密存储的文件无法由浏览器直接读取，同样返回空字符串
+func (s *fileService) GetPreviewURL(ctx context.Context, file *entity.File) (string, error) {
+	if file == nil || file.IsFolder || file.IsDeleted || file.WatermarkRequired || file.Encrypted() {
+		return "", nil
+	}
+	contentType, ok := previewContentType(file)
+	if !ok {
+		return "", nil
+	}
+
+	project, err := s.projectRepo.GetByID(ctx, file.ProjectID)
+	if err != nil {
+		return "", fmt.Errorf("获取项目信息失败: %w", err)
+	}
+	if project == nil {
+		return "", errors.New("项目不存在")
+	}
+
+	// 按文件所在的存储后端生成
+	client, err := s.fileStorage(file)
+	if err != nil {
+		return "", err
+	}
+	objectName := minio.GetObjectName(file.ProjectID, file.FilePath, file.FileName)
+	previewURL, err := client.GeneratePresignedInlineURL(ctx, s.sanitizeBucketName(project.Group.GroupKey), objectName, contentType, previewExpiry())
+	if err != nil {
+		return "", err
+	}
+	file.PreviewURL = previewURL
+	return previewURL, nil
+}
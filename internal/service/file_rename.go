@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/utils"
+	"oss-backend/pkg/minio"
+)
+
+// ErrInvalidRename 新文件名无效或与已有文件冲突
+var ErrInvalidRename = errors.New("无法重命名文件")
+
+// RenameFile 重命名文件
+// 扩展名变化时重新推断内容类型；新扩展名与文件实际内容明显不符时返回提示信息，但仍完成重命名
+func (s *fileService) RenameFile(ctx context.Context, fileID, userID, newName string) (*entity.File, string, error) {
+	newName = strings.TrimSpace(newName)
+	if err := utils.ValidateName(newName); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidRename, err)
+	}
+
+	// 1. 获取文件与项目信息
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return nil, "", err
+	}
+	if file == nil || file.IsDeleted {
+		return nil, "", errors.New("文件不存在")
+	}
+	if file.IsFolder {
+		return nil, "", fmt.Errorf("%w: 暂不支持重命名文件夹", ErrInvalidRename)
+	}
+	if file.FileName == newName {
+		return file, "", nil
+	}
+
+	project, err := s.projectRepo.GetByID(ctx, file.ProjectID)
+	if err != nil {
+		return nil, "", fmt.Errorf("获取项目信息失败: %w", err)
+	}
+	if project == nil {
+		return nil, "", errors.New("项目不存在")
+	}
+	if err := checkProjectWritable(project); err != nil {
+		return nil, "", err
+	}
+
+	// 2. 新名称需符合上传策略，且不能与同目录下的其他文件重名
+	if err := checkUploadFileName(resolveUploadPolicy(project), newName, file.FileSize); err != nil {
+		return nil, "", err
+	}
+	existing, err := s.findByPath(ctx, project, file.FilePath, newName)
+	if err != nil {
+		return nil, "", fmt.Errorf("检查文件路径失败: %w", err)
+	}
+	if existing != nil && existing.ID != file.ID {
+		return nil, "", fmt.Errorf("%w: 当前目录下已存在同名文件", ErrInvalidRename)
+	}
+
+	bucketName := s.sanitizeBucketName(project.Group.GroupKey)
+	oldObject := minio.GetObjectName(file.ProjectID, file.FilePath, file.FileName)
+	newObject := minio.GetObjectName(file.ProjectID, file.FilePath, newName)
+
+	client, err := s.fileStorage(file)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 3. 扩展名变化时重新推断内容类型
+	var warning string
+	mimeType := file.MimeType
+	newExt := filepath.Ext(newName)
+	if !strings.EqualFold(newExt, file.Extension) {
+		sniffed := s.sniffObjectContentType(ctx, client, bucketName, oldObject, project.GroupID, file.ObjectEncryption)
+		mimeType = mimeTypeForName(newName, sniffed)
+		if contentContradictsType(mimeType, sniffed) {
+			warning = fmt.Sprintf("新扩展名 %s 与文件实际内容(%s)不符", displayExtension(newExt), baseMimeType(sniffed))
+		}
+	}
+
+	// 4. 对象键由文件名决定，先复制到新键，数据库更新成功后再删除旧对象
+	if err := client.CopyObject(ctx, bucketName, oldObject, newObject, mimeType); err != nil {
+		return nil, "", fmt.Errorf("复制存储对象失败: %w", err)
+	}
+
+	oldName := file.FileName
+	file.FileName = newName
+	file.FullPath = file.FilePath + newName
+	file.Extension = newExt
+	file.MimeType = mimeType
+	file.UpdatedAt = time.Now()
+	if err := s.fileRepo.Update(ctx, file); err != nil {
+		if rmErr := client.RemoveObject(ctx, bucketName, newObject); rmErr != nil {
+			log.Printf("删除重命名失败的存储对象失败: %v", rmErr)
+		}
+		return nil, "", fmt.Errorf("更新文件记录失败: %w", err)
+	}
+
+	// 仅大小写不同时新旧对象键可能相同
+	if oldObject != newObject {
+		if err := client.RemoveObject(ctx, bucketName, oldObject); err != nil {
+			log.Printf("删除重命名前的存储对象 %s 失败: %v", oldName, err)
+		}
+	}
+
+	s.recordAudit(ctx, userID, entity.OperationRename, project, file)
+
+	return file, warning, nil
+}
+
+// sniffObjectContentType 读取对象开头检测实际内容类型，读取失败时返回空字符串
+func (s *fileService) sniffObjectContentType(ctx context.Context, client *minio.Client, bucketName, objectName, groupID string, enc entity.ObjectEncryption) string {
+	obj, err := s.openObject(ctx, client, bucketName, objectName, groupID, enc)
+	if err != nil {
+		return ""
+	}
+	defer obj.Close()
+
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(obj, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return ""
+	}
+	return http.DetectContentType(buf[:n])
+}
+
+// mimeTypeForName 按扩展名推断内容类型，扩展名未知时使用检测到的内容类型
+func mimeTypeForName(fileName, sniffed string) string {
+	if t := mime.TypeByExtension(strings.ToLower(filepath.Ext(fileName))); t != "" {
+		return t
+	}
+	if sniffed != "" {
+		return sniffed
+	}
+	return "application/octet-stream"
+}
+
+// contentContradictsType 判断按扩展名推断的类型是否与检测到的内容明显矛盾
+// 内容检测只能识别有限的格式，结果为通用类型时只做粗略判断，避免误报
+func contentContradictsType(mimeType, sniffed string) bool {
+	declared := baseMimeType(mimeType)
+	actual := baseMimeType(sniffed)
+	if actual == "" || declared == actual {
+		return false
+	}
+
+	declaredTop := strings.SplitN(declared, "/", 2)[0]
+	switch {
+	case actual == "application/octet-stream":
+		// 无法识别的二进制内容却使用文本类扩展名
+		return declaredTop == "text"
+	case strings.HasPrefix(actual, "text/"):
+		// 文本内容可对应多种文本格式，只在使用媒体类扩展名时提示
+		return declaredTop == "image" || declaredTop == "audio" || declaredTop == "video"
+	case actual == "application/zip":
+		// docx、xlsx、jar 等格式均为zip容器
+		return declaredTop == "text" || declaredTop == "image"
+	default:
+		return true
+	}
+}
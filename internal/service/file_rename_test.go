@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+	"oss-backend/pkg/minio"
+)
+
+// TestRenameFileUpdatesMimeType 重命名改变扩展名时同时更新扩展名和内容类型，新扩展名与实际内容不符时返回提示
+func TestRenameFileUpdatesMimeType(t *testing.T) {
+	ctx := context.Background()
+	project := newTestProject()
+	svc, store, db := newTestFileService(t, nil, project)
+	svc.fileRepo = repository.NewFileRepository(db)
+	bucket := groupBucketName(project.Group.GroupKey)
+
+	file, err := svc.Upload(ctx, project.ID, "user-1", newFileHeader(t, "data.bin", "plain text content\n"), "", "")
+	if err != nil {
+		t.Fatalf("上传失败: %v", err)
+	}
+	if file.Extension != ".bin" {
+		t.Fatalf("上传后扩展名为 %q，应为 .bin", file.Extension)
+	}
+
+	renamed, warning, err := svc.RenameFile(ctx, file.ID, "user-1", "data.txt")
+	if err != nil {
+		t.Fatalf("重命名失败: %v", err)
+	}
+	if warning != "" {
+		t.Fatalf("文本内容改为 .txt 不应提示，实际提示 %q", warning)
+	}
+	const wantMime = "text/plain; charset=utf-8"
+	if renamed.Extension != ".txt" || renamed.MimeType != wantMime || renamed.FullPath != "data.txt" {
+		t.Fatalf("重命名后为 %s/%s/%s，应为 data.txt/.txt/%s", renamed.FullPath, renamed.Extension, renamed.MimeType, wantMime)
+	}
+	var stored entity.File
+	if err := db.First(&stored, "id = ?", file.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Extension != ".txt" || stored.MimeType != wantMime {
+		t.Fatalf("数据库中为 %s/%s，没有保存新的扩展名和内容类型", stored.Extension, stored.MimeType)
+	}
+	if _, ok := store.object(bucket, minio.GetObjectName(project.ID, "", "data.bin")); ok {
+		t.Fatalf("重命名后旧的存储对象仍然存在")
+	}
+	if data, ok := store.object(bucket, minio.GetObjectName(project.ID, "", "data.txt")); !ok || string(data) != "plain text content\n" {
+		t.Fatalf("新的存储对象内容为 %q", data)
+	}
+
+	// 二进制内容改为图片扩展名时给出提示，但仍完成重命名
+	binary, err := svc.Upload(ctx, project.ID, "user-1", newFileHeader(t, "archive.bin", "PK\x03\x04\x14\x00\x00\x00\x08\x00"), "", "")
+	if err != nil {
+		t.Fatalf("上传失败: %v", err)
+	}
+	renamed, warning, err = svc.RenameFile(ctx, binary.ID, "user-1", "archive.png")
+	if err != nil {
+		t.Fatalf("重命名失败: %v", err)
+	}
+	if warning == "" {
+		t.Fatalf("zip 内容改为 .png 没有提示")
+	}
+	if renamed.Extension != ".png" || renamed.MimeType != "image/png" {
+		t.Fatalf("重命名后为 %s/%s，应为 .png/image/png", renamed.Extension, renamed.MimeType)
+	}
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"crypto/rand"
 	"crypto/sha256"
@@ -8,52 +9,130 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math/big"
 	"mime/multipart"
+	"oss-backend/internal/model/dto"
 	"oss-backend/internal/model/entity"
 	"oss-backend/internal/repository"
 	"oss-backend/internal/utils"
 	"oss-backend/pkg/minio"
+	"oss-backend/pkg/watermark"
 	"path/filepath"
-	"regexp"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
 
 	"log"
 
-	"github.com/google/uuid"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 // FileService 文件服务接口
 type FileService interface {
 	// 文件操作
-	Upload(ctx context.Context, projectID, uploaderID string, file *multipart.FileHeader, path string) (*entity.File, error)
+	Upload(ctx context.Context, projectID, uploaderID string, file *multipart.FileHeader, path, declaredHash string) (*entity.File, error)
+	UploadIdempotent(ctx context.Context, projectID, uploaderID, idempotencyKey string, file *multipart.FileHeader, path, declaredHash string) (*entity.File, error)
+	ResolveUploadPath(ctx context.Context, projectID, parentFolderID, path string) (string, error)
+	SearchFiles(ctx context.Context, projectID, userID, query string, filters *dto.FileSearchFilters, page, pageSize int) ([]*entity.File, int64, error)
+	GetUploadConfig(ctx context.Context, projectID string) (*dto.UploadConfigResponse, error)
+	UploadFolder(ctx context.Context, projectID, uploaderID, basePath string, files []*multipart.FileHeader, relativePaths []string) (*dto.FolderUploadResponse, error)
 	Download(ctx context.Context, fileID string, userID string) (io.ReadCloser, *entity.File, error)
-	ListFiles(ctx context.Context, projectID string, path string, recursive bool, page, pageSize int) ([]*entity.File, int64, error)
+	ListFiles(ctx context.Context, projectID, userID string, path, tag, category string, recursive bool, page, pageSize int) ([]*entity.File, int64, error)
 	CreateFolder(ctx context.Context, projectID, userID string, path, folderName string) (*entity.File, error)
-	DeleteFile(ctx context.Context, fileID, userID string) error
+	DeleteFile(ctx context.Context, fileID, userID string, recursive bool) error
 	RestoreFile(ctx context.Context, fileID, userID string) error
 	GetFileInfo(ctx context.Context, fileID string) (*entity.File, error)
 
+	// 重复文件
+	FindDuplicates(ctx context.Context, projectID, userID string) (*dto.DuplicatesResponse, error)
+	ResolveDuplicates(ctx context.Context, projectID, keepFileID, userID string) (*dto.ResolveDuplicatesResponse, error)
+
+	// 文件标签
+	AddFileTag(ctx context.Context, fileID, userID, name string) ([]string, error)
+	RemoveFileTag(ctx context.Context, fileID, name string) ([]string, error)
+	ListFileTags(ctx context.Context, fileID string) ([]string, error)
+	ListProjectTags(ctx context.Context, projectID string) ([]*dto.ProjectTagResponse, error)
+	GetProjectShareStats(ctx context.Context, projectID string) (*dto.ProjectShareStatsResponse, error)
+
+	// 文件夹打包下载
+	PrepareFolderArchive(ctx context.Context, folderID, userID string) (*FolderArchive, error)
+	WriteFolderArchive(ctx context.Context, archive *FolderArchive, w io.Writer) error
+
+	// 分块下载清单
+	GetDownloadManifest(ctx context.Context, fileID string) (*dto.DownloadManifestResponse, error)
+	GetThumbnail(ctx context.Context, fileID, userID string) (io.ReadCloser, int64, error)
+
 	// 版本管理
 	GetFileVersions(ctx context.Context, fileID string) ([]*entity.FileVersion, error)
 	GetFileVersion(ctx context.Context, fileID string, version int) (*entity.FileVersion, error)
+	RollbackToVersion(ctx context.Context, fileID string, version int, userID string) (*entity.File, error)
+	PruneVersions(ctx context.Context, fileID string) (*dto.VersionPruneResponse, error)
+	DownloadFileVersion(ctx context.Context, fileID string, version int, userID string) (io.ReadCloser, *entity.File, error)
 
 	// 文件分享
-	CreateShare(ctx context.Context, fileID, userID string, password string, expireHours, downloadLimit int) (*entity.FileShare, error)
+	CreateShare(ctx context.Context, fileID, userID string, password string, expireHours, downloadLimit *int, watermark *dto.ShareWatermarkRequest) (*entity.FileShare, error)
 	GetShareInfo(ctx context.Context, shareCode string) (*entity.FileShare, error)
 	DownloadSharedFile(ctx context.Context, shareCode, password string) (io.ReadCloser, *entity.File, error)
+	RevokeShare(ctx context.Context, shareID, userID string) error
+	ListUserShares(ctx context.Context, userID string, page, pageSize int) ([]*entity.FileShare, int64, error)
+
+	// 重命名
+	RenameFile(ctx context.Context, fileID, userID, newName string) (*entity.File, string, error)
+
+	// 复制
+	CopyFile(ctx context.Context, fileID, targetProjectID, targetPath, userID string) (*entity.File, error)
+
+	// 批量移动
+	BatchMoveFiles(ctx context.Context, fileIDs []string, targetPath, userID string) (*dto.FileBatchMoveResponse, error)
+
+	// 下载水印
+	SetFileWatermark(ctx context.Context, fileID string, required bool, text string) (*entity.File, error)
 
 	// 公共下载
 	GetPublicDownloadURL(ctx context.Context, fileID string) (string, error)
+	GetPreviewURL(ctx context.Context, file *entity.File) (string, error)
+
+	// 预签名直传
+	GetPresignedUploadURL(ctx context.Context, projectID, userID, fileName, path string) (string, string, time.Time, error)
+	ConfirmPresignedUpload(ctx context.Context, projectID, userID, objectKey string, size int64, hash string) (*entity.File, error)
+
+	// 审计日志
+	ListFileAuditLogs(ctx context.Context, fileID string, page, pageSize int) ([]*entity.Log, int64, error)
 
 	// 文件权限
 	CheckFilePermission(ctx context.Context, fileID, userID string, requiredAction string) (bool, error)
+	DenyFileAccess(ctx context.Context, fileID, targetUserID, adminID string) error
+	RemoveFileDeny(ctx context.Context, fileID, targetUserID string) error
+	ListFileDenies(ctx context.Context, fileID string) ([]*dto.FileDenyResponse, error)
+	CheckPathPermission(ctx context.Context, projectID, dirPath, userID, action string) (bool, error)
+	GrantFolderPermission(ctx context.Context, folderID, targetUserID, role, adminID string) (*dto.FolderPermissionResponse, error)
+	RevokeFolderPermission(ctx context.Context, folderID, targetUserID string) error
+	ListFolderPermissions(ctx context.Context, folderID string) ([]*dto.FolderPermissionResponse, error)
 
 	// 存储统计
 	UpdateStorageStats(ctx context.Context, projectID string, fileSize int64, isAdd bool) error
 	RecalculateProjectStats(ctx context.Context, projectID string) error
 	VerifyAllProjectsStats(ctx context.Context) error
+
+	// 存储报表
+	GenerateStorageReport(ctx context.Context, groupID, adminID string, from, to time.Time, format string) (string, error)
+	OpenStorageReport(ctx context.Context, objectName string) (io.ReadCloser, error)
+
+	// 定时备份
+	RunBackup(ctx context.Context, target BackupTarget) (string, error)
+
+	// 存储后端迁移
+	MigrateFileStorage(ctx context.Context, fileID, targetBackend, adminID string) error
+
+	// 存储桶生命周期
+	ApplyTempLifecycle(ctx context.Context) (*dto.BucketLifecycleResponse, error)
+
+	// 存储桶布局迁移
+	CheckBucketMigration(fromStrategy, fromSharedBucket string) error
+	MigrateBucketLayout(ctx context.Context, fromStrategy, fromSharedBucket string) (string, error)
 }
 
 // fileService 文件服务实现
@@ -61,8 +140,13 @@ type fileService struct {
 	fileRepo    repository.FileRepository
 	projectRepo repository.ProjectRepository
 	statRepo    repository.StorageStatRepository
+	auditRepo   repository.AuditRepository
+	statQueue   *StorageStatQueue
+	webhooks    *WebhookDispatcher
+	idempotency IdempotencyStore
 	minioClient *minio.Client
 	authService AuthService
+	mailer      Mailer
 	db          *gorm.DB
 }
 
@@ -71,22 +155,49 @@ func NewFileService(
 	fileRepo repository.FileRepository,
 	projectRepo repository.ProjectRepository,
 	statRepo repository.StorageStatRepository,
+	auditRepo repository.AuditRepository,
+	statQueue *StorageStatQueue,
+	webhooks *WebhookDispatcher,
+	idempotency IdempotencyStore,
 	minioClient *minio.Client,
 	authService AuthService,
+	mailer Mailer,
 	db *gorm.DB,
 ) FileService {
 	return &fileService{
 		fileRepo:    fileRepo,
 		projectRepo: projectRepo,
 		statRepo:    statRepo,
+		auditRepo:   auditRepo,
+		statQueue:   statQueue,
+		webhooks:    webhooks,
+		idempotency: idempotency,
 		minioClient: minioClient,
 		authService: authService,
+		mailer:      mailer,
 		db:          db,
 	}
 }
 
+// ResolveUploadPath 确定上传的目标目录，parentFolderID 为空时直接使用 path
+// 指定 parentFolderID 时该文件夹须存在、未删除且属于同一项目，返回其完整路径，此时忽略 path
+func (s *fileService) ResolveUploadPath(ctx context.Context, projectID, parentFolderID, path string) (string, error) {
+	if parentFolderID == "" {
+		return path, nil
+	}
+	folder, err := s.fileRepo.GetByID(ctx, parentFolderID)
+	if err != nil {
+		return "", fmt.Errorf("获取文件夹信息失败: %w", err)
+	}
+	if folder == nil || folder.IsDeleted || !folder.IsFolder || folder.ProjectID != projectID {
+		return "", fmt.Errorf("%w: 父文件夹不存在或不属于该项目", ErrInvalidPath)
+	}
+	return folder.FullPath, nil
+}
+
 // Upload 上传文件
-func (s *fileService) Upload(ctx context.Context, projectID, uploaderID string, file *multipart.FileHeader, path string) (*entity.File, error) {
+// declaredHash 为客户端预先计算的 SHA256，提供时直接用于秒传判断，文件内容只读取一次
+func (s *fileService) Upload(ctx context.Context, projectID, uploaderID string, file *multipart.FileHeader, path, declaredHash string) (*entity.File, error) {
 	// 1. 获取项目信息，检查项目是否存在
 	project, err := s.projectRepo.GetByID(ctx, projectID)
 	if err != nil {
@@ -95,6 +206,9 @@ func (s *fileService) Upload(ctx context.Context, projectID, uploaderID string,
 	if project == nil {
 		return nil, errors.New("项目不存在")
 	}
+	if err := checkProjectWritable(project); err != nil {
+		return nil, err
+	}
 
 	// 获取群组信息，确认存储桶名称
 	if project.Group.GroupKey == "" {
@@ -102,16 +216,6 @@ func (s *fileService) Upload(ctx context.Context, projectID, uploaderID string,
 	}
 	bucketName := s.sanitizeBucketName(project.Group.GroupKey)
 
-	// 确保存储桶存在
-	if err := s.ensureBucketExists(ctx, bucketName); err != nil {
-		return nil, fmt.Errorf("存储准备失败: %w", err)
-	}
-
-	// 确保路径以/结尾
-	if path != "" && !strings.HasSuffix(path, "/") {
-		path = path + "/"
-	}
-
 	// 2. 打开文件
 	src, err := file.Open()
 	if err != nil {
@@ -119,16 +223,45 @@ func (s *fileService) Upload(ctx context.Context, projectID, uploaderID string,
 	}
 	defer src.Close()
 
-	// 3. 计算文件哈希值
-	fileHash, err := calculateFileHash(src)
+	// 按上传策略校验文件大小、扩展名及内容类型，在写入存储前拒绝
+	if err := utils.ValidateName(file.Filename); err != nil {
+		return nil, err
+	}
+	policy := resolveUploadPolicy(project)
+	if err := checkUploadFileName(policy, file.Filename, file.Size); err != nil {
+		return nil, err
+	}
+	sniffedType, err := sniffContentType(src)
 	if err != nil {
-		return nil, fmt.Errorf("计算文件哈希失败: %w", err)
+		return nil, fmt.Errorf("读取文件内容失败: %w", err)
+	}
+	if err := checkUploadContentType(policy, file.Header.Get("Content-Type"), sniffedType); err != nil {
+		return nil, err
 	}
 
-	// 重置文件指针
-	_, err = src.Seek(0, io.SeekStart)
-	if err != nil {
-		return nil, fmt.Errorf("重置文件指针失败: %w", err)
+	// 确保存储桶存在
+	if err := s.ensureBucketExists(ctx, bucketName); err != nil {
+		return nil, fmt.Errorf("存储准备失败: %w", err)
+	}
+
+	// 规范化目录路径
+	if path, err = utils.NormalizeDirPath(path); err != nil {
+		return nil, err
+	}
+
+	// 3. 确定用于秒传判断的文件哈希
+	// 客户端未提供哈希时只能先完整读取一遍计算，再重置文件指针用于上传
+	fileHash := strings.ToLower(strings.TrimSpace(declaredHash))
+	if fileHash == "" {
+		fileHash, err = calculateFileHash(src)
+		if err != nil {
+			return nil, fmt.Errorf("计算文件哈希失败: %w", err)
+		}
+		if _, err := src.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("重置文件指针失败: %w", err)
+		}
+	} else if !isSHA256Hex(fileHash) {
+		return nil, ErrInvalidContentHash
 	}
 
 	// 4. 判断是否可以秒传
@@ -136,6 +269,16 @@ func (s *fileService) Upload(ctx context.Context, projectID, uploaderID string,
 	if err != nil {
 		return nil, fmt.Errorf("查询文件哈希失败: %w", err)
 	}
+	// 客户端声明的哈希命中秒传时，仍需校验内容确与声明一致，防止仅凭哈希引用他人文件
+	if existingFile != nil && declaredHash != "" {
+		actualHash, err := calculateFileHash(src)
+		if err != nil {
+			return nil, fmt.Errorf("计算文件哈希失败: %w", err)
+		}
+		if actualHash != fileHash {
+			return nil, ErrContentHashMismatch
+		}
+	}
 
 	// 5. 构建文件对象
 	fileName := filepath.Base(file.Filename)
@@ -143,155 +286,190 @@ func (s *fileService) Upload(ctx context.Context, projectID, uploaderID string,
 	fullPath := path + fileName
 
 	// 检查文件名是否在当前目录下已存在
-	existingFileAtPath, err := s.fileRepo.GetByPath(ctx, projectID, path, fileName)
+	existingFileAtPath, err := s.findByPath(ctx, project, path, fileName)
 	if err != nil {
 		return nil, fmt.Errorf("检查文件路径失败: %w", err)
 	}
+	if existingFileAtPath != nil {
+		if existingFileAtPath.IsFolder {
+			return nil, ErrFolderExists
+		}
+		// 忽略大小写时沿用已有文件的名称，保证存储键不变
+		path, fileName = existingFileAtPath.FilePath, existingFileAtPath.FileName
+		fullPath = path + fileName
+	}
 
-	// 如果同名文件已存在，则创建新版本
+	// 检查项目与群组存储配额，覆盖上传时只计算大小差值
+	additionalSize := file.Size
 	if existingFileAtPath != nil {
-		// 创建新版本
-		newVersion := &entity.FileVersion{
-			FileID:     existingFileAtPath.ID,
-			Version:    existingFileAtPath.CurrentVersion + 1,
-			FileHash:   fileHash,
-			FileSize:   file.Size,
-			UploaderID: uploaderID,
-			Comment:    "更新文件",
+		additionalSize = file.Size - existingFileAtPath.FileSize
+	}
+	releaseQuota, err := s.reserveStorage(ctx, project, additionalSize)
+	if err != nil {
+		return nil, err
+	}
+	defer releaseQuota()
+
+	// 覆盖已有文件时写入其所在的存储后端，新文件写入默认后端
+	// 覆盖前保存当前版本的内容，用于版本回滚
+	client, backend := s.minioClient, ""
+	if existingFileAtPath != nil {
+		backend = existingFileAtPath.StorageBackend
+		if client, err = s.fileStorage(existingFileAtPath); err != nil {
+			return nil, err
+		}
+		if err := s.archiveCurrentVersion(ctx, bucketName, existingFileAtPath); err != nil {
+			return nil, err
 		}
+	}
+
+	// 新文件先写入临时对象，创建记录占用路径后再复制到按路径生成的对象名，
+	// 避免并发上传同一路径时失败的一方覆盖成功一方的对象；覆盖已有文件时直接写入该文件的对象
+	objectName := minio.GetObjectName(projectID, path, fileName)
+	writeObject := objectName
+	if existingFileAtPath == nil {
+		writeObject = stagingObjectName()
+	}
 
-		// 开始事务
-		tx := s.db.Begin()
-		if tx.Error != nil {
-			return nil, tx.Error
+	// 秒传时在服务端复制已有对象，无需再次传输内容，复制的对象沿用已有文件的加密信息；无法复制时按普通上传处理
+	var objectEnc entity.ObjectEncryption
+	if existingFile != nil {
+		if s.copyDedupObject(ctx, &project.Group, backend, bucketName, writeObject, existingFile) {
+			objectEnc = existingFile.ObjectEncryption
+		} else {
+			if _, err := src.Seek(0, io.SeekStart); err != nil {
+				return nil, fmt.Errorf("重置文件指针失败: %w", err)
+			}
+			existingFile = nil
 		}
+	}
 
-		// 事务中添加版本记录
-		err = s.fileRepo.CreateVersion(ctx, newVersion)
+	// 未命中秒传时上传文件，哈希在上传的同一次读取中计算，以实际内容的哈希为准
+	if existingFile == nil {
+		uploadedHash, enc, err := s.uploadWithHash(ctx, client, bucketName, writeObject, &project.Group, src, file.Size, file.Header.Get("Content-Type"))
 		if err != nil {
-			tx.Rollback()
-			return nil, fmt.Errorf("创建版本记录失败: %w", err)
+			return nil, fmt.Errorf("上传文件失败: %w", err)
+		}
+		fileHash = uploadedHash
+		objectEnc = enc
+	}
+
+	// 如果同名文件已存在，则创建新版本
+	if existingFileAtPath != nil {
+		// 创建新版本
+		newVersion := &entity.FileVersion{
+			FileID:           existingFileAtPath.ID,
+			Version:          existingFileAtPath.CurrentVersion + 1,
+			FileHash:         fileHash,
+			FileSize:         file.Size,
+			StorageKey:       objectName,
+			UploaderID:       uploaderID,
+			Comment:          "更新文件",
+			ObjectEncryption: objectEnc,
 		}
 
 		// 计算文件大小差异，用于统计更新
 		sizeDiff := file.Size - existingFileAtPath.FileSize
 
-		// 更新文件记录
 		existingFileAtPath.FileHash = fileHash
 		existingFileAtPath.FileSize = file.Size
 		existingFileAtPath.CurrentVersion = newVersion.Version
+		existingFileAtPath.ObjectEncryption = objectEnc
 		existingFileAtPath.UpdatedAt = time.Now()
 
-		err = s.fileRepo.Update(ctx, existingFileAtPath)
-		if err != nil {
-			tx.Rollback()
-			return nil, fmt.Errorf("更新文件记录失败: %w", err)
-		}
-
-		// 如果不是秒传，则需要上传文件
-		if existingFile == nil {
-			// 在MinIO中创建文件
-			objectName := minio.GetObjectName(projectID, path, fileName)
-			_, err = s.minioClient.UploadFile(ctx, bucketName, objectName, src, file.Size, file.Header.Get("Content-Type"))
-			if err != nil {
-				tx.Rollback()
-				return nil, fmt.Errorf("上传文件失败: %w", err)
+		// 版本记录与文件记录在同一事务中写入
+		err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			newVersion.ID = utils.GenerateRecordID()
+			if err := tx.Create(newVersion).Error; err != nil {
+				return fmt.Errorf("创建版本记录失败: %w", err)
+			}
+			if err := tx.Save(existingFileAtPath).Error; err != nil {
+				return fmt.Errorf("更新文件记录失败: %w", err)
 			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
 		}
 
-		// 提交事务
-		if err := tx.Commit().Error; err != nil {
-			return nil, fmt.Errorf("提交事务失败: %w", err)
-		}
+		// 如果文件大小有变化，更新存储统计（新版本不改变文件数）
+		s.enqueueStats(projectID, 0, sizeDiff)
+		s.schedulePruneVersions(existingFileAtPath.ID)
+		s.scheduleThumbnail(existingFileAtPath.ID)
 
-		// 如果文件大小有变化，更新存储统计
-		if sizeDiff != 0 {
-			// 这里采用异步方式更新统计，避免阻塞主流程
-			go func() {
-				ctx := context.Background()
-				isAdd := sizeDiff > 0
-				size := sizeDiff
-				if !isAdd {
-					size = -sizeDiff
-				}
-				err := s.UpdateStorageStats(ctx, projectID, size, isAdd)
-				if err != nil {
-					log.Printf("更新存储统计失败: %v", err)
-				}
-			}()
-		}
+		s.recordAudit(ctx, uploaderID, entity.OperationUpload, project, existingFileAtPath)
+		s.notifyWebhook(uploaderID, entity.OperationUpload, existingFileAtPath, nil)
 
 		return existingFileAtPath, nil
 	}
 
 	// 6. 创建新文件记录
 	newFile := &entity.File{
-		ProjectID:      projectID,
-		FileName:       fileName,
-		FilePath:       path,
-		FullPath:       fullPath,
-		FileHash:       fileHash,
-		FileSize:       file.Size,
-		MimeType:       file.Header.Get("Content-Type"),
-		Extension:      extension,
-		IsFolder:       false,
-		UploaderID:     uploaderID,
-		CurrentVersion: 1,
-	}
-
-	// 开始事务
-	tx := s.db.Begin()
-	if tx.Error != nil {
-		return nil, tx.Error
-	}
+		ProjectID:        projectID,
+		FileName:         fileName,
+		FilePath:         path,
+		FullPath:         fullPath,
+		FileHash:         fileHash,
+		FileSize:         file.Size,
+		MimeType:         file.Header.Get("Content-Type"),
+		Extension:        extension,
+		IsFolder:         false,
+		UploaderID:       uploaderID,
+		CurrentVersion:   1,
+		ObjectEncryption: objectEnc,
+	}
+
+	// 创建文件及版本记录，并发上传同一路径时只有一个能创建成功；
+	// 记录创建后在同一事务中将临时对象复制到正式对象，路径在事务提交前一直被本次上传占用
+	promoted := false
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		newFile.ID = utils.GenerateFileID()
+		if err := tx.Create(newFile).Error; err != nil {
+			if repository.IsDuplicatedKey(s.db, err) {
+				return fmt.Errorf("%w: %s", ErrFileExists, newFile.FullPath)
+			}
+			return fmt.Errorf("创建文件记录失败: %w", err)
+		}
 
-	// 创建文件记录
-	err = s.fileRepo.Create(ctx, newFile)
-	if err != nil {
-		tx.Rollback()
-		return nil, fmt.Errorf("创建文件记录失败: %w", err)
-	}
+		version := &entity.FileVersion{
+			ID:               utils.GenerateRecordID(),
+			FileID:           newFile.ID,
+			Version:          1,
+			FileHash:         fileHash,
+			FileSize:         file.Size,
+			StorageKey:       objectName,
+			UploaderID:       uploaderID,
+			Comment:          "初始版本",
+			ObjectEncryption: objectEnc,
+		}
+		if err := tx.Create(version).Error; err != nil {
+			return fmt.Errorf("创建版本记录失败: %w", err)
+		}
 
-	// 创建版本记录
-	version := &entity.FileVersion{
-		FileID:     newFile.ID,
-		Version:    1,
-		FileHash:   fileHash,
-		FileSize:   file.Size,
-		UploaderID: uploaderID,
-		Comment:    "初始版本",
+		if err := client.CopyObject(ctx, bucketName, writeObject, objectName, ""); err != nil {
+			return fmt.Errorf("保存上传对象失败: %w", err)
+		}
+		promoted = true
+		return nil
+	})
+	// 临时对象不再需要，删除失败时由临时对象的过期规则清理
+	if rmErr := client.RemoveObject(ctx, bucketName, writeObject); rmErr != nil {
+		log.Printf("删除临时上传对象 %s 失败: %v", writeObject, rmErr)
 	}
-
-	err = s.fileRepo.CreateVersion(ctx, version)
 	if err != nil {
-		tx.Rollback()
-		return nil, fmt.Errorf("创建版本记录失败: %w", err)
-	}
-
-	// 如果不是秒传，则需要上传文件
-	if existingFile == nil {
-		// 在MinIO中创建文件
-		objectName := minio.GetObjectName(projectID, path, fileName)
-		_, err = s.minioClient.UploadFile(ctx, bucketName, objectName, src, file.Size, file.Header.Get("Content-Type"))
-		if err != nil {
-			tx.Rollback()
-			return nil, fmt.Errorf("上传文件失败: %w", err)
+		// 已复制到正式对象而事务提交失败时，路径可能已被其他上传占用，不删除正式对象
+		if promoted {
+			log.Printf("上传 %s 的记录提交失败，正式对象 %s 保留: %v", fullPath, objectName, err)
 		}
+		return nil, err
 	}
 
-	// 提交事务
-	if err := tx.Commit().Error; err != nil {
-		return nil, fmt.Errorf("提交事务失败: %w", err)
-	}
+	// 更新存储统计（投递到统计队列，不阻塞主流程）
+	s.enqueueStats(projectID, 1, file.Size)
+	s.scheduleThumbnail(newFile.ID)
 
-	// 更新存储统计（异步进行，不阻塞主流程）
-	go func() {
-		ctx := context.Background()
-		err := s.UpdateStorageStats(ctx, projectID, file.Size, true)
-		if err != nil {
-			log.Printf("更新存储统计失败: %v", err)
-		}
-	}()
+	s.recordAudit(ctx, uploaderID, entity.OperationUpload, project, newFile)
+	s.notifyWebhook(uploaderID, entity.OperationUpload, newFile, nil)
 
 	return newFile, nil
 }
@@ -321,19 +499,42 @@ func (s *fileService) Download(ctx context.Context, fileID, userID string) (io.R
 		return nil, nil, errors.New("项目不存在")
 	}
 
-	// 4. 从MinIO下载文件
+	// 4. 从文件所在的存储后端下载文件
+	client, err := s.fileStorage(file)
+	if err != nil {
+		return nil, nil, err
+	}
 	objectName := minio.GetObjectName(file.ProjectID, file.FilePath, file.FileName)
 	bucketName := s.sanitizeBucketName(project.Group.GroupKey)
-	fileReader, _, err := s.minioClient.DownloadFile(ctx, bucketName, objectName)
+	fileReader, err := s.openObject(ctx, client, bucketName, objectName, project.GroupID, file.ObjectEncryption)
 	if err != nil {
 		return nil, nil, fmt.Errorf("下载文件失败: %w", err)
 	}
 
+	// 5. 按文件设置添加水印，接收人为当前用户邮箱，匿名下载时为 anonymous
+	if file.WatermarkRequired {
+		var user entity.User
+		recipient := userID
+		if recipient == "" {
+			recipient = "anonymous"
+		}
+		if err := s.db.WithContext(ctx).Select("email").Where("id = ?", userID).First(&user).Error; err == nil {
+			recipient = user.Email
+		}
+		fileReader, file, err = s.applyWatermark(fileReader, file, file.WatermarkText, recipient)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	s.recordAudit(ctx, userID, entity.OperationDownload, project, file)
+
 	return fileReader, file, nil
 }
 
 // ListFiles 获取文件列表
-func (s *fileService) ListFiles(ctx context.Context, projectID string, path string, recursive bool, page, pageSize int) ([]*entity.File, int64, error) {
+// 指定标签时列出项目内所有带该标签的文件，忽略路径；指定分类时只列出该分类的文件；不包含 userID 被拒绝访问的文件
+func (s *fileService) ListFiles(ctx context.Context, projectID, userID string, path, tag, category string, recursive bool, page, pageSize int) ([]*entity.File, int64, error) {
 	// 检查项目是否存在
 	project, err := s.projectRepo.GetByID(ctx, projectID)
 	if err != nil {
@@ -344,7 +545,65 @@ func (s *fileService) ListFiles(ctx context.Context, projectID string, path stri
 	}
 
 	// 获取文件列表
-	return s.fileRepo.List(ctx, projectID, path, recursive, false, page, pageSize)
+	if tag != "" {
+		tag, err = normalizeTagName(tag)
+		if err != nil {
+			return nil, 0, err
+		}
+		return s.fileRepo.Search(ctx, projectID, "", userID, &dto.FileSearchFilters{Tag: tag, Category: category, OrderBy: "updated_at"}, page, pageSize)
+	}
+	if path, err = utils.NormalizeDirPath(path); err != nil {
+		return nil, 0, err
+	}
+	return s.fileRepo.List(ctx, projectID, path, category, userID, recursive, false, page, pageSize)
+}
+
+// ErrInvalidPath 文件路径无效，规范形式见 utils.NormalizeDirPath
+var ErrInvalidPath = utils.ErrInvalidPath
+
+// ErrFolderExists 同一目录下已存在同名文件夹
+var ErrFolderExists = errors.New("同名文件夹已存在")
+
+// ErrFileExists 同一目录下已存在同名文件
+var ErrFileExists = errors.New("同名文件已存在")
+
+// ErrInvalidSearch 搜索条件无效
+var ErrInvalidSearch = errors.New("搜索条件无效")
+
+// SearchFiles 在项目内按文件名关键字与筛选条件搜索文件，默认不包含已删除的文件，不包含 userID 被拒绝访问的文件
+func (s *fileService) SearchFiles(ctx context.Context, projectID, userID, query string, filters *dto.FileSearchFilters, page, pageSize int) ([]*entity.File, int64, error) {
+	if filters.MinSize != nil && filters.MaxSize != nil && *filters.MinSize > *filters.MaxSize {
+		return nil, 0, fmt.Errorf("%w: min_size 不能大于 max_size", ErrInvalidSearch)
+	}
+	if filters.UpdatedFrom != nil && filters.UpdatedTo != nil && filters.UpdatedFrom.After(*filters.UpdatedTo) {
+		return nil, 0, fmt.Errorf("%w: updated_from 不能晚于 updated_to", ErrInvalidSearch)
+	}
+
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if project == nil {
+		return nil, 0, errors.New("项目不存在")
+	}
+
+	query = strings.TrimSpace(query)
+	if filters.Tag != "" {
+		if filters.Tag, err = normalizeTagName(filters.Tag); err != nil {
+			return nil, 0, fmt.Errorf("%w: %v", ErrInvalidSearch, err)
+		}
+	}
+	if filters.Extension != "" && !strings.HasPrefix(filters.Extension, ".") {
+		filters.Extension = "." + filters.Extension
+	}
+	if filters.OrderBy == "" {
+		filters.OrderBy = "updated_at"
+		if query != "" {
+			filters.OrderBy = "relevance"
+		}
+	}
+
+	return s.fileRepo.Search(ctx, projectID, query, userID, filters, page, pageSize)
 }
 
 // CreateFolder 创建文件夹
@@ -357,26 +616,32 @@ func (s *fileService) CreateFolder(ctx context.Context, projectID, userID string
 	if project == nil {
 		return nil, errors.New("项目不存在")
 	}
+	if err := checkProjectWritable(project); err != nil {
+		return nil, err
+	}
 
-	// 确保路径以/结尾
-	if path != "" && !strings.HasSuffix(path, "/") {
-		path = path + "/"
+	// 规范化目录路径
+	if path, err = utils.NormalizeDirPath(path); err != nil {
+		return nil, err
 	}
 
-	// 确保文件夹名称不含/
+	// 文件夹名称允许以/结尾，其余规则同文件名
 	folderName = strings.TrimSuffix(folderName, "/")
-	if strings.Contains(folderName, "/") {
-		return nil, errors.New("文件夹名称不能包含'/'")
+	if err := utils.ValidateName(folderName); err != nil {
+		return nil, fmt.Errorf("文件夹名称无效: %w", err)
 	}
 
 	// 检查文件夹是否已存在
 	fullPath := path + folderName + "/"
-	existingFolder, err := s.fileRepo.GetByPath(ctx, projectID, path, folderName)
+	existing, err := s.findByPath(ctx, project, path, folderName)
 	if err != nil {
 		return nil, fmt.Errorf("检查文件夹是否存在失败: %w", err)
 	}
-	if existingFolder != nil {
-		return nil, errors.New("同名文件夹已存在")
+	if existing != nil {
+		if existing.IsFolder {
+			return nil, ErrFolderExists
+		}
+		return nil, ErrFileExists
 	}
 
 	// 2. 创建文件夹记录
@@ -396,22 +661,33 @@ func (s *fileService) CreateFolder(ctx context.Context, projectID, userID string
 
 	// 3. 在MinIO中创建文件夹
 	objectName := minio.GetObjectName(projectID, path, folderName) + "/"
-	err = s.minioClient.CreateFolder(ctx, project.Group.GroupKey, objectName)
+	bucketName := s.sanitizeBucketName(project.Group.GroupKey)
+	if err := s.ensureBucketExists(ctx, bucketName); err != nil {
+		return nil, fmt.Errorf("存储准备失败: %w", err)
+	}
+	err = s.minioClient.CreateFolder(ctx, bucketName, objectName)
 	if err != nil {
 		return nil, fmt.Errorf("创建文件夹失败: %w", err)
 	}
 
-	// 4. 保存到数据库
+	// 4. 保存到数据库，并发创建同名文件夹时由路径唯一索引保证只有一个成功
 	err = s.fileRepo.Create(ctx, folder)
 	if err != nil {
+		if errors.Is(err, repository.ErrFilePathTaken) {
+			return nil, ErrFolderExists
+		}
 		return nil, fmt.Errorf("保存文件夹记录失败: %w", err)
 	}
 
 	return folder, nil
 }
 
+// ErrFolderNotEmpty 文件夹不为空，未指定连同内容一起删除
+var ErrFolderNotEmpty = errors.New("文件夹不为空")
+
 // DeleteFile 删除文件(软删除)
-func (s *fileService) DeleteFile(ctx context.Context, fileID, userID string) error {
+// 文件夹不为空时，recursive 为 true 才连同其下的全部内容一起删除，否则返回 ErrFolderNotEmpty
+func (s *fileService) DeleteFile(ctx context.Context, fileID, userID string, recursive bool) error {
 	// 1. 获取文件信息
 	file, err := s.fileRepo.GetByID(ctx, fileID)
 	if err != nil {
@@ -425,37 +701,51 @@ func (s *fileService) DeleteFile(ctx context.Context, fileID, userID string) err
 	if file.IsDeleted {
 		return errors.New("文件已被删除")
 	}
+	if err := s.checkFileProjectWritable(ctx, file.ProjectID); err != nil {
+		return err
+	}
+	if file.IsFolder && !recursive {
+		hasChildren, err := s.fileRepo.HasChildren(ctx, file)
+		if err != nil {
+			return fmt.Errorf("检查文件夹内容失败: %w", err)
+		}
+		if hasChildren {
+			return fmt.Errorf("%w，需要指定 recursive=true 连同其中的内容一起删除", ErrFolderNotEmpty)
+		}
+	}
 
 	// 记录文件大小，用于统计更新
 	fileSize := file.FileSize
 	projectID := file.ProjectID
 
-	// 3. 软删除文件
+	// 3. 软删除文件，文件夹连同其下内容在一个事务中删除
+	deletedAt := time.Now()
 	file.IsDeleted = true
-	file.DeletedAt = new(time.Time)
-	*file.DeletedAt = time.Now()
+	file.DeletedAt = &deletedAt
 	file.DeletedBy = &userID
 
-	err = s.fileRepo.Update(ctx, file)
+	countDelta, sizeDelta := int64(1), fileSize
+	if file.IsFolder {
+		countDelta, sizeDelta, err = s.fileRepo.DeleteSubtree(ctx, file, userID, deletedAt)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("文件已被删除")
+		}
+	} else {
+		err = s.fileRepo.Update(ctx, file)
+	}
 	if err != nil {
 		return fmt.Errorf("删除文件失败: %w", err)
 	}
+	s.recordAudit(ctx, userID, entity.OperationDelete, nil, file)
+	s.notifyWebhook(userID, entity.OperationDelete, file, nil)
 
-	// 异步更新存储统计
-	if !file.IsFolder && fileSize > 0 {
-		go func() {
-			ctx := context.Background()
-			err := s.UpdateStorageStats(ctx, projectID, fileSize, false)
-			if err != nil {
-				log.Printf("更新存储统计失败: %v", err)
-			}
-		}()
-	}
+	// 更新存储统计
+	s.enqueueStats(projectID, -countDelta, -sizeDelta)
 
 	return nil
 }
 
-// RestoreFile 恢复文件
+// RestoreFile 恢复文件，恢复文件夹时一并恢复随其删除的内容
 func (s *fileService) RestoreFile(ctx context.Context, fileID, userID string) error {
 	// 1. 获取文件信息
 	file, err := s.fileRepo.GetByID(ctx, fileID)
@@ -470,31 +760,35 @@ func (s *fileService) RestoreFile(ctx context.Context, fileID, userID string) er
 	if !file.IsDeleted {
 		return errors.New("文件未被删除")
 	}
+	if err := s.checkFileProjectWritable(ctx, file.ProjectID); err != nil {
+		return err
+	}
 
 	// 记录文件大小，用于统计更新
 	fileSize := file.FileSize
 	projectID := file.ProjectID
 
-	// 3. 恢复文件
+	// 3. 恢复文件，文件夹连同随其删除的内容在一个事务中恢复
+	countDelta, sizeDelta := int64(1), fileSize
+	if file.IsFolder {
+		countDelta, sizeDelta, err = s.fileRepo.RestoreSubtree(ctx, file)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("文件未被删除")
+		}
+	}
 	file.IsDeleted = false
 	file.DeletedAt = nil
 	file.DeletedBy = nil
-
-	err = s.fileRepo.Update(ctx, file)
+	if !file.IsFolder {
+		err = s.fileRepo.Update(ctx, file)
+	}
 	if err != nil {
 		return fmt.Errorf("恢复文件失败: %w", err)
 	}
+	s.recordAudit(ctx, userID, entity.OperationRestore, nil, file)
 
-	// 异步更新存储统计
-	if !file.IsFolder && fileSize > 0 {
-		go func() {
-			ctx := context.Background()
-			err := s.UpdateStorageStats(ctx, projectID, fileSize, true)
-			if err != nil {
-				log.Printf("更新存储统计失败: %v", err)
-			}
-		}()
-	}
+	// 更新存储统计
+	s.enqueueStats(projectID, countDelta, sizeDelta)
 
 	return nil
 }
@@ -534,90 +828,372 @@ func (s *fileService) GetFileVersion(ctx context.Context, fileID string, version
 	return s.fileRepo.GetVersionByID(ctx, fileID, version)
 }
 
-// generateShareCode 生成分享码
-func generateShareCode() string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	const codeLength = 8
+// 分享码长度默认值与上限，上限受share_code列宽限制
+const (
+	defaultShareCodeMinLength = 8
+	defaultShareCodeMaxLength = 16
+	shareCodeLengthLimit      = 32
+	shareCodeMaxAttempts      = 5
+)
 
-	b := make([]byte, codeLength)
-	_, err := rand.Read(b)
-	if err != nil {
-		return uuid.New().String()[:8] // 如果随机数生成失败，使用UUID
+// shareCodeLengths 获取分享码的最小与最大长度
+func shareCodeLengths() (int, int) {
+	minLength := viper.GetInt("share.code_min_length")
+	if minLength <= 0 {
+		minLength = defaultShareCodeMinLength
+	}
+	maxLength := viper.GetInt("share.code_max_length")
+	if maxLength <= 0 {
+		maxLength = defaultShareCodeMaxLength
 	}
+	if minLength > shareCodeLengthLimit {
+		minLength = shareCodeLengthLimit
+	}
+	if maxLength > shareCodeLengthLimit {
+		maxLength = shareCodeLengthLimit
+	}
+	if maxLength < minLength {
+		maxLength = minLength
+	}
+	return minLength, maxLength
+}
 
-	for i := range b {
-		b[i] = charset[int(b[i])%len(charset)]
+// generateShareCode 生成指定长度的分享码，只包含字母和数字，可直接用于URL
+func generateShareCode(length int) (string, error) {
+	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	// 丢弃超出charset整数倍的随机字节，避免取模偏差
+	const limit = 256 - 256%len(charset)
+
+	b := make([]byte, 0, length)
+	buf := make([]byte, length)
+	for len(b) < length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("生成分享码失败: %w", err)
+		}
+		for _, v := range buf {
+			if int(v) >= limit {
+				continue
+			}
+			b = append(b, charset[int(v)%len(charset)])
+			if len(b) == length {
+				break
+			}
+		}
 	}
 
-	return string(b)
+	return string(b), nil
 }
 
-// CreateShare 创建文件分享
-func (s *fileService) CreateShare(ctx context.Context, fileID, userID string, password string, expireHours, downloadLimit int) (*entity.FileShare, error) {
-	// 1. 获取文件信息
-	file, err := s.fileRepo.GetByID(ctx, fileID)
-	if err != nil {
-		return nil, err
-	}
-	if file == nil {
-		return nil, errors.New("文件不存在")
-	}
+// createShareWithUniqueCode 生成分享码并保存分享记录
+// 分享码冲突时重新生成并重试，每次重试长度加一（不超过最大长度）
+func (s *fileService) createShareWithUniqueCode(ctx context.Context, share *entity.FileShare) error {
+	minLength, maxLength := shareCodeLengths()
+	for attempt := 0; attempt < shareCodeMaxAttempts; attempt++ {
+		length := minLength + attempt
+		if length > maxLength {
+			length = maxLength
+		}
+		code, err := generateShareCode(length)
+		if err != nil {
+			return err
+		}
+		share.ShareCode = code
 
-	// 2. 检查文件是否已被删除
-	if file.IsDeleted {
-		return nil, errors.New("文件已被删除")
+		err = s.fileRepo.CreateShare(ctx, share)
+		if !errors.Is(err, repository.ErrShareCodeTaken) {
+			return err
+		}
+		log.Printf("分享码冲突，重新生成: 长度=%d, 第%d次", length, attempt+1)
 	}
+	return fmt.Errorf("多次生成分享码均发生冲突: %w", repository.ErrShareCodeTaken)
+}
 
-	// 3. 创建分享记录
-	share := &entity.FileShare{
-		FileID:        fileID,
-		UserID:        userID,
-		ShareCode:     generateShareCode(),
-		Password:      password,
-		DownloadLimit: downloadLimit,
-		DownloadCount: 0,
-		CreatedAt:     time.Now(),
-	}
+// ErrWeakSharePassword 分享密码不满足强度要求
+var ErrWeakSharePassword = errors.New("分享密码强度不足")
 
-	// 设置过期时间
-	if expireHours > 0 {
-		expireTime := time.Now().Add(time.Duration(expireHours) * time.Hour)
-		share.ExpireAt = &expireTime
-	}
+// 分享密码默认策略
+const (
+	defaultSharePasswordMinLength = 6
+	generatedSharePasswordLength  = 12
+)
 
-	// 保存分享记录
-	err = s.fileRepo.CreateShare(ctx, share)
-	if err != nil {
-		return nil, fmt.Errorf("创建分享记录失败: %w", err)
+// sharePasswordMinLength 获取分享密码最小长度
+func sharePasswordMinLength() int {
+	minLength := viper.GetInt("share.password_min_length")
+	if minLength <= 0 {
+		minLength = defaultSharePasswordMinLength
 	}
-
-	return share, nil
+	return minLength
 }
 
-// GetShareInfo 获取分享信息
-func (s *fileService) GetShareInfo(ctx context.Context, shareCode string) (*entity.FileShare, error) {
-	// 获取分享记录
-	share, err := s.fileRepo.GetShareByCode(ctx, shareCode)
+// ErrShareNotFound 分享不存在、已过期或已撤销
+// 三种情况返回相同错误，避免暴露分享码是否曾经存在
+var ErrShareNotFound = errors.New("分享不存在或已过期")
+
+// ErrShareNotOwner 只有分享创建者可以撤销分享
+var ErrShareNotOwner = errors.New("只能撤销自己创建的分享")
+
+// ErrShareLimitReached 分享已达到下载次数限制
+var ErrShareLimitReached = errors.New("分享已达到下载次数限制")
+
+// ErrSharePasswordIncorrect 分享访问密码错误
+var ErrSharePasswordIncorrect = errors.New("密码错误")
+
+// ErrSharePolicyViolation 分享设置超出项目分享策略
+var ErrSharePolicyViolation = errors.New("分享设置超出项目限制")
+
+// applySharePolicy 按项目分享策略确定分享有效期（小时）与下载次数，0表示不限制
+// 未指定时使用项目默认值，未设置默认值但设置了上限时取上限
+func applySharePolicy(project *entity.Project, expireHours, downloadLimit *int) (int, int, error) {
+	resolve := func(requested *int, defaultValue, maxValue int, name, unit string) (int, error) {
+		value := defaultValue
+		if value == 0 {
+			value = maxValue
+		}
+		if requested != nil {
+			value = *requested
+		}
+		if value < 0 {
+			return 0, fmt.Errorf("%w: %s不能为负数", ErrSharePolicyViolation, name)
+		}
+		if maxValue > 0 && (value == 0 || value > maxValue) {
+			return 0, fmt.Errorf("%w: %s不能超过%d%s", ErrSharePolicyViolation, name, maxValue, unit)
+		}
+		return value, nil
+	}
+
+	expire, err := resolve(expireHours, project.ShareDefaultExpireHours, project.ShareMaxExpireHours, "分享有效期", "小时")
 	if err != nil {
-		return nil, err
+		return 0, 0, err
 	}
-	if share == nil {
-		return nil, errors.New("分享不存在或已过期")
+	limit, err := resolve(downloadLimit, project.ShareDefaultDownloadLimit, project.ShareMaxDownloadLimit, "下载次数", "次")
+	if err != nil {
+		return 0, 0, err
 	}
+	return expire, limit, nil
+}
 
-	// 检查是否过期
-	if share.ExpireAt != nil && share.ExpireAt.Before(time.Now()) {
-		return nil, errors.New("分享已过期")
+// validateSharePassword 按配置校验分享密码强度，空密码表示不设置密码
+func validateSharePassword(password string) error {
+	if password == "" {
+		return nil
 	}
 
-	// 检查下载次数是否达到限制
-	if share.DownloadLimit > 0 && share.DownloadCount >= share.DownloadLimit {
-		return nil, errors.New("分享已达到下载次数限制")
+	minLength := sharePasswordMinLength()
+	if len([]rune(password)) < minLength {
+		return fmt.Errorf("%w: 长度不能少于%d位", ErrWeakSharePassword, minLength)
 	}
 
+	if viper.GetBool("share.password_require_mixed") {
+		hasLetter, hasDigit := false, false
+		for _, r := range password {
+			switch {
+			case r >= '0' && r <= '9':
+				hasDigit = true
+			case (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+				hasLetter = true
+			}
+		}
+		if !hasLetter || !hasDigit {
+			return fmt.Errorf("%w: 必须同时包含字母和数字", ErrWeakSharePassword)
+		}
+	}
+
+	return nil
+}
+
+// GenerateSharePassword 生成满足强度策略的随机分享密码
+// 每个字符用 crypto/rand.Int 均匀选取，保证至少包含一个字母和一个数字，再随机打乱位置
+func GenerateSharePassword() (string, error) {
+	const letters = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ"
+	const digits = "23456789"
+	const charset = letters + digits
+
+	length := generatedSharePasswordLength
+	if minLength := sharePasswordMinLength(); minLength > length {
+		length = minLength
+	}
+
+	b := make([]byte, length)
+	for i := range b {
+		set := charset
+		switch i {
+		case 0:
+			set = letters
+		case 1:
+			set = digits
+		}
+		idx, err := randomIndex(len(set))
+		if err != nil {
+			return "", fmt.Errorf("生成分享密码失败: %w", err)
+		}
+		b[i] = set[idx]
+	}
+
+	// 打乱顺序，字母和数字不固定在开头
+	for i := len(b) - 1; i > 0; i-- {
+		j, err := randomIndex(i + 1)
+		if err != nil {
+			return "", fmt.Errorf("生成分享密码失败: %w", err)
+		}
+		b[i], b[j] = b[j], b[i]
+	}
+
+	return string(b), nil
+}
+
+// randomIndex 均匀随机地返回 [0, n) 中的整数
+func randomIndex(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(v.Int64()), nil
+}
+
+// CreateShare 创建文件分享
+// expireHours、downloadLimit 为nil时使用项目分享策略的默认值
+func (s *fileService) CreateShare(ctx context.Context, fileID, userID string, password string, expireHours, downloadLimit *int, watermarkReq *dto.ShareWatermarkRequest) (*entity.FileShare, error) {
+	// 1. 获取文件信息
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file == nil {
+		return nil, errors.New("文件不存在")
+	}
+
+	// 2. 检查文件是否已被删除
+	if file.IsDeleted {
+		return nil, errors.New("文件已被删除")
+	}
+
+	// 按项目分享策略确定有效期与下载次数
+	project, err := s.projectRepo.GetByID(ctx, file.ProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("获取项目信息失败: %w", err)
+	}
+	if project == nil {
+		return nil, errors.New("项目不存在")
+	}
+	expire, limit, err := applySharePolicy(project, expireHours, downloadLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. 校验密码强度并生成哈希，仅保存哈希
+	if err := validateSharePassword(password); err != nil {
+		return nil, err
+	}
+	passwordHash := ""
+	if password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("生成分享密码哈希失败: %w", err)
+		}
+		passwordHash = string(hash)
+	}
+
+	// 4. 创建分享记录
+	share := &entity.FileShare{
+		FileID:        fileID,
+		UserID:        userID,
+		Password:      passwordHash,
+		DownloadLimit: limit,
+		DownloadCount: 0,
+		CreatedAt:     time.Now(),
+	}
+
+	// 设置水印
+	if watermarkReq != nil {
+		if !watermark.Supported(file.MimeType) {
+			return nil, ErrWatermarkUnsupported
+		}
+		if err := validateWatermarkTemplate(watermarkReq.Text); err != nil {
+			return nil, err
+		}
+		share.Watermark = true
+		share.WatermarkText = watermarkReq.Text
+		share.Recipient = watermarkReq.Recipient
+	}
+
+	// 设置过期时间
+	if expire > 0 {
+		expireTime := time.Now().Add(time.Duration(expire) * time.Hour)
+		share.ExpireAt = &expireTime
+	}
+
+	// 生成唯一分享码并保存分享记录
+	if err := s.createShareWithUniqueCode(ctx, share); err != nil {
+		return nil, fmt.Errorf("创建分享记录失败: %w", err)
+	}
+
+	s.recordAudit(ctx, userID, entity.OperationShare, project, file)
+	s.notifyWebhook(userID, entity.OperationShare, file, share)
+
 	return share, nil
 }
 
+// GetShareInfo 获取分享信息
+func (s *fileService) GetShareInfo(ctx context.Context, shareCode string) (*entity.FileShare, error) {
+	// 获取分享记录
+	share, err := s.fileRepo.GetShareByCode(ctx, shareCode)
+	if err != nil {
+		return nil, err
+	}
+	if share == nil {
+		return nil, ErrShareNotFound
+	}
+
+	// 检查是否过期
+	if share.ExpireAt != nil && share.ExpireAt.Before(time.Now()) {
+		return nil, ErrShareNotFound
+	}
+
+	// 检查下载次数是否达到限制
+	if share.DownloadLimit > 0 && share.DownloadCount >= share.DownloadLimit {
+		return nil, ErrShareLimitReached
+	}
+
+	return share, nil
+}
+
+// RevokeShare 撤销分享，只有分享创建者可以撤销
+// 撤销后分享记录被删除，再访问该分享码与访问过期分享的结果相同
+func (s *fileService) RevokeShare(ctx context.Context, shareID, userID string) error {
+	share, err := s.fileRepo.GetShareByID(ctx, shareID)
+	if err != nil {
+		return err
+	}
+	if share == nil {
+		return ErrShareNotFound
+	}
+	if share.UserID != userID {
+		return ErrShareNotOwner
+	}
+
+	if err := s.fileRepo.DeleteShare(ctx, share.ID); err != nil {
+		return fmt.Errorf("删除分享记录失败: %w", err)
+	}
+
+	file, err := s.fileRepo.GetByID(ctx, share.FileID)
+	if err != nil {
+		log.Printf("记录撤销分享审计日志失败: %v", err)
+		return nil
+	}
+	s.recordAudit(ctx, userID, entity.OperationCancelShare, nil, file)
+
+	return nil
+}
+
+// ListUserShares 分页获取用户创建的有效分享
+func (s *fileService) ListUserShares(ctx context.Context, userID string, page, pageSize int) ([]*entity.FileShare, int64, error) {
+	if pageSize > 100 {
+		pageSize = 100
+	}
+	return s.fileRepo.ListActiveSharesByUser(ctx, userID, page, pageSize)
+}
+
 // DownloadSharedFile 下载分享文件
 func (s *fileService) DownloadSharedFile(ctx context.Context, shareCode, password string) (io.ReadCloser, *entity.File, error) {
 	// 1. 获取分享信息
@@ -627,8 +1203,10 @@ func (s *fileService) DownloadSharedFile(ctx context.Context, shareCode, passwor
 	}
 
 	// 2. 检查密码
-	if share.Password != "" && share.Password != password {
-		return nil, nil, errors.New("密码错误")
+	if share.Password != "" {
+		if password == "" || bcrypt.CompareHashAndPassword([]byte(share.Password), []byte(password)) != nil {
+			return nil, nil, ErrSharePasswordIncorrect
+		}
 	}
 
 	// 3. 获取文件信息
@@ -654,24 +1232,86 @@ func (s *fileService) DownloadSharedFile(ctx context.Context, shareCode, passwor
 		return nil, nil, errors.New("项目不存在")
 	}
 
-	// 6. 从MinIO下载文件
+	// 6. 占用一次下载次数，计数与限制检查原子完成，避免GetShareInfo检查之后的并发下载超出限制
+	if err := s.fileRepo.UpdateShareDownloadCount(ctx, share.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrShareLimitReached
+		}
+		return nil, nil, fmt.Errorf("更新分享下载次数失败: %w", err)
+	}
+	// 后续步骤失败时文件未输出，归还占用的下载次数
+	release := func() {
+		if err := s.fileRepo.ReleaseShareDownloadCount(ctx, share.ID); err != nil {
+			log.Printf("归还分享 %s 的下载次数失败: %v", share.ID, err)
+		}
+	}
+
+	// 7. 从文件所在的存储后端下载文件
+	client, err := s.fileStorage(file)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
 	objectName := minio.GetObjectName(file.ProjectID, file.FilePath, file.FileName)
 	bucketName := s.sanitizeBucketName(project.Group.GroupKey)
-	fileReader, _, err := s.minioClient.DownloadFile(ctx, bucketName, objectName)
+	fileReader, err := s.openObject(ctx, client, bucketName, objectName, project.GroupID, file.ObjectEncryption)
 	if err != nil {
+		release()
 		return nil, nil, fmt.Errorf("下载文件失败: %w", err)
 	}
 
-	// 7. 更新下载次数
-	err = s.fileRepo.UpdateShareDownloadCount(ctx, share.ID)
-	if err != nil {
-		// 非致命错误，可以忽略
-		log.Printf("更新分享下载次数失败: %v", err)
+	// 8. 按分享或文件设置添加水印，分享设置优先
+	if share.Watermark || file.WatermarkRequired {
+		text := share.WatermarkText
+		if text == "" {
+			text = file.WatermarkText
+		}
+		recipient := share.Recipient
+		if recipient == "" {
+			recipient = "SHARE " + share.ShareCode
+		}
+		fileReader, file, err = s.applyWatermark(fileReader, file, text, recipient)
+		if err != nil {
+			release()
+			return nil, nil, err
+		}
 	}
 
 	return fileReader, file, nil
 }
 
+// SetFileWatermark 设置文件下载时是否强制添加水印
+func (s *fileService) SetFileWatermark(ctx context.Context, fileID string, required bool, text string) (*entity.File, error) {
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file == nil {
+		return nil, errors.New("文件不存在")
+	}
+	if file.IsFolder {
+		return nil, errors.New("文件夹不支持水印")
+	}
+	if err := s.checkFileProjectWritable(ctx, file.ProjectID); err != nil {
+		return nil, err
+	}
+
+	if required && !watermark.Supported(file.MimeType) {
+		return nil, ErrWatermarkUnsupported
+	}
+	if err := validateWatermarkTemplate(text); err != nil {
+		return nil, err
+	}
+
+	file.WatermarkRequired = required
+	file.WatermarkText = text
+	if err := s.fileRepo.Update(ctx, file); err != nil {
+		return nil, fmt.Errorf("更新水印设置失败: %w", err)
+	}
+
+	return file, nil
+}
+
 // GetPublicDownloadURL 获取公共下载URL
 func (s *fileService) GetPublicDownloadURL(ctx context.Context, fileID string) (string, error) {
 	// 1. 获取文件信息
@@ -692,12 +1332,337 @@ func (s *fileService) GetPublicDownloadURL(ctx context.Context, fileID string) (
 		return "", errors.New("项目不存在")
 	}
 
-	// 3. 生成公共下载URL
+	// 加密存储的对象直接从存储读取得到的是密文
+	if file.Encrypted() {
+		return "", ErrEncryptedDirectAccess
+	}
+	// 直接访问存储拿到的是未加水印的原文件
+	if file.WatermarkRequired {
+		return "", ErrWatermarkDirectAccess
+	}
+
+	// 3. 按文件所在的存储后端生成公共下载URL
+	client, err := s.fileStorage(file)
+	if err != nil {
+		return "", err
+	}
 	objectName := minio.GetObjectName(file.ProjectID, file.FilePath, file.FileName)
 	bucketName := s.sanitizeBucketName(project.Group.GroupKey)
-	return s.minioClient.GetPublicDownloadURL(ctx, bucketName, objectName)
+	return client.GetPublicDownloadURL(ctx, bucketName, objectName)
+}
+
+// GetPresignedUploadURL 获取预签名上传URL，客户端可直接向MinIO上传文件
+// 返回上传URL、对象键和过期时间
+func (s *fileService) GetPresignedUploadURL(ctx context.Context, projectID, userID, fileName, path string) (string, string, time.Time, error) {
+	// 1. 获取项目信息
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+	if project == nil {
+		return "", "", time.Time{}, errors.New("项目不存在")
+	}
+	if err := checkProjectWritable(project); err != nil {
+		return "", "", time.Time{}, err
+	}
+	if project.Group.GroupKey == "" {
+		return "", "", time.Time{}, errors.New("项目未关联有效群组")
+	}
+	// 直传的内容不经过服务端，无法加密
+	if project.Group.EncryptionEnabled {
+		return "", "", time.Time{}, fmt.Errorf("%w: 群组已开启加密存储，请通过服务端上传", ErrEncryptedDirectAccess)
+	}
+
+	// 2. 校验文件名
+	if err := utils.ValidateName(fileName); err != nil {
+		return "", "", time.Time{}, err
+	}
+	// 直传时服务端无法检查内容，签发前先按扩展名校验，大小在确认上传时校验
+	if err := checkUploadFileName(resolveUploadPolicy(project), fileName, 0); err != nil {
+		return "", "", time.Time{}, err
+	}
+
+	// 规范化目录路径
+	if path, err = utils.NormalizeDirPath(path); err != nil {
+		return "", "", time.Time{}, err
+	}
+
+	// 忽略大小写时沿用已有文件的名称，使对象键与已有文件一致
+	existing, err := s.findByPath(ctx, project, path, fileName)
+	if err != nil {
+		return "", "", time.Time{}, fmt.Errorf("检查文件路径失败: %w", err)
+	}
+	if existing != nil {
+		if existing.IsFolder {
+			return "", "", time.Time{}, ErrFolderExists
+		}
+		path, fileName = existing.FilePath, existing.FileName
+	}
+
+	// 3. 确保存储桶存在
+	bucketName := s.sanitizeBucketName(project.Group.GroupKey)
+	if err := s.ensureBucketExists(ctx, bucketName); err != nil {
+		return "", "", time.Time{}, fmt.Errorf("存储准备失败: %w", err)
+	}
+
+	// 4. 生成预签名URL，客户端上传到临时对象，确认上传时再复制到按路径生成的对象名，
+	// 覆盖已有文件时不会在确认前改动其当前内容；覆盖已有文件时临时对象位于其所在的存储后端
+	client := s.minioClient
+	if existing != nil {
+		if client, err = s.fileStorage(existing); err != nil {
+			return "", "", time.Time{}, err
+		}
+	}
+	expiry := presignUploadExpiry()
+	objectKey := presignStagingObjectName(projectID, path, fileName)
+	uploadURL, err := client.GeneratePresignedPutURL(ctx, bucketName, objectKey, expiry)
+	if err != nil {
+		return "", "", time.Time{}, err
+	}
+
+	return uploadURL, objectKey, time.Now().Add(expiry), nil
+}
+
+// ConfirmPresignedUpload 确认预签名上传完成，创建文件及版本记录并更新存储统计
+// 文件哈希由服务端读取上传的对象计算，客户端提供的 hash 不为空时须与实际内容一致
+func (s *fileService) ConfirmPresignedUpload(ctx context.Context, projectID, userID, objectKey string, size int64, hash string) (*entity.File, error) {
+	// 1. 获取项目信息
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, errors.New("项目不存在")
+	}
+	if err := checkProjectWritable(project); err != nil {
+		return nil, err
+	}
+
+	// 2. 解析临时对象键，确认属于该项目
+	path, fileName, err := parsePresignStagingObjectName(projectID, objectKey)
+	if err != nil {
+		return nil, err
+	}
+	objectName := minio.GetObjectName(projectID, path, fileName)
+
+	// 3. 同名文件已存在时创建新版本，否则创建新文件；覆盖已有文件时对象上传在其所在的存储后端
+	existingFile, err := s.findByPath(ctx, project, path, fileName)
+	if err != nil {
+		return nil, fmt.Errorf("检查文件路径失败: %w", err)
+	}
+	client := s.minioClient
+	if existingFile != nil && !existingFile.IsFolder && existingFile.FullPath == path+fileName {
+		if client, err = s.fileStorage(existingFile); err != nil {
+			return nil, err
+		}
+	}
+
+	// 4. 校验对象已上传且大小一致
+	bucketName := s.sanitizeBucketName(project.Group.GroupKey)
+	objInfo, err := client.StatObject(ctx, bucketName, objectKey, nil)
+	if err != nil {
+		return nil, fmt.Errorf("上传的对象不存在: %w", err)
+	}
+	if objInfo.Size != size {
+		return nil, fmt.Errorf("文件大小不匹配: 期望 %d，实际 %d", size, objInfo.Size)
+	}
+
+	// 之后无论成功与否临时对象都不再需要，删除失败时由临时对象的过期规则清理
+	defer func() {
+		if rmErr := client.RemoveObject(ctx, bucketName, objectKey); rmErr != nil {
+			log.Printf("删除临时上传对象 %s 失败: %v", objectKey, rmErr)
+		}
+	}()
+
+	if existingFile != nil && (existingFile.IsFolder || existingFile.FullPath != path+fileName) {
+		// 仅大小写不同或与文件夹同名，对象键与已有记录不一致
+		return nil, fmt.Errorf("%w: %s", ErrFileExists, existingFile.FullPath)
+	}
+
+	// 5. 按上传策略检查文件大小
+	if err := checkUploadFileName(resolveUploadPolicy(project), fileName, size); err != nil {
+		return nil, err
+	}
+
+	// 检查存储配额，覆盖上传时只计算大小差值
+	additionalSize := size
+	if existingFile != nil {
+		additionalSize = size - existingFile.FileSize
+	}
+	releaseQuota, err := s.reserveStorage(ctx, project, additionalSize)
+	if err != nil {
+		return nil, err
+	}
+	defer releaseQuota()
+
+	// 6. 读取上传的对象计算哈希，不信任客户端提供的值，避免错误的哈希影响秒传
+	reader, _, err := client.DownloadFile(ctx, bucketName, objectKey)
+	if err != nil {
+		return nil, fmt.Errorf("读取上传的对象失败: %w", err)
+	}
+	fileHash, err := calculateFileHash(reader)
+	reader.Close()
+	if err != nil {
+		return nil, fmt.Errorf("计算文件哈希失败: %w", err)
+	}
+	if declared := strings.ToLower(strings.TrimSpace(hash)); declared != "" && declared != fileHash {
+		return nil, ErrPresignHashMismatch
+	}
+
+	// 7. 覆盖已有文件时先保存当前版本的内容，再用上传的对象替换
+	if existingFile != nil {
+		if err := s.archiveCurrentVersion(ctx, bucketName, existingFile); err != nil {
+			return nil, err
+		}
+		if err := client.CopyObject(ctx, bucketName, objectKey, objectName, ""); err != nil {
+			return nil, fmt.Errorf("保存上传对象失败: %w", err)
+		}
+
+		version := &entity.FileVersion{
+			ID:         utils.GenerateRecordID(),
+			FileID:     existingFile.ID,
+			Version:    existingFile.CurrentVersion + 1,
+			FileHash:   fileHash,
+			FileSize:   size,
+			StorageKey: objectName,
+			UploaderID: userID,
+			Comment:    "更新文件",
+		}
+		sizeDiff := size - existingFile.FileSize
+		err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(version).Error; err != nil {
+				return fmt.Errorf("创建版本记录失败: %w", err)
+			}
+			existingFile.FileHash = fileHash
+			existingFile.FileSize = size
+			existingFile.ObjectEncryption = entity.ObjectEncryption{} // 直传的对象未加密
+			existingFile.CurrentVersion = version.Version
+			existingFile.UpdatedAt = time.Now()
+			if err := tx.Save(existingFile).Error; err != nil {
+				return fmt.Errorf("更新文件记录失败: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		s.enqueueStats(projectID, 0, sizeDiff)
+		s.schedulePruneVersions(existingFile.ID)
+		s.scheduleThumbnail(existingFile.ID)
+
+		s.recordAudit(ctx, userID, entity.OperationUpload, project, existingFile)
+		s.notifyWebhook(userID, entity.OperationUpload, existingFile, nil)
+
+		return existingFile, nil
+	}
+
+	// 8. 创建文件及版本记录，记录占用路径后在同一事务中将临时对象复制到正式对象
+	newFile := &entity.File{
+		ProjectID:      projectID,
+		FileName:       fileName,
+		FilePath:       path,
+		FullPath:       path + fileName,
+		FileHash:       fileHash,
+		FileSize:       size,
+		MimeType:       objInfo.ContentType,
+		Extension:      filepath.Ext(fileName),
+		IsFolder:       false,
+		UploaderID:     userID,
+		CurrentVersion: 1,
+	}
+	promoted := false
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		newFile.ID = utils.GenerateFileID()
+		if err := tx.Create(newFile).Error; err != nil {
+			if repository.IsDuplicatedKey(s.db, err) {
+				return fmt.Errorf("%w: %s", ErrFileExists, newFile.FullPath)
+			}
+			return fmt.Errorf("创建文件记录失败: %w", err)
+		}
+
+		version := &entity.FileVersion{
+			ID:         utils.GenerateRecordID(),
+			FileID:     newFile.ID,
+			Version:    1,
+			FileHash:   fileHash,
+			FileSize:   size,
+			StorageKey: objectName,
+			UploaderID: userID,
+			Comment:    "初始版本",
+		}
+		if err := tx.Create(version).Error; err != nil {
+			return fmt.Errorf("创建版本记录失败: %w", err)
+		}
+
+		if err := client.CopyObject(ctx, bucketName, objectKey, objectName, ""); err != nil {
+			return fmt.Errorf("保存上传对象失败: %w", err)
+		}
+		promoted = true
+		return nil
+	})
+	if err != nil {
+		// 已复制到正式对象而事务提交失败时，路径可能已被其他上传占用，不删除正式对象
+		if promoted {
+			log.Printf("确认上传 %s 的记录提交失败，正式对象 %s 保留: %v", newFile.FullPath, objectName, err)
+		}
+		return nil, err
+	}
+
+	// 更新存储统计（投递到统计队列，不阻塞主流程）
+	s.enqueueStats(projectID, 1, size)
+	s.scheduleThumbnail(newFile.ID)
+
+	s.recordAudit(ctx, userID, entity.OperationUpload, project, newFile)
+	s.notifyWebhook(userID, entity.OperationUpload, newFile, nil)
+
+	return newFile, nil
+}
+
+// presignStagingPrefix 预签名直传临时对象的前缀
+const presignStagingPrefix = TempObjectPrefix + "presign/"
+
+// ErrPresignHashMismatch 确认上传时提供的哈希与上传的对象内容不一致
+var ErrPresignHashMismatch = errors.New("file_hash 与上传的对象内容不一致")
+
+// presignStagingObjectName 生成预签名直传的临时对象名，包含随机部分和目标对象名，确认上传时据此还原目标路径
+func presignStagingObjectName(projectID, path, fileName string) string {
+	return presignStagingPrefix + utils.GenerateUUID() + "/" + minio.GetObjectName(projectID, path, fileName)
+}
+
+// parsePresignStagingObjectName 从预签名直传的临时对象名解析目标目录和文件名，对象不属于该项目时返回错误
+func parsePresignStagingObjectName(projectID, objectKey string) (string, string, error) {
+	mismatch := errors.New("对象键与项目不匹配")
+	rest, ok := strings.CutPrefix(objectKey, presignStagingPrefix)
+	if !ok || strings.Contains(objectKey, "..") {
+		return "", "", mismatch
+	}
+	_, target, ok := strings.Cut(rest, "/")
+	if !ok {
+		return "", "", mismatch
+	}
+	relative, ok := strings.CutPrefix(target, fmt.Sprintf("project_%s/", projectID))
+	if !ok || relative == "" {
+		return "", "", mismatch
+	}
+	path := ""
+	if dir := filepath.ToSlash(filepath.Dir(relative)); dir != "." {
+		path = dir + "/"
+	}
+	return path, filepath.Base(relative), nil
+}
+
+// presignUploadExpiry 获取预签名上传URL有效期，默认15分钟
+func presignUploadExpiry() time.Duration {
+	minutes := viper.GetInt("storage.presign_expire_minutes")
+	if minutes <= 0 {
+		minutes = 15
+	}
+	return time.Duration(minutes) * time.Minute
 }
 
+// CheckFilePermission 检查用户能否对文件执行指定操作，文件或其上级文件夹拒绝该用户访问时直接返回 false；
+// 否则按离文件最近的文件夹授权检查，没有授权时按项目角色检查
 func (s *fileService) CheckFilePermission(ctx context.Context, fileID, userID string, requiredAction string) (bool, error) {
 	// 1. 获取文件信息
 	file, err := s.fileRepo.GetByID(ctx, fileID)
@@ -708,6 +1673,15 @@ func (s *fileService) CheckFilePermission(ctx context.Context, fileID, userID st
 		return false, errors.New("文件不存在")
 	}
 
+	// 访问拒绝规则优先于角色授予的权限
+	denied, err := s.fileRepo.IsDenied(ctx, file, userID)
+	if err != nil {
+		return false, fmt.Errorf("检查访问拒绝规则失败: %w", err)
+	}
+	if denied {
+		return false, nil
+	}
+
 	// 2. 获取项目信息
 	project, err := s.projectRepo.GetByID(ctx, file.ProjectID)
 	if err != nil {
@@ -717,17 +1691,22 @@ func (s *fileService) CheckFilePermission(ctx context.Context, fileID, userID st
 		return false, errors.New("项目不存在")
 	}
 
-	// 3. 检查用户是否拥有执行所需操作的权限
-	projectDomain := fmt.Sprintf("project:%s", file.ProjectID)
-	return s.authService.CanUserAccessResource(ctx, userID, "files", requiredAction, projectDomain)
+	// 3. 检查用户是否拥有执行所需操作的权限，上级文件夹上的授权优先于项目角色
+	return s.checkPathPermission(ctx, file.ProjectID, file.FullPath, userID, requiredAction)
 }
 
-// ensureBucketExists 确保存储桶存在
+// ensureBucketExists 确保存储桶存在，新建存储桶时设置临时对象的过期规则
 func (s *fileService) ensureBucketExists(ctx context.Context, bucketName string) error {
 	// bucketName应该已经通过sanitizeBucketName函数处理过了
 
+	// 存储桶已存在时直接返回
+	exists, err := s.minioClient.BucketExists(ctx, bucketName)
+	if err == nil && exists {
+		return nil
+	}
+
 	// 检查并创建存储桶
-	err := s.minioClient.CreateBucketIfNotExists(ctx, bucketName)
+	err = s.minioClient.CreateBucketIfNotExists(ctx, bucketName)
 	if err != nil {
 		log.Printf("确保存储桶 %s 存在时发生错误: %v", bucketName, err)
 		// 检查是否是网络问题
@@ -740,29 +1719,17 @@ func (s *fileService) ensureBucketExists(ctx context.Context, bucketName string)
 		}
 		return fmt.Errorf("创建MinIO存储桶失败: %w", err)
 	}
+
+	// 生命周期规则设置失败不影响使用，可通过管理接口重新设置
+	if err := s.applyTempLifecycle(ctx, bucketName); err != nil {
+		log.Printf("设置存储桶 %s 的生命周期规则失败: %v", bucketName, err)
+	}
 	return nil
 }
 
 // sanitizeBucketName 规范化桶名称，使其符合S3规范
 func (s *fileService) sanitizeBucketName(key string) string {
-	// 生成符合S3规范的桶名称：只能包含小写字母、数字和连字符
-	// 1. 将所有字符转为小写
-	lowerKey := strings.ToLower(key)
-	// 2. 替换所有非法字符为连字符
-	reg := regexp.MustCompile(`[^a-z0-9\-]`)
-	sanitizedKey := reg.ReplaceAllString(lowerKey, "-")
-	// 3. 确保不以连字符开头或结尾
-	sanitizedKey = strings.Trim(sanitizedKey, "-")
-	// 4. 如果长度不足，添加前缀
-	if len(sanitizedKey) < 3 {
-		sanitizedKey = fmt.Sprintf("grp-%s", sanitizedKey)
-	}
-	// 5. 如果长度过长，截断
-	if len(sanitizedKey) > 60 {
-		sanitizedKey = sanitizedKey[:60]
-	}
-
-	return fmt.Sprintf("group-%s", sanitizedKey)
+	return groupBucketName(key)
 }
 
 // calculateFileHash 计算文件哈希
@@ -774,74 +1741,262 @@ func calculateFileHash(reader io.Reader) (string, error) {
 	return hex.EncodeToString(hash.Sum(nil)), nil
 }
 
-// UpdateStorageStats 更新存储统计
-func (s *fileService) UpdateStorageStats(ctx context.Context, projectID string, fileSize int64, isAdd bool) error {
-	today := time.Now().Truncate(24 * time.Hour)
+// uploadWithHash 上传对象并在同一次读取中计算内容的 SHA256，群组开启加密存储时加密后写入，返回对象的加密信息
+func (s *fileService) uploadWithHash(ctx context.Context, client *minio.Client, bucketName, objectName string, group *entity.Group, reader io.Reader, size int64, contentType string) (string, entity.ObjectEncryption, error) {
+	hash := sha256.New()
+	body, objectSize, enc, err := sealForGroup(group, io.TeeReader(reader, hash), size)
+	if err != nil {
+		return "", enc, err
+	}
+	if _, err := client.UploadFile(ctx, bucketName, objectName, body, objectSize, contentType); err != nil {
+		return "", enc, err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), enc, nil
+}
 
-	// 事务操作
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		// 先获取项目信息
-		project, err := s.projectRepo.GetByID(ctx, projectID)
+// stagingObjectName 生成新文件上传时使用的临时对象名，位于临时对象前缀下，未清理的对象按过期规则自动删除
+func stagingObjectName() string {
+	return TempObjectPrefix + "uploads/" + utils.GenerateUUID()
+}
+
+// copyDedupObject 秒传时将内容相同的已有文件的对象复制到新对象名，文件下载按自身路径读取对象
+// 已有文件不在同一存储后端和存储桶、群组开启加密存储而已有对象未加密或复制失败时返回false
+func (s *fileService) copyDedupObject(ctx context.Context, group *entity.Group, backend, bucketName, objectName string, existing *entity.File) bool {
+	if existing.StorageBackend != backend {
+		return false
+	}
+	if group.EncryptionEnabled && !existing.Encrypted() {
+		return false
+	}
+	client, err := s.storageClient(backend)
+	if err != nil {
+		return false
+	}
+	sourceProject, err := s.projectRepo.GetByID(ctx, existing.ProjectID)
+	if err != nil || sourceProject == nil || s.sanitizeBucketName(sourceProject.Group.GroupKey) != bucketName {
+		return false
+	}
+	sourceObject := minio.GetObjectName(existing.ProjectID, existing.FilePath, existing.FileName)
+	if sourceObject == objectName {
+		return true
+	}
+	if err := client.CopyObject(ctx, bucketName, sourceObject, objectName, ""); err != nil {
+		log.Printf("秒传复制对象 %s 失败，改为上传: %v", sourceObject, err)
+		return false
+	}
+	return true
+}
+
+// isSHA256Hex 判断是否为64位十六进制的 SHA256 值
+func isSHA256Hex(value string) bool {
+	if len(value) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
+
+// ErrInvalidContentHash 客户端声明的文件哈希格式错误
+var ErrInvalidContentHash = errors.New("X-Content-SHA256 格式错误，应为64位十六进制字符串")
+
+// ErrContentHashMismatch 文件内容与客户端声明的哈希不一致
+var ErrContentHashMismatch = errors.New("文件内容与 X-Content-SHA256 不一致")
+
+// ErrProjectQuotaExceeded 项目存储配额不足
+var ErrProjectQuotaExceeded = errors.New("项目存储配额不足")
+
+// ErrGroupQuotaExceeded 群组存储配额不足
+var ErrGroupQuotaExceeded = errors.New("群组存储配额不足")
+
+// reserveStorage 预占写入 additionalSize 字节所需的项目与群组配额，配额为0表示不限制
+// 判断与预占由一条条件更新完成，并发写入不会一起超出配额；成功时返回的 release 须在写入提交或失败后调用，
+// 提交后文件记录已计入用量，此时释放预占不会少算
+func (s *fileService) reserveStorage(ctx context.Context, project *entity.Project, additionalSize int64) (func(), error) {
+	var releases []func()
+	release := func() {
+		for _, r := range releases {
+			r()
+		}
+	}
+	if additionalSize <= 0 {
+		return release, nil
+	}
+
+	// 项目配额
+	if project.StorageQuota > 0 {
+		ok, err := s.statRepo.ReserveProjectStorage(ctx, project.ID, additionalSize)
 		if err != nil {
-			return fmt.Errorf("获取项目信息失败: %w", err)
+			return nil, fmt.Errorf("预占项目存储配额失败: %w", err)
 		}
-		if project == nil {
-			return errors.New("项目不存在")
+		if !ok {
+			used, err := s.projectStorageUsed(ctx, project)
+			if err != nil {
+				return nil, err
+			}
+			s.notifyQuotaExceeded(ctx, "项目", project.ID, project.Name, project.CreatorID, used, project.StorageQuota)
+			return nil, fmt.Errorf("%w: 已用 %d 字节，配额 %d 字节，本次需要 %d 字节", ErrProjectQuotaExceeded, used, project.StorageQuota, additionalSize)
 		}
-
-		// 查找今日统计记录
-		var stat entity.StorageStat
-		result := tx.Where("project_id = ? AND stat_date = ?", projectID, today).First(&stat)
-
-		if result.Error != nil {
-			if !errors.Is(result.Error, gorm.ErrRecordNotFound) {
-				return fmt.Errorf("查询存储统计失败: %w", result.Error)
+		releases = append(releases, func() {
+			// 请求可能已取消，释放不受其影响
+			if err := s.statRepo.ReleaseProjectStorage(context.Background(), project.ID, additionalSize); err != nil {
+				log.Printf("释放项目 %s 预占的存储配额失败: %v", project.ID, err)
 			}
+		})
+	}
 
-			// 记录不存在，创建新记录
-			// 计算当前文件数和大小
-			fileCount, totalSize, err := s.statRepo.GetProjectTotalStats(ctx, projectID)
+	// 群组配额
+	if project.Group.StorageQuota > 0 {
+		ok, err := s.statRepo.ReserveGroupStorage(ctx, project.GroupID, additionalSize)
+		if err != nil {
+			release()
+			return nil, fmt.Errorf("预占群组存储配额失败: %w", err)
+		}
+		if !ok {
+			release()
+			used, err := s.groupStorageUsed(ctx, project)
 			if err != nil {
-				return fmt.Errorf("计算项目统计失败: %w", err)
+				return nil, err
 			}
-
-			// 创建今天的统计记录
-			var increaseValue int64 = 0
-			if isAdd {
-				increaseValue = fileSize
+			s.notifyQuotaExceeded(ctx, "群组", project.GroupID, project.Group.Name, project.Group.CreatorID, used, project.Group.StorageQuota)
+			return nil, fmt.Errorf("%w: 已用 %d 字节，配额 %d 字节，本次需要 %d 字节", ErrGroupQuotaExceeded, used, project.Group.StorageQuota, additionalSize)
+		}
+		releases = append(releases, func() {
+			if err := s.statRepo.ReleaseGroupStorage(context.Background(), project.GroupID, additionalSize); err != nil {
+				log.Printf("释放群组 %s 预占的存储配额失败: %v", project.GroupID, err)
 			}
+		})
+	}
 
-			stat = entity.StorageStat{
-				ID:           utils.GenerateRecordID(),
-				GroupID:      project.GroupID,
-				ProjectID:    projectID,
-				StatDate:     today,
-				FileCount:    fileCount,
-				TotalSize:    totalSize,
-				IncreaseSize: increaseValue, // 如果是添加文件，则增加增量
-				CreatedAt:    time.Now(),
-			}
+	return release, nil
+}
 
-			return tx.Create(&stat).Error
-		}
+// projectStorageUsed 获取项目已用存储量
+func (s *fileService) projectStorageUsed(ctx context.Context, project *entity.Project) (int64, error) {
+	_, used, err := s.statRepo.GetProjectTotalStats(ctx, project.ID)
+	if err != nil {
+		return 0, fmt.Errorf("获取项目存储用量失败: %w", err)
+	}
+	return used, nil
+}
 
-		// 更新已有记录
-		updates := map[string]interface{}{}
+// groupStorageUsed 获取项目所属群组已用存储量，汇总群组下所有项目的用量
+func (s *fileService) groupStorageUsed(ctx context.Context, project *entity.Project) (int64, error) {
+	projects, err := s.projectRepo.GetByGroupID(ctx, project.GroupID)
+	if err != nil {
+		return 0, fmt.Errorf("获取群组项目失败: %w", err)
+	}
+	var used int64
+	for _, p := range projects {
+		_, size, err := s.statRepo.GetProjectTotalStats(ctx, p.ID)
+		if err != nil {
+			return 0, fmt.Errorf("获取群组存储用量失败: %w", err)
+		}
+		used += size
+	}
+	return used, nil
+}
 
-		if isAdd {
-			updates["file_count"] = gorm.Expr("file_count + ?", 1)
-			updates["total_size"] = gorm.Expr("total_size + ?", fileSize)
-			updates["increase_size"] = gorm.Expr("increase_size + ?", fileSize)
-		} else {
-			updates["file_count"] = gorm.Expr("file_count - ?", 1)
-			updates["total_size"] = gorm.Expr("total_size - ?", fileSize)
-			// 不减少 increase_size，因为它表示的是一段时间内的增量
+// quotaHeadroom 根据配额与用量计算剩余空间，配额为0时剩余为-1表示不限制
+func quotaHeadroom(quota, used int64) dto.QuotaHeadroom {
+	headroom := dto.QuotaHeadroom{Quota: quota, Used: used, Remaining: -1}
+	if quota > 0 {
+		headroom.Remaining = quota - used
+		if headroom.Remaining < 0 {
+			headroom.Remaining = 0
 		}
+	}
+	return headroom
+}
 
-		return tx.Model(&entity.StorageStat{}).
-			Where("id = ?", stat.ID).
-			Updates(updates).Error
-	})
+// GetUploadConfig 获取项目的上传配置：生效的上传策略以及项目、群组两级配额的剩余空间
+func (s *fileService) GetUploadConfig(ctx context.Context, projectID string) (*dto.UploadConfigResponse, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, errors.New("项目不存在")
+	}
+
+	projectUsed, err := s.projectStorageUsed(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	groupUsed, err := s.groupStorageUsed(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dto.UploadConfigResponse{
+		ProjectID:    project.ID,
+		UploadPolicy: resolveUploadPolicy(project),
+		ProjectQuota: quotaHeadroom(project.StorageQuota, projectUsed),
+		GroupQuota:   quotaHeadroom(project.Group.StorageQuota, groupUsed),
+	}
+	// 可上传的空间取两级剩余空间中较小的一个
+	response.Remaining = response.ProjectQuota.Remaining
+	if groupRemaining := response.GroupQuota.Remaining; groupRemaining >= 0 && (response.Remaining < 0 || groupRemaining < response.Remaining) {
+		response.Remaining = groupRemaining
+	}
+	return response, nil
+}
+
+// quotaAlertSent 记录各项目/群组最近一次发送配额告警的时间，避免每次被拒绝的上传都发送邮件
+var quotaAlertSent sync.Map
+
+// notifyQuotaExceeded 上传因超出配额被拒绝时向项目或群组创建者发送告警邮件
+// 同一对象在 mail.quota_alert_interval_minutes（默认60分钟）内只告警一次
+func (s *fileService) notifyQuotaExceeded(ctx context.Context, scope, targetID, name, ownerID string, used, quota int64) {
+	if s.mailer == nil {
+		return
+	}
+
+	interval := time.Duration(viper.GetInt("mail.quota_alert_interval_minutes")) * time.Minute
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	now := time.Now()
+	if last, ok := quotaAlertSent.Load(targetID); ok && now.Sub(last.(time.Time)) < interval {
+		return
+	}
+	quotaAlertSent.Store(targetID, now)
+
+	var owner entity.User
+	if err := s.db.WithContext(ctx).Select("email").Where("id = ?", ownerID).First(&owner).Error; err != nil {
+		log.Printf("获取配额告警收件人失败: %v", err)
+		return
+	}
+
+	if err := SendMail(ctx, s.mailer, MailTypeQuotaAlert, owner.Email, map[string]interface{}{
+		"Scope": scope,
+		"Name":  name,
+		"Used":  used,
+		"Quota": quota,
+	}); err != nil {
+		log.Printf("发送配额告警邮件失败: %v", err)
+	}
+}
+
+// UpdateStorageStats 同步更新存储统计，文件数按 isAdd 增减1
+func (s *fileService) UpdateStorageStats(ctx context.Context, projectID string, fileSize int64, isAdd bool) error {
+	if isAdd {
+		return applyStorageStatDelta(ctx, s.statRepo, s.projectRepo, projectID, fileSize)
+	}
+	return applyStorageStatDelta(ctx, s.statRepo, s.projectRepo, projectID, -fileSize)
+}
+
+// enqueueStats 投递存储统计变更，未配置队列时同步写入
+func (s *fileService) enqueueStats(projectID string, countDelta, sizeDelta int64) {
+	if s.statQueue != nil {
+		s.statQueue.Enqueue(projectID, countDelta, sizeDelta)
+		return
+	}
+	if countDelta == 0 && sizeDelta == 0 {
+		return
+	}
+	if err := applyStorageStatDelta(context.Background(), s.statRepo, s.projectRepo, projectID, sizeDelta); err != nil {
+		log.Printf("更新存储统计失败: %v", err)
+	}
 }
 
 // RecalculateProjectStats 重新计算项目统计
@@ -923,8 +2078,13 @@ func (s *fileService) VerifyAllProjectsStats(ctx context.Context) error {
 		return fmt.Errorf("获取项目列表失败: %w", err)
 	}
 
-	// 逐个重新计算项目统计
-	for _, project := range projects {
+	// 逐个重新计算项目统计，每个项目独立事务，取消时已完成的项目保持一致
+	for i, project := range projects {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ReportJobProgress(ctx, i, len(projects))
+
 		err := s.RecalculateProjectStats(ctx, project.ID)
 		if err != nil {
 			log.Printf("重新计算项目 %s 统计失败: %v", project.ID, err)
@@ -932,6 +2092,174 @@ func (s *fileService) VerifyAllProjectsStats(ctx context.Context) error {
 			continue
 		}
 	}
+	ReportJobProgress(ctx, len(projects), len(projects))
 
 	return nil
 }
+
+// findByPath 按项目的大小写设置查找同一位置的文件或文件夹
+func (s *fileService) findByPath(ctx context.Context, project *entity.Project, path, name string) (*entity.File, error) {
+	return s.fileRepo.FindByPath(ctx, project.ID, path, name, !project.CaseInsensitivePaths)
+}
+
+// ListFileAuditLogs 分页获取文件的审计日志
+func (s *fileService) ListFileAuditLogs(ctx context.Context, fileID string, page, pageSize int) ([]*entity.Log, int64, error) {
+	if pageSize > 100 {
+		pageSize = 100
+	}
+	return s.auditRepo.ListByFile(ctx, fileID, page, pageSize)
+}
+
+// recordAudit 记录文件操作审计日志，失败只打印日志，不影响主流程
+// project 为空时按文件所属项目查询
+func (s *fileService) recordAudit(ctx context.Context, userID, operation string, project *entity.Project, file *entity.File) {
+	// 匿名读取公开项目时没有可关联的用户，不记录审计日志
+	if file == nil || userID == "" {
+		return
+	}
+
+	if project == nil {
+		p, err := s.projectRepo.GetByID(ctx, file.ProjectID)
+		if err != nil || p == nil {
+			log.Printf("记录审计日志失败: 获取项目信息失败: %v", err)
+			return
+		}
+		project = p
+	}
+
+	fileID := file.ID
+	entry := &entity.Log{
+		UserID:    userID,
+		GroupID:   project.GroupID,
+		ProjectID: project.ID,
+		FileID:    &fileID,
+		Operation: operation,
+		Status:    200,
+	}
+
+	// 控制器传入的是 *gin.Context，可从中取得客户端信息
+	if c, ok := ctx.(interface{ ClientIP() string }); ok {
+		entry.IPAddress = c.ClientIP()
+	}
+	if c, ok := ctx.(interface{ GetHeader(key string) string }); ok {
+		entry.UserAgent = c.GetHeader("User-Agent")
+	}
+
+	if err := s.auditRepo.Create(ctx, entry); err != nil {
+		log.Printf("记录审计日志失败: %v", err)
+	}
+}
+
+// notifyWebhook 向项目Webhook投递文件事件，不等待推送结果
+func (s *fileService) notifyWebhook(userID, event string, file *entity.File, share *entity.FileShare) {
+	if s.webhooks == nil || file == nil {
+		return
+	}
+	payload := &dto.WebhookPayload{
+		Event:      event,
+		OccurredAt: time.Now(),
+		ProjectID:  file.ProjectID,
+		UserID:     userID,
+		File: dto.WebhookFile{
+			ID:       file.ID,
+			FileName: file.FileName,
+			FullPath: file.FullPath,
+			FileSize: file.FileSize,
+			FileHash: file.FileHash,
+			MimeType: file.MimeType,
+			IsFolder: file.IsFolder,
+			Version:  file.CurrentVersion,
+		},
+	}
+	if share != nil {
+		payload.Share = &dto.WebhookShare{
+			ID:            share.ID,
+			ExpireAt:      share.ExpireAt,
+			DownloadLimit: share.DownloadLimit,
+		}
+	}
+	s.webhooks.Publish(file.ProjectID, payload)
+}
+
+// ErrWatermarkUnsupported 文件类型不支持水印
+var ErrWatermarkUnsupported = errors.New("该文件类型暂不支持水印，目前仅支持PNG、JPEG图片和PDF文档")
+
+// ErrWatermarkDirectAccess 下载时需要添加水印的文件不能由客户端直接从存储读取
+var ErrWatermarkDirectAccess = errors.New("该文件下载时需要添加水印，不支持直接访问存储")
+
+// watermarkTemplateData 水印模板变量
+type watermarkTemplateData struct {
+	Recipient string // 接收人
+	Date      string // 下载日期
+	FileName  string // 文件名
+}
+
+// defaultWatermarkTemplate 默认水印模板
+func defaultWatermarkTemplate() string {
+	text := viper.GetString("watermark.default_text")
+	if text == "" {
+		text = "CONFIDENTIAL {{.Recipient}} {{.Date}}"
+	}
+	return text
+}
+
+// validateWatermarkTemplate 校验水印模板语法
+func validateWatermarkTemplate(text string) error {
+	if text == "" {
+		return nil
+	}
+	if _, err := template.New("watermark").Parse(text); err != nil {
+		return fmt.Errorf("水印模板格式错误: %w", err)
+	}
+	return nil
+}
+
+// renderWatermarkText 渲染水印文本
+func renderWatermarkText(text, recipient, fileName string) (string, error) {
+	if text == "" {
+		text = defaultWatermarkTemplate()
+	}
+
+	tmpl, err := template.New("watermark").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("水印模板格式错误: %w", err)
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, watermarkTemplateData{
+		Recipient: recipient,
+		Date:      time.Now().Format("2006-01-02"),
+		FileName:  fileName,
+	})
+	if err != nil {
+		return "", fmt.Errorf("渲染水印模板失败: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// applyWatermark 对下载流实时添加水印，存储中的原文件保持不变
+// 返回的文件信息为副本，FileSize为加水印后的大小
+func (s *fileService) applyWatermark(reader io.ReadCloser, file *entity.File, text, recipient string) (io.ReadCloser, *entity.File, error) {
+	defer reader.Close()
+
+	if !watermark.Supported(file.MimeType) {
+		return nil, nil, ErrWatermarkUnsupported
+	}
+
+	rendered, err := renderWatermarkText(text, recipient, file.FileName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, err := watermark.Apply(reader, file.MimeType, rendered)
+	if errors.Is(err, watermark.ErrUnsupportedType) {
+		return nil, nil, fmt.Errorf("%w: %v", ErrWatermarkUnsupported, err)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("添加水印失败: %w", err)
+	}
+
+	watermarked := *file
+	watermarked.FileSize = int64(len(data))
+	return io.NopCloser(bytes.NewReader(data)), &watermarked, nil
+}
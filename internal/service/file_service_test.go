@@ -0,0 +1,353 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"mime/multipart"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+	"oss-backend/pkg/minio"
+)
+
+// testFileRepo 文件仓库的测试实现，按ID、哈希和路径查找文件时查询测试数据库，用户对所有路径都有管理员授权，其余方法未实现
+// afterFind 不为空时在按路径查找后调用，用于在重名检查与写入之间插入并发操作
+type testFileRepo struct {
+	repository.FileRepository
+	db        *gorm.DB
+	afterFind func(path, fileName string)
+}
+
+func (r *testFileRepo) GetByID(ctx context.Context, id string) (*entity.File, error) {
+	var files []*entity.File
+	if err := r.db.WithContext(ctx).Where("id = ?", id).Find(&files).Error; err != nil || len(files) == 0 {
+		return nil, err
+	}
+	return files[0], nil
+}
+
+func (r *testFileRepo) GetByHash(ctx context.Context, hash string) (*entity.File, error) {
+	var files []*entity.File
+	if err := r.db.WithContext(ctx).Where("file_hash = ? AND is_deleted = ?", hash, false).Find(&files).Error; err != nil || len(files) == 0 {
+		return nil, err
+	}
+	return files[0], nil
+}
+
+func (r *testFileRepo) FindByPath(ctx context.Context, projectID, path, fileName string, caseSensitive bool) (*entity.File, error) {
+	var files []*entity.File
+	err := r.db.WithContext(ctx).
+		Where("project_id = ? AND file_path = ? AND file_name = ? AND is_deleted = ?", projectID, path, fileName, false).
+		Find(&files).Error
+	if r.afterFind != nil {
+		r.afterFind(path, fileName)
+	}
+	if err != nil || len(files) == 0 {
+		return nil, err
+	}
+	return files[0], nil
+}
+
+func (r *testFileRepo) IsDenied(ctx context.Context, file *entity.File, userID string) (bool, error) {
+	return false, nil
+}
+
+func (r *testFileRepo) GetNearestFolderPermission(ctx context.Context, projectID, userID, fullPath string) (*entity.FolderPermission, error) {
+	return &entity.FolderPermission{Role: ProjectRoleAdmin}, nil
+}
+
+// testProjectRepo 只包含一个项目的项目仓库
+type testProjectRepo struct {
+	repository.ProjectRepository
+	project *entity.Project
+}
+
+func (r *testProjectRepo) GetByID(ctx context.Context, id string) (*entity.Project, error) {
+	if id != r.project.ID {
+		return nil, nil
+	}
+	project := *r.project
+	return &project, nil
+}
+
+// testAuditRepo 丢弃审计日志的审计仓库
+type testAuditRepo struct {
+	repository.AuditRepository
+}
+
+func (testAuditRepo) Create(ctx context.Context, log *entity.Log) error {
+	return nil
+}
+
+// newTestFileService 创建连接测试数据库和内存对象存储的文件服务，项目所属群组的存储桶已创建
+func newTestFileService(t *testing.T, fileRepo repository.FileRepository, project *entity.Project) (*fileService, *fakeObjectStore, *gorm.DB) {
+	t.Helper()
+	db := newTestDB(t, &entity.File{}, &entity.FileVersion{})
+	store, client := newFakeObjectStore(t)
+	store.buckets[groupBucketName(project.Group.GroupKey)] = true
+	if repo, ok := fileRepo.(*testFileRepo); ok {
+		repo.db = db
+	}
+
+	return &fileService{
+		fileRepo:    fileRepo,
+		projectRepo: &testProjectRepo{project: project},
+		auditRepo:   testAuditRepo{},
+		// 统计变更只进入队列，不启动后台写入
+		statQueue:   &StorageStatQueue{queue: make(chan storageStatDelta, 64), done: make(chan struct{})},
+		minioClient: client,
+		db:          db,
+	}, store, db
+}
+
+// newTestProject 创建测试项目
+func newTestProject() *entity.Project {
+	return &entity.Project{
+		ID:      "project-1",
+		GroupID: "group-1",
+		Status:  entity.ProjectStatusNormal,
+		Group:   entity.Group{ID: "group-1", GroupKey: "team"},
+	}
+}
+
+// newFileHeader 构造上传的文件
+func newFileHeader(t *testing.T, name, content string) *multipart.FileHeader {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte(content))
+	writer.Close()
+
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { form.RemoveAll() })
+	return form.File["file"][0]
+}
+
+// TestUploadConcurrentSamePath 两个请求同时上传到同一个新路径时只有一个成功，
+// 失败的一方不能覆盖成功一方的对象，也不留下临时对象
+func TestUploadConcurrentSamePath(t *testing.T) {
+	project := newTestProject()
+	// 两个请求都完成重名检查后才继续，模拟同时到达
+	var arrived sync.WaitGroup
+	arrived.Add(2)
+	repo := &testFileRepo{afterFind: func(path, fileName string) {
+		arrived.Done()
+		arrived.Wait()
+	}}
+	svc, store, db := newTestFileService(t, repo, project)
+
+	contents := []string{"content from the first uploader", "content from the second uploader, which is longer"}
+	files := make([]*entity.File, len(contents))
+	errs := make([]error, len(contents))
+	var wg sync.WaitGroup
+	for i, content := range contents {
+		header := newFileHeader(t, "report.txt", content)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			files[i], errs[i] = svc.Upload(context.Background(), project.ID, "user-1", header, "docs/", "")
+		}(i)
+	}
+	wg.Wait()
+
+	winner := -1
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			if winner >= 0 {
+				t.Fatalf("两个上传都成功了")
+			}
+			winner = i
+		case errors.Is(err, ErrFileExists):
+		default:
+			t.Fatalf("上传 %d 返回了意外的错误: %v", i, err)
+		}
+	}
+	if winner < 0 {
+		t.Fatalf("两个上传都失败了: %v", errs)
+	}
+
+	bucket := groupBucketName(project.Group.GroupKey)
+	data, ok := store.object(bucket, minio.GetObjectName(project.ID, "docs/", "report.txt"))
+	if !ok {
+		t.Fatalf("成功的上传没有写入对象")
+	}
+	if string(data) != contents[winner] {
+		t.Fatalf("对象内容为 %q，应为成功上传的内容 %q", data, contents[winner])
+	}
+	sum := sha256.Sum256(data)
+	if got := files[winner].FileHash; got != hex.EncodeToString(sum[:]) {
+		t.Fatalf("文件记录的哈希 %s 与对象内容不一致", got)
+	}
+	if keys := store.keys(bucket, TempObjectPrefix); len(keys) != 0 {
+		t.Fatalf("临时对象未删除: %v", keys)
+	}
+
+	var count int64
+	db.Model(&entity.File{}).Where("project_id = ? AND full_path = ?", project.ID, "docs/report.txt").Count(&count)
+	if count != 1 {
+		t.Fatalf("文件记录数为 %d，应为1", count)
+	}
+	db.Model(&entity.FileVersion{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("版本记录数为 %d，应为1", count)
+	}
+}
+
+// TestUploadDedupCopiesObject 秒传的文件使用自己的对象，删除内容相同的原文件的对象不影响秒传的文件
+func TestUploadDedupCopiesObject(t *testing.T) {
+	project := newTestProject()
+	svc, store, db := newTestFileService(t, &testFileRepo{}, project)
+	bucket := groupBucketName(project.Group.GroupKey)
+
+	content := "shared content"
+	sum := sha256.Sum256([]byte(content))
+	original := &entity.File{ID: "file-a", ProjectID: project.ID, FileName: "a.txt", FilePath: "", FullPath: "a.txt", FileHash: hex.EncodeToString(sum[:]), FileSize: int64(len(content)), UploaderID: "user-1", CurrentVersion: 1}
+	if err := db.Create(original).Error; err != nil {
+		t.Fatal(err)
+	}
+	originalObject := minio.GetObjectName(project.ID, "", "a.txt")
+	store.putObject(bucket, originalObject, []byte(content))
+	// 记录从原文件的对象复制的次数，确认走了秒传
+	var dedupCopies atomic.Int32
+	store.beforeCopy = func(bucket, src, dst string) {
+		if src == originalObject {
+			dedupCopies.Add(1)
+		}
+	}
+
+	file, err := svc.Upload(context.Background(), project.ID, "user-1", newFileHeader(t, "b.txt", content), "", "")
+	if err != nil {
+		t.Fatalf("上传失败: %v", err)
+	}
+	if n := dedupCopies.Load(); n != 1 || file.FileHash != original.FileHash {
+		t.Fatalf("上传内容相同的文件时复制了 %d 次原对象，哈希为 %s，应秒传", n, file.FileHash)
+	}
+	dedupObject := minio.GetObjectName(project.ID, "", "b.txt")
+	var version entity.FileVersion
+	if err := db.First(&version, "file_id = ?", file.ID).Error; err != nil {
+		t.Fatalf("查询版本记录失败: %v", err)
+	}
+	if version.StorageKey != dedupObject {
+		t.Fatalf("秒传文件的版本指向 %s，应指向自己的对象 %s", version.StorageKey, dedupObject)
+	}
+
+	// 删除原文件的对象后，秒传的文件仍可读取
+	if err := svc.minioClient.RemoveObject(context.Background(), bucket, originalObject); err != nil {
+		t.Fatal(err)
+	}
+	if data, ok := store.object(bucket, dedupObject); !ok || string(data) != content {
+		t.Fatalf("原文件的对象删除后秒传文件的对象不可用: %q", data)
+	}
+}
+
+// TestGetPublicDownloadURLSanitizedBucket 公共下载URL指向按群组标识规范化后的存储桶
+func TestGetPublicDownloadURLSanitizedBucket(t *testing.T) {
+	project := newTestProject()
+	project.Group.GroupKey = "Team_Alpha"
+	svc, store, db := newTestFileService(t, &testFileRepo{}, project)
+	bucket := groupBucketName(project.Group.GroupKey)
+	if bucket != "group-team-alpha" {
+		t.Fatalf("群组标识 %s 规范化为 %s，应为 group-team-alpha", project.Group.GroupKey, bucket)
+	}
+
+	file := &entity.File{ID: "file-1", ProjectID: project.ID, FileName: "report.txt", FilePath: "docs/", FullPath: "docs/report.txt", UploaderID: "user-1", CurrentVersion: 1}
+	if err := db.Create(file).Error; err != nil {
+		t.Fatal(err)
+	}
+	objectName := minio.GetObjectName(project.ID, "docs/", "report.txt")
+	store.putObject(bucket, objectName, []byte("report"))
+
+	rawURL, err := svc.GetPublicDownloadURL(context.Background(), file.ID)
+	if err != nil {
+		t.Fatalf("获取公共下载URL失败: %v", err)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("返回的URL无效: %v", err)
+	}
+	if want := "/" + bucket + "/" + objectName; u.Path != want {
+		t.Fatalf("公共下载URL的路径为 %s，应为 %s", u.Path, want)
+	}
+	if u.Query().Get("X-Amz-Expires") != "604800" {
+		t.Fatalf("公共下载URL的有效期为 %s 秒，应为7天", u.Query().Get("X-Amz-Expires"))
+	}
+}
+
+// TestUploadToParentFolder 按父文件夹ID上传时文件保存到该文件夹下；其他项目的、已删除的文件夹和普通文件不能作为父文件夹，
+// 未指定父文件夹ID时仍按 path 上传
+func TestUploadToParentFolder(t *testing.T) {
+	ctx := context.Background()
+	project := newTestProject()
+	svc, store, db := newTestFileService(t, nil, project)
+	svc.fileRepo = repository.NewFileRepository(db)
+	bucket := groupBucketName(project.Group.GroupKey)
+
+	files := []*entity.File{
+		{ID: "docs", ProjectID: project.ID, FileName: "docs", FullPath: "docs/", IsFolder: true},
+		{ID: "specs", ProjectID: project.ID, FileName: "specs", FilePath: "docs/", FullPath: "docs/specs/", IsFolder: true},
+		{ID: "trash", ProjectID: project.ID, FileName: "trash", FullPath: "trash/", IsFolder: true, IsDeleted: true},
+		{ID: "readme", ProjectID: project.ID, FileName: "readme.txt", FullPath: "readme.txt", FileSize: 1},
+		{ID: "other", ProjectID: "project-2", FileName: "other", FullPath: "other/", IsFolder: true},
+	}
+	for _, f := range files {
+		f.UploaderID, f.CurrentVersion = "user-1", 1
+		if err := db.Create(f).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	upload := func(parentFolderID, path, name string) (*entity.File, error) {
+		resolved, err := svc.ResolveUploadPath(ctx, project.ID, parentFolderID, path)
+		if err != nil {
+			return nil, err
+		}
+		return svc.Upload(ctx, project.ID, "user-1", newFileHeader(t, name, "content"), resolved, "")
+	}
+
+	uploaded, err := upload("specs", "", "a.txt")
+	if err != nil {
+		t.Fatalf("按父文件夹ID上传失败: %v", err)
+	}
+	if uploaded.FilePath != "docs/specs/" || uploaded.FullPath != "docs/specs/a.txt" {
+		t.Fatalf("上传的文件保存在 %s，应在 docs/specs/ 下", uploaded.FullPath)
+	}
+	if _, ok := store.object(bucket, minio.GetObjectName(project.ID, "docs/specs/", "a.txt")); !ok {
+		t.Fatalf("存储中没有 docs/specs/a.txt 的对象")
+	}
+
+	for _, parentID := range []string{"other", "trash", "readme", "missing"} {
+		if _, err := upload(parentID, "", "b.txt"); !errors.Is(err, ErrInvalidPath) {
+			t.Fatalf("以 %s 作为父文件夹上传返回 %v，应返回 ErrInvalidPath", parentID, err)
+		}
+	}
+	var count int64
+	db.Model(&entity.File{}).Where("file_name = ?", "b.txt").Count(&count)
+	if count != 0 {
+		t.Fatalf("父文件夹无效的上传创建了 %d 条文件记录", count)
+	}
+
+	byPath, err := upload("", "docs/", "c.txt")
+	if err != nil {
+		t.Fatalf("按路径上传失败: %v", err)
+	}
+	if byPath.FullPath != "docs/c.txt" {
+		t.Fatalf("按路径上传的文件保存在 %s，应为 docs/c.txt", byPath.FullPath)
+	}
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+
+	"oss-backend/internal/model/dto"
+	"oss-backend/internal/model/entity"
+)
+
+// 文件标签限制
+const (
+	maxTagNameLength = 32 // 标签名称的最大字符数
+	maxFileTags      = 20 // 每个文件的最大标签数
+)
+
+// ErrInvalidTag 标签名称无效或超出数量限制
+var ErrInvalidTag = errors.New("标签无效")
+
+// ErrTagNotFound 文件没有该标签
+var ErrTagNotFound = errors.New("文件没有该标签")
+
+// normalizeTagName 规范化标签名称：去除首尾空白并转为小写，同一项目内大小写不同的标签视为同一个
+func normalizeTagName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return "", fmt.Errorf("%w: 标签名称不能为空", ErrInvalidTag)
+	}
+	if utf8.RuneCountInString(name) > maxTagNameLength {
+		return "", fmt.Errorf("%w: 标签名称不能超过 %d 个字符", ErrInvalidTag, maxTagNameLength)
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) || r == '/' || r == ',' {
+			return "", fmt.Errorf("%w: 标签名称不能包含控制字符、/ 或 ,", ErrInvalidTag)
+		}
+	}
+	return name, nil
+}
+
+// getTaggableFile 获取可添加标签的文件，已删除的文件和已归档项目中的文件不可修改标签
+func (s *fileService) getTaggableFile(ctx context.Context, fileID string) (*entity.File, error) {
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file == nil || file.IsDeleted {
+		return nil, errors.New("文件不存在")
+	}
+	if err := s.checkFileProjectWritable(ctx, file.ProjectID); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// AddFileTag 为文件添加标签，文件已有该标签时不重复添加，返回文件的全部标签
+func (s *fileService) AddFileTag(ctx context.Context, fileID, userID, name string) ([]string, error) {
+	name, err := normalizeTagName(name)
+	if err != nil {
+		return nil, err
+	}
+	file, err := s.getTaggableFile(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := s.ListFileTags(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		if tag == name {
+			return tags, nil
+		}
+	}
+	if len(tags) >= maxFileTags {
+		return nil, fmt.Errorf("%w: 每个文件最多 %d 个标签", ErrInvalidTag, maxFileTags)
+	}
+
+	if err := s.fileRepo.AddTag(ctx, &entity.FileTag{
+		ProjectID: file.ProjectID,
+		FileID:    file.ID,
+		Name:      name,
+		CreatedBy: userID,
+	}); err != nil {
+		return nil, fmt.Errorf("添加标签失败: %w", err)
+	}
+	return s.ListFileTags(ctx, fileID)
+}
+
+// RemoveFileTag 移除文件的标签，返回文件剩余的标签
+func (s *fileService) RemoveFileTag(ctx context.Context, fileID, name string) ([]string, error) {
+	name, err := normalizeTagName(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.getTaggableFile(ctx, fileID); err != nil {
+		return nil, err
+	}
+
+	if err := s.fileRepo.RemoveTag(ctx, fileID, name); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTagNotFound
+		}
+		return nil, fmt.Errorf("移除标签失败: %w", err)
+	}
+	return s.ListFileTags(ctx, fileID)
+}
+
+// ListFileTags 获取文件的标签名称，按名称排序
+func (s *fileService) ListFileTags(ctx context.Context, fileID string) ([]string, error) {
+	tags, err := s.fileRepo.ListTags(ctx, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("获取文件标签失败: %w", err)
+	}
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	return names, nil
+}
+
+// ListProjectTags 获取项目内使用中的标签及带有各标签的文件数，用于复用已有标签
+func (s *fileService) ListProjectTags(ctx context.Context, projectID string) ([]*dto.ProjectTagResponse, error) {
+	tags, err := s.fileRepo.ListProjectTags(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("获取项目标签失败: %w", err)
+	}
+	result := make([]*dto.ProjectTagResponse, 0, len(tags))
+	for _, tag := range tags {
+		result = append(result, &dto.ProjectTagResponse{Name: tag.Name, FileCount: tag.FileCount})
+	}
+	return result, nil
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/pkg/minio"
+	"oss-backend/pkg/thumbnail"
+)
+
+// 缩略图的默认配置
+const (
+	defaultThumbnailMaxWidth      = 256
+	defaultThumbnailMaxHeight     = 256
+	defaultThumbnailMaxSourceSize = 20 << 20
+	defaultThumbnailMaxPixels     = 40000000
+	thumbnailTimeout              = 2 * time.Minute
+)
+
+// thumbnailSlots 限制同时生成缩略图的数量，避免批量上传图片时同时解码占用过多内存
+var thumbnailSlots = make(chan struct{}, 2)
+
+// ErrThumbnailUnavailable 文件没有可用的缩略图
+var ErrThumbnailUnavailable = errors.New("文件没有可用的缩略图")
+
+// ErrThumbnailForbidden 没有读取文件的权限
+var ErrThumbnailForbidden = errors.New("没有文件读取权限")
+
+// thumbnailObjectName 缩略图对象名，包含版本号，文件内容更新后旧缩略图不会被误用
+func thumbnailObjectName(file *entity.File) string {
+	return fmt.Sprintf("thumbnails/%s/%s-v%d.jpg", file.ProjectID, file.ID, file.CurrentVersion)
+}
+
+// thumbnailSupported 判断文件是否需要生成缩略图：按 MIME 类型前缀识别图片，超过 thumbnail.max_source_size 的不生成，未配置 thumbnail.enabled 时默认开启
+// 加密存储的文件不生成缩略图，避免以明文保存图片内容
+func thumbnailSupported(file *entity.File) bool {
+	if viper.IsSet("thumbnail.enabled") && !viper.GetBool("thumbnail.enabled") {
+		return false
+	}
+	if file.IsFolder || file.IsDeleted || file.Encrypted() {
+		return false
+	}
+	if !strings.HasPrefix(strings.ToLower(file.MimeType), "image/") {
+		return false
+	}
+	maxSize := viper.GetInt64("thumbnail.max_source_size")
+	if maxSize <= 0 {
+		maxSize = defaultThumbnailMaxSourceSize
+	}
+	return file.FileSize <= maxSize
+}
+
+// thumbnailSize 获取缩略图的最大宽高
+func thumbnailSize() (int, int) {
+	width := viper.GetInt("thumbnail.max_width")
+	if width <= 0 {
+		width = defaultThumbnailMaxWidth
+	}
+	height := viper.GetInt("thumbnail.max_height")
+	if height <= 0 {
+		height = defaultThumbnailMaxHeight
+	}
+	return width, height
+}
+
+// scheduleThumbnail 文件内容更新后在后台重新生成缩略图，失败只记录日志，不影响上传结果
+func (s *fileService) scheduleThumbnail(fileID string) {
+	go func() {
+		thumbnailSlots <- struct{}{}
+		defer func() { <-thumbnailSlots }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), thumbnailTimeout)
+		defer cancel()
+		if err := s.refreshThumbnail(ctx, fileID); err != nil {
+			log.Printf("生成文件 %s 的缩略图失败: %v", fileID, err)
+		}
+	}()
+}
+
+// refreshThumbnail 按文件当前版本生成缩略图并更新记录，删除上一版本的缩略图
+// 当前版本不是图片或无法生成时清除记录中的缩略图；生成期间文件又有新版本时放弃本次结果
+func (s *fileService) refreshThumbnail(ctx context.Context, fileID string) error {
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return err
+	}
+	if file == nil {
+		return nil
+	}
+	project, err := s.projectRepo.GetByID(ctx, file.ProjectID)
+	if err != nil {
+		return fmt.Errorf("获取项目信息失败: %w", err)
+	}
+	if project == nil {
+		return nil
+	}
+	bucketName := s.sanitizeBucketName(project.Group.GroupKey)
+
+	previous := file.ThumbnailKey
+	key := ""
+	var genErr error
+	if thumbnailSupported(file) {
+		key = thumbnailObjectName(file)
+		if key == previous {
+			return nil
+		}
+		if genErr = s.generateThumbnail(ctx, file, bucketName, key); genErr != nil {
+			key = ""
+		}
+	}
+	if key == previous {
+		return genErr
+	}
+
+	if err := s.fileRepo.UpdateThumbnailKey(ctx, file.ID, file.CurrentVersion, key); err != nil {
+		if key != "" {
+			// 文件已有新版本，由新版本的任务生成；同一版本已被其他任务记录时保留对象
+			if current, getErr := s.fileRepo.GetByID(ctx, file.ID); getErr != nil || current == nil || current.ThumbnailKey != key {
+				s.removeThumbnail(ctx, bucketName, key)
+			}
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return genErr
+		}
+		return err
+	}
+	if previous != "" {
+		s.removeThumbnail(ctx, bucketName, previous)
+	}
+	return genErr
+}
+
+// generateThumbnail 读取文件当前对象，生成缩略图后写入默认存储后端
+func (s *fileService) generateThumbnail(ctx context.Context, file *entity.File, bucketName, key string) error {
+	client, err := s.fileStorage(file)
+	if err != nil {
+		return err
+	}
+	objectName := minio.GetObjectName(file.ProjectID, file.FilePath, file.FileName)
+	reader, _, err := client.DownloadFile(ctx, bucketName, objectName)
+	if err != nil {
+		return fmt.Errorf("读取文件失败: %w", err)
+	}
+	defer reader.Close()
+
+	// 多读一个字节，对象比记录大时说明正在被覆盖，放弃本次生成
+	data, err := io.ReadAll(io.LimitReader(reader, file.FileSize+1))
+	if err != nil {
+		return fmt.Errorf("读取文件失败: %w", err)
+	}
+	if int64(len(data)) != file.FileSize {
+		return errors.New("文件内容与记录不一致，可能正在被更新")
+	}
+
+	maxPixels := viper.GetInt("thumbnail.max_source_pixels")
+	if maxPixels <= 0 {
+		maxPixels = defaultThumbnailMaxPixels
+	}
+	width, height := thumbnailSize()
+	thumb, err := thumbnail.Generate(data, width, height, maxPixels)
+	if err != nil {
+		return err
+	}
+
+	if err := s.ensureBucketExists(ctx, bucketName); err != nil {
+		return fmt.Errorf("存储准备失败: %w", err)
+	}
+	return s.minioClient.PutObject(ctx, bucketName, key, bytes.NewReader(thumb), int64(len(thumb)), thumbnail.ContentType)
+}
+
+// removeThumbnail 删除缩略图对象，失败只记录日志
+func (s *fileService) removeThumbnail(ctx context.Context, bucketName, key string) {
+	if err := s.minioClient.RemoveObject(ctx, bucketName, key); err != nil {
+		log.Printf("删除缩略图 %s 失败: %v", key, err)
+	}
+}
+
+// GetThumbnail 获取图片文件当前版本的缩略图，缩略图统一为JPEG
+// userID 为空表示项目开启匿名读取时的匿名访问，否则检查用户对文件的读取权限（包括访问拒绝）；
+// 需要水印的文件不提供缩略图，缩略图尚未生成或文件已有新版本时返回 ErrThumbnailUnavailable
+func (s *fileService) GetThumbnail(ctx context.Context, fileID, userID string) (io.ReadCloser, int64, error) {
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return nil, 0, err
+	}
+	if file == nil || file.IsDeleted {
+		return nil, 0, errors.New("文件不存在")
+	}
+	if userID != "" {
+		allowed, err := s.CheckFilePermission(ctx, file.ID, userID, ActionRead)
+		if err != nil {
+			return nil, 0, fmt.Errorf("检查权限失败: %w", err)
+		}
+		if !allowed {
+			return nil, 0, ErrThumbnailForbidden
+		}
+	}
+	if file.WatermarkRequired || file.ThumbnailKey == "" || file.ThumbnailKey != thumbnailObjectName(file) {
+		return nil, 0, ErrThumbnailUnavailable
+	}
+
+	project, err := s.projectRepo.GetByID(ctx, file.ProjectID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("获取项目信息失败: %w", err)
+	}
+	if project == nil {
+		return nil, 0, errors.New("项目不存在")
+	}
+	reader, size, err := s.minioClient.DownloadFile(ctx, s.sanitizeBucketName(project.Group.GroupKey), file.ThumbnailKey)
+	if err != nil {
+		return nil, 0, fmt.Errorf("读取缩略图失败: %w", err)
+	}
+	return reader, size, nil
+}
@@ -0,0 +1,391 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+
+	"oss-backend/internal/model/dto"
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/utils"
+	"oss-backend/pkg/minio"
+)
+
+// ErrInvalidRollback 回滚的目标版本无效
+var ErrInvalidRollback = errors.New("无法回滚到该版本")
+
+// ErrVersionNotFound 文件版本不存在
+var ErrVersionNotFound = errors.New("版本不存在")
+
+// ErrVersionContentUnavailable 历史版本的内容已不在存储中
+var ErrVersionContentUnavailable = errors.New("该版本的文件内容已不可用")
+
+// archiveCurrentVersion 在当前版本被覆盖前将其内容复制为历史版本对象，并记录到版本的 StorageKey
+// 当前对象不存在时（如早期秒传引用的文件）跳过
+func (s *fileService) archiveCurrentVersion(ctx context.Context, bucketName string, file *entity.File) error {
+	client, err := s.fileStorage(file)
+	if err != nil {
+		return err
+	}
+	objectName := minio.GetObjectName(file.ProjectID, file.FilePath, file.FileName)
+	exists, err := client.FileExists(ctx, bucketName, objectName)
+	if err != nil {
+		return fmt.Errorf("检查当前版本对象失败: %w", err)
+	}
+	if !exists {
+		return nil
+	}
+	versionObject := minio.GetVersionObjectName(file.ID, file.CurrentVersion)
+	if err := client.CopyObject(ctx, bucketName, objectName, versionObject, ""); err != nil {
+		return fmt.Errorf("保存历史版本失败: %w", err)
+	}
+	if err := s.fileRepo.UpdateVersionStorageKey(ctx, file.ID, file.CurrentVersion, versionObject); err != nil {
+		return fmt.Errorf("更新版本记录失败: %w", err)
+	}
+	return nil
+}
+
+// findVersionSource 查找历史版本内容所在的对象，返回对象名及其加密信息
+// 依次使用版本记录的 StorageKey、该版本的历史对象，都不存在时使用同一存储后端和存储桶内当前内容哈希相同的文件
+func (s *fileService) findVersionSource(ctx context.Context, bucketName string, file *entity.File, target *entity.FileVersion) (string, entity.ObjectEncryption, error) {
+	var none entity.ObjectEncryption
+	client, err := s.fileStorage(file)
+	if err != nil {
+		return "", none, err
+	}
+	liveObject := minio.GetObjectName(file.ProjectID, file.FilePath, file.FileName)
+	if target.FileHash == file.FileHash {
+		return liveObject, file.ObjectEncryption, nil
+	}
+
+	for _, candidate := range []string{target.StorageKey, minio.GetVersionObjectName(file.ID, target.Version)} {
+		// 非当前版本的 StorageKey 指向当前对象时，内容已被覆盖
+		if candidate == "" || candidate == liveObject {
+			continue
+		}
+		exists, err := client.FileExists(ctx, bucketName, candidate)
+		if err != nil {
+			return "", none, fmt.Errorf("检查历史版本对象失败: %w", err)
+		}
+		if exists {
+			return candidate, target.ObjectEncryption, nil
+		}
+	}
+
+	same, err := s.fileRepo.GetByHash(ctx, target.FileHash)
+	if err != nil {
+		return "", none, fmt.Errorf("查询文件哈希失败: %w", err)
+	}
+	if same == nil || same.IsFolder || same.ID == file.ID || same.StorageBackend != file.StorageBackend {
+		return "", none, ErrVersionContentUnavailable
+	}
+	if same.ProjectID != file.ProjectID {
+		sameProject, err := s.projectRepo.GetByID(ctx, same.ProjectID)
+		if err != nil || sameProject == nil || s.sanitizeBucketName(sameProject.Group.GroupKey) != bucketName {
+			return "", none, ErrVersionContentUnavailable
+		}
+	}
+	sameObject := minio.GetObjectName(same.ProjectID, same.FilePath, same.FileName)
+	exists, err := client.FileExists(ctx, bucketName, sameObject)
+	if err != nil {
+		return "", none, fmt.Errorf("检查文件对象失败: %w", err)
+	}
+	if !exists {
+		return "", none, ErrVersionContentUnavailable
+	}
+	return sameObject, same.ObjectEncryption, nil
+}
+
+// RollbackToVersion 将文件回滚到指定历史版本
+// 回滚不删除任何版本，而是以目标版本的内容创建一个新版本
+func (s *fileService) RollbackToVersion(ctx context.Context, fileID string, version int, userID string) (*entity.File, error) {
+	// 1. 获取文件、目标版本与项目信息
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file == nil || file.IsDeleted {
+		return nil, errors.New("文件不存在")
+	}
+	if file.IsFolder {
+		return nil, fmt.Errorf("%w: 文件夹没有版本", ErrInvalidRollback)
+	}
+	if version == file.CurrentVersion {
+		return nil, fmt.Errorf("%w: 版本 %d 已是当前版本", ErrInvalidRollback, version)
+	}
+
+	target, err := s.fileRepo.GetVersionByID(ctx, fileID, version)
+	if err != nil {
+		return nil, fmt.Errorf("获取版本信息失败: %w", err)
+	}
+	if target == nil {
+		return nil, fmt.Errorf("%w: 版本 %d 不存在", ErrInvalidRollback, version)
+	}
+
+	project, err := s.projectRepo.GetByID(ctx, file.ProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("获取项目信息失败: %w", err)
+	}
+	if project == nil {
+		return nil, errors.New("项目不存在")
+	}
+	if err := checkProjectWritable(project); err != nil {
+		return nil, err
+	}
+	bucketName := s.sanitizeBucketName(project.Group.GroupKey)
+
+	// 2. 检查配额，只计算大小差值
+	sizeDiff := target.FileSize - file.FileSize
+	releaseQuota, err := s.reserveStorage(ctx, project, sizeDiff)
+	if err != nil {
+		return nil, err
+	}
+	defer releaseQuota()
+
+	// 3. 找到目标版本的内容，保存当前版本后覆盖当前对象
+	client, err := s.fileStorage(file)
+	if err != nil {
+		return nil, err
+	}
+	source, sourceEnc, err := s.findVersionSource(ctx, bucketName, file, target)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.archiveCurrentVersion(ctx, bucketName, file); err != nil {
+		return nil, err
+	}
+	objectName := minio.GetObjectName(file.ProjectID, file.FilePath, file.FileName)
+	// 目标版本与当前内容相同时无需复制
+	if source != objectName {
+		if err := client.CopyObject(ctx, bucketName, source, objectName, file.MimeType); err != nil {
+			return nil, fmt.Errorf("恢复版本内容失败: %w", err)
+		}
+	}
+
+	// 4. 事务中创建新版本并更新文件记录
+	previousVersion := file.CurrentVersion
+	newVersion := &entity.FileVersion{
+		ID:               utils.GenerateRecordID(),
+		FileID:           file.ID,
+		Version:          file.CurrentVersion + 1,
+		FileHash:         target.FileHash,
+		FileSize:         target.FileSize,
+		StorageKey:       objectName,
+		UploaderID:       userID,
+		Comment:          fmt.Sprintf("回滚到版本 %d", version),
+		ObjectEncryption: sourceEnc,
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(newVersion).Error; err != nil {
+			return fmt.Errorf("创建版本记录失败: %w", err)
+		}
+		file.FileHash = target.FileHash
+		file.FileSize = target.FileSize
+		file.CurrentVersion = newVersion.Version
+		file.ObjectEncryption = sourceEnc
+		file.UpdatedAt = time.Now()
+		if err := tx.Save(file).Error; err != nil {
+			return fmt.Errorf("更新文件记录失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		// 记录未更新，将当前对象恢复为回滚前的内容
+		if restoreErr := client.CopyObject(ctx, bucketName, minio.GetVersionObjectName(file.ID, previousVersion), objectName, ""); restoreErr != nil {
+			log.Printf("回滚失败后恢复文件 %s 的内容失败: %v", file.ID, restoreErr)
+		}
+		return nil, err
+	}
+
+	s.enqueueStats(file.ProjectID, 0, sizeDiff)
+	s.schedulePruneVersions(file.ID)
+	s.scheduleThumbnail(file.ID)
+	s.recordAudit(ctx, userID, entity.OperationRollback, project, file)
+
+	return file, nil
+}
+
+// DownloadFileVersion 下载文件的指定版本，返回的文件信息中哈希与大小为该版本的值
+func (s *fileService) DownloadFileVersion(ctx context.Context, fileID string, version int, userID string) (io.ReadCloser, *entity.File, error) {
+	// 1. 获取文件、版本与项目信息
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if file == nil || file.IsDeleted {
+		return nil, nil, errors.New("文件不存在")
+	}
+	if file.IsFolder {
+		return nil, nil, fmt.Errorf("%w: 文件夹没有版本", ErrVersionNotFound)
+	}
+
+	target, err := s.fileRepo.GetVersionByID(ctx, fileID, version)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取版本信息失败: %w", err)
+	}
+	if target == nil {
+		return nil, nil, ErrVersionNotFound
+	}
+
+	project, err := s.projectRepo.GetByID(ctx, file.ProjectID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("获取项目信息失败: %w", err)
+	}
+	if project == nil {
+		return nil, nil, errors.New("项目不存在")
+	}
+	bucketName := s.sanitizeBucketName(project.Group.GroupKey)
+
+	// 2. 当前版本直接读取当前对象，历史版本查找其内容所在的对象
+	client, err := s.fileStorage(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	objectName := minio.GetObjectName(file.ProjectID, file.FilePath, file.FileName)
+	objectEnc := file.ObjectEncryption
+	if version != file.CurrentVersion {
+		objectName, objectEnc, err = s.findVersionSource(ctx, bucketName, file, target)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	fileReader, err := s.openObject(ctx, client, bucketName, objectName, project.GroupID, objectEnc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("下载文件失败: %w", err)
+	}
+
+	versioned := *file
+	versioned.FileHash = target.FileHash
+	versioned.FileSize = target.FileSize
+	result := &versioned
+
+	// 3. 与下载当前版本相同，按文件设置添加水印
+	if file.WatermarkRequired {
+		var user entity.User
+		recipient := userID
+		if err := s.db.WithContext(ctx).Select("email").Where("id = ?", userID).First(&user).Error; err == nil {
+			recipient = user.Email
+		}
+		fileReader, result, err = s.applyWatermark(fileReader, result, file.WatermarkText, recipient)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	s.recordAudit(ctx, userID, entity.OperationDownload, project, file)
+
+	return fileReader, result, nil
+}
+
+// resolveMaxVersions 获取项目每个文件保留的最大版本数，项目未设置时使用 storage.max_versions，0表示不限制
+func resolveMaxVersions(project *entity.Project) int {
+	if project.MaxVersions > 0 {
+		return project.MaxVersions
+	}
+	if maxVersions := viper.GetInt("storage.max_versions"); maxVersions > 0 {
+		return maxVersions
+	}
+	return 0
+}
+
+// schedulePruneVersions 创建新版本后在后台清理超出保留数量的旧版本，失败只记录日志
+func (s *fileService) schedulePruneVersions(fileID string) {
+	go func() {
+		if _, err := s.PruneVersions(context.Background(), fileID); err != nil {
+			log.Printf("清理文件 %s 的旧版本失败: %v", fileID, err)
+		}
+	}()
+}
+
+// PruneVersions 按项目的最大版本数删除文件最旧的版本，当前版本始终保留
+// 只删除该文件自己的历史版本对象；版本内容指向其他对象时（如早期数据引用的同哈希文件）不删除对象，只删除版本记录
+func (s *fileService) PruneVersions(ctx context.Context, fileID string) (*dto.VersionPruneResponse, error) {
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file == nil || file.IsDeleted {
+		return nil, errors.New("文件不存在")
+	}
+
+	project, err := s.projectRepo.GetByID(ctx, file.ProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("获取项目信息失败: %w", err)
+	}
+	if project == nil {
+		return nil, errors.New("项目不存在")
+	}
+	if err := checkProjectWritable(project); err != nil {
+		return nil, err
+	}
+
+	response := &dto.VersionPruneResponse{
+		FileID:      file.ID,
+		MaxVersions: resolveMaxVersions(project),
+	}
+	if file.IsFolder || response.MaxVersions == 0 {
+		return response, nil
+	}
+
+	versions, err := s.fileRepo.GetVersions(ctx, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("获取版本列表失败: %w", err)
+	}
+	if len(versions) <= response.MaxVersions {
+		response.Remaining = len(versions)
+		return response, nil
+	}
+
+	// 版本按版本号倒序排列，保留最新的 MaxVersions 个
+	bucketName := s.sanitizeBucketName(project.Group.GroupKey)
+	for i, version := range versions {
+		if i < response.MaxVersions || version.Version == file.CurrentVersion {
+			response.Remaining++
+			continue
+		}
+		if err := s.removeVersionObject(ctx, bucketName, file, version); err != nil {
+			return nil, err
+		}
+		if err := s.fileRepo.DeleteVersion(ctx, file.ID, version.Version); err != nil {
+			return nil, fmt.Errorf("删除版本 %d 失败: %w", version.Version, err)
+		}
+		response.Pruned = append(response.Pruned, version.Version)
+	}
+
+	return response, nil
+}
+
+// removeVersionObject 删除版本独占的历史版本对象，对象不存在时忽略
+func (s *fileService) removeVersionObject(ctx context.Context, bucketName string, file *entity.File, version *entity.FileVersion) error {
+	objectName := version.StorageKey
+	if objectName == "" {
+		objectName = minio.GetVersionObjectName(file.ID, version.Version)
+	}
+	// 只删除该文件自己的历史版本对象，其他对象可能仍被其他文件使用
+	if !strings.HasPrefix(objectName, minio.GetVersionObjectPrefix(file.ID)) {
+		return nil
+	}
+
+	client, err := s.fileStorage(file)
+	if err != nil {
+		return err
+	}
+	exists, err := client.FileExists(ctx, bucketName, objectName)
+	if err != nil {
+		return fmt.Errorf("检查版本 %d 的对象失败: %w", version.Version, err)
+	}
+	if !exists {
+		return nil
+	}
+	if err := client.RemoveObject(ctx, bucketName, objectName); err != nil {
+		return fmt.Errorf("删除版本 %d 的对象失败: %w", version.Version, err)
+	}
+	return nil
+}
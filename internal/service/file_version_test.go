@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+	"oss-backend/pkg/minio"
+)
+
+// TestRemoveVersionObjectKeepsForeignObject 清理版本时只删除该文件自己的历史版本对象，
+// 指向其他文件对象的版本记录不删除对象
+func TestRemoveVersionObjectKeepsForeignObject(t *testing.T) {
+	project := newTestProject()
+	svc, store, _ := newTestFileService(t, &testFileRepo{}, project)
+	bucket := groupBucketName(project.Group.GroupKey)
+
+	file := &entity.File{ID: "file-b", ProjectID: project.ID, FileName: "b.txt", FullPath: "b.txt", CurrentVersion: 3}
+	foreignObject := minio.GetObjectName(project.ID, "", "a.txt")
+	ownObject := minio.GetVersionObjectName(file.ID, 1)
+	store.putObject(bucket, foreignObject, []byte("content of a.txt"))
+	store.putObject(bucket, ownObject, []byte("version 1 of b.txt"))
+
+	foreign := &entity.FileVersion{FileID: file.ID, Version: 2, StorageKey: foreignObject}
+	if err := svc.removeVersionObject(context.Background(), bucket, file, foreign); err != nil {
+		t.Fatalf("清理版本失败: %v", err)
+	}
+	if _, ok := store.object(bucket, foreignObject); !ok {
+		t.Fatalf("其他文件的对象被删除")
+	}
+
+	own := &entity.FileVersion{FileID: file.ID, Version: 1}
+	if err := svc.removeVersionObject(context.Background(), bucket, file, own); err != nil {
+		t.Fatalf("清理版本失败: %v", err)
+	}
+	if _, ok := store.object(bucket, ownObject); ok {
+		t.Fatalf("该文件自己的历史版本对象未删除")
+	}
+}
+
+// TestPruneVersionsKeepsReferencedObject 清理旧版本时，引用其他文件对象的去重版本只删除记录，
+// 仍被其他文件使用的对象保留，该文件自己的历史版本对象被删除
+func TestPruneVersionsKeepsReferencedObject(t *testing.T) {
+	ctx := context.Background()
+	project := newTestProject()
+	project.MaxVersions = 1
+	svc, store, db := newTestFileService(t, nil, project)
+	svc.fileRepo = &testFileRepo{FileRepository: repository.NewFileRepository(db), db: db}
+	bucket := groupBucketName(project.Group.GroupKey)
+
+	// a.txt 与 b.txt 的第1个版本内容相同，b.txt 的第1个版本引用 a.txt 的对象
+	sharedObject := minio.GetObjectName(project.ID, "", "a.txt")
+	a := &entity.File{ID: "file-a", ProjectID: project.ID, FileName: "a.txt", FullPath: "a.txt", FileHash: "hash-shared", FileSize: 6, UploaderID: "user-1", CurrentVersion: 1}
+	b := &entity.File{ID: "file-b", ProjectID: project.ID, FileName: "b.txt", FullPath: "b.txt", FileHash: "hash-3", FileSize: 2, UploaderID: "user-1", CurrentVersion: 3}
+	ownObject := minio.GetVersionObjectName(b.ID, 2)
+	currentObject := minio.GetObjectName(project.ID, "", "b.txt")
+	versions := []*entity.FileVersion{
+		{ID: "a-1", FileID: a.ID, Version: 1, FileHash: "hash-shared", StorageKey: sharedObject},
+		{ID: "b-1", FileID: b.ID, Version: 1, FileHash: "hash-shared", StorageKey: sharedObject},
+		{ID: "b-2", FileID: b.ID, Version: 2, FileHash: "hash-2", StorageKey: ownObject},
+		{ID: "b-3", FileID: b.ID, Version: 3, FileHash: "hash-3", StorageKey: currentObject},
+	}
+	for _, record := range []interface{}{a, b, versions} {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.putObject(bucket, sharedObject, []byte("shared"))
+	store.putObject(bucket, ownObject, []byte("v2"))
+	store.putObject(bucket, currentObject, []byte("v3"))
+
+	result, err := svc.PruneVersions(ctx, b.ID)
+	if err != nil {
+		t.Fatalf("清理旧版本失败: %v", err)
+	}
+	if len(result.Pruned) != 2 || result.Remaining != 1 {
+		t.Fatalf("清理了版本 %v、保留 %d 个，应清理2个、保留1个", result.Pruned, result.Remaining)
+	}
+
+	if data, ok := store.object(bucket, sharedObject); !ok || string(data) != "shared" {
+		t.Fatalf("仍被 a.txt 使用的对象被删除")
+	}
+	if _, ok := store.object(bucket, ownObject); ok {
+		t.Fatalf("b.txt 自己的历史版本对象没有删除")
+	}
+	if _, ok := store.object(bucket, currentObject); !ok {
+		t.Fatalf("b.txt 的当前版本对象被删除")
+	}
+	remaining, err := svc.fileRepo.GetVersions(ctx, b.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) != 1 || remaining[0].Version != 3 {
+		t.Fatalf("b.txt 剩余 %d 个版本记录，应只剩当前版本", len(remaining))
+	}
+	if kept, err := svc.fileRepo.GetVersions(ctx, a.ID); err != nil || len(kept) != 1 {
+		t.Fatalf("a.txt 的版本记录被修改: %d, %v", len(kept), err)
+	}
+}
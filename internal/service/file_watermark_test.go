@@ -0,0 +1,67 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"testing"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/pkg/minio"
+)
+
+// TestDownloadWatermarkedImage 需要水印的图片下载内容与原文件不同，存储中的原文件不变，也不能通过公共URL绕过水印
+func TestDownloadWatermarkedImage(t *testing.T) {
+	project := newTestProject()
+	svc, store, db := newTestFileService(t, &testFileRepo{}, project)
+	bucket := groupBucketName(project.Group.GroupKey)
+
+	img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 200; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	original := buf.Bytes()
+
+	file := &entity.File{ID: "file-1", ProjectID: project.ID, FileName: "photo.png", FullPath: "photo.png", MimeType: "image/png", FileSize: int64(len(original)), UploaderID: "user-1", CurrentVersion: 1, WatermarkRequired: true}
+	if err := db.Create(file).Error; err != nil {
+		t.Fatal(err)
+	}
+	objectName := minio.GetObjectName(project.ID, "", "photo.png")
+	store.putObject(bucket, objectName, original)
+
+	reader, downloaded, err := svc.Download(context.Background(), file.ID, "user-1")
+	if err != nil {
+		t.Fatalf("下载失败: %v", err)
+	}
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(data, original) {
+		t.Fatalf("下载内容与原文件相同，没有添加水印")
+	}
+	if downloaded.FileSize != int64(len(data)) {
+		t.Fatalf("返回的文件大小为 %d，应为加水印后的 %d", downloaded.FileSize, len(data))
+	}
+	if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+		t.Fatalf("加水印后的内容不是有效的PNG: %v", err)
+	}
+	if stored, _ := store.object(bucket, objectName); !bytes.Equal(stored, original) {
+		t.Fatalf("存储中的原文件被修改")
+	}
+
+	if _, err := svc.GetPublicDownloadURL(context.Background(), file.ID); !errors.Is(err, ErrWatermarkDirectAccess) {
+		t.Fatalf("需要水印的文件获取公共URL返回 %v，应返回 ErrWatermarkDirectAccess", err)
+	}
+}
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/pkg/minio"
+	"oss-backend/pkg/watermark"
+)
+
+// ErrArchiveLimitExceeded 打包下载超出条目数或总大小限制
+var ErrArchiveLimitExceeded = errors.New("打包下载超出限制")
+
+// 打包下载默认配置
+const (
+	defaultArchiveConcurrency = 4
+	defaultArchiveMaxEntries  = 10000
+	defaultArchiveMaxSize     = 10 << 30 // 10GB
+	archivePrefetchSize       = 32 << 10 // 每个预取对象的缓冲区大小
+)
+
+// FolderArchive 待打包下载的文件夹
+// 由 PrepareFolderArchive 在写出响应前完成校验，WriteFolderArchive 负责流式写出
+type FolderArchive struct {
+	Folder    *entity.File
+	FileCount int
+	TotalSize int64
+
+	bucketName string
+	groupID    string
+	recipient  string
+	entries    []*entity.File
+}
+
+// archiveFetch 一个对象的预取结果
+type archiveFetch struct {
+	reader io.ReadCloser
+	err    error
+}
+
+// PrepareFolderArchive 校验文件夹并收集待打包的文件，不包含 userID 被拒绝访问的文件，超出限制时返回 ErrArchiveLimitExceeded
+func (s *fileService) PrepareFolderArchive(ctx context.Context, folderID, userID string) (*FolderArchive, error) {
+	// 1. 获取文件夹信息
+	folder, err := s.fileRepo.GetByID(ctx, folderID)
+	if err != nil {
+		return nil, err
+	}
+	if folder == nil || folder.IsDeleted {
+		return nil, errors.New("文件夹不存在")
+	}
+	if !folder.IsFolder {
+		return nil, errors.New("目标不是文件夹")
+	}
+
+	project, err := s.projectRepo.GetByID(ctx, folder.ProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("获取项目信息失败: %w", err)
+	}
+	if project == nil {
+		return nil, errors.New("项目不存在")
+	}
+
+	// 2. 获取全部子项，多取一条用于判断是否超出条目数限制
+	maxEntries, maxSize := archiveLimits()
+	children, _, err := s.fileRepo.List(ctx, folder.ProjectID, folder.FullPath, "", userID, true, false, 1, maxEntries+1)
+	if err != nil {
+		return nil, fmt.Errorf("获取文件夹内容失败: %w", err)
+	}
+	if len(children) > maxEntries {
+		return nil, fmt.Errorf("%w: 文件数超过 %d", ErrArchiveLimitExceeded, maxEntries)
+	}
+
+	archive := &FolderArchive{
+		Folder:     folder,
+		bucketName: s.sanitizeBucketName(project.Group.GroupKey),
+		groupID:    project.GroupID,
+		recipient:  userID,
+	}
+	needRecipient := false
+	for _, child := range children {
+		// LIKE 匹配可能包含名称相近的其他目录，按前缀再过滤一次
+		if !strings.HasPrefix(child.FullPath, folder.FullPath) {
+			continue
+		}
+		if !child.IsFolder {
+			if child.WatermarkRequired {
+				if !watermark.Supported(child.MimeType) {
+					return nil, fmt.Errorf("%w: %s", ErrWatermarkUnsupported, child.FullPath)
+				}
+				needRecipient = true
+			}
+			archive.FileCount++
+			archive.TotalSize += child.FileSize
+		}
+		archive.entries = append(archive.entries, child)
+	}
+	if maxSize > 0 && archive.TotalSize > maxSize {
+		return nil, fmt.Errorf("%w: 总大小超过 %d 字节", ErrArchiveLimitExceeded, maxSize)
+	}
+
+	// 3. 强制水印的文件按当前用户邮箱生成水印
+	if needRecipient {
+		var user entity.User
+		if err := s.db.WithContext(ctx).Select("email").Where("id = ?", userID).First(&user).Error; err == nil {
+			archive.recipient = user.Email
+		}
+	}
+
+	s.recordAudit(ctx, userID, entity.OperationDownload, project, folder)
+
+	return archive, nil
+}
+
+// WriteFolderArchive 将文件夹以zip格式流式写入w
+// 对象按配置的并发数预取，写入仍按顺序进行，同时打开的对象数不超过并发数；
+// ctx 取消（如客户端断开）或写入失败时停止剩余的预取
+func (s *fileService) WriteFolderArchive(ctx context.Context, archive *FolderArchive, w io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+
+	results := make([]chan archiveFetch, len(archive.entries))
+	for i := range results {
+		results[i] = make(chan archiveFetch, 1)
+	}
+	sem := make(chan struct{}, archiveConcurrency())
+	producerDone := make(chan struct{})
+	var wg sync.WaitGroup
+
+	// 预取协程：占用并发名额后才开始获取下一个对象，写入方处理完一个对象后释放名额
+	go func() {
+		defer close(producerDone)
+		for i, entry := range archive.entries {
+			if entry.IsFolder {
+				continue
+			}
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			wg.Add(1)
+			go func(i int, entry *entity.File) {
+				defer wg.Done()
+				results[i] <- s.fetchArchiveEntry(ctx, archive, entry)
+			}(i, entry)
+		}
+	}()
+
+	// 退出时停止预取，并关闭已预取但未写入的对象
+	next := 0
+	defer func() {
+		cancel()
+		<-producerDone
+		wg.Wait()
+		for ; next < len(results); next++ {
+			select {
+			case fetched := <-results[next]:
+				if fetched.reader != nil {
+					fetched.reader.Close()
+				}
+			default:
+			}
+		}
+	}()
+
+	zw := zip.NewWriter(w)
+	root := archive.Folder.FileName + "/"
+	if _, err := zw.CreateHeader(&zip.FileHeader{Name: root, Modified: archive.Folder.UpdatedAt}); err != nil {
+		return err
+	}
+
+	for ; next < len(archive.entries); next++ {
+		entry := archive.entries[next]
+		name := root + strings.TrimPrefix(entry.FullPath, archive.Folder.FullPath)
+
+		if entry.IsFolder {
+			if !strings.HasSuffix(name, "/") {
+				name += "/"
+			}
+			if _, err := zw.CreateHeader(&zip.FileHeader{Name: name, Modified: entry.UpdatedAt}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var fetched archiveFetch
+		select {
+		case fetched = <-results[next]:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if fetched.err != nil {
+			return fmt.Errorf("获取文件 %s 失败: %w", entry.FullPath, fetched.err)
+		}
+
+		err := s.writeArchiveEntry(zw, name, entry, fetched.reader)
+		fetched.reader.Close()
+		<-sem
+		if err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// writeArchiveEntry 写入一个zip条目
+func (s *fileService) writeArchiveEntry(zw *zip.Writer, name string, entry *entity.File, reader io.Reader) error {
+	writer, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: entry.UpdatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(writer, reader); err != nil {
+		return fmt.Errorf("写入文件 %s 失败: %w", entry.FullPath, err)
+	}
+	return nil
+}
+
+// fetchArchiveEntry 打开对象并预读第一块数据，使请求在写入方处理到该文件前已发出
+func (s *fileService) fetchArchiveEntry(ctx context.Context, archive *FolderArchive, entry *entity.File) archiveFetch {
+	client, err := s.fileStorage(entry)
+	if err != nil {
+		return archiveFetch{err: err}
+	}
+	objectName := minio.GetObjectName(entry.ProjectID, entry.FilePath, entry.FileName)
+	raw, err := client.GetObject(ctx, archive.bucketName, objectName, nil)
+	if err != nil {
+		return archiveFetch{err: err}
+	}
+	obj, err := openSealed(raw, archive.groupID, entry.ObjectEncryption)
+	if err != nil {
+		return archiveFetch{err: err}
+	}
+
+	buffered := bufio.NewReaderSize(obj, archivePrefetchSize)
+	if _, err := buffered.Peek(1); err != nil && err != io.EOF {
+		obj.Close()
+		return archiveFetch{err: err}
+	}
+	var reader io.ReadCloser = struct {
+		io.Reader
+		io.Closer
+	}{buffered, obj}
+
+	if entry.WatermarkRequired {
+		reader, _, err = s.applyWatermark(reader, entry, entry.WatermarkText, archive.recipient)
+		if err != nil {
+			return archiveFetch{err: err}
+		}
+	}
+	return archiveFetch{reader: reader}
+}
+
+// archiveConcurrency 打包下载时并发获取对象的数量
+func archiveConcurrency() int {
+	if n := viper.GetInt("download.archive_concurrency"); n > 0 {
+		return n
+	}
+	return defaultArchiveConcurrency
+}
+
+// archiveLimits 打包下载的条目数与总大小上限
+func archiveLimits() (int, int64) {
+	maxEntries := viper.GetInt("download.archive_max_entries")
+	if maxEntries <= 0 {
+		maxEntries = defaultArchiveMaxEntries
+	}
+	maxSize := viper.GetInt64("download.archive_max_size")
+	if maxSize <= 0 {
+		maxSize = defaultArchiveMaxSize
+	}
+	return maxEntries, maxSize
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+	"oss-backend/pkg/minio"
+)
+
+// TestWriteFolderArchiveStreamsLargeFolder 打包下载大文件夹时边取边写，内存占用不随文件夹大小增长，生成的压缩包完整有效
+func TestWriteFolderArchiveStreamsLargeFolder(t *testing.T) {
+	const files = 48
+	const fileSize = 512 << 10 // 共24MB
+	ctx := context.Background()
+	project := newTestProject()
+	svc, store, db := newTestFileService(t, nil, project)
+	createTestTables(t, db, &entity.FileDeny{})
+	svc.fileRepo = repository.NewFileRepository(db)
+	bucket := groupBucketName(project.Group.GroupKey)
+
+	folders := []*entity.File{
+		{ID: "folder", ProjectID: project.ID, FileName: "big", FilePath: "", FullPath: "big/", IsFolder: true},
+		{ID: "sub", ProjectID: project.ID, FileName: "sub", FilePath: "big/", FullPath: "big/sub/", IsFolder: true},
+	}
+	for _, f := range folders {
+		f.UploaderID, f.CurrentVersion = "user-1", 1
+		if err := db.Create(f).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	contents := make(map[string][]byte, files)
+	for i := 0; i < files; i++ {
+		dir := "big/"
+		if i%2 == 1 {
+			dir = "big/sub/"
+		}
+		name := fmt.Sprintf("part-%02d.bin", i)
+		data := make([]byte, fileSize)
+		rand.Read(data)
+		file := &entity.File{ID: fmt.Sprintf("file-%02d", i), ProjectID: project.ID, FileName: name, FilePath: dir, FullPath: dir + name, FileSize: fileSize, UploaderID: "user-1", CurrentVersion: 1}
+		if err := db.Create(file).Error; err != nil {
+			t.Fatal(err)
+		}
+		store.putObject(bucket, minio.GetObjectName(project.ID, dir, name), data)
+		contents[dir+name] = data
+	}
+
+	archive, err := svc.PrepareFolderArchive(ctx, "folder", "user-1")
+	if err != nil {
+		t.Fatalf("准备打包失败: %v", err)
+	}
+	if archive.FileCount != files || archive.TotalSize != files*fileSize {
+		t.Fatalf("待打包 %d 个文件共 %d 字节，应为 %d 个共 %d 字节", archive.FileCount, archive.TotalSize, files, files*fileSize)
+	}
+
+	// 降低GC阈值使堆大小接近实际占用，写入期间采样堆的峰值
+	defer debug.SetGCPercent(debug.SetGCPercent(10))
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	baseline := stats.HeapAlloc
+	var peak atomic.Uint64
+	stop := make(chan struct{})
+	var sampler sync.WaitGroup
+	sampler.Add(1)
+	go func() {
+		defer sampler.Done()
+		ticker := time.NewTicker(5 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				var s runtime.MemStats
+				runtime.ReadMemStats(&s)
+				if s.HeapAlloc > peak.Load() {
+					peak.Store(s.HeapAlloc)
+				}
+			}
+		}
+	}()
+
+	path := filepath.Join(t.TempDir(), "big.zip")
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = svc.WriteFolderArchive(ctx, archive, out)
+	close(stop)
+	sampler.Wait()
+	out.Close()
+	if err != nil {
+		t.Fatalf("写入压缩包失败: %v", err)
+	}
+
+	growth := int64(peak.Load()) - int64(baseline)
+	if limit := int64(archive.TotalSize / 3); growth > limit {
+		t.Fatalf("写入 %d 字节的压缩包时堆增长 %d 字节，超过 %d 字节，没有流式写出", archive.TotalSize, growth, limit)
+	}
+
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("压缩包无效: %v", err)
+	}
+	defer reader.Close()
+	found := 0
+	for _, f := range reader.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		want, ok := contents[f.Name]
+		if !ok {
+			t.Fatalf("压缩包中有多余的条目 %s", f.Name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("读取条目 %s 失败: %v", f.Name, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("条目 %s 的内容与原文件不同", f.Name)
+		}
+		found++
+	}
+	if found != files {
+		t.Fatalf("压缩包中有 %d 个文件，应为 %d 个", found, files)
+	}
+}
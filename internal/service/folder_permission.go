@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"oss-backend/internal/model/dto"
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/utils"
+)
+
+// ErrInvalidFolderPermission 无法对该用户设置文件夹授权
+var ErrInvalidFolderPermission = errors.New("无法设置文件夹授权")
+
+// ErrFolderPermissionNotFound 该用户在文件夹上没有授权
+var ErrFolderPermissionNotFound = errors.New("该用户在此文件夹上没有授权")
+
+// folderRoleAllows 判断文件夹授权的角色是否允许执行操作，与项目角色授予的文件权限一致
+func folderRoleAllows(role, action string) bool {
+	switch role {
+	case ProjectRoleAdmin:
+		return true
+	case ProjectRoleEditor:
+		return action == ActionRead || action == ActionCreate || action == ActionUpdate
+	case ProjectRoleViewer:
+		return action == ActionRead
+	}
+	return false
+}
+
+// checkPathPermission 检查用户能否对项目内 fullPath 处的内容执行操作
+// 离 fullPath 最近的上级文件夹（包括其本身）上有该用户的授权时以授权的角色为准，否则使用用户的项目角色
+func (s *fileService) checkPathPermission(ctx context.Context, projectID, fullPath, userID, action string) (bool, error) {
+	permission, err := s.fileRepo.GetNearestFolderPermission(ctx, projectID, userID, fullPath)
+	if err != nil {
+		return false, fmt.Errorf("检查文件夹授权失败: %w", err)
+	}
+	if permission != nil {
+		return folderRoleAllows(permission.Role, action), nil
+	}
+	projectDomain := fmt.Sprintf("project:%s", projectID)
+	return s.authService.CanUserAccessResource(ctx, userID, ResourceFile, action, projectDomain)
+}
+
+// CheckPathPermission 检查用户能否对项目内的目录执行操作，如在目录下列出或上传文件，规则同文件夹授权
+func (s *fileService) CheckPathPermission(ctx context.Context, projectID, dirPath, userID, action string) (bool, error) {
+	dirPath, err := utils.NormalizeDirPath(dirPath)
+	if err != nil {
+		return false, err
+	}
+	return s.checkPathPermission(ctx, projectID, dirPath, userID, action)
+}
+
+// GrantFolderPermission 授予用户对文件夹及其下全部内容的角色，已有授权时更新角色
+// 授权代替用户在这部分内容上的项目角色，可以高于或低于项目角色；项目管理员不受文件夹授权限制，不能对其授权
+func (s *fileService) GrantFolderPermission(ctx context.Context, folderID, targetUserID, role, adminID string) (*dto.FolderPermissionResponse, error) {
+	folder, err := s.fileRepo.GetByID(ctx, folderID)
+	if err != nil {
+		return nil, err
+	}
+	if folder == nil || folder.IsDeleted {
+		return nil, errors.New("文件不存在")
+	}
+	if !folder.IsFolder {
+		return nil, fmt.Errorf("%w: 只能对文件夹授权", ErrInvalidFolderPermission)
+	}
+	if !folderRoleAllows(role, ActionRead) {
+		return nil, fmt.Errorf("%w: 不支持的角色 %q", ErrInvalidFolderPermission, role)
+	}
+
+	var user entity.User
+	if err := s.db.WithContext(ctx).Select("id").Where("id = ?", targetUserID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: 用户不存在", ErrInvalidFolderPermission)
+		}
+		return nil, fmt.Errorf("获取用户信息失败: %w", err)
+	}
+	member, err := s.projectRepo.GetProjectMember(ctx, folder.ProjectID, targetUserID)
+	if err != nil {
+		return nil, fmt.Errorf("获取项目成员失败: %w", err)
+	}
+	if member != nil && member.Role == ProjectRoleAdmin {
+		return nil, fmt.Errorf("%w: 不能对项目管理员授权", ErrInvalidFolderPermission)
+	}
+
+	permission := &entity.FolderPermission{
+		ProjectID: folder.ProjectID,
+		FolderID:  folder.ID,
+		UserID:    targetUserID,
+		Role:      role,
+		CreatedBy: adminID,
+	}
+	if err := s.fileRepo.SaveFolderPermission(ctx, permission); err != nil {
+		return nil, fmt.Errorf("保存文件夹授权失败: %w", err)
+	}
+	return buildFolderPermissionResponse(permission), nil
+}
+
+// RevokeFolderPermission 移除直接设置在文件夹上的授权，用户恢复上级文件夹上的授权或项目角色
+func (s *fileService) RevokeFolderPermission(ctx context.Context, folderID, targetUserID string) error {
+	if err := s.fileRepo.RemoveFolderPermission(ctx, folderID, targetUserID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrFolderPermissionNotFound
+		}
+		return fmt.Errorf("移除文件夹授权失败: %w", err)
+	}
+	return nil
+}
+
+// ListFolderPermissions 获取直接设置在文件夹上的授权，不包括上级文件夹上的授权
+func (s *fileService) ListFolderPermissions(ctx context.Context, folderID string) ([]*dto.FolderPermissionResponse, error) {
+	permissions, err := s.fileRepo.ListFolderPermissions(ctx, folderID)
+	if err != nil {
+		return nil, fmt.Errorf("获取文件夹授权失败: %w", err)
+	}
+	result := make([]*dto.FolderPermissionResponse, 0, len(permissions))
+	for _, permission := range permissions {
+		result = append(result, buildFolderPermissionResponse(permission))
+	}
+	return result, nil
+}
+
+// buildFolderPermissionResponse 构建文件夹授权响应
+func buildFolderPermissionResponse(permission *entity.FolderPermission) *dto.FolderPermissionResponse {
+	return &dto.FolderPermissionResponse{
+		UserID:    permission.UserID,
+		Role:      permission.Role,
+		CreatedBy: permission.CreatedBy,
+		CreatedAt: permission.CreatedAt,
+		UpdatedAt: permission.UpdatedAt,
+	}
+}
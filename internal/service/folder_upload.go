@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"path"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"oss-backend/internal/model/dto"
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/utils"
+)
+
+// 文件夹上传结果状态
+const (
+	FolderUploadUploaded = "uploaded" // 已上传
+	FolderUploadSkipped  = "skipped"  // 同路径已有内容相同的文件，未重复上传
+	FolderUploadFailed   = "failed"   // 上传失败
+)
+
+// 文件夹上传默认的文件数上限
+const defaultFolderUploadMaxFiles = 1000
+
+// ErrInvalidFolderUpload 文件夹上传请求无效
+var ErrInvalidFolderUpload = errors.New("文件夹上传请求无效")
+
+// folderUploadMaxFiles 获取单次文件夹上传的文件数上限
+func folderUploadMaxFiles() int {
+	maxFiles := viper.GetInt("storage.folder_upload_max_files")
+	if maxFiles <= 0 {
+		maxFiles = defaultFolderUploadMaxFiles
+	}
+	return maxFiles
+}
+
+// cleanRelativePath 校验并规范化文件夹上传中的相对路径，返回所在目录（以/结尾，根目录为空）和文件名
+func cleanRelativePath(relativePath string) (string, string, error) {
+	relativePath = strings.ReplaceAll(strings.TrimSpace(relativePath), "\\", "/")
+	if relativePath == "" || strings.HasPrefix(relativePath, "/") {
+		return "", "", errors.New("相对路径不能为空或以/开头")
+	}
+
+	dir, name := path.Split(relativePath)
+	if name == "" {
+		return "", "", errors.New("相对路径缺少文件名")
+	}
+	if err := utils.ValidateName(name); err != nil {
+		return "", "", err
+	}
+	dir, err := utils.NormalizeDirPath(dir)
+	if err != nil {
+		return "", "", err
+	}
+	return dir, name, nil
+}
+
+// UploadFolder 按相对路径上传一组文件，保留目录结构
+// 缺少的中间文件夹自动创建；每个文件单独上传，一个文件失败不影响其他文件。
+// 同路径已有内容相同的文件时跳过，客户端中断后重新提交同一批文件即可从未完成的文件继续
+func (s *fileService) UploadFolder(ctx context.Context, projectID, uploaderID, basePath string, files []*multipart.FileHeader, relativePaths []string) (*dto.FolderUploadResponse, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%w: 没有上传的文件", ErrInvalidFolderUpload)
+	}
+	if len(files) != len(relativePaths) {
+		return nil, fmt.Errorf("%w: 文件数(%d)与相对路径数(%d)不一致", ErrInvalidFolderUpload, len(files), len(relativePaths))
+	}
+	if maxFiles := folderUploadMaxFiles(); len(files) > maxFiles {
+		return nil, fmt.Errorf("%w: 单次最多上传 %d 个文件", ErrInvalidFolderUpload, maxFiles)
+	}
+
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, errors.New("项目不存在")
+	}
+	if err := checkProjectWritable(project); err != nil {
+		return nil, err
+	}
+
+	if basePath, err = utils.NormalizeDirPath(basePath); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFolderUpload, err)
+	}
+
+	response := &dto.FolderUploadResponse{
+		Results: make([]dto.FolderUploadResult, 0, len(files)),
+	}
+	// 本次请求中已确认的目录，按请求的路径索引
+	folders := make(map[string]folderState)
+
+	for i, fileHeader := range files {
+		result := dto.FolderUploadResult{Path: relativePaths[i]}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		dir, name, err := cleanRelativePath(relativePaths[i])
+		if err == nil {
+			dir, err = s.ensureFolderPath(ctx, project, uploaderID, basePath, dir, folders, &response.FoldersCreated)
+		}
+		if err == nil {
+			// 上传时以相对路径中的文件名为准
+			fileHeader.Filename = name
+			var file *entity.File
+			var skipped bool
+			file, skipped, err = s.uploadFolderMember(ctx, project, uploaderID, dir, fileHeader)
+			if err == nil {
+				result.FileID = file.ID
+				result.Size = file.FileSize
+				result.Status = FolderUploadUploaded
+				if skipped {
+					result.Status = FolderUploadSkipped
+				}
+			}
+		}
+
+		switch {
+		case err != nil:
+			result.Status = FolderUploadFailed
+			result.Error = err.Error()
+			response.Failed++
+		case result.Status == FolderUploadSkipped:
+			response.Skipped++
+		default:
+			response.Uploaded++
+			response.TotalSize += result.Size
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	return response, nil
+}
+
+// uploadFolderMember 上传文件夹中的一个文件，同路径已有内容相同的文件时直接返回已有文件
+func (s *fileService) uploadFolderMember(ctx context.Context, project *entity.Project, uploaderID, dir string, fileHeader *multipart.FileHeader) (*entity.File, bool, error) {
+	src, err := fileHeader.Open()
+	if err != nil {
+		return nil, false, fmt.Errorf("打开文件失败: %w", err)
+	}
+	hash, err := calculateFileHash(src)
+	src.Close()
+	if err != nil {
+		return nil, false, fmt.Errorf("计算文件哈希失败: %w", err)
+	}
+
+	existing, err := s.findByPath(ctx, project, dir, fileHeader.Filename)
+	if err != nil {
+		return nil, false, fmt.Errorf("检查文件路径失败: %w", err)
+	}
+	if existing != nil && !existing.IsFolder && existing.FileHash == hash {
+		return existing, true, nil
+	}
+
+	file, err := s.Upload(ctx, project.ID, uploaderID, fileHeader, dir, hash)
+	if err != nil {
+		return nil, false, err
+	}
+	return file, false, nil
+}
+
+// folderState 文件夹上传中一个目录的确认结果
+type folderState struct {
+	fullPath string // 实际的目录路径，忽略大小写的项目中可能与请求的大小写不同
+	err      error
+}
+
+// ensureFolderPath 逐级确认 basePath 下的相对目录存在，不存在时创建，返回实际的目录路径
+// folders 缓存本次请求中已确认的目录，created 累计新建的文件夹数
+func (s *fileService) ensureFolderPath(ctx context.Context, project *entity.Project, userID, basePath, dir string, folders map[string]folderState, created *int) (string, error) {
+	parent := basePath
+	requested := basePath
+	for _, name := range strings.Split(strings.TrimSuffix(dir, "/"), "/") {
+		if name == "" {
+			continue
+		}
+		requested += name + "/"
+		state, ok := folders[requested]
+		if !ok {
+			state.fullPath, state.err = s.ensureFolder(ctx, project, userID, parent, name, created)
+			folders[requested] = state
+		}
+		if state.err != nil {
+			return "", state.err
+		}
+		parent = state.fullPath
+	}
+	return parent, nil
+}
+
+// ensureFolder 确认文件夹存在，不存在时创建并返回其完整路径；同名位置是文件时返回错误
+func (s *fileService) ensureFolder(ctx context.Context, project *entity.Project, userID, parent, name string, created *int) (string, error) {
+	existing, err := s.findByPath(ctx, project, parent, name)
+	if err != nil {
+		return "", fmt.Errorf("检查文件夹 %s%s 失败: %w", parent, name, err)
+	}
+	if existing != nil {
+		if !existing.IsFolder {
+			return "", fmt.Errorf("%s%s 已存在同名文件", parent, name)
+		}
+		return existing.FullPath, nil
+	}
+
+	folder, err := s.CreateFolder(ctx, project.ID, userID, parent, name)
+	if errors.Is(err, ErrFolderExists) {
+		// 其他请求同时创建了该文件夹
+		if existing, findErr := s.findByPath(ctx, project, parent, name); findErr == nil && existing != nil && existing.IsFolder {
+			return existing.FullPath, nil
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("创建文件夹 %s%s 失败: %w", parent, name, err)
+	}
+	*created++
+	return folder.FullPath, nil
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"mime/multipart"
+	"path"
+	"testing"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+)
+
+// TestUploadFolderNested 一次上传嵌套目录，自动创建中间文件夹，文件位置和大小与上传内容一致；重新提交同一批文件时全部跳过
+func TestUploadFolderNested(t *testing.T) {
+	ctx := context.Background()
+	project := newTestProject()
+	svc, _, db := newTestFileService(t, nil, project)
+	svc.fileRepo = &testFileRepo{FileRepository: repository.NewFileRepository(db), db: db}
+
+	members := map[string]string{
+		"site/index.html":            "<html>home</html>",
+		"site/README.txt":            "readme",
+		"site/css/style.css":         "body { margin: 0; }",
+		"site/js/app.js":             "console.log('app');",
+		"site/js/vendor/lib.js":      "var lib = {};",
+		"site/js/vendor/lib-copy.js": "var lib = {};",
+	}
+	var paths []string
+	var totalSize int64
+	for p, content := range members {
+		paths = append(paths, p)
+		totalSize += int64(len(content))
+	}
+	headers := func() []*multipart.FileHeader {
+		files := make([]*multipart.FileHeader, len(paths))
+		for i, p := range paths {
+			files[i] = newFileHeader(t, path.Base(p), members[p])
+		}
+		return files
+	}
+
+	result, err := svc.UploadFolder(ctx, project.ID, "user-1", "", headers(), paths)
+	if err != nil {
+		t.Fatalf("上传文件夹失败: %v", err)
+	}
+	if result.Uploaded != len(members) || result.Failed != 0 || result.Skipped != 0 {
+		t.Fatalf("上传 %d 个、跳过 %d 个、失败 %d 个，应全部上传: %+v", result.Uploaded, result.Skipped, result.Failed, result.Results)
+	}
+	if result.FoldersCreated != 4 || result.TotalSize != totalSize {
+		t.Fatalf("新建 %d 个文件夹、共 %d 字节，应为4个、%d 字节", result.FoldersCreated, result.TotalSize, totalSize)
+	}
+
+	var files []entity.File
+	if err := db.Where("project_id = ? AND is_deleted = ?", project.ID, false).Find(&files).Error; err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]entity.File, len(files))
+	for _, f := range files {
+		got[f.FullPath] = f
+	}
+	for _, dir := range []string{"site/", "site/css/", "site/js/", "site/js/vendor/"} {
+		if f, ok := got[dir]; !ok || !f.IsFolder || f.FilePath+f.FileName+"/" != dir {
+			t.Errorf("缺少文件夹 %s，实际为 %+v", dir, f)
+		}
+	}
+	for p, content := range members {
+		f, ok := got[p]
+		if !ok {
+			t.Errorf("缺少文件 %s", p)
+			continue
+		}
+		if f.IsFolder || f.FilePath != path.Dir(p)+"/" || f.FileSize != int64(len(content)) {
+			t.Errorf("文件 %s 位于 %q、大小 %d，应位于 %q、大小 %d", p, f.FilePath, f.FileSize, path.Dir(p)+"/", len(content))
+		}
+	}
+	if len(got) != len(members)+4 {
+		t.Fatalf("项目中有 %d 个条目，应为 %d 个", len(got), len(members)+4)
+	}
+
+	// 存储统计按实际上传的文件累计
+	var count, size int64
+	for len(svc.statQueue.queue) > 0 {
+		delta := <-svc.statQueue.queue
+		count += delta.countDelta
+		size += delta.sizeDelta
+	}
+	if count != int64(len(members)) || size != totalSize {
+		t.Fatalf("存储统计增加 %d 个文件、%d 字节，应为 %d 个、%d 字节", count, size, len(members), totalSize)
+	}
+
+	// 中断后重新提交同一批文件，已完成的文件全部跳过
+	again, err := svc.UploadFolder(ctx, project.ID, "user-1", "", headers(), paths)
+	if err != nil {
+		t.Fatalf("重新上传文件夹失败: %v", err)
+	}
+	if again.Skipped != len(members) || again.Uploaded != 0 || again.FoldersCreated != 0 {
+		t.Fatalf("重新上传时上传 %d 个、跳过 %d 个、新建 %d 个文件夹，应全部跳过", again.Uploaded, again.Skipped, again.FoldersCreated)
+	}
+	if n := len(svc.statQueue.queue); n != 0 {
+		t.Fatalf("跳过的文件产生了 %d 条统计变更", n)
+	}
+}
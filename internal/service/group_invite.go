@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"oss-backend/internal/model/dto"
+	"oss-backend/internal/model/entity"
+)
+
+// ErrGroupInviteForbidden 只有群组管理员可以按邮箱邀请成员
+var ErrGroupInviteForbidden = errors.New("无权限执行此操作")
+
+// ErrGroupNotFound 群组不存在
+var ErrGroupNotFound = errors.New("群组不存在")
+
+// InviteMembersByEmail 按邮箱批量邀请成员加入群组，需要群组管理员权限
+// 已注册的用户直接加入群组；未注册的邮箱记录邀请，用户注册并激活后自动以邀请的角色加入。
+// 每个邮箱单独处理并返回结果，同一邮箱重复邀请时以最后一次的角色为准
+func (s *groupService) InviteMembersByEmail(ctx context.Context, groupID string, emails []string, role string, operatorID string) (*dto.GroupInviteMembersResponse, error) {
+	group, err := s.groupRepo.GetGroupByID(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return nil, ErrGroupNotFound
+	}
+	member, err := s.groupRepo.GetMember(ctx, groupID, operatorID)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil || member.Role != "admin" {
+		return nil, ErrGroupInviteForbidden
+	}
+
+	if role == "" {
+		role = "member"
+	}
+	response := &dto.GroupInviteMembersResponse{
+		GroupID: groupID,
+		Role:    role,
+		Results: make([]dto.GroupInviteMemberResult, 0, len(emails)),
+	}
+	seen := make(map[string]bool, len(emails))
+	for _, email := range emails {
+		email = strings.ToLower(strings.TrimSpace(email))
+		if seen[email] {
+			continue
+		}
+		seen[email] = true
+
+		result := s.inviteMemberByEmail(ctx, groupID, email, role, operatorID)
+		switch result.Status {
+		case dto.GroupInviteStatusAdded:
+			response.Added++
+		case dto.GroupInviteStatusInvited:
+			response.Invited++
+		case dto.GroupInviteStatusFailed:
+			response.Failed++
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	log.Printf("用户 %s 按邮箱邀请成员加入群组 %s：加入 %d 名，待注册 %d 名，失败 %d 名", operatorID, groupID, response.Added, response.Invited, response.Failed)
+	return response, nil
+}
+
+// inviteMemberByEmail 处理单个邮箱的邀请
+func (s *groupService) inviteMemberByEmail(ctx context.Context, groupID, email, role, operatorID string) dto.GroupInviteMemberResult {
+	result := dto.GroupInviteMemberResult{Email: email}
+	fail := func(err error) dto.GroupInviteMemberResult {
+		result.Status = dto.GroupInviteStatusFailed
+		result.Error = err.Error()
+		return result
+	}
+
+	now := time.Now()
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if err := s.groupRepo.SaveInvitation(ctx, &entity.GroupInvitation{
+			GroupID:   groupID,
+			Email:     email,
+			Role:      role,
+			InviterID: operatorID,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}); err != nil {
+			return fail(fmt.Errorf("保存邀请失败: %w", err))
+		}
+		result.Status = dto.GroupInviteStatusInvited
+		return result
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	result.UserID = user.ID
+	existing, err := s.groupRepo.GetMember(ctx, groupID, user.ID)
+	if err != nil {
+		return fail(err)
+	}
+	if existing != nil {
+		result.Status = dto.GroupInviteStatusAlreadyMember
+		return result
+	}
+	if err := s.groupRepo.AddMember(ctx, &entity.GroupMember{
+		GroupID:   groupID,
+		UserID:    user.ID,
+		Role:      role,
+		JoinedAt:  now,
+		UpdatedAt: now,
+	}); err != nil {
+		return fail(fmt.Errorf("添加成员失败: %w", err))
+	}
+	result.Status = dto.GroupInviteStatusAdded
+	return result
+}
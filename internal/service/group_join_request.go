@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"oss-backend/internal/model/dto"
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+)
+
+// ErrInvalidJoinRequest 无法提交或审批入群申请
+var ErrInvalidJoinRequest = errors.New("无效的入群申请")
+
+// ErrJoinRequestNotFound 入群申请不存在
+var ErrJoinRequestNotFound = errors.New("入群申请不存在")
+
+// ErrJoinRequestForbidden 只有群组管理员可以查看和审批入群申请
+var ErrJoinRequestForbidden = errors.New("无权限审批入群申请")
+
+// ErrJoinRequestPending 用户在该群组已有待审批的入群申请
+var ErrJoinRequestPending = repository.ErrJoinRequestPending
+
+// ErrJoinRequestProcessed 入群申请已被审批
+var ErrJoinRequestProcessed = repository.ErrJoinRequestProcessed
+
+// RequestToJoin 申请加入群组，群组管理员批准后成为普通成员；同一用户在一个群组同时只能有一个待审批的申请
+func (s *groupService) RequestToJoin(ctx context.Context, groupID string, userID string, message string) (*dto.GroupJoinRequestResponse, error) {
+	group, err := s.groupRepo.GetGroupByID(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return nil, ErrGroupNotFound
+	}
+	member, err := s.groupRepo.GetMember(ctx, groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member != nil {
+		return nil, fmt.Errorf("%w: 您已经是该群组成员", ErrInvalidJoinRequest)
+	}
+
+	now := time.Now()
+	request := &entity.GroupJoinRequest{
+		GroupID:   groupID,
+		UserID:    userID,
+		Message:   strings.TrimSpace(message),
+		Status:    entity.GroupJoinRequestPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.groupRepo.CreateJoinRequest(ctx, request); err != nil {
+		return nil, err
+	}
+	return buildJoinRequestResponse(request), nil
+}
+
+// ListJoinRequests 获取群组的入群申请，仅群组管理员可查看；status 为空时返回待审批的申请
+func (s *groupService) ListJoinRequests(ctx context.Context, groupID string, status string, page, size int, operatorID string) (*dto.PageResult[dto.GroupJoinRequestResponse], error) {
+	if err := s.checkJoinRequestAdmin(ctx, groupID, operatorID); err != nil {
+		return nil, err
+	}
+	switch status {
+	case "":
+		status = entity.GroupJoinRequestPending
+	case entity.GroupJoinRequestPending, entity.GroupJoinRequestApproved, entity.GroupJoinRequestRejected:
+	default:
+		return nil, fmt.Errorf("%w: 不支持的状态 %q", ErrInvalidJoinRequest, status)
+	}
+
+	requests, total, err := s.groupRepo.ListJoinRequests(ctx, groupID, status, page, size)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]dto.GroupJoinRequestResponse, 0, len(requests))
+	for i := range requests {
+		list = append(list, *buildJoinRequestResponse(&requests[i]))
+	}
+	return dto.NewPageResult(list, total, dto.PageQuery{Page: page, Size: size}), nil
+}
+
+// ApproveJoinRequest 批准入群申请，申请人以普通成员加入群组并获得群组域的成员角色
+func (s *groupService) ApproveJoinRequest(ctx context.Context, groupID string, requestID string, operatorID string) (*dto.GroupJoinRequestResponse, error) {
+	request, err := s.getPendingJoinRequest(ctx, groupID, requestID, operatorID)
+	if err != nil {
+		return nil, err
+	}
+	member, err := s.groupRepo.GetMember(ctx, groupID, request.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if member != nil {
+		return nil, fmt.Errorf("%w: 用户已经是该群组成员，请拒绝该申请", ErrInvalidJoinRequest)
+	}
+
+	now := time.Now()
+	request.Status = entity.GroupJoinRequestApproved
+	request.ReviewerID = operatorID
+	request.ReviewedAt = &now
+	request.UpdatedAt = now
+	if err := s.groupRepo.ReviewJoinRequest(ctx, request, &entity.GroupMember{
+		GroupID:   groupID,
+		UserID:    request.UserID,
+		Role:      "member",
+		JoinedAt:  now,
+		UpdatedAt: now,
+	}); err != nil {
+		return nil, err
+	}
+
+	if s.authService != nil {
+		groupDomain := fmt.Sprintf("group:%s", groupID)
+		if err := s.authService.AddRoleForUser(ctx, request.UserID, entity.RoleMember, groupDomain); err != nil {
+			log.Printf("设置用户 %s 在 %s 的Casbin角色失败: %v", request.UserID, groupDomain, err)
+		}
+	}
+	return buildJoinRequestResponse(request), nil
+}
+
+// RejectJoinRequest 拒绝入群申请，申请人之后可以重新申请
+func (s *groupService) RejectJoinRequest(ctx context.Context, groupID string, requestID string, operatorID string) (*dto.GroupJoinRequestResponse, error) {
+	request, err := s.getPendingJoinRequest(ctx, groupID, requestID, operatorID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	request.Status = entity.GroupJoinRequestRejected
+	request.ReviewerID = operatorID
+	request.ReviewedAt = &now
+	request.UpdatedAt = now
+	if err := s.groupRepo.ReviewJoinRequest(ctx, request, nil); err != nil {
+		return nil, err
+	}
+	return buildJoinRequestResponse(request), nil
+}
+
+// getPendingJoinRequest 检查操作者是群组管理员，获取待审批的入群申请
+func (s *groupService) getPendingJoinRequest(ctx context.Context, groupID, requestID, operatorID string) (*entity.GroupJoinRequest, error) {
+	if err := s.checkJoinRequestAdmin(ctx, groupID, operatorID); err != nil {
+		return nil, err
+	}
+	request, err := s.groupRepo.GetJoinRequest(ctx, groupID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if request == nil {
+		return nil, ErrJoinRequestNotFound
+	}
+	if request.Status != entity.GroupJoinRequestPending {
+		return nil, ErrJoinRequestProcessed
+	}
+	return request, nil
+}
+
+// checkJoinRequestAdmin 检查群组存在且操作者是群组管理员
+func (s *groupService) checkJoinRequestAdmin(ctx context.Context, groupID, operatorID string) error {
+	group, err := s.groupRepo.GetGroupByID(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	if group == nil {
+		return ErrGroupNotFound
+	}
+	member, err := s.groupRepo.GetMember(ctx, groupID, operatorID)
+	if err != nil {
+		return err
+	}
+	if member == nil || member.Role != "admin" {
+		return ErrJoinRequestForbidden
+	}
+	return nil
+}
+
+// buildJoinRequestResponse 构建入群申请响应
+func buildJoinRequestResponse(request *entity.GroupJoinRequest) *dto.GroupJoinRequestResponse {
+	response := &dto.GroupJoinRequestResponse{
+		ID:         request.ID,
+		GroupID:    request.GroupID,
+		UserID:     request.UserID,
+		Message:    request.Message,
+		Status:     request.Status,
+		ReviewerID: request.ReviewerID,
+		ReviewedAt: request.ReviewedAt,
+		CreatedAt:  request.CreatedAt,
+	}
+	if request.User.ID != "" {
+		response.UserName = request.User.Name
+		response.Email = request.User.Email
+	}
+	return response
+}
@@ -4,31 +4,52 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"log"
 	"regexp"
 	"strings"
 	"time"
 
+	"github.com/spf13/viper"
+
 	"oss-backend/internal/model/dto"
 	"oss-backend/internal/model/entity"
 	"oss-backend/internal/repository"
 	"oss-backend/pkg/minio"
 )
 
+// ErrGroupNotEmpty 群组下还有项目或未删除的文件，需指定 force 才能删除
+var ErrGroupNotEmpty = repository.ErrGroupNotEmpty
+
 // GroupService 群组服务接口
 type GroupService interface {
 	// 群组管理
 	CreateGroup(ctx context.Context, req *dto.GroupCreateRequest, creatorID string) error
 	UpdateGroup(ctx context.Context, req *dto.GroupUpdateRequest, updaterID string) error
+	DeleteGroup(ctx context.Context, groupID string, req *dto.GroupDeleteRequest, userID string) error
 	GetGroupByID(ctx context.Context, id string, userID string) (*dto.GroupResponse, error)
-	ListGroups(ctx context.Context, req *dto.GroupListRequest, userID string) (*dto.GroupListResponse, error)
+	ListGroups(ctx context.Context, req *dto.GroupListRequest, userID string) (*dto.PageResult[dto.GroupResponse], error)
+	GetGroupQuota(ctx context.Context, groupID string, userID string) (*dto.GroupQuotaResponse, error)
+	SetDefaultProjectQuota(ctx context.Context, groupID string, quota int64, operatorID string) (*dto.GroupQuotaResponse, error)
+	GetGroupStorageTrend(ctx context.Context, groupID, userID string, from, to time.Time) (*dto.StorageTrendResponse, error)
 
 	// 成员管理
 	JoinGroup(ctx context.Context, req *dto.GroupJoinRequest, userID string) error
 	AddMember(ctx context.Context, groupID string, userID string, role string, operatorID string) error
 	UpdateMemberRole(ctx context.Context, groupID string, req *dto.GroupMemberUpdateRequest, operatorID string) error
 	RemoveMember(ctx context.Context, groupID string, userID string, operatorID string) error
-	ListMembers(ctx context.Context, groupID string, page, size int) (*dto.GroupMemberListResponse, error)
+	ListMembers(ctx context.Context, groupID string, page, size int) (*dto.PageResult[dto.GroupMemberResponse], error)
+	TransferGroupOwnership(ctx context.Context, groupID, newOwnerID, currentOwnerID string) error
+	LeaveGroup(ctx context.Context, groupID, userID string) error
+	BulkMoveMembers(ctx context.Context, req *dto.GroupBulkMoveRequest, adminID string) (*dto.GroupBulkMoveResponse, error)
+	InviteMembersByEmail(ctx context.Context, groupID string, emails []string, role string, operatorID string) (*dto.GroupInviteMembersResponse, error)
+
+	// 入群申请
+	RequestToJoin(ctx context.Context, groupID string, userID string, message string) (*dto.GroupJoinRequestResponse, error)
+	ListJoinRequests(ctx context.Context, groupID string, status string, page, size int, operatorID string) (*dto.PageResult[dto.GroupJoinRequestResponse], error)
+	ApproveJoinRequest(ctx context.Context, groupID string, requestID string, operatorID string) (*dto.GroupJoinRequestResponse, error)
+	RejectJoinRequest(ctx context.Context, groupID string, requestID string, operatorID string) (*dto.GroupJoinRequestResponse, error)
 
 	// 用户群组
 	GetUserGroups(ctx context.Context, userID string) ([]dto.GroupResponse, error)
@@ -36,6 +57,7 @@ type GroupService interface {
 
 	// 邀请码
 	GenerateInviteCode(ctx context.Context, req *dto.GroupInviteRequest, userID string) (*dto.GroupInviteResponse, error)
+	RotateInviteCode(ctx context.Context, groupID string, expireDays int, operatorID string) (*dto.GroupInviteResponse, error)
 
 	// 存储桶管理
 	EnsureGroupBucket(ctx context.Context, groupKey string) error
@@ -46,8 +68,10 @@ type groupService struct {
 	groupRepo   repository.GroupRepository
 	userRepo    repository.UserRepository
 	roleRepo    repository.RoleRepository
+	statRepo    repository.StorageStatRepository
 	authService AuthService
 	minioClient *minio.Client
+	mailer      Mailer
 }
 
 // NewGroupService 创建群组服务
@@ -55,15 +79,19 @@ func NewGroupService(
 	groupRepo repository.GroupRepository,
 	userRepo repository.UserRepository,
 	roleRepo repository.RoleRepository,
+	statRepo repository.StorageStatRepository,
 	authService AuthService,
 	minioClient *minio.Client,
+	mailer Mailer,
 ) GroupService {
 	return &groupService{
 		groupRepo:   groupRepo,
 		userRepo:    userRepo,
 		roleRepo:    roleRepo,
+		statRepo:    statRepo,
 		authService: authService,
 		minioClient: minioClient,
+		mailer:      mailer,
 	}
 }
 
@@ -82,8 +110,11 @@ func (s *groupService) CreateGroup(ctx context.Context, req *dto.GroupCreateRequ
 	groupKey := generateGroupKey(req.Name)
 
 	// 生成邀请码
-	inviteCode := generateInviteCode()
-	expireAt := time.Now().AddDate(0, 0, 30) // 默认30天
+	inviteCode, err := s.newInviteCode(ctx)
+	if err != nil {
+		return err
+	}
+	expireAt := inviteExpireAt(0)
 
 	// 创建群组
 	group := &entity.Group{
@@ -159,10 +190,136 @@ func (s *groupService) UpdateGroup(ctx context.Context, req *dto.GroupUpdateRequ
 	if req.Status != nil {
 		group.Status = *req.Status
 	}
+	// 加密存储只影响之后写入的对象，已有文件保持原样
+	if req.EncryptionEnabled != nil {
+		if *req.EncryptionEnabled && !group.EncryptionEnabled {
+			if err := EncryptionConfigured(); err != nil {
+				return fmt.Errorf("无法开启加密存储: %w", err)
+			}
+		}
+		group.EncryptionEnabled = *req.EncryptionEnabled
+	}
 
 	return s.groupRepo.UpdateGroup(ctx, group)
 }
 
+// DeleteGroup 删除群组，仅群组管理员可操作
+// 群组下还有项目或未删除的文件时需指定 force，项目、文件、成员关系和Casbin规则在同一事务中删除；
+// 指定 remove_bucket 时事务提交后再删除各存储后端中的群组存储桶，删除失败只记录日志
+func (s *groupService) DeleteGroup(ctx context.Context, groupID string, req *dto.GroupDeleteRequest, userID string) error {
+	group, err := s.groupRepo.GetGroupByID(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	if group == nil {
+		return fmt.Errorf("群组不存在")
+	}
+
+	// 检查用户是否为群组管理员
+	role, err := s.CheckUserGroupRole(ctx, groupID, userID)
+	if err != nil {
+		return err
+	}
+	if role != "admin" {
+		return fmt.Errorf("无权限执行此操作")
+	}
+
+	if err := s.groupRepo.DeleteGroup(ctx, groupID, req.Force); err != nil {
+		return err
+	}
+
+	// Casbin规则已直接从数据库删除，重新加载使其生效
+	if s.authService != nil {
+		if err := s.authService.ReloadPolicy(); err != nil {
+			log.Printf("删除群组 %s 后重新加载Casbin策略失败: %v", groupID, err)
+		}
+	}
+
+	if req.RemoveBucket {
+		s.removeGroupBuckets(ctx, group.GroupKey)
+	}
+
+	return nil
+}
+
+// removeGroupBuckets 删除默认存储和各额外存储后端中的群组存储桶
+func (s *groupService) removeGroupBuckets(ctx context.Context, groupKey string) {
+	bucketName := groupBucketName(groupKey)
+	if s.minioClient != nil {
+		if err := s.minioClient.RemoveBucketWithObjects(ctx, bucketName); err != nil {
+			log.Printf("删除存储桶 %s 失败: %v", bucketName, err)
+		}
+	}
+
+	clients, err := storageBackendClients()
+	if err != nil {
+		log.Printf("获取存储后端失败，未删除其中的存储桶 %s: %v", bucketName, err)
+		return
+	}
+	for name, client := range clients {
+		if err := client.RemoveBucketWithObjects(ctx, bucketName); err != nil {
+			log.Printf("删除存储后端 %s 中的存储桶 %s 失败: %v", name, bucketName, err)
+		}
+	}
+}
+
+// GetGroupQuota 获取群组配额与新建项目的默认配额，群组成员可查看
+func (s *groupService) GetGroupQuota(ctx context.Context, groupID string, userID string) (*dto.GroupQuotaResponse, error) {
+	group, err := s.groupRepo.GetGroupByID(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 非群组成员时返回错误
+	if _, err := s.CheckUserGroupRole(ctx, groupID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.buildGroupQuotaResponse(ctx, group), nil
+}
+
+// SetDefaultProjectQuota 设置新建项目的默认存储配额，仅群组管理员可操作
+// 默认配额只作用于之后创建且未指定配额的项目，已有项目不受影响
+func (s *groupService) SetDefaultProjectQuota(ctx context.Context, groupID string, quota int64, operatorID string) (*dto.GroupQuotaResponse, error) {
+	group, err := s.groupRepo.GetGroupByID(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := s.CheckUserGroupRole(ctx, groupID, operatorID)
+	if err != nil {
+		return nil, err
+	}
+	if role != "admin" {
+		return nil, fmt.Errorf("无权限执行此操作")
+	}
+
+	if quota < 0 {
+		return nil, fmt.Errorf("默认项目配额不能为负数")
+	}
+	if quota > 0 && group.StorageQuota > 0 && quota > group.StorageQuota {
+		return nil, fmt.Errorf("默认项目配额不能超过群组存储配额(%d字节)", group.StorageQuota)
+	}
+
+	group.DefaultProjectQuota = quota
+	if err := s.groupRepo.UpdateGroup(ctx, group); err != nil {
+		return nil, err
+	}
+
+	return s.buildGroupQuotaResponse(ctx, group), nil
+}
+
+// buildGroupQuotaResponse 构建群组配额响应
+func (s *groupService) buildGroupQuotaResponse(ctx context.Context, group *entity.Group) *dto.GroupQuotaResponse {
+	storageUsed, _ := s.groupRepo.GetStorageUsed(ctx, group.ID)
+	return &dto.GroupQuotaResponse{
+		GroupID:             group.ID,
+		StorageQuota:        group.StorageQuota,
+		StorageUsed:         storageUsed,
+		DefaultProjectQuota: group.DefaultProjectQuota,
+	}
+}
+
 // GetGroupByID 根据ID获取群组
 func (s *groupService) GetGroupByID(ctx context.Context, id string, userID string) (*dto.GroupResponse, error) {
 	// 获取群组
@@ -181,18 +338,20 @@ func (s *groupService) GetGroupByID(ctx context.Context, id string, userID strin
 
 	// 构建响应
 	response := &dto.GroupResponse{
-		ID:           group.ID,
-		Name:         group.Name,
-		Description:  group.Description,
-		GroupKey:     group.GroupKey,
-		StorageQuota: group.StorageQuota,
-		StorageUsed:  storageUsed,
-		MemberCount:  memberCount,
-		ProjectCount: projectCount,
-		Status:       group.Status,
-		CreatorID:    group.CreatorID,
-		CreatedAt:    group.CreatedAt,
-		UserRole:     userRole,
+		ID:                  group.ID,
+		Name:                group.Name,
+		Description:         group.Description,
+		GroupKey:            group.GroupKey,
+		StorageQuota:        group.StorageQuota,
+		DefaultProjectQuota: group.DefaultProjectQuota,
+		StorageUsed:         storageUsed,
+		MemberCount:         memberCount,
+		ProjectCount:        projectCount,
+		Status:              group.Status,
+		EncryptionEnabled:   group.EncryptionEnabled,
+		CreatorID:           group.CreatorID,
+		CreatedAt:           group.CreatedAt,
+		UserRole:            userRole,
 	}
 
 	// 添加创建者信息
@@ -209,7 +368,12 @@ func (s *groupService) GetGroupByID(ctx context.Context, id string, userID strin
 }
 
 // ListGroups 获取群组列表
-func (s *groupService) ListGroups(ctx context.Context, req *dto.GroupListRequest, userID string) (*dto.GroupListResponse, error) {
+func (s *groupService) ListGroups(ctx context.Context, req *dto.GroupListRequest, userID string) (*dto.PageResult[dto.GroupResponse], error) {
+	// size 与 page_size 等效，仓库按 page_size 分页
+	if req.PageSize <= 0 {
+		req.PageSize = req.Size
+	}
+
 	// 获取数据
 	groups, total, err := s.groupRepo.ListGroups(ctx, req)
 	if err != nil {
@@ -217,11 +381,7 @@ func (s *groupService) ListGroups(ctx context.Context, req *dto.GroupListRequest
 	}
 
 	// 构建响应
-	response := &dto.GroupListResponse{
-		Total: total,
-		Items: make([]dto.GroupResponse, 0, len(groups)),
-	}
-
+	list := make([]dto.GroupResponse, 0, len(groups))
 	for _, group := range groups {
 		// 获取统计信息
 		memberCount, _ := s.groupRepo.GetMemberCount(ctx, group.ID)
@@ -232,18 +392,20 @@ func (s *groupService) ListGroups(ctx context.Context, req *dto.GroupListRequest
 		userRole, _ := s.CheckUserGroupRole(ctx, group.ID, userID)
 
 		item := dto.GroupResponse{
-			ID:           group.ID,
-			Name:         group.Name,
-			Description:  group.Description,
-			GroupKey:     group.GroupKey,
-			StorageQuota: group.StorageQuota,
-			StorageUsed:  storageUsed,
-			MemberCount:  memberCount,
-			ProjectCount: projectCount,
-			Status:       group.Status,
-			CreatorID:    group.CreatorID,
-			CreatedAt:    group.CreatedAt,
-			UserRole:     userRole,
+			ID:                  group.ID,
+			Name:                group.Name,
+			Description:         group.Description,
+			GroupKey:            group.GroupKey,
+			StorageQuota:        group.StorageQuota,
+			DefaultProjectQuota: group.DefaultProjectQuota,
+			StorageUsed:         storageUsed,
+			MemberCount:         memberCount,
+			ProjectCount:        projectCount,
+			Status:              group.Status,
+			EncryptionEnabled:   group.EncryptionEnabled,
+			CreatorID:           group.CreatorID,
+			CreatedAt:           group.CreatedAt,
+			UserRole:            userRole,
 		}
 
 		if len(group.Creator.ID) > 0 {
@@ -255,10 +417,10 @@ func (s *groupService) ListGroups(ctx context.Context, req *dto.GroupListRequest
 			item.InviteCode = group.InviteCode
 		}
 
-		response.Items = append(response.Items, item)
+		list = append(list, item)
 	}
 
-	return response, nil
+	return dto.NewPageResult(list, total, dto.PageQuery{Page: req.Page, Size: req.PageSize}), nil
 }
 
 // JoinGroup 加入群组
@@ -268,6 +430,12 @@ func (s *groupService) JoinGroup(ctx context.Context, req *dto.GroupJoinRequest,
 	if err != nil {
 		return err
 	}
+	if group == nil {
+		return fmt.Errorf("邀请码无效")
+	}
+	if group.InviteExpiresAt != nil && time.Now().After(*group.InviteExpiresAt) {
+		return fmt.Errorf("邀请码已过期")
+	}
 
 	// 检查用户是否已经是群组成员
 	member, err := s.groupRepo.GetMember(ctx, group.ID, userID)
@@ -396,8 +564,132 @@ func (s *groupService) RemoveMember(ctx context.Context, groupID string, userID
 	return s.groupRepo.RemoveMember(ctx, groupID, userID)
 }
 
+// LeaveGroup 成员主动退出群组，同时移除其在群组域中的Casbin角色
+// 唯一的管理员不能退出，需先转让群组或将其他成员设为管理员
+func (s *groupService) LeaveGroup(ctx context.Context, groupID, userID string) error {
+	group, err := s.groupRepo.GetGroupByID(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	if group == nil {
+		return fmt.Errorf("群组不存在")
+	}
+
+	member, err := s.groupRepo.GetMember(ctx, groupID, userID)
+	if err != nil {
+		return err
+	}
+	if member == nil {
+		return fmt.Errorf("用户不是该群组成员")
+	}
+
+	if member.Role == "admin" {
+		adminCount, err := s.groupRepo.GetMemberCountByRole(ctx, groupID, "admin")
+		if err != nil {
+			return err
+		}
+		if adminCount <= 1 {
+			return fmt.Errorf("你是群组唯一的管理员，请先转让群组后再退出")
+		}
+	}
+
+	if err := s.groupRepo.RemoveMember(ctx, groupID, userID); err != nil {
+		return err
+	}
+
+	// 移除用户在群组域中的全部角色
+	if s.authService != nil {
+		groupDomain := fmt.Sprintf("group:%s", groupID)
+		roles, err := s.authService.GetRolesForUser(fmt.Sprintf("user:%s", userID), groupDomain)
+		if err != nil {
+			log.Printf("获取用户 %s 在 %s 的Casbin角色失败: %v", userID, groupDomain, err)
+		}
+		for _, role := range roles {
+			if err := s.authService.RemoveRoleForUser(ctx, userID, role, groupDomain); err != nil {
+				log.Printf("移除用户 %s 在 %s 的Casbin角色 %s 失败: %v", userID, groupDomain, role, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// TransferGroupOwnership 将群组转让给另一名成员，新所有者成为管理员并获得 GROUP_ADMIN 角色
+// 由所有者操作；所有者已不在群组中时，群组管理员也可以转让。原所有者仍在群组中时保留管理员身份
+func (s *groupService) TransferGroupOwnership(ctx context.Context, groupID, newOwnerID, currentOwnerID string) error {
+	group, err := s.groupRepo.GetGroupByID(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	if group == nil {
+		return fmt.Errorf("群组不存在")
+	}
+
+	// 检查操作者是否为群组管理员
+	operatorRole, err := s.CheckUserGroupRole(ctx, groupID, currentOwnerID)
+	if err != nil {
+		return err
+	}
+	if operatorRole != "admin" {
+		return fmt.Errorf("无权限执行此操作")
+	}
+
+	// 所有者仍在群组中时只有所有者本人可以转让
+	previousOwner, err := s.groupRepo.GetMember(ctx, groupID, group.CreatorID)
+	if err != nil {
+		return err
+	}
+	if group.CreatorID != currentOwnerID && previousOwner != nil {
+		return fmt.Errorf("只有群组所有者可以转让群组")
+	}
+	if newOwnerID == group.CreatorID {
+		return fmt.Errorf("该用户已是群组所有者")
+	}
+
+	// 新所有者必须已是群组成员
+	member, err := s.groupRepo.GetMember(ctx, groupID, newOwnerID)
+	if err != nil {
+		return err
+	}
+	if member == nil {
+		return fmt.Errorf("用户不是该群组成员")
+	}
+
+	// 先授予Casbin角色，失败时不修改群组
+	groupDomain := fmt.Sprintf("group:%s", groupID)
+	if s.authService != nil {
+		if err := s.authService.AddRoleForUser(ctx, newOwnerID, entity.RoleGroupAdmin, groupDomain); err != nil {
+			return fmt.Errorf("设置Casbin角色失败: %w", err)
+		}
+	}
+
+	if member.Role != "admin" {
+		member.Role = "admin"
+		member.UpdatedAt = time.Now()
+		if err := s.groupRepo.UpdateMember(ctx, member); err != nil {
+			return err
+		}
+	}
+
+	oldOwnerID := group.CreatorID
+	group.CreatorID = newOwnerID
+	if err := s.groupRepo.UpdateGroup(ctx, group); err != nil {
+		return err
+	}
+
+	// 原所有者已离开群组时收回其残留的管理员角色
+	if s.authService != nil && previousOwner == nil {
+		if err := s.authService.RemoveRoleForUser(ctx, oldOwnerID, entity.RoleGroupAdmin, groupDomain); err != nil {
+			log.Printf("移除原所有者 %s 的Casbin角色失败: %v", oldOwnerID, err)
+		}
+	}
+
+	log.Printf("群组 %s 已由 %s 转让给 %s", groupID, currentOwnerID, newOwnerID)
+	return nil
+}
+
 // ListMembers 获取成员列表
-func (s *groupService) ListMembers(ctx context.Context, groupID string, page, size int) (*dto.GroupMemberListResponse, error) {
+func (s *groupService) ListMembers(ctx context.Context, groupID string, page, size int) (*dto.PageResult[dto.GroupMemberResponse], error) {
 	// 获取数据
 	members, total, err := s.groupRepo.ListMembers(ctx, groupID, page, size)
 	if err != nil {
@@ -405,11 +697,7 @@ func (s *groupService) ListMembers(ctx context.Context, groupID string, page, si
 	}
 
 	// 构建响应
-	response := &dto.GroupMemberListResponse{
-		Total: total,
-		Items: make([]dto.GroupMemberResponse, 0, len(members)),
-	}
-
+	list := make([]dto.GroupMemberResponse, 0, len(members))
 	for _, member := range members {
 		item := dto.GroupMemberResponse{
 			ID:           member.ID,
@@ -425,10 +713,10 @@ func (s *groupService) ListMembers(ctx context.Context, groupID string, page, si
 			item.Avatar = member.User.Avatar
 		}
 
-		response.Items = append(response.Items, item)
+		list = append(list, item)
 	}
 
-	return response, nil
+	return dto.NewPageResult(list, total, dto.PageQuery{Page: page, Size: size}), nil
 }
 
 // GetUserGroups 获取用户所属的群组
@@ -452,18 +740,20 @@ func (s *groupService) GetUserGroups(ctx context.Context, userID string) ([]dto.
 		userRole, _ := s.CheckUserGroupRole(ctx, group.ID, userID)
 
 		item := dto.GroupResponse{
-			ID:           group.ID,
-			Name:         group.Name,
-			Description:  group.Description,
-			GroupKey:     group.GroupKey,
-			StorageQuota: group.StorageQuota,
-			StorageUsed:  storageUsed,
-			MemberCount:  memberCount,
-			ProjectCount: projectCount,
-			Status:       group.Status,
-			CreatorID:    group.CreatorID,
-			CreatedAt:    group.CreatedAt,
-			UserRole:     userRole,
+			ID:                  group.ID,
+			Name:                group.Name,
+			Description:         group.Description,
+			GroupKey:            group.GroupKey,
+			StorageQuota:        group.StorageQuota,
+			DefaultProjectQuota: group.DefaultProjectQuota,
+			StorageUsed:         storageUsed,
+			MemberCount:         memberCount,
+			ProjectCount:        projectCount,
+			Status:              group.Status,
+			EncryptionEnabled:   group.EncryptionEnabled,
+			CreatorID:           group.CreatorID,
+			CreatedAt:           group.CreatedAt,
+			UserRole:            userRole,
 		}
 
 		if len(group.Creator.ID) > 0 {
@@ -509,28 +799,143 @@ func (s *groupService) GenerateInviteCode(ctx context.Context, req *dto.GroupInv
 	if err != nil {
 		return nil, err
 	}
+	if group == nil {
+		return nil, fmt.Errorf("群组不存在")
+	}
 
-	// 生成邀请码
-	code, expireAt, err := s.groupRepo.GenerateInviteCode(ctx, req.GroupID, req.ExpireDays)
+	// 生成新邀请码，原邀请码随即失效
+	response, err := s.replaceInviteCode(ctx, group, req.ExpireDays)
 	if err != nil {
 		return nil, err
 	}
 
-	// 构建响应
-	response := &dto.GroupInviteResponse{
-		GroupID:    req.GroupID,
+	// 发送邀请邮件
+	if len(req.Emails) > 0 {
+		s.sendInviteEmails(ctx, group, userID, response.InviteCode, *response.ExpireAt, req.Emails)
+	}
+
+	return response, nil
+}
+
+// RotateInviteCode 轮换群组邀请码，原邀请码立即失效，用于邀请码泄露等情况
+func (s *groupService) RotateInviteCode(ctx context.Context, groupID string, expireDays int, operatorID string) (*dto.GroupInviteResponse, error) {
+	// 检查用户是否为群组管理员
+	role, err := s.CheckUserGroupRole(ctx, groupID, operatorID)
+	if err != nil {
+		return nil, err
+	}
+	if role != "admin" {
+		return nil, fmt.Errorf("无权限执行此操作")
+	}
+
+	group, err := s.groupRepo.GetGroupByID(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return nil, fmt.Errorf("群组不存在")
+	}
+
+	response, err := s.replaceInviteCode(ctx, group, expireDays)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("用户 %s 轮换了群组 %s 的邀请码", operatorID, groupID)
+	return response, nil
+}
+
+// replaceInviteCode 为群组生成新邀请码并保存，原邀请码随即失效
+func (s *groupService) replaceInviteCode(ctx context.Context, group *entity.Group, expireDays int) (*dto.GroupInviteResponse, error) {
+	code, err := s.newInviteCode(ctx)
+	if err != nil {
+		return nil, err
+	}
+	expireAt := inviteExpireAt(expireDays)
+	if err := s.groupRepo.UpdateGroupInviteCode(ctx, group.ID, code, &expireAt); err != nil {
+		return nil, err
+	}
+	return &dto.GroupInviteResponse{
+		GroupID:    group.ID,
 		GroupName:  group.Name,
 		InviteCode: code,
 		ExpireAt:   &expireAt,
+	}, nil
+}
+
+// sendInviteEmails 向指定邮箱发送群组邀请码，发送失败只记录日志
+func (s *groupService) sendInviteEmails(ctx context.Context, group *entity.Group, inviterID, code string, expireAt time.Time, emails []string) {
+	inviterName := "群组管理员"
+	if inviter, err := s.userRepo.GetByID(ctx, inviterID); err == nil {
+		inviterName = inviter.Name
 	}
 
-	return response, nil
+	data := map[string]interface{}{
+		"GroupName":   group.Name,
+		"InviterName": inviterName,
+		"InviteCode":  code,
+		"ExpireAt":    expireAt.Format("2006-01-02 15:04"),
+	}
+
+	for _, email := range emails {
+		if err := SendMail(ctx, s.mailer, MailTypeGroupInvite, email, data); err != nil {
+			log.Printf("发送群组邀请邮件失败: %v", err)
+		}
+	}
+}
+
+// 邀请码默认配置
+const (
+	defaultInviteCodeLength = 16 // 默认邀请码长度
+	inviteCodeMinLength     = 8  // 邀请码最小长度
+	inviteCodeLengthLimit   = 32 // 邀请码最大长度，受 invite_code 字段长度限制
+	inviteCodeMaxAttempts   = 5  // 邀请码冲突时最多尝试的次数
+	defaultInviteExpireDays = 30 // 未指定有效期时邀请码的有效天数
+)
+
+// inviteCodeLength 获取邀请码长度，由 group.invite_code_length 配置
+func inviteCodeLength() int {
+	length := viper.GetInt("group.invite_code_length")
+	if length <= 0 {
+		return defaultInviteCodeLength
+	}
+	return min(max(length, inviteCodeMinLength), inviteCodeLengthLimit)
+}
+
+// generateInviteCode 生成指定长度的随机邀请码，只包含小写十六进制字符
+func generateInviteCode(length int) (string, error) {
+	buf := make([]byte, (length+1)/2)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成邀请码失败: %w", err)
+	}
+	return hex.EncodeToString(buf)[:length], nil
+}
+
+// newInviteCode 生成未被占用的邀请码，冲突时重新生成
+func (s *groupService) newInviteCode(ctx context.Context) (string, error) {
+	length := inviteCodeLength()
+	for attempt := 0; attempt < inviteCodeMaxAttempts; attempt++ {
+		code, err := generateInviteCode(length)
+		if err != nil {
+			return "", err
+		}
+		exists, err := s.groupRepo.InviteCodeExists(ctx, code)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return code, nil
+		}
+		log.Printf("邀请码冲突，重新生成: 长度=%d, 第%d次", length, attempt+1)
+	}
+	return "", fmt.Errorf("多次生成邀请码均发生冲突")
 }
 
-// 生成随机邀请码
-func generateInviteCode() string {
-	// 简单实现，实际项目中应该使用更复杂的算法
-	return fmt.Sprintf("%d", time.Now().UnixNano())
+// inviteExpireAt 计算邀请码的过期时间，expireDays 不大于0时使用默认有效期
+func inviteExpireAt(expireDays int) time.Time {
+	if expireDays <= 0 {
+		expireDays = defaultInviteExpireDays
+	}
+	return time.Now().AddDate(0, 0, expireDays)
 }
 
 // 根据群组名生成唯一标识
@@ -554,6 +959,17 @@ func (s *groupService) EnsureGroupBucket(ctx context.Context, groupKey string) e
 		return fmt.Errorf("MinIO客户端未初始化")
 	}
 
+	// 尝试创建存储桶(如果不存在)
+	err := s.minioClient.CreateBucketIfNotExists(ctx, groupBucketName(groupKey))
+	if err != nil {
+		return fmt.Errorf("创建群组存储桶失败: %w", err)
+	}
+
+	return nil
+}
+
+// groupBucketName 根据群组标识生成群组存储桶名称
+func groupBucketName(groupKey string) string {
 	// 生成符合S3规范的桶名称：只能包含小写字母、数字和连字符
 	// 1. 将所有字符转为小写
 	lowerKey := strings.ToLower(groupKey)
@@ -562,7 +978,7 @@ func (s *groupService) EnsureGroupBucket(ctx context.Context, groupKey string) e
 	sanitizedKey := reg.ReplaceAllString(lowerKey, "-")
 	// 3. 确保不以连字符开头或结尾
 	sanitizedKey = strings.Trim(sanitizedKey, "-")
-	// 4. 如果长度不足，添加随机字符串
+	// 4. 如果长度不足，添加前缀
 	if len(sanitizedKey) < 3 {
 		sanitizedKey = fmt.Sprintf("grp-%s", sanitizedKey)
 	}
@@ -571,13 +987,5 @@ func (s *groupService) EnsureGroupBucket(ctx context.Context, groupKey string) e
 		sanitizedKey = sanitizedKey[:60]
 	}
 
-	bucketName := fmt.Sprintf("group-%s", sanitizedKey)
-
-	// 尝试创建存储桶(如果不存在)
-	err := s.minioClient.CreateBucketIfNotExists(ctx, bucketName)
-	if err != nil {
-		return fmt.Errorf("创建群组存储桶失败: %w", err)
-	}
-
-	return nil
+	return fmt.Sprintf("group-%s", sanitizedKey)
 }
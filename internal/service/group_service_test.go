@@ -0,0 +1,325 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	gormadapter "github.com/casbin/gorm-adapter/v3"
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+
+	"oss-backend/internal/model/dto"
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+)
+
+// newTestGroupService 创建连接测试数据库的群组服务，Casbin策略保存在同一数据库中
+func newTestGroupService(t *testing.T) (*groupService, *casbin.Enforcer, *gorm.DB) {
+	t.Helper()
+	db := newTestDB(t, &entity.User{}, &entity.Group{}, &entity.GroupMember{}, &entity.Project{}, &entity.ProjectMember{})
+	adapter, err := gormadapter.NewAdapterByDB(db)
+	if err != nil {
+		t.Fatalf("创建 Casbin adapter 失败: %v", err)
+	}
+	enforcer, err := casbin.NewEnforcer("../../configs/rbac_model.conf", adapter)
+	if err != nil {
+		t.Fatalf("创建 Enforcer 失败: %v", err)
+	}
+	svc := &groupService{
+		groupRepo:   repository.NewGroupRepository(db),
+		userRepo:    repository.NewUserRepository(db),
+		statRepo:    repository.NewStorageStatRepository(db),
+		authService: NewAuthService(enforcer, nil, nil, nil, db),
+	}
+	return svc, enforcer, db
+}
+
+// TestLeaveGroup 普通成员退出后成员关系和群组域角色都被移除；唯一的管理员不能退出，成员关系保持不变
+func TestLeaveGroup(t *testing.T) {
+	svc, enforcer, db := newTestGroupService(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	records := []interface{}{
+		&entity.User{ID: "owner", Email: "owner@example.com", Name: "owner", Status: entity.UserStatusNormal},
+		&entity.User{ID: "member", Email: "member@example.com", Name: "member", Status: entity.UserStatusNormal},
+		&entity.User{ID: "outsider", Email: "outsider@example.com", Name: "outsider", Status: entity.UserStatusNormal},
+		&entity.Group{ID: "group-1", Name: "team", GroupKey: "team", InviteCode: "invite", CreatorID: "owner", Status: 1},
+		&entity.GroupMember{ID: "gm-1", GroupID: "group-1", UserID: "owner", Role: "admin", JoinedAt: now},
+		&entity.GroupMember{ID: "gm-2", GroupID: "group-1", UserID: "member", Role: "member", JoinedAt: now},
+	}
+	for _, record := range records {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatalf("写入测试数据失败: %v", err)
+		}
+	}
+	enforcer.AddGroupingPolicies([][]string{
+		{"user:owner", entity.RoleGroupAdmin, "group:group-1"},
+		{"user:member", entity.RoleMember, "group:group-1"},
+		// 其他群组中的角色不受影响
+		{"user:member", entity.RoleMember, "group:group-2"},
+	})
+
+	memberCount := func(userID string) int64 {
+		var count int64
+		db.Model(&entity.GroupMember{}).Where("group_id = ? AND user_id = ?", "group-1", userID).Count(&count)
+		return count
+	}
+
+	err := svc.LeaveGroup(ctx, "group-1", "owner")
+	if err == nil || err.Error() != "你是群组唯一的管理员，请先转让群组后再退出" {
+		t.Fatalf("唯一的管理员退出返回 %v，应拒绝", err)
+	}
+	if memberCount("owner") != 1 {
+		t.Fatalf("被拒绝退出的管理员失去了成员关系")
+	}
+	if roles, _ := enforcer.GetRolesForUser("user:owner", "group:group-1"); len(roles) != 1 {
+		t.Fatalf("被拒绝退出的管理员角色为 %v，应保留 GROUP_ADMIN", roles)
+	}
+
+	if err := svc.LeaveGroup(ctx, "group-1", "member"); err != nil {
+		t.Fatalf("普通成员退出失败: %v", err)
+	}
+	if memberCount("member") != 0 {
+		t.Fatalf("退出后成员关系仍然存在")
+	}
+	if roles, _ := enforcer.GetRolesForUser("user:member", "group:group-1"); len(roles) != 0 {
+		t.Fatalf("退出后在群组域中仍有角色 %v", roles)
+	}
+	if roles, _ := enforcer.GetRolesForUser("user:member", "group:group-2"); len(roles) != 1 {
+		t.Fatalf("其他群组中的角色为 %v，不应被移除", roles)
+	}
+
+	if err := svc.LeaveGroup(ctx, "group-1", "outsider"); err == nil || err.Error() != "用户不是该群组成员" {
+		t.Fatalf("非成员退出返回 %v，应返回不是成员", err)
+	}
+}
+
+// TestBulkMoveMembers 批量移动后用户只在目标群组中并拥有对应的群组域角色；按配置删除或保留原群组项目中的权限，
+// 不能移动的用户返回各自的失败原因
+func TestBulkMoveMembers(t *testing.T) {
+	svc, enforcer, db := newTestGroupService(t)
+	createTestTables(t, db, &entity.Permission{})
+	ctx := context.Background()
+
+	now := time.Now()
+	records := []interface{}{
+		&entity.Group{ID: "from", Name: "from", GroupKey: "from", InviteCode: "invite-from", CreatorID: "owner", Status: 1},
+		&entity.Group{ID: "to", Name: "to", GroupKey: "to", InviteCode: "invite-to", CreatorID: "owner", Status: 1},
+		&entity.Project{ID: "project-1", GroupID: "from", Name: "phase 1", PathPrefix: "/from/phase_1", CreatorID: "owner", Status: entity.ProjectStatusNormal},
+	}
+	for _, userID := range []string{"owner", "user-1", "user-2", "user-3", "user-4"} {
+		records = append(records,
+			&entity.User{ID: userID, Email: userID + "@example.com", Name: userID, Status: entity.UserStatusNormal},
+			&entity.ProjectMember{ID: "pm-" + userID, ProjectID: "project-1", UserID: userID, Role: ProjectRoleEditor},
+		)
+		role := "member"
+		if userID == "owner" {
+			role = "admin"
+		}
+		records = append(records, &entity.GroupMember{ID: "from-" + userID, GroupID: "from", UserID: userID, Role: role, JoinedAt: now})
+		enforcer.AddGroupingPolicy("user:"+userID, entity.RoleMember, "group:from")
+		enforcer.AddGroupingPolicy("user:"+userID, ProjectRoleEditor, "project:project-1")
+	}
+	records = append(records, &entity.GroupMember{ID: "to-user-3", GroupID: "to", UserID: "user-3", Role: "member", JoinedAt: now})
+	for _, record := range records {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatalf("写入测试数据失败: %v", err)
+		}
+	}
+	enforcer.AddGroupingPolicy("user:root", entity.RoleAdmin, "system")
+
+	if _, err := svc.BulkMoveMembers(ctx, &dto.GroupBulkMoveRequest{FromGroupID: "from", ToGroupID: "to", UserIDs: []string{"user-1"}}, "owner"); err == nil {
+		t.Fatalf("非系统管理员批量移动成员应被拒绝")
+	}
+
+	reset := true
+	resp, err := svc.BulkMoveMembers(ctx, &dto.GroupBulkMoveRequest{
+		FromGroupID:   "from",
+		ToGroupID:     "to",
+		UserIDs:       []string{"user-1", "user-2", "user-2", "owner", "user-3", "outsider"},
+		Role:          "admin",
+		ResetProjects: &reset,
+	}, "root")
+	if err != nil {
+		t.Fatalf("批量移动成员失败: %v", err)
+	}
+	if resp.Moved != 2 || resp.Failed != 3 || len(resp.Results) != 5 {
+		t.Fatalf("移动 %d 名、失败 %d 名、结果 %d 条，应为2名、3名、5条", resp.Moved, resp.Failed, len(resp.Results))
+	}
+	for _, result := range resp.Results {
+		wantSuccess := result.UserID == "user-1" || result.UserID == "user-2"
+		if result.Success != wantSuccess || (!wantSuccess && result.Error == "") {
+			t.Fatalf("用户 %s 的结果为 %+v", result.UserID, result)
+		}
+	}
+
+	memberRole := func(groupID, userID string) string {
+		member, err := svc.groupRepo.GetMember(ctx, groupID, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if member == nil {
+			return ""
+		}
+		return member.Role
+	}
+	roles := func(userID, domain string) []string {
+		roles, err := enforcer.GetRolesForUser("user:"+userID, domain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return roles
+	}
+	projectMember := func(userID string) bool {
+		var count int64
+		db.Model(&entity.ProjectMember{}).Where("project_id = ? AND user_id = ?", "project-1", userID).Count(&count)
+		return count > 0
+	}
+	for _, userID := range []string{"user-1", "user-2"} {
+		if memberRole("from", userID) != "" || memberRole("to", userID) != "admin" {
+			t.Fatalf("%s 移动后在原群组为 %q、目标群组为 %q，应只以管理员身份在目标群组", userID, memberRole("from", userID), memberRole("to", userID))
+		}
+		if got := roles(userID, "group:from"); len(got) != 0 {
+			t.Fatalf("%s 移动后在原群组域仍有角色 %v", userID, got)
+		}
+		if got := roles(userID, "group:to"); len(got) != 1 || got[0] != entity.RoleGroupAdmin {
+			t.Fatalf("%s 在目标群组域的角色为 %v，应为 GROUP_ADMIN", userID, got)
+		}
+		if projectMember(userID) || len(roles(userID, "project:project-1")) != 0 {
+			t.Fatalf("%s 在原群组项目中的权限没有被删除", userID)
+		}
+	}
+	// 移动失败的用户保持原样
+	if memberRole("from", "owner") != "admin" || memberRole("from", "user-3") != "member" || !projectMember("user-3") {
+		t.Fatalf("移动失败的用户成员关系被修改")
+	}
+
+	// 保留项目权限、使用默认角色移动
+	reset = false
+	resp, err = svc.BulkMoveMembers(ctx, &dto.GroupBulkMoveRequest{FromGroupID: "from", ToGroupID: "to", UserIDs: []string{"user-4"}, ResetProjects: &reset}, "root")
+	if err != nil || resp.Moved != 1 {
+		t.Fatalf("移动 user-4 返回 %+v, %v，应成功", resp, err)
+	}
+	if memberRole("to", "user-4") != "member" || len(roles("user-4", "group:to")) != 0 {
+		t.Fatalf("以普通成员移动的用户角色为 %q，群组域角色为 %v", memberRole("to", "user-4"), roles("user-4", "group:to"))
+	}
+	if !projectMember("user-4") || len(roles("user-4", "project:project-1")) != 1 {
+		t.Fatalf("保留项目权限时 user-4 在原群组项目中的权限被删除")
+	}
+}
+
+// TestNewInviteCodeUnique 连续生成1000个邀请码互不相同、长度符合配置且不随生成顺序单调变化
+func TestNewInviteCodeUnique(t *testing.T) {
+	const iterations = 1000
+	viper.Set("group.invite_code_length", 12)
+	t.Cleanup(func() { viper.Set("group.invite_code_length", 0) })
+	svc, _, _ := newTestGroupService(t)
+	ctx := context.Background()
+
+	seen := make(map[string]bool, iterations)
+	increases, decreases := 0, 0
+	previous := ""
+	for i := 0; i < iterations; i++ {
+		code, err := svc.newInviteCode(ctx)
+		if err != nil {
+			t.Fatalf("第 %d 次生成邀请码失败: %v", i+1, err)
+		}
+		if len(code) != 12 || strings.Trim(code, "0123456789abcdef") != "" {
+			t.Fatalf("邀请码 %q 应为12位小写十六进制字符", code)
+		}
+		if seen[code] {
+			t.Fatalf("第 %d 次生成了重复的邀请码 %s", i+1, code)
+		}
+		seen[code] = true
+		if previous != "" {
+			if code > previous {
+				increases++
+			} else {
+				decreases++
+			}
+		}
+		previous = code
+	}
+	if increases == 0 || decreases == 0 {
+		t.Fatalf("邀请码递增 %d 次、递减 %d 次，随生成顺序单调变化", increases, decreases)
+	}
+
+	// 配置的长度超出范围时限制在允许范围内
+	viper.Set("group.invite_code_length", 4)
+	if code, err := svc.newInviteCode(ctx); err != nil || len(code) != inviteCodeMinLength {
+		t.Fatalf("配置长度为4时生成 %q（%v），应为 %d 位", code, err, inviteCodeMinLength)
+	}
+}
+
+// TestJoinGroupInviteCode 有效邀请码可以加入群组；过期的邀请码返回“邀请码已过期”；轮换后原邀请码立即失效
+func TestJoinGroupInviteCode(t *testing.T) {
+	svc, _, db := newTestGroupService(t)
+	ctx := context.Background()
+
+	now := time.Now()
+	expireAt := now.Add(24 * time.Hour)
+	records := []interface{}{
+		&entity.Group{ID: "group-1", Name: "team", GroupKey: "team", InviteCode: "valid-code", InviteExpiresAt: &expireAt, CreatorID: "owner", Status: 1},
+		&entity.GroupMember{ID: "gm-owner", GroupID: "group-1", UserID: "owner", Role: "admin", JoinedAt: now},
+	}
+	for _, userID := range []string{"owner", "user-1", "user-2"} {
+		records = append(records, &entity.User{ID: userID, Email: userID + "@example.com", Name: userID, Status: entity.UserStatusNormal})
+	}
+	for _, record := range records {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatalf("写入测试数据失败: %v", err)
+		}
+	}
+	join := func(code, userID string) error {
+		return svc.JoinGroup(ctx, &dto.GroupJoinRequest{InviteCode: code}, userID)
+	}
+	isMember := func(userID string) bool {
+		member, err := svc.groupRepo.GetMember(ctx, "group-1", userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return member != nil
+	}
+
+	if err := join("valid-code", "user-1"); err != nil {
+		t.Fatalf("使用有效邀请码加入失败: %v", err)
+	}
+	if !isMember("user-1") {
+		t.Fatalf("使用有效邀请码加入后不是群组成员")
+	}
+
+	expired := now.Add(-time.Minute)
+	if err := db.Model(&entity.Group{}).Where("id = ?", "group-1").Update("invite_expires_at", expired).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := join("valid-code", "user-2"); err == nil || err.Error() != "邀请码已过期" {
+		t.Fatalf("使用过期邀请码加入返回 %v，应返回邀请码已过期", err)
+	}
+	if isMember("user-2") {
+		t.Fatalf("使用过期邀请码加入了群组")
+	}
+
+	if _, err := svc.RotateInviteCode(ctx, "group-1", 7, "user-1"); err == nil {
+		t.Fatalf("普通成员轮换邀请码应被拒绝")
+	}
+	rotated, err := svc.RotateInviteCode(ctx, "group-1", 7, "owner")
+	if err != nil {
+		t.Fatalf("轮换邀请码失败: %v", err)
+	}
+	if rotated.InviteCode == "valid-code" || rotated.ExpireAt == nil || !rotated.ExpireAt.After(now.AddDate(0, 0, 6)) {
+		t.Fatalf("轮换后的邀请码为 %q，过期时间 %v", rotated.InviteCode, rotated.ExpireAt)
+	}
+	if err := join("valid-code", "user-2"); err == nil || err.Error() != "邀请码无效" {
+		t.Fatalf("使用轮换前的邀请码加入返回 %v，应返回邀请码无效", err)
+	}
+	if err := join(rotated.InviteCode, "user-2"); err != nil {
+		t.Fatalf("使用轮换后的邀请码加入失败: %v", err)
+	}
+	if !isMember("user-2") {
+		t.Fatalf("使用轮换后的邀请码加入后不是群组成员")
+	}
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+
+	"oss-backend/internal/model/dto"
+	"oss-backend/pkg/minio"
+)
+
+// readinessTimeout 每项依赖检查的超时时间，避免探针请求长时间挂起
+const readinessTimeout = 2 * time.Second
+
+// HealthChecker 检查服务依赖的 MySQL、Redis 和 MinIO 是否可用
+type HealthChecker struct {
+	db          *gorm.DB
+	minioClient *minio.Client
+}
+
+// NewHealthChecker 创建依赖检查
+func NewHealthChecker(db *gorm.DB, minioClient *minio.Client) *HealthChecker {
+	return &HealthChecker{db: db, minioClient: minioClient}
+}
+
+// CheckReadiness 并发检查各依赖，全部可用时返回 Ready 为 true；未配置 redis.addr 时不检查 Redis
+func (h *HealthChecker) CheckReadiness(ctx context.Context) *dto.ReadinessResponse {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	checks := map[string]func(context.Context) error{
+		"mysql": h.pingDB,
+		"minio": h.pingMinio,
+	}
+	if viper.GetString("redis.addr") != "" {
+		checks["redis"] = pingRedis
+	}
+
+	response := &dto.ReadinessResponse{Ready: true, Checks: make(map[string]string, len(checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check func(context.Context) error) {
+			defer wg.Done()
+			status := "ok"
+			if err := check(ctx); err != nil {
+				status = err.Error()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			response.Checks[name] = status
+			if status != "ok" {
+				response.Ready = false
+			}
+		}(name, check)
+	}
+	wg.Wait()
+	return response
+}
+
+// pingDB 检查数据库连接
+func (h *HealthChecker) pingDB(ctx context.Context) error {
+	sqlDB, err := h.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// pingMinio 检查默认存储后端，只需确认服务可访问，存储桶是否存在不影响结果
+func (h *HealthChecker) pingMinio(ctx context.Context) error {
+	_, err := h.minioClient.BucketExists(ctx, "oss-health-check")
+	return err
+}
+
+// pingRedis 连接 redis.addr 并发送 PING，配置了密码时先认证
+func pingRedis(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", viper.GetString("redis.addr"))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	reader := bufio.NewReader(conn)
+	if password := viper.GetString("redis.password"); password != "" {
+		if err := redisCommand(conn, reader, "AUTH", password); err != nil {
+			return err
+		}
+	}
+	return redisCommand(conn, reader, "PING")
+}
+
+// redisCommand 以 RESP 协议发送命令并读取单行回复，错误回复转换为 error
+func redisCommand(conn net.Conn, reader *bufio.Reader, args ...string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := conn.Write([]byte(b.String())); err != nil {
+		return err
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if strings.HasPrefix(line, "-") {
+		return fmt.Errorf("redis: %s", strings.TrimPrefix(line, "-"))
+	}
+	return nil
+}
@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"oss-backend/internal/model/dto"
+	"oss-backend/internal/utils"
+)
+
+// 后台任务状态常量
+const (
+	JobStatusRunning   = "running"
+	JobStatusCompleted = "completed"
+	JobStatusFailed    = "failed"
+	JobStatusCancelled = "cancelled"
+)
+
+// 后台任务类型常量
+const (
+	JobTypeStatsRecalculate = "stats_recalculate"
+	JobTypeStorageReport    = "storage_report"
+	JobTypeBackup           = "backup"
+	JobTypeFileMigration    = "file_migration"
+	JobTypeBucketMigration  = "bucket_migration"
+)
+
+// JobFunc 后台任务执行函数，需在安全点检查 ctx 是否已取消
+type JobFunc func(ctx context.Context) error
+
+// JobService 后台任务服务接口
+type JobService interface {
+	// Submit 提交并立即在后台执行任务，返回任务ID
+	Submit(jobType, creatorID string, fn JobFunc) string
+	// GetJob 获取任务详情
+	GetJob(ctx context.Context, jobID string) (*dto.JobResponse, error)
+	// ListJobs 列出任务
+	ListJobs(ctx context.Context, adminID string, filter *dto.JobListRequest) ([]*dto.JobResponse, error)
+	// CancelJob 取消运行中的任务，返回取消时的进度
+	CancelJob(ctx context.Context, jobID, adminID string) (*dto.JobResponse, error)
+}
+
+// job 后台任务运行时状态
+type job struct {
+	mu         sync.Mutex
+	id         string
+	jobType    string
+	creatorID  string
+	status     string
+	processed  int
+	total      int
+	message    string
+	result     string
+	createdAt  time.Time
+	finishedAt *time.Time
+	cancel     context.CancelFunc
+}
+
+// 已结束任务的默认保留时间和数量
+const (
+	defaultJobRetention    = 24 * time.Hour
+	defaultMaxFinishedJobs = 1000
+)
+
+// jobService 后台任务服务实现，任务状态保存在进程内存中，已结束的任务在提交新任务时按保留时间和数量清理
+type jobService struct {
+	mu   sync.RWMutex
+	jobs map[string]*job
+}
+
+// NewJobService 创建后台任务服务实例
+func NewJobService() JobService {
+	return &jobService{
+		jobs: make(map[string]*job),
+	}
+}
+
+// jobContextKey 任务在 context 中的键
+type jobContextKey struct{}
+
+// ReportJobProgress 上报当前任务进度，ctx 不属于后台任务时忽略
+func ReportJobProgress(ctx context.Context, processed, total int) {
+	if j, ok := ctx.Value(jobContextKey{}).(*job); ok {
+		j.mu.Lock()
+		j.processed = processed
+		j.total = total
+		j.mu.Unlock()
+	}
+}
+
+// SetJobResult 设置当前任务的结果（如生成文件的对象键），ctx 不属于后台任务时忽略
+func SetJobResult(ctx context.Context, result string) {
+	if j, ok := ctx.Value(jobContextKey{}).(*job); ok {
+		j.mu.Lock()
+		j.result = result
+		j.mu.Unlock()
+	}
+}
+
+// Submit 提交并立即在后台执行任务
+func (s *jobService) Submit(jobType, creatorID string, fn JobFunc) string {
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{
+		id:        utils.GenerateRecordID(),
+		jobType:   jobType,
+		creatorID: creatorID,
+		status:    JobStatusRunning,
+		createdAt: time.Now(),
+		cancel:    cancel,
+	}
+
+	s.mu.Lock()
+	s.evictFinished(j.createdAt)
+	s.jobs[j.id] = j
+	s.mu.Unlock()
+
+	go func() {
+		defer cancel()
+		err := fn(context.WithValue(ctx, jobContextKey{}, j))
+
+		now := time.Now()
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		j.finishedAt = &now
+		switch {
+		case j.status == JobStatusCancelled:
+			// 已被取消，保留取消状态
+		case err != nil && errors.Is(err, context.Canceled):
+			j.status = JobStatusCancelled
+		case err != nil:
+			j.status = JobStatusFailed
+			j.message = err.Error()
+			log.Printf("后台任务 %s(%s) 执行失败: %v", j.id, j.jobType, err)
+		default:
+			j.status = JobStatusCompleted
+		}
+	}()
+
+	return j.id
+}
+
+// GetJob 获取任务详情
+func (s *jobService) GetJob(ctx context.Context, jobID string) (*dto.JobResponse, error) {
+	s.mu.RLock()
+	j, ok := s.jobs[jobID]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, errors.New("任务不存在")
+	}
+	return j.toResponse(), nil
+}
+
+// ListJobs 列出任务，按创建时间倒序
+func (s *jobService) ListJobs(ctx context.Context, adminID string, filter *dto.JobListRequest) ([]*dto.JobResponse, error) {
+	s.mu.RLock()
+	result := make([]*dto.JobResponse, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		resp := j.toResponse()
+		if filter != nil {
+			if filter.Status != "" && resp.Status != filter.Status {
+				continue
+			}
+			if filter.Type != "" && resp.Type != filter.Type {
+				continue
+			}
+		}
+		result = append(result, resp)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(result, func(i, k int) bool {
+		return result[i].CreatedAt.After(result[k].CreatedAt)
+	})
+	return result, nil
+}
+
+// CancelJob 协作式取消任务，任务在下一个安全点退出
+func (s *jobService) CancelJob(ctx context.Context, jobID, adminID string) (*dto.JobResponse, error) {
+	s.mu.RLock()
+	j, ok := s.jobs[jobID]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, errors.New("任务不存在")
+	}
+
+	j.mu.Lock()
+	if j.status != JobStatusRunning {
+		j.mu.Unlock()
+		return nil, errors.New("任务已结束，无法取消")
+	}
+	j.status = JobStatusCancelled
+	j.message = "由管理员 " + adminID + " 取消"
+	j.mu.Unlock()
+
+	j.cancel()
+	log.Printf("管理员 %s 取消了后台任务 %s(%s)", adminID, j.id, j.jobType)
+
+	return j.toResponse(), nil
+}
+
+// evictFinished 清理超过保留时间的已结束任务，剩余的已结束任务超过上限时先清理最早结束的，调用方需持有写锁
+func (s *jobService) evictFinished(now time.Time) {
+	retention, maxFinished := jobRetention()
+
+	type finishedJob struct {
+		id         string
+		finishedAt time.Time
+	}
+	var finished []finishedJob
+	for id, j := range s.jobs {
+		j.mu.Lock()
+		finishedAt := j.finishedAt
+		j.mu.Unlock()
+		if finishedAt == nil {
+			continue
+		}
+		if now.Sub(*finishedAt) > retention {
+			delete(s.jobs, id)
+			continue
+		}
+		finished = append(finished, finishedJob{id: id, finishedAt: *finishedAt})
+	}
+
+	if len(finished) <= maxFinished {
+		return
+	}
+	sort.Slice(finished, func(i, k int) bool {
+		return finished[i].finishedAt.Before(finished[k].finishedAt)
+	})
+	for _, f := range finished[:len(finished)-maxFinished] {
+		delete(s.jobs, f.id)
+	}
+}
+
+// jobRetention 已结束任务的保留时间和最大数量
+func jobRetention() (time.Duration, int) {
+	retention := defaultJobRetention
+	if minutes := viper.GetInt("jobs.retention_minutes"); minutes > 0 {
+		retention = time.Duration(minutes) * time.Minute
+	}
+	maxFinished := defaultMaxFinishedJobs
+	if n := viper.GetInt("jobs.max_finished"); n > 0 {
+		maxFinished = n
+	}
+	return retention, maxFinished
+}
+
+// toResponse 转换为响应对象
+func (j *job) toResponse() *dto.JobResponse {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return &dto.JobResponse{
+		ID:         j.id,
+		Type:       j.jobType,
+		CreatorID:  j.creatorID,
+		Status:     j.status,
+		Processed:  j.processed,
+		Total:      j.total,
+		Message:    j.message,
+		Result:     j.result,
+		CreatedAt:  j.createdAt,
+		FinishedAt: j.finishedAt,
+	}
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"oss-backend/internal/model/dto"
+)
+
+// waitJob 等待任务结束并返回其状态
+func waitJob(t *testing.T, svc JobService, jobID string) *dto.JobResponse {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := svc.GetJob(context.Background(), jobID)
+		if err != nil {
+			t.Fatalf("获取任务失败: %v", err)
+		}
+		if job.FinishedAt != nil {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("任务 %s 没有结束", jobID)
+	return nil
+}
+
+// TestJobStartListCancel 启动示例任务后可在列表中看到，取消后在安全点退出并保留取消时的进度
+func TestJobStartListCancel(t *testing.T) {
+	svc := NewJobService()
+	ctx := context.Background()
+
+	const total = 1000
+	halfway := make(chan struct{})
+	jobID := svc.Submit(JobTypeStatsRecalculate, "admin", func(ctx context.Context) error {
+		for i := 1; i <= total; i++ {
+			// 安全点：处理下一项之前检查是否已取消
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			ReportJobProgress(ctx, i, total)
+			if i == total/2 {
+				close(halfway)
+				<-ctx.Done()
+			}
+		}
+		return nil
+	})
+	<-halfway
+
+	jobs, err := svc.ListJobs(ctx, "admin", &dto.JobListRequest{Status: JobStatusRunning})
+	if err != nil {
+		t.Fatalf("列出任务失败: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != jobID || jobs[0].Type != JobTypeStatsRecalculate {
+		t.Fatalf("运行中的任务为 %+v，应只有刚提交的任务", jobs)
+	}
+
+	cancelled, err := svc.CancelJob(ctx, jobID, "admin")
+	if err != nil {
+		t.Fatalf("取消任务失败: %v", err)
+	}
+	if cancelled.Status != JobStatusCancelled || cancelled.Processed != total/2 || cancelled.Total != total {
+		t.Fatalf("取消后的任务为 %+v，应为已取消并保留进度 %d/%d", cancelled, total/2, total)
+	}
+
+	finished := waitJob(t, svc, jobID)
+	if finished.Status != JobStatusCancelled || finished.Processed != total/2 {
+		t.Fatalf("任务结束时为 %+v，应在取消时的进度处退出", finished)
+	}
+	if _, err := svc.CancelJob(ctx, jobID, "admin"); err == nil {
+		t.Fatalf("已结束的任务不能再次取消")
+	}
+	if jobs, _ := svc.ListJobs(ctx, "admin", &dto.JobListRequest{Status: JobStatusCancelled}); len(jobs) != 1 {
+		t.Fatalf("已取消的任务有 %d 个，应为1个", len(jobs))
+	}
+}
+
+// TestJobEviction 提交新任务时清理超过保留时间的已结束任务，已结束任务的数量不超过上限，运行中的任务不清理
+func TestJobEviction(t *testing.T) {
+	viper.Set("jobs.max_finished", 3)
+	t.Cleanup(func() { viper.Set("jobs.max_finished", 0) })
+
+	svc := NewJobService().(*jobService)
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	running := svc.Submit(JobTypeBackup, "admin", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	defer close(release)
+
+	var finished []string
+	for i := 0; i < 5; i++ {
+		id := svc.Submit(JobTypeStorageReport, "admin", func(ctx context.Context) error {
+			if i%2 == 1 {
+				return fmt.Errorf("第%d个任务失败", i)
+			}
+			return nil
+		})
+		waitJob(t, svc, id)
+		finished = append(finished, id)
+	}
+
+	jobs, _ := svc.ListJobs(ctx, "admin", nil)
+	// 提交第5个任务时已有4个已结束任务，清理最早的1个
+	if len(jobs) != 5 {
+		t.Fatalf("任务列表有 %d 个任务，应为运行中的1个加已结束的4个", len(jobs))
+	}
+	if _, err := svc.GetJob(ctx, finished[0]); err == nil {
+		t.Fatalf("最早结束的任务应已被清理")
+	}
+	if _, err := svc.GetJob(ctx, running); err != nil {
+		t.Fatalf("运行中的任务不应被清理: %v", err)
+	}
+
+	// 超过保留时间的已结束任务在下次提交时清理
+	svc.mu.RLock()
+	for _, id := range finished[1:] {
+		j := svc.jobs[id]
+		j.mu.Lock()
+		expired := time.Now().Add(-defaultJobRetention - time.Minute)
+		j.finishedAt = &expired
+		j.mu.Unlock()
+	}
+	svc.mu.RUnlock()
+	last := svc.Submit(JobTypeStorageReport, "admin", func(ctx context.Context) error { return nil })
+	waitJob(t, svc, last)
+
+	jobs, _ = svc.ListJobs(ctx, "admin", nil)
+	ids := make(map[string]bool)
+	for _, job := range jobs {
+		ids[job.ID] = true
+	}
+	if len(jobs) != 2 || !ids[running] || !ids[last] {
+		t.Fatalf("清理后剩余的任务为 %v，应只剩运行中的任务和最新提交的任务", ids)
+	}
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// ErrAccountLocked 登录失败次数过多，账号暂时锁定
+var ErrAccountLocked = errors.New("账号暂时锁定")
+
+// 登录失败限制的默认配置
+const (
+	defaultLoginMaxFailures = 5
+	defaultLoginWindow      = 15 * time.Minute
+	defaultLoginLockout     = 15 * time.Minute
+)
+
+// LoginLimiter 登录失败限制，按邮箱+IP统计失败次数，窗口内达到上限后锁定该邮箱在该IP上的登录
+// 当前提供进程内实现；多实例部署时可替换为基于 Redis 的实现（INCR + EXPIRE 计数，SET EX 记录锁定）
+type LoginLimiter interface {
+	// Locked 返回剩余锁定时间，未锁定时为0
+	Locked(ctx context.Context, email, ip string) (time.Duration, error)
+	// RecordFailure 记录一次登录失败，达到上限时开始锁定并返回锁定时长，否则返回0
+	RecordFailure(ctx context.Context, email, ip string) (time.Duration, error)
+	// Reset 登录成功后清除该邮箱在该IP上的失败次数
+	Reset(ctx context.Context, email, ip string) error
+	// Unlock 清除该邮箱在所有IP上的失败次数和锁定
+	Unlock(ctx context.Context, email string) error
+}
+
+// loginAttempt 一个邮箱+IP的登录失败记录
+type loginAttempt struct {
+	failures    int
+	windowStart time.Time
+	lockedUntil time.Time
+}
+
+// memoryLoginLimiter 进程内登录失败限制
+type memoryLoginLimiter struct {
+	mu          sync.Mutex
+	maxFailures int           // 窗口内允许的失败次数，0表示不限制
+	window      time.Duration // 统计失败次数的时间窗口
+	lockout     time.Duration // 锁定时长
+	attempts    map[string]*loginAttempt
+}
+
+// NewMemoryLoginLimiter 创建进程内登录失败限制，从 security.login_* 配置读取失败上限、统计窗口和锁定时长
+func NewMemoryLoginLimiter() LoginLimiter {
+	maxFailures := defaultLoginMaxFailures
+	if viper.IsSet("security.login_max_failures") {
+		maxFailures = viper.GetInt("security.login_max_failures")
+	}
+	window := defaultLoginWindow
+	if seconds := viper.GetInt("security.login_failure_window"); seconds > 0 {
+		window = time.Duration(seconds) * time.Second
+	}
+	lockout := defaultLoginLockout
+	if seconds := viper.GetInt("security.login_lockout_duration"); seconds > 0 {
+		lockout = time.Duration(seconds) * time.Second
+	}
+	return &memoryLoginLimiter{
+		maxFailures: maxFailures,
+		window:      window,
+		lockout:     lockout,
+		attempts:    make(map[string]*loginAttempt),
+	}
+}
+
+// loginAttemptKey 邮箱不区分大小写
+func loginAttemptKey(email, ip string) string {
+	return strings.ToLower(strings.TrimSpace(email)) + "|" + ip
+}
+
+// Locked 返回剩余锁定时间
+func (l *memoryLoginLimiter) Locked(ctx context.Context, email, ip string) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	attempt, ok := l.attempts[loginAttemptKey(email, ip)]
+	if !ok {
+		return 0, nil
+	}
+	if remaining := time.Until(attempt.lockedUntil); remaining > 0 {
+		return remaining, nil
+	}
+	return 0, nil
+}
+
+// RecordFailure 记录一次登录失败
+func (l *memoryLoginLimiter) RecordFailure(ctx context.Context, email, ip string) (time.Duration, error) {
+	if l.maxFailures <= 0 {
+		return 0, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// 顺带清理窗口和锁定都已过期的记录，避免无限增长
+	now := time.Now()
+	for key, attempt := range l.attempts {
+		if now.Sub(attempt.windowStart) > l.window && now.After(attempt.lockedUntil) {
+			delete(l.attempts, key)
+		}
+	}
+
+	key := loginAttemptKey(email, ip)
+	attempt, ok := l.attempts[key]
+	if !ok || now.Sub(attempt.windowStart) > l.window {
+		attempt = &loginAttempt{windowStart: now}
+		l.attempts[key] = attempt
+	}
+	attempt.failures++
+	if attempt.failures < l.maxFailures {
+		return 0, nil
+	}
+
+	// 锁定后重新计数，解锁后再次达到上限时重新锁定
+	attempt.failures = 0
+	attempt.windowStart = now
+	attempt.lockedUntil = now.Add(l.lockout)
+	return l.lockout, nil
+}
+
+// Reset 清除该邮箱在该IP上的失败次数
+func (l *memoryLoginLimiter) Reset(ctx context.Context, email, ip string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.attempts, loginAttemptKey(email, ip))
+	return nil
+}
+
+// Unlock 清除该邮箱在所有IP上的记录
+func (l *memoryLoginLimiter) Unlock(ctx context.Context, email string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	prefix := loginAttemptKey(email, "")
+	for key := range l.attempts {
+		if strings.HasPrefix(key, prefix) {
+			delete(l.attempts, key)
+		}
+	}
+	return nil
+}
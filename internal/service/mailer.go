@@ -0,0 +1,239 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Mailer 邮件发送接口
+// 注册验证、密码重置等需要发送邮件的功能统一通过该接口发送
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// 邮件通知类型，对应 mail.templates 下的模板名
+const (
+	MailTypeVerifyEmail   = "verify_email"   // 注册邮箱验证
+	MailTypePasswordReset = "password_reset" // 密码重置
+	MailTypeGroupInvite   = "group_invite"   // 群组邀请
+	MailTypeQuotaAlert    = "quota_alert"    // 存储配额告警
+)
+
+// MailTemplate 邮件模板，主题与正文均使用 text/template 语法
+type MailTemplate struct {
+	Subject string
+	Body    string
+}
+
+// defaultMailTemplates 默认邮件模板，可通过 mail.templates.<类型>.subject/body 覆盖
+var defaultMailTemplates = map[string]MailTemplate{
+	MailTypeVerifyEmail: {
+		Subject: "请验证您的邮箱",
+		Body:    "{{.Name}}，您好：\n\n请在{{.ExpireHours}}小时内打开以下链接完成邮箱验证：\n{{.Link}}\n\n如果这不是您本人的操作，请忽略本邮件。",
+	},
+	MailTypePasswordReset: {
+		Subject: "重置您的密码",
+		Body:    "{{.Name}}，您好：\n\n请在{{.ExpireMinutes}}分钟内打开以下链接重置密码：\n{{.Link}}\n\n如果这不是您本人的操作，请忽略本邮件，您的密码不会被修改。",
+	},
+	MailTypeGroupInvite: {
+		Subject: "{{.InviterName}} 邀请您加入群组「{{.GroupName}}」",
+		Body:    "您好：\n\n{{.InviterName}} 邀请您加入群组「{{.GroupName}}」。\n登录后使用以下邀请码加入群组：\n{{.InviteCode}}\n{{if .ExpireAt}}\n邀请码有效期至 {{.ExpireAt}}。\n{{end}}",
+	},
+	MailTypeQuotaAlert: {
+		Subject: "{{.Scope}}「{{.Name}}」存储配额不足",
+		Body:    "您好：\n\n{{.Scope}}「{{.Name}}」的存储配额不足，已有上传因超出配额被拒绝。\n已用：{{.Used}} 字节\n配额：{{.Quota}} 字节\n\n请清理文件或联系管理员调整配额。",
+	},
+}
+
+// RenderMail 按通知类型渲染邮件主题与正文
+func RenderMail(mailType string, data interface{}) (string, string, error) {
+	tpl, ok := defaultMailTemplates[mailType]
+	if !ok {
+		return "", "", fmt.Errorf("未知的邮件类型: %s", mailType)
+	}
+	if subject := viper.GetString("mail.templates." + mailType + ".subject"); subject != "" {
+		tpl.Subject = subject
+	}
+	if body := viper.GetString("mail.templates." + mailType + ".body"); body != "" {
+		tpl.Body = body
+	}
+
+	subject, err := executeMailTemplate(mailType+".subject", tpl.Subject, data)
+	if err != nil {
+		return "", "", err
+	}
+	body, err := executeMailTemplate(mailType+".body", tpl.Body, data)
+	if err != nil {
+		return "", "", err
+	}
+	return subject, body, nil
+}
+
+// SendMail 渲染指定类型的邮件模板并发送
+func SendMail(ctx context.Context, mailer Mailer, mailType, to string, data interface{}) error {
+	subject, body, err := RenderMail(mailType, data)
+	if err != nil {
+		return err
+	}
+	return mailer.Send(ctx, to, subject, body)
+}
+
+// executeMailTemplate 渲染单个模板
+func executeMailTemplate(name, text string, data interface{}) (string, error) {
+	tpl, err := template.New(name).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("解析邮件模板 %s 失败: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("渲染邮件模板 %s 失败: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// NewMailer 按 mail.driver 配置创建邮件发送器
+// 返回的发送器异步发送，发送失败只记录日志，不影响调用方的业务流程
+func NewMailer() Mailer {
+	var mailer Mailer
+	switch driver := viper.GetString("mail.driver"); driver {
+	case "smtp":
+		mailer = NewSMTPMailer(
+			viper.GetString("mail.smtp.host"),
+			viper.GetInt("mail.smtp.port"),
+			viper.GetString("mail.smtp.username"),
+			viper.GetString("mail.smtp.password"),
+			viper.GetString("mail.from"),
+		)
+	case "noop":
+		mailer = NewNoopMailer()
+	case "", "log":
+		mailer = NewLogMailer()
+	default:
+		log.Printf("未知的邮件发送方式 %q，使用日志方式", driver)
+		mailer = NewLogMailer()
+	}
+	return NewAsyncMailer(mailer)
+}
+
+// logMailer 只记录日志的邮件发送实现，未接入邮件服务时使用
+type logMailer struct{}
+
+// NewLogMailer 创建只记录日志的邮件发送器
+func NewLogMailer() Mailer {
+	return &logMailer{}
+}
+
+// Send 将邮件内容写入日志
+func (m *logMailer) Send(ctx context.Context, to, subject, body string) error {
+	log.Printf("发送邮件 to=%s subject=%s\n%s", to, subject, body)
+	return nil
+}
+
+// noopMailer 不发送任何邮件
+type noopMailer struct{}
+
+// NewNoopMailer 创建不发送邮件的发送器
+func NewNoopMailer() Mailer {
+	return &noopMailer{}
+}
+
+// Send 直接丢弃邮件
+func (m *noopMailer) Send(ctx context.Context, to, subject, body string) error {
+	return nil
+}
+
+// smtpMailer 通过SMTP服务器发送邮件，服务器支持时自动启用STARTTLS
+type smtpMailer struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+}
+
+// NewSMTPMailer 创建SMTP邮件发送器，username 为空时不进行认证
+func NewSMTPMailer(host string, port int, username, password, from string) Mailer {
+	if port <= 0 {
+		port = 587
+	}
+	return &smtpMailer{
+		addr:     net.JoinHostPort(host, strconv.Itoa(port)),
+		host:     host,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+// Send 发送纯文本邮件
+func (m *smtpMailer) Send(ctx context.Context, to, subject, body string) error {
+	from, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return fmt.Errorf("发件人地址无效: %w", err)
+	}
+	rcpt, err := mail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("收件人地址无效: %w", err)
+	}
+
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+	if err := smtp.SendMail(m.addr, auth, from.Address, []string{rcpt.Address}, buildMailMessage(from, rcpt, subject, body)); err != nil {
+		return fmt.Errorf("SMTP发送失败: %w", err)
+	}
+	return nil
+}
+
+// buildMailMessage 构造UTF-8纯文本邮件，正文使用base64编码
+func buildMailMessage(from, to *mail.Address, subject, body string) []byte {
+	var msg bytes.Buffer
+	msg.WriteString("From: " + from.String() + "\r\n")
+	msg.WriteString("To: " + to.String() + "\r\n")
+	msg.WriteString("Subject: " + mime.BEncoding.Encode("UTF-8", subject) + "\r\n")
+	msg.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(body))
+	for len(encoded) > 76 {
+		msg.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	msg.WriteString(encoded + "\r\n")
+	return msg.Bytes()
+}
+
+// asyncMailer 异步发送邮件，失败时只记录日志
+type asyncMailer struct {
+	mailer Mailer
+}
+
+// NewAsyncMailer 包装发送器，使发送在后台协程中进行
+func NewAsyncMailer(mailer Mailer) Mailer {
+	return &asyncMailer{mailer: mailer}
+}
+
+// Send 在后台发送邮件并立即返回；请求结束后仍需完成发送，因此不使用调用方的ctx
+func (m *asyncMailer) Send(ctx context.Context, to, subject, body string) error {
+	go func() {
+		if err := m.mailer.Send(context.Background(), to, subject, body); err != nil {
+			log.Printf("发送邮件失败 to=%s subject=%s: %v", to, subject, err)
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+)
+
+// sentMail 测试发送器记录的一封邮件
+type sentMail struct {
+	to, subject, body string
+}
+
+// testMailer 记录所有发送的邮件，err 不为空时发送失败
+type testMailer struct {
+	mu   sync.Mutex
+	sent []sentMail
+	err  error
+}
+
+func (m *testMailer) Send(ctx context.Context, to, subject, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, sentMail{to, subject, body})
+	return m.err
+}
+
+// messages 返回已发送邮件的副本
+func (m *testMailer) messages() []sentMail {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]sentMail(nil), m.sent...)
+}
+
+// TestMailNotifications 邮箱验证、群组邀请和配额告警通过配置的发送器发出，内容由对应模板渲染
+func TestMailNotifications(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t, &entity.User{})
+	userRepo := repository.NewUserRepository(db)
+	for _, u := range []*entity.User{
+		{ID: "alice", Email: "alice@example.com", Name: "Alice", Status: entity.UserStatusPendingVerification},
+		{ID: "bob", Email: "bob@example.com", Name: "Bob", Status: entity.UserStatusNormal},
+	} {
+		if err := userRepo.Create(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("邮箱验证", func(t *testing.T) {
+		viper.Set("auth.email_verification", true)
+		viper.Set("auth.verification_url", "https://oss.example.com/verify?token={token}")
+		t.Cleanup(func() {
+			viper.Set("auth.email_verification", false)
+			viper.Set("auth.verification_url", "")
+		})
+		mailer := &testMailer{}
+		svc := &userService{userRepo: userRepo, mailer: mailer, jwtSecret: []byte("0123456789abcdef0123456789abcdef")}
+
+		if err := svc.ResendVerificationEmail(ctx, "alice@example.com"); err != nil {
+			t.Fatalf("发送验证邮件失败: %v", err)
+		}
+		// 已验证和不存在的邮箱不发送
+		svc.ResendVerificationEmail(ctx, "bob@example.com")
+		svc.ResendVerificationEmail(ctx, "nobody@example.com")
+
+		sent := mailer.messages()
+		if len(sent) != 1 {
+			t.Fatalf("发送了 %d 封验证邮件，应为1封", len(sent))
+		}
+		if sent[0].to != "alice@example.com" || sent[0].subject != "请验证您的邮箱" {
+			t.Fatalf("验证邮件为 %+v", sent[0])
+		}
+		if !strings.Contains(sent[0].body, "Alice") || !strings.Contains(sent[0].body, "https://oss.example.com/verify?token=") {
+			t.Fatalf("验证邮件正文缺少用户名或验证链接: %s", sent[0].body)
+		}
+	})
+
+	t.Run("群组邀请", func(t *testing.T) {
+		mailer := &testMailer{}
+		svc := &groupService{userRepo: userRepo, mailer: mailer}
+		group := &entity.Group{ID: "group-1", Name: "设计组"}
+		expireAt := time.Date(2030, 1, 2, 15, 4, 0, 0, time.Local)
+		svc.sendInviteEmails(ctx, group, "bob", "INVITE-CODE", expireAt, []string{"carol@example.com", "dave@example.com"})
+
+		sent := mailer.messages()
+		if len(sent) != 2 || sent[0].to != "carol@example.com" || sent[1].to != "dave@example.com" {
+			t.Fatalf("邀请邮件为 %+v，应发给两个被邀请的邮箱", sent)
+		}
+		if sent[0].subject != "Bob 邀请您加入群组「设计组」" {
+			t.Fatalf("邀请邮件主题为 %q", sent[0].subject)
+		}
+		if !strings.Contains(sent[0].body, "INVITE-CODE") || !strings.Contains(sent[0].body, "2030-01-02 15:04") {
+			t.Fatalf("邀请邮件正文缺少邀请码或有效期: %s", sent[0].body)
+		}
+	})
+
+	t.Run("配额告警", func(t *testing.T) {
+		mailer := &testMailer{}
+		svc := &fileService{db: db, mailer: mailer}
+		targetID := "quota-alert-" + t.Name()
+		svc.notifyQuotaExceeded(ctx, "项目", targetID, "素材库", "bob", 120, 100)
+		// 告警间隔内同一对象只告警一次
+		svc.notifyQuotaExceeded(ctx, "项目", targetID, "素材库", "bob", 130, 100)
+
+		sent := mailer.messages()
+		if len(sent) != 1 {
+			t.Fatalf("发送了 %d 封配额告警，应为1封", len(sent))
+		}
+		if sent[0].to != "bob@example.com" || sent[0].subject != "项目「素材库」存储配额不足" {
+			t.Fatalf("配额告警为 %+v", sent[0])
+		}
+		if !strings.Contains(sent[0].body, "已用：120 字节") || !strings.Contains(sent[0].body, "配额：100 字节") {
+			t.Fatalf("配额告警正文缺少用量: %s", sent[0].body)
+		}
+	})
+
+	t.Run("密码重置模板", func(t *testing.T) {
+		mailer := &testMailer{}
+		data := map[string]interface{}{"Name": "Bob", "ExpireMinutes": 30, "Link": "https://oss.example.com/reset?token=abc"}
+		if err := SendMail(ctx, mailer, MailTypePasswordReset, "bob@example.com", data); err != nil {
+			t.Fatal(err)
+		}
+		sent := mailer.messages()
+		if len(sent) != 1 || sent[0].subject != "重置您的密码" || !strings.Contains(sent[0].body, "30分钟") || !strings.Contains(sent[0].body, "reset?token=abc") {
+			t.Fatalf("密码重置邮件为 %+v", sent)
+		}
+
+		// 配置的模板覆盖默认模板
+		viper.Set("mail.templates.password_reset.subject", "{{.Name}} 的密码重置")
+		t.Cleanup(func() { viper.Set("mail.templates.password_reset.subject", "") })
+		subject, _, err := RenderMail(MailTypePasswordReset, data)
+		if err != nil || subject != "Bob 的密码重置" {
+			t.Fatalf("覆盖后的主题为 %q, %v", subject, err)
+		}
+		if err := SendMail(ctx, mailer, "unknown", "bob@example.com", data); err == nil {
+			t.Fatalf("未知的邮件类型没有返回错误")
+		}
+	})
+}
+
+// TestAsyncMailerNonFatal 异步发送器立即返回，底层发送失败不影响调用方
+func TestAsyncMailerNonFatal(t *testing.T) {
+	inner := &testMailer{err: errors.New("smtp unavailable")}
+	mailer := NewAsyncMailer(inner)
+	if err := mailer.Send(context.Background(), "bob@example.com", "subject", "body"); err != nil {
+		t.Fatalf("异步发送返回了错误: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for len(inner.messages()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("后台没有发送邮件")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,461 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+
+	"oss-backend/internal/model/dto"
+	"oss-backend/internal/model/entity"
+)
+
+// ErrOAuthProviderNotFound 未配置该第三方登录提供方
+var ErrOAuthProviderNotFound = errors.New("不支持的登录方式")
+
+// ErrInvalidOAuthState 回调的 state 无效、已过期或与发起登录的浏览器不一致
+var ErrInvalidOAuthState = errors.New("登录请求无效或已过期，请重新登录")
+
+// ErrOAuthFailed 与第三方登录提供方交互失败
+var ErrOAuthFailed = errors.New("第三方登录失败")
+
+// ErrOAuthEmailNotVerified 第三方账号没有已验证的邮箱
+var ErrOAuthEmailNotVerified = errors.New("第三方账号的邮箱未验证")
+
+// ErrOAuthUserNotFound 邮箱没有对应的本地账号且未开启自动创建
+var ErrOAuthUserNotFound = errors.New("该邮箱没有对应的账号，请联系管理员")
+
+// ErrOAuthAccountDisabled 邮箱对应的本地账号已被禁用、锁定或删除
+var ErrOAuthAccountDisabled = errors.New("账号已被禁用或锁定")
+
+const (
+	// oauthStateExpiry 从跳转到登录提供方到回调的最长时间
+	oauthStateExpiry = 10 * time.Minute
+	// oauthStateSubjectSuffix state 令牌Subject后缀，前接提供方名称
+	oauthStateSubjectSuffix = ":oauth_state"
+	// OAuthCallbackPath 回调地址路径，%s 为提供方名称
+	OAuthCallbackPath = "/api/oss/auth/oauth/%s/callback"
+)
+
+// oauthProviderNamePattern 提供方名称即配置 oauth.providers 下的键
+var oauthProviderNamePattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// oauthHTTPClient 访问登录提供方使用的客户端
+var oauthHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// oauthProvider 第三方登录提供方配置
+type oauthProvider struct {
+	Name         string
+	Type         string // google、github、oidc
+	ClientID     string
+	ClientSecret string
+	AuthURL      string
+	TokenURL     string
+	UserInfoURL  string
+	Scopes       []string
+	RedirectURL  string
+	TrustEmail   bool // 用户信息不返回 email_verified 时视为邮箱已验证，仅用于确认会验证邮箱的提供方
+}
+
+// oauthIdentity 从登录提供方获取的用户信息
+type oauthIdentity struct {
+	Email         string
+	Name          string
+	EmailVerified bool
+}
+
+// oidcDiscovery 缓存通用OIDC提供方的发现文档，键为 issuer
+var oidcDiscovery sync.Map
+
+// loadOAuthProvider 读取 oauth.providers.<name> 配置，未配置 client_id 时视为未启用
+// type 默认与名称相同，名称不是 google 或 github 时为通用 oidc；google、github 的地址有默认值，
+// 通用OIDC可配置 issuer 通过发现文档获取地址，也可直接配置各地址
+func loadOAuthProvider(ctx context.Context, name string) (*oauthProvider, error) {
+	if !oauthProviderNamePattern.MatchString(name) {
+		return nil, ErrOAuthProviderNotFound
+	}
+	key := "oauth.providers." + name
+	if viper.GetString(key+".client_id") == "" {
+		return nil, ErrOAuthProviderNotFound
+	}
+
+	provider := &oauthProvider{
+		Name:         name,
+		Type:         viper.GetString(key + ".type"),
+		ClientID:     viper.GetString(key + ".client_id"),
+		ClientSecret: viper.GetString(key + ".client_secret"),
+		AuthURL:      viper.GetString(key + ".auth_url"),
+		TokenURL:     viper.GetString(key + ".token_url"),
+		UserInfoURL:  viper.GetString(key + ".userinfo_url"),
+		Scopes:       viper.GetStringSlice(key + ".scopes"),
+		RedirectURL:  viper.GetString(key + ".redirect_url"),
+		TrustEmail:   viper.GetBool(key + ".trust_email"),
+	}
+	if provider.Type == "" {
+		switch name {
+		case "google", "github":
+			provider.Type = name
+		default:
+			provider.Type = "oidc"
+		}
+	}
+
+	var defaults oauthProvider
+	switch provider.Type {
+	case "google":
+		defaults = oauthProvider{
+			AuthURL:     "https://accounts.google.com/o/oauth2/v2/auth",
+			TokenURL:    "https://oauth2.googleapis.com/token",
+			UserInfoURL: "https://openidconnect.googleapis.com/v1/userinfo",
+			Scopes:      []string{"openid", "email", "profile"},
+		}
+	case "github":
+		defaults = oauthProvider{
+			AuthURL:     "https://github.com/login/oauth/authorize",
+			TokenURL:    "https://github.com/login/oauth/access_token",
+			UserInfoURL: "https://api.github.com/user",
+			Scopes:      []string{"read:user", "user:email"},
+		}
+	case "oidc":
+		defaults.Scopes = []string{"openid", "email", "profile"}
+		if issuer := viper.GetString(key + ".issuer"); issuer != "" && (provider.AuthURL == "" || provider.TokenURL == "" || provider.UserInfoURL == "") {
+			discovered, err := discoverOIDC(ctx, issuer)
+			if err != nil {
+				return nil, err
+			}
+			defaults.AuthURL = discovered.AuthURL
+			defaults.TokenURL = discovered.TokenURL
+			defaults.UserInfoURL = discovered.UserInfoURL
+		}
+	default:
+		return nil, fmt.Errorf("%w: 提供方 %s 的类型 %q 无效", ErrOAuthProviderNotFound, name, provider.Type)
+	}
+	if provider.AuthURL == "" {
+		provider.AuthURL = defaults.AuthURL
+	}
+	if provider.TokenURL == "" {
+		provider.TokenURL = defaults.TokenURL
+	}
+	if provider.UserInfoURL == "" {
+		provider.UserInfoURL = defaults.UserInfoURL
+	}
+	if len(provider.Scopes) == 0 {
+		provider.Scopes = defaults.Scopes
+	}
+	if provider.AuthURL == "" || provider.TokenURL == "" || provider.UserInfoURL == "" {
+		return nil, fmt.Errorf("%w: 提供方 %s 缺少 issuer 或授权、令牌、用户信息地址", ErrOAuthProviderNotFound, name)
+	}
+	if provider.RedirectURL == "" {
+		provider.RedirectURL = strings.TrimSuffix(viper.GetString("oauth.redirect_base_url"), "/") + fmt.Sprintf(OAuthCallbackPath, name)
+	}
+	return provider, nil
+}
+
+// discoverOIDC 读取 issuer 的发现文档，成功后缓存
+func discoverOIDC(ctx context.Context, issuer string) (*oauthProvider, error) {
+	issuer = strings.TrimSuffix(issuer, "/")
+	if cached, ok := oidcDiscovery.Load(issuer); ok {
+		return cached.(*oauthProvider), nil
+	}
+	var doc struct {
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	}
+	if err := oauthGetJSON(ctx, issuer+"/.well-known/openid-configuration", "", &doc); err != nil {
+		return nil, fmt.Errorf("%w: 读取OIDC发现文档失败: %v", ErrOAuthFailed, err)
+	}
+	discovered := &oauthProvider{
+		AuthURL:     doc.AuthorizationEndpoint,
+		TokenURL:    doc.TokenEndpoint,
+		UserInfoURL: doc.UserinfoEndpoint,
+	}
+	oidcDiscovery.Store(issuer, discovered)
+	return discovered, nil
+}
+
+// oauthGetJSON 发送GET请求并解析JSON响应，accessToken 不为空时作为 Bearer 令牌
+func oauthGetJSON(ctx context.Context, endpoint, accessToken string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	resp, err := oauthHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s 返回状态码 %d", endpoint, resp.StatusCode)
+	}
+	return json.Unmarshal(body, out)
+}
+
+// exchangeCode 使用授权码换取访问令牌
+func (p *oauthProvider) exchangeCode(ctx context.Context, code string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.RedirectURL)
+	form.Set("client_id", p.ClientID)
+	form.Set("client_secret", p.ClientSecret)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := oauthHTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var token struct {
+		AccessToken      string `json:"access_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&token); err != nil {
+		return "", fmt.Errorf("解析令牌响应失败（状态码 %d）: %w", resp.StatusCode, err)
+	}
+	if token.Error != "" {
+		return "", fmt.Errorf("%s: %s", token.Error, token.ErrorDescription)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("令牌响应缺少 access_token（状态码 %d）", resp.StatusCode)
+	}
+	return token.AccessToken, nil
+}
+
+// fetchIdentity 使用访问令牌获取用户邮箱和名称
+func (p *oauthProvider) fetchIdentity(ctx context.Context, accessToken string) (*oauthIdentity, error) {
+	if p.Type == "github" {
+		return p.fetchGitHubIdentity(ctx, accessToken)
+	}
+
+	var info struct {
+		Email         string      `json:"email"`
+		EmailVerified interface{} `json:"email_verified"`
+		Name          string      `json:"name"`
+	}
+	if err := oauthGetJSON(ctx, p.UserInfoURL, accessToken, &info); err != nil {
+		return nil, err
+	}
+	identity := &oauthIdentity{Email: info.Email, Name: info.Name}
+	// 部分提供方以字符串返回 email_verified
+	switch verified := info.EmailVerified.(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	case nil:
+		identity.EmailVerified = p.TrustEmail
+	}
+	return identity, nil
+}
+
+// fetchGitHubIdentity GitHub 的用户信息不包含邮箱验证状态，从邮箱列表中取已验证的主邮箱
+func (p *oauthProvider) fetchGitHubIdentity(ctx context.Context, accessToken string) (*oauthIdentity, error) {
+	var user struct {
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if err := oauthGetJSON(ctx, p.UserInfoURL, accessToken, &user); err != nil {
+		return nil, err
+	}
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	emailsURL := strings.TrimSuffix(p.UserInfoURL, "/") + "/emails"
+	if err := oauthGetJSON(ctx, emailsURL, accessToken, &emails); err != nil {
+		return nil, err
+	}
+	identity := &oauthIdentity{Name: user.Name}
+	if identity.Name == "" {
+		identity.Name = user.Login
+	}
+	for _, email := range emails {
+		if email.Primary {
+			identity.Email = email.Email
+			identity.EmailVerified = email.Verified
+			break
+		}
+	}
+	return identity, nil
+}
+
+// oauthStateKey 由JWT密钥派生签名 state 的密钥，state 不能当作访问令牌使用
+func (s *userService) oauthStateKey() []byte {
+	mac := hmac.New(sha256.New, s.jwtSecret)
+	mac.Write([]byte("oss-backend/oauth-state"))
+	return mac.Sum(nil)
+}
+
+// OAuthAuthURL 生成跳转到第三方登录提供方的授权地址
+// 返回的 nonce 需由调用方保存在浏览器 Cookie 中，回调时与 state 比对，防止跨站请求伪造
+func (s *userService) OAuthAuthURL(ctx context.Context, providerName string) (string, string, error) {
+	provider, err := loadOAuthProvider(ctx, providerName)
+	if err != nil {
+		return "", "", err
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	nonce := hex.EncodeToString(buf)
+	now := time.Now()
+	state, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		ID:        nonce,
+		Subject:   provider.Name + oauthStateSubjectSuffix,
+		ExpiresAt: jwt.NewNumericDate(now.Add(oauthStateExpiry)),
+		IssuedAt:  jwt.NewNumericDate(now),
+	}).SignedString(s.oauthStateKey())
+	if err != nil {
+		return "", "", fmt.Errorf("生成登录请求失败: %w", err)
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", provider.ClientID)
+	query.Set("redirect_uri", provider.RedirectURL)
+	query.Set("scope", strings.Join(provider.Scopes, " "))
+	query.Set("state", state)
+	separator := "?"
+	if strings.Contains(provider.AuthURL, "?") {
+		separator = "&"
+	}
+	return provider.AuthURL + separator + query.Encode(), nonce, nil
+}
+
+// OAuthLogin 处理第三方登录回调：校验 state，用授权码换取令牌并获取已验证的邮箱，
+// 按邮箱找到本地账号（oauth.auto_provision 开启时自动创建并分配普通成员角色）后签发与本地登录相同的令牌；
+// 账号开启两步验证时同样返回两步验证挑战
+func (s *userService) OAuthLogin(ctx context.Context, providerName, code, state, nonce, ip string) (*dto.LoginResponse, error) {
+	provider, err := loadOAuthProvider(ctx, providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &jwt.RegisteredClaims{}
+	token, err := jwt.ParseWithClaims(state, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return s.oauthStateKey(), nil
+	})
+	if err != nil || !token.Valid || claims.Subject != provider.Name+oauthStateSubjectSuffix ||
+		nonce == "" || !hmac.Equal([]byte(claims.ID), []byte(nonce)) {
+		return nil, ErrInvalidOAuthState
+	}
+	if code == "" {
+		return nil, fmt.Errorf("%w: 缺少授权码", ErrOAuthFailed)
+	}
+
+	accessToken, err := provider.exchangeCode(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("%w: 换取令牌失败: %v", ErrOAuthFailed, err)
+	}
+	identity, err := provider.fetchIdentity(ctx, accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: 获取用户信息失败: %v", ErrOAuthFailed, err)
+	}
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, ErrOAuthEmailNotVerified
+	}
+
+	user, err := s.oauthUser(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("用户 %s 通过 %s 登录", user.ID, provider.Name)
+
+	if user.TOTPEnabled {
+		return s.newTwoFactorChallenge(user)
+	}
+	return s.completeLogin(ctx, user, ip)
+}
+
+// oauthUser 按已验证的邮箱获取本地账号，待验证邮箱的账号直接激活，不存在时按配置自动创建
+func (s *userService) oauthUser(ctx context.Context, identity *oauthIdentity) (*entity.User, error) {
+	user, err := s.userRepo.GetByEmail(ctx, identity.Email)
+	if err == nil {
+		switch user.Status {
+		case entity.UserStatusNormal:
+			return user, nil
+		case entity.UserStatusPendingVerification:
+			// 登录提供方已验证邮箱
+			if err := s.userRepo.UpdateStatus(ctx, user.ID, entity.UserStatusNormal); err != nil {
+				return nil, err
+			}
+			user.Status = entity.UserStatusNormal
+			s.acceptGroupInvitations(ctx, user)
+			return user, nil
+		default:
+			return nil, ErrOAuthAccountDisabled
+		}
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("获取用户信息失败: %w", err)
+	}
+	if viper.IsSet("oauth.auto_provision") && !viper.GetBool("oauth.auto_provision") {
+		return nil, ErrOAuthUserNotFound
+	}
+
+	// 自动创建的账号没有可用的本地密码
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(hex.EncodeToString(random)), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	name := identity.Name
+	if name == "" {
+		name = strings.SplitN(identity.Email, "@", 2)[0]
+	}
+	if len([]rune(name)) > 50 {
+		name = string([]rune(name)[:50])
+	}
+	user = &entity.User{
+		Email:        identity.Email,
+		Name:         name,
+		PasswordHash: string(passwordHash),
+		Status:       entity.UserStatusNormal,
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, fmt.Errorf("创建用户失败: %w", err)
+	}
+	s.assignDefaultRole(ctx, user)
+	s.acceptGroupInvitations(ctx, user)
+	return user, nil
+}
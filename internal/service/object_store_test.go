@@ -0,0 +1,272 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"oss-backend/pkg/minio"
+)
+
+// fakeObjectStore 内存中的对象存储，实现 Client 用到的最少的 S3 接口：存储桶检查与创建、生命周期规则、上传、复制、读取、列出和删除对象
+type fakeObjectStore struct {
+	mu         sync.Mutex
+	buckets    map[string]bool
+	objects    map[string][]byte // 键为 存储桶/对象名
+	lifecycles map[string][]byte // 存储桶的生命周期配置XML
+
+	// beforeCopy 在执行复制前调用，用于在测试中插入并发操作
+	beforeCopy func(bucket, src, dst string)
+}
+
+// newFakeObjectStore 启动内存对象存储，返回连接到它的客户端
+func newFakeObjectStore(t testing.TB) (*fakeObjectStore, *minio.Client) {
+	t.Helper()
+	store := &fakeObjectStore{buckets: map[string]bool{}, objects: map[string][]byte{}, lifecycles: map[string][]byte{}}
+	server := httptest.NewServer(store)
+	t.Cleanup(server.Close)
+
+	client, err := minio.NewClient(minio.Config{
+		Endpoint:  strings.TrimPrefix(server.URL, "http://"),
+		AccessKey: "test",
+		SecretKey: "test-secret",
+	})
+	if err != nil {
+		t.Fatalf("创建对象存储客户端失败: %v", err)
+	}
+	return store, client
+}
+
+// object 获取对象内容
+func (s *fakeObjectStore) object(bucket, key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.objects[bucket+"/"+key]
+	return data, ok
+}
+
+// putObject 直接写入对象
+func (s *fakeObjectStore) putObject(bucket, key string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buckets[bucket] = true
+	s.objects[bucket+"/"+key] = data
+}
+
+// keys 列出存储桶中指定前缀下的对象名
+func (s *fakeObjectStore) keys(bucket, prefix string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var keys []string
+	for k := range s.objects {
+		if key, ok := strings.CutPrefix(k, bucket+"/"); ok && strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *fakeObjectStore) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+
+	switch {
+	case key == "" && r.URL.Query().Has("lifecycle"):
+		s.bucketLifecycle(w, r, bucket)
+	case key == "" && r.URL.Query().Has("location"):
+		fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/"></LocationConstraint>`)
+	case key == "" && r.Method == http.MethodHead:
+		s.mu.Lock()
+		exists := s.buckets[bucket]
+		s.mu.Unlock()
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case key == "" && r.Method == http.MethodPut:
+		s.mu.Lock()
+		s.buckets[bucket] = true
+		s.mu.Unlock()
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		s.copyObject(w, r, bucket, key)
+	case r.Method == http.MethodPut:
+		data, err := readObjectBody(r)
+		if err != nil {
+			writeS3Error(w, http.StatusBadRequest, "InvalidRequest", err.Error())
+			return
+		}
+		s.putObject(bucket, key, data)
+		w.Header().Set("ETag", `"etag"`)
+	case key == "" && r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+		s.listObjects(w, bucket, r.URL.Query().Get("prefix"), r.URL.Query().Get("delimiter"))
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		data, ok := s.object(bucket, key)
+		if !ok {
+			writeS3Error(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.Header().Set("ETag", `"etag"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case r.Method == http.MethodDelete:
+		s.mu.Lock()
+		delete(s.objects, bucket+"/"+key)
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeS3Error(w, http.StatusNotImplemented, "NotImplemented", r.Method+" "+r.URL.String())
+	}
+}
+
+// lifecycle 获取存储桶的生命周期配置XML
+func (s *fakeObjectStore) lifecycle(bucket string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	config, ok := s.lifecycles[bucket]
+	return config, ok
+}
+
+// bucketLifecycle 处理存储桶生命周期配置的读取、设置和删除
+func (s *fakeObjectStore) bucketLifecycle(w http.ResponseWriter, r *http.Request, bucket string) {
+	switch r.Method {
+	case http.MethodGet:
+		config, ok := s.lifecycle(bucket)
+		if !ok {
+			writeS3Error(w, http.StatusNotFound, "NoSuchLifecycleConfiguration", "The lifecycle configuration does not exist.")
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write(config)
+	case http.MethodPut:
+		config, err := readObjectBody(r)
+		if err != nil {
+			writeS3Error(w, http.StatusBadRequest, "InvalidRequest", err.Error())
+			return
+		}
+		s.mu.Lock()
+		s.lifecycles[bucket] = config
+		s.mu.Unlock()
+	case http.MethodDelete:
+		s.mu.Lock()
+		delete(s.lifecycles, bucket)
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeS3Error(w, http.StatusNotImplemented, "NotImplemented", r.Method+" "+r.URL.String())
+	}
+}
+
+// copyObject 处理服务端复制
+func (s *fakeObjectStore) copyObject(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	source, err := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+	if err != nil {
+		writeS3Error(w, http.StatusBadRequest, "InvalidArgument", err.Error())
+		return
+	}
+	srcBucket, srcKey, _ := strings.Cut(strings.TrimPrefix(source, "/"), "/")
+	if s.beforeCopy != nil {
+		s.beforeCopy(srcBucket, srcKey, key)
+	}
+
+	data, ok := s.object(srcBucket, srcKey)
+	if !ok {
+		writeS3Error(w, http.StatusNotFound, "NoSuchKey", "The specified key does not exist.")
+		return
+	}
+	s.putObject(bucket, key, append([]byte(nil), data...))
+	fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><CopyObjectResult><ETag>"etag"</ETag><LastModified>2006-01-02T15:04:05.000Z</LastModified></CopyObjectResult>`)
+}
+
+// listObjects 处理 ListObjectsV2，一次返回全部结果；指定分隔符时将下一级目录合并为 CommonPrefixes
+func (s *fakeObjectStore) listObjects(w http.ResponseWriter, bucket, prefix, delimiter string) {
+	type content struct {
+		Key          string
+		LastModified string
+		ETag         string
+		Size         int
+	}
+	type commonPrefix struct {
+		Prefix string
+	}
+	result := struct {
+		XMLName        xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
+		Name           string
+		Prefix         string
+		Delimiter      string
+		KeyCount       int
+		MaxKeys        int
+		IsTruncated    bool
+		Contents       []content
+		CommonPrefixes []commonPrefix
+	}{Name: bucket, Prefix: prefix, Delimiter: delimiter, MaxKeys: 1000}
+
+	seen := map[string]bool{}
+	for _, key := range s.keys(bucket, prefix) {
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				dir := key[:len(prefix)+i+len(delimiter)]
+				if !seen[dir] {
+					seen[dir] = true
+					result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: dir})
+				}
+				continue
+			}
+		}
+		data, _ := s.object(bucket, key)
+		result.Contents = append(result.Contents, content{Key: key, LastModified: "2006-01-02T15:04:05.000Z", ETag: `"etag"`, Size: len(data)})
+	}
+	result.KeyCount = len(result.Contents) + len(result.CommonPrefixes)
+
+	w.Header().Set("Content-Type", "application/xml")
+	fmt.Fprint(w, xml.Header)
+	xml.NewEncoder(w).Encode(result)
+}
+
+// readObjectBody 读取上传的对象内容，按需解码 aws-chunked 编码
+func readObjectBody(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+
+	var data bytes.Buffer
+	reader := bufio.NewReader(r.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("分块大小无效: %q", line)
+		}
+		if size == 0 {
+			return data.Bytes(), nil
+		}
+		if _, err := io.CopyN(&data, reader, size); err != nil {
+			return nil, err
+		}
+		if _, err := reader.Discard(2); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// writeS3Error 返回 S3 格式的错误
+func writeS3Error(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>%s</Code><Message>%s</Message></Error>`, code, message)
+}
@@ -0,0 +1,267 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+
+	"oss-backend/internal/model/dto"
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+)
+
+// PolicyService 授权策略备份与恢复服务接口
+type PolicyService interface {
+	// ExportPolicies 导出全部策略与角色继承规则
+	ExportPolicies(ctx context.Context, adminID string) (*dto.PolicySnapshot, error)
+	// ImportPolicies 校验并应用策略快照，dryRun为true时只返回差异
+	ImportPolicies(ctx context.Context, adminID string, snapshot *dto.PolicySnapshot, dryRun bool) (*dto.PolicyImportResponse, error)
+}
+
+// policyService 授权策略备份与恢复服务实现
+type policyService struct {
+	enforcer   *casbin.Enforcer
+	casbinRepo repository.CasbinRepository
+}
+
+// NewPolicyService 创建授权策略服务
+func NewPolicyService(enforcer *casbin.Enforcer, casbinRepo repository.CasbinRepository) PolicyService {
+	return &policyService{
+		enforcer:   enforcer,
+		casbinRepo: casbinRepo,
+	}
+}
+
+// ExportPolicies 导出全部策略与角色继承规则
+func (s *policyService) ExportPolicies(ctx context.Context, adminID string) (*dto.PolicySnapshot, error) {
+	policies, groupings, err := s.currentRules()
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.PolicySnapshot{
+		Version:    dto.PolicySnapshotVersion,
+		ExportedAt: time.Now(),
+		ExportedBy: adminID,
+		Policies:   policies,
+		Groupings:  groupings,
+	}, nil
+}
+
+// ImportPolicies 校验并应用策略快照
+func (s *policyService) ImportPolicies(ctx context.Context, adminID string, snapshot *dto.PolicySnapshot, dryRun bool) (*dto.PolicyImportResponse, error) {
+	// 1. 校验快照
+	if err := s.validateSnapshot(snapshot); err != nil {
+		return nil, err
+	}
+
+	// 2. 计算与当前策略的差异
+	currentPolicies, currentGroupings, err := s.currentRules()
+	if err != nil {
+		return nil, err
+	}
+	current := append(append([]dto.PolicyRule{}, currentPolicies...), currentGroupings...)
+	target := append(append([]dto.PolicyRule{}, snapshot.Policies...), snapshot.Groupings...)
+	added, removed := diffRules(current, target)
+
+	response := &dto.PolicyImportResponse{
+		DryRun:  dryRun,
+		Added:   added,
+		Removed: removed,
+	}
+	if dryRun || (len(added) == 0 && len(removed) == 0) {
+		return response, nil
+	}
+
+	// 3. 保存导入前快照用于审计与回滚
+	previous, err := json.Marshal(&dto.PolicySnapshot{
+		Version:    dto.PolicySnapshotVersion,
+		ExportedAt: time.Now(),
+		ExportedBy: adminID,
+		Policies:   currentPolicies,
+		Groupings:  currentGroupings,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("序列化当前策略失败: %w", err)
+	}
+
+	// 4. 先删除后新增，按规则类型批量提交
+	if err := s.applyRules(removed, false); err != nil {
+		return nil, fmt.Errorf("删除策略失败: %w", err)
+	}
+	if err := s.applyRules(added, true); err != nil {
+		return nil, fmt.Errorf("新增策略失败: %w", err)
+	}
+	response.Applied = true
+
+	// 5. 记录审计，失败不影响导入结果
+	record := &entity.PolicyImportRecord{
+		AdminID:        adminID,
+		Version:        snapshot.Version,
+		AddedCount:     len(added),
+		RemovedCount:   len(removed),
+		PreviousPolicy: string(previous),
+	}
+	if err := s.casbinRepo.CreateImportRecord(ctx, record); err != nil {
+		log.Printf("记录策略导入审计失败: %v", err)
+	}
+
+	return response, nil
+}
+
+// currentRules 获取当前全部规则，按类型与字段排序
+func (s *policyService) currentRules() ([]dto.PolicyRule, []dto.PolicyRule, error) {
+	model := s.enforcer.GetModel()
+
+	var policies []dto.PolicyRule
+	for ptype := range model["p"] {
+		rules, err := s.enforcer.GetNamedPolicy(ptype)
+		if err != nil {
+			return nil, nil, fmt.Errorf("获取策略失败: %w", err)
+		}
+		for _, rule := range rules {
+			policies = append(policies, dto.PolicyRule{PType: ptype, Values: rule})
+		}
+	}
+
+	var groupings []dto.PolicyRule
+	for ptype := range model["g"] {
+		rules, err := s.enforcer.GetNamedGroupingPolicy(ptype)
+		if err != nil {
+			return nil, nil, fmt.Errorf("获取角色继承规则失败: %w", err)
+		}
+		for _, rule := range rules {
+			groupings = append(groupings, dto.PolicyRule{PType: ptype, Values: rule})
+		}
+	}
+
+	sortRules(policies)
+	sortRules(groupings)
+	return policies, groupings, nil
+}
+
+// validateSnapshot 校验快照版本、规则类型与字段数量
+func (s *policyService) validateSnapshot(snapshot *dto.PolicySnapshot) error {
+	if snapshot == nil {
+		return errors.New("策略快照不能为空")
+	}
+	if snapshot.Version != dto.PolicySnapshotVersion {
+		return fmt.Errorf("不支持的策略快照版本: %d", snapshot.Version)
+	}
+
+	model := s.enforcer.GetModel()
+	check := func(sec string, rules []dto.PolicyRule) error {
+		seen := make(map[string]bool, len(rules))
+		for i, rule := range rules {
+			assertion, ok := model[sec][rule.PType]
+			if !ok {
+				return fmt.Errorf("第%d条规则类型无效: %s", i+1, rule.PType)
+			}
+			if len(rule.Values) != len(assertion.Tokens) {
+				return fmt.Errorf("第%d条规则字段数量应为%d，实际为%d", i+1, len(assertion.Tokens), len(rule.Values))
+			}
+			for _, v := range rule.Values {
+				if strings.TrimSpace(v) == "" {
+					return fmt.Errorf("第%d条规则存在空字段", i+1)
+				}
+			}
+			key := ruleKey(rule)
+			if seen[key] {
+				return fmt.Errorf("第%d条规则重复: %s", i+1, key)
+			}
+			seen[key] = true
+		}
+		return nil
+	}
+
+	if err := check("p", snapshot.Policies); err != nil {
+		return fmt.Errorf("策略规则校验失败: %w", err)
+	}
+	if err := check("g", snapshot.Groupings); err != nil {
+		return fmt.Errorf("角色继承规则校验失败: %w", err)
+	}
+	return nil
+}
+
+// applyRules 按规则类型批量新增或删除
+func (s *policyService) applyRules(rules []dto.PolicyRule, add bool) error {
+	model := s.enforcer.GetModel()
+
+	byType := make(map[string][][]string)
+	var ptypes []string
+	for _, rule := range rules {
+		if _, ok := byType[rule.PType]; !ok {
+			ptypes = append(ptypes, rule.PType)
+		}
+		byType[rule.PType] = append(byType[rule.PType], rule.Values)
+	}
+
+	for _, ptype := range ptypes {
+		batch := byType[ptype]
+		_, isGrouping := model["g"][ptype]
+
+		var err error
+		switch {
+		case add && isGrouping:
+			_, err = s.enforcer.AddNamedGroupingPolicies(ptype, batch)
+		case add:
+			_, err = s.enforcer.AddNamedPolicies(ptype, batch)
+		case isGrouping:
+			_, err = s.enforcer.RemoveNamedGroupingPolicies(ptype, batch)
+		default:
+			_, err = s.enforcer.RemoveNamedPolicies(ptype, batch)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diffRules 计算从 current 变为 target 需要新增和删除的规则
+func diffRules(current, target []dto.PolicyRule) ([]dto.PolicyRule, []dto.PolicyRule) {
+	currentSet := make(map[string]bool, len(current))
+	for _, rule := range current {
+		currentSet[ruleKey(rule)] = true
+	}
+	targetSet := make(map[string]bool, len(target))
+	for _, rule := range target {
+		targetSet[ruleKey(rule)] = true
+	}
+
+	added := []dto.PolicyRule{}
+	for _, rule := range target {
+		if !currentSet[ruleKey(rule)] {
+			added = append(added, rule)
+		}
+	}
+	removed := []dto.PolicyRule{}
+	for _, rule := range current {
+		if !targetSet[ruleKey(rule)] {
+			removed = append(removed, rule)
+		}
+	}
+
+	sortRules(added)
+	sortRules(removed)
+	return added, removed
+}
+
+// sortRules 按类型与字段排序，保证导出结果稳定
+func sortRules(rules []dto.PolicyRule) {
+	sort.Slice(rules, func(i, j int) bool {
+		return ruleKey(rules[i]) < ruleKey(rules[j])
+	})
+}
+
+// ruleKey 规则的唯一标识
+func ruleKey(rule dto.PolicyRule) string {
+	return rule.PType + ", " + strings.Join(rule.Values, ", ")
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/casbin/casbin/v2"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+)
+
+// testCasbinRepo 记录策略导入审计的仓库
+type testCasbinRepo struct {
+	repository.CasbinRepository
+	records []*entity.PolicyImportRecord
+}
+
+func (r *testCasbinRepo) CreateImportRecord(ctx context.Context, record *entity.PolicyImportRecord) error {
+	r.records = append(r.records, record)
+	return nil
+}
+
+// TestPolicyExportImportRoundTrip 导出策略、清空后重新导入，所有请求的授权结果与导出前相同
+func TestPolicyExportImportRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	enforcer, err := casbin.NewEnforcer("../../configs/rbac_model.conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	enforcer.AddPolicies([][]string{
+		{"role:admin", "group:g1", "*", "*"},
+		{"role:editor", "project:p1", ResourceFile, ActionRead},
+		{"role:editor", "project:p1", ResourceFile, ActionUpdate},
+		{"user:carol", "project:p2", ResourceFile, ActionRead},
+	})
+	enforcer.AddGroupingPolicies([][]string{
+		{"user:alice", "role:admin", "group:g1"},
+		{"user:bob", "role:editor", "project:p1"},
+	})
+	repo := &testCasbinRepo{}
+	svc := NewPolicyService(enforcer, repo)
+
+	subjects := []string{"user:alice", "user:bob", "user:carol", "user:dave"}
+	domains := []string{"group:g1", "project:p1", "project:p2"}
+	actions := []string{ActionCreate, ActionRead, ActionUpdate, ActionDelete}
+	decisions := func() []bool {
+		var results []bool
+		for _, sub := range subjects {
+			for _, dom := range domains {
+				for _, act := range actions {
+					ok, err := enforcer.Enforce(sub, dom, ResourceFile, act)
+					if err != nil {
+						t.Fatal(err)
+					}
+					results = append(results, ok)
+				}
+			}
+		}
+		return results
+	}
+	before := decisions()
+
+	snapshot, err := svc.ExportPolicies(ctx, "admin-1")
+	if err != nil {
+		t.Fatalf("导出策略失败: %v", err)
+	}
+	if len(snapshot.Policies) != 4 || len(snapshot.Groupings) != 2 {
+		t.Fatalf("导出 %d 条策略、%d 条角色继承，应为4条和2条", len(snapshot.Policies), len(snapshot.Groupings))
+	}
+
+	enforcer.ClearPolicy()
+	if reflect.DeepEqual(decisions(), before) {
+		t.Fatalf("清空策略后授权结果没有变化")
+	}
+
+	// 预览只返回差异，不应用
+	preview, err := svc.ImportPolicies(ctx, "admin-1", snapshot, true)
+	if err != nil {
+		t.Fatalf("预览导入失败: %v", err)
+	}
+	if preview.Applied || len(preview.Added) != 6 || len(preview.Removed) != 0 {
+		t.Fatalf("预览结果为 applied=%v added=%d removed=%d，应为未应用、新增6条", preview.Applied, len(preview.Added), len(preview.Removed))
+	}
+	if policies, _ := enforcer.GetPolicy(); len(policies) != 0 {
+		t.Fatalf("预览导入修改了策略")
+	}
+
+	result, err := svc.ImportPolicies(ctx, "admin-1", snapshot, false)
+	if err != nil {
+		t.Fatalf("导入策略失败: %v", err)
+	}
+	if !result.Applied {
+		t.Fatalf("导入未应用")
+	}
+	if after := decisions(); !reflect.DeepEqual(after, before) {
+		t.Fatalf("重新导入后授权结果与导出前不同")
+	}
+	if len(repo.records) != 1 || repo.records[0].AddedCount != 6 || repo.records[0].AdminID != "admin-1" {
+		t.Fatalf("导入审计记录为 %+v，应记录一次新增6条", repo.records)
+	}
+
+	reexported, err := svc.ExportPolicies(ctx, "admin-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reexported.Policies, snapshot.Policies) || !reflect.DeepEqual(reexported.Groupings, snapshot.Groupings) {
+		t.Fatalf("重新导出的规则与原快照不同")
+	}
+}
@@ -5,12 +5,16 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"oss-backend/internal/model/dto"
 	"oss-backend/internal/model/entity"
 	"oss-backend/internal/repository"
+	"oss-backend/internal/utils"
+	"oss-backend/pkg/logger"
 	"oss-backend/pkg/minio"
 )
 
@@ -18,6 +22,7 @@ import (
 const (
 	ProjectStatusActive   = "active"
 	ProjectStatusInactive = "inactive"
+	ProjectStatusArchived = "archived"
 	ProjectStatusDeleted  = "deleted"
 )
 
@@ -34,9 +39,23 @@ type ProjectService interface {
 	CreateProject(ctx context.Context, req *dto.CreateProjectRequest, creatorID string) (*dto.ProjectResponse, error)
 	UpdateProject(ctx context.Context, req *dto.UpdateProjectRequest, userID string) (*dto.ProjectResponse, error)
 	GetProjectByID(ctx context.Context, id string, userID string) (*dto.ProjectResponse, error)
-	ListProjects(ctx context.Context, groupID string, userID string, query *dto.ProjectQuery) (*dto.PaginatedProjectResponse, error)
-	GetUserProjects(ctx context.Context, query *dto.ProjectQuery, userID string) ([]*dto.ProjectResponse, int64, error)
+	ListProjects(ctx context.Context, groupID string, userID string, query *dto.ProjectQuery) (*dto.PageResult[*dto.ProjectResponse], error)
+	GetUserProjects(ctx context.Context, query *dto.ProjectQuery, userID string) (*dto.PageResult[*dto.ProjectResponse], error)
 	DeleteProject(ctx context.Context, id string, userID string) error
+	CloneProject(ctx context.Context, sourceProjectID, newName, userID string, includeMembers bool) (*dto.ProjectResponse, error)
+	ArchiveProject(ctx context.Context, id string, userID string) (*dto.ProjectResponse, error)
+	UnarchiveProject(ctx context.Context, id string, userID string) (*dto.ProjectResponse, error)
+	GetProjectStorageTrend(ctx context.Context, projectID, userID string, from, to time.Time) (*dto.StorageTrendResponse, error)
+
+	// 项目分享策略
+	SetSharePolicy(ctx context.Context, req *dto.SharePolicyRequest, userID string) (*dto.SharePolicyResponse, error)
+	GetSharePolicy(ctx context.Context, projectID, userID string) (*dto.SharePolicyResponse, error)
+
+	// 项目Webhook
+	ListWebhooks(ctx context.Context, projectID, userID string) ([]*dto.WebhookResponse, error)
+	CreateWebhook(ctx context.Context, projectID string, req *dto.WebhookRequest, userID string) (*dto.WebhookResponse, error)
+	UpdateWebhook(ctx context.Context, projectID, webhookID string, req *dto.WebhookRequest, userID string) (*dto.WebhookResponse, error)
+	DeleteWebhook(ctx context.Context, projectID, webhookID, userID string) error
 
 	// 项目权限操作
 	SetPermission(ctx context.Context, req *dto.SetPermissionRequest, granterID string) error
@@ -123,14 +142,34 @@ func (s *projectService) CreateProject(ctx context.Context, req *dto.CreateProje
 		}
 	}
 
+	// 未指定项目配额时使用群组的默认项目配额，项目配额不能超过群组配额
+	storageQuota := group.DefaultProjectQuota
+	if req.StorageQuota != nil {
+		storageQuota = *req.StorageQuota
+	}
+	if err := validateProjectQuota(storageQuota, group.StorageQuota); err != nil {
+		return nil, err
+	}
+
+	// 校验项目级上传策略
+	uploadPolicy, err := encodeUploadPolicy(req.UploadPolicy)
+	if err != nil {
+		return nil, err
+	}
+
 	// 创建项目
 	project := &entity.Project{
-		Name:        req.Name,
-		Description: req.Description,
-		GroupID:     req.GroupID,
-		CreatorID:   creatorID,
-		Status:      1, // 1: 正常
-		PathPrefix:  fmt.Sprintf("/%s/%s", group.GroupKey, strings.ReplaceAll(req.Name, " ", "_")),
+		Name:                 req.Name,
+		Description:          req.Description,
+		GroupID:              req.GroupID,
+		CreatorID:            creatorID,
+		StorageQuota:         storageQuota,
+		CaseInsensitivePaths: req.CaseInsensitivePaths,
+		PublicRead:           req.PublicRead,
+		MaxVersions:          req.MaxVersions,
+		UploadPolicy:         uploadPolicy,
+		Status:               entity.ProjectStatusNormal,
+		PathPrefix:           fmt.Sprintf("/%s/%s", group.GroupKey, strings.ReplaceAll(req.Name, " ", "_")),
 	}
 
 	// 启动事务
@@ -208,19 +247,25 @@ func (s *projectService) CreateProject(ctx context.Context, req *dto.CreateProje
 
 	// 构建响应
 	return &dto.ProjectResponse{
-		ID:          createdProject.ID,
-		Name:        createdProject.Name,
-		Description: createdProject.Description,
-		GroupID:     createdProject.GroupID,
-		GroupName:   group.Name,
-		PathPrefix:  createdProject.PathPrefix,
-		CreatorID:   createdProject.CreatorID,
-		CreatorName: creator.Name,
-		Status:      createdProject.Status,
-		CreatedAt:   createdProject.CreatedAt,
-		UpdatedAt:   createdProject.UpdatedAt,
-		FileCount:   0, // 初始文件数为0
-		TotalSize:   0, // 初始存储大小为0
+		ID:                   createdProject.ID,
+		Name:                 createdProject.Name,
+		Description:          createdProject.Description,
+		GroupID:              createdProject.GroupID,
+		GroupName:            group.Name,
+		PathPrefix:           createdProject.PathPrefix,
+		CreatorID:            createdProject.CreatorID,
+		CreatorName:          creator.Name,
+		Status:               createdProject.Status,
+		StatusName:           projectStatusName(createdProject.Status),
+		StorageQuota:         createdProject.StorageQuota,
+		CaseInsensitivePaths: createdProject.CaseInsensitivePaths,
+		PublicRead:           createdProject.PublicRead,
+		MaxVersions:          createdProject.MaxVersions,
+		UploadPolicy:         decodeUploadPolicy(createdProject.UploadPolicy),
+		CreatedAt:            createdProject.CreatedAt,
+		UpdatedAt:            createdProject.UpdatedAt,
+		FileCount:            0, // 初始文件数为0
+		TotalSize:            0, // 初始存储大小为0
 	}, nil
 }
 
@@ -251,6 +296,28 @@ func (s *projectService) UpdateProject(ctx context.Context, req *dto.UpdateProje
 	if req.Status > 0 {
 		project.Status = req.Status
 	}
+	if req.StorageQuota != nil {
+		if err := validateProjectQuota(*req.StorageQuota, project.Group.StorageQuota); err != nil {
+			return nil, err
+		}
+		project.StorageQuota = *req.StorageQuota
+	}
+	if req.CaseInsensitivePaths != nil {
+		project.CaseInsensitivePaths = *req.CaseInsensitivePaths
+	}
+	if req.PublicRead != nil {
+		project.PublicRead = *req.PublicRead
+	}
+	if req.UploadPolicy != nil {
+		uploadPolicy, err := encodeUploadPolicy(req.UploadPolicy)
+		if err != nil {
+			return nil, err
+		}
+		project.UploadPolicy = uploadPolicy
+	}
+	if req.MaxVersions != nil {
+		project.MaxVersions = *req.MaxVersions
+	}
 
 	err = s.projectRepo.Update(ctx, project)
 	if err != nil {
@@ -275,21 +342,30 @@ func (s *projectService) UpdateProject(ctx context.Context, req *dto.UpdateProje
 		return nil, err
 	}
 
+	// 获取存储统计数据
+	fileCount, totalSize := s.projectStorageStats(ctx, updatedProject.ID)
+
 	// 构建响应
 	return &dto.ProjectResponse{
-		ID:          updatedProject.ID,
-		Name:        updatedProject.Name,
-		Description: updatedProject.Description,
-		GroupID:     updatedProject.GroupID,
-		GroupName:   group.Name,
-		PathPrefix:  updatedProject.PathPrefix,
-		CreatorID:   updatedProject.CreatorID,
-		CreatorName: creator.Name,
-		Status:      updatedProject.Status,
-		CreatedAt:   updatedProject.CreatedAt,
-		UpdatedAt:   updatedProject.UpdatedAt,
-		FileCount:   0, // 此处需补充文件统计逻辑
-		TotalSize:   0, // 此处需补充存储统计逻辑
+		ID:                   updatedProject.ID,
+		Name:                 updatedProject.Name,
+		Description:          updatedProject.Description,
+		GroupID:              updatedProject.GroupID,
+		GroupName:            group.Name,
+		PathPrefix:           updatedProject.PathPrefix,
+		CreatorID:            updatedProject.CreatorID,
+		CreatorName:          creator.Name,
+		Status:               updatedProject.Status,
+		StatusName:           projectStatusName(updatedProject.Status),
+		StorageQuota:         updatedProject.StorageQuota,
+		CaseInsensitivePaths: updatedProject.CaseInsensitivePaths,
+		PublicRead:           updatedProject.PublicRead,
+		MaxVersions:          updatedProject.MaxVersions,
+		UploadPolicy:         decodeUploadPolicy(updatedProject.UploadPolicy),
+		CreatedAt:            updatedProject.CreatedAt,
+		UpdatedAt:            updatedProject.UpdatedAt,
+		FileCount:            fileCount,
+		TotalSize:            totalSize,
 	}, nil
 }
 
@@ -345,41 +421,46 @@ func (s *projectService) GetProjectByID(ctx context.Context, id string, userID s
 	}
 
 	// 获取存储统计数据
-	var fileCount int64 = 0
-	var totalSize int64 = 0
-
-	// 先尝试从最新的统计记录获取
-	stat, err := s.statRepo.GetLatestByProject(ctx, id)
-	if err == nil && stat != nil {
-		fileCount = stat.FileCount
-		totalSize = stat.TotalSize
-	} else {
-		// 如果没有统计记录，则实时计算
-		fileCount, totalSize, _ = s.statRepo.GetProjectTotalStats(ctx, id)
-	}
+	fileCount, totalSize := s.projectStorageStats(ctx, id)
 
 	// 构建响应
 	return &dto.ProjectResponse{
-		ID:          project.ID,
-		Name:        project.Name,
-		Description: project.Description,
-		GroupID:     project.GroupID,
-		GroupName:   group.Name,
-		PathPrefix:  project.PathPrefix,
-		CreatorID:   project.CreatorID,
-		CreatorName: creator.Name,
-		Status:      project.Status,
-		CreatedAt:   project.CreatedAt,
-		UpdatedAt:   project.UpdatedAt,
-		FileCount:   fileCount,
-		TotalSize:   totalSize,
+		ID:                   project.ID,
+		Name:                 project.Name,
+		Description:          project.Description,
+		GroupID:              project.GroupID,
+		GroupName:            group.Name,
+		PathPrefix:           project.PathPrefix,
+		CreatorID:            project.CreatorID,
+		CreatorName:          creator.Name,
+		Status:               project.Status,
+		StatusName:           projectStatusName(project.Status),
+		StorageQuota:         project.StorageQuota,
+		CaseInsensitivePaths: project.CaseInsensitivePaths,
+		PublicRead:           project.PublicRead,
+		MaxVersions:          project.MaxVersions,
+		UploadPolicy:         decodeUploadPolicy(project.UploadPolicy),
+		CreatedAt:            project.CreatedAt,
+		UpdatedAt:            project.UpdatedAt,
+		FileCount:            fileCount,
+		TotalSize:            totalSize,
 	}, nil
 }
 
+// projectStorageStats 获取项目的文件数和存储大小，优先使用最新的统计记录，没有统计记录时实时计算
+func (s *projectService) projectStorageStats(ctx context.Context, projectID string) (int64, int64) {
+	stat, err := s.statRepo.GetLatestByProject(ctx, projectID)
+	if err == nil && stat != nil {
+		return stat.FileCount, stat.TotalSize
+	}
+	fileCount, totalSize, _ := s.statRepo.GetProjectTotalStats(ctx, projectID)
+	return fileCount, totalSize
+}
+
 // ListProjects 列出项目
-func (s *projectService) ListProjects(ctx context.Context, groupID string, userID string, query *dto.ProjectQuery) (*dto.PaginatedProjectResponse, error) {
-	// 添加调试日志
-	fmt.Printf("ListProjects - 用户ID: %s, 群组ID: %s\n", userID, groupID)
+func (s *projectService) ListProjects(ctx context.Context, groupID string, userID string, query *dto.ProjectQuery) (*dto.PageResult[*dto.ProjectResponse], error) {
+	log := logger.FromContext(ctx)
+	log.Debug("列出项目", "user_id", userID, "group_id", groupID)
 
 	// 检查用户是否属于该分组
 	if len(groupID) > 0 {
@@ -387,34 +468,27 @@ func (s *projectService) ListProjects(ctx context.Context, groupID string, userI
 		groupDomain := fmt.Sprintf("group:%s", groupID)
 		isGroupAdmin, err := s.authService.IsUserInRole(ctx, userID, entity.RoleGroupAdmin, groupDomain)
 		if err != nil {
-			fmt.Printf("检查群组管理员角色失败: %v\n", err)
 			return nil, fmt.Errorf("检查用户角色失败: %w", err)
 		}
-		fmt.Printf("用户是否是群组管理员: %v\n", isGroupAdmin)
 
 		// 检查是否系统管理员
 		systemDomain := "system"
 		isSysAdmin, err := s.authService.IsUserInRole(ctx, userID, entity.RoleAdmin, systemDomain)
 		if err != nil {
-			fmt.Printf("检查系统管理员角色失败: %v\n", err)
 			return nil, fmt.Errorf("检查用户角色失败: %w", err)
 		}
-		fmt.Printf("用户是否是系统管理员: %v\n", isSysAdmin)
+		log.Debug("检查群组项目访问权限", "is_group_admin", isGroupAdmin, "is_system_admin", isSysAdmin)
 
 		// 如果既不是群组管理员也不是系统管理员，则检查是否是群组成员
 		if !isGroupAdmin && !isSysAdmin {
-			fmt.Printf("用户既不是群组管理员也不是系统管理员，检查是否是群组成员\n")
 			isMember, err := s.groupRepo.CheckUserInGroup(ctx, groupID, userID)
 			if err != nil {
-				fmt.Printf("检查群组成员失败: %v\n", err)
 				return nil, err
 			}
-			fmt.Printf("用户是否是群组成员: %v\n", isMember)
+			log.Debug("检查群组成员", "is_member", isMember)
 			if !isMember {
 				return nil, errors.New("没有权限查看该分组项目")
 			}
-		} else {
-			fmt.Printf("用户是管理员，跳过群组成员检查\n")
 		}
 	}
 
@@ -467,48 +541,40 @@ func (s *projectService) ListProjects(ctx context.Context, groupID string, userI
 		}
 
 		// 获取存储统计数据
-		var fileCount int64 = 0
-		var totalSize int64 = 0
-
-		// 先尝试从最新的统计记录获取
-		stat, err := s.statRepo.GetLatestByProject(ctx, project.ID)
-		if err == nil && stat != nil {
-			fileCount = stat.FileCount
-			totalSize = stat.TotalSize
-		} else {
-			// 如果没有统计记录，则实时计算
-			fileCount, totalSize, _ = s.statRepo.GetProjectTotalStats(ctx, project.ID)
-		}
+		fileCount, totalSize := s.projectStorageStats(ctx, project.ID)
 
 		items = append(items, &dto.ProjectResponse{
-			ID:          project.ID,
-			Name:        project.Name,
-			Description: project.Description,
-			GroupID:     project.GroupID,
-			GroupName:   group.Name,
-			PathPrefix:  project.PathPrefix,
-			CreatorID:   project.CreatorID,
-			CreatorName: creator.Name,
-			Status:      project.Status,
-			CreatedAt:   project.CreatedAt,
-			UpdatedAt:   project.UpdatedAt,
-			FileCount:   fileCount,
-			TotalSize:   totalSize,
+			ID:                   project.ID,
+			Name:                 project.Name,
+			Description:          project.Description,
+			GroupID:              project.GroupID,
+			GroupName:            group.Name,
+			PathPrefix:           project.PathPrefix,
+			CreatorID:            project.CreatorID,
+			CreatorName:          creator.Name,
+			Status:               project.Status,
+			StatusName:           projectStatusName(project.Status),
+			StorageQuota:         project.StorageQuota,
+			CaseInsensitivePaths: project.CaseInsensitivePaths,
+			PublicRead:           project.PublicRead,
+			MaxVersions:          project.MaxVersions,
+			UploadPolicy:         decodeUploadPolicy(project.UploadPolicy),
+			CreatedAt:            project.CreatedAt,
+			UpdatedAt:            project.UpdatedAt,
+			FileCount:            fileCount,
+			TotalSize:            totalSize,
 		})
 	}
 
-	return &dto.PaginatedProjectResponse{
-		Items: items,
-		Total: total,
-	}, nil
+	return dto.NewPageResult(items, total, dto.PageQuery{Page: query.Page, Size: query.Size}), nil
 }
 
 // GetUserProjects 获取用户项目
-func (s *projectService) GetUserProjects(ctx context.Context, query *dto.ProjectQuery, userID string) ([]*dto.ProjectResponse, int64, error) {
+func (s *projectService) GetUserProjects(ctx context.Context, query *dto.ProjectQuery, userID string) (*dto.PageResult[*dto.ProjectResponse], error) {
 	// 检查用户是否存在
 	_, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
-		return nil, 0, errors.New("用户不存在")
+		return nil, errors.New("用户不存在")
 	}
 
 	// 确保分页参数有效
@@ -523,41 +589,19 @@ func (s *projectService) GetUserProjects(ctx context.Context, query *dto.Project
 	pageQuery := dto.PageQuery{
 		Page:      query.Page,
 		Size:      query.Size,
-		SortBy:    "created_at", // 默认按创建时间排序
-		SortOrder: "desc",       // 默认降序
+		SortBy:    "projects.created_at", // 默认按创建时间排序
+		SortOrder: "desc",                // 默认降序
 	}
 
-	// 直接使用数据库分页查询
-	projects, total, err := s.projectRepo.GetUserProjects(ctx, userID, pageQuery)
+	// 筛选与分页都在数据库中进行
+	projects, total, err := s.projectRepo.GetUserProjects(ctx, userID, query, pageQuery)
 	if err != nil {
-		return nil, 0, err
-	}
-
-	// 过滤项目（根据查询条件）- 现在在数据库层进行
-	// TODO: 将过滤条件也传递到数据库层
-	var filteredProjects []entity.Project
-	for _, project := range projects {
-		// 按状态过滤
-		if query.Status > 0 && project.Status != query.Status {
-			continue
-		}
-
-		// 按关键词过滤
-		if query.Keyword != "" && !strings.Contains(strings.ToLower(project.Name), strings.ToLower(query.Keyword)) {
-			continue
-		}
-
-		// 按群组过滤
-		if query.GroupID != "" && project.GroupID != query.GroupID {
-			continue
-		}
-
-		filteredProjects = append(filteredProjects, project)
+		return nil, err
 	}
 
 	// 构建响应
 	var responses []*dto.ProjectResponse
-	for _, project := range filteredProjects {
+	for _, project := range projects {
 		// 获取创建者信息
 		creator, err := s.userRepo.GetByID(ctx, project.CreatorID)
 		if err != nil {
@@ -577,37 +621,32 @@ func (s *projectService) GetUserProjects(ctx context.Context, query *dto.Project
 		}
 
 		// 获取存储统计数据
-		var fileCount int64 = 0
-		var totalSize int64 = 0
-
-		// 先尝试从最新的统计记录获取
-		stat, err := s.statRepo.GetLatestByProject(ctx, project.ID)
-		if err == nil && stat != nil {
-			fileCount = stat.FileCount
-			totalSize = stat.TotalSize
-		} else {
-			// 如果没有统计记录，则实时计算
-			fileCount, totalSize, _ = s.statRepo.GetProjectTotalStats(ctx, project.ID)
-		}
+		fileCount, totalSize := s.projectStorageStats(ctx, project.ID)
 
 		responses = append(responses, &dto.ProjectResponse{
-			ID:          project.ID,
-			Name:        project.Name,
-			Description: project.Description,
-			GroupID:     project.GroupID,
-			GroupName:   group.Name,
-			PathPrefix:  project.PathPrefix,
-			CreatorID:   project.CreatorID,
-			CreatorName: creator.Name,
-			Status:      project.Status,
-			CreatedAt:   project.CreatedAt,
-			UpdatedAt:   project.UpdatedAt,
-			FileCount:   fileCount,
-			TotalSize:   totalSize,
+			ID:                   project.ID,
+			Name:                 project.Name,
+			Description:          project.Description,
+			GroupID:              project.GroupID,
+			GroupName:            group.Name,
+			PathPrefix:           project.PathPrefix,
+			CreatorID:            project.CreatorID,
+			CreatorName:          creator.Name,
+			Status:               project.Status,
+			StatusName:           projectStatusName(project.Status),
+			StorageQuota:         project.StorageQuota,
+			CaseInsensitivePaths: project.CaseInsensitivePaths,
+			PublicRead:           project.PublicRead,
+			MaxVersions:          project.MaxVersions,
+			UploadPolicy:         decodeUploadPolicy(project.UploadPolicy),
+			CreatedAt:            project.CreatedAt,
+			UpdatedAt:            project.UpdatedAt,
+			FileCount:            fileCount,
+			TotalSize:            totalSize,
 		})
 	}
 
-	return responses, total, nil
+	return dto.NewPageResult(responses, total, pageQuery.WithDefaultValues()), nil
 }
 
 // DeleteProject 删除项目
@@ -637,7 +676,7 @@ func (s *projectService) DeleteProject(ctx context.Context, id string, userID st
 		projectRepo := s.projectRepo.WithTx(tx)
 
 		// 逻辑删除项目
-		project.Status = 3 // 3表示已删除
+		project.Status = entity.ProjectStatusDeleted
 		err = projectRepo.Update(ctx, project)
 		if err != nil {
 			return err
@@ -649,6 +688,197 @@ func (s *projectService) DeleteProject(ctx context.Context, id string, userID st
 	})
 }
 
+// CloneProject 克隆项目结构
+// 复制源项目的文件夹树（不含文件），可选复制成员角色及其文件权限
+func (s *projectService) CloneProject(ctx context.Context, sourceProjectID, newName, userID string, includeMembers bool) (*dto.ProjectResponse, error) {
+	// 检查用户是否有权限克隆项目（需要源项目管理员权限）
+	hasAccess, err := s.CheckUserProjectAccess(ctx, userID, sourceProjectID, []string{ProjectRoleAdmin})
+	if err != nil {
+		return nil, err
+	}
+	if !hasAccess {
+		return nil, errors.New("没有权限克隆该项目")
+	}
+
+	// 获取源项目信息
+	source, err := s.projectRepo.GetByID(ctx, sourceProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if source == nil || source.Status == entity.ProjectStatusDeleted {
+		return nil, errors.New("项目不存在")
+	}
+
+	// 检查分组是否存在
+	group, err := s.groupRepo.GetGroupByID(ctx, source.GroupID)
+	if err != nil {
+		return nil, errors.New("项目所属分组不存在")
+	}
+
+	// 新项目
+	project := &entity.Project{
+		Name:                      newName,
+		Description:               source.Description,
+		GroupID:                   source.GroupID,
+		CreatorID:                 userID,
+		StorageQuota:              source.StorageQuota,
+		CaseInsensitivePaths:      source.CaseInsensitivePaths,
+		UploadPolicy:              source.UploadPolicy,
+		MaxVersions:               source.MaxVersions,
+		ShareDefaultExpireHours:   source.ShareDefaultExpireHours,
+		ShareMaxExpireHours:       source.ShareMaxExpireHours,
+		ShareDefaultDownloadLimit: source.ShareDefaultDownloadLimit,
+		ShareMaxDownloadLimit:     source.ShareMaxDownloadLimit,
+		Status:                    entity.ProjectStatusNormal,
+		PathPrefix:                fmt.Sprintf("/%s/%s", group.GroupKey, strings.ReplaceAll(newName, " ", "_")),
+	}
+
+	// 新项目的成员及其角色，用于写入Casbin规则
+	clonedRoles := map[string]string{userID: ProjectRoleAdmin}
+
+	// 启动事务
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		projectRepo := s.projectRepo.WithTx(tx)
+
+		// 创建项目
+		if err := projectRepo.Create(ctx, project); err != nil {
+			return err
+		}
+
+		// 克隆者为新项目管理员
+		err := projectRepo.CreateProjectMember(ctx, &entity.ProjectMember{
+			ProjectID: project.ID,
+			UserID:    userID,
+			Role:      ProjectRoleAdmin,
+		})
+		if err != nil {
+			return err
+		}
+
+		// 复制文件夹树（仅元数据，文件夹不对应实际对象内容）
+		var folders []entity.File
+		err = tx.WithContext(ctx).
+			Where("project_id = ? AND is_folder = ? AND is_deleted = ?", sourceProjectID, true, false).
+			Order("full_path ASC").
+			Find(&folders).Error
+		if err != nil {
+			return fmt.Errorf("获取源项目文件夹失败: %w", err)
+		}
+		for _, folder := range folders {
+			newFolder := &entity.File{
+				ID:             utils.GenerateFileID(),
+				ProjectID:      project.ID,
+				FileName:       folder.FileName,
+				FilePath:       folder.FilePath,
+				FullPath:       folder.FullPath,
+				FileHash:       "",
+				FileSize:       0,
+				MimeType:       folder.MimeType,
+				IsFolder:       true,
+				UploaderID:     userID,
+				CurrentVersion: 1,
+			}
+			if err := tx.Create(newFolder).Error; err != nil {
+				return fmt.Errorf("复制文件夹失败: %w", err)
+			}
+		}
+
+		// 复制成员角色
+		if includeMembers {
+			var members []entity.ProjectMember
+			if err := tx.WithContext(ctx).Where("project_id = ?", sourceProjectID).Find(&members).Error; err != nil {
+				return fmt.Errorf("获取源项目成员失败: %w", err)
+			}
+			for _, member := range members {
+				if member.UserID == userID {
+					continue
+				}
+				err := projectRepo.CreateProjectMember(ctx, &entity.ProjectMember{
+					ProjectID: project.ID,
+					UserID:    member.UserID,
+					Role:      member.Role,
+				})
+				if err != nil {
+					return fmt.Errorf("复制项目成员失败: %w", err)
+				}
+				clonedRoles[member.UserID] = member.Role
+			}
+		}
+
+		// 权限规则与项目记录在同一事务中写入，任何一条失败时整个克隆回滚
+		if err := cloneProjectRules(tx, sourceProjectID, project.ID, userID, clonedRoles); err != nil {
+			return fmt.Errorf("复制项目权限失败: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 规则直接写入了 casbin_rule 表，重新加载后生效
+	if err := s.authService.ReloadPolicy(); err != nil {
+		logger.FromContext(ctx).Error("克隆项目后重新加载Casbin策略失败", "project_id", project.ID, "error", err)
+		return nil, fmt.Errorf("重新加载权限策略失败: %w", err)
+	}
+
+	return s.GetProjectByID(ctx, project.ID, userID)
+}
+
+// cloneProjectRules 在事务中为克隆出的项目写入Casbin规则：
+// 克隆者获得项目域的 GROUP_ADMIN 角色，成员按角色获得文件权限（与 EnsureProjectMemberPermissions 一致），
+// 成员在源项目域中单独授予的策略和角色也复制到新项目域
+func cloneProjectRules(tx *gorm.DB, sourceProjectID, targetProjectID, ownerID string, roles map[string]string) error {
+	sourceDomain := fmt.Sprintf("project:%s", sourceProjectID)
+	targetDomain := fmt.Sprintf("project:%s", targetProjectID)
+
+	rules := []entity.CasbinRule{{Ptype: "g", V0: fmt.Sprintf("user:%s", ownerID), V1: entity.RoleGroupAdmin, V2: targetDomain}}
+	subjects := make([]string, 0, len(roles))
+	for memberID, role := range roles {
+		subject := fmt.Sprintf("user:%s", memberID)
+		subjects = append(subjects, subject)
+		for _, action := range projectRoleFileActions(role) {
+			rules = append(rules, entity.CasbinRule{Ptype: "p", V0: subject, V1: targetDomain, V2: ResourceFile, V3: action})
+		}
+	}
+
+	// 策略规则为 p, 主体, 域, 资源, 操作；角色关联规则为 g, 用户, 角色, 域
+	var explicit []entity.CasbinRule
+	if err := tx.Where("v0 IN ? AND ((ptype = ? AND v1 = ?) OR (ptype = ? AND v2 = ?))", subjects, "p", sourceDomain, "g", sourceDomain).
+		Find(&explicit).Error; err != nil {
+		return err
+	}
+	for _, rule := range explicit {
+		if rule.Ptype == "p" {
+			rule.V1 = targetDomain
+		} else {
+			rule.V2 = targetDomain
+		}
+		rules = append(rules, rule)
+	}
+
+	for _, rule := range rules {
+		if err := tx.Table("casbin_rule").Clauses(clause.OnConflict{DoNothing: true}).Create(map[string]interface{}{
+			"ptype": rule.Ptype, "v0": rule.V0, "v1": rule.V1, "v2": rule.V2, "v3": rule.V3, "v4": rule.V4, "v5": rule.V5,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// projectRoleFileActions 项目角色拥有的文件操作：所有角色可读取，admin 和 editor 可创建、更新，admin 可删除
+func projectRoleFileActions(role string) []string {
+	actions := []string{ActionRead}
+	if role == ProjectRoleAdmin || role == ProjectRoleEditor {
+		actions = append(actions, ActionCreate, ActionUpdate)
+	}
+	if role == ProjectRoleAdmin {
+		actions = append(actions, ActionDelete)
+	}
+	return actions
+}
+
 // SetPermission 设置权限
 func (s *projectService) SetPermission(ctx context.Context, req *dto.SetPermissionRequest, granterID string) error {
 	// 检查授权者是否有权限设置项目权限
@@ -796,15 +1026,14 @@ func (s *projectService) ListProjectUsers(ctx context.Context, projectID string,
 		return nil, 0, errors.New("项目不存在")
 	}
 
-	// 设置默认分页参数
+	// 设置默认分页参数，按加入时间排序使各页之间的顺序稳定
 	var query dto.PageQuery
 	if pageQuery != nil {
 		query = *pageQuery
-	} else {
-		query = dto.PageQuery{
-			Page: 1,
-			Size: 10,
-		}
+	}
+	query = query.WithDefaultValues()
+	if query.SortBy == "" {
+		query.SortBy = "created_at"
 	}
 
 	// 获取项目成员列表
@@ -814,7 +1043,7 @@ func (s *projectService) ListProjectUsers(ctx context.Context, projectID string,
 	}
 
 	// 构建响应
-	var response []*dto.ProjectUserResponse
+	response := make([]*dto.ProjectUserResponse, 0, len(members))
 	for _, member := range members {
 		// 获取用户信息
 		user, err := s.userRepo.GetByID(ctx, member.UserID)
@@ -953,3 +1182,89 @@ func (s *projectService) EnsureProjectMemberPermissions(ctx context.Context, pro
 
 	return nil
 }
+
+// SetSharePolicy 设置项目分享策略，仅项目管理员可操作
+func (s *projectService) SetSharePolicy(ctx context.Context, req *dto.SharePolicyRequest, userID string) (*dto.SharePolicyResponse, error) {
+	project, err := s.projectRepo.GetByID(ctx, req.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, errors.New("项目不存在")
+	}
+
+	hasAccess, err := s.CheckUserProjectAccess(ctx, userID, req.ProjectID, []string{ProjectRoleAdmin})
+	if err != nil {
+		return nil, err
+	}
+	if !hasAccess {
+		return nil, errors.New("没有权限设置项目分享策略")
+	}
+
+	// 默认值不能超过上限
+	if req.MaxExpireHours > 0 && req.DefaultExpireHours > req.MaxExpireHours {
+		return nil, errors.New("默认有效期不能超过最长有效期")
+	}
+	if req.MaxDownloadLimit > 0 && req.DefaultDownloadLimit > req.MaxDownloadLimit {
+		return nil, errors.New("默认下载次数不能超过最大下载次数")
+	}
+
+	project.ShareDefaultExpireHours = req.DefaultExpireHours
+	project.ShareMaxExpireHours = req.MaxExpireHours
+	project.ShareDefaultDownloadLimit = req.DefaultDownloadLimit
+	project.ShareMaxDownloadLimit = req.MaxDownloadLimit
+	if err := s.projectRepo.Update(ctx, project); err != nil {
+		return nil, fmt.Errorf("保存分享策略失败: %w", err)
+	}
+
+	return buildSharePolicyResponse(project), nil
+}
+
+// GetSharePolicy 获取项目分享策略，项目成员或所属群组成员可查看
+func (s *projectService) GetSharePolicy(ctx context.Context, projectID, userID string) (*dto.SharePolicyResponse, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, errors.New("项目不存在")
+	}
+
+	hasAccess, err := s.CheckUserProjectAccess(ctx, userID, projectID, []string{ProjectRoleAdmin, ProjectRoleEditor, ProjectRoleViewer})
+	if err != nil {
+		return nil, err
+	}
+	if !hasAccess {
+		isGroupMember, err := s.groupRepo.CheckUserInGroup(ctx, project.GroupID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !isGroupMember {
+			return nil, errors.New("没有权限查看该项目")
+		}
+	}
+
+	return buildSharePolicyResponse(project), nil
+}
+
+// buildSharePolicyResponse 构建项目分享策略响应
+func buildSharePolicyResponse(project *entity.Project) *dto.SharePolicyResponse {
+	return &dto.SharePolicyResponse{
+		ProjectID:            project.ID,
+		DefaultExpireHours:   project.ShareDefaultExpireHours,
+		MaxExpireHours:       project.ShareMaxExpireHours,
+		DefaultDownloadLimit: project.ShareDefaultDownloadLimit,
+		MaxDownloadLimit:     project.ShareMaxDownloadLimit,
+	}
+}
+
+// validateProjectQuota 校验项目配额不超过所属群组配额
+func validateProjectQuota(projectQuota, groupQuota int64) error {
+	if projectQuota < 0 {
+		return errors.New("项目存储配额不能为负数")
+	}
+	if projectQuota > 0 && groupQuota > 0 && projectQuota > groupQuota {
+		return fmt.Errorf("项目存储配额不能超过群组存储配额(%d字节)", groupQuota)
+	}
+	return nil
+}
@@ -0,0 +1,326 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	gormadapter "github.com/casbin/gorm-adapter/v3"
+	"gorm.io/gorm"
+
+	"oss-backend/internal/model/dto"
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+)
+
+// newTestProjectService 创建连接测试数据库的项目服务，Casbin策略保存在同一数据库中
+func newTestProjectService(t *testing.T) (*projectService, *casbin.Enforcer, *gorm.DB) {
+	t.Helper()
+	db := newTestDB(t, &entity.User{}, &entity.Group{}, &entity.GroupMember{}, &entity.Project{}, &entity.ProjectMember{}, &entity.File{})
+	adapter, err := gormadapter.NewAdapterByDB(db)
+	if err != nil {
+		t.Fatalf("创建 Casbin adapter 失败: %v", err)
+	}
+	enforcer, err := casbin.NewEnforcer("../../configs/rbac_model.conf", adapter)
+	if err != nil {
+		t.Fatalf("创建 Enforcer 失败: %v", err)
+	}
+	svc := &projectService{
+		projectRepo: repository.NewProjectRepository(db),
+		groupRepo:   repository.NewGroupRepository(db),
+		userRepo:    repository.NewUserRepository(db),
+		statRepo:    repository.NewStorageStatRepository(db),
+		authService: NewAuthService(enforcer, nil, nil, nil, db),
+		db:          db,
+	}
+	return svc, enforcer, db
+}
+
+// TestCloneProject 克隆项目复制文件夹树和成员角色，不复制文件；成员在新项目中拥有与源项目相同的权限
+func TestCloneProject(t *testing.T) {
+	svc, enforcer, db := newTestProjectService(t)
+	ctx := context.Background()
+
+	records := []interface{}{
+		&entity.User{ID: "owner", Email: "owner@example.com", Name: "owner", Status: entity.UserStatusNormal},
+		&entity.User{ID: "editor", Email: "editor@example.com", Name: "editor", Status: entity.UserStatusNormal},
+		&entity.User{ID: "viewer", Email: "viewer@example.com", Name: "viewer", Status: entity.UserStatusNormal},
+		&entity.Group{ID: "group-1", Name: "team", GroupKey: "team", InviteCode: "invite", CreatorID: "owner", Status: 1},
+		&entity.Project{ID: "source", GroupID: "group-1", Name: "phase 1", PathPrefix: "/team/phase_1", CreatorID: "owner", Status: entity.ProjectStatusNormal, StorageQuota: 1 << 20},
+		&entity.ProjectMember{ID: "m-1", ProjectID: "source", UserID: "owner", Role: ProjectRoleAdmin},
+		&entity.ProjectMember{ID: "m-2", ProjectID: "source", UserID: "editor", Role: ProjectRoleEditor},
+		&entity.ProjectMember{ID: "m-3", ProjectID: "source", UserID: "viewer", Role: ProjectRoleViewer},
+		&entity.File{ID: "f-1", ProjectID: "source", FileName: "docs", FullPath: "docs/", IsFolder: true, UploaderID: "owner", CurrentVersion: 1},
+		&entity.File{ID: "f-2", ProjectID: "source", FileName: "specs", FilePath: "docs/", FullPath: "docs/specs/", IsFolder: true, UploaderID: "owner", CurrentVersion: 1},
+		&entity.File{ID: "f-3", ProjectID: "source", FileName: "empty", FullPath: "empty/", IsFolder: true, UploaderID: "owner", CurrentVersion: 1},
+		&entity.File{ID: "f-4", ProjectID: "source", FileName: "a.txt", FilePath: "docs/", FullPath: "docs/a.txt", FileSize: 10, UploaderID: "owner", CurrentVersion: 1},
+	}
+	for _, record := range records {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatalf("写入测试数据失败: %v", err)
+		}
+	}
+	// 查看者在源项目上单独获得了删除权限
+	if _, err := enforcer.AddPermissionForUser("user:viewer", "project:source", ResourceFile, ActionDelete); err != nil {
+		t.Fatal(err)
+	}
+
+	cloned, err := svc.CloneProject(ctx, "source", "phase 2", "owner", true)
+	if err != nil {
+		t.Fatalf("克隆项目失败: %v", err)
+	}
+	if cloned.ID == "source" || cloned.Name != "phase 2" {
+		t.Fatalf("克隆出的项目为 %s/%s", cloned.ID, cloned.Name)
+	}
+
+	folderPaths := func(projectID string) []string {
+		var paths []string
+		db.Model(&entity.File{}).Where("project_id = ? AND is_folder = ?", projectID, true).Order("full_path").Pluck("full_path", &paths)
+		return paths
+	}
+	source, target := folderPaths("source"), folderPaths(cloned.ID)
+	if len(target) != len(source) {
+		t.Fatalf("克隆的文件夹为 %v，应为 %v", target, source)
+	}
+	for i := range source {
+		if source[i] != target[i] {
+			t.Fatalf("克隆的文件夹为 %v，应为 %v", target, source)
+		}
+	}
+	var files int64
+	db.Model(&entity.File{}).Where("project_id = ? AND is_folder = ?", cloned.ID, false).Count(&files)
+	if files != 0 {
+		t.Fatalf("克隆复制了 %d 个文件，应只复制文件夹", files)
+	}
+
+	memberRoles := func(projectID string) []string {
+		var members []entity.ProjectMember
+		db.Where("project_id = ?", projectID).Find(&members)
+		roles := make([]string, 0, len(members))
+		for _, m := range members {
+			roles = append(roles, m.UserID+":"+m.Role)
+		}
+		sort.Strings(roles)
+		return roles
+	}
+	if got, want := memberRoles(cloned.ID), memberRoles("source"); len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Fatalf("克隆的成员为 %v，应为 %v", got, want)
+	}
+
+	domain := "project:" + cloned.ID
+	checks := []struct {
+		sub, act string
+		want     bool
+	}{
+		{"user:owner", ActionDelete, true},
+		{"user:editor", ActionUpdate, true},
+		{"user:editor", ActionDelete, false},
+		{"user:viewer", ActionRead, true},
+		{"user:viewer", ActionCreate, false},
+		{"user:viewer", ActionDelete, true},
+	}
+	for _, c := range checks {
+		if ok, err := enforcer.Enforce(c.sub, domain, ResourceFile, c.act); err != nil || ok != c.want {
+			t.Errorf("%s 对新项目的 %s 权限为 %v (%v)，应为 %v", c.sub, c.act, ok, err, c.want)
+		}
+	}
+	if roles, _ := enforcer.GetRolesForUser("user:owner", domain); len(roles) != 1 || roles[0] != entity.RoleGroupAdmin {
+		t.Fatalf("克隆者在新项目中的角色为 %v，应为 %s", roles, entity.RoleGroupAdmin)
+	}
+
+	// 规则已写入数据库，重新加载后仍然存在
+	if err := enforcer.LoadPolicy(); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := enforcer.Enforce("user:viewer", domain, ResourceFile, ActionDelete); !ok {
+		t.Fatalf("重新加载策略后复制的授权丢失")
+	}
+}
+
+// TestListProjectUsersPaginated 成员列表按加入时间分页，两页合起来恰好是全部成员，总数不随页码变化
+func TestListProjectUsersPaginated(t *testing.T) {
+	svc, _, db := newTestProjectService(t)
+	ctx := context.Background()
+
+	records := []interface{}{
+		&entity.User{ID: "owner", Email: "owner@example.com", Name: "owner", Status: entity.UserStatusNormal},
+		&entity.User{ID: "outsider", Email: "outsider@example.com", Name: "outsider", Status: entity.UserStatusNormal},
+		&entity.Group{ID: "group-1", Name: "team", GroupKey: "team", InviteCode: "invite", CreatorID: "owner", Status: 1},
+		&entity.Project{ID: "project-1", GroupID: "group-1", Name: "phase 1", PathPrefix: "/team/phase_1", CreatorID: "owner", Status: entity.ProjectStatusNormal},
+	}
+	joined := time.Now().Add(-time.Hour)
+	var want []string
+	for i := 0; i < 5; i++ {
+		userID := fmt.Sprintf("user-%d", i)
+		records = append(records,
+			&entity.User{ID: userID, Email: userID + "@example.com", Name: userID, Status: entity.UserStatusNormal},
+			// 成员ID与加入顺序相反，验证按加入时间而不是ID排序
+			&entity.ProjectMember{ID: fmt.Sprintf("m-%d", 9-i), ProjectID: "project-1", UserID: userID, Role: ProjectRoleViewer, CreatedAt: joined.Add(time.Duration(i) * time.Minute)},
+		)
+		want = append(want, userID)
+	}
+	for _, record := range records {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatalf("写入测试数据失败: %v", err)
+		}
+	}
+
+	var got []string
+	for page := 1; page <= 2; page++ {
+		users, total, err := svc.ListProjectUsers(ctx, "project-1", "user-0", &dto.PageQuery{Page: page, Size: 3})
+		if err != nil {
+			t.Fatalf("获取第%d页成员失败: %v", page, err)
+		}
+		if total != 5 {
+			t.Fatalf("第%d页返回总数 %d，应为5", page, total)
+		}
+		if wantLen := []int{3, 2}[page-1]; len(users) != wantLen {
+			t.Fatalf("第%d页返回 %d 个成员，应为 %d 个", page, len(users), wantLen)
+		}
+		for _, user := range users {
+			got = append(got, user.UserID)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("两页成员依次为 %v，应为 %v", got, want)
+	}
+
+	if _, _, err := svc.ListProjectUsers(ctx, "project-1", "outsider", &dto.PageQuery{Page: 1, Size: 3}); err == nil {
+		t.Fatalf("非成员查看项目成员应被拒绝")
+	}
+}
+
+// TestUpdateProjectKeepsStorageStats 修改项目后返回的文件数和总大小与项目详情一致，没有统计快照时按文件表计算
+func TestUpdateProjectKeepsStorageStats(t *testing.T) {
+	svc, _, db := newTestProjectService(t)
+	createTestTables(t, db, &entity.StorageStat{})
+	ctx := context.Background()
+
+	records := []interface{}{
+		&entity.User{ID: "owner", Email: "owner@example.com", Name: "owner", Status: entity.UserStatusNormal},
+		&entity.Group{ID: "group-1", Name: "team", GroupKey: "team", InviteCode: "invite", CreatorID: "owner", Status: 1},
+		&entity.Project{ID: "project-1", GroupID: "group-1", Name: "phase 1", PathPrefix: "/team/phase_1", CreatorID: "owner", Status: entity.ProjectStatusNormal},
+		&entity.File{ID: "f-1", ProjectID: "project-1", FileName: "docs", FullPath: "docs/", IsFolder: true, UploaderID: "owner", CurrentVersion: 1},
+		&entity.File{ID: "f-2", ProjectID: "project-1", FileName: "a.txt", FilePath: "docs/", FullPath: "docs/a.txt", FileSize: 10, UploaderID: "owner", CurrentVersion: 1},
+		&entity.File{ID: "f-3", ProjectID: "project-1", FileName: "b.txt", FullPath: "b.txt", FileSize: 20, UploaderID: "owner", CurrentVersion: 1},
+	}
+	for _, record := range records {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatalf("写入测试数据失败: %v", err)
+		}
+	}
+
+	update := func(name string) *dto.ProjectResponse {
+		resp, err := svc.UpdateProject(ctx, &dto.UpdateProjectRequest{ID: "project-1", Name: name}, "owner")
+		if err != nil {
+			t.Fatalf("修改项目失败: %v", err)
+		}
+		if resp.Name != name {
+			t.Fatalf("修改后项目名称为 %q，应为 %q", resp.Name, name)
+		}
+		detail, err := svc.GetProjectByID(ctx, "project-1", "owner")
+		if err != nil {
+			t.Fatalf("获取项目详情失败: %v", err)
+		}
+		if resp.FileCount != detail.FileCount || resp.TotalSize != detail.TotalSize {
+			t.Fatalf("修改后返回 %d 个文件、%d 字节，项目详情为 %d 个文件、%d 字节", resp.FileCount, resp.TotalSize, detail.FileCount, detail.TotalSize)
+		}
+		return resp
+	}
+
+	if resp := update("phase 1 renamed"); resp.FileCount != 2 || resp.TotalSize != 30 {
+		t.Fatalf("修改后返回 %d 个文件、%d 字节，应为2个文件、30字节", resp.FileCount, resp.TotalSize)
+	}
+
+	// 有统计快照时使用最新的快照
+	if err := db.Create(&entity.StorageStat{ID: "stat-1", GroupID: "group-1", ProjectID: "project-1", StatDate: time.Now(), FileCount: 5, TotalSize: 500}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if resp := update("phase 1 again"); resp.FileCount != 5 || resp.TotalSize != 500 {
+		t.Fatalf("修改后返回 %d 个文件、%d 字节，应为快照中的5个文件、500字节", resp.FileCount, resp.TotalSize)
+	}
+}
+
+// TestGetUserProjectsKeywordBeyondFirstPage 关键字只匹配排在第一页之后的项目时仍能查到，总数按筛选后的结果计算
+func TestGetUserProjectsKeywordBeyondFirstPage(t *testing.T) {
+	svc, _, db := newTestProjectService(t)
+	ctx := context.Background()
+
+	records := []interface{}{
+		&entity.User{ID: "owner", Email: "owner@example.com", Name: "owner", Status: entity.UserStatusNormal},
+		&entity.User{ID: "user-1", Email: "user-1@example.com", Name: "user-1", Status: entity.UserStatusNormal},
+		&entity.Group{ID: "group-1", Name: "team", GroupKey: "team", InviteCode: "invite", CreatorID: "owner", Status: 1},
+	}
+	created := time.Now().Add(-time.Hour)
+	addProject := func(id, name string, status int, member bool) {
+		created = created.Add(time.Minute)
+		records = append(records, &entity.Project{ID: id, GroupID: "group-1", Name: name, PathPrefix: "/team/" + id, CreatorID: "owner", Status: status, CreatedAt: created})
+		if member {
+			records = append(records, &entity.ProjectMember{ID: "m-" + id, ProjectID: id, UserID: "user-1", Role: ProjectRoleViewer})
+		}
+	}
+	// 最早创建的项目按创建时间倒序排在最后几页
+	addProject("target-1", "target one", entity.ProjectStatusNormal, true)
+	addProject("target-2", "target two", entity.ProjectStatusNormal, true)
+	addProject("target-archived", "target archived", entity.ProjectStatusArchived, true)
+	addProject("target-other", "target not joined", entity.ProjectStatusNormal, false)
+	for i := 0; i < 12; i++ {
+		addProject(fmt.Sprintf("p-%02d", i), fmt.Sprintf("project %02d", i), entity.ProjectStatusNormal, true)
+	}
+	for _, record := range records {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatalf("写入测试数据失败: %v", err)
+		}
+	}
+
+	all, err := svc.GetUserProjects(ctx, &dto.ProjectQuery{Page: 1, Size: 5}, "user-1")
+	if err != nil {
+		t.Fatalf("获取用户项目失败: %v", err)
+	}
+	if all.Total != 15 || len(all.List) != 5 {
+		t.Fatalf("不筛选时总数 %d、本页 %d 个，应为15个、5个", all.Total, len(all.List))
+	}
+	for _, project := range all.List {
+		if strings.HasPrefix(project.ID, "target") {
+			t.Fatalf("第一页包含了最早创建的项目 %s", project.ID)
+		}
+	}
+
+	ids := func(result *dto.PageResult[*dto.ProjectResponse]) []string {
+		var ids []string
+		for _, project := range result.List {
+			ids = append(ids, project.ID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+	found, err := svc.GetUserProjects(ctx, &dto.ProjectQuery{Keyword: "target", Page: 1, Size: 5}, "user-1")
+	if err != nil {
+		t.Fatalf("按关键字查询失败: %v", err)
+	}
+	if want := []string{"target-1", "target-2", "target-archived"}; found.Total != 3 || !reflect.DeepEqual(ids(found), want) {
+		t.Fatalf("按关键字查到 %v（总数 %d），应为 %v", ids(found), found.Total, want)
+	}
+
+	normal, err := svc.GetUserProjects(ctx, &dto.ProjectQuery{Keyword: "target", Status: entity.ProjectStatusNormal, GroupID: "group-1", Page: 1, Size: 5}, "user-1")
+	if err != nil {
+		t.Fatalf("按关键字和状态查询失败: %v", err)
+	}
+	if want := []string{"target-1", "target-2"}; normal.Total != 2 || !reflect.DeepEqual(ids(normal), want) {
+		t.Fatalf("按关键字和状态查到 %v（总数 %d），应为 %v", ids(normal), normal.Total, want)
+	}
+
+	// 第二页没有剩余的匹配项目
+	second, err := svc.GetUserProjects(ctx, &dto.ProjectQuery{Keyword: "target", Page: 2, Size: 5}, "user-1")
+	if err != nil {
+		t.Fatalf("获取第二页失败: %v", err)
+	}
+	if second.Total != 3 || len(second.List) != 0 {
+		t.Fatalf("第二页总数 %d、本页 %d 个，应为3个、0个", second.Total, len(second.List))
+	}
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"oss-backend/internal/model/dto"
+	"oss-backend/internal/model/entity"
+)
+
+// defaultWebhookMaxPerProject 每个项目默认最多配置的Webhook数量
+const defaultWebhookMaxPerProject = 10
+
+// ErrInvalidWebhook Webhook配置无效
+var ErrInvalidWebhook = errors.New("Webhook配置无效")
+
+// ErrWebhookNotFound Webhook不存在
+var ErrWebhookNotFound = errors.New("Webhook不存在")
+
+// ErrWebhookForbidden 没有管理项目Webhook的权限
+var ErrWebhookForbidden = errors.New("没有权限管理项目Webhook")
+
+// webhookEvents 可订阅的事件
+var webhookEvents = []string{entity.OperationUpload, entity.OperationDelete, entity.OperationShare}
+
+// ListWebhooks 获取项目的Webhook列表，仅项目管理员可查看，不返回密钥
+func (s *projectService) ListWebhooks(ctx context.Context, projectID, userID string) ([]*dto.WebhookResponse, error) {
+	if err := s.checkWebhookAccess(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+	webhooks, err := s.projectRepo.ListWebhooks(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("获取Webhook列表失败: %w", err)
+	}
+	responses := make([]*dto.WebhookResponse, 0, len(webhooks))
+	for i := range webhooks {
+		responses = append(responses, buildWebhookResponse(&webhooks[i], false))
+	}
+	return responses, nil
+}
+
+// CreateWebhook 为项目添加Webhook，仅项目管理员可操作
+// 未指定密钥时自动生成，密钥只在本次响应中返回
+func (s *projectService) CreateWebhook(ctx context.Context, projectID string, req *dto.WebhookRequest, userID string) (*dto.WebhookResponse, error) {
+	if err := s.checkWebhookAccess(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+	targetURL, events, err := validateWebhook(req)
+	if err != nil {
+		return nil, err
+	}
+
+	maxCount := viper.GetInt64("webhook.max_per_project")
+	if maxCount <= 0 {
+		maxCount = defaultWebhookMaxPerProject
+	}
+	count, err := s.projectRepo.CountWebhooks(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxCount {
+		return nil, fmt.Errorf("%w: 每个项目最多配置%d个Webhook", ErrInvalidWebhook, maxCount)
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		if secret, err = generateWebhookSecret(); err != nil {
+			return nil, fmt.Errorf("生成密钥失败: %w", err)
+		}
+	}
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+
+	now := time.Now()
+	webhook := &entity.ProjectWebhook{
+		ProjectID: projectID,
+		URL:       targetURL,
+		Secret:    secret,
+		Events:    events,
+		Enabled:   enabled,
+		CreatedBy: userID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.projectRepo.CreateWebhook(ctx, webhook); err != nil {
+		return nil, fmt.Errorf("保存Webhook失败: %w", err)
+	}
+	return buildWebhookResponse(webhook, true), nil
+}
+
+// UpdateWebhook 更新项目Webhook，仅项目管理员可操作；指定新密钥时在响应中返回
+func (s *projectService) UpdateWebhook(ctx context.Context, projectID, webhookID string, req *dto.WebhookRequest, userID string) (*dto.WebhookResponse, error) {
+	if err := s.checkWebhookAccess(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+	targetURL, events, err := validateWebhook(req)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook, err := s.projectRepo.GetWebhook(ctx, projectID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if webhook == nil {
+		return nil, ErrWebhookNotFound
+	}
+
+	secretChanged := req.Secret != "" && req.Secret != webhook.Secret
+	if secretChanged {
+		webhook.Secret = req.Secret
+	}
+	if req.Enabled != nil {
+		webhook.Enabled = *req.Enabled
+	}
+	webhook.URL = targetURL
+	webhook.Events = events
+	webhook.UpdatedAt = time.Now()
+	if err := s.projectRepo.UpdateWebhook(ctx, webhook); err != nil {
+		return nil, fmt.Errorf("保存Webhook失败: %w", err)
+	}
+	return buildWebhookResponse(webhook, secretChanged), nil
+}
+
+// DeleteWebhook 删除项目Webhook，仅项目管理员可操作；已在队列中的推送仍会发出
+func (s *projectService) DeleteWebhook(ctx context.Context, projectID, webhookID, userID string) error {
+	if err := s.checkWebhookAccess(ctx, projectID, userID); err != nil {
+		return err
+	}
+	webhook, err := s.projectRepo.GetWebhook(ctx, projectID, webhookID)
+	if err != nil {
+		return err
+	}
+	if webhook == nil {
+		return ErrWebhookNotFound
+	}
+	return s.projectRepo.DeleteWebhook(ctx, projectID, webhookID)
+}
+
+// checkWebhookAccess 检查项目存在且用户是项目管理员
+func (s *projectService) checkWebhookAccess(ctx context.Context, projectID, userID string) error {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	if project == nil {
+		return errors.New("项目不存在")
+	}
+	hasAccess, err := s.CheckUserProjectAccess(ctx, userID, projectID, []string{ProjectRoleAdmin})
+	if err != nil {
+		return err
+	}
+	if !hasAccess {
+		return ErrWebhookForbidden
+	}
+	return nil
+}
+
+// validateWebhook 校验推送地址和订阅事件，返回规范化的地址和逗号分隔的事件
+func validateWebhook(req *dto.WebhookRequest) (string, string, error) {
+	targetURL := strings.TrimSpace(req.URL)
+	parsed, err := url.Parse(targetURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", "", fmt.Errorf("%w: 推送地址必须是 http 或 https 地址", ErrInvalidWebhook)
+	}
+	if parsed.User != nil {
+		return "", "", fmt.Errorf("%w: 推送地址不能包含用户名和密码", ErrInvalidWebhook)
+	}
+	if len(targetURL) > 1024 {
+		return "", "", fmt.Errorf("%w: 推送地址不能超过1024个字符", ErrInvalidWebhook)
+	}
+	if len(req.Secret) > 128 {
+		return "", "", fmt.Errorf("%w: 密钥不能超过128个字符", ErrInvalidWebhook)
+	}
+
+	// 按固定顺序保存，去掉重复的事件
+	subscribed := make(map[string]bool, len(req.Events))
+	for _, event := range req.Events {
+		event = strings.ToLower(strings.TrimSpace(event))
+		if !isWebhookEvent(event) {
+			return "", "", fmt.Errorf("%w: 不支持的事件 %q，可选 %s", ErrInvalidWebhook, event, strings.Join(webhookEvents, "、"))
+		}
+		subscribed[event] = true
+	}
+	events := make([]string, 0, len(subscribed))
+	for _, event := range webhookEvents {
+		if subscribed[event] {
+			events = append(events, event)
+		}
+	}
+	return targetURL, strings.Join(events, ","), nil
+}
+
+// isWebhookEvent 判断是否是可订阅的事件
+func isWebhookEvent(event string) bool {
+	for _, e := range webhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// generateWebhookSecret 生成32字节的随机密钥，十六进制编码
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// buildWebhookResponse 构建Webhook响应，withSecret 为 true 时返回密钥
+func buildWebhookResponse(webhook *entity.ProjectWebhook, withSecret bool) *dto.WebhookResponse {
+	response := &dto.WebhookResponse{
+		ID:        webhook.ID,
+		ProjectID: webhook.ProjectID,
+		URL:       webhook.URL,
+		Events:    strings.Split(webhook.Events, ","),
+		Enabled:   webhook.Enabled,
+		CreatedBy: webhook.CreatedBy,
+		CreatedAt: webhook.CreatedAt,
+		UpdatedAt: webhook.UpdatedAt,
+	}
+	if withSecret {
+		response.Secret = webhook.Secret
+	}
+	return response
+}
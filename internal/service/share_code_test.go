@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+)
+
+const shareCodeCharset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// setShareCodeLengths 为测试配置分享码长度
+func setShareCodeLengths(t *testing.T, minLength, maxLength int) {
+	t.Helper()
+	viper.Set("share.code_min_length", minLength)
+	viper.Set("share.code_max_length", maxLength)
+	t.Cleanup(func() {
+		viper.Set("share.code_min_length", 0)
+		viper.Set("share.code_max_length", 0)
+	})
+}
+
+// takenShareRepo 前 taken 次保存分享时返回分享码冲突，之后交给真实仓库保存
+type takenShareRepo struct {
+	repository.FileRepository
+	taken int
+	codes []string
+}
+
+func (r *takenShareRepo) CreateShare(ctx context.Context, share *entity.FileShare) error {
+	r.codes = append(r.codes, share.ShareCode)
+	if len(r.codes) <= r.taken {
+		return repository.ErrShareCodeTaken
+	}
+	return r.FileRepository.CreateShare(ctx, share)
+}
+
+func TestGenerateShareCode(t *testing.T) {
+	seen := make(map[string]bool)
+	used := make(map[rune]bool)
+	for i := 0; i < 2000; i++ {
+		code, err := generateShareCode(16)
+		if err != nil {
+			t.Fatalf("生成分享码失败: %v", err)
+		}
+		if len(code) != 16 {
+			t.Fatalf("分享码 %q 长度为 %d，应为16", code, len(code))
+		}
+		for _, c := range code {
+			if !strings.ContainsRune(shareCodeCharset, c) {
+				t.Fatalf("分享码 %q 包含字母和数字以外的字符 %q", code, c)
+			}
+			used[c] = true
+		}
+		if seen[code] {
+			t.Fatalf("分享码 %q 重复生成", code)
+		}
+		seen[code] = true
+	}
+	// 32000个字符应覆盖字符集中的每个字符
+	if len(used) != len(shareCodeCharset) {
+		t.Fatalf("只用到了 %d 个字符，应为 %d 个", len(used), len(shareCodeCharset))
+	}
+}
+
+func TestShareCodeLengths(t *testing.T) {
+	tests := []struct {
+		minLength, maxLength int
+		wantMin, wantMax     int
+	}{
+		{0, 0, defaultShareCodeMinLength, defaultShareCodeMaxLength},
+		{10, 12, 10, 12},
+		{12, 10, 12, 12},
+		{40, 50, shareCodeLengthLimit, shareCodeLengthLimit},
+	}
+	for _, tt := range tests {
+		setShareCodeLengths(t, tt.minLength, tt.maxLength)
+		if gotMin, gotMax := shareCodeLengths(); gotMin != tt.wantMin || gotMax != tt.wantMax {
+			t.Errorf("配置 %d-%d 时长度为 %d-%d，应为 %d-%d", tt.minLength, tt.maxLength, gotMin, gotMax, tt.wantMin, tt.wantMax)
+		}
+	}
+}
+
+// TestCreateShareRetriesOnCollision 分享码冲突时换码重试，每次重试长度加一且不超过最大长度
+func TestCreateShareRetriesOnCollision(t *testing.T) {
+	setShareCodeLengths(t, 8, 9)
+	db := newTestDB(t, &entity.FileShare{})
+	repo := &takenShareRepo{FileRepository: repository.NewFileRepository(db), taken: 2}
+	svc := &fileService{fileRepo: repo}
+
+	share := &entity.FileShare{FileID: "file-1", UserID: "user-1"}
+	if err := svc.createShareWithUniqueCode(context.Background(), share); err != nil {
+		t.Fatalf("创建分享失败: %v", err)
+	}
+	if len(repo.codes) != 3 {
+		t.Fatalf("尝试了 %d 次，应为3次", len(repo.codes))
+	}
+	for i, want := range []int{8, 9, 9} {
+		if len(repo.codes[i]) != want {
+			t.Errorf("第%d次尝试的分享码长度为 %d，应为 %d", i+1, len(repo.codes[i]), want)
+		}
+	}
+	if share.ShareCode != repo.codes[2] {
+		t.Fatalf("分享码 %q 不是最后一次生成的分享码", share.ShareCode)
+	}
+
+	repo = &takenShareRepo{FileRepository: repository.NewFileRepository(db), taken: shareCodeMaxAttempts}
+	svc.fileRepo = repo
+	err := svc.createShareWithUniqueCode(context.Background(), &entity.FileShare{FileID: "file-1", UserID: "user-1"})
+	if !errors.Is(err, repository.ErrShareCodeTaken) || len(repo.codes) != shareCodeMaxAttempts {
+		t.Fatalf("冲突 %d 次后返回 %v，应在尝试 %d 次后返回 ErrShareCodeTaken", len(repo.codes), err, shareCodeMaxAttempts)
+	}
+}
+
+// TestCreateShareConcurrentUniqueCodes 并发创建分享时依赖唯一索引识别冲突，所有分享码互不相同
+func TestCreateShareConcurrentUniqueCodes(t *testing.T) {
+	// 最短长度为1时前几个分享必然冲突，覆盖按唯一索引重试的路径
+	setShareCodeLengths(t, 1, 4)
+	db := newTestDB(t, &entity.FileShare{})
+	svc := &fileService{fileRepo: repository.NewFileRepository(db)}
+
+	const count = 100
+	errs := make([]error, count)
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			share := &entity.FileShare{FileID: fmt.Sprintf("file-%d", i), UserID: "user-1"}
+			errs[i] = svc.createShareWithUniqueCode(context.Background(), share)
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("创建第%d个分享失败: %v", i, err)
+		}
+	}
+
+	var codes []string
+	db.Model(&entity.FileShare{}).Pluck("share_code", &codes)
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if seen[code] {
+			t.Fatalf("分享码 %q 重复", code)
+		}
+		seen[code] = true
+	}
+	if len(seen) != count {
+		t.Fatalf("保存了 %d 个分享码，应为 %d 个", len(seen), count)
+	}
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+	"oss-backend/pkg/minio"
+)
+
+// newTestShareService 创建带有一个已上传文件的文件服务，用于分享下载测试
+func newTestShareService(t *testing.T) (*fileService, *fakeObjectStore, *gorm.DB, *entity.File) {
+	t.Helper()
+	project := newTestProject()
+	svc, store, db := newTestFileService(t, nil, project)
+	createTestTables(t, db, &entity.FileShare{})
+	svc.fileRepo = &testFileRepo{FileRepository: repository.NewFileRepository(db), db: db}
+
+	file := &entity.File{ID: "file-1", ProjectID: project.ID, FileName: "a.txt", FullPath: "a.txt", FileSize: 7, UploaderID: "user-1", CurrentVersion: 1}
+	if err := db.Create(file).Error; err != nil {
+		t.Fatal(err)
+	}
+	store.putObject(groupBucketName(project.Group.GroupKey), minio.GetObjectName(project.ID, "", file.FileName), []byte("content"))
+	return svc, store, db, file
+}
+
+// downloadShare 下载分享文件并读完内容
+func downloadShare(svc *fileService, code, password string) error {
+	reader, _, err := svc.DownloadSharedFile(context.Background(), code, password)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	_, err = io.ReadAll(reader)
+	return err
+}
+
+// TestShareDownloadCountLimit 每次下载计数加1，达到次数限制后拒绝下载且计数不再增加
+func TestShareDownloadCountLimit(t *testing.T) {
+	svc, _, db, file := newTestShareService(t)
+	share, err := svc.CreateShare(context.Background(), file.ID, "user-1", "", nil, intPtr(2), nil)
+	if err != nil {
+		t.Fatalf("创建分享失败: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := downloadShare(svc, share.ShareCode, ""); err != nil {
+			t.Fatalf("第 %d 次下载失败: %v", i+1, err)
+		}
+	}
+	if err := downloadShare(svc, share.ShareCode, ""); !errors.Is(err, ErrShareLimitReached) {
+		t.Fatalf("超出次数限制的下载返回 %v，应返回 ErrShareLimitReached", err)
+	}
+
+	var stored entity.FileShare
+	if err := db.First(&stored, "id = ?", share.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.DownloadCount != 2 {
+		t.Fatalf("下载计数为 %d，应为2", stored.DownloadCount)
+	}
+}
+
+// TestShareDownloadLimitConcurrent 次数限制为1时10个并发下载只有1个成功，其余返回 ErrShareLimitReached
+func TestShareDownloadLimitConcurrent(t *testing.T) {
+	const downloaders = 10
+	svc, _, db, file := newTestShareService(t)
+	share, err := svc.CreateShare(context.Background(), file.ID, "user-1", "", nil, intPtr(1), nil)
+	if err != nil {
+		t.Fatalf("创建分享失败: %v", err)
+	}
+
+	errs := make([]error, downloaders)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < downloaders; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = downloadShare(svc, share.ShareCode, "")
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	succeeded := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.Is(err, ErrShareLimitReached):
+		default:
+			t.Fatalf("下载 %d 返回了意外的错误: %v", i, err)
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("%d 个并发下载中有 %d 个成功，应只有1个", downloaders, succeeded)
+	}
+	var stored entity.FileShare
+	if err := db.First(&stored, "id = ?", share.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.DownloadCount != 1 {
+		t.Fatalf("下载计数为 %d，应为1", stored.DownloadCount)
+	}
+}
+
+// TestShareDownloadReleasesSlot 占用下载次数后读取文件失败时归还次数，之后仍可正常下载
+func TestShareDownloadReleasesSlot(t *testing.T) {
+	svc, store, db, file := newTestShareService(t)
+	share, err := svc.CreateShare(context.Background(), file.ID, "user-1", "", nil, intPtr(1), nil)
+	if err != nil {
+		t.Fatalf("创建分享失败: %v", err)
+	}
+	bucket := groupBucketName(newTestProject().Group.GroupKey)
+	key := minio.GetObjectName(file.ProjectID, "", file.FileName)
+	content, _ := store.object(bucket, key)
+
+	// 存储对象暂时不可用
+	store.mu.Lock()
+	delete(store.objects, bucket+"/"+key)
+	store.mu.Unlock()
+	if err := downloadShare(svc, share.ShareCode, ""); err == nil || errors.Is(err, ErrShareLimitReached) {
+		t.Fatalf("对象不存在时下载返回 %v，应返回读取失败", err)
+	}
+	count := func() int {
+		var stored entity.FileShare
+		if err := db.First(&stored, "id = ?", share.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		return stored.DownloadCount
+	}
+	if n := count(); n != 0 {
+		t.Fatalf("下载失败后计数为 %d，占用的次数没有归还", n)
+	}
+
+	store.putObject(bucket, key, content)
+	if err := downloadShare(svc, share.ShareCode, ""); err != nil {
+		t.Fatalf("归还次数后下载失败: %v", err)
+	}
+	if err := downloadShare(svc, share.ShareCode, ""); !errors.Is(err, ErrShareLimitReached) {
+		t.Fatalf("用完次数后下载返回 %v，应返回 ErrShareLimitReached", err)
+	}
+	if n := count(); n != 1 {
+		t.Fatalf("下载计数为 %d，应为1", n)
+	}
+}
+
+// TestRevokeAndListUserShares 撤销的分享码与过期分享返回相同的错误，我的分享只列出自己创建的有效分享
+func TestRevokeAndListUserShares(t *testing.T) {
+	ctx := context.Background()
+	svc, _, db, file := newTestShareService(t)
+	create := func(userID string, limit int) *entity.FileShare {
+		t.Helper()
+		share, err := svc.CreateShare(ctx, file.ID, userID, "", nil, intPtr(limit), nil)
+		if err != nil {
+			t.Fatalf("创建分享失败: %v", err)
+		}
+		return share
+	}
+	active := create("user-1", 0)
+	revoked := create("user-1", 0)
+	expired := create("user-1", 0)
+	exhausted := create("user-1", 1)
+	others := create("user-2", 0)
+	past := time.Now().Add(-time.Hour)
+	if err := db.Model(&entity.FileShare{}).Where("id = ?", expired.ID).Update("expire_at", past).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := downloadShare(svc, exhausted.ShareCode, ""); err != nil {
+		t.Fatalf("下载失败: %v", err)
+	}
+
+	// 只有创建者可以撤销
+	if err := svc.RevokeShare(ctx, revoked.ID, "user-2"); !errors.Is(err, ErrShareNotOwner) {
+		t.Fatalf("他人撤销分享返回 %v，应返回 ErrShareNotOwner", err)
+	}
+	if err := svc.RevokeShare(ctx, revoked.ID, "user-1"); err != nil {
+		t.Fatalf("撤销分享失败: %v", err)
+	}
+	if err := svc.RevokeShare(ctx, revoked.ID, "user-1"); !errors.Is(err, ErrShareNotFound) {
+		t.Fatalf("重复撤销返回 %v，应返回 ErrShareNotFound", err)
+	}
+
+	_, revokedErr := svc.GetShareInfo(ctx, revoked.ShareCode)
+	_, expiredErr := svc.GetShareInfo(ctx, expired.ShareCode)
+	_, unknownErr := svc.GetShareInfo(ctx, "never-existed")
+	for name, err := range map[string]error{"撤销的分享": revokedErr, "过期的分享": expiredErr, "不存在的分享码": unknownErr} {
+		if !errors.Is(err, ErrShareNotFound) || err.Error() != "分享不存在或已过期" {
+			t.Errorf("%s返回 %v，应返回“分享不存在或已过期”", name, err)
+		}
+	}
+	if err := downloadShare(svc, revoked.ShareCode, ""); !errors.Is(err, ErrShareNotFound) {
+		t.Fatalf("下载撤销的分享返回 %v，应返回 ErrShareNotFound", err)
+	}
+
+	shares, total, err := svc.ListUserShares(ctx, "user-1", 1, 10)
+	if err != nil {
+		t.Fatalf("获取我的分享失败: %v", err)
+	}
+	if total != 1 || len(shares) != 1 || shares[0].ID != active.ID {
+		t.Fatalf("我的分享共 %d 个，应只有未撤销、未过期、未用完的 %s", total, active.ID)
+	}
+	if shares[0].File.FileName != file.FileName {
+		t.Fatalf("分享的文件名为 %q，应为 %q", shares[0].File.FileName, file.FileName)
+	}
+	if shares, total, _ := svc.ListUserShares(ctx, "user-2", 1, 10); total != 1 || shares[0].ID != others.ID {
+		t.Fatalf("user-2 的分享共 %d 个，应只有自己创建的1个", total)
+	}
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// TestSharePassword 未设置密码的分享无需密码即可下载，设置的密码只保存哈希并可用原密码校验
+func TestSharePassword(t *testing.T) {
+	ctx := context.Background()
+	svc, _, db, file := newTestShareService(t)
+
+	download := func(code, password string) error {
+		reader, _, err := svc.DownloadSharedFile(ctx, code, password)
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		if string(data) != "content" {
+			t.Fatalf("下载内容为 %q", data)
+		}
+		return nil
+	}
+
+	open, err := svc.CreateShare(ctx, file.ID, "user-1", "", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("创建无密码分享失败: %v", err)
+	}
+	if open.Password != "" {
+		t.Fatalf("无密码分享保存了密码 %q", open.Password)
+	}
+	if err := download(open.ShareCode, ""); err != nil {
+		t.Fatalf("无密码分享下载失败: %v", err)
+	}
+	if err := download(open.ShareCode, "anything"); err != nil {
+		t.Fatalf("无密码分享附带密码时下载失败: %v", err)
+	}
+
+	const password = "secret42"
+	protected, err := svc.CreateShare(ctx, file.ID, "user-1", password, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("创建带密码分享失败: %v", err)
+	}
+	var stored entity.FileShare
+	if err := db.First(&stored, "id = ?", protected.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Password == password || !strings.HasPrefix(stored.Password, "$2") {
+		t.Fatalf("数据库中的密码为 %q，应为bcrypt哈希", stored.Password)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(stored.Password), []byte(password)) != nil {
+		t.Fatalf("保存的哈希与原密码不匹配")
+	}
+
+	for _, wrong := range []string{"", "secret43", stored.Password} {
+		if err := download(protected.ShareCode, wrong); err == nil || err.Error() != "密码错误" {
+			t.Fatalf("使用密码 %q 下载返回 %v，应返回密码错误", wrong, err)
+		}
+	}
+	if err := download(protected.ShareCode, password); err != nil {
+		t.Fatalf("使用正确密码下载失败: %v", err)
+	}
+}
+
+// setSharePasswordPolicy 设置分享密码强度策略，测试结束后恢复默认
+func setSharePasswordPolicy(t *testing.T, minLength int, requireMixed bool) {
+	t.Helper()
+	viper.Set("share.password_min_length", minLength)
+	viper.Set("share.password_require_mixed", requireMixed)
+	t.Cleanup(func() {
+		viper.Set("share.password_min_length", 0)
+		viper.Set("share.password_require_mixed", false)
+	})
+}
+
+// TestSharePasswordStrength 不满足强度策略的密码被拒绝，生成的密码满足策略且互不相同
+func TestSharePasswordStrength(t *testing.T) {
+	setSharePasswordPolicy(t, 16, true)
+
+	project := newTestProject()
+	db := newTestDB(t, &entity.File{}, &entity.FileShare{})
+	repo := &testFileRepo{FileRepository: repository.NewFileRepository(db), db: db}
+	svc := &fileService{fileRepo: repo, projectRepo: &testProjectRepo{project: project}, auditRepo: testAuditRepo{}}
+	file := &entity.File{ID: "file-1", ProjectID: project.ID, FileName: "a.txt", FullPath: "a.txt", UploaderID: "user-1", CurrentVersion: 1}
+	if err := db.Create(file).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for _, weak := range []string{"short1", "abcdefghijklmnopq", "12345678901234567"} {
+		if _, err := svc.CreateShare(context.Background(), file.ID, "user-1", weak, nil, nil, nil); !errors.Is(err, ErrWeakSharePassword) {
+			t.Fatalf("密码 %q 返回 %v，应返回 ErrWeakSharePassword", weak, err)
+		}
+	}
+	var count int64
+	db.Model(&entity.FileShare{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("弱密码创建了 %d 个分享", count)
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i < 200; i++ {
+		password, err := GenerateSharePassword()
+		if err != nil {
+			t.Fatalf("生成密码失败: %v", err)
+		}
+		if len(password) != 16 {
+			t.Fatalf("生成的密码 %q 长度不是策略要求的16位", password)
+		}
+		if err := validateSharePassword(password); err != nil {
+			t.Fatalf("生成的密码 %q 不满足策略: %v", password, err)
+		}
+		if seen[password] {
+			t.Fatalf("生成了重复的密码 %q", password)
+		}
+		seen[password] = true
+	}
+
+	password, _ := GenerateSharePassword()
+	share, err := svc.CreateShare(context.Background(), file.ID, "user-1", password, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("使用生成的密码创建分享失败: %v", err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(share.Password), []byte(password)) != nil {
+		t.Fatalf("分享保存的哈希与生成的密码不匹配")
+	}
+}
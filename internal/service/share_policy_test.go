@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+)
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestApplySharePolicy(t *testing.T) {
+	tests := []struct {
+		name                   string
+		defaultExpire, maxExp  int
+		defaultLimit, maxLimit int
+		expireHours, limit     *int
+		wantExpire, wantLimit  int
+		wantErr                bool
+	}{
+		{name: "未设置策略时不限制", wantExpire: 0, wantLimit: 0},
+		{name: "未设置策略时使用请求值", expireHours: intPtr(48), limit: intPtr(3), wantExpire: 48, wantLimit: 3},
+		{name: "未指定时使用默认值", defaultExpire: 72, maxExp: 720, defaultLimit: 5, maxLimit: 10, wantExpire: 72, wantLimit: 5},
+		{name: "未设置默认值时取上限", maxExp: 720, maxLimit: 10, wantExpire: 720, wantLimit: 10},
+		{name: "不超过上限的请求值", defaultExpire: 72, maxExp: 720, expireHours: intPtr(720), limit: intPtr(1), wantExpire: 720, wantLimit: 1},
+		{name: "有效期超过上限", maxExp: 720, expireHours: intPtr(721), wantErr: true},
+		{name: "下载次数超过上限", maxLimit: 10, limit: intPtr(11), wantErr: true},
+		{name: "有上限时不能永不过期", maxExp: 720, expireHours: intPtr(0), wantErr: true},
+		{name: "有上限时不能不限次数", maxLimit: 10, limit: intPtr(0), wantErr: true},
+		{name: "负数", expireHours: intPtr(-1), wantErr: true},
+	}
+	for _, tt := range tests {
+		project := &entity.Project{
+			ShareDefaultExpireHours:   tt.defaultExpire,
+			ShareMaxExpireHours:       tt.maxExp,
+			ShareDefaultDownloadLimit: tt.defaultLimit,
+			ShareMaxDownloadLimit:     tt.maxLimit,
+		}
+		expire, limit, err := applySharePolicy(project, tt.expireHours, tt.limit)
+		if tt.wantErr {
+			if !errors.Is(err, ErrSharePolicyViolation) {
+				t.Errorf("%s: 返回 %d/%d, %v，应返回 ErrSharePolicyViolation", tt.name, expire, limit, err)
+			}
+			continue
+		}
+		if err != nil || expire != tt.wantExpire || limit != tt.wantLimit {
+			t.Errorf("%s: 返回 %d/%d, %v，应为 %d/%d", tt.name, expire, limit, err, tt.wantExpire, tt.wantLimit)
+		}
+	}
+}
+
+// TestCreateShareAppliesPolicy 超出项目上限的分享被拒绝，未指定有效期的分享使用项目默认值
+func TestCreateShareAppliesPolicy(t *testing.T) {
+	project := newTestProject()
+	project.ShareDefaultExpireHours = 72
+	project.ShareMaxExpireHours = 720
+	project.ShareMaxDownloadLimit = 10
+
+	db := newTestDB(t, &entity.File{}, &entity.FileShare{})
+	repo := &testFileRepo{FileRepository: repository.NewFileRepository(db), db: db}
+	svc := &fileService{fileRepo: repo, projectRepo: &testProjectRepo{project: project}, auditRepo: testAuditRepo{}}
+	file := &entity.File{ID: "file-1", ProjectID: project.ID, FileName: "a.txt", FullPath: "a.txt", UploaderID: "user-1", CurrentVersion: 1}
+	if err := db.Create(file).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := svc.CreateShare(context.Background(), file.ID, "user-1", "", intPtr(721), nil, nil); !errors.Is(err, ErrSharePolicyViolation) {
+		t.Fatalf("超过最长有效期的分享返回 %v，应返回 ErrSharePolicyViolation", err)
+	}
+	if _, err := svc.CreateShare(context.Background(), file.ID, "user-1", "", nil, intPtr(0), nil); !errors.Is(err, ErrSharePolicyViolation) {
+		t.Fatalf("设置了下载上限时不限次数的分享返回 %v，应返回 ErrSharePolicyViolation", err)
+	}
+	var count int64
+	db.Model(&entity.FileShare{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("被拒绝的请求保存了 %d 条分享记录", count)
+	}
+
+	before := time.Now()
+	share, err := svc.CreateShare(context.Background(), file.ID, "user-1", "", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("创建分享失败: %v", err)
+	}
+	if share.ExpireAt == nil {
+		t.Fatalf("未指定有效期的分享应使用项目默认有效期")
+	}
+	if d := share.ExpireAt.Sub(before); d < 72*time.Hour || d > 72*time.Hour+time.Minute {
+		t.Fatalf("分享有效期为 %s，应为72小时", d)
+	}
+	if share.DownloadLimit != 10 {
+		t.Fatalf("未设置默认下载次数时应取上限10，实际为 %d", share.DownloadLimit)
+	}
+}
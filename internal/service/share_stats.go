@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"oss-backend/internal/model/dto"
+)
+
+// 分享统计中列出的分享最多的文件数
+const shareStatsTopFiles = 10
+
+// GetProjectShareStats 获取项目的分享统计：分享数、有效分享数、通过分享的下载次数、
+// 密码与有效期等设置的使用情况，以及分享最多的文件
+func (s *fileService) GetProjectShareStats(ctx context.Context, projectID string) (*dto.ProjectShareStatsResponse, error) {
+	project, err := s.projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if project == nil {
+		return nil, errors.New("项目不存在")
+	}
+
+	stats, err := s.fileRepo.GetProjectShareStats(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("统计项目分享失败: %w", err)
+	}
+	topFiles, err := s.fileRepo.ListMostSharedFiles(ctx, projectID, shareStatsTopFiles)
+	if err != nil {
+		return nil, fmt.Errorf("统计分享最多的文件失败: %w", err)
+	}
+
+	response := &dto.ProjectShareStatsResponse{
+		ProjectID:         projectID,
+		TotalShares:       stats.TotalShares,
+		ActiveShares:      stats.ActiveShares,
+		TotalDownloads:    stats.TotalDownloads,
+		PasswordProtected: stats.PasswordProtected,
+		WithExpiry:        stats.WithExpiry,
+		WithDownloadLimit: stats.WithDownloadLimit,
+		TopFiles:          make([]dto.SharedFileStat, 0, len(topFiles)),
+	}
+	for _, file := range topFiles {
+		response.TopFiles = append(response.TopFiles, dto.SharedFileStat{
+			FileID:        file.FileID,
+			FileName:      file.FileName,
+			FullPath:      file.FullPath,
+			ShareCount:    file.ShareCount,
+			DownloadCount: file.DownloadCount,
+		})
+	}
+	return response, nil
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"oss-backend/internal/model/entity"
+)
+
+// TestGetProjectShareStats 按项目汇总分享数、有效分享数、下载次数和设置使用情况，已删除文件和其他项目的分享不计入有效分享和分享最多的文件
+func TestGetProjectShareStats(t *testing.T) {
+	ctx := context.Background()
+	svc, _, db, file := newTestShareService(t)
+	project := newTestProject()
+
+	past := time.Now().Add(-time.Hour)
+	records := []interface{}{
+		&entity.File{ID: "file-2", ProjectID: project.ID, FileName: "b.txt", FullPath: "b.txt", FileSize: 7, UploaderID: "user-1", CurrentVersion: 1},
+		&entity.File{ID: "file-3", ProjectID: project.ID, FileName: "c.txt", FullPath: "c.txt", FileSize: 7, UploaderID: "user-1", CurrentVersion: 1, IsDeleted: true, DeletedAt: &past},
+		&entity.File{ID: "other-file", ProjectID: "project-2", FileName: "d.txt", FullPath: "d.txt", FileSize: 7, UploaderID: "user-1", CurrentVersion: 1},
+		&entity.FileShare{ID: "expired", FileID: "file-2", UserID: "user-1", ShareCode: "expired", ExpireAt: &past},
+		&entity.FileShare{ID: "deleted-file", FileID: "file-3", UserID: "user-1", ShareCode: "deleted-file", DownloadCount: 3},
+		&entity.FileShare{ID: "other-project", FileID: "other-file", UserID: "user-1", ShareCode: "other-project", DownloadCount: 5},
+	}
+	for _, record := range records {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatalf("写入测试数据失败: %v", err)
+		}
+	}
+
+	const password = "secret42"
+	plain, err := svc.CreateShare(ctx, file.ID, "user-1", "", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("创建分享失败: %v", err)
+	}
+	protected, err := svc.CreateShare(ctx, file.ID, "user-1", password, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("创建带密码的分享失败: %v", err)
+	}
+	limited, err := svc.CreateShare(ctx, file.ID, "user-1", "", nil, intPtr(1), nil)
+	if err != nil {
+		t.Fatalf("创建限制次数的分享失败: %v", err)
+	}
+	if _, err := svc.CreateShare(ctx, "file-2", "user-1", "", intPtr(24), nil, nil); err != nil {
+		t.Fatalf("创建带有效期的分享失败: %v", err)
+	}
+
+	downloads := []struct {
+		code     string
+		password string
+	}{
+		{plain.ShareCode, ""},
+		{plain.ShareCode, ""},
+		{protected.ShareCode, password},
+		// 达到次数限制后分享不再有效
+		{limited.ShareCode, ""},
+	}
+	for _, d := range downloads {
+		if err := downloadShare(svc, d.code, d.password); err != nil {
+			t.Fatalf("下载分享 %s 失败: %v", d.code, err)
+		}
+	}
+
+	stats, err := svc.GetProjectShareStats(ctx, project.ID)
+	if err != nil {
+		t.Fatalf("获取分享统计失败: %v", err)
+	}
+	if stats.TotalShares != 6 || stats.ActiveShares != 3 || stats.TotalDownloads != 7 {
+		t.Fatalf("分享 %d 个、有效 %d 个、下载 %d 次，应为6个、3个、7次", stats.TotalShares, stats.ActiveShares, stats.TotalDownloads)
+	}
+	if stats.PasswordProtected != 1 || stats.WithExpiry != 2 || stats.WithDownloadLimit != 1 {
+		t.Fatalf("带密码 %d 个、带有效期 %d 个、限制次数 %d 个，应为1个、2个、1个", stats.PasswordProtected, stats.WithExpiry, stats.WithDownloadLimit)
+	}
+
+	if len(stats.TopFiles) != 2 {
+		t.Fatalf("分享最多的文件有 %d 个，应只列出未删除的2个: %+v", len(stats.TopFiles), stats.TopFiles)
+	}
+	first, second := stats.TopFiles[0], stats.TopFiles[1]
+	if first.FileID != file.ID || first.ShareCount != 3 || first.DownloadCount != 4 || first.FullPath != "a.txt" {
+		t.Fatalf("分享最多的文件为 %+v，应为 a.txt 分享3次、下载4次", first)
+	}
+	if second.FileID != "file-2" || second.ShareCount != 2 || second.DownloadCount != 0 {
+		t.Fatalf("第二个文件为 %+v，应为 b.txt 分享2次、下载0次", second)
+	}
+}
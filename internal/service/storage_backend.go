@@ -0,0 +1,133 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/pkg/minio"
+)
+
+// DefaultStorageBackend 默认存储后端的名称，即 minio 配置的服务
+// 文件记录中存储后端为空表示位于默认后端
+const DefaultStorageBackend = "default"
+
+// ErrInvalidStorageBackendConfig 存储后端配置无效
+var ErrInvalidStorageBackendConfig = errors.New("存储后端配置无效")
+
+// ErrStorageBackendNotFound 存储后端不存在
+var ErrStorageBackendNotFound = errors.New("存储后端不存在")
+
+// StorageBackendConfig 额外的S3兼容存储后端，对应配置中 storage.backends 的一项
+type StorageBackendConfig struct {
+	Name      string `mapstructure:"name"` // 后端名称，记录在文件的 storage_backend 字段中
+	Endpoint  string `mapstructure:"endpoint"`
+	AccessKey string `mapstructure:"access_key"`
+	SecretKey string `mapstructure:"secret_key"`
+	UseSSL    bool   `mapstructure:"use_ssl"`
+}
+
+// storageBackends 按名称缓存的额外存储后端客户端，首次使用时按配置创建
+var storageBackends struct {
+	once    sync.Once
+	clients map[string]*minio.Client
+	err     error
+}
+
+// LoadStorageBackends 读取并校验额外存储后端配置
+func LoadStorageBackends() ([]StorageBackendConfig, error) {
+	var backends []StorageBackendConfig
+	if err := viper.UnmarshalKey("storage.backends", &backends); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStorageBackendConfig, err)
+	}
+
+	names := make(map[string]bool, len(backends))
+	for i, backend := range backends {
+		if backend.Name == "" || strings.ContainsAny(backend.Name, "/\\ ") {
+			return nil, fmt.Errorf("%w: 第%d个存储后端名称为空或包含非法字符", ErrInvalidStorageBackendConfig, i+1)
+		}
+		if backend.Name == DefaultStorageBackend {
+			return nil, fmt.Errorf("%w: 存储后端名称 %s 已被默认后端使用", ErrInvalidStorageBackendConfig, backend.Name)
+		}
+		if names[backend.Name] {
+			return nil, fmt.Errorf("%w: 存储后端 %s 重复", ErrInvalidStorageBackendConfig, backend.Name)
+		}
+		names[backend.Name] = true
+
+		if backend.Endpoint == "" {
+			return nil, fmt.Errorf("%w: 存储后端 %s 未配置 endpoint", ErrInvalidStorageBackendConfig, backend.Name)
+		}
+	}
+	return backends, nil
+}
+
+// storageBackendClients 获取额外存储后端的客户端
+func storageBackendClients() (map[string]*minio.Client, error) {
+	storageBackends.once.Do(func() {
+		backends, err := LoadStorageBackends()
+		if err != nil {
+			storageBackends.err = err
+			return
+		}
+		clients := make(map[string]*minio.Client, len(backends))
+		for _, backend := range backends {
+			client, err := minio.NewClient(minio.Config{
+				Endpoint:  backend.Endpoint,
+				AccessKey: backend.AccessKey,
+				SecretKey: backend.SecretKey,
+				UseSSL:    backend.UseSSL,
+			})
+			if err != nil {
+				storageBackends.err = fmt.Errorf("%w: 存储后端 %s: %v", ErrInvalidStorageBackendConfig, backend.Name, err)
+				return
+			}
+			clients[backend.Name] = client
+		}
+		storageBackends.clients = clients
+	})
+	return storageBackends.clients, storageBackends.err
+}
+
+// normalizeStorageBackend 将默认后端统一为空字符串，与文件记录中的存储方式一致
+func normalizeStorageBackend(name string) string {
+	if name == DefaultStorageBackend {
+		return ""
+	}
+	return name
+}
+
+// CheckStorageBackend 检查存储后端是否已配置，default 表示默认后端
+func CheckStorageBackend(name string) error {
+	if normalizeStorageBackend(name) == "" {
+		return nil
+	}
+	clients, err := storageBackendClients()
+	if err != nil {
+		return err
+	}
+	if _, ok := clients[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrStorageBackendNotFound, name)
+	}
+	return nil
+}
+
+// storageClient 获取指定存储后端的客户端，空字符串或 default 表示默认后端
+func (s *fileService) storageClient(name string) (*minio.Client, error) {
+	name = normalizeStorageBackend(name)
+	if name == "" {
+		return s.minioClient, nil
+	}
+	if err := CheckStorageBackend(name); err != nil {
+		return nil, err
+	}
+	return storageBackends.clients[name], nil
+}
+
+// fileStorage 获取文件当前所在存储后端的客户端，文件内容及其历史版本都从这里读写
+func (s *fileService) fileStorage(file *entity.File) (*minio.Client, error) {
+	return s.storageClient(file.StorageBackend)
+}
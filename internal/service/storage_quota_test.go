@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"oss-backend/internal/model/dto"
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+)
+
+// TestStorageQuotaConcurrentUploads 并发上传同时通过重名检查后，配额预占保证写入总量不超过项目配额，预占在上传结束后全部释放
+func TestStorageQuotaConcurrentUploads(t *testing.T) {
+	const uploads = 10
+	const size = 30
+	project := newTestProject()
+	project.StorageQuota = 100
+
+	// 所有上传都完成重名检查后才继续，同时进入配额判断
+	var arrived sync.WaitGroup
+	arrived.Add(uploads)
+	repo := &testFileRepo{afterFind: func(path, fileName string) {
+		arrived.Done()
+		arrived.Wait()
+	}}
+	svc, _, db := newTestFileService(t, repo, project)
+	createTestTables(t, db, &entity.Project{}, &entity.Group{})
+	svc.statRepo = repository.NewStorageStatRepository(db)
+	if err := db.Create(&project.Group).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Omit("Group", "Creator").Create(project).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	errs := make([]error, uploads)
+	var wg sync.WaitGroup
+	for i := 0; i < uploads; i++ {
+		header := newFileHeader(t, fmt.Sprintf("file-%d.txt", i), fmt.Sprintf("%0*d", size, i))
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = svc.Upload(context.Background(), project.ID, "user-1", header, "", "")
+		}(i)
+	}
+	wg.Wait()
+	repo.afterFind = nil
+
+	succeeded := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.Is(err, ErrProjectQuotaExceeded):
+		default:
+			t.Fatalf("上传 %d 返回了意外的错误: %v", i, err)
+		}
+	}
+	if want := int(project.StorageQuota / size); succeeded != want {
+		t.Fatalf("成功上传 %d 个，配额 %d 字节只能容纳 %d 个", succeeded, project.StorageQuota, want)
+	}
+
+	_, used, err := svc.statRepo.GetProjectTotalStats(context.Background(), project.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if used > project.StorageQuota {
+		t.Fatalf("已用 %d 字节，超出配额 %d 字节", used, project.StorageQuota)
+	}
+	var reserved int64
+	db.Model(&entity.Project{}).Where("id = ?", project.ID).Select("storage_reserved").Scan(&reserved)
+	if reserved != 0 {
+		t.Fatalf("上传结束后仍预占 %d 字节", reserved)
+	}
+
+	// 预占释放后，剩余空间仍可使用，超出的部分仍被拒绝
+	if _, err := svc.Upload(context.Background(), project.ID, "user-1", newFileHeader(t, "small.txt", "0123456789"), "", ""); err != nil {
+		t.Fatalf("剩余配额内的上传失败: %v", err)
+	}
+	if _, err := svc.Upload(context.Background(), project.ID, "user-1", newFileHeader(t, "big.txt", "0123456789"), "", ""); !errors.Is(err, ErrProjectQuotaExceeded) {
+		t.Fatalf("超出配额的上传返回 %v，应返回 ErrProjectQuotaExceeded", err)
+	}
+}
+
+// TestProjectQuotaBeforeGroupQuota 项目配额小于群组剩余空间时先达到项目配额，上传配置同时返回两级的剩余空间
+func TestProjectQuotaBeforeGroupQuota(t *testing.T) {
+	ctx := context.Background()
+	project := newTestProject()
+	project.StorageQuota = 100
+	project.Group.StorageQuota = 1000
+	svc, _, db := newTestFileService(t, &testFileRepo{}, project)
+	createTestTables(t, db, &entity.Project{}, &entity.Group{})
+	svc.statRepo = repository.NewStorageStatRepository(db)
+	svc.projectRepo = repository.NewProjectRepository(db)
+	other := &entity.Project{ID: "project-2", GroupID: project.GroupID, Status: entity.ProjectStatusNormal}
+	if err := db.Create(&project.Group).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []*entity.Project{project, other} {
+		if err := db.Omit("Group", "Creator").Create(p).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 同群组的其他项目已占用500字节
+	if err := db.Create(&entity.File{ID: "other-file", ProjectID: other.ID, FileName: "big.bin", FullPath: "big.bin", FileSize: 500, UploaderID: "user-1", CurrentVersion: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := svc.Upload(ctx, project.ID, "user-1", newFileHeader(t, "a.txt", strings.Repeat("a", 60)), "", ""); err != nil {
+		t.Fatalf("项目配额内的上传失败: %v", err)
+	}
+	_, err := svc.Upload(ctx, project.ID, "user-1", newFileHeader(t, "b.txt", strings.Repeat("b", 50)), "", "")
+	if !errors.Is(err, ErrProjectQuotaExceeded) {
+		t.Fatalf("超出项目配额的上传返回 %v，应返回 ErrProjectQuotaExceeded", err)
+	}
+
+	config, err := svc.GetUploadConfig(ctx, project.ID)
+	if err != nil {
+		t.Fatalf("获取上传配置失败: %v", err)
+	}
+	if config.ProjectQuota != (dto.QuotaHeadroom{Quota: 100, Used: 60, Remaining: 40}) {
+		t.Fatalf("项目配额为 %+v，应为配额100、已用60、剩余40", config.ProjectQuota)
+	}
+	if config.GroupQuota != (dto.QuotaHeadroom{Quota: 1000, Used: 560, Remaining: 440}) {
+		t.Fatalf("群组配额为 %+v，应为配额1000、已用560、剩余440", config.GroupQuota)
+	}
+	if config.Remaining != 40 {
+		t.Fatalf("可上传 %d 字节，应为两级中较小的40字节", config.Remaining)
+	}
+
+	// 项目配额放宽后，超出群组剩余空间的上传由群组配额拒绝
+	if err := db.Model(&entity.Project{}).Where("id = ?", project.ID).Update("storage_quota", 800).Error; err != nil {
+		t.Fatal(err)
+	}
+	_, err = svc.Upload(ctx, project.ID, "user-1", newFileHeader(t, "c.txt", strings.Repeat("c", 450)), "", "")
+	if !errors.Is(err, ErrGroupQuotaExceeded) {
+		t.Fatalf("超出群组配额的上传返回 %v，应返回 ErrGroupQuotaExceeded", err)
+	}
+	var reserved int64
+	db.Model(&entity.Project{}).Where("id = ?", project.ID).Select("storage_reserved").Scan(&reserved)
+	if reserved != 0 {
+		t.Fatalf("群组配额拒绝后项目仍预占 %d 字节", reserved)
+	}
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/pkg/report"
+)
+
+// ErrInvalidReportRange 报表日期范围无效
+var ErrInvalidReportRange = errors.New("报表日期范围无效")
+
+// 存储报表默认配置
+const (
+	defaultReportBucket  = "oss-reports"
+	defaultReportMaxDays = 366
+	reportDateLayout     = "2006-01-02"
+)
+
+// 存储报表格式
+const (
+	ReportFormatCSV = "csv"
+	ReportFormatPDF = "pdf"
+)
+
+// storageReportContentTypes 各格式报表的内容类型
+var storageReportContentTypes = map[string]string{
+	ReportFormatCSV: "text/csv; charset=utf-8",
+	ReportFormatPDF: "application/pdf",
+}
+
+// storageReportHeader 存储报表表头
+var storageReportHeader = []string{"日期", "总大小(字节)", "新增大小(字节)", "文件数"}
+
+// ParseStorageReportRange 解析报表日期范围，结束日期包含当天，跨度不能超过 report.max_days
+// 日期按UTC零点解析，与存储统计的 stat_date 取值方式一致
+func ParseStorageReportRange(startDate, endDate string) (time.Time, time.Time, error) {
+	from, err := time.Parse(reportDateLayout, startDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: 开始日期格式错误，应为 %s", ErrInvalidReportRange, reportDateLayout)
+	}
+	to, err := time.Parse(reportDateLayout, endDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: 结束日期格式错误，应为 %s", ErrInvalidReportRange, reportDateLayout)
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: 结束日期不能早于开始日期", ErrInvalidReportRange)
+	}
+	maxDays := viper.GetInt("report.max_days")
+	if maxDays <= 0 {
+		maxDays = defaultReportMaxDays
+	}
+	if days := int(to.Sub(from)/(24*time.Hour)) + 1; days > maxDays {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: 跨度不能超过 %d 天", ErrInvalidReportRange, maxDays)
+	}
+	return from, to, nil
+}
+
+// GenerateStorageReport 生成群组在日期范围内的每日存储报表，format 为 csv（默认）或 pdf，上传到报表存储桶并返回对象名
+// 统计记录只在项目有变更的日期生成，没有记录的日期沿用项目最近一次的总量
+func (s *fileService) GenerateStorageReport(ctx context.Context, groupID, adminID string, from, to time.Time, format string) (string, error) {
+	if format == "" {
+		format = ReportFormatCSV
+	}
+	contentType, ok := storageReportContentTypes[format]
+	if !ok {
+		return "", fmt.Errorf("不支持的报表格式: %s", format)
+	}
+
+	var group entity.Group
+	if err := s.db.WithContext(ctx).Select("id", "name").Where("id = ?", groupID).Limit(1).Find(&group).Error; err != nil {
+		return "", fmt.Errorf("获取群组信息失败: %w", err)
+	}
+	if group.ID == "" {
+		return "", errors.New("群组不存在")
+	}
+
+	// 1. 范围开始前各项目的最后一次统计作为初始总量
+	previous, err := s.statRepo.GetGroupLatestStatsBefore(ctx, groupID, from)
+	if err != nil {
+		return "", fmt.Errorf("获取存储统计失败: %w", err)
+	}
+	stats, err := s.statRepo.GetGroupStatsByDateRange(ctx, groupID, from, to)
+	if err != nil {
+		return "", fmt.Errorf("获取存储统计失败: %w", err)
+	}
+
+	points := buildStorageTrend(previous, stats, from, to)
+
+	// 2. 逐日生成，每天一行
+	totalDays := len(points)
+	rows := make([][]string, 0, totalDays)
+	for i, point := range points {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		ReportJobProgress(ctx, i, totalDays)
+
+		rows = append(rows, []string{
+			point.Date,
+			strconv.FormatInt(point.TotalSize, 10),
+			strconv.FormatInt(point.IncreaseSize, 10),
+			strconv.FormatInt(point.FileCount, 10),
+		})
+	}
+
+	var data []byte
+	switch format {
+	case ReportFormatPDF:
+		title := fmt.Sprintf("%s 存储报表 %s 至 %s", group.Name, from.Format(reportDateLayout), to.Format(reportDateLayout))
+		data, err = report.TablePDF(title, storageReportHeader, rows)
+	default:
+		data, err = storageReportCSV(rows)
+	}
+	if err != nil {
+		return "", fmt.Errorf("生成报表失败: %w", err)
+	}
+	ReportJobProgress(ctx, totalDays, totalDays)
+
+	// 3. 上传报表
+	bucketName := storageReportBucket()
+	if err := s.minioClient.CreateBucketIfNotExists(ctx, bucketName); err != nil {
+		return "", fmt.Errorf("创建报表存储桶失败: %w", err)
+	}
+	objectName := fmt.Sprintf("storage/%s/%s_%s_%d.%s", groupID, from.Format(reportDateLayout), to.Format(reportDateLayout), time.Now().UnixNano(), format)
+	if err := s.minioClient.PutObject(ctx, bucketName, objectName, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		return "", fmt.Errorf("上传报表失败: %w", err)
+	}
+	log.Printf("管理员 %s 生成了群组 %s 的存储报表: %s", adminID, groupID, objectName)
+
+	return objectName, nil
+}
+
+// storageReportCSV 生成CSV报表
+func storageReportCSV(rows [][]string) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("\xEF\xBB\xBF") // UTF-8 BOM，便于表格软件正确识别中文表头
+	w := csv.NewWriter(&buf)
+	if err := w.Write(storageReportHeader); err != nil {
+		return nil, err
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// StorageReportFormat 根据报表对象名判断格式，返回格式和内容类型
+func StorageReportFormat(objectName string) (string, string) {
+	format := ReportFormatCSV
+	if ext := path.Ext(objectName); ext != "" {
+		format = ext[1:]
+	}
+	contentType, ok := storageReportContentTypes[format]
+	if !ok {
+		return ReportFormatCSV, storageReportContentTypes[ReportFormatCSV]
+	}
+	return format, contentType
+}
+
+// OpenStorageReport 打开已生成的存储报表
+func (s *fileService) OpenStorageReport(ctx context.Context, objectName string) (io.ReadCloser, error) {
+	reader, err := s.minioClient.GetObject(ctx, storageReportBucket(), objectName, nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取报表失败: %w", err)
+	}
+	return reader, nil
+}
+
+// storageReportBucket 获取报表存储桶名称
+func storageReportBucket() string {
+	if bucket := viper.GetString("report.bucket"); bucket != "" {
+		return bucket
+	}
+	return defaultReportBucket
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"slices"
+	"testing"
+	"time"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+	"oss-backend/pkg/report"
+)
+
+// TestGenerateStorageReport 按预置的存储统计生成报表，范围内每天一行，没有统计的日期沿用最近一次的总量
+func TestGenerateStorageReport(t *testing.T) {
+	project := newTestProject()
+	svc, store, db := newTestFileService(t, &testFileRepo{}, project)
+	createTestTables(t, db, &entity.Group{}, &entity.StorageStat{})
+	svc.statRepo = repository.NewStorageStatRepository(db)
+
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 0, 0, 0, 0, time.UTC) }
+	records := []interface{}{
+		&entity.Group{ID: "group-1", Name: "team", GroupKey: "team", InviteCode: "invite", CreatorID: "admin", Status: 1},
+		// 范围开始前的统计作为初始总量
+		&entity.StorageStat{ID: "s-1", GroupID: "group-1", ProjectID: "project-1", StatDate: day(1), FileCount: 2, TotalSize: 100, IncreaseSize: 100},
+		&entity.StorageStat{ID: "s-2", GroupID: "group-1", ProjectID: "project-1", StatDate: day(3), FileCount: 3, TotalSize: 150, IncreaseSize: 50},
+		&entity.StorageStat{ID: "s-3", GroupID: "group-1", ProjectID: "project-2", StatDate: day(5), FileCount: 1, TotalSize: 1000, IncreaseSize: 1000},
+		// 其他群组和范围之后的统计不计入
+		&entity.StorageStat{ID: "s-4", GroupID: "group-2", ProjectID: "project-3", StatDate: day(4), FileCount: 9, TotalSize: 9999, IncreaseSize: 9999},
+		&entity.StorageStat{ID: "s-5", GroupID: "group-1", ProjectID: "project-2", StatDate: day(12), FileCount: 5, TotalSize: 5000, IncreaseSize: 4000},
+	}
+	for _, record := range records {
+		if err := db.Create(record).Error; err != nil {
+			t.Fatalf("写入测试数据失败: %v", err)
+		}
+	}
+
+	ctx := context.Background()
+	from, to := day(2), day(10)
+	const days = 9
+
+	objectName, err := svc.GenerateStorageReport(ctx, "group-1", "admin", from, to, ReportFormatCSV)
+	if err != nil {
+		t.Fatalf("生成CSV报表失败: %v", err)
+	}
+	if format, contentType := StorageReportFormat(objectName); format != ReportFormatCSV || contentType != "text/csv; charset=utf-8" {
+		t.Fatalf("报表 %s 的格式为 %s(%s)", objectName, format, contentType)
+	}
+	data, ok := store.object(storageReportBucket(), objectName)
+	if !ok {
+		t.Fatalf("报表没有上传到报表存储桶")
+	}
+	rows, err := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF")))).ReadAll()
+	if err != nil {
+		t.Fatalf("解析CSV报表失败: %v", err)
+	}
+	if len(rows) != days+1 {
+		t.Fatalf("CSV报表有 %d 行，应为表头加 %d 天", len(rows), days)
+	}
+	want := map[string][]string{
+		"2026-01-02": {"2026-01-02", "100", "0", "2"},
+		"2026-01-03": {"2026-01-03", "150", "50", "3"},
+		"2026-01-05": {"2026-01-05", "1150", "1000", "4"},
+		"2026-01-10": {"2026-01-10", "1150", "0", "4"},
+	}
+	for _, row := range rows[1:] {
+		if w, ok := want[row[0]]; ok && !slices.Equal(row, w) {
+			t.Fatalf("%s 的数据为 %v，应为 %v", row[0], row, w)
+		}
+	}
+
+	objectName, err = svc.GenerateStorageReport(ctx, "group-1", "admin", from, to, ReportFormatPDF)
+	if err != nil {
+		t.Fatalf("生成PDF报表失败: %v", err)
+	}
+	if format, contentType := StorageReportFormat(objectName); format != ReportFormatPDF || contentType != "application/pdf" {
+		t.Fatalf("报表 %s 的格式为 %s(%s)", objectName, format, contentType)
+	}
+	data, _ = store.object(storageReportBucket(), objectName)
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		t.Fatalf("PDF报表不是PDF文件")
+	}
+	// PDF报表与相同数据生成的表格一致，每天一行
+	expected, err := report.TablePDF("team 存储报表 2026-01-02 至 2026-01-10", storageReportHeader, rows[1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, expected) {
+		t.Fatalf("PDF报表的内容与CSV报表的 %d 行数据不一致", days)
+	}
+
+	if _, err := svc.GenerateStorageReport(ctx, "group-1", "admin", from, to, "xlsx"); err == nil {
+		t.Fatalf("不支持的格式应返回错误")
+	}
+	if _, err := svc.GenerateStorageReport(ctx, "missing", "admin", from, to, ReportFormatCSV); err == nil {
+		t.Fatalf("群组不存在时应返回错误")
+	}
+}
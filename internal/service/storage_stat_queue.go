@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+)
+
+// 统计队列默认容量
+const defaultStatsQueueSize = 1024
+
+// storageStatDelta 一次存储统计变更
+type storageStatDelta struct {
+	projectID  string
+	countDelta int64 // 文件数变化，覆盖上传新版本时为0
+	sizeDelta  int64 // 大小变化，可为负
+}
+
+// StorageStatQueue 存储统计更新队列
+// 上传、删除、恢复等操作将统计变更投递到队列，由单个后台协程顺序写入；
+// 服务关闭时调用 Shutdown 处理完队列中剩余的变更，避免统计丢失
+type StorageStatQueue struct {
+	statRepo    repository.StorageStatRepository
+	projectRepo repository.ProjectRepository
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan storageStatDelta
+	done   chan struct{}
+}
+
+// NewStorageStatQueue 创建存储统计队列并启动后台协程
+func NewStorageStatQueue(statRepo repository.StorageStatRepository, projectRepo repository.ProjectRepository) *StorageStatQueue {
+	size := viper.GetInt("storage.stats_queue_size")
+	if size <= 0 {
+		size = defaultStatsQueueSize
+	}
+
+	q := &StorageStatQueue{
+		statRepo:    statRepo,
+		projectRepo: projectRepo,
+		queue:       make(chan storageStatDelta, size),
+		done:        make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// Enqueue 投递一次统计变更，队列满时阻塞等待；队列已关闭时同步写入
+func (q *StorageStatQueue) Enqueue(projectID string, countDelta, sizeDelta int64) {
+	if countDelta == 0 && sizeDelta == 0 {
+		return
+	}
+	delta := storageStatDelta{projectID: projectID, countDelta: countDelta, sizeDelta: sizeDelta}
+
+	q.mu.RLock()
+	if !q.closed {
+		q.queue <- delta
+		q.mu.RUnlock()
+		return
+	}
+	q.mu.RUnlock()
+
+	if err := q.apply(context.Background(), delta); err != nil {
+		log.Printf("更新存储统计失败: %v", err)
+	}
+}
+
+// Shutdown 停止接收新变更并等待队列处理完毕
+func (q *StorageStatQueue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.queue)
+	}
+	q.mu.Unlock()
+
+	select {
+	case <-q.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("等待存储统计队列处理超时，剩余 %d 条: %w", len(q.queue), ctx.Err())
+	}
+}
+
+// run 顺序处理队列中的统计变更
+func (q *StorageStatQueue) run() {
+	defer close(q.done)
+	for delta := range q.queue {
+		if err := q.apply(context.Background(), delta); err != nil {
+			log.Printf("更新存储统计失败: %v", err)
+		}
+	}
+}
+
+// apply 将一次变更写入项目当日统计
+func (q *StorageStatQueue) apply(ctx context.Context, delta storageStatDelta) error {
+	return applyStorageStatDelta(ctx, q.statRepo, q.projectRepo, delta.projectID, delta.sizeDelta)
+}
+
+// applyStorageStatDelta 将一次变更写入项目当日统计
+// 变更已落库，文件数和总大小按项目当前的实际总量重新计算，不再累加变更，避免与已计入总量的变更重复；
+// 只有新增量按本次变更累加
+func applyStorageStatDelta(ctx context.Context, statRepo repository.StorageStatRepository, projectRepo repository.ProjectRepository, projectID string, sizeDelta int64) error {
+	project, err := projectRepo.GetByID(ctx, projectID)
+	if err != nil {
+		return fmt.Errorf("获取项目信息失败: %w", err)
+	}
+	if project == nil {
+		return errors.New("项目不存在")
+	}
+
+	fileCount, totalSize, err := statRepo.GetProjectTotalStats(ctx, projectID)
+	if err != nil {
+		return fmt.Errorf("计算项目统计失败: %w", err)
+	}
+
+	// increase_size 表示一段时间内的新增量，只累加正向变化
+	var increase int64
+	if sizeDelta > 0 {
+		increase = sizeDelta
+	}
+
+	stat := &entity.StorageStat{
+		GroupID:      project.GroupID,
+		ProjectID:    projectID,
+		StatDate:     time.Now().Truncate(24 * time.Hour),
+		FileCount:    fileCount,
+		TotalSize:    totalSize,
+		IncreaseSize: increase,
+	}
+	return statRepo.UpsertDailyStat(ctx, stat, increase)
+}
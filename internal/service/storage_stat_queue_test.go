@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+)
+
+// TestConcurrentUploadsStorageStat 当天首批并发上传的统计只计入一次，文件数与实际上传的数量一致
+func TestConcurrentUploadsStorageStat(t *testing.T) {
+	project := newTestProject()
+	svc, _, db := newTestFileService(t, &testFileRepo{}, project)
+	createTestTables(t, db, &entity.StorageStat{})
+	statRepo := repository.NewStorageStatRepository(db)
+	svc.statRepo = statRepo
+	svc.statQueue = NewStorageStatQueue(statRepo, &testProjectRepo{project: project})
+
+	const uploads = 50
+	var totalSize int64
+	errs := make([]error, uploads)
+	var wg sync.WaitGroup
+	for i := 0; i < uploads; i++ {
+		content := fmt.Sprintf("content of file %d", i)
+		totalSize += int64(len(content))
+		header := newFileHeader(t, fmt.Sprintf("file-%d.txt", i), content)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = svc.Upload(context.Background(), project.ID, "user-1", header, "docs/", "")
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("上传 %d 失败: %v", i, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := svc.statQueue.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var stats []entity.StorageStat
+	if err := db.Where("project_id = ?", project.ID).Find(&stats).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 {
+		t.Fatalf("当日统计记录为 %d 条，应为1条", len(stats))
+	}
+	if stats[0].FileCount != uploads {
+		t.Fatalf("文件数为 %d，应为 %d", stats[0].FileCount, uploads)
+	}
+	if stats[0].TotalSize != totalSize || stats[0].IncreaseSize != totalSize {
+		t.Fatalf("总大小为 %d、新增量为 %d，应都为 %d", stats[0].TotalSize, stats[0].IncreaseSize, totalSize)
+	}
+}
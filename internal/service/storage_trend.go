@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"oss-backend/internal/model/dto"
+	"oss-backend/internal/model/entity"
+)
+
+// defaultTrendDays 未指定开始日期时趋势默认覆盖的天数
+const defaultTrendDays = 30
+
+// ParseStorageTrendRange 解析趋势查询的日期范围，结束日期为空时取今天，开始日期为空时取结束日期前30天
+// 跨度限制与存储报表相同
+func ParseStorageTrendRange(from, to string) (time.Time, time.Time, error) {
+	if to == "" {
+		to = time.Now().UTC().Format(reportDateLayout)
+	}
+	if from == "" {
+		end, err := time.Parse(reportDateLayout, to)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: 结束日期格式错误，应为 %s", ErrInvalidReportRange, reportDateLayout)
+		}
+		from = end.AddDate(0, 0, -(defaultTrendDays - 1)).Format(reportDateLayout)
+	}
+	return ParseStorageReportRange(from, to)
+}
+
+// GetGroupStorageTrend 获取群组在日期范围内的每日存储用量，群组成员可查看
+func (s *groupService) GetGroupStorageTrend(ctx context.Context, groupID, userID string, from, to time.Time) (*dto.StorageTrendResponse, error) {
+	if _, err := s.groupRepo.GetGroupByID(ctx, groupID); err != nil {
+		return nil, err
+	}
+	if _, err := s.CheckUserGroupRole(ctx, groupID, userID); err != nil {
+		return nil, err
+	}
+
+	previous, err := s.statRepo.GetGroupLatestStatsBefore(ctx, groupID, from)
+	if err != nil {
+		return nil, fmt.Errorf("获取存储统计失败: %w", err)
+	}
+	stats, err := s.statRepo.GetGroupStatsByDateRange(ctx, groupID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("获取存储统计失败: %w", err)
+	}
+
+	return &dto.StorageTrendResponse{
+		GroupID: groupID,
+		From:    from.Format(reportDateLayout),
+		To:      to.Format(reportDateLayout),
+		Points:  buildStorageTrend(previous, stats, from, to),
+	}, nil
+}
+
+// GetProjectStorageTrend 获取项目在日期范围内的每日存储用量，项目成员与群组成员可查看
+func (s *projectService) GetProjectStorageTrend(ctx context.Context, projectID, userID string, from, to time.Time) (*dto.StorageTrendResponse, error) {
+	hasAccess, err := s.CheckUserProjectAccess(ctx, userID, projectID, []string{ProjectRoleAdmin, ProjectRoleEditor, ProjectRoleViewer})
+	if err != nil {
+		return nil, err
+	}
+	if !hasAccess {
+		project, err := s.projectRepo.GetByID(ctx, projectID)
+		if err != nil {
+			return nil, err
+		}
+		if project == nil {
+			return nil, errors.New("项目不存在")
+		}
+		isGroupMember, err := s.groupRepo.CheckUserInGroup(ctx, project.GroupID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !isGroupMember {
+			return nil, errors.New("没有权限查看该项目")
+		}
+	}
+
+	var previous []*entity.StorageStat
+	last, err := s.statRepo.GetLatestByProjectBefore(ctx, projectID, from)
+	if err != nil {
+		return nil, fmt.Errorf("获取存储统计失败: %w", err)
+	}
+	if last != nil {
+		previous = append(previous, last)
+	}
+	stats, err := s.statRepo.GetByDateRange(ctx, projectID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("获取存储统计失败: %w", err)
+	}
+
+	return &dto.StorageTrendResponse{
+		ProjectID: projectID,
+		From:      from.Format(reportDateLayout),
+		To:        to.Format(reportDateLayout),
+		Points:    buildStorageTrend(previous, stats, from, to),
+	}, nil
+}
+
+// buildStorageTrend 将项目统计逐日汇总为连续的序列，每天一条
+// 统计记录只在项目有变更的日期生成，没有记录的日期沿用项目最近一次的总量，previous 为范围开始前各项目的最后一次统计
+func buildStorageTrend(previous, stats []*entity.StorageStat, from, to time.Time) []dto.StorageTrendPoint {
+	current := make(map[string]*entity.StorageStat, len(previous))
+	for _, stat := range previous {
+		current[stat.ProjectID] = stat
+	}
+	byDay := make(map[int64][]*entity.StorageStat)
+	for _, stat := range stats {
+		day := stat.StatDate.Truncate(24 * time.Hour).Unix()
+		byDay[day] = append(byDay[day], stat)
+	}
+
+	totalDays := int(to.Sub(from)/(24*time.Hour)) + 1
+	points := make([]dto.StorageTrendPoint, 0, totalDays)
+	for i := 0; i < totalDays; i++ {
+		day := from.AddDate(0, 0, i)
+		var increase int64
+		for _, stat := range byDay[day.Unix()] {
+			current[stat.ProjectID] = stat
+			increase += stat.IncreaseSize
+		}
+		var totalSize, fileCount int64
+		for _, stat := range current {
+			totalSize += stat.TotalSize
+			fileCount += stat.FileCount
+		}
+		points = append(points, dto.StorageTrendPoint{
+			Date:         day.Format(reportDateLayout),
+			TotalSize:    totalSize,
+			IncreaseSize: increase,
+			FileCount:    fileCount,
+		})
+	}
+	return points
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TokenBlacklist 令牌黑名单接口，按 jti 记录已注销的令牌
+// 当前提供进程内实现；多实例部署时可替换为基于 Redis 的实现（SET jti EX ttl）
+type TokenBlacklist interface {
+	// Revoke 将令牌加入黑名单，ttl 为令牌剩余有效期
+	Revoke(ctx context.Context, tokenID string, ttl time.Duration) error
+	// RevokeIfAbsent 令牌未被注销时将其加入黑名单并返回 true，已被注销时返回 false，检查与写入是原子的
+	RevokeIfAbsent(ctx context.Context, tokenID string, ttl time.Duration) (bool, error)
+	// IsRevoked 检查令牌是否已被注销
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+}
+
+// memoryTokenBlacklist 进程内令牌黑名单
+type memoryTokenBlacklist struct {
+	mu      sync.Mutex
+	entries map[string]time.Time // jti -> 过期时间
+}
+
+// NewMemoryTokenBlacklist 创建进程内令牌黑名单
+func NewMemoryTokenBlacklist() TokenBlacklist {
+	return &memoryTokenBlacklist{
+		entries: make(map[string]time.Time),
+	}
+}
+
+// Revoke 将令牌加入黑名单
+func (b *memoryTokenBlacklist) Revoke(ctx context.Context, tokenID string, ttl time.Duration) error {
+	if tokenID == "" || ttl <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.purgeExpired(now)
+	b.entries[tokenID] = now.Add(ttl)
+	return nil
+}
+
+// RevokeIfAbsent 在同一把锁内检查并注销令牌，并发使用同一令牌时只有一个调用返回 true
+func (b *memoryTokenBlacklist) RevokeIfAbsent(ctx context.Context, tokenID string, ttl time.Duration) (bool, error) {
+	if tokenID == "" || ttl <= 0 {
+		return false, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.purgeExpired(now)
+	if _, ok := b.entries[tokenID]; ok {
+		return false, nil
+	}
+	b.entries[tokenID] = now.Add(ttl)
+	return true, nil
+}
+
+// purgeExpired 清理已过期的记录，避免无限增长，调用方需持有锁
+func (b *memoryTokenBlacklist) purgeExpired(now time.Time) {
+	for id, expireAt := range b.entries {
+		if now.After(expireAt) {
+			delete(b.entries, id)
+		}
+	}
+}
+
+// IsRevoked 检查令牌是否已被注销
+func (b *memoryTokenBlacklist) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	if tokenID == "" {
+		return false, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	expireAt, ok := b.entries[tokenID]
+	if !ok {
+		return false, nil
+	}
+	if time.Now().After(expireAt) {
+		delete(b.entries, tokenID)
+		return false, nil
+	}
+	return true, nil
+}
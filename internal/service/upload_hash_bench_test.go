@@ -0,0 +1,73 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"oss-backend/internal/model/entity"
+)
+
+// countingReader 统计从源读取的字节数
+type countingReader struct {
+	r *bytes.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) Seek(offset int64, whence int) (int64, error) {
+	return c.r.Seek(offset, whence)
+}
+
+// BenchmarkUploadHash 对比先完整读取计算哈希再上传与上传时同步计算哈希，read-bytes/op 为从上传文件读取的字节数
+func BenchmarkUploadHash(b *testing.B) {
+	const size = 4 << 20
+	data := make([]byte, size)
+	rand.Read(data)
+
+	store, client := newFakeObjectStore(b)
+	svc := &fileService{minioClient: client}
+	bucket := "bench"
+	store.buckets[bucket] = true
+	group := &entity.Group{}
+	ctx := context.Background()
+
+	b.Run("hash-then-upload", func(b *testing.B) {
+		b.SetBytes(size)
+		var read int64
+		for i := 0; i < b.N; i++ {
+			src := &countingReader{r: bytes.NewReader(data)}
+			if _, err := calculateFileHash(src); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := src.Seek(0, io.SeekStart); err != nil {
+				b.Fatal(err)
+			}
+			if _, _, err := svc.uploadWithHash(ctx, client, bucket, "object", group, src, size, "application/octet-stream"); err != nil {
+				b.Fatal(err)
+			}
+			read += src.n
+		}
+		b.ReportMetric(float64(read)/float64(b.N), "read-bytes/op")
+	})
+
+	b.Run("single-pass", func(b *testing.B) {
+		b.SetBytes(size)
+		var read int64
+		for i := 0; i < b.N; i++ {
+			src := &countingReader{r: bytes.NewReader(data)}
+			if _, _, err := svc.uploadWithHash(ctx, client, bucket, "object", group, src, size, "application/octet-stream"); err != nil {
+				b.Fatal(err)
+			}
+			read += src.n
+		}
+		b.ReportMetric(float64(read)/float64(b.N), "read-bytes/op")
+	})
+}
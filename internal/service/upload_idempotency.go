@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"oss-backend/internal/model/entity"
+)
+
+// defaultIdempotencyTTL 幂等键默认保留时间
+const defaultIdempotencyTTL = 24 * time.Hour
+
+// maxIdempotencyKeyLength 幂等键的最大长度
+const maxIdempotencyKeyLength = 255
+
+// ErrInvalidIdempotencyKey 幂等键格式错误
+var ErrInvalidIdempotencyKey = errors.New("Idempotency-Key 格式错误")
+
+// ErrIdempotencyInProgress 使用同一幂等键的请求正在处理
+var ErrIdempotencyInProgress = errors.New("使用该 Idempotency-Key 的上传正在处理，请稍后重试")
+
+// ErrIdempotencyKeyReused 幂等键已用于参数不同的请求
+var ErrIdempotencyKeyReused = errors.New("该 Idempotency-Key 已用于其他上传请求")
+
+// IdempotencyStore 记录幂等键及其结果，键按用户隔离
+// 当前提供进程内实现；多实例部署时可替换为基于 Redis 的实现（SET key NX EX ttl 占用，完成后写入结果）
+type IdempotencyStore interface {
+	// Reserve 占用幂等键；键已完成时返回记录的结果ID，正在处理时返回 ErrIdempotencyInProgress，
+	// fingerprint 与首次请求不同时返回 ErrIdempotencyKeyReused
+	Reserve(ctx context.Context, userID, key, fingerprint string) (string, error)
+	// Complete 记录幂等键的结果ID，在保留时间内重放时返回
+	Complete(ctx context.Context, userID, key, resultID string) error
+	// Release 请求失败时释放幂等键，之后可用同一键重试
+	Release(ctx context.Context, userID, key string) error
+}
+
+// idempotencyEntry 一个幂等键的状态
+type idempotencyEntry struct {
+	fingerprint string
+	resultID    string // 为空表示正在处理
+	expireAt    time.Time
+}
+
+// memoryIdempotencyStore 进程内幂等键存储
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*idempotencyEntry // 用户ID + 幂等键 -> 状态
+}
+
+// NewMemoryIdempotencyStore 创建进程内幂等键存储，保留时间由 storage.idempotency_ttl_seconds 配置
+func NewMemoryIdempotencyStore() IdempotencyStore {
+	ttl := defaultIdempotencyTTL
+	if seconds := viper.GetInt("storage.idempotency_ttl_seconds"); seconds > 0 {
+		ttl = time.Duration(seconds) * time.Second
+	}
+	return &memoryIdempotencyStore{
+		ttl:     ttl,
+		entries: make(map[string]*idempotencyEntry),
+	}
+}
+
+// Reserve 占用幂等键
+func (m *memoryIdempotencyStore) Reserve(ctx context.Context, userID, key, fingerprint string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// 顺带清理已过期的记录，避免无限增长
+	now := time.Now()
+	for k, entry := range m.entries {
+		if now.After(entry.expireAt) {
+			delete(m.entries, k)
+		}
+	}
+
+	scoped := userID + "\x00" + key
+	if entry, ok := m.entries[scoped]; ok {
+		if entry.fingerprint != fingerprint {
+			return "", ErrIdempotencyKeyReused
+		}
+		if entry.resultID == "" {
+			return "", ErrIdempotencyInProgress
+		}
+		return entry.resultID, nil
+	}
+	m.entries[scoped] = &idempotencyEntry{fingerprint: fingerprint, expireAt: now.Add(m.ttl)}
+	return "", nil
+}
+
+// Complete 记录幂等键的结果
+func (m *memoryIdempotencyStore) Complete(ctx context.Context, userID, key, resultID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if entry, ok := m.entries[userID+"\x00"+key]; ok {
+		entry.resultID = resultID
+		entry.expireAt = time.Now().Add(m.ttl)
+	}
+	return nil
+}
+
+// Release 释放幂等键
+func (m *memoryIdempotencyStore) Release(ctx context.Context, userID, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.entries, userID+"\x00"+key)
+	return nil
+}
+
+// UploadIdempotent 带幂等键的上传，idempotencyKey 为空时等同于 Upload
+// 同一用户在保留时间内用同一幂等键重放相同的上传时，不再写入存储和创建记录，直接返回首次上传的文件
+func (s *fileService) UploadIdempotent(ctx context.Context, projectID, uploaderID, idempotencyKey string, file *multipart.FileHeader, path, declaredHash string) (*entity.File, error) {
+	if idempotencyKey == "" || s.idempotency == nil {
+		return s.Upload(ctx, projectID, uploaderID, file, path, declaredHash)
+	}
+	if !validIdempotencyKey(idempotencyKey) {
+		return nil, fmt.Errorf("%w: 应为1到%d个可打印ASCII字符", ErrInvalidIdempotencyKey, maxIdempotencyKeyLength)
+	}
+
+	// 同一幂等键只能用于同一项目、目录、文件名和大小的上传
+	fingerprint := fmt.Sprintf("%s\x00%s\x00%s\x00%d", projectID, path, filepath.Base(file.Filename), file.Size)
+	fileID, err := s.idempotency.Reserve(ctx, uploaderID, idempotencyKey, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	if fileID != "" {
+		existing, err := s.fileRepo.GetByID(ctx, fileID)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return existing, nil
+		}
+		return nil, errors.New("首次上传的文件已不存在")
+	}
+
+	uploaded, err := s.Upload(ctx, projectID, uploaderID, file, path, declaredHash)
+	if err != nil {
+		if relErr := s.idempotency.Release(ctx, uploaderID, idempotencyKey); relErr != nil {
+			return nil, errors.Join(err, relErr)
+		}
+		return nil, err
+	}
+	// 上传已完成，记录结果失败只影响之后的重放
+	if err := s.idempotency.Complete(ctx, uploaderID, idempotencyKey, uploaded.ID); err != nil {
+		log.Printf("记录幂等键结果失败: %v", err)
+	}
+	return uploaded, nil
+}
+
+// validIdempotencyKey 幂等键只接受长度有限的可打印ASCII字符
+func validIdempotencyKey(key string) bool {
+	if len(key) > maxIdempotencyKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x21 || key[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
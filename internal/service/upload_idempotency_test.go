@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+)
+
+// TestUploadIdempotentReplay 同一用户用同一幂等键重放上传时返回首次上传的文件，只有一条文件记录和一次统计变更；
+// 同一幂等键用于不同的文件被拒绝，其他用户的同名幂等键互不影响
+func TestUploadIdempotentReplay(t *testing.T) {
+	ctx := context.Background()
+	project := newTestProject()
+	svc, store, db := newTestFileService(t, nil, project)
+	svc.fileRepo = repository.NewFileRepository(db)
+	svc.idempotency = NewMemoryIdempotencyStore()
+
+	first, err := svc.UploadIdempotent(ctx, project.ID, "user-1", "key-1", newFileHeader(t, "a.txt", "content"), "", "")
+	if err != nil {
+		t.Fatalf("首次上传失败: %v", err)
+	}
+	replayed, err := svc.UploadIdempotent(ctx, project.ID, "user-1", "key-1", newFileHeader(t, "a.txt", "content"), "", "")
+	if err != nil {
+		t.Fatalf("重放上传失败: %v", err)
+	}
+	if replayed.ID != first.ID || replayed.CurrentVersion != first.CurrentVersion {
+		t.Fatalf("重放返回文件 %s 版本 %d，应为首次上传的 %s 版本 %d", replayed.ID, replayed.CurrentVersion, first.ID, first.CurrentVersion)
+	}
+
+	var files, versions int64
+	db.Model(&entity.File{}).Count(&files)
+	db.Model(&entity.FileVersion{}).Count(&versions)
+	if files != 1 || versions != 1 {
+		t.Fatalf("重放后有 %d 条文件记录、%d 条版本记录，应各为1条", files, versions)
+	}
+	if n := len(svc.statQueue.queue); n != 1 {
+		t.Fatalf("重放后有 %d 次统计变更，应为1次", n)
+	}
+	delta := <-svc.statQueue.queue
+	if delta.countDelta != 1 || delta.sizeDelta != int64(len("content")) {
+		t.Fatalf("统计变更为 %+v，应为1个文件、7字节", delta)
+	}
+	if keys := store.keys(groupBucketName(project.Group.GroupKey), ""); len(keys) != 1 {
+		t.Fatalf("存储中有 %d 个对象，应为1个: %v", len(keys), keys)
+	}
+
+	// 同一幂等键不能用于不同的上传
+	if _, err := svc.UploadIdempotent(ctx, project.ID, "user-1", "key-1", newFileHeader(t, "b.txt", "other"), "", ""); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Fatalf("同一幂等键上传其他文件返回 %v，应返回 ErrIdempotencyKeyReused", err)
+	}
+
+	// 幂等键按用户隔离，其他用户使用相同的键时正常上传
+	other, err := svc.UploadIdempotent(ctx, project.ID, "user-2", "key-1", newFileHeader(t, "b.txt", "other"), "", "")
+	if err != nil {
+		t.Fatalf("其他用户使用相同幂等键上传失败: %v", err)
+	}
+	if other.ID == first.ID {
+		t.Fatalf("其他用户的上传返回了 user-1 的文件")
+	}
+	db.Model(&entity.File{}).Count(&files)
+	if files != 2 {
+		t.Fatalf("其他用户上传后有 %d 条文件记录，应为2条", files)
+	}
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	"oss-backend/internal/model/dto"
+	"oss-backend/internal/model/entity"
+)
+
+// ErrUploadPolicyViolation 文件不符合上传策略
+var ErrUploadPolicyViolation = errors.New("文件不符合上传策略")
+
+// sniffLen 内容类型检测读取的字节数，与 http.DetectContentType 一致
+const sniffLen = 512
+
+// loadUploadPolicy 读取全局上传策略
+func loadUploadPolicy() dto.UploadPolicy {
+	return dto.UploadPolicy{
+		MaxFileSize:         viper.GetInt64("upload_policy.max_file_size"),
+		AllowedExtensions:   viper.GetStringSlice("upload_policy.allowed_extensions"),
+		BlockedExtensions:   viper.GetStringSlice("upload_policy.blocked_extensions"),
+		AllowedMimePrefixes: viper.GetStringSlice("upload_policy.allowed_mime_prefixes"),
+	}
+}
+
+// resolveUploadPolicy 合并全局策略与项目覆盖配置
+// 项目配置中 max_file_size 为0或列表为 null 的字段沿用全局配置，空列表表示不限制
+func resolveUploadPolicy(project *entity.Project) dto.UploadPolicy {
+	policy := loadUploadPolicy()
+
+	override := decodeUploadPolicy(project.UploadPolicy)
+	if override == nil {
+		return policy
+	}
+	if override.MaxFileSize > 0 {
+		policy.MaxFileSize = override.MaxFileSize
+	}
+	if override.AllowedExtensions != nil {
+		policy.AllowedExtensions = override.AllowedExtensions
+	}
+	if override.BlockedExtensions != nil {
+		policy.BlockedExtensions = override.BlockedExtensions
+	}
+	if override.AllowedMimePrefixes != nil {
+		policy.AllowedMimePrefixes = override.AllowedMimePrefixes
+	}
+	return policy
+}
+
+// encodeUploadPolicy 校验并序列化项目上传策略，全部字段未设置时返回空字符串表示不覆盖
+func encodeUploadPolicy(policy *dto.UploadPolicy) (string, error) {
+	if policy == nil {
+		return "", nil
+	}
+	if policy.MaxFileSize < 0 {
+		return "", errors.New("上传文件大小限制不能为负数")
+	}
+
+	normalized := dto.UploadPolicy{MaxFileSize: policy.MaxFileSize}
+	if policy.AllowedExtensions != nil {
+		normalized.AllowedExtensions = normalizeExtensions(policy.AllowedExtensions)
+	}
+	if policy.BlockedExtensions != nil {
+		normalized.BlockedExtensions = normalizeExtensions(policy.BlockedExtensions)
+	}
+	if policy.AllowedMimePrefixes != nil {
+		normalized.AllowedMimePrefixes = make([]string, 0, len(policy.AllowedMimePrefixes))
+		for _, prefix := range policy.AllowedMimePrefixes {
+			if prefix = strings.ToLower(strings.TrimSpace(prefix)); prefix != "" {
+				normalized.AllowedMimePrefixes = append(normalized.AllowedMimePrefixes, prefix)
+			}
+		}
+	}
+
+	if normalized.MaxFileSize == 0 && normalized.AllowedExtensions == nil &&
+		normalized.BlockedExtensions == nil && normalized.AllowedMimePrefixes == nil {
+		return "", nil
+	}
+
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return "", fmt.Errorf("序列化上传策略失败: %w", err)
+	}
+	return string(data), nil
+}
+
+// decodeUploadPolicy 解析项目上传策略，未设置或格式错误时返回nil
+func decodeUploadPolicy(raw string) *dto.UploadPolicy {
+	if raw == "" {
+		return nil
+	}
+	var policy dto.UploadPolicy
+	if err := json.Unmarshal([]byte(raw), &policy); err != nil {
+		return nil
+	}
+	return &policy
+}
+
+// checkUploadFileName 按扩展名和大小校验文件，禁止列表优先于允许列表
+func checkUploadFileName(policy dto.UploadPolicy, fileName string, size int64) error {
+	if policy.MaxFileSize > 0 && size > policy.MaxFileSize {
+		return fmt.Errorf("%w: 文件大小超过限制(%d字节)", ErrUploadPolicyViolation, policy.MaxFileSize)
+	}
+
+	ext := strings.ToLower(filepath.Ext(fileName))
+	for _, blocked := range normalizeExtensions(policy.BlockedExtensions) {
+		if ext == blocked {
+			return fmt.Errorf("%w: 禁止上传 %s 类型的文件", ErrUploadPolicyViolation, ext)
+		}
+	}
+
+	allowed := normalizeExtensions(policy.AllowedExtensions)
+	if len(allowed) == 0 {
+		return nil
+	}
+	for _, a := range allowed {
+		if ext == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: 不允许上传 %s 类型的文件", ErrUploadPolicyViolation, displayExtension(ext))
+}
+
+// checkUploadContentType 校验声明的内容类型与实际检测到的内容类型
+// 声明类型为空或为通用二进制类型时只校验检测结果
+func checkUploadContentType(policy dto.UploadPolicy, declaredType, sniffedType string) error {
+	if len(policy.AllowedMimePrefixes) == 0 {
+		return nil
+	}
+
+	declared := baseMimeType(declaredType)
+	if declared != "" && declared != "application/octet-stream" && !matchMimePrefix(policy.AllowedMimePrefixes, declared) {
+		return fmt.Errorf("%w: 不允许上传 %s 类型的内容", ErrUploadPolicyViolation, declared)
+	}
+
+	sniffed := baseMimeType(sniffedType)
+	if !matchMimePrefix(policy.AllowedMimePrefixes, sniffed) {
+		return fmt.Errorf("%w: 文件实际内容类型 %s 不被允许", ErrUploadPolicyViolation, sniffed)
+	}
+	return nil
+}
+
+// sniffContentType 读取文件开头检测内容类型，检测后将读取位置重置到开头
+func sniffContentType(src io.ReadSeeker) (string, error) {
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(src, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return http.DetectContentType(buf[:n]), nil
+}
+
+// normalizeExtensions 统一扩展名为小写并以点开头
+func normalizeExtensions(exts []string) []string {
+	result := make([]string, 0, len(exts))
+	for _, ext := range exts {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		result = append(result, ext)
+	}
+	return result
+}
+
+// matchMimePrefix 判断内容类型是否匹配任一允许的前缀
+func matchMimePrefix(prefixes []string, mimeType string) bool {
+	for _, prefix := range prefixes {
+		if prefix = strings.ToLower(strings.TrimSpace(prefix)); prefix != "" && strings.HasPrefix(mimeType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// baseMimeType 去掉内容类型中的参数部分，如 "text/plain; charset=utf-8" -> "text/plain"
+func baseMimeType(mimeType string) string {
+	if i := strings.Index(mimeType, ";"); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(mimeType))
+}
+
+// displayExtension 无扩展名时的提示文本
+func displayExtension(ext string) string {
+	if ext == "" {
+		return "无扩展名"
+	}
+	return ext
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+
+	"oss-backend/internal/model/entity"
+)
+
+// TestUploadPolicyRejectsBeforeStorage 被禁止的扩展名、超过大小限制和实际内容类型不被允许的文件在写入存储前被拒绝
+func TestUploadPolicyRejectsBeforeStorage(t *testing.T) {
+	viper.Set("upload_policy.max_file_size", 1024)
+	viper.Set("upload_policy.blocked_extensions", []string{"exe", ".BAT"})
+	t.Cleanup(func() {
+		viper.Set("upload_policy.max_file_size", 0)
+		viper.Set("upload_policy.blocked_extensions", nil)
+	})
+
+	project := newTestProject()
+	project.UploadPolicy = `{"allowed_mime_prefixes":["text/"]}`
+	svc, store, db := newTestFileService(t, &testFileRepo{}, project)
+	bucket := groupBucketName(project.Group.GroupKey)
+	// 存储桶尚未创建，被拒绝的上传不应创建存储桶
+	delete(store.buckets, bucket)
+
+	tests := []struct {
+		name     string
+		fileName string
+		content  string
+	}{
+		{"可执行文件", "setup.exe", "MZ\x90\x00"},
+		{"大小写不同的扩展名", "run.Bat", "echo hi"},
+		{"超过大小限制", "big.txt", strings.Repeat("a", 1025)},
+		{"扩展名伪装的可执行文件", "notes.txt", "MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xff\xff"},
+	}
+	for _, tt := range tests {
+		_, err := svc.Upload(context.Background(), project.ID, "user-1", newFileHeader(t, tt.fileName, tt.content), "", "")
+		if !errors.Is(err, ErrUploadPolicyViolation) {
+			t.Errorf("%s: 返回 %v，应返回 ErrUploadPolicyViolation", tt.name, err)
+		}
+	}
+
+	store.mu.Lock()
+	created, objects := store.buckets[bucket], len(store.objects)
+	store.mu.Unlock()
+	if created || objects != 0 {
+		t.Fatalf("被拒绝的上传访问了存储：存储桶已创建=%v，对象数=%d", created, objects)
+	}
+	var count int64
+	db.Model(&entity.File{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("被拒绝的上传创建了 %d 条文件记录", count)
+	}
+
+	// 不超过限制的文本文件可以上传
+	if _, err := svc.Upload(context.Background(), project.ID, "user-1", newFileHeader(t, "ok.txt", strings.Repeat("a", 1024)), "", ""); err != nil {
+		t.Fatalf("符合策略的文件上传失败: %v", err)
+	}
+}
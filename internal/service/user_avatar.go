@@ -0,0 +1,159 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+
+	"oss-backend/internal/utils"
+)
+
+// ErrInvalidAvatar 上传的头像不是支持的图片或超过大小上限
+var ErrInvalidAvatar = errors.New("头像无效")
+
+// ErrAvatarNotFound 头像不存在或已被替换
+var ErrAvatarNotFound = errors.New("头像不存在")
+
+const (
+	defaultAvatarBucket  = "oss-avatars"
+	defaultAvatarMaxSize = 2 << 20
+	// avatarURLPrefix 上传的头像保存在 User.Avatar 中的URL前缀，后接对象名称 <用户ID>/<随机名称>
+	avatarURLPrefix = "/api/oss/user/avatar/"
+)
+
+// avatarExtensions 支持的头像格式及对象名称的扩展名
+var avatarExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// avatarBucket 获取头像存储桶名称
+func avatarBucket() string {
+	bucket := viper.GetString("avatar.bucket")
+	if bucket == "" {
+		bucket = defaultAvatarBucket
+	}
+	return bucket
+}
+
+// avatarMaxSize 获取头像大小上限（字节），默认2MB
+func avatarMaxSize() int64 {
+	size := viper.GetInt64("avatar.max_size")
+	if size <= 0 {
+		size = defaultAvatarMaxSize
+	}
+	return size
+}
+
+// avatarObjectName 从上传头像的URL中解析对象名称，不是上传的头像时返回空
+func avatarObjectName(avatar string) string {
+	if !strings.HasPrefix(avatar, avatarURLPrefix) {
+		return ""
+	}
+	return strings.TrimPrefix(avatar, avatarURLPrefix)
+}
+
+// UploadAvatar 上传头像图片并设为用户头像，返回头像URL
+// 图片按内容识别格式，支持 JPEG、PNG、GIF 和 WebP；每次上传使用新的对象名称，替换后删除原头像对象
+func (s *userService) UploadAvatar(ctx context.Context, userID string, file *multipart.FileHeader) (string, error) {
+	if s.minioClient == nil {
+		return "", errors.New("未配置对象存储")
+	}
+	maxSize := avatarMaxSize()
+	if file.Size > maxSize {
+		return "", fmt.Errorf("%w: 头像不能超过 %d 字节", ErrInvalidAvatar, maxSize)
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return "", fmt.Errorf("打开上传文件失败: %w", err)
+	}
+	defer src.Close()
+	data, err := io.ReadAll(io.LimitReader(src, maxSize+1))
+	if err != nil {
+		return "", fmt.Errorf("读取上传文件失败: %w", err)
+	}
+	if int64(len(data)) > maxSize {
+		return "", fmt.Errorf("%w: 头像不能超过 %d 字节", ErrInvalidAvatar, maxSize)
+	}
+	contentType := http.DetectContentType(data)
+	ext, ok := avatarExtensions[contentType]
+	if !ok {
+		return "", fmt.Errorf("%w: 只支持 JPEG、PNG、GIF 和 WebP 图片", ErrInvalidAvatar)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("获取用户信息失败: %w", err)
+	}
+
+	bucket := avatarBucket()
+	objectName := fmt.Sprintf("%s/%s%s", userID, utils.GenerateRecordID(), ext)
+	if _, err := s.minioClient.UploadFile(ctx, bucket, objectName, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		return "", fmt.Errorf("保存头像失败: %w", err)
+	}
+
+	previous := user.Avatar
+	user.Avatar = avatarURLPrefix + objectName
+	user.UpdatedAt = time.Now()
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		s.removeAvatarObject(ctx, userID, user.Avatar)
+		return "", fmt.Errorf("更新用户头像失败: %w", err)
+	}
+	s.removeAvatarObject(ctx, userID, previous)
+	return user.Avatar, nil
+}
+
+// OpenAvatar 读取用户当前的头像，返回内容、大小和内容类型；name 与用户当前头像不一致时返回 ErrAvatarNotFound
+func (s *userService) OpenAvatar(ctx context.Context, userID, name string) (io.ReadCloser, int64, string, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, 0, "", ErrAvatarNotFound
+		}
+		return nil, 0, "", fmt.Errorf("获取用户信息失败: %w", err)
+	}
+	if user.DeletedAt != nil {
+		return nil, 0, "", ErrAvatarNotFound
+	}
+	objectName := userID + "/" + name
+	if avatarObjectName(user.Avatar) != objectName {
+		return nil, 0, "", ErrAvatarNotFound
+	}
+
+	contentType := "application/octet-stream"
+	for t, ext := range avatarExtensions {
+		if strings.HasSuffix(name, ext) {
+			contentType = t
+			break
+		}
+	}
+	reader, size, err := s.minioClient.DownloadFile(ctx, avatarBucket(), objectName)
+	if err != nil {
+		return nil, 0, "", fmt.Errorf("读取头像失败: %w", err)
+	}
+	return reader, size, contentType, nil
+}
+
+// removeAvatarObject 删除用户上传的头像对象，外部头像URL和不属于该用户的对象不处理；失败只记录日志
+func (s *userService) removeAvatarObject(ctx context.Context, userID, avatar string) {
+	objectName := avatarObjectName(avatar)
+	if !strings.HasPrefix(objectName, userID+"/") || s.minioClient == nil {
+		return
+	}
+	if err := s.minioClient.DeleteFile(ctx, avatarBucket(), objectName); err != nil {
+		log.Printf("删除头像对象 %s 失败: %v", objectName, err)
+	}
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"oss-backend/internal/model/dto"
+)
+
+// GetUserPermissions 汇总用户的系统角色、群组角色、项目角色和直接授予的权限策略
+// 角色同时取自成员关系表和 Casbin 各个域中的角色关联，只出现在其中一处的群组或项目也会列出；已删除的群组和项目不列出
+func (s *authService) GetUserPermissions(ctx context.Context, userID string) (*dto.UserPermissionsResponse, error) {
+	sub := fmt.Sprintf("user:%s", userID)
+
+	// Casbin 中的角色关联，按域归类
+	groupingRules, err := s.enforcer.GetFilteredGroupingPolicy(0, sub)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户角色关联失败: %w", err)
+	}
+	systemRoles := make(map[string]bool)
+	domainRoles := make(map[string][]string)
+	for _, rule := range groupingRules {
+		if len(rule) < 3 {
+			continue
+		}
+		role, domain := rule[1], rule[2]
+		if strings.HasPrefix(domain, "group:") || strings.HasPrefix(domain, "project:") {
+			domainRoles[domain] = append(domainRoles[domain], role)
+		} else {
+			systemRoles[role] = true
+		}
+	}
+
+	dbRoles, err := s.userRepo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户角色失败: %w", err)
+	}
+	for _, role := range dbRoles {
+		systemRoles[role.Code] = true
+	}
+
+	groups, err := s.userGroupPermissions(ctx, userID, domainRoles)
+	if err != nil {
+		return nil, err
+	}
+	projects, err := s.userProjectPermissions(ctx, userID, domainRoles)
+	if err != nil {
+		return nil, err
+	}
+
+	policies, err := s.ListPolicies(ctx, sub, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.UserPermissionsResponse{
+		SystemRoles: sortedKeys(systemRoles),
+		Groups:      groups,
+		Projects:    projects,
+		Policies:    policies,
+	}, nil
+}
+
+// userGroupPermissions 获取用户所在的群组及在 Casbin 群组域中有角色的群组
+func (s *authService) userGroupPermissions(ctx context.Context, userID string, domainRoles map[string][]string) ([]dto.GroupPermissionItem, error) {
+	var rows []struct {
+		ID   string
+		Name string
+		Role string
+	}
+	err := s.db.WithContext(ctx).Table("groups").
+		Select("groups.id, groups.name, group_members.role").
+		Joins("LEFT JOIN group_members ON group_members.group_id = groups.id AND group_members.user_id = ?", userID).
+		Where("groups.deleted_at IS NULL").
+		Where("group_members.id IS NOT NULL OR groups.id IN ?", domainIDs(domainRoles, "group:")).
+		Order("groups.name").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("获取用户群组失败: %w", err)
+	}
+
+	items := make([]dto.GroupPermissionItem, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, dto.GroupPermissionItem{
+			GroupID:   row.ID,
+			GroupName: row.Name,
+			Role:      row.Role,
+			Roles:     nonNilRoles(domainRoles["group:"+row.ID]),
+		})
+	}
+	return items, nil
+}
+
+// userProjectPermissions 获取用户参与的项目及在 Casbin 项目域中有角色或权限的项目，并计算对项目文件可执行的操作
+func (s *authService) userProjectPermissions(ctx context.Context, userID string, domainRoles map[string][]string) ([]dto.ProjectPermissionItem, error) {
+	// 直接授予的文件权限也是项目访问途径，一并纳入
+	projectDomains := make(map[string][]string, len(domainRoles))
+	for domain, roles := range domainRoles {
+		projectDomains[domain] = roles
+	}
+	policies, err := s.enforcer.GetFilteredPolicy(0, fmt.Sprintf("user:%s", userID))
+	if err != nil {
+		return nil, fmt.Errorf("获取用户权限策略失败: %w", err)
+	}
+	for _, rule := range policies {
+		if len(rule) >= 2 {
+			if _, ok := projectDomains[rule[1]]; !ok {
+				projectDomains[rule[1]] = nil
+			}
+		}
+	}
+
+	var rows []struct {
+		ID      string
+		Name    string
+		GroupID string
+		Role    string
+	}
+	err = s.db.WithContext(ctx).Table("projects").
+		Select("projects.id, projects.name, projects.group_id, project_members.role").
+		Joins("LEFT JOIN project_members ON project_members.project_id = projects.id AND project_members.user_id = ?", userID).
+		Where("projects.deleted_at IS NULL").
+		Where("project_members.id IS NOT NULL OR projects.id IN ?", domainIDs(projectDomains, "project:")).
+		Order("projects.name").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("获取用户项目失败: %w", err)
+	}
+
+	actions := []string{ActionRead, ActionCreate, ActionUpdate, ActionDelete}
+	items := make([]dto.ProjectPermissionItem, 0, len(rows))
+	for _, row := range rows {
+		domain := "project:" + row.ID
+		fileActions := make([]string, 0, len(actions))
+		for _, action := range actions {
+			allowed, err := s.CanUserAccessResource(ctx, userID, ResourceFile, action, domain)
+			if err != nil {
+				return nil, fmt.Errorf("检查项目文件权限失败: %w", err)
+			}
+			if allowed {
+				fileActions = append(fileActions, action)
+			}
+		}
+		items = append(items, dto.ProjectPermissionItem{
+			ProjectID:   row.ID,
+			ProjectName: row.Name,
+			GroupID:     row.GroupID,
+			Role:        row.Role,
+			Roles:       nonNilRoles(domainRoles[domain]),
+			FileActions: fileActions,
+		})
+	}
+	return items, nil
+}
+
+// domainIDs 取出指定前缀的域中的ID；没有时返回空字符串占位，避免 IN 条件为空
+func domainIDs(domains map[string][]string, prefix string) []string {
+	ids := []string{""}
+	for domain := range domains {
+		if id := strings.TrimPrefix(domain, prefix); id != domain && id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// nonNilRoles 排序角色列表，没有角色时返回空列表而不是 null
+func nonNilRoles(roles []string) []string {
+	if roles == nil {
+		return []string{}
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// sortedKeys 返回集合中的元素，按字典序排列
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
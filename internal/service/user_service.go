@@ -2,20 +2,144 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/spf13/viper"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 
 	"oss-backend/internal/model/dto"
 	"oss-backend/internal/model/entity"
 	"oss-backend/internal/repository"
+	"oss-backend/internal/utils"
+	"oss-backend/pkg/minio"
 )
 
-// 定义JWT密钥
-var jwtSecret = []byte("oss-backend-secret-key")
+// refreshTokenSubjectSuffix 刷新令牌Subject后缀，用于区分访问令牌
+const refreshTokenSubjectSuffix = ":refresh"
+
+// emailVerifySubjectSuffix 邮箱验证令牌Subject后缀
+const emailVerifySubjectSuffix = ":verify_email"
+
+// ErrEmailNotVerified 邮箱尚未验证
+var ErrEmailNotVerified = errors.New("邮箱尚未验证，请先完成邮箱验证")
+
+// IsEmailVerificationSubject 判断令牌Subject是否属于邮箱验证令牌
+func IsEmailVerificationSubject(subject string) bool {
+	return strings.HasSuffix(subject, emailVerifySubjectSuffix)
+}
+
+// IsRefreshTokenSubject 判断令牌Subject是否属于刷新令牌
+func IsRefreshTokenSubject(subject string) bool {
+	return strings.HasSuffix(subject, refreshTokenSubjectSuffix)
+}
+
+// minJWTSecretLength JWT签名密钥的最小长度（字节）
+const minJWTSecretLength = 32
+
+// ErrJWTSecretRequired 生产环境未配置JWT签名密钥
+var ErrJWTSecretRequired = errors.New("生产环境必须配置JWT签名密钥 auth.jwt_secret")
+
+// 开发环境未配置JWT签名密钥时，进程内随机生成一次并复用
+var (
+	generatedJWTSecret     []byte
+	generatedJWTSecretOnce sync.Once
+)
+
+// LoadJWTSecret 从配置读取JWT签名密钥
+// 生产环境未配置时返回空，由 ValidateJWTSecret 拒绝启动；开发环境未配置时使用本进程随机生成的密钥，重启后已签发的令牌全部失效
+func LoadJWTSecret() []byte {
+	if secret := viper.GetString("auth.jwt_secret"); secret != "" {
+		return []byte(secret)
+	}
+	if viper.GetString("server.mode") == "production" {
+		return nil
+	}
+
+	generatedJWTSecretOnce.Do(func() {
+		b := make([]byte, minJWTSecretLength)
+		if _, err := rand.Read(b); err != nil {
+			log.Printf("生成JWT签名密钥失败: %v", err)
+			return
+		}
+		generatedJWTSecret = b
+		log.Printf("未配置 auth.jwt_secret，使用随机生成的JWT签名密钥，服务重启后需重新登录")
+	})
+	return generatedJWTSecret
+}
+
+// ValidateJWTSecret 校验JWT签名密钥，密钥为空或长度不足时返回错误
+func ValidateJWTSecret(secret []byte) error {
+	if len(secret) == 0 {
+		if viper.GetString("server.mode") == "production" {
+			return ErrJWTSecretRequired
+		}
+		return errors.New("未配置JWT签名密钥 auth.jwt_secret")
+	}
+	if len(secret) < minJWTSecretLength {
+		return fmt.Errorf("JWT签名密钥 auth.jwt_secret 长度不能少于%d字节", minJWTSecretLength)
+	}
+	return nil
+}
+
+// 令牌默认有效期
+const (
+	defaultAccessTokenTTL  = 24 * time.Hour
+	defaultRefreshTokenTTL = 7 * 24 * time.Hour
+)
+
+// parseTokenTTL 解析令牌有效期配置（Go duration 格式，如 "30m"、"12h"），未配置时使用默认值
+func parseTokenTTL(key string, defaultTTL time.Duration) (time.Duration, error) {
+	value := viper.GetString(key)
+	if value == "" {
+		return defaultTTL, nil
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s 格式无效: %w", key, err)
+	}
+	if ttl <= 0 {
+		return 0, fmt.Errorf("%s 必须大于0", key)
+	}
+	return ttl, nil
+}
+
+// LoadTokenTTL 从配置读取访问令牌和刷新令牌的有效期
+func LoadTokenTTL() (time.Duration, time.Duration, error) {
+	accessTTL, err := parseTokenTTL("auth.access_ttl", defaultAccessTokenTTL)
+	if err != nil {
+		return 0, 0, err
+	}
+	refreshTTL, err := parseTokenTTL("auth.refresh_ttl", defaultRefreshTokenTTL)
+	if err != nil {
+		return 0, 0, err
+	}
+	return accessTTL, refreshTTL, nil
+}
+
+// ValidateTokenTTL 校验令牌有效期配置，刷新令牌有效期必须长于访问令牌
+func ValidateTokenTTL() error {
+	accessTTL, refreshTTL, err := LoadTokenTTL()
+	if err != nil {
+		return err
+	}
+	if refreshTTL <= accessTTL {
+		return fmt.Errorf("auth.refresh_ttl(%s) 必须大于 auth.access_ttl(%s)", refreshTTL, accessTTL)
+	}
+	return nil
+}
 
 // JWTClaims 自定义JWT声明结构
 type JWTClaims struct {
@@ -37,32 +161,78 @@ type UserService interface {
 	// UpdatePassword 更新密码
 	UpdatePassword(ctx context.Context, id string, req *dto.UserPasswordUpdateRequest) error
 	// ListUsers 获取用户列表
-	ListUsers(ctx context.Context, req *dto.UserListRequest) (*dto.UserListResponse, error)
+	ListUsers(ctx context.Context, req *dto.UserListRequest) (*dto.PageResult[dto.UserResponse], error)
 	// UpdateUserStatus 更新用户状态
 	UpdateUserStatus(ctx context.Context, id string, status int) error
+	// BulkUpdateUserStatus 批量更新用户状态，返回每个用户的结果
+	BulkUpdateUserStatus(ctx context.Context, userIDs []string, status int, adminID string) (*dto.BulkUserStatusResponse, error)
+	// DeleteUser 软删除用户并移除其全部授权与成员关系
+	DeleteUser(ctx context.Context, id string) error
+	// ReactivateUser 恢复已删除的用户
+	ReactivateUser(ctx context.Context, id string) error
+	// UnlockUser 解除登录失败次数过多导致的锁定
+	UnlockUser(ctx context.Context, id string) error
 	// GetUserRoles 获取用户角色
 	GetUserRoles(ctx context.Context, userID string) ([]entity.Role, error)
+	// GetUserPermissions 获取用户的角色与权限汇总
+	GetUserPermissions(ctx context.Context, userID string) (*dto.UserPermissionsResponse, error)
 	// AssignRoles 为用户分配角色
 	AssignRoles(ctx context.Context, userID string, roleIDs []uint) error
 	// RemoveRoles 移除用户角色
 	RemoveRoles(ctx context.Context, userID string, roleIDs []uint) error
+	// RefreshToken 使用刷新令牌换取新的令牌对
+	RefreshToken(ctx context.Context, refreshToken string) (*dto.LoginResponse, error)
+	// Logout 注销令牌，可同时注销刷新令牌
+	Logout(ctx context.Context, tokenID string, expiresAt time.Time, refreshToken string) error
+	// VerifyEmail 使用邮箱验证令牌激活账号
+	VerifyEmail(ctx context.Context, token string) error
+	// ResendVerificationEmail 重新发送邮箱验证邮件
+	ResendVerificationEmail(ctx context.Context, email string) error
+	// UploadAvatar 上传头像图片并设为用户头像，返回头像URL
+	UploadAvatar(ctx context.Context, userID string, file *multipart.FileHeader) (string, error)
+	// OpenAvatar 读取用户当前的头像
+	OpenAvatar(ctx context.Context, userID, name string) (io.ReadCloser, int64, string, error)
+	// EnableTOTP 开始开启两步验证，返回密钥和 otpauth URI
+	EnableTOTP(ctx context.Context, userID string) (*dto.TOTPSetupResponse, error)
+	// ConfirmTOTP 使用验证码确认密钥并开启两步验证，返回恢复码
+	ConfirmTOTP(ctx context.Context, userID, code string) (*dto.TOTPRecoveryCodesResponse, error)
+	// DisableTOTP 使用验证码或恢复码关闭两步验证
+	DisableTOTP(ctx context.Context, userID, code string) error
+	// RegenerateRecoveryCodes 使用验证码重新生成恢复码
+	RegenerateRecoveryCodes(ctx context.Context, userID, code string) (*dto.TOTPRecoveryCodesResponse, error)
+	// VerifyTOTP 使用登录挑战令牌和验证码完成登录
+	VerifyTOTP(ctx context.Context, challengeToken, code, ip string) (*dto.LoginResponse, error)
+	// OAuthAuthURL 生成跳转到第三方登录提供方的授权地址和需保存在浏览器的 nonce
+	OAuthAuthURL(ctx context.Context, provider string) (string, string, error)
+	// OAuthLogin 处理第三方登录回调并签发令牌
+	OAuthLogin(ctx context.Context, provider, code, state, nonce, ip string) (*dto.LoginResponse, error)
 	// InitAdminUser 初始化系统管理员用户
 	InitAdminUser(ctx context.Context) error
 }
 
 // userService 用户服务实现
 type userService struct {
-	userRepo    repository.UserRepository
-	roleRepo    repository.RoleRepository
-	authService AuthService
+	userRepo     repository.UserRepository
+	roleRepo     repository.RoleRepository
+	authService  AuthService
+	blacklist    TokenBlacklist
+	loginLimiter LoginLimiter
+	mailer       Mailer
+	minioClient  *minio.Client
+	jwtSecret    []byte
 }
 
 // NewUserService 创建用户服务
-func NewUserService(userRepo repository.UserRepository, roleRepo repository.RoleRepository, authService AuthService) UserService {
+func NewUserService(userRepo repository.UserRepository, roleRepo repository.RoleRepository, authService AuthService, blacklist TokenBlacklist, loginLimiter LoginLimiter, mailer Mailer, minioClient *minio.Client) UserService {
 	return &userService{
-		userRepo:    userRepo,
-		roleRepo:    roleRepo,
-		authService: authService,
+		userRepo:     userRepo,
+		roleRepo:     roleRepo,
+		authService:  authService,
+		blacklist:    blacklist,
+		loginLimiter: loginLimiter,
+		mailer:       mailer,
+		minioClient:  minioClient,
+		jwtSecret:    LoadJWTSecret(),
 	}
 }
 
@@ -80,12 +250,18 @@ func (s *userService) Register(ctx context.Context, req *dto.UserRegisterRequest
 		return nil, err
 	}
 
+	// 开启邮箱验证时，新用户需验证邮箱后才能登录
+	status := entity.UserStatusNormal
+	if emailVerificationEnabled() {
+		status = entity.UserStatusPendingVerification
+	}
+
 	// 创建用户
 	user := &entity.User{
 		Email:        req.Email,
 		Name:         req.Name,
 		PasswordHash: string(passwordHash),
-		Status:       entity.UserStatusNormal,
+		Status:       status,
 	}
 
 	// 保存用户
@@ -95,19 +271,15 @@ func (s *userService) Register(ctx context.Context, req *dto.UserRegisterRequest
 	}
 
 	// 为用户分配默认角色（普通成员）
-	defaultRole, err := s.roleRepo.GetByCode(ctx, entity.RoleMember)
-	if err == nil && defaultRole != nil {
-		// 分配默认角色
-		err = s.userRepo.AssignRoles(ctx, string(user.ID), []uint{defaultRole.ID})
-		if err != nil {
-			// 记录错误但不阻止注册完成
-			// TODO: 记录日志
-		}
+	s.assignDefaultRole(ctx, user)
 
-		// 同步到Casbin
-		if s.authService != nil {
-			_ = s.authService.AddRoleForUser(ctx, string(user.ID), entity.RoleMember, "0")
+	// 发送验证邮件，失败时用户可重新发送；无需验证邮箱时直接接受待处理的群组邀请
+	if status == entity.UserStatusPendingVerification {
+		if err := s.sendVerificationEmail(ctx, user); err != nil {
+			log.Printf("发送邮箱验证邮件失败: %v", err)
 		}
+	} else {
+		s.acceptGroupInvitations(ctx, user)
 	}
 
 	// 获取刚创建的用户完整信息（包括角色）
@@ -124,27 +296,69 @@ func (s *userService) Register(ctx context.Context, req *dto.UserRegisterRequest
 	return userResponse, nil
 }
 
+// assignDefaultRole 为新用户分配默认角色（普通成员）并同步到Casbin，失败不阻止注册完成
+func (s *userService) assignDefaultRole(ctx context.Context, user *entity.User) {
+	defaultRole, err := s.roleRepo.GetByCode(ctx, entity.RoleMember)
+	if err != nil || defaultRole == nil {
+		return
+	}
+	if err := s.userRepo.AssignRoles(ctx, string(user.ID), []uint{defaultRole.ID}); err != nil {
+		log.Printf("为用户 %s 分配默认角色失败: %v", user.ID, err)
+	}
+
+	// 同步到Casbin
+	if s.authService != nil {
+		_ = s.authService.AddRoleForUser(ctx, string(user.ID), entity.RoleMember, "0")
+	}
+}
+
 // Login 用户登录
 func (s *userService) Login(ctx context.Context, req *dto.UserLoginRequest, ip string) (*dto.LoginResponse, error) {
+	// 该邮箱在该IP上登录失败次数过多时拒绝登录
+	locked, err := s.loginLimiter.Locked(ctx, req.Email, ip)
+	if err != nil {
+		return nil, fmt.Errorf("检查登录限制失败: %w", err)
+	}
+	if locked > 0 {
+		return nil, accountLockedError(locked)
+	}
+
 	// 根据邮箱获取用户
 	user, err := s.userRepo.GetByEmail(ctx, req.Email)
 	if err != nil {
-		return nil, errors.New("用户不存在或密码错误")
+		return nil, s.loginFailed(ctx, req.Email, ip)
 	}
 
 	// 检查用户状态
-	if user.Status != entity.UserStatusNormal {
+	if user.Status != entity.UserStatusNormal && user.Status != entity.UserStatusPendingVerification {
 		return nil, errors.New("账号已被禁用或锁定")
 	}
 
 	// 验证密码
 	err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password))
 	if err != nil {
-		return nil, errors.New("用户不存在或密码错误")
+		return nil, s.loginFailed(ctx, req.Email, ip)
 	}
+	if err := s.loginLimiter.Reset(ctx, req.Email, ip); err != nil {
+		log.Printf("清除登录失败次数失败: %v", err)
+	}
+
+	// 密码正确但邮箱未验证时返回单独的错误，便于客户端提示重新发送验证邮件
+	if user.Status == entity.UserStatusPendingVerification {
+		return nil, ErrEmailNotVerified
+	}
+
+	// 开启两步验证时先返回挑战，提交验证码后才签发令牌
+	if user.TOTPEnabled {
+		return s.newTwoFactorChallenge(user)
+	}
+	return s.completeLogin(ctx, user, ip)
+}
 
+// completeLogin 用户通过全部验证后记录登录信息并签发令牌
+func (s *userService) completeLogin(ctx context.Context, user *entity.User, ip string) (*dto.LoginResponse, error) {
 	// 更新最后登录信息
-	err = s.userRepo.UpdateLastLogin(ctx, string(user.ID), ip)
+	err := s.userRepo.UpdateLastLogin(ctx, string(user.ID), ip)
 	if err != nil {
 		// 非致命错误，可以继续
 		// TODO: 记录日志
@@ -170,16 +384,250 @@ func (s *userService) Login(ctx context.Context, req *dto.UserLoginRequest, ip s
 	}, nil
 }
 
+// loginFailed 记录一次登录失败，邮箱不存在时同样计数，避免通过锁定与否判断邮箱是否注册
+func (s *userService) loginFailed(ctx context.Context, email, ip string) error {
+	lockout, err := s.loginLimiter.RecordFailure(ctx, email, ip)
+	if err != nil {
+		log.Printf("记录登录失败次数失败: %v", err)
+	}
+	if lockout > 0 {
+		log.Printf("邮箱 %s 在 %s 登录失败次数过多，锁定 %s", email, ip, lockout)
+		return accountLockedError(lockout)
+	}
+	return errors.New("用户不存在或密码错误")
+}
+
+// accountLockedError 账号锁定错误，提示剩余锁定时间（向上取整到分钟）
+func accountLockedError(remaining time.Duration) error {
+	minutes := int((remaining + time.Minute - 1) / time.Minute)
+	return fmt.Errorf("%w，请在%d分钟后重试", ErrAccountLocked, minutes)
+}
+
+// UnlockUser 清除用户因登录失败次数过多产生的锁定，不修改账号状态
+func (s *userService) UnlockUser(ctx context.Context, id string) error {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("用户不存在")
+		}
+		return fmt.Errorf("获取用户信息失败: %w", err)
+	}
+	if err := s.loginLimiter.Unlock(ctx, user.Email); err != nil {
+		return fmt.Errorf("解除登录锁定失败: %w", err)
+	}
+	return nil
+}
+
+// RefreshToken 使用刷新令牌换取新的访问令牌与刷新令牌
+func (s *userService) RefreshToken(ctx context.Context, refreshToken string) (*dto.LoginResponse, error) {
+	// 解析刷新令牌
+	token, err := jwt.ParseWithClaims(refreshToken, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return s.jwtSecret, nil
+	})
+	if err != nil {
+		return nil, errors.New("刷新令牌无效或已过期")
+	}
+
+	claims, ok := token.Claims.(*JWTClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("刷新令牌无效或已过期")
+	}
+
+	// 只接受刷新令牌，拒绝访问令牌
+	if !IsRefreshTokenSubject(claims.Subject) {
+		return nil, errors.New("请使用刷新令牌")
+	}
+
+	if claims.ExpiresAt == nil {
+		return nil, errors.New("刷新令牌无效或已过期")
+	}
+
+	// 旧刷新令牌只能使用一次：检查与注销原子完成，并发使用同一令牌时只有一个请求能换取新令牌
+	revoked, err := s.blacklist.RevokeIfAbsent(ctx, claims.ID, time.Until(claims.ExpiresAt.Time))
+	if err != nil {
+		return nil, fmt.Errorf("注销刷新令牌失败: %w", err)
+	}
+	if !revoked {
+		return nil, errors.New("刷新令牌已注销")
+	}
+
+	// 检查用户状态
+	user, err := s.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil || user == nil {
+		return nil, errors.New("用户不存在")
+	}
+	if user.Status != entity.UserStatusNormal {
+		return nil, errors.New("账号已被禁用或锁定")
+	}
+
+	// 生成新的令牌对
+	newToken, newRefreshToken, expiresAt, err := s.generateToken(string(user.ID), user.Email)
+	if err != nil {
+		return nil, errors.New("生成令牌失败")
+	}
+
+	// 获取用户角色
+	roles, _ := s.userRepo.GetUserRoles(ctx, string(user.ID))
+	userResponse := s.convertToUserResponse(user)
+	userResponse.Roles = s.convertToRoleResponses(roles)
+
+	return &dto.LoginResponse{
+		Token:        newToken,
+		RefreshToken: newRefreshToken,
+		ExpiresAt:    expiresAt,
+		UserInfo:     *userResponse,
+	}, nil
+}
+
+// Logout 注销令牌，将其 jti 加入黑名单直到原过期时间
+func (s *userService) Logout(ctx context.Context, tokenID string, expiresAt time.Time, refreshToken string) error {
+	if tokenID == "" {
+		return errors.New("令牌缺少jti，无法注销")
+	}
+
+	if err := s.blacklist.Revoke(ctx, tokenID, time.Until(expiresAt)); err != nil {
+		return fmt.Errorf("注销令牌失败: %w", err)
+	}
+
+	// 同时注销刷新令牌（可选）
+	if refreshToken != "" {
+		claims := &JWTClaims{}
+		token, err := jwt.ParseWithClaims(refreshToken, claims, func(token *jwt.Token) (interface{}, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, jwt.ErrSignatureInvalid
+			}
+			return s.jwtSecret, nil
+		})
+		if err == nil && token.Valid && IsRefreshTokenSubject(claims.Subject) && claims.ExpiresAt != nil {
+			if err := s.blacklist.Revoke(ctx, claims.ID, time.Until(claims.ExpiresAt.Time)); err != nil {
+				return fmt.Errorf("注销刷新令牌失败: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// VerifyEmail 使用邮箱验证令牌激活账号，已激活的账号重复验证视为成功
+func (s *userService) VerifyEmail(ctx context.Context, tokenString string) error {
+	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return s.jwtSecret, nil
+	})
+	if err != nil {
+		return errors.New("验证链接无效或已过期")
+	}
+
+	claims, ok := token.Claims.(*JWTClaims)
+	if !ok || !token.Valid || !IsEmailVerificationSubject(claims.Subject) {
+		return errors.New("验证链接无效或已过期")
+	}
+
+	user, err := s.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil || user == nil {
+		return errors.New("用户不存在")
+	}
+	// 邮箱已变更的旧链接不再有效
+	if user.Email != claims.Email {
+		return errors.New("验证链接无效或已过期")
+	}
+
+	switch user.Status {
+	case entity.UserStatusNormal:
+		return nil
+	case entity.UserStatusPendingVerification:
+		if err := s.userRepo.UpdateStatus(ctx, user.ID, entity.UserStatusNormal); err != nil {
+			return err
+		}
+		s.acceptGroupInvitations(ctx, user)
+		return nil
+	default:
+		return errors.New("账号已被禁用或锁定")
+	}
+}
+
+// acceptGroupInvitations 用户激活后按邮箱收到的邀请加入群组，失败只记录日志，不影响注册和验证
+func (s *userService) acceptGroupInvitations(ctx context.Context, user *entity.User) {
+	if _, err := s.userRepo.AcceptGroupInvitations(ctx, user.ID, user.Email); err != nil {
+		log.Printf("处理用户 %s 的群组邀请失败: %v", user.ID, err)
+	}
+}
+
+// ResendVerificationEmail 重新发送邮箱验证邮件
+// 邮箱不存在或无需验证时同样返回成功，避免通过该接口探测已注册邮箱
+func (s *userService) ResendVerificationEmail(ctx context.Context, email string) error {
+	if !emailVerificationEnabled() {
+		return errors.New("未开启邮箱验证")
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil || user == nil || user.Status != entity.UserStatusPendingVerification {
+		return nil
+	}
+
+	return s.sendVerificationEmail(ctx, user)
+}
+
+// sendVerificationEmail 生成邮箱验证令牌并发送验证链接
+func (s *userService) sendVerificationEmail(ctx context.Context, user *entity.User) error {
+	expireHours := viper.GetInt("auth.verification_expire_hours")
+	if expireHours <= 0 {
+		expireHours = 24
+	}
+
+	claims := JWTClaims{
+		UserID: user.ID,
+		Email:  user.Email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        utils.GenerateUUID(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(expireHours) * time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Subject:   user.Email + emailVerifySubjectSuffix,
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.jwtSecret)
+	if err != nil {
+		return fmt.Errorf("生成验证令牌失败: %w", err)
+	}
+
+	link := strings.ReplaceAll(viper.GetString("auth.verification_url"), "{token}", url.QueryEscape(token))
+	if link == "" {
+		link = token
+	}
+
+	return SendMail(ctx, s.mailer, MailTypeVerifyEmail, user.Email, map[string]interface{}{
+		"Name":        user.Name,
+		"ExpireHours": expireHours,
+		"Link":        link,
+	})
+}
+
+// emailVerificationEnabled 是否开启注册邮箱验证
+func emailVerificationEnabled() bool {
+	return viper.GetBool("auth.email_verification")
+}
+
 // generateToken 生成JWT令牌
 func (s *userService) generateToken(userID string, email string) (string, string, int64, error) {
-	// Token过期时间：24小时
-	expiresAt := time.Now().Add(24 * time.Hour)
+	// 有效期在启动时已校验，这里读取失败时回退到默认值
+	accessTTL, refreshTTL, err := LoadTokenTTL()
+	if err != nil {
+		accessTTL, refreshTTL = defaultAccessTokenTTL, defaultRefreshTokenTTL
+	}
+	expiresAt := time.Now().Add(accessTTL)
 
 	// 创建JWT声明
 	claims := JWTClaims{
 		UserID: userID,
 		Email:  email,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        utils.GenerateUUID(),
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
@@ -189,26 +637,27 @@ func (s *userService) generateToken(userID string, email string) (string, string
 
 	// 生成JWT令牌
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtSecret)
+	tokenString, err := token.SignedString(s.jwtSecret)
 	if err != nil {
 		return "", "", 0, err
 	}
 
-	// 生成刷新令牌，过期时间更长：7天
-	refreshExpiresAt := time.Now().Add(7 * 24 * time.Hour)
+	// 生成刷新令牌，过期时间更长
+	refreshExpiresAt := time.Now().Add(refreshTTL)
 	refreshClaims := JWTClaims{
 		UserID: userID,
 		Email:  email,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        utils.GenerateUUID(),
 			ExpiresAt: jwt.NewNumericDate(refreshExpiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
-			Subject:   email + ":refresh",
+			Subject:   email + refreshTokenSubjectSuffix,
 		},
 	}
 
 	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	refreshTokenString, err := refreshToken.SignedString(jwtSecret)
+	refreshTokenString, err := refreshToken.SignedString(s.jwtSecret)
 	if err != nil {
 		return "", "", 0, err
 	}
@@ -240,11 +689,19 @@ func (s *userService) UpdateUserInfo(ctx context.Context, id string, req *dto.Us
 	}
 
 	// 更新用户信息
+	previous := user.Avatar
 	user.Name = req.Name
 	user.Avatar = req.Avatar
 	user.UpdatedAt = time.Now()
 
-	return s.userRepo.Update(ctx, user)
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+	// 不再使用上传的头像时删除其对象
+	if previous != user.Avatar {
+		s.removeAvatarObject(ctx, id, previous)
+	}
+	return nil
 }
 
 // UpdatePassword 更新密码
@@ -271,7 +728,7 @@ func (s *userService) UpdatePassword(ctx context.Context, id string, req *dto.Us
 }
 
 // ListUsers 获取用户列表
-func (s *userService) ListUsers(ctx context.Context, req *dto.UserListRequest) (*dto.UserListResponse, error) {
+func (s *userService) ListUsers(ctx context.Context, req *dto.UserListRequest) (*dto.PageResult[dto.UserResponse], error) {
 	// 默认值处理
 	if req.Page <= 0 {
 		req.Page = 1
@@ -281,27 +738,23 @@ func (s *userService) ListUsers(ctx context.Context, req *dto.UserListRequest) (
 	}
 
 	// 获取用户列表
-	users, total, err := s.userRepo.List(ctx, req.Email, req.Name, req.Status, req.Page, req.Size)
+	users, total, err := s.userRepo.List(ctx, req.Email, req.Name, req.Status, req.Page, req.Size, req.IncludeDeleted)
 	if err != nil {
 		return nil, err
 	}
 
 	// 转换为响应
-	result := &dto.UserListResponse{
-		Total: total,
-		List:  make([]dto.UserResponse, 0, len(users)),
-	}
-
+	list := make([]dto.UserResponse, 0, len(users))
 	for _, user := range users {
 		// 获取用户角色
 		roles, _ := s.userRepo.GetUserRoles(ctx, string(user.ID))
 		userResponse := s.convertToUserResponse(user)
 		userResponse.Roles = s.convertToRoleResponses(roles)
 
-		result.List = append(result.List, *userResponse)
+		list = append(list, *userResponse)
 	}
 
-	return result, nil
+	return dto.NewPageResult(list, total, dto.PageQuery{Page: req.Page, Size: req.Size}), nil
 }
 
 // UpdateUserStatus 更新用户状态
@@ -314,11 +767,142 @@ func (s *userService) UpdateUserStatus(ctx context.Context, id string, status in
 	return s.userRepo.UpdateStatus(ctx, id, status)
 }
 
+// BulkUpdateUserStatus 批量更新用户状态
+// 不存在的用户和管理员自身的禁用/锁定请求记为失败，其余用户在同一事务中更新并逐个记录操作日志
+func (s *userService) BulkUpdateUserStatus(ctx context.Context, userIDs []string, status int, adminID string) (*dto.BulkUserStatusResponse, error) {
+	// 检查状态值是否有效
+	if status != entity.UserStatusNormal && status != entity.UserStatusDisabled && status != entity.UserStatusLocked {
+		return nil, errors.New("无效的状态值")
+	}
+
+	ip := ""
+	if c, ok := ctx.(interface{ ClientIP() string }); ok {
+		ip = c.ClientIP()
+	}
+
+	response := &dto.BulkUserStatusResponse{Results: make([]dto.BulkUserStatusResult, 0, len(userIDs))}
+	var ids []string
+	var logs []*entity.OperationLog
+	seen := make(map[string]bool, len(userIDs))
+	for _, id := range userIDs {
+		result := dto.BulkUserStatusResult{UserID: id}
+
+		switch {
+		case seen[id]:
+			result.Error = "用户ID重复"
+		case id == adminID && status != entity.UserStatusNormal:
+			result.Error = "不能禁用或锁定自己的账号"
+		default:
+			user, err := s.userRepo.GetByID(ctx, id)
+			if err != nil {
+				if !errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil, fmt.Errorf("获取用户信息失败: %w", err)
+				}
+				result.Error = "用户不存在"
+				break
+			}
+
+			details, _ := json.Marshal(map[string]int{"old_status": user.Status, "new_status": status})
+			logs = append(logs, &entity.OperationLog{
+				UserID:     adminID,
+				Action:     entity.OperationUpdateStatus,
+				Module:     "user",
+				TargetID:   id,
+				TargetType: "user",
+				Details:    string(details),
+				IP:         ip,
+				CreatedAt:  time.Now(),
+			})
+			ids = append(ids, id)
+			result.Success = true
+		}
+		seen[id] = true
+
+		response.Results = append(response.Results, result)
+	}
+
+	// 状态更新与操作日志在同一事务中提交
+	if err := s.userRepo.BulkUpdateStatus(ctx, ids, status, logs); err != nil {
+		return nil, fmt.Errorf("批量更新用户状态失败: %w", err)
+	}
+
+	for _, result := range response.Results {
+		if result.Success {
+			response.Updated++
+		} else {
+			response.Failed++
+		}
+	}
+	return response, nil
+}
+
+// DeleteUser 软删除用户
+// 邮箱追加墓碑后缀以便重新注册，用户角色、Casbin规则和群组/项目成员关系在同一事务中删除
+func (s *userService) DeleteUser(ctx context.Context, id string) error {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("用户不存在")
+		}
+		return fmt.Errorf("获取用户信息失败: %w", err)
+	}
+	if user.DeletedAt != nil {
+		return errors.New("用户已被删除")
+	}
+
+	now := time.Now()
+	tombstone := fmt.Sprintf("%s%s%d", user.Email, entity.UserEmailTombstone, now.Unix())
+	if err := s.userRepo.SoftDelete(ctx, id, tombstone, now); err != nil {
+		return fmt.Errorf("删除用户失败: %w", err)
+	}
+
+	// 规则已直接从数据库删除，需重新加载到内存中的策略
+	if err := s.authService.ReloadPolicy(); err != nil {
+		return fmt.Errorf("重新加载权限策略失败: %w", err)
+	}
+	return nil
+}
+
+// ReactivateUser 恢复已删除的用户
+// 原邮箱已被其他账号使用时无法恢复；删除时移除的角色与成员关系不会自动恢复
+func (s *userService) ReactivateUser(ctx context.Context, id string) error {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("用户不存在")
+		}
+		return fmt.Errorf("获取用户信息失败: %w", err)
+	}
+	if user.DeletedAt == nil {
+		return errors.New("用户未被删除")
+	}
+
+	email := user.Email
+	if i := strings.LastIndex(email, entity.UserEmailTombstone); i >= 0 {
+		email = email[:i]
+	}
+	if _, err := s.userRepo.GetByEmail(ctx, email); err == nil {
+		return errors.New("原邮箱已被其他账号使用，无法恢复")
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("检查邮箱失败: %w", err)
+	}
+
+	if err := s.userRepo.Reactivate(ctx, id, email); err != nil {
+		return fmt.Errorf("恢复用户失败: %w", err)
+	}
+	return nil
+}
+
 // GetUserRoles 获取用户角色
 func (s *userService) GetUserRoles(ctx context.Context, userID string) ([]entity.Role, error) {
 	return s.userRepo.GetUserRoles(ctx, userID)
 }
 
+// GetUserPermissions 获取用户的系统角色、群组角色、项目角色和直接授予的权限策略
+func (s *userService) GetUserPermissions(ctx context.Context, userID string) (*dto.UserPermissionsResponse, error) {
+	return s.authService.GetUserPermissions(ctx, userID)
+}
+
 // AssignRoles 为用户分配角色
 func (s *userService) AssignRoles(ctx context.Context, userID string, roleIDs []uint) error {
 	// 检查用户是否存在
@@ -398,6 +982,7 @@ func (s *userService) convertToUserResponse(user *entity.User) *dto.UserResponse
 		LastLoginAt: user.LastLoginAt,
 		LastLoginIP: user.LastLoginIP,
 		CreatedAt:   user.CreatedAt,
+		TOTPEnabled: user.TOTPEnabled,
 	}
 }
 
@@ -423,7 +1008,47 @@ func (s *userService) convertToRoleResponses(roles []entity.Role) []dto.RoleResp
 	return result
 }
 
+// ErrAdminConfigRequired 生产环境需要创建初始管理员但未配置账号
+var ErrAdminConfigRequired = errors.New("生产环境必须配置初始管理员账号 admin.email 和 admin.password")
+
+// 开发环境未配置 admin.email 时初始管理员使用的邮箱
+const defaultAdminEmail = "admin@x.com"
+
+// initialAdminAccount 从配置读取初始管理员账号
+// 生产环境必须配置邮箱和密码；开发环境未配置邮箱时使用默认邮箱，未配置密码时随机生成，generated 为 true
+func initialAdminAccount() (account *dto.UserRegisterRequest, generated bool, err error) {
+	account = &dto.UserRegisterRequest{
+		Email:    strings.TrimSpace(viper.GetString("admin.email")),
+		Password: viper.GetString("admin.password"),
+		Name:     viper.GetString("admin.name"),
+	}
+	if account.Name == "" {
+		account.Name = "Admin"
+	}
+	if viper.GetString("server.mode") == "production" {
+		if account.Email == "" || account.Password == "" {
+			return nil, false, ErrAdminConfigRequired
+		}
+		return account, false, nil
+	}
+
+	if account.Email == "" {
+		account.Email = defaultAdminEmail
+	}
+	if account.Password == "" {
+		b := make([]byte, 9)
+		if _, err := rand.Read(b); err != nil {
+			return nil, false, fmt.Errorf("生成管理员密码失败: %w", err)
+		}
+		account.Password = base64.RawURLEncoding.EncodeToString(b)
+		generated = true
+	}
+	return account, generated, nil
+}
+
 // InitAdminUser 初始化系统管理员用户
+// 已存在管理员时不做处理；开启 admin.recheck 时每次启动都确认配置的管理员账号存在、已激活并拥有管理员角色，
+// 账号已存在时不修改其密码
 func (s *userService) InitAdminUser(ctx context.Context) error {
 	// 检查是否已存在管理员用户
 	adminRole, err := s.roleRepo.GetByCode(ctx, entity.RoleAdmin)
@@ -433,7 +1058,7 @@ func (s *userService) InitAdminUser(ctx context.Context) error {
 
 	// 查询是否有用户拥有管理员角色
 	// 这里查询所有正常状态的用户，不进行分页限制
-	users, _, err := s.userRepo.List(ctx, "", "", entity.UserStatusNormal, 0, 0)
+	users, _, err := s.userRepo.List(ctx, "", "", entity.UserStatusNormal, 0, 0, false)
 	if err != nil {
 		return fmt.Errorf("查询用户失败: %w", err)
 	}
@@ -458,24 +1083,48 @@ func (s *userService) InitAdminUser(ctx context.Context) error {
 	}
 
 	// 如果已存在管理员用户，则不需要创建
-	if hasAdmin {
+	if hasAdmin && !viper.GetBool("admin.recheck") {
 		return nil
 	}
 
-	// 创建管理员用户
-	adminReq := &dto.UserRegisterRequest{
-		Email:    "admin@x.com",
-		Password: "admin123",
-		Name:     "Admin",
+	account, generated, err := initialAdminAccount()
+	if err != nil {
+		return err
 	}
 
-	admin, err := s.Register(ctx, adminReq)
-	if err != nil {
-		return fmt.Errorf("创建管理员用户失败: %w", err)
+	// 配置的邮箱已注册时将该账号设为管理员；默认邮箱可能被他人注册，不自动提升
+	user, err := s.userRepo.GetByEmail(ctx, account.Email)
+	switch {
+	case err == nil:
+		if viper.GetString("admin.email") == "" {
+			return fmt.Errorf("默认管理员邮箱 %s 已被其他账号使用，请配置 admin.email", account.Email)
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		// 创建管理员用户
+		admin, err := s.Register(ctx, account)
+		if err != nil {
+			return fmt.Errorf("创建管理员用户失败: %w", err)
+		}
+		if user, err = s.userRepo.GetByID(ctx, admin.ID); err != nil {
+			return fmt.Errorf("获取管理员用户失败: %w", err)
+		}
+		if generated {
+			log.Printf("警告: 已创建初始管理员 %s，随机生成的密码为 %s，该密码只显示这一次，请登录后立即修改", account.Email, account.Password)
+		}
+	default:
+		return fmt.Errorf("查询管理员账号失败: %w", err)
+	}
+
+	// 开启邮箱验证时注册的账号待验证，管理员账号直接激活
+	if user.Status == entity.UserStatusPendingVerification {
+		user.Status = entity.UserStatusNormal
+		if err := s.userRepo.Update(ctx, user); err != nil {
+			return fmt.Errorf("激活管理员用户失败: %w", err)
+		}
 	}
 
 	// 分配管理员角色
-	err = s.AssignRoles(ctx, admin.ID, []uint{adminRole.ID})
+	err = s.AssignRoles(ctx, string(user.ID), []uint{adminRole.ID})
 	if err != nil {
 		return fmt.Errorf("分配管理员角色失败: %w", err)
 	}
@@ -0,0 +1,319 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/bcrypt"
+
+	"oss-backend/internal/model/dto"
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+)
+
+// setTokenTTL 为测试配置令牌有效期
+func setTokenTTL(t *testing.T, accessTTL, refreshTTL string) {
+	t.Helper()
+	viper.Set("auth.access_ttl", accessTTL)
+	viper.Set("auth.refresh_ttl", refreshTTL)
+	t.Cleanup(func() {
+		viper.Set("auth.access_ttl", "")
+		viper.Set("auth.refresh_ttl", "")
+	})
+}
+
+func TestLoadTokenTTL(t *testing.T) {
+	tests := []struct {
+		accessTTL, refreshTTL string
+		wantAccess            time.Duration
+		wantRefresh           time.Duration
+		wantErr               bool
+	}{
+		{"", "", defaultAccessTokenTTL, defaultRefreshTokenTTL, false},
+		{"30m", "12h", 30 * time.Minute, 12 * time.Hour, false},
+		{"1h30m", "", 90 * time.Minute, defaultRefreshTokenTTL, false},
+		{"24", "", 0, 0, true},
+		{"abc", "", 0, 0, true},
+		{"", "7d", 0, 0, true},
+		{"0s", "", 0, 0, true},
+		{"", "-1h", 0, 0, true},
+	}
+	for _, tt := range tests {
+		setTokenTTL(t, tt.accessTTL, tt.refreshTTL)
+		access, refresh, err := LoadTokenTTL()
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("配置 %q/%q 应返回错误，实际为 %s/%s", tt.accessTTL, tt.refreshTTL, access, refresh)
+			}
+			continue
+		}
+		if err != nil || access != tt.wantAccess || refresh != tt.wantRefresh {
+			t.Errorf("配置 %q/%q 解析为 %s/%s, %v，应为 %s/%s", tt.accessTTL, tt.refreshTTL, access, refresh, err, tt.wantAccess, tt.wantRefresh)
+		}
+	}
+}
+
+func TestValidateTokenTTL(t *testing.T) {
+	tests := []struct {
+		accessTTL, refreshTTL string
+		wantErr               bool
+	}{
+		{"", "", false},
+		{"15m", "1h", false},
+		{"1h", "1h", true},
+		{"2h", "1h", true},
+		// 只配置访问令牌时与默认刷新令牌有效期比较
+		{"200h", "", true},
+		{"bad", "1h", true},
+	}
+	for _, tt := range tests {
+		setTokenTTL(t, tt.accessTTL, tt.refreshTTL)
+		if err := ValidateTokenTTL(); (err != nil) != tt.wantErr {
+			t.Errorf("配置 %q/%q 校验结果为 %v，是否应报错: %v", tt.accessTTL, tt.refreshTTL, err, tt.wantErr)
+		}
+	}
+}
+
+// setJWTSecretConfig 为测试配置JWT签名密钥和运行模式
+func setJWTSecretConfig(t *testing.T, secret, mode string) {
+	t.Helper()
+	viper.Set("auth.jwt_secret", secret)
+	viper.Set("server.mode", mode)
+	t.Cleanup(func() {
+		viper.Set("auth.jwt_secret", "")
+		viper.Set("server.mode", "")
+	})
+}
+
+func TestLoadJWTSecret(t *testing.T) {
+	setJWTSecretConfig(t, "", "production")
+	if secret := LoadJWTSecret(); len(secret) != 0 {
+		t.Fatalf("生产环境未配置密钥时不应生成密钥")
+	}
+	if err := ValidateJWTSecret(LoadJWTSecret()); !errors.Is(err, ErrJWTSecretRequired) {
+		t.Fatalf("生产环境未配置密钥时应拒绝启动，实际为 %v", err)
+	}
+
+	// 开发环境未配置时随机生成，同一进程内保持不变
+	setJWTSecretConfig(t, "", "development")
+	secret := LoadJWTSecret()
+	if err := ValidateJWTSecret(secret); err != nil {
+		t.Fatalf("随机生成的密钥未通过校验: %v", err)
+	}
+	if !bytes.Equal(LoadJWTSecret(), secret) {
+		t.Fatalf("同一进程内两次读取的密钥不同")
+	}
+
+	configured := "0123456789abcdef0123456789abcdef"
+	setJWTSecretConfig(t, configured, "development")
+	if string(LoadJWTSecret()) != configured {
+		t.Fatalf("应使用配置的密钥")
+	}
+}
+
+func TestValidateJWTSecret(t *testing.T) {
+	for _, mode := range []string{"development", "production"} {
+		setJWTSecretConfig(t, "", mode)
+		// 原配置文件中公开过的默认密钥长度不足，任何模式下都不能使用
+		if err := ValidateJWTSecret([]byte("LeonColeSuperSecretkey20250424")); err == nil {
+			t.Errorf("%s 模式下接受了长度不足的密钥", mode)
+		}
+		if err := ValidateJWTSecret(bytes.Repeat([]byte("k"), minJWTSecretLength)); err != nil {
+			t.Errorf("%s 模式下拒绝了%d字节的密钥: %v", mode, minJWTSecretLength, err)
+		}
+	}
+}
+
+// TestRefreshTokenConcurrentUse 并发使用同一刷新令牌时只有一个请求换到新令牌
+func TestRefreshTokenConcurrentUse(t *testing.T) {
+	db := newTestDB(t, &entity.User{})
+	svc := &userService{
+		userRepo:  repository.NewUserRepository(db),
+		blacklist: NewMemoryTokenBlacklist(),
+		jwtSecret: []byte("0123456789abcdef0123456789abcdef"),
+	}
+	user := &entity.User{ID: "user-1", Email: "alice@example.com", Name: "alice", Status: entity.UserStatusNormal}
+	if err := svc.userRepo.Create(context.Background(), user); err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	_, refreshToken, _, err := svc.generateToken(string(user.ID), user.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const workers = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var succeeded []string
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := svc.RefreshToken(context.Background(), refreshToken)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			succeeded = append(succeeded, resp.RefreshToken)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if len(succeeded) != 1 {
+		t.Fatalf("%d 个并发刷新请求中有 %d 个成功，应只有1个", workers, len(succeeded))
+	}
+	if _, err := svc.RefreshToken(context.Background(), refreshToken); err == nil {
+		t.Fatalf("已使用的刷新令牌仍可再次使用")
+	}
+	// 新的刷新令牌可以继续使用
+	if _, err := svc.RefreshToken(context.Background(), succeeded[0]); err != nil {
+		t.Fatalf("使用新的刷新令牌失败: %v", err)
+	}
+}
+
+// TestBulkUpdateUserStatusBlocksLogin 批量锁定/禁用的账号无法再登录，管理员不能锁定自己，每个变更都记录操作日志
+func TestBulkUpdateUserStatusBlocksLogin(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t, &entity.User{}, &entity.OperationLog{})
+	svc := &userService{
+		userRepo:     repository.NewUserRepository(db),
+		blacklist:    NewMemoryTokenBlacklist(),
+		loginLimiter: NewMemoryLoginLimiter(),
+		jwtSecret:    []byte("0123456789abcdef0123456789abcdef"),
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"admin", "user-1", "user-2", "user-3", "user-4"} {
+		user := &entity.User{ID: id, Email: id + "@example.com", Name: id, PasswordHash: string(hash), Status: entity.UserStatusNormal}
+		if err := svc.userRepo.Create(ctx, user); err != nil {
+			t.Fatalf("创建用户失败: %v", err)
+		}
+	}
+	login := func(id string) error {
+		_, err := svc.Login(ctx, &dto.UserLoginRequest{Email: id + "@example.com", Password: "password123"}, "127.0.0.1")
+		return err
+	}
+	for _, id := range []string{"user-1", "user-2", "user-3"} {
+		if err := login(id); err != nil {
+			t.Fatalf("锁定前 %s 登录失败: %v", id, err)
+		}
+	}
+
+	resp, err := svc.BulkUpdateUserStatus(ctx, []string{"user-1", "user-2", "user-2", "admin", "missing"}, entity.UserStatusLocked, "admin")
+	if err != nil {
+		t.Fatalf("批量锁定失败: %v", err)
+	}
+	if resp.Updated != 2 || resp.Failed != 3 {
+		t.Fatalf("批量锁定成功 %d 个、失败 %d 个，应为2个和3个", resp.Updated, resp.Failed)
+	}
+	wantErrors := []string{"", "", "用户ID重复", "不能禁用或锁定自己的账号", "用户不存在"}
+	for i, result := range resp.Results {
+		if result.Error != wantErrors[i] || result.Success != (wantErrors[i] == "") {
+			t.Errorf("用户 %s 的结果为 %+v，错误应为 %q", result.UserID, result, wantErrors[i])
+		}
+	}
+	if _, err := svc.BulkUpdateUserStatus(ctx, []string{"user-3"}, entity.UserStatusDisabled, "admin"); err != nil {
+		t.Fatalf("批量禁用失败: %v", err)
+	}
+	if _, err := svc.BulkUpdateUserStatus(ctx, []string{"user-4"}, 99, "admin"); err == nil {
+		t.Fatalf("无效的状态值没有被拒绝")
+	}
+
+	for _, id := range []string{"user-1", "user-2", "user-3"} {
+		if err := login(id); err == nil || err.Error() != "账号已被禁用或锁定" {
+			t.Errorf("%s 被锁定或禁用后登录返回 %v，应被拒绝", id, err)
+		}
+	}
+	for _, id := range []string{"admin", "user-4"} {
+		if err := login(id); err != nil {
+			t.Errorf("未被锁定的 %s 登录失败: %v", id, err)
+		}
+	}
+
+	var logs []entity.OperationLog
+	db.Order("target_id").Find(&logs)
+	if len(logs) != 3 {
+		t.Fatalf("记录了 %d 条操作日志，应为3条", len(logs))
+	}
+	for i, id := range []string{"user-1", "user-2", "user-3"} {
+		if logs[i].TargetID != id || logs[i].UserID != "admin" || logs[i].Action != entity.OperationUpdateStatus {
+			t.Errorf("第 %d 条操作日志为 %+v，应记录管理员修改 %s 的状态", i, logs[i], id)
+		}
+	}
+}
+
+// TestInitAdminUserRequiresConfigInProduction 生产环境未配置初始管理员邮箱或密码时中止初始化且不创建账号；
+// 已存在管理员时不需要配置；开发环境未配置密码时随机生成
+func TestInitAdminUserRequiresConfigInProduction(t *testing.T) {
+	ctx := context.Background()
+	viper.Set("server.mode", "production")
+	t.Cleanup(func() {
+		viper.Set("server.mode", "")
+		viper.Set("admin.email", "")
+		viper.Set("admin.password", "")
+	})
+	db := newTestDB(t, &entity.User{}, &entity.Role{}, &entity.UserRole{})
+	svc := &userService{
+		userRepo: repository.NewUserRepository(db),
+		roleRepo: repository.NewRoleRepository(db),
+	}
+	adminRole := &entity.Role{ID: 1, Name: "管理员", Code: entity.RoleAdmin, Status: 1, IsSystem: true}
+	if err := db.Create(adminRole).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&entity.User{ID: "user-1", Email: "user-1@example.com", Name: "user-1", Status: entity.UserStatusNormal}).Error; err != nil {
+		t.Fatal(err)
+	}
+	userCount := func() int64 {
+		var count int64
+		db.Model(&entity.User{}).Count(&count)
+		return count
+	}
+
+	configs := []struct {
+		name     string
+		email    string
+		password string
+	}{
+		{"未配置邮箱和密码", "", ""},
+		{"只配置邮箱", "admin@example.com", ""},
+		{"只配置密码", "", "Str0ng-password"},
+	}
+	for _, cfg := range configs {
+		viper.Set("admin.email", cfg.email)
+		viper.Set("admin.password", cfg.password)
+		if err := svc.InitAdminUser(ctx); !errors.Is(err, ErrAdminConfigRequired) {
+			t.Fatalf("%s: 返回 %v，应返回 ErrAdminConfigRequired", cfg.name, err)
+		}
+		if count := userCount(); count != 1 {
+			t.Fatalf("%s: 初始化中止后有 %d 个用户，不应创建账号", cfg.name, count)
+		}
+	}
+
+	// 已存在管理员时不读取初始管理员配置
+	if err := db.Create(&entity.UserRole{UserID: "user-1", RoleID: adminRole.ID}).Error; err != nil {
+		t.Fatal(err)
+	}
+	viper.Set("admin.email", "")
+	viper.Set("admin.password", "")
+	if err := svc.InitAdminUser(ctx); err != nil {
+		t.Fatalf("已存在管理员时初始化返回 %v，应跳过", err)
+	}
+
+	viper.Set("server.mode", "debug")
+	account, generated, err := initialAdminAccount()
+	if err != nil {
+		t.Fatalf("开发环境读取初始管理员失败: %v", err)
+	}
+	if account.Email != defaultAdminEmail || !generated || len(account.Password) < 12 {
+		t.Fatalf("开发环境初始管理员为 %s（生成密码=%v，长度 %d），应使用默认邮箱和随机生成的密码", account.Email, generated, len(account.Password))
+	}
+}
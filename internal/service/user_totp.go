@@ -0,0 +1,389 @@
+package service
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/spf13/viper"
+
+	"oss-backend/internal/model/dto"
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/utils"
+	"oss-backend/pkg/totp"
+)
+
+// twoFactorSubjectSuffix 两步验证挑战令牌Subject后缀，挑战令牌只能用于提交验证码
+const twoFactorSubjectSuffix = ":2fa"
+
+const (
+	// twoFactorChallengeExpiry 登录挑战的有效期，超过后需要重新输入密码
+	twoFactorChallengeExpiry = 5 * time.Minute
+	// totpSkew 允许验证码与服务器时间相差的时间步数
+	totpSkew = 1
+	// recoveryCodeCount 每次生成的恢复码个数
+	recoveryCodeCount = 10
+	defaultTOTPIssuer = "OSS"
+)
+
+// ErrTOTPAlreadyEnabled 用户已开启两步验证
+var ErrTOTPAlreadyEnabled = errors.New("已开启两步验证")
+
+// ErrTOTPNotEnabled 用户未开启两步验证或开启流程尚未开始
+var ErrTOTPNotEnabled = errors.New("未开启两步验证")
+
+// ErrInvalidTOTPCode 验证码或恢复码无效
+var ErrInvalidTOTPCode = errors.New("验证码无效")
+
+// ErrInvalidTwoFactorChallenge 登录挑战无效或已过期
+var ErrInvalidTwoFactorChallenge = errors.New("两步验证已过期，请重新登录")
+
+// IsTwoFactorChallengeSubject 判断令牌Subject是否属于两步验证挑战令牌
+func IsTwoFactorChallengeSubject(subject string) bool {
+	return strings.HasSuffix(subject, twoFactorSubjectSuffix)
+}
+
+// totpIssuer 身份验证器应用中显示的服务名称
+func totpIssuer() string {
+	issuer := viper.GetString("auth.totp_issuer")
+	if issuer == "" {
+		issuer = defaultTOTPIssuer
+	}
+	return issuer
+}
+
+// totpAEAD 由加密主密钥派生保存TOTP密钥使用的 AES-256-GCM
+func totpAEAD() (cipher.AEAD, error) {
+	master, err := encryptionMasterKey()
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, master)
+	mac.Write([]byte("oss-backend/totp"))
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealTOTPSecret 加密TOTP密钥，以用户ID作为附加数据，密文不能挪用到其他用户
+func sealTOTPSecret(userID, secret string) (string, error) {
+	aead, err := totpAEAD()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(secret), []byte(userID))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// openTOTPSecret 解密TOTP密钥
+func openTOTPSecret(userID, sealed string) (string, error) {
+	aead, err := totpAEAD()
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < aead.NonceSize() {
+		return "", errors.New("TOTP密钥已损坏")
+	}
+	secret, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(userID))
+	if err != nil {
+		return "", errors.New("TOTP密钥已损坏或加密主密钥已变更")
+	}
+	return string(secret), nil
+}
+
+// hashRecoveryCode 计算恢复码的SHA-256，忽略大小写和分隔符
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateRecoveryCodes 生成一组恢复码，返回明文和保存用的哈希列表
+func generateRecoveryCodes() ([]string, string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, "", err
+		}
+		raw := hex.EncodeToString(buf)
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, hashRecoveryCode(raw))
+	}
+	return codes, strings.Join(hashes, ","), nil
+}
+
+// EnableTOTP 开始开启两步验证，生成新的密钥并返回用于扫码的 otpauth URI
+// 需要通过 ConfirmTOTP 提交一次验证码后才会生效；重复调用会替换尚未确认的密钥
+func (s *userService) EnableTOTP(ctx context.Context, userID string) (*dto.TOTPSetupResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户信息失败: %w", err)
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("生成密钥失败: %w", err)
+	}
+	sealed, err := sealTOTPSecret(user.ID, secret)
+	if err != nil {
+		return nil, fmt.Errorf("无法开启两步验证: %w", err)
+	}
+	user.TOTPSecret = sealed
+	user.TOTPLastStep = 0
+	user.TOTPRecoveryCodes = ""
+	if err := s.userRepo.UpdateTOTP(ctx, user); err != nil {
+		return nil, fmt.Errorf("保存密钥失败: %w", err)
+	}
+
+	return &dto.TOTPSetupResponse{
+		Secret: secret,
+		URI:    totp.URI(totpIssuer(), user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTP 使用身份验证器应用生成的验证码确认密钥，开启两步验证并返回恢复码
+// 恢复码只在此时返回一次，每个恢复码可代替验证码使用一次
+func (s *userService) ConfirmTOTP(ctx context.Context, userID, code string) (*dto.TOTPRecoveryCodesResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户信息失败: %w", err)
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, fmt.Errorf("%w: 请先获取密钥", ErrTOTPNotEnabled)
+	}
+	secret, err := openTOTPSecret(user.ID, user.TOTPSecret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := totp.Validate(secret, strings.TrimSpace(code), time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidTOTPCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("生成恢复码失败: %w", err)
+	}
+	user.TOTPEnabled = true
+	user.TOTPLastStep = step
+	user.TOTPRecoveryCodes = hashes
+	if err := s.userRepo.UpdateTOTP(ctx, user); err != nil {
+		return nil, fmt.Errorf("开启两步验证失败: %w", err)
+	}
+	return &dto.TOTPRecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableTOTP 使用验证码或恢复码关闭两步验证，同时清除密钥和恢复码
+func (s *userService) DisableTOTP(ctx context.Context, userID, code string) error {
+	user, err := s.enabledTOTPUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.consumeTOTPCode(ctx, user, code); err != nil {
+		return err
+	}
+	user.TOTPSecret = ""
+	user.TOTPEnabled = false
+	user.TOTPLastStep = 0
+	user.TOTPRecoveryCodes = ""
+	if err := s.userRepo.UpdateTOTP(ctx, user); err != nil {
+		return fmt.Errorf("关闭两步验证失败: %w", err)
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes 使用验证码重新生成恢复码，原有的恢复码全部失效
+func (s *userService) RegenerateRecoveryCodes(ctx context.Context, userID, code string) (*dto.TOTPRecoveryCodesResponse, error) {
+	user, err := s.enabledTOTPUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	// 只接受验证码，避免用恢复码换取新的恢复码
+	secret, err := openTOTPSecret(user.ID, user.TOTPSecret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := totp.Validate(secret, strings.TrimSpace(code), time.Now(), totpSkew)
+	if !ok || step <= user.TOTPLastStep {
+		return nil, ErrInvalidTOTPCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, fmt.Errorf("生成恢复码失败: %w", err)
+	}
+	updated, err := s.userRepo.ConsumeTOTP(ctx, user, step, hashes)
+	if err != nil {
+		return nil, fmt.Errorf("保存恢复码失败: %w", err)
+	}
+	if !updated {
+		return nil, ErrInvalidTOTPCode
+	}
+	return &dto.TOTPRecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// VerifyTOTP 登录的第二步，使用登录返回的挑战令牌和验证码（或恢复码）换取访问令牌
+// 验证失败与密码错误一样计入登录失败次数
+func (s *userService) VerifyTOTP(ctx context.Context, challengeToken, code, ip string) (*dto.LoginResponse, error) {
+	token, err := jwt.ParseWithClaims(challengeToken, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return s.jwtSecret, nil
+	})
+	if err != nil {
+		return nil, ErrInvalidTwoFactorChallenge
+	}
+	claims, ok := token.Claims.(*JWTClaims)
+	if !ok || !token.Valid || !IsTwoFactorChallengeSubject(claims.Subject) {
+		return nil, ErrInvalidTwoFactorChallenge
+	}
+	revoked, err := s.blacklist.IsRevoked(ctx, claims.ID)
+	if err != nil {
+		return nil, fmt.Errorf("检查令牌状态失败: %w", err)
+	}
+	if revoked {
+		return nil, ErrInvalidTwoFactorChallenge
+	}
+
+	locked, err := s.loginLimiter.Locked(ctx, claims.Email, ip)
+	if err != nil {
+		return nil, fmt.Errorf("检查登录限制失败: %w", err)
+	}
+	if locked > 0 {
+		return nil, accountLockedError(locked)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil || user.Email != claims.Email || user.Status != entity.UserStatusNormal || !user.TOTPEnabled {
+		return nil, ErrInvalidTwoFactorChallenge
+	}
+	if err := s.consumeTOTPCode(ctx, user, code); err != nil {
+		if errors.Is(err, ErrInvalidTOTPCode) {
+			if lockErr := s.loginFailed(ctx, claims.Email, ip); errors.Is(lockErr, ErrAccountLocked) {
+				return nil, lockErr
+			}
+		}
+		return nil, err
+	}
+	if err := s.loginLimiter.Reset(ctx, claims.Email, ip); err != nil {
+		log.Printf("清除登录失败次数失败: %v", err)
+	}
+
+	// 挑战令牌只能使用一次
+	if ttl := time.Until(claims.ExpiresAt.Time); ttl > 0 {
+		if err := s.blacklist.Revoke(ctx, claims.ID, ttl); err != nil {
+			return nil, fmt.Errorf("注销挑战令牌失败: %w", err)
+		}
+	}
+	return s.completeLogin(ctx, user, ip)
+}
+
+// newTwoFactorChallenge 为已通过密码验证的用户生成登录挑战
+func (s *userService) newTwoFactorChallenge(user *entity.User) (*dto.LoginResponse, error) {
+	expiresAt := time.Now().Add(twoFactorChallengeExpiry)
+	claims := JWTClaims{
+		UserID: user.ID,
+		Email:  user.Email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        utils.GenerateUUID(),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Subject:   user.Email + twoFactorSubjectSuffix,
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.jwtSecret)
+	if err != nil {
+		return nil, errors.New("生成令牌失败")
+	}
+	return &dto.LoginResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    token,
+		ExpiresAt:         expiresAt.Unix(),
+	}, nil
+}
+
+// enabledTOTPUser 获取已开启两步验证的用户
+func (s *userService) enabledTOTPUser(ctx context.Context, userID string) (*entity.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取用户信息失败: %w", err)
+	}
+	if !user.TOTPEnabled {
+		return nil, ErrTOTPNotEnabled
+	}
+	return user, nil
+}
+
+// consumeTOTPCode 校验验证码或恢复码并记录使用：验证码不能重复使用同一时间步，恢复码使用后删除
+func (s *userService) consumeTOTPCode(ctx context.Context, user *entity.User, code string) error {
+	code = strings.TrimSpace(code)
+	lastStep := user.TOTPLastStep
+	recoveryCodes := user.TOTPRecoveryCodes
+
+	if len(code) == totp.Digits {
+		secret, err := openTOTPSecret(user.ID, user.TOTPSecret)
+		if err != nil {
+			return err
+		}
+		step, ok := totp.Validate(secret, code, time.Now(), totpSkew)
+		if !ok || step <= user.TOTPLastStep {
+			return ErrInvalidTOTPCode
+		}
+		lastStep = step
+	} else {
+		hash := hashRecoveryCode(code)
+		remaining := make([]string, 0, recoveryCodeCount)
+		found := false
+		for _, h := range strings.Split(user.TOTPRecoveryCodes, ",") {
+			if h == "" {
+				continue
+			}
+			if !found && hmac.Equal([]byte(h), []byte(hash)) {
+				found = true
+				continue
+			}
+			remaining = append(remaining, h)
+		}
+		if !found {
+			return ErrInvalidTOTPCode
+		}
+		recoveryCodes = strings.Join(remaining, ",")
+	}
+
+	updated, err := s.userRepo.ConsumeTOTP(ctx, user, lastStep, recoveryCodes)
+	if err != nil {
+		return fmt.Errorf("记录两步验证失败: %w", err)
+	}
+	if !updated {
+		return ErrInvalidTOTPCode
+	}
+	return nil
+}
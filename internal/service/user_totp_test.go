@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+	"oss-backend/pkg/encryption"
+	"oss-backend/pkg/totp"
+)
+
+// setTestMasterKey 为测试配置加密主密钥
+func setTestMasterKey(t *testing.T) {
+	t.Helper()
+	viper.Set("encryption.master_key", base64.StdEncoding.EncodeToString(make([]byte, encryption.KeySize)))
+	t.Cleanup(func() { viper.Set("encryption.master_key", "") })
+}
+
+// newTOTPUser 创建已开启两步验证的用户，返回用户、TOTP密钥和恢复码
+func newTOTPUser(t *testing.T, svc *userService) (*entity.User, string, []string) {
+	t.Helper()
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &entity.User{ID: "user-1", Email: "alice@example.com", Name: "alice", Status: entity.UserStatusNormal}
+	if user.TOTPSecret, err = sealTOTPSecret(user.ID, secret); err != nil {
+		t.Fatalf("加密TOTP密钥失败: %v", err)
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.TOTPEnabled = true
+	user.TOTPRecoveryCodes = hashes
+	if err := svc.userRepo.Create(context.Background(), user); err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	return user, secret, codes
+}
+
+func TestTOTPSecretSealing(t *testing.T) {
+	setTestMasterKey(t)
+
+	sealed, err := sealTOTPSecret("user-1", "JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+	if strings.Contains(sealed, "JBSWY3DPEHPK3PXP") {
+		t.Fatalf("密文中包含明文密钥")
+	}
+	again, _ := sealTOTPSecret("user-1", "JBSWY3DPEHPK3PXP")
+	if again == sealed {
+		t.Fatalf("两次加密的结果相同，nonce 未随机生成")
+	}
+	if secret, err := openTOTPSecret("user-1", sealed); err != nil || secret != "JBSWY3DPEHPK3PXP" {
+		t.Fatalf("解密结果为 %q, %v", secret, err)
+	}
+	if _, err := openTOTPSecret("user-2", sealed); err == nil {
+		t.Fatalf("其他用户不能解密该密钥")
+	}
+}
+
+func TestConsumeTOTPCodeRejectsReplay(t *testing.T) {
+	setTestMasterKey(t)
+	db := newTestDB(t, &entity.User{})
+	svc := &userService{userRepo: repository.NewUserRepository(db)}
+	user, secret, _ := newTOTPUser(t, svc)
+
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.consumeTOTPCode(context.Background(), user, code); err != nil {
+		t.Fatalf("首次提交验证码失败: %v", err)
+	}
+	if err := svc.consumeTOTPCode(context.Background(), user, code); !errors.Is(err, ErrInvalidTOTPCode) {
+		t.Fatalf("重复提交同一验证码应失败，实际为 %v", err)
+	}
+
+	// 使用读取时的旧状态并发提交同一验证码也只有一次成功
+	stale, err := svc.userRepo.GetByID(context.Background(), user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.TOTPLastStep = 0
+	if err := svc.consumeTOTPCode(context.Background(), stale, code); !errors.Is(err, ErrInvalidTOTPCode) {
+		t.Fatalf("以过期状态提交验证码应失败，实际为 %v", err)
+	}
+}
+
+func TestConsumeTOTPRecoveryCodeOnce(t *testing.T) {
+	setTestMasterKey(t)
+	db := newTestDB(t, &entity.User{})
+	svc := &userService{userRepo: repository.NewUserRepository(db)}
+	user, _, codes := newTOTPUser(t, svc)
+
+	// 恢复码忽略大小写和分隔符
+	if err := svc.consumeTOTPCode(context.Background(), user, strings.ToUpper(strings.ReplaceAll(codes[0], "-", ""))); err != nil {
+		t.Fatalf("使用恢复码失败: %v", err)
+	}
+	if err := svc.consumeTOTPCode(context.Background(), user, codes[0]); !errors.Is(err, ErrInvalidTOTPCode) {
+		t.Fatalf("恢复码只能使用一次，实际为 %v", err)
+	}
+	if remaining := strings.Count(user.TOTPRecoveryCodes, ",") + 1; remaining != recoveryCodeCount-1 {
+		t.Fatalf("剩余恢复码 %d 个，应为 %d 个", remaining, recoveryCodeCount-1)
+	}
+	if err := svc.consumeTOTPCode(context.Background(), user, codes[1]); err != nil {
+		t.Fatalf("其他恢复码仍应可用: %v", err)
+	}
+}
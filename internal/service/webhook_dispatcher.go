@@ -0,0 +1,305 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"oss-backend/internal/model/dto"
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+	"oss-backend/internal/utils"
+)
+
+// Webhook 推送的默认配置
+const (
+	defaultWebhookQueueSize   = 1024
+	defaultWebhookWorkers     = 4
+	defaultWebhookTimeout     = 10 * time.Second
+	defaultWebhookMaxAttempts = 5
+	defaultWebhookRetryBase   = 5 * time.Second
+	maxWebhookRetryDelay      = 10 * time.Minute
+)
+
+// Webhook 推送的请求头
+const (
+	WebhookSignatureHeader = "X-Signature"        // 请求体的 HMAC-SHA256 签名，十六进制
+	WebhookEventHeader     = "X-Webhook-Event"    // 事件
+	WebhookDeliveryHeader  = "X-Webhook-Delivery" // 推送ID，重试时不变，接收方可用于去重
+	webhookUserAgent       = "OSS-Backend-Webhook/1"
+)
+
+// webhookEvent 待推送的事件，由后台协程查询订阅该事件的Webhook
+type webhookEvent struct {
+	projectID string
+	payload   *dto.WebhookPayload
+}
+
+// webhookDelivery 对一个Webhook的一次推送，失败后按退避间隔重试
+type webhookDelivery struct {
+	webhookID string
+	url       string
+	secret    string
+	event     string
+	id        string
+	body      []byte
+	attempt   int
+}
+
+// webhookTask 队列中的任务，event 与 delivery 二选一
+type webhookTask struct {
+	event    *webhookEvent
+	delivery *webhookDelivery
+}
+
+// WebhookDispatcher Webhook推送队列
+// 文件操作只向队列投递事件，不等待推送；队列满时丢弃事件并记录日志，保证不阻塞原请求。
+// 后台协程按项目配置推送，失败时按指数退避重试，达到 webhook.max_attempts 次后放弃
+type WebhookDispatcher struct {
+	projectRepo repository.ProjectRepository
+	client      *http.Client
+	maxAttempts int
+	retryBase   time.Duration
+
+	mu      sync.RWMutex
+	closed  bool
+	queue   chan webhookTask
+	workers sync.WaitGroup
+}
+
+// NewWebhookDispatcher 创建Webhook推送队列并启动后台协程
+func NewWebhookDispatcher(projectRepo repository.ProjectRepository) *WebhookDispatcher {
+	size := viper.GetInt("webhook.queue_size")
+	if size <= 0 {
+		size = defaultWebhookQueueSize
+	}
+	workers := viper.GetInt("webhook.workers")
+	if workers <= 0 {
+		workers = defaultWebhookWorkers
+	}
+	timeout := defaultWebhookTimeout
+	if seconds := viper.GetInt("webhook.timeout_seconds"); seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	}
+	maxAttempts := viper.GetInt("webhook.max_attempts")
+	if maxAttempts <= 0 {
+		maxAttempts = defaultWebhookMaxAttempts
+	}
+	retryBase := defaultWebhookRetryBase
+	if seconds := viper.GetInt("webhook.retry_base_seconds"); seconds > 0 {
+		retryBase = time.Duration(seconds) * time.Second
+	}
+
+	d := &WebhookDispatcher{
+		projectRepo: projectRepo,
+		client:      newWebhookHTTPClient(timeout, viper.GetBool("webhook.allow_private_networks")),
+		maxAttempts: maxAttempts,
+		retryBase:   retryBase,
+		queue:       make(chan webhookTask, size),
+	}
+	for i := 0; i < workers; i++ {
+		d.workers.Add(1)
+		go d.run()
+	}
+	return d
+}
+
+// newWebhookHTTPClient 创建推送使用的HTTP客户端
+// 默认拒绝连接回环、内网和链路本地地址（包括重定向后的地址），避免项目管理员借助Webhook访问内部服务
+func newWebhookHTTPClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+				ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+				return fmt.Errorf("不允许推送到内部地址 %s", host)
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// Publish 投递一个文件事件，不阻塞调用方
+func (d *WebhookDispatcher) Publish(projectID string, payload *dto.WebhookPayload) {
+	if d == nil {
+		return
+	}
+	if !d.enqueue(webhookTask{event: &webhookEvent{projectID: projectID, payload: payload}}) {
+		log.Printf("Webhook队列已满或已关闭，丢弃项目 %s 的 %s 事件", projectID, payload.Event)
+	}
+}
+
+// enqueue 非阻塞地投递任务，队列满或已关闭时返回 false
+func (d *WebhookDispatcher) enqueue(task webhookTask) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return false
+	}
+	select {
+	case d.queue <- task:
+		return true
+	default:
+		return false
+	}
+}
+
+// Shutdown 停止接收新事件，等待队列中已有的任务处理完毕；等待重试的推送不再执行
+func (d *WebhookDispatcher) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("等待Webhook队列处理超时，剩余 %d 条: %w", len(d.queue), ctx.Err())
+	}
+}
+
+// run 处理队列中的事件和推送
+func (d *WebhookDispatcher) run() {
+	defer d.workers.Done()
+	for task := range d.queue {
+		if task.event != nil {
+			d.expand(task.event)
+		} else {
+			d.deliver(task.delivery)
+		}
+	}
+}
+
+// expand 查询项目中订阅该事件的Webhook，逐个推送
+func (d *WebhookDispatcher) expand(event *webhookEvent) {
+	webhooks, err := d.projectRepo.ListWebhooks(context.Background(), event.projectID)
+	if err != nil {
+		log.Printf("获取项目 %s 的Webhook失败: %v", event.projectID, err)
+		return
+	}
+
+	var body []byte
+	for _, webhook := range webhooks {
+		if !webhook.Enabled || !webhookSubscribed(&webhook, event.payload.Event) {
+			continue
+		}
+		if body == nil {
+			if body, err = json.Marshal(event.payload); err != nil {
+				log.Printf("序列化Webhook事件失败: %v", err)
+				return
+			}
+		}
+		d.deliver(&webhookDelivery{
+			webhookID: webhook.ID,
+			url:       webhook.URL,
+			secret:    webhook.Secret,
+			event:     event.payload.Event,
+			id:        utils.GenerateRecordID(),
+			body:      body,
+		})
+	}
+}
+
+// deliver 推送一次，失败时安排重试
+func (d *WebhookDispatcher) deliver(delivery *webhookDelivery) {
+	delivery.attempt++
+	err := d.send(delivery)
+	if err == nil {
+		return
+	}
+	if delivery.attempt >= d.maxAttempts {
+		log.Printf("Webhook %s 推送 %s 失败，已重试 %d 次，放弃: %v", delivery.webhookID, delivery.id, delivery.attempt, err)
+		return
+	}
+
+	delay := webhookRetryDelay(d.retryBase, delivery.attempt)
+	log.Printf("Webhook %s 推送 %s 失败，%s 后重试: %v", delivery.webhookID, delivery.id, delay, err)
+	time.AfterFunc(delay, func() {
+		if !d.enqueue(webhookTask{delivery: delivery}) {
+			log.Printf("Webhook队列已满或已关闭，放弃重试推送 %s", delivery.id)
+		}
+	})
+}
+
+// send 发送签名的推送请求，2xx 视为成功
+func (d *WebhookDispatcher) send(delivery *webhookDelivery) error {
+	req, err := http.NewRequest(http.MethodPost, delivery.url, bytes.NewReader(delivery.body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", webhookUserAgent)
+	req.Header.Set(WebhookEventHeader, delivery.event)
+	req.Header.Set(WebhookDeliveryHeader, delivery.id)
+	req.Header.Set(WebhookSignatureHeader, SignWebhookBody(delivery.secret, delivery.body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("接收方返回状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SignWebhookBody 计算推送请求体的签名：以Webhook密钥对请求体做 HMAC-SHA256，十六进制编码
+// 接收方用同样的方式计算后与 X-Signature 请求头比较
+func SignWebhookBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookRetryDelay 第 attempt 次失败后的重试间隔，从 base 开始每次翻倍，不超过10分钟
+func webhookRetryDelay(base time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < maxWebhookRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxWebhookRetryDelay)
+}
+
+// webhookSubscribed 判断Webhook是否订阅了事件
+func webhookSubscribed(webhook *entity.ProjectWebhook, event string) bool {
+	for _, subscribed := range strings.Split(webhook.Events, ",") {
+		if subscribed == event {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"oss-backend/internal/model/dto"
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+)
+
+// testWebhookRepo 返回固定Webhook列表的项目仓库
+type testWebhookRepo struct {
+	repository.ProjectRepository
+	webhooks []entity.ProjectWebhook
+}
+
+func (r *testWebhookRepo) ListWebhooks(ctx context.Context, projectID string) ([]entity.ProjectWebhook, error) {
+	return r.webhooks, nil
+}
+
+// webhookRequest 接收方收到的推送
+type webhookRequest struct {
+	path      string
+	event     string
+	delivery  string
+	signature string
+	body      []byte
+}
+
+// TestWebhookSignature 推送的 X-Signature 是以项目密钥对请求体计算的 HMAC-SHA256；
+// 只推送给启用且订阅了该事件的Webhook，失败后以相同的推送ID重试
+func TestWebhookSignature(t *testing.T) {
+	viper.Set("webhook.allow_private_networks", true)
+	viper.Set("webhook.retry_base_seconds", 1)
+	t.Cleanup(func() {
+		viper.Set("webhook.allow_private_networks", false)
+		viper.Set("webhook.retry_base_seconds", 0)
+	})
+
+	var mu sync.Mutex
+	var requests []webhookRequest
+	received := make(chan struct{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, webhookRequest{
+			path:      r.URL.Path,
+			event:     r.Header.Get(WebhookEventHeader),
+			delivery:  r.Header.Get(WebhookDeliveryHeader),
+			signature: r.Header.Get(WebhookSignatureHeader),
+			body:      body,
+		})
+		first := len(requests) == 1
+		mu.Unlock()
+		// 第一次推送失败，触发重试
+		if first {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		received <- struct{}{}
+	}))
+	defer server.Close()
+
+	const secret = "project-secret"
+	repo := &testWebhookRepo{webhooks: []entity.ProjectWebhook{
+		{ID: "hook-1", ProjectID: "project-1", URL: server.URL + "/upload", Secret: secret, Events: "upload,share", Enabled: true},
+		{ID: "hook-2", ProjectID: "project-1", URL: server.URL + "/delete", Secret: "other", Events: "delete", Enabled: true},
+		{ID: "hook-3", ProjectID: "project-1", URL: server.URL + "/disabled", Secret: "other", Events: "upload", Enabled: false},
+	}}
+	dispatcher := NewWebhookDispatcher(repo)
+	defer dispatcher.Shutdown(context.Background())
+
+	dispatcher.Publish("project-1", &dto.WebhookPayload{
+		Event:      "upload",
+		OccurredAt: time.Now(),
+		ProjectID:  "project-1",
+		UserID:     "user-1",
+		File:       dto.WebhookFile{ID: "file-1", FileName: "a.txt", FullPath: "docs/a.txt", FileSize: 7},
+	})
+	for i := 0; i < 2; i++ {
+		select {
+		case <-received:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("5秒内只收到 %d 次推送，应收到首次推送和一次重试", i)
+		}
+	}
+	// 等待可能的多余推送
+	time.Sleep(200 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) != 2 {
+		t.Fatalf("收到 %d 次推送，应为2次", len(requests))
+	}
+	for _, req := range requests {
+		if req.path != "/upload" || req.event != "upload" {
+			t.Fatalf("推送到 %s 的 %s 事件，只应推送给订阅了 upload 的Webhook", req.path, req.event)
+		}
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(req.body)
+		if want := hex.EncodeToString(mac.Sum(nil)); req.signature != want {
+			t.Fatalf("X-Signature 为 %q，应为 %q", req.signature, want)
+		}
+		var payload dto.WebhookPayload
+		if err := json.Unmarshal(req.body, &payload); err != nil {
+			t.Fatalf("推送的请求体无效: %v", err)
+		}
+		if payload.File.FullPath != "docs/a.txt" || payload.UserID != "user-1" {
+			t.Fatalf("推送的内容为 %+v", payload)
+		}
+	}
+	if requests[0].delivery == "" || requests[0].delivery != requests[1].delivery {
+		t.Fatalf("重试的推送ID为 %q，应与首次推送的 %q 相同", requests[1].delivery, requests[0].delivery)
+	}
+
+	// 被篡改的请求体不能通过签名校验
+	if SignWebhookBody(secret, append(requests[0].body, ' ')) == requests[0].signature {
+		t.Fatalf("修改请求体后签名没有变化")
+	}
+}
@@ -14,6 +14,7 @@ type MinioClient interface {
 	ListBuckets(ctx context.Context) ([]miniolib.BucketInfo, error)
 	BucketExists(ctx context.Context, bucketName string) (bool, error)
 	MakeBucket(ctx context.Context, bucketName string) error
+	SetPrefixExpiration(ctx context.Context, bucketName, ruleID, prefix string, days int) error
 
 	// 对象操作
 	ListObjects(ctx context.Context, bucketName, prefix string, recursive bool) <-chan miniolib.ObjectInfo
@@ -32,5 +33,6 @@ type MinioClient interface {
 	GetFileHash(reader io.Reader) (string, error)
 	FileExists(ctx context.Context, bucketName, objectName string) (bool, error)
 	GeneratePreSignedURL(ctx context.Context, bucketName, objectName string, expiry time.Duration) (string, error)
+	GeneratePresignedPutURL(ctx context.Context, bucketName, objectName string, expiry time.Duration) (string, error)
 	GetPublicDownloadURL(ctx context.Context, bucketName, objectName string) (string, error)
 }
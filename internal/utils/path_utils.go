@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrInvalidPath 文件路径无效
+var ErrInvalidPath = errors.New("文件路径无效")
+
+// NormalizeDirPath 将客户端传入的目录路径规范化为文件记录中 file_path 的形式
+// 根目录为空字符串，其他目录不以/开头、以/结尾，如 docs/2024/；
+// 连续的/和 . 段被忽略，以/开头（根目录 / 除外）或包含 .. 时返回 ErrInvalidPath，各段名称的规则同 ValidateName；名称中的空白原样保留。
+// 文件夹的完整路径为目录路径加名称再加/，文件的完整路径为目录路径加名称
+func NormalizeDirPath(p string) (string, error) {
+	if p == "/" {
+		return "", nil
+	}
+	if strings.HasPrefix(p, "/") {
+		return "", fmt.Errorf("%w: 不能以/开头", ErrInvalidPath)
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(p, "/") {
+		if segment == "" || segment == "." {
+			continue
+		}
+		if err := ValidateName(segment); err != nil {
+			return "", err
+		}
+		segments = append(segments, segment)
+	}
+	if len(segments) == 0 {
+		return "", nil
+	}
+	return strings.Join(segments, "/") + "/", nil
+}
+
+// ValidateName 校验单个文件或文件夹名称，无效时返回 ErrInvalidPath
+// 名称不能为空、. 或 ..，不能包含/、\、空字符、控制字符和不可见的格式字符（如零宽字符、文字方向控制符），
+// 也不能使用看起来像/或..的全角及其他Unicode字符，避免名称在显示或其他系统中被解释为路径
+func ValidateName(name string) error {
+	if !utf8.ValidString(name) {
+		return fmt.Errorf("%w: 名称不是有效的UTF-8", ErrInvalidPath)
+	}
+	if name == "" || name == "." || name == ".." {
+		return fmt.Errorf("%w: 名称不能为空、.或..", ErrInvalidPath)
+	}
+
+	var folded strings.Builder
+	for _, r := range name {
+		switch {
+		case r == '/' || r == '\\' || isSlashLike(r):
+			return fmt.Errorf("%w: 名称不能包含/或\\", ErrInvalidPath)
+		case unicode.IsControl(r):
+			return fmt.Errorf("%w: 名称不能包含控制字符", ErrInvalidPath)
+		case unicode.Is(unicode.Cf, r):
+			return fmt.Errorf("%w: 名称不能包含不可见的格式字符", ErrInvalidPath)
+		}
+		folded.WriteString(foldDotLike(r))
+	}
+	if dots := folded.String(); dots == "." || dots == ".." {
+		return fmt.Errorf("%w: 名称不能为.或..", ErrInvalidPath)
+	}
+	return nil
+}
+
+// isSlashLike 判断字符是否为形似/或\的Unicode字符
+func isSlashLike(r rune) bool {
+	switch r {
+	case '\u2044', // ⁄ 分数斜线
+		'\u2215', // ∕ 除法斜线
+		'\u29F8', // ⧸ 大斜线
+		'\uFF0F', // ／ 全角斜线
+		'\u29F9', // ⧹ 大反斜线
+		'\uFF3C': // ＼ 全角反斜线
+		return true
+	}
+	return false
+}
+
+// foldDotLike 将形似.的Unicode字符折叠为对应数量的.，其他字符原样返回
+func foldDotLike(r rune) string {
+	switch r {
+	case '\uFF0E', '\uFF61', '\uFE52', '\u3002': // ．｡﹒。
+		return "."
+	case '\u2025': // ‥
+		return ".."
+	case '\u2026': // …
+		return "..."
+	}
+	return string(r)
+}
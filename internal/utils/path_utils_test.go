@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizeDirPath(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "", want: ""},
+		{in: "/", want: ""},
+		{in: "docs", want: "docs/"},
+		{in: "docs/2024/", want: "docs/2024/"},
+		{in: "a//b", want: "a/b/"},
+		{in: "./a/./b/", want: "a/b/"},
+		{in: " a /b", want: " a /b/"},
+		{in: "../../etc", wantErr: true},
+		{in: "a/../b", wantErr: true},
+		{in: "/etc", wantErr: true},
+		{in: "a\\b", wantErr: true},
+		{in: "a/．．/b", wantErr: true},
+		{in: "a∕b", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeDirPath(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidPath) {
+				t.Errorf("NormalizeDirPath(%q) = %q, %v，应返回 ErrInvalidPath", tt.in, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("NormalizeDirPath(%q) = %q, %v，应为 %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	valid := []string{"report.pdf", ".gitignore", "...", "a..b", "文档 2024", "résumé.txt"}
+	for _, name := range valid {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("ValidateName(%q) = %v，应为有效名称", name, err)
+		}
+	}
+
+	invalid := []string{
+		"", ".", "..",
+		"a/b", "a\\b",
+		"a\x00b", "a\nb", "a\x7fb",
+		"a\u200bb",      // 零宽空格
+		"evil\u202etxt", // 文字方向控制符
+		"\ufeffname",    // 字节顺序标记
+		"a／b",           // 全角斜线
+		"a⁄b",           // 分数斜线
+		"a＼b",           // 全角反斜线
+		"．",             // 全角句点
+		"．．",            // 两个全角句点
+		".。",            // 句点与中文句号
+		"‥",             // 二点省略号
+		"\xff\xfe",      // 无效的UTF-8
+	}
+	for _, name := range invalid {
+		if err := ValidateName(name); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("ValidateName(%q) = %v，应返回 ErrInvalidPath", name, err)
+		}
+	}
+}
@@ -2,14 +2,21 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/casbin/casbin/v2"
 	gormadapter "github.com/casbin/gorm-adapter/v3"
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/viper"
+	"golang.org/x/crypto/bcrypt"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 
@@ -19,9 +26,11 @@ import (
 	_ "oss-backend/internal/controller"
 
 	"oss-backend/internal/controller"
+	"oss-backend/internal/middleware"
 	"oss-backend/internal/model/entity"
 	"oss-backend/internal/repository"
 	"oss-backend/internal/service"
+	"oss-backend/pkg/logger"
 	"oss-backend/pkg/minio"
 )
 
@@ -75,18 +84,50 @@ func main() {
 		log.Fatalf("初始化配置失败: %v", err)
 	}
 
+	// 初始化日志，之后标准库 log 的输出也按配置的格式写入
+	var logConfig logger.Config
+	if err := viper.UnmarshalKey("log", &logConfig); err != nil {
+		log.Fatalf("日志配置错误: %v", err)
+	}
+	if err := logger.Init(logConfig); err != nil {
+		log.Fatalf("日志配置错误: %v", err)
+	}
+
+	// 校验JWT签名密钥和令牌有效期
+	if err := service.ValidateJWTSecret(service.LoadJWTSecret()); err != nil {
+		log.Fatalf("JWT配置错误: %v", err)
+	}
+	if err := service.ValidateTokenTTL(); err != nil {
+		log.Fatalf("JWT配置错误: %v", err)
+	}
+
+	// 校验定时备份配置
+	if _, err := service.LoadBackupTargets(); err != nil {
+		log.Fatalf("备份配置错误: %v", err)
+	}
+
+	// 校验额外存储后端配置
+	if _, err := service.LoadStorageBackends(); err != nil {
+		log.Fatalf("存储后端配置错误: %v", err)
+	}
+
 	// 初始化数据库
 	db, err := initDB()
 	if err != nil {
 		log.Fatalf("初始化数据库失败: %v", err)
 	}
 
-	// 初始化MinIO客户端
+	// 初始化MinIO客户端，存储桶策略在启动时确定
+	bucketLayout, err := minio.NewBucketLayout(viper.GetString("minio.bucket_strategy"), viper.GetString("minio.shared_bucket"))
+	if err != nil {
+		log.Fatalf("存储桶配置错误: %v", err)
+	}
 	minioConfig := minio.Config{
 		Endpoint:  viper.GetString("minio.endpoint"),
 		AccessKey: viper.GetString("minio.access_key"),
 		SecretKey: viper.GetString("minio.secret_key"),
 		UseSSL:    viper.GetBool("minio.use_ssl"),
+		Layout:    bucketLayout,
 	}
 
 	minioClient, err := minio.NewClient(minioConfig)
@@ -102,14 +143,57 @@ func main() {
 
 	// 初始化角色和管理员用户 (传入 Enforcer)
 	if err := initRolesAndAdmin(db, enforcer); err != nil {
+		if errors.Is(err, service.ErrAdminConfigRequired) {
+			log.Fatalf("初始化管理员用户失败: %v", err)
+		}
 		log.Printf("初始化角色和管理员用户失败: %v", err)
 	}
 
-	// 初始化应用
-	r := gin.Default()
+	// 初始化存储统计队列
+	statQueue := service.NewStorageStatQueue(repository.NewStorageStatRepository(db), repository.NewProjectRepository(db))
+
+	// 初始化项目Webhook推送队列
+	webhooks := service.NewWebhookDispatcher(repository.NewProjectRepository(db))
+
+	// 初始化应用，请求日志带有请求ID
+	r := gin.New()
+	r.Use(gin.Recovery(), middleware.RequestLogger())
+
+	// 路由路径匹配：末尾多或少一个/时重定向到已注册的路由（GET为301，其他方法为307），匹配前合并连续的/
+	routing := struct {
+		RedirectTrailingSlash bool `mapstructure:"redirect_trailing_slash"`
+		RemoveExtraSlash      bool `mapstructure:"remove_extra_slash"`
+	}{RedirectTrailingSlash: true, RemoveExtraSlash: true}
+	if err := viper.UnmarshalKey("server", &routing); err != nil {
+		log.Fatalf("服务器配置错误: %v", err)
+	}
+	r.RedirectTrailingSlash = routing.RedirectTrailingSlash
+	r.RemoveExtraSlash = routing.RemoveExtraSlash
+
+	// 安全响应头，未配置的项使用默认值
+	securityConfig := middleware.DefaultSecurityConfig()
+	if err := viper.UnmarshalKey("security", &securityConfig); err != nil {
+		log.Fatalf("安全配置错误: %v", err)
+	}
+	r.Use(middleware.SecurityHeaders(securityConfig))
+
+	// 服务器超时配置，未配置的项使用默认值
+	timeoutConfig := middleware.DefaultServerTimeoutConfig()
+	if err := viper.UnmarshalKey("server", &timeoutConfig); err != nil {
+		log.Fatalf("服务器配置错误: %v", err)
+	}
+
+	// 跨域访问配置，未配置允许的来源时不允许跨域访问
+	corsConfig := middleware.DefaultCorsConfig()
+	if err := viper.UnmarshalKey("cors", &corsConfig); err != nil {
+		log.Fatalf("跨域配置错误: %v", err)
+	}
+	if err := corsConfig.Validate(); err != nil {
+		log.Fatalf("跨域配置错误: %v", err)
+	}
 
 	// 设置路由
-	controller.SetupRouter(r, db, enforcer, minioClient)
+	controller.SetupRouter(r, db, enforcer, minioClient, statQueue, webhooks, time.Duration(timeoutConfig.StreamTimeout)*time.Second, corsConfig)
 
 	// 读取服务器端口配置
 	port := viper.GetInt("server.port")
@@ -118,8 +202,45 @@ func main() {
 	}
 
 	// 启动服务
-	if err := r.Run(fmt.Sprintf(":%d", port)); err != nil {
-		log.Fatalf("启动服务失败: %v", err)
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%d", port),
+		Handler: r,
+	}
+	timeoutConfig.Apply(srv)
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("启动服务失败: %v", err)
+		}
+	}()
+
+	// 等待退出信号，优雅关闭
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	log.Println("正在关闭服务...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	shutdown(ctx, srv, statQueue, webhooks)
+
+	log.Println("服务已退出")
+}
+
+// shutdown 优雅关闭服务：先停止接收新请求并等待处理中的请求（包括下载）完成，
+// 再处理完这些请求产生的存储统计变更和Webhook推送，ctx 到期后不再等待
+func shutdown(ctx context.Context, srv *http.Server, statQueue *service.StorageStatQueue, webhooks *service.WebhookDispatcher) {
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("关闭HTTP服务失败: %v", err)
+	}
+
+	// 处理完剩余的存储统计变更
+	if err := statQueue.Shutdown(ctx); err != nil {
+		log.Printf("关闭存储统计队列失败: %v", err)
+	}
+
+	// 发出队列中剩余的Webhook推送，等待重试的推送不再执行
+	if err := webhooks.Shutdown(ctx); err != nil {
+		log.Printf("关闭Webhook推送队列失败: %v", err)
 	}
 }
 
@@ -176,27 +297,128 @@ func initDB() (*gorm.DB, error) {
 	}
 
 	// 自动迁移表结构
+	// 合并旧版本并发写入产生的重复日统计行，否则无法建立唯一索引
+	if err := dedupeStorageStats(db); err != nil {
+		return nil, fmt.Errorf("清理重复存储统计失败: %w", err)
+	}
+	// 合并旧版本并发创建产生的同路径文件记录，否则无法建立路径唯一索引
+	if err := dedupeFilePaths(db); err != nil {
+		return nil, fmt.Errorf("清理重复文件记录失败: %w", err)
+	}
+
 	err = db.AutoMigrate(
 		&entity.Role{},
 		&entity.User{},
 		&entity.UserRole{},
 		&entity.Log{},
+		&entity.OperationLog{},
+		&entity.LogArchive{},
 		&entity.Project{},
 		&entity.ProjectMember{},
 		&entity.Permission{},
+		&entity.ProjectWebhook{},
 		&entity.File{},
 		&entity.FileVersion{},
 		&entity.FileShare{},
+		&entity.FileTag{},
+		&entity.FileDeny{},
+		&entity.FolderPermission{},
 		&entity.Group{},
 		&entity.GroupMember{},
+		&entity.GroupInvitation{},
+		&entity.GroupJoinRequest{},
+		&entity.PolicyImportRecord{},
+		&entity.StorageStat{},
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	// 迁移历史明文分享密码
+	if err := migrateSharePasswords(db); err != nil {
+		return nil, fmt.Errorf("迁移分享密码失败: %w", err)
+	}
+
+	// 清除上次进程退出时遗留的存储配额预占
+	if err := resetStorageReservations(db); err != nil {
+		return nil, fmt.Errorf("清除存储配额预占失败: %w", err)
+	}
+
 	return db, nil
 }
 
+// 迁移分享密码：将历史版本保存的明文密码转换为bcrypt哈希
+func migrateSharePasswords(db *gorm.DB) error {
+	var shares []entity.FileShare
+	err := db.Where("password <> '' AND password NOT LIKE ?", "$2%").Find(&shares).Error
+	if err != nil {
+		return err
+	}
+
+	for _, share := range shares {
+		hash, err := bcrypt.GenerateFromPassword([]byte(share.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		err = db.Model(&entity.FileShare{}).Where("id = ?", share.ID).Update("password", string(hash)).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(shares) > 0 {
+		log.Printf("已将 %d 条分享密码迁移为哈希存储", len(shares))
+	}
+	return nil
+}
+
+// resetStorageReservations 清零项目与群组的存储配额预占量
+// 预占只存在于进行中的写入期间，启动时没有进行中的写入，非零值是上次进程异常退出时未释放的
+func resetStorageReservations(db *gorm.DB) error {
+	for _, table := range []string{entity.Project{}.TableName(), entity.Group{}.TableName()} {
+		err := db.Table(table).Where("storage_reserved <> 0").UpdateColumn("storage_reserved", 0).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dedupeStorageStats 删除同一项目同一天的重复统计行，只保留最新一条
+// 被删除行的数据可通过统计重算任务恢复
+func dedupeStorageStats(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&entity.StorageStat{}) {
+		return nil
+	}
+	return db.Exec(`DELETE s1 FROM storage_stats s1
+		JOIN storage_stats s2
+		ON s1.project_id = s2.project_id AND s1.stat_date = s2.stat_date
+		AND (s1.created_at < s2.created_at OR (s1.created_at = s2.created_at AND s1.id < s2.id))`).Error
+}
+
+// dedupeFilePaths 将同一项目内路径相同的未删除文件或文件夹标记为已删除，只保留最早创建的一条
+// 只在路径唯一索引建立之前执行；被标记的记录可在回收站中查看，同名记录存在时无法恢复
+func dedupeFilePaths(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&entity.File{}) || db.Migrator().HasIndex(&entity.File{}, "idx_project_live_path") {
+		return nil
+	}
+	result := db.Exec(`UPDATE files f1
+		JOIN files f2
+		ON f1.project_id = f2.project_id
+		AND BINARY TRIM(TRAILING '/' FROM f1.full_path) = BINARY TRIM(TRAILING '/' FROM f2.full_path)
+		AND f2.is_deleted = FALSE AND f2.gorm_deleted_at IS NULL
+		AND (f1.created_at > f2.created_at OR (f1.created_at = f2.created_at AND f1.id > f2.id))
+		SET f1.is_deleted = TRUE, f1.deleted_at = NOW()
+		WHERE f1.is_deleted = FALSE AND f1.gorm_deleted_at IS NULL`)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("已将 %d 条重复路径的文件记录标记为删除", result.RowsAffected)
+	}
+	return nil
+}
+
 // 初始化 Casbin Enforcer
 func initCasbin(db *gorm.DB) (*casbin.Enforcer, error) {
 	// 1. 创建 Gorm Adapter
@@ -237,7 +459,7 @@ func initRolesAndAdmin(db *gorm.DB, enforcer *casbin.Enforcer) error {
 	// 初始化服务 (传入 Enforcer)
 	casbinRepo := repository.NewCasbinRepository(db)
 	authService := service.NewAuthService(enforcer, roleRepo, userRepo, casbinRepo, db)
-	userService := service.NewUserService(userRepo, roleRepo, authService)
+	userService := service.NewUserService(userRepo, roleRepo, authService, service.NewMemoryTokenBlacklist(), service.NewMemoryLoginLimiter(), service.NewMailer(), nil)
 
 	// 初始化系统管理员用户
 	return userService.InitAdminUser(ctx)
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"oss-backend/internal/model/entity"
+	"oss-backend/internal/repository"
+	"oss-backend/internal/service"
+
+	"github.com/glebarez/sqlite"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// testProjectRepo 只包含一个项目的项目仓库
+type testProjectRepo struct {
+	repository.ProjectRepository
+}
+
+func (testProjectRepo) GetByID(ctx context.Context, id string) (*entity.Project, error) {
+	return &entity.Project{ID: id, GroupID: "group-1"}, nil
+}
+
+// testStatRepo 记录写入的存储统计变更
+type testStatRepo struct {
+	repository.StorageStatRepository
+	mu         sync.Mutex
+	sizeDeltas []int64
+}
+
+func (r *testStatRepo) GetProjectTotalStats(ctx context.Context, projectID string) (int64, int64, error) {
+	return 0, 0, nil
+}
+
+func (r *testStatRepo) UpsertDailyStat(ctx context.Context, stat *entity.StorageStat, increaseDelta int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sizeDeltas = append(r.sizeDeltas, increaseDelta)
+	return nil
+}
+
+// TestShutdownDrainsInFlightDownload 关闭服务时正在进行的下载完整返回，下载产生的存储统计变更在退出前写入
+func TestShutdownDrainsInFlightDownload(t *testing.T) {
+	statRepo := &testStatRepo{}
+	statQueue := service.NewStorageStatQueue(statRepo, testProjectRepo{})
+	webhooks := service.NewWebhookDispatcher(testProjectRepo{})
+
+	const chunk = "0123456789"
+	const chunks = 5
+	started := make(chan struct{})
+	shuttingDown := make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(chunk))
+		w.(http.Flusher).Flush()
+		close(started)
+		// 开始关闭后才发送剩余内容
+		<-shuttingDown
+		for i := 1; i < chunks; i++ {
+			time.Sleep(20 * time.Millisecond)
+			w.Write([]byte(chunk))
+			w.(http.Flusher).Flush()
+		}
+		statQueue.Enqueue("project-1", 0, int64(chunks*len(chunk)))
+	})}
+	srv.RegisterOnShutdown(func() { close(shuttingDown) })
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(listener)
+	url := "http://" + listener.Addr().String() + "/download"
+
+	type result struct {
+		body string
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		done <- result{body: string(body), err: err}
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	shutdown(ctx, srv, statQueue, webhooks)
+
+	got := <-done
+	if got.err != nil {
+		t.Fatalf("下载中断: %v", got.err)
+	}
+	if want := strings.Repeat(chunk, chunks); got.body != want {
+		t.Fatalf("下载内容为 %q，应为 %q", got.body, want)
+	}
+
+	statRepo.mu.Lock()
+	deltas := statRepo.sizeDeltas
+	statRepo.mu.Unlock()
+	if len(deltas) != 1 || deltas[0] != int64(chunks*len(chunk)) {
+		t.Fatalf("关闭后写入的存储统计变更为 %v，应为下载结束时投递的一条", deltas)
+	}
+
+	// 关闭后不再接受新请求
+	if resp, err := http.Get(url); err == nil {
+		resp.Body.Close()
+		t.Fatalf("关闭后仍接受新请求")
+	}
+}
+
+// TestMigrateSharePasswords 明文分享密码迁移为哈希，空密码保持无需密码，已是哈希的不重复处理
+func TestMigrateSharePasswords(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("CREATE TABLE file_shares (id TEXT PRIMARY KEY, password TEXT, updated_at DATETIME)").Error; err != nil {
+		t.Fatal(err)
+	}
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("hashed1"), bcrypt.MinCost)
+	shares := map[string]string{"open": "", "plain": "secret1", "hashed": string(hashed)}
+	for id, password := range shares {
+		db.Exec("INSERT INTO file_shares (id, password) VALUES (?, ?)", id, password)
+	}
+
+	if err := migrateSharePasswords(db); err != nil {
+		t.Fatalf("迁移失败: %v", err)
+	}
+
+	stored := make(map[string]string)
+	rows, err := db.Raw("SELECT id, password FROM file_shares").Rows()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var id, password string
+		rows.Scan(&id, &password)
+		stored[id] = password
+	}
+	rows.Close()
+
+	if stored["open"] != "" {
+		t.Fatalf("空密码被迁移为 %q", stored["open"])
+	}
+	if bcrypt.CompareHashAndPassword([]byte(stored["plain"]), []byte("secret1")) != nil {
+		t.Fatalf("明文密码迁移后为 %q，应为原密码的哈希", stored["plain"])
+	}
+	if stored["hashed"] != string(hashed) {
+		t.Fatalf("已是哈希的密码被重复处理")
+	}
+}
@@ -0,0 +1,304 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// 对象加密格式：明文按 ChunkSize 分块，每块以 AES-256-GCM 单独加密并附带16字节认证标签。
+// 第 i 块的 nonce 为对象的8字节随机前缀加上4字节大端序块序号，最后一块以附加数据标记，
+// 可发现分块被截断、重排或替换；空文件也写入一个空的最后一块。
+const (
+	Version         = 1        // 当前加密格式版本，记录在文件上，0表示未加密
+	KeySize         = 32       // AES-256 密钥长度
+	NoncePrefixSize = 8        // 每个对象随机生成的 nonce 前缀长度
+	ChunkSize       = 64 << 10 // 明文分块大小
+	tagSize         = 16
+	sealedChunkSize = ChunkSize + tagSize
+)
+
+// ErrCorrupted 密文被篡改、截断或使用了错误的密钥
+var ErrCorrupted = errors.New("加密对象已损坏或密钥不匹配")
+
+// NewNoncePrefix 为新对象生成随机 nonce 前缀
+func NewNoncePrefix() ([]byte, error) {
+	prefix := make([]byte, NoncePrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+	return prefix, nil
+}
+
+// EncryptedSize 明文加密后的对象大小
+func EncryptedSize(size int64) int64 {
+	chunks := (size + ChunkSize - 1) / ChunkSize
+	if chunks == 0 {
+		chunks = 1
+	}
+	return size + chunks*tagSize
+}
+
+// PlainSize 由对象大小计算明文大小
+func PlainSize(encryptedSize int64) (int64, error) {
+	chunks := (encryptedSize + sealedChunkSize - 1) / sealedChunkSize
+	size := encryptedSize - chunks*tagSize
+	if chunks == 0 || size < 0 {
+		return 0, ErrCorrupted
+	}
+	return size, nil
+}
+
+// newAEAD 创建 AES-256-GCM
+func newAEAD(key, prefix []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("密钥长度应为%d字节", KeySize)
+	}
+	if len(prefix) != NoncePrefixSize {
+		return nil, fmt.Errorf("nonce 前缀长度应为%d字节", NoncePrefixSize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce 第 index 块的 nonce
+func chunkNonce(nonce, prefix []byte, index uint32) []byte {
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[NoncePrefixSize:], index)
+	return nonce
+}
+
+// chunkAD 分块的附加数据，标记是否为最后一块
+func chunkAD(final bool) []byte {
+	if final {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+// encryptReader 边读取明文边加密
+type encryptReader struct {
+	src    io.Reader
+	aead   cipher.AEAD
+	prefix []byte
+	nonce  []byte
+	index  uint32
+	buf    []byte // 一块明文，多读的一个字节用于判断是否还有下一块
+	ahead  bool   // buf[0] 是上次多读的字节
+	out    []byte
+	done   bool
+}
+
+// NewEncryptReader 返回读取 src 明文加密结果的 Reader，输出大小为 EncryptedSize(明文大小)
+func NewEncryptReader(src io.Reader, key, prefix []byte) (io.Reader, error) {
+	aead, err := newAEAD(key, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return &encryptReader{
+		src:    src,
+		aead:   aead,
+		prefix: prefix,
+		nonce:  make([]byte, aead.NonceSize()),
+		buf:    make([]byte, ChunkSize+1),
+		out:    make([]byte, 0, sealedChunkSize),
+	}, nil
+}
+
+// Read 实现 io.Reader
+func (r *encryptReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.sealNext(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// sealNext 读取并加密下一块
+func (r *encryptReader) sealNext() error {
+	start := 0
+	if r.ahead {
+		start = 1
+	}
+	n, err := io.ReadFull(r.src, r.buf[start:])
+	total := start + n
+	final := false
+	switch {
+	case err == nil:
+		total = ChunkSize
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		final = true
+	default:
+		return err
+	}
+	if !final && r.index == ^uint32(0) {
+		return errors.New("文件过大，超出加密分块数量上限")
+	}
+
+	r.out = r.aead.Seal(r.out[:0], chunkNonce(r.nonce, r.prefix, r.index), r.buf[:total], chunkAD(final))
+	r.index++
+	r.done = final
+	if !final {
+		r.buf[0] = r.buf[ChunkSize]
+		r.ahead = true
+	}
+	return nil
+}
+
+// decryptReader 边读取密文边解密，底层支持 io.Seeker 时可按明文位置随机读取
+type decryptReader struct {
+	src    io.Reader
+	seeker io.Seeker
+	aead   cipher.AEAD
+	prefix []byte
+	nonce  []byte
+	index  uint32
+	buf    []byte // 一块密文，多读的一个字节用于判断是否为最后一块
+	ahead  bool
+	out    []byte // 解密缓冲区
+	plain  []byte // 尚未读出的明文
+	done   bool
+	size   int64 // 明文大小，底层不支持随机读取时为 -1
+	pos    int64 // 当前明文位置
+}
+
+// NewDecryptReader 返回解密 src 的 Reader；src 实现 io.ReadSeeker 时返回值同样实现 io.ReadSeeker
+// 认证失败时 Read 返回 ErrCorrupted，调用方在读完之前不能把已读出的内容当作完整可信的文件
+func NewDecryptReader(src io.Reader, key, prefix []byte) (io.Reader, error) {
+	aead, err := newAEAD(key, prefix)
+	if err != nil {
+		return nil, err
+	}
+	r := &decryptReader{
+		src:    src,
+		aead:   aead,
+		prefix: prefix,
+		nonce:  make([]byte, aead.NonceSize()),
+		buf:    make([]byte, sealedChunkSize+1),
+		out:    make([]byte, 0, ChunkSize),
+		size:   -1,
+	}
+	seeker, ok := src.(io.Seeker)
+	if !ok {
+		return r, nil
+	}
+	end, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if r.size, err = PlainSize(end); err != nil {
+		return nil, err
+	}
+	r.seeker = seeker
+	return &seekableDecryptReader{r}, nil
+}
+
+// Read 实现 io.Reader
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.openNext(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	r.pos += int64(n)
+	return n, nil
+}
+
+// openNext 读取并解密下一块
+func (r *decryptReader) openNext() error {
+	start := 0
+	if r.ahead {
+		start = 1
+	}
+	n, err := io.ReadFull(r.src, r.buf[start:])
+	total := start + n
+	final := false
+	switch {
+	case err == nil:
+		total = sealedChunkSize
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		final = true
+	default:
+		return err
+	}
+	if total < tagSize {
+		return ErrCorrupted
+	}
+
+	plain, err := r.aead.Open(r.out[:0], chunkNonce(r.nonce, r.prefix, r.index), r.buf[:total], chunkAD(final))
+	if err != nil {
+		return ErrCorrupted
+	}
+	r.plain = plain
+	r.index++
+	r.done = final
+	r.ahead = !final
+	if !final {
+		r.buf[0] = r.buf[sealedChunkSize]
+	}
+	return nil
+}
+
+// seekableDecryptReader 支持随机读取的解密 Reader
+type seekableDecryptReader struct {
+	*decryptReader
+}
+
+// Seek 实现 io.Seeker，定位到目标所在分块的开头重新解密
+func (r *seekableDecryptReader) Seek(offset int64, whence int) (int64, error) {
+	var target int64
+	switch whence {
+	case io.SeekStart:
+		target = offset
+	case io.SeekCurrent:
+		target = r.pos + offset
+	case io.SeekEnd:
+		target = r.size + offset
+	default:
+		return 0, errors.New("无效的 whence")
+	}
+	if target < 0 {
+		return 0, errors.New("位置不能为负数")
+	}
+
+	r.plain = nil
+	r.ahead = false
+	r.pos = target
+	if target >= r.size {
+		r.done = true
+		return target, nil
+	}
+
+	index := target / ChunkSize
+	if _, err := r.seeker.Seek(index*sealedChunkSize, io.SeekStart); err != nil {
+		return 0, err
+	}
+	r.index = uint32(index)
+	r.done = false
+	if err := r.openNext(); err != nil {
+		return 0, err
+	}
+	r.plain = r.plain[target-index*ChunkSize:]
+	return target, nil
+}
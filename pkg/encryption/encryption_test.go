@@ -0,0 +1,160 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+)
+
+func testKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func seal(t *testing.T, plain, key, prefix []byte) []byte {
+	t.Helper()
+	reader, err := NewEncryptReader(bytes.NewReader(plain), key, prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("加密失败: %v", err)
+	}
+	return sealed
+}
+
+func open(src io.Reader, key, prefix []byte) ([]byte, error) {
+	reader, err := NewDecryptReader(src, key, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}
+
+func TestRoundTrip(t *testing.T) {
+	key := testKey(t)
+	for _, size := range []int{0, 1, ChunkSize - 1, ChunkSize, ChunkSize + 1, 3*ChunkSize + 5} {
+		plain := make([]byte, size)
+		rand.Read(plain)
+		prefix, err := NewNoncePrefix()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sealed := seal(t, plain, key, prefix)
+		if int64(len(sealed)) != EncryptedSize(int64(size)) {
+			t.Errorf("大小 %d: 密文 %d 字节，EncryptedSize 为 %d", size, len(sealed), EncryptedSize(int64(size)))
+		}
+		if got, err := PlainSize(int64(len(sealed))); err != nil || got != int64(size) {
+			t.Errorf("大小 %d: PlainSize 为 %d, %v", size, got, err)
+		}
+		if size > 0 && bytes.Contains(sealed, plain[:min(size, 32)]) {
+			t.Errorf("大小 %d: 密文中包含明文", size)
+		}
+
+		// 不支持随机读取与支持随机读取的底层 Reader 都能解密
+		streamed, err := open(io.MultiReader(bytes.NewReader(sealed)), key, prefix)
+		if err != nil || !bytes.Equal(streamed, plain) {
+			t.Errorf("大小 %d: 顺序解密结果不一致, %v", size, err)
+		}
+		seekable, err := open(bytes.NewReader(sealed), key, prefix)
+		if err != nil || !bytes.Equal(seekable, plain) {
+			t.Errorf("大小 %d: 随机读取解密结果不一致, %v", size, err)
+		}
+	}
+}
+
+func TestNoncePrefixUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		prefix, err := NewNoncePrefix()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(prefix) != NoncePrefixSize {
+			t.Fatalf("nonce 前缀长度为 %d", len(prefix))
+		}
+		if seen[string(prefix)] {
+			t.Fatalf("nonce 前缀重复")
+		}
+		seen[string(prefix)] = true
+	}
+
+	// 同一密钥、同一内容使用不同前缀加密时密文不同
+	key := testKey(t)
+	plain := []byte("same content")
+	a, _ := NewNoncePrefix()
+	b, _ := NewNoncePrefix()
+	if bytes.Equal(seal(t, plain, key, a), seal(t, plain, key, b)) {
+		t.Fatalf("不同 nonce 前缀的密文相同")
+	}
+}
+
+func TestDecryptRejectsTampering(t *testing.T) {
+	key := testKey(t)
+	prefix, _ := NewNoncePrefix()
+	plain := make([]byte, 2*ChunkSize+10)
+	rand.Read(plain)
+	sealed := seal(t, plain, key, prefix)
+
+	flipped := append([]byte(nil), sealed...)
+	flipped[ChunkSize+100] ^= 1
+	// 去掉最后一块后，剩余的块都不是以最后一块的附加数据加密的
+	truncated := sealed[:2*sealedChunkSize]
+	swapped := append(append(append([]byte(nil), sealed[sealedChunkSize:2*sealedChunkSize]...), sealed[:sealedChunkSize]...), sealed[2*sealedChunkSize:]...)
+	otherPrefix, _ := NewNoncePrefix()
+
+	cases := map[string]func() ([]byte, error){
+		"篡改":    func() ([]byte, error) { return open(bytes.NewReader(flipped), key, prefix) },
+		"截断":    func() ([]byte, error) { return open(bytes.NewReader(truncated), key, prefix) },
+		"重排":    func() ([]byte, error) { return open(bytes.NewReader(swapped), key, prefix) },
+		"错误的密钥": func() ([]byte, error) { return open(bytes.NewReader(sealed), testKey(t), prefix) },
+		"错误的前缀": func() ([]byte, error) { return open(bytes.NewReader(sealed), key, otherPrefix) },
+		"空密文":   func() ([]byte, error) { return open(io.MultiReader(), key, prefix) },
+	}
+	for name, decrypt := range cases {
+		if _, err := decrypt(); !errors.Is(err, ErrCorrupted) {
+			t.Errorf("%s: 应返回 ErrCorrupted，实际为 %v", name, err)
+		}
+	}
+}
+
+func TestDecryptSeek(t *testing.T) {
+	key := testKey(t)
+	prefix, _ := NewNoncePrefix()
+	plain := make([]byte, 3*ChunkSize+17)
+	rand.Read(plain)
+	sealed := seal(t, plain, key, prefix)
+
+	reader, err := NewDecryptReader(bytes.NewReader(sealed), key, prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seeker, ok := reader.(io.ReadSeeker)
+	if !ok {
+		t.Fatalf("底层支持随机读取时解密结果应实现 io.ReadSeeker")
+	}
+	for _, offset := range []int64{0, 5, ChunkSize - 1, ChunkSize, 2*ChunkSize + 3, int64(len(plain)) - 1} {
+		if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+			t.Fatalf("定位到 %d 失败: %v", offset, err)
+		}
+		buf := make([]byte, 10)
+		n, err := io.ReadFull(seeker, buf)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Fatalf("从 %d 读取失败: %v", offset, err)
+		}
+		if !bytes.Equal(buf[:n], plain[offset:offset+int64(n)]) {
+			t.Errorf("从 %d 读取的内容不一致", offset)
+		}
+	}
+	if end, err := seeker.Seek(0, io.SeekEnd); err != nil || end != int64(len(plain)) {
+		t.Errorf("定位到末尾返回 %d, %v，应为明文大小 %d", end, err, len(plain))
+	}
+}
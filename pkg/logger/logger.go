@@ -0,0 +1,90 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// ContextKey 请求日志记录器在 gin.Context 中的键，服务层通过 FromContext 取出
+const ContextKey = "logger"
+
+// contextKey 日志记录器在 context.Context 中的键
+type contextKey struct{}
+
+// Config 日志配置
+type Config struct {
+	Level  string `mapstructure:"level"`  // debug、info、warn、error，默认 info
+	Format string `mapstructure:"format"` // text 或 json，默认 json
+	Output string `mapstructure:"output"` // stdout、stderr 或文件路径，默认 stdout
+}
+
+// Init 按配置创建默认日志记录器，标准库 log 包的输出也写入同一位置
+func Init(cfg Config) error {
+	var level slog.Level
+	switch strings.ToLower(cfg.Level) {
+	case "", "info":
+		level = slog.LevelInfo
+	case "debug":
+		level = slog.LevelDebug
+	case "warn":
+		level = slog.LevelWarn
+	case "error":
+		level = slog.LevelError
+	default:
+		return fmt.Errorf("不支持的日志级别: %s", cfg.Level)
+	}
+
+	var output io.Writer
+	switch cfg.Output {
+	case "", "stdout":
+		output = os.Stdout
+	case "stderr":
+		output = os.Stderr
+	default:
+		file, err := os.OpenFile(cfg.Output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return fmt.Errorf("打开日志文件失败: %w", err)
+		}
+		output = file
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "", "json":
+		handler = slog.NewJSONHandler(output, options)
+	case "text":
+		handler = slog.NewTextHandler(output, options)
+	default:
+		return fmt.Errorf("不支持的日志格式: %s", cfg.Format)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	// SetDefault 会把标准库 log 的输出转到 slog，按 info 级别记录
+	log.SetFlags(0)
+	return nil
+}
+
+// WithContext 返回带有日志记录器的 context
+func WithContext(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext 取出请求的日志记录器，其中带有请求ID等字段；没有时返回默认记录器
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+			return l
+		}
+		// 控制器把 gin.Context 直接作为 context 传给服务层，其 Value 按字符串键读取 gin.Context 中的值
+		if l, ok := ctx.Value(ContextKey).(*slog.Logger); ok {
+			return l
+		}
+	}
+	return slog.Default()
+}
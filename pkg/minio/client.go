@@ -6,12 +6,15 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"net/url"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
 
 	"oss-backend/internal/utils"
 )
@@ -25,11 +28,15 @@ type Config struct {
 	AccessKey string
 	SecretKey string
 	UseSSL    bool
+	Layout    BucketLayout // 存储桶布局，默认每个逻辑存储桶对应一个实际存储桶
 }
 
 // Client MinIO客户端包装
 type Client struct {
 	client *minio.Client
+	layout BucketLayout
+	// lifecycleMu 串行修改生命周期配置，共享存储桶模式下各逻辑存储桶的规则保存在同一配置中
+	lifecycleMu sync.Mutex
 }
 
 // NewClient 创建新的MinIO客户端
@@ -43,7 +50,12 @@ func NewClient(cfg Config) (*Client, error) {
 		return nil, err
 	}
 
-	return &Client{client: mc}, nil
+	return &Client{client: mc, layout: cfg.Layout}, nil
+}
+
+// Layout 获取客户端使用的存储桶布局
+func (c *Client) Layout() BucketLayout {
+	return c.layout
 }
 
 // PutObjectOptions 上传对象选项
@@ -68,6 +80,7 @@ func (c *Client) PutObject(ctx context.Context, bucketName, objectName string, r
 	}
 
 	// 上传对象
+	bucketName, objectName = c.layout.Locate(bucketName, objectName)
 	_, err := c.client.PutObject(ctx, bucketName, objectName, reader, size, options)
 	return err
 }
@@ -78,6 +91,7 @@ func (c *Client) GetObject(ctx context.Context, bucketName, objectName string, o
 	options := minio.GetObjectOptions{}
 
 	// 获取对象
+	bucketName, objectName = c.layout.Locate(bucketName, objectName)
 	obj, err := c.client.GetObject(ctx, bucketName, objectName, options)
 	if err != nil {
 		return nil, err
@@ -86,42 +100,134 @@ func (c *Client) GetObject(ctx context.Context, bucketName, objectName string, o
 	return obj, nil
 }
 
-// ListBuckets 列出所有桶
+// ListBuckets 列出所有实际存储桶，共享存储桶模式下不列出逻辑存储桶
 func (c *Client) ListBuckets(ctx context.Context) ([]minio.BucketInfo, error) {
 	return c.client.ListBuckets(ctx)
 }
 
-// MakeBucket 创建存储桶
+// MakeBucket 创建存储桶，共享存储桶模式下创建逻辑存储桶
 func (c *Client) MakeBucket(ctx context.Context, bucketName string) error {
+	if c.layout.Shared() {
+		return c.CreateBucketIfNotExists(ctx, bucketName)
+	}
 	return c.client.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{})
 }
 
 // BucketExists 检查存储桶是否存在
+// 共享存储桶模式下检查逻辑存储桶的标记对象，标记对象在创建逻辑存储桶时写入
 func (c *Client) BucketExists(ctx context.Context, bucketName string) (bool, error) {
+	if c.layout.Shared() {
+		return c.FileExists(ctx, bucketName, "")
+	}
 	return c.client.BucketExists(ctx, bucketName)
 }
 
 // RemoveObject 删除对象
 func (c *Client) RemoveObject(ctx context.Context, bucketName, objectName string) error {
+	bucketName, objectName = c.layout.Locate(bucketName, objectName)
 	return c.client.RemoveObject(ctx, bucketName, objectName, minio.RemoveObjectOptions{})
 }
 
+// CopyObject 在同一存储桶内复制对象，contentType 非空时替换目标对象的内容类型
+func (c *Client) CopyObject(ctx context.Context, bucketName, srcObject, dstObject, contentType string) error {
+	_, srcObject = c.layout.Locate(bucketName, srcObject)
+	bucketName, dstObject = c.layout.Locate(bucketName, dstObject)
+	dst := minio.CopyDestOptions{
+		Bucket: bucketName,
+		Object: dstObject,
+	}
+	if contentType != "" {
+		dst.ReplaceMetadata = true
+		dst.UserMetadata = map[string]string{"Content-Type": contentType}
+	}
+	_, err := c.client.CopyObject(ctx, dst, minio.CopySrcOptions{
+		Bucket: bucketName,
+		Object: srcObject,
+	})
+	return err
+}
+
+// CopyObjectToBucket 在服务端将对象复制到另一个存储桶（也可以是同一存储桶）
+func (c *Client) CopyObjectToBucket(ctx context.Context, srcBucket, srcObject, dstBucket, dstObject string) error {
+	srcBucket, srcObject = c.layout.Locate(srcBucket, srcObject)
+	dstBucket, dstObject = c.layout.Locate(dstBucket, dstObject)
+	_, err := c.client.CopyObject(ctx, minio.CopyDestOptions{
+		Bucket: dstBucket,
+		Object: dstObject,
+	}, minio.CopySrcOptions{
+		Bucket: srcBucket,
+		Object: srcObject,
+	})
+	return err
+}
+
 // StatObject 获取对象信息
 func (c *Client) StatObject(ctx context.Context, bucketName, objectName string, opts interface{}) (minio.ObjectInfo, error) {
 	options := minio.StatObjectOptions{}
-	return c.client.StatObject(ctx, bucketName, objectName, options)
+	logicalBucket := bucketName
+	bucketName, objectName = c.layout.Locate(bucketName, objectName)
+	info, err := c.client.StatObject(ctx, bucketName, objectName, options)
+	info.Key = c.layout.ObjectName(logicalBucket, info.Key)
+	return info, err
 }
 
-// ListObjects 列出对象
+// ListObjects 列出对象，返回的对象名称相对于逻辑存储桶
 func (c *Client) ListObjects(ctx context.Context, bucketName, prefix string, recursive bool) <-chan minio.ObjectInfo {
-	return c.client.ListObjects(ctx, bucketName, minio.ListObjectsOptions{
-		Prefix:    prefix,
+	actualBucket, actualPrefix := c.layout.Locate(bucketName, prefix)
+	objects := c.client.ListObjects(ctx, actualBucket, minio.ListObjectsOptions{
+		Prefix:    actualPrefix,
 		Recursive: recursive,
 	})
+	if !c.layout.Shared() {
+		return objects
+	}
+
+	// 还原对象名称并跳过逻辑存储桶的标记对象
+	out := make(chan minio.ObjectInfo)
+	go func() {
+		defer close(out)
+		for object := range objects {
+			if object.Err == nil {
+				object.Key = c.layout.ObjectName(bucketName, object.Key)
+				if object.Key == "" {
+					continue
+				}
+			}
+			select {
+			case out <- object:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
 }
 
 // CreateBucketIfNotExists 如果存储桶不存在，则创建
+// 共享存储桶模式下创建共享存储桶（如不存在）并写入逻辑存储桶的标记对象
 func (c *Client) CreateBucketIfNotExists(ctx context.Context, bucketName string) error {
+	if c.layout.Shared() {
+		exists, err := c.BucketExists(ctx, bucketName)
+		if err != nil {
+			return fmt.Errorf("检查存储桶是否存在失败: %w", err)
+		}
+		if exists {
+			return nil
+		}
+		if err := c.createBucket(ctx, c.layout.SharedBucket); err != nil {
+			return err
+		}
+		actualBucket, marker := c.layout.Locate(bucketName, "")
+		if _, err := c.client.PutObject(ctx, actualBucket, marker, strings.NewReader(""), 0, minio.PutObjectOptions{}); err != nil {
+			return fmt.Errorf("创建存储桶失败: %w", err)
+		}
+		return nil
+	}
+	return c.createBucket(ctx, bucketName)
+}
+
+// createBucket 实际存储桶不存在时创建
+func (c *Client) createBucket(ctx context.Context, bucketName string) error {
 	// 检查存储桶是否存在
 	exists, err := c.client.BucketExists(ctx, bucketName)
 	if err != nil {
@@ -146,6 +252,137 @@ func (c *Client) CreateBucketIfNotExists(ctx context.Context, bucketName string)
 	return nil
 }
 
+// SetPrefixExpiration 设置存储桶中 prefix 下的对象在 days 天后过期删除，days 为0时移除该规则
+// 规则以 ruleID 标识，重复设置时替换同一规则，存储桶上的其他生命周期规则保持不变；
+// 共享存储桶模式下规则设置在共享存储桶上，每个逻辑存储桶一条规则
+func (c *Client) SetPrefixExpiration(ctx context.Context, bucketName, ruleID, prefix string, days int) error {
+	c.lifecycleMu.Lock()
+	defer c.lifecycleMu.Unlock()
+
+	if c.layout.Shared() {
+		ruleID = ruleID + ":" + bucketName
+	}
+	bucketName, prefix = c.layout.Locate(bucketName, prefix)
+	config, err := c.client.GetBucketLifecycle(ctx, bucketName)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code != "NoSuchLifecycleConfiguration" {
+			return fmt.Errorf("获取存储桶生命周期规则失败: %w", err)
+		}
+		config = lifecycle.NewConfiguration()
+	}
+
+	rules := make([]lifecycle.Rule, 0, len(config.Rules)+1)
+	for _, rule := range config.Rules {
+		if rule.ID != ruleID {
+			rules = append(rules, rule)
+		}
+	}
+	if days > 0 {
+		rules = append(rules, lifecycle.Rule{
+			ID:         ruleID,
+			Status:     "Enabled",
+			RuleFilter: lifecycle.Filter{Prefix: prefix},
+			Expiration: lifecycle.Expiration{Days: lifecycle.ExpirationDays(days)},
+		})
+	}
+	config.Rules = rules
+
+	if err := c.client.SetBucketLifecycle(ctx, bucketName, config); err != nil {
+		return fmt.Errorf("设置存储桶生命周期规则失败: %w", err)
+	}
+	return nil
+}
+
+// RemoveBucketWithObjects 删除存储桶及其中的全部对象，存储桶不存在时直接返回
+// 共享存储桶模式下删除逻辑存储桶前缀下的全部对象及其生命周期规则，共享存储桶本身保留
+func (c *Client) RemoveBucketWithObjects(ctx context.Context, bucketName string) error {
+	if c.layout.Shared() {
+		exists, err := c.client.BucketExists(ctx, c.layout.SharedBucket)
+		if err != nil {
+			return fmt.Errorf("检查存储桶是否存在失败: %w", err)
+		}
+		if !exists {
+			return nil
+		}
+		if err := c.removeObjects(ctx, c.layout.SharedBucket, c.layout.Prefix(bucketName)); err != nil {
+			return err
+		}
+		return c.removePrefixRules(ctx, c.layout.SharedBucket, c.layout.Prefix(bucketName))
+	}
+
+	exists, err := c.client.BucketExists(ctx, bucketName)
+	if err != nil {
+		return fmt.Errorf("检查存储桶是否存在失败: %w", err)
+	}
+	if !exists {
+		return nil
+	}
+	if err := c.removeObjects(ctx, bucketName, ""); err != nil {
+		return err
+	}
+
+	if err := c.client.RemoveBucket(ctx, bucketName); err != nil {
+		return fmt.Errorf("删除存储桶失败: %w", err)
+	}
+	return nil
+}
+
+// removeObjects 删除实际存储桶中 prefix 下的全部对象
+func (c *Client) removeObjects(ctx context.Context, bucketName, prefix string) error {
+	// 列出对象出错时停止删除，已删除的对象不再恢复
+	var listErr error
+	objects := make(chan minio.ObjectInfo)
+	go func() {
+		defer close(objects)
+		for object := range c.client.ListObjects(ctx, bucketName, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+			if object.Err != nil {
+				listErr = object.Err
+				return
+			}
+			objects <- object
+		}
+	}()
+
+	var removeErr error
+	for result := range c.client.RemoveObjects(ctx, bucketName, objects, minio.RemoveObjectsOptions{}) {
+		if result.Err != nil && removeErr == nil {
+			removeErr = fmt.Errorf("删除对象 %s 失败: %w", result.ObjectName, result.Err)
+		}
+	}
+	if listErr != nil {
+		return fmt.Errorf("列出存储桶对象失败: %w", listErr)
+	}
+	return removeErr
+}
+
+// removePrefixRules 移除实际存储桶中过滤前缀位于 prefix 下的生命周期规则
+func (c *Client) removePrefixRules(ctx context.Context, bucketName, prefix string) error {
+	c.lifecycleMu.Lock()
+	defer c.lifecycleMu.Unlock()
+
+	config, err := c.client.GetBucketLifecycle(ctx, bucketName)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchLifecycleConfiguration" {
+			return nil
+		}
+		return fmt.Errorf("获取存储桶生命周期规则失败: %w", err)
+	}
+	rules := make([]lifecycle.Rule, 0, len(config.Rules))
+	for _, rule := range config.Rules {
+		if !strings.HasPrefix(rule.RuleFilter.Prefix, prefix) {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) == len(config.Rules) {
+		return nil
+	}
+	config.Rules = rules
+	if err := c.client.SetBucketLifecycle(ctx, bucketName, config); err != nil {
+		return fmt.Errorf("设置存储桶生命周期规则失败: %w", err)
+	}
+	return nil
+}
+
 // UploadFile 上传文件
 func (c *Client) UploadFile(ctx context.Context, bucketName, objectName string, reader io.Reader, fileSize int64, contentType string) (string, error) {
 	// 检查桶是否存在，不存在则创建
@@ -155,6 +392,7 @@ func (c *Client) UploadFile(ctx context.Context, bucketName, objectName string,
 	}
 
 	// 上传文件
+	bucketName, objectName = c.layout.Locate(bucketName, objectName)
 	info, err := c.client.PutObject(ctx, bucketName, objectName, reader, fileSize, minio.PutObjectOptions{
 		ContentType: contentType,
 	})
@@ -168,6 +406,7 @@ func (c *Client) UploadFile(ctx context.Context, bucketName, objectName string,
 // DownloadFile 下载文件
 func (c *Client) DownloadFile(ctx context.Context, bucketName, objectName string) (io.ReadCloser, int64, error) {
 	// 获取对象信息
+	bucketName, objectName = c.layout.Locate(bucketName, objectName)
 	objInfo, err := c.client.StatObject(ctx, bucketName, objectName, minio.StatObjectOptions{})
 	if err != nil {
 		return nil, 0, fmt.Errorf("获取文件信息失败: %w", err)
@@ -184,6 +423,7 @@ func (c *Client) DownloadFile(ctx context.Context, bucketName, objectName string
 
 // DeleteFile 删除文件
 func (c *Client) DeleteFile(ctx context.Context, bucketName, objectName string) error {
+	bucketName, objectName = c.layout.Locate(bucketName, objectName)
 	err := c.client.RemoveObject(ctx, bucketName, objectName, minio.RemoveObjectOptions{})
 	if err != nil {
 		return fmt.Errorf("删除文件失败: %w", err)
@@ -195,10 +435,7 @@ func (c *Client) DeleteFile(ctx context.Context, bucketName, objectName string)
 // ListFiles 列出文件
 func (c *Client) ListFiles(ctx context.Context, bucketName, prefix string) ([]minio.ObjectInfo, error) {
 	// 列出对象
-	objectCh := c.client.ListObjects(ctx, bucketName, minio.ListObjectsOptions{
-		Prefix:    prefix,
-		Recursive: true,
-	})
+	objectCh := c.ListObjects(ctx, bucketName, prefix, true)
 
 	var objects []minio.ObjectInfo
 	for object := range objectCh {
@@ -219,6 +456,7 @@ func (c *Client) CreateFolder(ctx context.Context, bucketName, folderPath string
 	}
 
 	// 上传一个空文件作为文件夹标识
+	bucketName, folderPath = c.layout.Locate(bucketName, folderPath)
 	_, err := c.client.PutObject(ctx, bucketName, folderPath, strings.NewReader(""), 0, minio.PutObjectOptions{})
 	if err != nil {
 		return fmt.Errorf("创建文件夹失败: %w", err)
@@ -239,10 +477,11 @@ func (c *Client) GetFileHash(reader io.Reader) (string, error) {
 
 // FileExists 检查文件是否存在
 func (c *Client) FileExists(ctx context.Context, bucketName, objectName string) (bool, error) {
+	bucketName, objectName = c.layout.Locate(bucketName, objectName)
 	_, err := c.client.StatObject(ctx, bucketName, objectName, minio.StatObjectOptions{})
 	if err != nil {
-		// 检查是否是文件不存在错误
-		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		// 检查是否是文件或存储桶不存在错误
+		if code := minio.ToErrorResponse(err).Code; code == "NoSuchKey" || code == "NoSuchBucket" {
 			return false, nil
 		}
 		return false, err
@@ -254,6 +493,7 @@ func (c *Client) FileExists(ctx context.Context, bucketName, objectName string)
 // GeneratePreSignedURL 生成预签名URL
 func (c *Client) GeneratePreSignedURL(ctx context.Context, bucketName, objectName string, expiry time.Duration) (string, error) {
 	// 生成预签名URL
+	bucketName, objectName = c.layout.Locate(bucketName, objectName)
 	presignedURL, err := c.client.PresignedGetObject(ctx, bucketName, objectName, expiry, nil)
 	if err != nil {
 		return "", fmt.Errorf("生成预签名URL失败: %w", err)
@@ -262,6 +502,35 @@ func (c *Client) GeneratePreSignedURL(ctx context.Context, bucketName, objectNam
 	return presignedURL.String(), nil
 }
 
+// GeneratePresignedInlineURL 生成在浏览器中直接打开的预签名URL，响应的 Content-Disposition 为 inline
+// contentType 不为空时同时覆盖响应的 Content-Type
+func (c *Client) GeneratePresignedInlineURL(ctx context.Context, bucketName, objectName, contentType string, expiry time.Duration) (string, error) {
+	params := url.Values{}
+	params.Set("response-content-disposition", "inline")
+	if contentType != "" {
+		params.Set("response-content-type", contentType)
+	}
+
+	bucketName, objectName = c.layout.Locate(bucketName, objectName)
+	presignedURL, err := c.client.PresignedGetObject(ctx, bucketName, objectName, expiry, params)
+	if err != nil {
+		return "", fmt.Errorf("生成预签名URL失败: %w", err)
+	}
+
+	return presignedURL.String(), nil
+}
+
+// GeneratePresignedPutURL 生成预签名上传URL，客户端可直接通过PUT上传对象
+func (c *Client) GeneratePresignedPutURL(ctx context.Context, bucketName, objectName string, expiry time.Duration) (string, error) {
+	bucketName, objectName = c.layout.Locate(bucketName, objectName)
+	presignedURL, err := c.client.PresignedPutObject(ctx, bucketName, objectName, expiry)
+	if err != nil {
+		return "", fmt.Errorf("生成预签名上传URL失败: %w", err)
+	}
+
+	return presignedURL.String(), nil
+}
+
 // GetPublicDownloadURL 获取公共下载URL，使用7天的过期时间
 func (c *Client) GetPublicDownloadURL(ctx context.Context, bucketName, objectName string) (string, error) {
 	// 使用7天过期时间
@@ -269,7 +538,7 @@ func (c *Client) GetPublicDownloadURL(ctx context.Context, bucketName, objectNam
 	return c.GeneratePreSignedURL(ctx, bucketName, objectName, expiry)
 }
 
-// GetObjectName 生成对象名称
+// GetObjectName 生成对象名称，名称相对于群组的逻辑存储桶，实际的对象键由 Client 按存储桶布局换算
 func GetObjectName(projectID string, filePath, fileName string) string {
 	// 构建对象名称
 	objectPath := filepath.Join(fmt.Sprintf("project_%s", projectID), filePath)
@@ -284,3 +553,14 @@ func GetObjectName(projectID string, filePath, fileName string) string {
 	// 拼接文件名
 	return strings.TrimPrefix(filepath.Join(objectPath, fileName), "/")
 }
+
+// GetVersionObjectName 生成文件历史版本的对象名称
+// 以文件ID而非路径区分，文件重命名后历史版本仍可找到
+func GetVersionObjectName(fileID string, version int) string {
+	return fmt.Sprintf("%s%d", GetVersionObjectPrefix(fileID), version)
+}
+
+// GetVersionObjectPrefix 生成文件所有历史版本对象的公共前缀
+func GetVersionObjectPrefix(fileID string) string {
+	return fmt.Sprintf(".versions/%s/", fileID)
+}
@@ -0,0 +1,136 @@
+package minio
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// 存储桶策略
+const (
+	// BucketStrategyGroup 每个逻辑存储桶（如群组存储桶）对应一个实际存储桶
+	BucketStrategyGroup = "group"
+	// BucketStrategyShared 所有逻辑存储桶共用一个实际存储桶，对象键以逻辑存储桶名称为前缀
+	BucketStrategyShared = "shared"
+)
+
+// BucketLayout 逻辑存储桶在对象存储中的布局
+// 调用方始终使用逻辑存储桶名称和相对于逻辑存储桶的对象名称，由 Client 按布局换算为实际的存储桶和对象键
+type BucketLayout struct {
+	// SharedBucket 共享存储桶名称，为空时使用每个逻辑存储桶一个实际存储桶的布局
+	SharedBucket string
+}
+
+// NewBucketLayout 根据存储桶策略创建布局，策略为空时按 group 处理
+func NewBucketLayout(strategy, sharedBucket string) (BucketLayout, error) {
+	switch strategy {
+	case "", BucketStrategyGroup:
+		return BucketLayout{}, nil
+	case BucketStrategyShared:
+		if sharedBucket == "" {
+			return BucketLayout{}, fmt.Errorf("存储桶策略为 %s 时需要配置共享存储桶名称", BucketStrategyShared)
+		}
+		return BucketLayout{SharedBucket: sharedBucket}, nil
+	default:
+		return BucketLayout{}, fmt.Errorf("存储桶策略只能是 %s 或 %s", BucketStrategyGroup, BucketStrategyShared)
+	}
+}
+
+// Shared 是否使用共享存储桶
+func (l BucketLayout) Shared() bool {
+	return l.SharedBucket != ""
+}
+
+// Strategy 布局对应的存储桶策略
+func (l BucketLayout) Strategy() string {
+	if l.Shared() {
+		return BucketStrategyShared
+	}
+	return BucketStrategyGroup
+}
+
+// Prefix 逻辑存储桶中的对象在实际存储桶中的键前缀，独立存储桶时为空
+func (l BucketLayout) Prefix(bucketName string) string {
+	if !l.Shared() {
+		return ""
+	}
+	return bucketName + "/"
+}
+
+// Locate 将逻辑存储桶和对象名称换算为实际的存储桶和对象键
+func (l BucketLayout) Locate(bucketName, objectName string) (string, string) {
+	if !l.Shared() {
+		return bucketName, objectName
+	}
+	return l.SharedBucket, l.Prefix(bucketName) + objectName
+}
+
+// ObjectName 将实际的对象键还原为相对于逻辑存储桶的对象名称
+func (l BucketLayout) ObjectName(bucketName, key string) string {
+	return strings.TrimPrefix(key, l.Prefix(bucketName))
+}
+
+// MigrateBucket 将逻辑存储桶中的对象从原布局移动到客户端当前使用的布局，用于切换存储桶策略后迁移已有对象，返回移动的对象数
+// 对象在服务端复制，全部复制成功后才删除原位置的对象（原布局为独立存储桶时同时删除该存储桶）；
+// 复制失败时原对象保留，可重新执行；原位置不存在时直接返回
+func (c *Client) MigrateBucket(ctx context.Context, bucketName string, from BucketLayout) (int, error) {
+	srcBucket, srcPrefix := from.Locate(bucketName, "")
+	dstBucket, dstPrefix := c.layout.Locate(bucketName, "")
+	if srcBucket == dstBucket && srcPrefix == dstPrefix {
+		return 0, nil
+	}
+
+	exists, err := c.client.BucketExists(ctx, srcBucket)
+	if err != nil {
+		return 0, fmt.Errorf("检查存储桶是否存在失败: %w", err)
+	}
+	if !exists {
+		return 0, nil
+	}
+	if err := c.CreateBucketIfNotExists(ctx, bucketName); err != nil {
+		return 0, err
+	}
+
+	var keys []string
+	for object := range c.client.ListObjects(ctx, srcBucket, minio.ListObjectsOptions{Prefix: srcPrefix, Recursive: true}) {
+		if object.Err != nil {
+			return 0, fmt.Errorf("列出存储桶对象失败: %w", object.Err)
+		}
+		keys = append(keys, object.Key)
+	}
+
+	moved := 0
+	for _, key := range keys {
+		name := from.ObjectName(bucketName, key)
+		if name == "" {
+			// 逻辑存储桶的标记对象，目标位置已在创建存储桶时写入
+			continue
+		}
+		if _, err := c.client.CopyObject(ctx, minio.CopyDestOptions{
+			Bucket: dstBucket,
+			Object: dstPrefix + name,
+		}, minio.CopySrcOptions{
+			Bucket: srcBucket,
+			Object: key,
+		}); err != nil {
+			return moved, fmt.Errorf("复制对象 %s 失败: %w", name, err)
+		}
+		moved++
+	}
+
+	if from.Shared() {
+		if err := c.removeObjects(ctx, srcBucket, srcPrefix); err != nil {
+			return moved, err
+		}
+		return moved, c.removePrefixRules(ctx, srcBucket, srcPrefix)
+	}
+	if err := c.removeObjects(ctx, srcBucket, ""); err != nil {
+		return moved, err
+	}
+	if err := c.client.RemoveBucket(ctx, srcBucket); err != nil {
+		return moved, fmt.Errorf("删除存储桶失败: %w", err)
+	}
+	return moved, nil
+}
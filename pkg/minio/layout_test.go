@@ -0,0 +1,80 @@
+package minio
+
+import "testing"
+
+func TestNewBucketLayout(t *testing.T) {
+	tests := []struct {
+		strategy, shared string
+		want             BucketLayout
+		wantErr          bool
+	}{
+		{strategy: "", want: BucketLayout{}},
+		{strategy: BucketStrategyGroup, shared: "ignored", want: BucketLayout{}},
+		{strategy: BucketStrategyShared, shared: "oss-data", want: BucketLayout{SharedBucket: "oss-data"}},
+		{strategy: BucketStrategyShared, wantErr: true},
+		{strategy: "per-project", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := NewBucketLayout(tt.strategy, tt.shared)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("NewBucketLayout(%q, %q) = %+v, %v", tt.strategy, tt.shared, got, err)
+		}
+	}
+}
+
+func TestBucketLayoutLocate(t *testing.T) {
+	group := BucketLayout{}
+	shared := BucketLayout{SharedBucket: "oss-data"}
+
+	tests := []struct {
+		layout              BucketLayout
+		bucket, object      string
+		wantBucket, wantKey string
+	}{
+		{group, "team-a", "project_1/docs/a.txt", "team-a", "project_1/docs/a.txt"},
+		{group, "team-a", "", "team-a", ""},
+		{shared, "team-a", "project_1/docs/a.txt", "oss-data", "team-a/project_1/docs/a.txt"},
+		{shared, "team-a", "", "oss-data", "team-a/"},
+		{shared, "team-b", ".versions/f1/2", "oss-data", "team-b/.versions/f1/2"},
+	}
+	for _, tt := range tests {
+		bucket, key := tt.layout.Locate(tt.bucket, tt.object)
+		if bucket != tt.wantBucket || key != tt.wantKey {
+			t.Errorf("%s.Locate(%q, %q) = %q, %q，应为 %q, %q", tt.layout.Strategy(), tt.bucket, tt.object, bucket, key, tt.wantBucket, tt.wantKey)
+		}
+		// 实际的对象键可还原为逻辑存储桶中的对象名
+		if name := tt.layout.ObjectName(tt.bucket, key); name != tt.object {
+			t.Errorf("%s.ObjectName(%q, %q) = %q，应为 %q", tt.layout.Strategy(), tt.bucket, key, name, tt.object)
+		}
+	}
+}
+
+func TestBucketLayoutStrategy(t *testing.T) {
+	if l := (BucketLayout{}); l.Shared() || l.Strategy() != BucketStrategyGroup || l.Prefix("team-a") != "" {
+		t.Errorf("独立存储桶布局: Shared=%v Strategy=%q Prefix=%q", l.Shared(), l.Strategy(), l.Prefix("team-a"))
+	}
+	if l := (BucketLayout{SharedBucket: "oss-data"}); !l.Shared() || l.Strategy() != BucketStrategyShared || l.Prefix("team-a") != "team-a/" {
+		t.Errorf("共享存储桶布局: Shared=%v Strategy=%q Prefix=%q", l.Shared(), l.Strategy(), l.Prefix("team-a"))
+	}
+}
+
+func TestObjectNames(t *testing.T) {
+	tests := []struct {
+		projectID, filePath, fileName, want string
+	}{
+		{"p1", "", "a.txt", "project_p1/a.txt"},
+		{"p1", "docs/", "a.txt", "project_p1/docs/a.txt"},
+		{"p1", "docs/2024/", "a.txt", "project_p1/docs/2024/a.txt"},
+	}
+	for _, tt := range tests {
+		if got := GetObjectName(tt.projectID, tt.filePath, tt.fileName); got != tt.want {
+			t.Errorf("GetObjectName(%q, %q, %q) = %q，应为 %q", tt.projectID, tt.filePath, tt.fileName, got, tt.want)
+		}
+	}
+	if got := GetVersionObjectName("f1", 3); got != ".versions/f1/3" {
+		t.Errorf("GetVersionObjectName = %q", got)
+	}
+	if got := GetVersionObjectPrefix("f1"); got != ".versions/f1/" {
+		t.Errorf("GetVersionObjectPrefix = %q", got)
+	}
+}
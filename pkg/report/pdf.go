@@ -0,0 +1,164 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// 页面布局，单位为点(1/72英寸)，页面为A4纵向
+const (
+	pageWidth   = 595
+	pageHeight  = 842
+	pageMargin  = 50
+	titleSize   = 16
+	fontSize    = 10
+	rowHeight   = 20
+	tableTop    = pageHeight - 100
+	tableBottom = 60
+	footerY     = 30
+	cellPadding = 4
+)
+
+// 字符宽度，以字号为单位：字体中ASCII字符为半角，其余字符为全角
+const (
+	halfWidth = 0.5
+	fullWidth = 1.0
+)
+
+// rowsPerPage 每页除表头外的行数
+const rowsPerPage = (tableTop-tableBottom)/rowHeight - 1
+
+// fontObjects 表格使用的字体：Adobe-GB1 预置的 STSong-Light，阅读器自带该字体，不需要嵌入，可显示中文
+// ASCII 字符对应的 CID 1-95 为半角字形
+var fontObjects = []string{
+	"<< /Type /Font /Subtype /Type0 /BaseFont /STSong-Light-UniGB-UCS2-H /Encoding /UniGB-UCS2-H /DescendantFonts [%d 0 R] >>",
+	"<< /Type /Font /Subtype /CIDFontType0 /BaseFont /STSong-Light /CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 2 >> /FontDescriptor %d 0 R /DW 1000 /W [1 95 500] >>",
+	"<< /Type /FontDescriptor /FontName /STSong-Light /Flags 6 /FontBBox [-25 -254 1000 880] /ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>",
+}
+
+// TablePDF 生成包含标题和表格的PDF，行数超过一页时分页，每页重复表头并在页脚标注页码
+// 各列等宽，第一列左对齐，其余列右对齐；不在字体中的字符（BMP之外）显示为 ?
+func TablePDF(title string, header []string, rows [][]string) ([]byte, error) {
+	if len(header) == 0 {
+		return nil, errors.New("表格至少需要一列")
+	}
+	for i, row := range rows {
+		if len(row) != len(header) {
+			return nil, fmt.Errorf("第%d行有%d列，表头有%d列", i+1, len(row), len(header))
+		}
+	}
+
+	pageCount := (len(rows) + rowsPerPage - 1) / rowsPerPage
+	if pageCount == 0 {
+		pageCount = 1
+	}
+
+	// 对象编号：1 目录，2 页面树，3-5 字体，之后每页依次为页面对象和内容流
+	const firstPage = 6
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"",
+		fmt.Sprintf(fontObjects[0], 4),
+		fmt.Sprintf(fontObjects[1], 5),
+		fontObjects[2],
+	}
+	kids := make([]string, 0, pageCount)
+	for page := 0; page < pageCount; page++ {
+		end := (page + 1) * rowsPerPage
+		if end > len(rows) {
+			end = len(rows)
+		}
+		content := pageContent(title, header, rows[page*rowsPerPage:end], page+1, pageCount)
+
+		pageNum := firstPage + page*2
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageNum))
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", pageWidth, pageHeight, pageNum+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		)
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), pageCount)
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes(), nil
+}
+
+// pageContent 生成一页的内容流：标题、表头、数据行和页码
+func pageContent(title string, header []string, rows [][]string, page, pageCount int) string {
+	var b strings.Builder
+	colWidth := float64(pageWidth-2*pageMargin) / float64(len(header))
+
+	writeText(&b, title, pageMargin, pageHeight-60, titleSize)
+
+	y := tableTop
+	writeRow(&b, header, y, colWidth)
+	// 表头上下各一条横线
+	fmt.Fprintf(&b, "0.8 w %d %d m %d %d l S\n", pageMargin, y+rowHeight-6, pageWidth-pageMargin, y+rowHeight-6)
+	fmt.Fprintf(&b, "0.5 w %d %d m %d %d l S\n", pageMargin, y-6, pageWidth-pageMargin, y-6)
+	for _, row := range rows {
+		y -= rowHeight
+		writeRow(&b, row, y, colWidth)
+	}
+	fmt.Fprintf(&b, "0.8 w %d %d m %d %d l S\n", pageMargin, y-6, pageWidth-pageMargin, y-6)
+
+	footer := fmt.Sprintf("第 %d / %d 页", page, pageCount)
+	writeText(&b, footer, (pageWidth-textWidth(footer, fontSize))/2, footerY, fontSize)
+	return b.String()
+}
+
+// writeRow 写入一行，第一列左对齐，其余列右对齐
+func writeRow(b *strings.Builder, cells []string, y int, colWidth float64) {
+	for i, cell := range cells {
+		left := float64(pageMargin) + float64(i)*colWidth
+		x := left + cellPadding
+		if i > 0 {
+			x = left + colWidth - cellPadding - textWidth(cell, fontSize)
+		}
+		writeText(b, cell, x, y, fontSize)
+	}
+}
+
+// writeText 在指定位置写入一段文字
+func writeText(b *strings.Builder, text string, x float64, y, size int) {
+	fmt.Fprintf(b, "BT /F1 %d Tf %.2f %d Td <%s> Tj ET\n", size, x, y, encodeText(text))
+}
+
+// encodeText 将文字编码为 UCS-2 大端序的十六进制字符串
+func encodeText(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if r > 0xFFFF || utf16.IsSurrogate(r) {
+			r = '?'
+		}
+		fmt.Fprintf(&b, "%04X", r)
+	}
+	return b.String()
+}
+
+// textWidth 文字的显示宽度
+func textWidth(text string, size int) float64 {
+	width := 0.0
+	for _, r := range text {
+		if r >= 0x20 && r < 0x7F {
+			width += halfWidth
+		} else {
+			width += fullWidth
+		}
+	}
+	return width * float64(size)
+}
@@ -0,0 +1,87 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestTablePDF(t *testing.T) {
+	header := []string{"日期", "总大小(字节)", "新增大小(字节)", "文件数"}
+	rows := make([][]string, rowsPerPage+5)
+	for i := range rows {
+		rows[i] = []string{fmt.Sprintf("row-%03d", i), "1024", "0", "3"}
+	}
+
+	data, err := TablePDF("存储报表", header, rows)
+	if err != nil {
+		t.Fatalf("生成PDF失败: %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF-")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Fatalf("不是完整的PDF文件")
+	}
+
+	// 交叉引用表中的每个偏移都指向对应的对象
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(data)
+	if startxref == nil {
+		t.Fatalf("缺少 startxref")
+	}
+	xref, _ := strconv.Atoi(string(startxref[1]))
+	if !bytes.HasPrefix(data[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref 没有指向交叉引用表")
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n \n`).FindAllSubmatch(data[xref:], -1)
+	for i, e := range entries {
+		offset, _ := strconv.Atoi(string(e[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(data[offset:], []byte(want)) {
+			t.Fatalf("对象 %d 的偏移 %d 处不是对象开头", i+1, offset)
+		}
+	}
+
+	// 超过一页时分页，每页重复表头，每行只出现一次
+	if !bytes.Contains(data, []byte("/Count 2 ")) {
+		t.Fatalf("%d 行应分为2页", len(rows))
+	}
+	if got := bytes.Count(data, []byte("<"+encodeText(header[0])+">")); got != 2 {
+		t.Fatalf("表头出现 %d 次，应每页一次", got)
+	}
+	for _, row := range rows {
+		if got := bytes.Count(data, []byte("<"+encodeText(row[0])+">")); got != 1 {
+			t.Fatalf("行 %s 出现 %d 次", row[0], got)
+		}
+	}
+	if !bytes.Contains(data, []byte(encodeText("第 2 / 2 页"))) {
+		t.Fatalf("缺少页码")
+	}
+
+	// 内容流的 /Length 与实际长度一致
+	streams := regexp.MustCompile(`(?s)<< /Length (\d+) >>\nstream\n(.*?)\nendstream`).FindAllSubmatch(data, -1)
+	if len(streams) != 2 {
+		t.Fatalf("内容流有 %d 个，应为2个", len(streams))
+	}
+	for _, s := range streams {
+		if length, _ := strconv.Atoi(string(s[1])); length != len(s[2]) {
+			t.Fatalf("内容流 /Length 为 %d，实际为 %d", length, len(s[2]))
+		}
+	}
+}
+
+func TestTablePDFEmptyAndInvalid(t *testing.T) {
+	data, err := TablePDF("空报表", []string{"a", "b"}, nil)
+	if err != nil {
+		t.Fatalf("没有数据行时生成PDF失败: %v", err)
+	}
+	if !bytes.Contains(data, []byte("/Count 1 ")) {
+		t.Fatalf("没有数据行时应生成只有表头的一页")
+	}
+
+	if _, err := TablePDF("x", []string{"a", "b"}, [][]string{{"1"}}); err == nil || !strings.Contains(err.Error(), "第1行") {
+		t.Fatalf("列数不一致时返回 %v，应返回错误", err)
+	}
+	if encodeText("A\U0001F600") != "0041003F" {
+		t.Fatalf("BMP之外的字符应显示为 ?")
+	}
+}
@@ -0,0 +1,101 @@
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // 注册GIF解码，动图只取第一帧
+	"image/jpeg"
+	_ "image/png" // 注册PNG解码
+)
+
+// ContentType 缩略图统一编码为JPEG
+const ContentType = "image/jpeg"
+
+// ErrTooLarge 原图像素数超过限制，不生成缩略图
+var ErrTooLarge = errors.New("图片尺寸过大")
+
+// Generate 将图片按比例缩小到不超过 maxWidth x maxHeight 并编码为JPEG，原图更小时不放大
+// 先只读取图片头检查像素数，超过 maxPixels（大于0时）返回 ErrTooLarge，避免解码超大图片占用过多内存；
+// 透明区域以白色填充
+func Generate(data []byte, maxWidth, maxHeight, maxPixels int) ([]byte, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, errors.New("图片尺寸无效")
+	}
+	if maxPixels > 0 && config.Width*config.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	// 铺白底后再缩放，透明像素不会变成黑色
+	bounds := src.Bounds()
+	flat := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), src, bounds.Min, draw.Over)
+
+	width, height := fitSize(bounds.Dx(), bounds.Dy(), maxWidth, maxHeight)
+	dst := flat
+	if width != bounds.Dx() || height != bounds.Dy() {
+		dst = downscale(flat, width, height)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// fitSize 计算等比缩小后的尺寸，宽高至少为1
+func fitSize(width, height, maxWidth, maxHeight int) (int, int) {
+	if maxWidth <= 0 || maxHeight <= 0 || (width <= maxWidth && height <= maxHeight) {
+		return width, height
+	}
+	if width*maxHeight > height*maxWidth {
+		return maxWidth, max(1, height*maxWidth/width)
+	}
+	return max(1, width*maxHeight/height), maxHeight
+}
+
+// downscale 按区域平均缩小图片，每个目标像素取原图中对应矩形区域的平均颜色
+func downscale(src *image.RGBA, width, height int) *image.RGBA {
+	srcWidth, srcHeight := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := y * srcHeight / height
+		y1 := max(y0+1, (y+1)*srcHeight/height)
+		for x := 0; x < width; x++ {
+			x0 := x * srcWidth / width
+			x1 := max(x0+1, (x+1)*srcWidth/width)
+
+			var r, g, b, count uint64
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+3]
+					r += uint64(p[0])
+					g += uint64(p[1])
+					b += uint64(p[2])
+					count++
+				}
+			}
+			i := y*dst.Stride + x*4
+			dst.Pix[i] = uint8(r / count)
+			dst.Pix[i+1] = uint8(g / count)
+			dst.Pix[i+2] = uint8(b / count)
+			dst.Pix[i+3] = 0xff
+		}
+	}
+	return dst
+}
@@ -0,0 +1,103 @@
+package totp
+
+import (
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret RFC 6238 附录B中 SHA1 测试向量使用的密钥 "12345678901234567890"
+var rfcSecret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestCodeRFC6238(t *testing.T) {
+	// 取 RFC 6238 测试向量8位结果的后6位
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code 返回错误: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("时间 %d 的验证码为 %s，应为 %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestCodeLowercaseSecret(t *testing.T) {
+	upper, _ := Code(rfcSecret, 1)
+	lower, err := Code(strings.ToLower(rfcSecret), 1)
+	if err != nil || lower != upper {
+		t.Fatalf("小写密钥的验证码为 %s, %v，应与大写密钥相同 %s", lower, err, upper)
+	}
+	if _, err := Code("not-base32!", 1); err == nil {
+		t.Fatalf("无效的密钥应返回错误")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := Step(now)
+	code := func(step int64) string {
+		c, err := Code(rfcSecret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	for _, offset := range []int64{-1, 0, 1} {
+		step, ok := Validate(rfcSecret, code(current+offset), now, 1)
+		if !ok || step != current+offset {
+			t.Errorf("相差 %d 个时间步的验证码应通过并返回对应的时间步，实际为 %d, %v", offset, step, ok)
+		}
+	}
+	for _, offset := range []int64{-2, 2} {
+		if _, ok := Validate(rfcSecret, code(current+offset), now, 1); ok {
+			t.Errorf("相差 %d 个时间步的验证码不应通过", offset)
+		}
+	}
+	if _, ok := Validate(rfcSecret, code(current)[:5], now, 1); ok {
+		t.Errorf("长度不足的验证码不应通过")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	a, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := GenerateSecret()
+	if a == b {
+		t.Fatalf("两次生成的密钥相同")
+	}
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(a)
+	if err != nil || len(key) != SecretSize {
+		t.Fatalf("密钥应为 %d 字节的 base32 编码，实际为 %d 字节, %v", SecretSize, len(key), err)
+	}
+}
+
+func TestURI(t *testing.T) {
+	u, err := url.Parse(URI("OSS", "alice@example.com", rfcSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/OSS:alice@example.com" {
+		t.Fatalf("URI 格式错误: %s", u)
+	}
+	query := u.Query()
+	if query.Get("secret") != rfcSecret || query.Get("issuer") != "OSS" || query.Get("digits") != "6" || query.Get("period") != "30" {
+		t.Fatalf("URI 参数错误: %s", u.RawQuery)
+	}
+}
@@ -0,0 +1,454 @@
+package watermark
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupportedPDF PDF的结构无法添加水印，如已加密、页面对象位于压缩的对象流中
+var ErrUnsupportedPDF = fmt.Errorf("%w: PDF已加密或使用了压缩对象流", ErrUnsupportedType)
+
+var (
+	pdfObjectPattern    = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	pdfStartXrefPattern = regexp.MustCompile(`startxref\s+(\d+)`)
+	pdfRefPattern       = regexp.MustCompile(`^(\d+)\s+(\d+)\s+R\b`)
+	pdfRefsPattern      = regexp.MustCompile(`(\d+)\s+(\d+)\s+R\b`)
+)
+
+// 页面未声明 MediaBox 时使用 Letter 尺寸
+var defaultMediaBox = [4]float64{0, 0, 612, 792}
+
+// pdfDict 字典顶层的键及其原始值，保持原有顺序
+type pdfDict struct {
+	keys   []string
+	values map[string][]byte
+}
+
+// pdfObject 文件中一个未压缩的间接对象
+type pdfObject struct {
+	num, gen int
+	dict     *pdfDict
+}
+
+// pdfPage 页面对象及其生效的页面尺寸
+type pdfPage struct {
+	object   *pdfObject
+	mediaBox [4]float64
+}
+
+// applyPDF 以增量更新的方式在每一页的内容之后追加水印，原文件的字节保持不变
+// 页面内容流前后分别追加 q/Q，水印绘制不受原内容图形状态的影响；水印使用矩形点阵绘制，不需要修改页面资源
+func applyPDF(data []byte, text string) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return nil, errors.New("不是有效的PDF文件")
+	}
+
+	prev, trailer, err := readTrailer(data)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := trailer.values["/Encrypt"]; ok {
+		return nil, ErrUnsupportedPDF
+	}
+	size, err := strconv.Atoi(string(trailer.values["/Size"]))
+	if err != nil {
+		return nil, errors.New("PDF尾部字典缺少 /Size")
+	}
+
+	objects := scanObjects(data)
+	pages, err := collectPages(objects, trailer.values["/Root"])
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(data)
+	if !bytes.HasSuffix(data, []byte("\n")) {
+		buf.WriteByte('\n')
+	}
+	type xrefEntry struct{ num, gen, offset int }
+	var entries []xrefEntry
+	writeObject := func(num, gen int, body []byte) {
+		entries = append(entries, xrefEntry{num, gen, buf.Len()})
+		fmt.Fprintf(&buf, "%d %d obj\n", num, gen)
+		buf.Write(body)
+		buf.WriteString("\nendobj\n")
+	}
+	writeStream := func(num int, content string) {
+		writeObject(num, 0, []byte(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content)))
+	}
+
+	next := size
+	saveState := next
+	next++
+	writeStream(saveState, "q")
+	for _, page := range pages {
+		stamp := next
+		next++
+		writeStream(stamp, "Q\n"+pdfStampContent(page.mediaBox, text))
+
+		contents := page.object.dict.values["/Contents"]
+		if bytes.HasPrefix(contents, []byte("[")) {
+			contents = bytes.TrimSuffix(contents[1:], []byte("]"))
+		} else if len(contents) > 0 && lookupObject(objects, contents) == nil {
+			// 内容为间接数组时不能直接放入新的内容数组
+			return nil, ErrUnsupportedPDF
+		}
+		page.object.dict.set("/Contents", []byte(fmt.Sprintf("[%d 0 R %s %d 0 R]", saveState, contents, stamp)))
+		writeObject(page.object.num, page.object.gen, page.object.dict.bytes())
+	}
+
+	xrefOffset := buf.Len()
+	sort.Slice(entries, func(i, j int) bool { return entries[i].num < entries[j].num })
+	buf.WriteString("xref\n0 1\n0000000000 65535 f \n")
+	for _, entry := range entries {
+		fmt.Fprintf(&buf, "%d 1\n%010d %05d n \n", entry.num, entry.offset, entry.gen)
+	}
+
+	newTrailer := &pdfDict{values: map[string][]byte{}}
+	newTrailer.set("/Size", []byte(strconv.Itoa(next)))
+	newTrailer.set("/Root", trailer.values["/Root"])
+	for _, key := range []string{"/Info", "/ID"} {
+		if value, ok := trailer.values[key]; ok {
+			newTrailer.set(key, value)
+		}
+	}
+	newTrailer.set("/Prev", []byte(strconv.Itoa(prev)))
+	fmt.Fprintf(&buf, "trailer\n%s\nstartxref\n%d\n%%%%EOF\n", newTrailer.bytes(), xrefOffset)
+	return buf.Bytes(), nil
+}
+
+// readTrailer 读取最后一个交叉引用段的位置和尾部字典，兼容交叉引用表和交叉引用流
+func readTrailer(data []byte) (int, *pdfDict, error) {
+	matches := pdfStartXrefPattern.FindAllSubmatch(data, -1)
+	if len(matches) == 0 {
+		return 0, nil, errors.New("PDF缺少 startxref")
+	}
+	prev, err := strconv.Atoi(string(matches[len(matches)-1][1]))
+	if err != nil || prev >= len(data) {
+		return 0, nil, errors.New("PDF的 startxref 无效")
+	}
+
+	pos := prev
+	if bytes.HasPrefix(data[prev:], []byte("xref")) {
+		idx := bytes.Index(data[prev:], []byte("trailer"))
+		if idx < 0 {
+			return 0, nil, errors.New("PDF缺少尾部字典")
+		}
+		pos = skipSpace(data, prev+idx+len("trailer"))
+	} else {
+		// 交叉引用流的字典即尾部字典
+		loc := pdfObjectPattern.FindIndex(data[prev:])
+		if loc == nil || loc[0] != 0 {
+			return 0, nil, errors.New("PDF的 startxref 无效")
+		}
+		pos = skipSpace(data, prev+loc[1])
+	}
+	trailer, _, err := parseDict(data, pos)
+	if err != nil {
+		return 0, nil, fmt.Errorf("解析PDF尾部字典失败: %w", err)
+	}
+	return prev, trailer, nil
+}
+
+// scanObjects 扫描文件中未压缩的间接对象，同一对象出现多次时以后出现的定义为准
+func scanObjects(data []byte) map[int]*pdfObject {
+	objects := make(map[int]*pdfObject)
+	for _, m := range pdfObjectPattern.FindAllSubmatchIndex(data, -1) {
+		if m[0] > 0 && !isSpace(data[m[0]-1]) {
+			continue
+		}
+		num, _ := strconv.Atoi(string(data[m[2]:m[3]]))
+		gen, _ := strconv.Atoi(string(data[m[4]:m[5]]))
+		dict, _, err := parseDict(data, skipSpace(data, m[1]))
+		if err != nil {
+			continue
+		}
+		objects[num] = &pdfObject{num: num, gen: gen, dict: dict}
+	}
+	return objects
+}
+
+// collectPages 从文档目录出发按顺序遍历页面树，页面树中的对象无法解析时返回 ErrUnsupportedPDF
+func collectPages(objects map[int]*pdfObject, root []byte) ([]pdfPage, error) {
+	lookup := func(ref []byte) *pdfObject { return lookupObject(objects, ref) }
+
+	catalog := lookup(root)
+	if catalog == nil {
+		return nil, ErrUnsupportedPDF
+	}
+
+	var pages []pdfPage
+	visited := make(map[int]bool)
+	var walk func(ref []byte, mediaBox [4]float64) error
+	walk = func(ref []byte, mediaBox [4]float64) error {
+		node := lookup(ref)
+		if node == nil {
+			return ErrUnsupportedPDF
+		}
+		if visited[node.num] {
+			return errors.New("PDF页面树存在循环引用")
+		}
+		visited[node.num] = true
+
+		if box, ok := parseMediaBox(node.dict.values["/MediaBox"]); ok {
+			mediaBox = box
+		}
+		switch string(node.dict.values["/Type"]) {
+		case "/Page":
+			pages = append(pages, pdfPage{object: node, mediaBox: mediaBox})
+		case "/Pages":
+			kids := node.dict.values["/Kids"]
+			if !bytes.HasPrefix(kids, []byte("[")) {
+				return ErrUnsupportedPDF
+			}
+			for _, kid := range pdfRefsPattern.FindAll(kids, -1) {
+				if err := walk(kid, mediaBox); err != nil {
+					return err
+				}
+			}
+		default:
+			return ErrUnsupportedPDF
+		}
+		return nil
+	}
+	if err := walk(catalog.dict.values["/Pages"], defaultMediaBox); err != nil {
+		return nil, err
+	}
+	if len(pages) == 0 {
+		return nil, errors.New("PDF没有页面")
+	}
+	return pages, nil
+}
+
+// lookupObject 按引用查找对象，找不到时返回 nil
+func lookupObject(objects map[int]*pdfObject, ref []byte) *pdfObject {
+	m := pdfRefPattern.FindSubmatch(ref)
+	if m == nil {
+		return nil
+	}
+	num, _ := strconv.Atoi(string(m[1]))
+	return objects[num]
+}
+
+// parseMediaBox 解析直接给出的页面尺寸数组
+func parseMediaBox(value []byte) ([4]float64, bool) {
+	var box [4]float64
+	if !bytes.HasPrefix(value, []byte("[")) {
+		return box, false
+	}
+	fields := strings.Fields(strings.Trim(string(value), "[]"))
+	if len(fields) != 4 {
+		return box, false
+	}
+	for i, field := range fields {
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return box, false
+		}
+		box[i] = v
+	}
+	return box, true
+}
+
+// pdfStampContent 生成平铺水印的内容流，布局与图片水印一致
+func pdfStampContent(mediaBox [4]float64, text string) string {
+	textRunes := []rune(text)
+	if len(textRunes) == 0 {
+		return ""
+	}
+	left, bottom, right, top := mediaBox[0], mediaBox[1], mediaBox[2], mediaBox[3]
+
+	// 单行文本约占页面宽度的一半
+	scale := (right - left) / float64(len(textRunes)*(glyphWidth+1)*2)
+	if scale < 1 {
+		scale = 1
+	}
+	lineWidth := float64(len(textRunes)*(glyphWidth+1)) * scale
+	lineHeight := glyphHeight * scale
+
+	var b strings.Builder
+	b.WriteString("0.75 g\n")
+	row := 0
+	for y := top - lineHeight; y > bottom; y -= lineHeight * 4 {
+		// 奇数行错开半个文本宽度
+		offset := 0.0
+		if row%2 == 1 {
+			offset = -lineWidth / 2
+		}
+		for x := left + offset; x < right; x += lineWidth * 1.5 {
+			for i, r := range textRunes {
+				glyph, ok := glyphs[r]
+				if !ok {
+					glyph = glyphs['?']
+				}
+				gx := x + float64(i*(glyphWidth+1))*scale
+				for gr := 0; gr < glyphHeight; gr++ {
+					for col := 0; col < glyphWidth; col++ {
+						if glyph[gr]&(1<<(glyphWidth-1-col)) == 0 {
+							continue
+						}
+						// PDF坐标系y轴向上，字形第0行在最上方
+						fmt.Fprintf(&b, "%.2f %.2f %.2f %.2f re\n", gx+float64(col)*scale, y-float64(gr+1)*scale, scale, scale)
+					}
+				}
+			}
+		}
+		row++
+	}
+	b.WriteString("f")
+	return b.String()
+}
+
+// set 设置键的值，键不存在时追加到末尾
+func (d *pdfDict) set(key string, value []byte) {
+	if _, ok := d.values[key]; !ok {
+		d.keys = append(d.keys, key)
+	}
+	d.values[key] = value
+}
+
+// bytes 序列化字典
+func (d *pdfDict) bytes() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("<<")
+	for _, key := range d.keys {
+		buf.WriteByte(' ')
+		buf.WriteString(key)
+		buf.WriteByte(' ')
+		buf.Write(d.values[key])
+	}
+	buf.WriteString(" >>")
+	return buf.Bytes()
+}
+
+// parseDict 解析 pos 处的字典，返回字典和字典结束后的位置
+func parseDict(data []byte, pos int) (*pdfDict, int, error) {
+	if !bytes.HasPrefix(data[pos:], []byte("<<")) {
+		return nil, pos, errors.New("不是字典")
+	}
+	dict := &pdfDict{values: map[string][]byte{}}
+	pos += 2
+	for {
+		pos = skipSpace(data, pos)
+		if pos >= len(data) {
+			return nil, pos, errors.New("字典未结束")
+		}
+		if bytes.HasPrefix(data[pos:], []byte(">>")) {
+			return dict, pos + 2, nil
+		}
+		if data[pos] != '/' {
+			return nil, pos, errors.New("字典的键不是名称")
+		}
+		keyEnd := skipToken(data, pos+1)
+		key := string(data[pos:keyEnd])
+
+		valueStart := skipSpace(data, keyEnd)
+		valueEnd, err := skipValue(data, valueStart)
+		if err != nil {
+			return nil, pos, err
+		}
+		dict.set(key, data[valueStart:valueEnd])
+		pos = valueEnd
+	}
+}
+
+// skipValue 跳过 pos 处的一个值，返回值结束后的位置
+func skipValue(data []byte, pos int) (int, error) {
+	if pos >= len(data) {
+		return pos, errors.New("缺少值")
+	}
+	switch data[pos] {
+	case '<':
+		if bytes.HasPrefix(data[pos:], []byte("<<")) {
+			_, end, err := parseDict(data, pos)
+			return end, err
+		}
+		end := bytes.IndexByte(data[pos:], '>')
+		if end < 0 {
+			return pos, errors.New("十六进制字符串未结束")
+		}
+		return pos + end + 1, nil
+	case '(':
+		depth := 0
+		for i := pos; i < len(data); i++ {
+			switch data[i] {
+			case '\\':
+				i++
+			case '(':
+				depth++
+			case ')':
+				depth--
+				if depth == 0 {
+					return i + 1, nil
+				}
+			}
+		}
+		return pos, errors.New("字符串未结束")
+	case '[':
+		pos++
+		for {
+			pos = skipSpace(data, pos)
+			if pos >= len(data) {
+				return pos, errors.New("数组未结束")
+			}
+			if data[pos] == ']' {
+				return pos + 1, nil
+			}
+			end, err := skipValue(data, pos)
+			if err != nil {
+				return pos, err
+			}
+			pos = end
+		}
+	case '/':
+		return skipToken(data, pos+1), nil
+	}
+	if loc := pdfRefPattern.FindIndex(data[pos:]); loc != nil {
+		return pos + loc[1], nil
+	}
+	end := skipToken(data, pos)
+	if end == pos {
+		return pos, fmt.Errorf("无法识别的字符 %q", data[pos])
+	}
+	return end, nil
+}
+
+// skipToken 跳到下一个空白或分隔符
+func skipToken(data []byte, pos int) int {
+	for pos < len(data) && !isSpace(data[pos]) && !strings.ContainsRune("()<>[]{}/%", rune(data[pos])) {
+		pos++
+	}
+	return pos
+}
+
+// skipSpace 跳过空白和注释
+func skipSpace(data []byte, pos int) int {
+	for pos < len(data) {
+		switch {
+		case isSpace(data[pos]):
+			pos++
+		case data[pos] == '%':
+			for pos < len(data) && data[pos] != '\n' && data[pos] != '\r' {
+				pos++
+			}
+		default:
+			return pos
+		}
+	}
+	return pos
+}
+
+// isSpace 判断是否为PDF空白字符
+func isSpace(c byte) bool {
+	switch c {
+	case 0, '\t', '\n', '\f', '\r', ' ':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package watermark
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+// buildPDF 按顺序写入对象并生成交叉引用表
+func buildPDF(trailerExtra string, objects ...string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R%s >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, trailerExtra, xref)
+	return buf.Bytes()
+}
+
+func TestApplyPDF(t *testing.T) {
+	content := "BT /F1 12 Tf 72 720 Td (Hello) Tj ET"
+	original := buildPDF("",
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R 5 0 R] /Count 2 /MediaBox [0 0 595 842] >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R /Resources << /Font << /F1 6 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		"<< /Type /Page /Parent 2 0 R /Contents [4 0 R] /MediaBox [0 0 300 400] >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	)
+
+	stamped, err := Apply(bytes.NewReader(original), "application/pdf", "confidential alice")
+	if err != nil {
+		t.Fatalf("添加水印失败: %v", err)
+	}
+	if !bytes.HasPrefix(stamped, original) {
+		t.Fatalf("增量更新修改了原文件的内容")
+	}
+
+	prev, trailer, err := readTrailer(stamped)
+	if err != nil {
+		t.Fatalf("读取新的尾部字典失败: %v", err)
+	}
+	_, oldTrailer, _ := readTrailer(original)
+	if got := string(trailer.values["/Prev"]); got != strconv.Itoa(mustStartXref(t, original)) {
+		t.Fatalf("/Prev 为 %s，应指向原交叉引用表", got)
+	}
+	if string(trailer.values["/Root"]) != string(oldTrailer.values["/Root"]) {
+		t.Fatalf("/Root 改变为 %s", trailer.values["/Root"])
+	}
+
+	// 新交叉引用表中的每个偏移都指向对应的对象
+	entry := regexp.MustCompile(`(\d+) 1\n(\d{10}) (\d{5}) n \n`)
+	entries := entry.FindAllSubmatch(stamped[prev:], -1)
+	if len(entries) != 5 {
+		t.Fatalf("交叉引用表有 %d 个对象，应为两页加三个新内容流共5个", len(entries))
+	}
+	for _, e := range entries {
+		offset, _ := strconv.Atoi(string(e[2]))
+		want := fmt.Sprintf("%s 0 obj", e[1])
+		if !bytes.HasPrefix(stamped[offset:], []byte(want)) {
+			t.Fatalf("对象 %s 的偏移 %d 处不是对象开头", e[1], offset)
+		}
+	}
+
+	objects := scanObjects(stamped)
+	for _, num := range []int{3, 5} {
+		contents := string(objects[num].dict.values["/Contents"])
+		if !regexp.MustCompile(`^\[7 0 R 4 0 R \d+ 0 R\]$`).MatchString(contents) {
+			t.Fatalf("页面 %d 的内容为 %s，应在原内容前后加入新的内容流", num, contents)
+		}
+	}
+	if string(objects[3].dict.values["/Parent"]) != "2 0 R" || objects[3].dict.values["/Resources"] == nil {
+		t.Fatalf("页面的其他属性被修改: %s", objects[3].dict.bytes())
+	}
+	if !bytes.Contains(stamped[len(original):], []byte(" re\n")) {
+		t.Fatalf("没有绘制水印")
+	}
+}
+
+func TestApplyPDFUnsupported(t *testing.T) {
+	encrypted := buildPDF(" /Encrypt 3 0 R",
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [] /Count 0 >>",
+		"<< /Filter /Standard >>",
+	)
+	if _, err := Apply(bytes.NewReader(encrypted), "application/pdf", "x"); !errors.Is(err, ErrUnsupportedType) {
+		t.Fatalf("加密的PDF返回 %v，应返回 ErrUnsupportedType", err)
+	}
+
+	// 页面位于对象流中，扫描不到
+	compressed := buildPDF("",
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [9 0 R] /Count 1 >>",
+	)
+	if _, err := Apply(bytes.NewReader(compressed), "application/pdf", "x"); !errors.Is(err, ErrUnsupportedType) {
+		t.Fatalf("页面对象不可读的PDF返回 %v，应返回 ErrUnsupportedType", err)
+	}
+}
+
+func mustStartXref(t *testing.T, data []byte) int {
+	t.Helper()
+	prev, _, err := readTrailer(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return prev
+}
@@ -0,0 +1,128 @@
+package watermark
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// ErrUnsupportedType 不支持添加水印的文件类型
+var ErrUnsupportedType = errors.New("该文件类型不支持水印")
+
+// Supported 判断MIME类型是否支持添加水印
+func Supported(mimeType string) bool {
+	switch normalizeMimeType(mimeType) {
+	case "image/png", "image/jpeg", "application/pdf":
+		return true
+	}
+	return false
+}
+
+// Apply 读取原始内容并返回加上水印后的数据，原始数据不做修改
+func Apply(r io.Reader, mimeType, text string) ([]byte, error) {
+	mimeType = normalizeMimeType(mimeType)
+	if !Supported(mimeType) {
+		return nil, ErrUnsupportedType
+	}
+
+	if mimeType == "application/pdf" {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return applyPDF(data, strings.ToUpper(text))
+	}
+
+	src, _, err := image.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := src.Bounds()
+	dst := image.NewRGBA(bounds)
+	draw.Draw(dst, bounds, src, bounds.Min, draw.Src)
+	drawTiledText(dst, strings.ToUpper(text))
+
+	var buf bytes.Buffer
+	switch mimeType {
+	case "image/png":
+		err = png.Encode(&buf, dst)
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 90})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// normalizeMimeType 去掉参数部分并统一小写
+func normalizeMimeType(mimeType string) string {
+	if i := strings.Index(mimeType, ";"); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+	mimeType = strings.ToLower(strings.TrimSpace(mimeType))
+	if mimeType == "image/jpg" {
+		return "image/jpeg"
+	}
+	return mimeType
+}
+
+// drawTiledText 将文本以半透明方式平铺到整张图片上
+func drawTiledText(img *image.RGBA, text string) {
+	if text == "" {
+		return
+	}
+
+	bounds := img.Bounds()
+	textRunes := []rune(text)
+
+	// 按图片宽度计算缩放，使单行文本约占图片宽度的一半
+	scale := bounds.Dx() / (len(textRunes) * (glyphWidth + 1) * 2)
+	if scale < 1 {
+		scale = 1
+	}
+	lineWidth := len(textRunes) * (glyphWidth + 1) * scale
+	lineHeight := glyphHeight * scale
+
+	ink := image.NewUniform(color.NRGBA{R: 128, G: 128, B: 128, A: 96})
+
+	row := 0
+	for y := bounds.Min.Y + lineHeight; y < bounds.Max.Y; y += lineHeight * 4 {
+		// 奇数行错开半个文本宽度
+		offset := 0
+		if row%2 == 1 {
+			offset = -lineWidth / 2
+		}
+		for x := bounds.Min.X + offset; x < bounds.Max.X; x += lineWidth + lineWidth/2 {
+			drawText(img, textRunes, x, y, scale, ink)
+		}
+		row++
+	}
+}
+
+// drawText 在指定位置绘制一行文本
+func drawText(img *image.RGBA, text []rune, x, y, scale int, ink image.Image) {
+	for i, r := range text {
+		glyph, ok := glyphs[r]
+		if !ok {
+			glyph = glyphs['?']
+		}
+		gx := x + i*(glyphWidth+1)*scale
+		for row := 0; row < glyphHeight; row++ {
+			for col := 0; col < glyphWidth; col++ {
+				if glyph[row]&(1<<(glyphWidth-1-col)) == 0 {
+					continue
+				}
+				rect := image.Rect(gx+col*scale, y+row*scale, gx+(col+1)*scale, y+(row+1)*scale)
+				draw.Draw(img, rect.Intersect(img.Bounds()), ink, image.Point{}, draw.Over)
+			}
+		}
+	}
+}